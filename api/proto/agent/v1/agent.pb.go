@@ -30,6 +30,7 @@ type RegisterRequest struct {
 	AgentVersion  string                 `protobuf:"bytes,2,opt,name=agent_version,json=agentVersion,proto3" json:"agent_version,omitempty"`
 	Fingerprint   string                 `protobuf:"bytes,3,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
 	ClusterInfo   *ClusterInfo           `protobuf:"bytes,4,opt,name=cluster_info,json=clusterInfo,proto3" json:"cluster_info,omitempty"`
+	Capabilities  *AgentCapabilities     `protobuf:"bytes,5,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -92,6 +93,67 @@ func (x *RegisterRequest) GetClusterInfo() *ClusterInfo {
 	return nil
 }
 
+func (x *RegisterRequest) GetCapabilities() *AgentCapabilities {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+// AgentCapabilities advertises what an agent supports. Agents that do not send
+// capabilities are assumed to support every operation type.
+type AgentCapabilities struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	OperationTypes []string               `protobuf:"bytes,1,rep,name=operation_types,json=operationTypes,proto3" json:"operation_types,omitempty"` // e.g. "apply", "exec", "sync"
+	Features       []string               `protobuf:"bytes,2,rep,name=features,proto3" json:"features,omitempty"`                                   // optional features, e.g. "dry_run"
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AgentCapabilities) Reset() {
+	*x = AgentCapabilities{}
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentCapabilities) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentCapabilities) ProtoMessage() {}
+
+func (x *AgentCapabilities) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentCapabilities.ProtoReflect.Descriptor instead.
+func (*AgentCapabilities) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AgentCapabilities) GetOperationTypes() []string {
+	if x != nil {
+		return x.OperationTypes
+	}
+	return nil
+}
+
+func (x *AgentCapabilities) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
 // RegisterResponse is the response to registration
 type RegisterResponse struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
@@ -106,7 +168,7 @@ type RegisterResponse struct {
 
 func (x *RegisterResponse) Reset() {
 	*x = RegisterResponse{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[1]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -118,7 +180,7 @@ func (x *RegisterResponse) String() string {
 func (*RegisterResponse) ProtoMessage() {}
 
 func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[1]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -131,7 +193,7 @@ func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
 func (*RegisterResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{1}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *RegisterResponse) GetSuccess() bool {
@@ -171,17 +233,22 @@ func (x *RegisterResponse) GetHeartbeatInterval() int64 {
 
 // HeartbeatRequest is sent periodically
 type HeartbeatRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ClusterId     string                 `protobuf:"bytes,1,opt,name=cluster_id,json=clusterId,proto3" json:"cluster_id,omitempty"`
-	SessionToken  string                 `protobuf:"bytes,2,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
-	Status        *ClusterStatus         `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	ClusterId    string                 `protobuf:"bytes,1,opt,name=cluster_id,json=clusterId,proto3" json:"cluster_id,omitempty"`
+	SessionToken string                 `protobuf:"bytes,2,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
+	Status       *ClusterStatus         `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	// Agent's local clock when the heartbeat was sent, used to detect clock skew
+	AgentTime *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=agent_time,json=agentTime,proto3" json:"agent_time,omitempty"`
+	// Set once the agent passed its readiness check; agents advertising the
+	// "readiness" feature get no operations until they report ready
+	Ready         bool `protobuf:"varint,5,opt,name=ready,proto3" json:"ready,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *HeartbeatRequest) Reset() {
 	*x = HeartbeatRequest{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[2]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -193,7 +260,7 @@ func (x *HeartbeatRequest) String() string {
 func (*HeartbeatRequest) ProtoMessage() {}
 
 func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[2]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -206,7 +273,7 @@ func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
 func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{2}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *HeartbeatRequest) GetClusterId() string {
@@ -230,18 +297,38 @@ func (x *HeartbeatRequest) GetStatus() *ClusterStatus {
 	return nil
 }
 
+func (x *HeartbeatRequest) GetAgentTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AgentTime
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetReady() bool {
+	if x != nil {
+		return x.Ready
+	}
+	return false
+}
+
 // HeartbeatResponse confirms heartbeat
 type HeartbeatResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Set when the hub is overloaded; agents should slow their heartbeats and
+	// result reporting until it clears
+	Busy bool `protobuf:"varint,3,opt,name=busy,proto3" json:"busy,omitempty"`
+	// Heartbeat interval the hub asks for while busy, in seconds; 0 keeps the
+	// agent's configured interval
+	SuggestedInterval int64 `protobuf:"varint,4,opt,name=suggested_interval,json=suggestedInterval,proto3" json:"suggested_interval,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *HeartbeatResponse) Reset() {
 	*x = HeartbeatResponse{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[3]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -253,7 +340,7 @@ func (x *HeartbeatResponse) String() string {
 func (*HeartbeatResponse) ProtoMessage() {}
 
 func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[3]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -266,7 +353,7 @@ func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
 func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{3}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *HeartbeatResponse) GetSuccess() bool {
@@ -283,6 +370,20 @@ func (x *HeartbeatResponse) GetMessage() string {
 	return ""
 }
 
+func (x *HeartbeatResponse) GetBusy() bool {
+	if x != nil {
+		return x.Busy
+	}
+	return false
+}
+
+func (x *HeartbeatResponse) GetSuggestedInterval() int64 {
+	if x != nil {
+		return x.SuggestedInterval
+	}
+	return 0
+}
+
 // StreamOperationsRequest requests operation stream
 type StreamOperationsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -294,7 +395,7 @@ type StreamOperationsRequest struct {
 
 func (x *StreamOperationsRequest) Reset() {
 	*x = StreamOperationsRequest{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[4]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -306,7 +407,7 @@ func (x *StreamOperationsRequest) String() string {
 func (*StreamOperationsRequest) ProtoMessage() {}
 
 func (x *StreamOperationsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[4]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -319,7 +420,7 @@ func (x *StreamOperationsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamOperationsRequest.ProtoReflect.Descriptor instead.
 func (*StreamOperationsRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{4}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *StreamOperationsRequest) GetClusterId() string {
@@ -345,13 +446,14 @@ type Operation struct {
 	Payload        *anypb.Any             `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
 	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
 	TimeoutSeconds int32                  `protobuf:"varint,6,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	Cancel         bool                   `protobuf:"varint,7,opt,name=cancel,proto3" json:"cancel,omitempty"` // stop the previously sent operation with this id
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
 
 func (x *Operation) Reset() {
 	*x = Operation{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[5]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -363,7 +465,7 @@ func (x *Operation) String() string {
 func (*Operation) ProtoMessage() {}
 
 func (x *Operation) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[5]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -376,7 +478,7 @@ func (x *Operation) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Operation.ProtoReflect.Descriptor instead.
 func (*Operation) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{5}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *Operation) GetId() string {
@@ -421,6 +523,13 @@ func (x *Operation) GetTimeoutSeconds() int32 {
 	return 0
 }
 
+func (x *Operation) GetCancel() bool {
+	if x != nil {
+		return x.Cancel
+	}
+	return false
+}
+
 // ReportResultRequest reports operation completion
 type ReportResultRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -430,13 +539,14 @@ type ReportResultRequest struct {
 	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
 	Result        *anypb.Any             `protobuf:"bytes,5,opt,name=result,proto3" json:"result,omitempty"`
 	CompletedAt   *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	SessionToken  string                 `protobuf:"bytes,7,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ReportResultRequest) Reset() {
 	*x = ReportResultRequest{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[6]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -448,7 +558,7 @@ func (x *ReportResultRequest) String() string {
 func (*ReportResultRequest) ProtoMessage() {}
 
 func (x *ReportResultRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[6]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -461,7 +571,7 @@ func (x *ReportResultRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportResultRequest.ProtoReflect.Descriptor instead.
 func (*ReportResultRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{6}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ReportResultRequest) GetOperationId() string {
@@ -506,6 +616,13 @@ func (x *ReportResultRequest) GetCompletedAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *ReportResultRequest) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
 // ReportResultResponse confirms result reporting
 type ReportResultResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -517,7 +634,7 @@ type ReportResultResponse struct {
 
 func (x *ReportResultResponse) Reset() {
 	*x = ReportResultResponse{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[7]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -529,7 +646,7 @@ func (x *ReportResultResponse) String() string {
 func (*ReportResultResponse) ProtoMessage() {}
 
 func (x *ReportResultResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[7]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -542,7 +659,7 @@ func (x *ReportResultResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportResultResponse.ProtoReflect.Descriptor instead.
 func (*ReportResultResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{7}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ReportResultResponse) GetSuccess() bool {
@@ -559,6 +676,129 @@ func (x *ReportResultResponse) GetMessage() string {
 	return ""
 }
 
+// OutputChunk carries output produced by a running operation, appended to the
+// output reported before it
+type OutputChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OperationId   string                 `protobuf:"bytes,1,opt,name=operation_id,json=operationId,proto3" json:"operation_id,omitempty"`
+	ClusterId     string                 `protobuf:"bytes,2,opt,name=cluster_id,json=clusterId,proto3" json:"cluster_id,omitempty"`
+	SessionToken  string                 `protobuf:"bytes,3,opt,name=session_token,json=sessionToken,proto3" json:"session_token,omitempty"`
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OutputChunk) Reset() {
+	*x = OutputChunk{}
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OutputChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OutputChunk) ProtoMessage() {}
+
+func (x *OutputChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OutputChunk.ProtoReflect.Descriptor instead.
+func (*OutputChunk) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *OutputChunk) GetOperationId() string {
+	if x != nil {
+		return x.OperationId
+	}
+	return ""
+}
+
+func (x *OutputChunk) GetClusterId() string {
+	if x != nil {
+		return x.ClusterId
+	}
+	return ""
+}
+
+func (x *OutputChunk) GetSessionToken() string {
+	if x != nil {
+		return x.SessionToken
+	}
+	return ""
+}
+
+func (x *OutputChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// ReportOutputResponse confirms output receipt
+type ReportOutputResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportOutputResponse) Reset() {
+	*x = ReportOutputResponse{}
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportOutputResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportOutputResponse) ProtoMessage() {}
+
+func (x *ReportOutputResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportOutputResponse.ProtoReflect.Descriptor instead.
+func (*ReportOutputResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ReportOutputResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportOutputResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 // LogEntry represents a log entry
 type LogEntry struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -573,7 +813,7 @@ type LogEntry struct {
 
 func (x *LogEntry) Reset() {
 	*x = LogEntry{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[8]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -585,7 +825,7 @@ func (x *LogEntry) String() string {
 func (*LogEntry) ProtoMessage() {}
 
 func (x *LogEntry) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[8]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -598,7 +838,7 @@ func (x *LogEntry) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
 func (*LogEntry) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{8}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *LogEntry) GetLevel() string {
@@ -647,7 +887,7 @@ type LogStreamResponse struct {
 
 func (x *LogStreamResponse) Reset() {
 	*x = LogStreamResponse{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[9]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -659,7 +899,7 @@ func (x *LogStreamResponse) String() string {
 func (*LogStreamResponse) ProtoMessage() {}
 
 func (x *LogStreamResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[9]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -672,7 +912,7 @@ func (x *LogStreamResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LogStreamResponse.ProtoReflect.Descriptor instead.
 func (*LogStreamResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{9}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *LogStreamResponse) GetSuccess() bool {
@@ -702,7 +942,7 @@ type MetricEntry struct {
 
 func (x *MetricEntry) Reset() {
 	*x = MetricEntry{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[10]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -714,7 +954,7 @@ func (x *MetricEntry) String() string {
 func (*MetricEntry) ProtoMessage() {}
 
 func (x *MetricEntry) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[10]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -727,7 +967,7 @@ func (x *MetricEntry) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MetricEntry.ProtoReflect.Descriptor instead.
 func (*MetricEntry) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{10}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *MetricEntry) GetName() string {
@@ -769,7 +1009,7 @@ type MetricStreamResponse struct {
 
 func (x *MetricStreamResponse) Reset() {
 	*x = MetricStreamResponse{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[11]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -781,7 +1021,7 @@ func (x *MetricStreamResponse) String() string {
 func (*MetricStreamResponse) ProtoMessage() {}
 
 func (x *MetricStreamResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[11]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -794,7 +1034,7 @@ func (x *MetricStreamResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MetricStreamResponse.ProtoReflect.Descriptor instead.
 func (*MetricStreamResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{11}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *MetricStreamResponse) GetSuccess() bool {
@@ -825,7 +1065,7 @@ type ClusterInfo struct {
 
 func (x *ClusterInfo) Reset() {
 	*x = ClusterInfo{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[12]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -837,7 +1077,7 @@ func (x *ClusterInfo) String() string {
 func (*ClusterInfo) ProtoMessage() {}
 
 func (x *ClusterInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[12]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -850,7 +1090,7 @@ func (x *ClusterInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClusterInfo.ProtoReflect.Descriptor instead.
 func (*ClusterInfo) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{12}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *ClusterInfo) GetKubernetesVersion() string {
@@ -902,7 +1142,7 @@ type ClusterStatus struct {
 
 func (x *ClusterStatus) Reset() {
 	*x = ClusterStatus{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[13]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -914,7 +1154,7 @@ func (x *ClusterStatus) String() string {
 func (*ClusterStatus) ProtoMessage() {}
 
 func (x *ClusterStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[13]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -927,7 +1167,7 @@ func (x *ClusterStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClusterStatus.ProtoReflect.Descriptor instead.
 func (*ClusterStatus) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{13}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *ClusterStatus) GetStatus() string {
@@ -978,7 +1218,7 @@ type CancelOperationRequest struct {
 
 func (x *CancelOperationRequest) Reset() {
 	*x = CancelOperationRequest{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[14]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -990,7 +1230,7 @@ func (x *CancelOperationRequest) String() string {
 func (*CancelOperationRequest) ProtoMessage() {}
 
 func (x *CancelOperationRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[14]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1003,7 +1243,7 @@ func (x *CancelOperationRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelOperationRequest.ProtoReflect.Descriptor instead.
 func (*CancelOperationRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{14}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *CancelOperationRequest) GetOperationId() string {
@@ -1045,7 +1285,7 @@ type CancelOperationResponse struct {
 
 func (x *CancelOperationResponse) Reset() {
 	*x = CancelOperationResponse{}
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[15]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1057,7 +1297,7 @@ func (x *CancelOperationResponse) String() string {
 func (*CancelOperationResponse) ProtoMessage() {}
 
 func (x *CancelOperationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[15]
+	mi := &file_api_proto_agent_v1_agent_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1070,7 +1310,7 @@ func (x *CancelOperationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelOperationResponse.ProtoReflect.Descriptor instead.
 func (*CancelOperationResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{15}
+	return file_api_proto_agent_v1_agent_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *CancelOperationResponse) GetSuccess() bool {
@@ -1091,31 +1331,40 @@ var File_api_proto_agent_v1_agent_proto protoreflect.FileDescriptor
 
 const file_api_proto_agent_v1_agent_proto_rawDesc = "" +
 	"\n" +
-	"\x1eapi/proto/agent/v1/agent.proto\x12\x0emckma.agent.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x19google/protobuf/any.proto\"\xbb\x01\n" +
+	"\x1eapi/proto/agent/v1/agent.proto\x12\x0emckma.agent.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x19google/protobuf/any.proto\"\x82\x02\n" +
 	"\x0fRegisterRequest\x12!\n" +
 	"\fcluster_name\x18\x01 \x01(\tR\vclusterName\x12#\n" +
 	"\ragent_version\x18\x02 \x01(\tR\fagentVersion\x12 \n" +
 	"\vfingerprint\x18\x03 \x01(\tR\vfingerprint\x12>\n" +
-	"\fcluster_info\x18\x04 \x01(\v2\x1b.mckma.agent.v1.ClusterInfoR\vclusterInfo\"\xb9\x01\n" +
+	"\fcluster_info\x18\x04 \x01(\v2\x1b.mckma.agent.v1.ClusterInfoR\vclusterInfo\x12E\n" +
+	"\fcapabilities\x18\x05 \x01(\v2!.mckma.agent.v1.AgentCapabilitiesR\fcapabilities\"X\n" +
+	"\x11AgentCapabilities\x12'\n" +
+	"\x0foperation_types\x18\x01 \x03(\tR\x0eoperationTypes\x12\x1a\n" +
+	"\bfeatures\x18\x02 \x03(\tR\bfeatures\"\xb9\x01\n" +
 	"\x10RegisterResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1d\n" +
 	"\n" +
 	"cluster_id\x18\x03 \x01(\tR\tclusterId\x12#\n" +
 	"\rsession_token\x18\x04 \x01(\tR\fsessionToken\x12-\n" +
-	"\x12heartbeat_interval\x18\x05 \x01(\x03R\x11heartbeatInterval\"\x8d\x01\n" +
+	"\x12heartbeat_interval\x18\x05 \x01(\x03R\x11heartbeatInterval\"\xde\x01\n" +
 	"\x10HeartbeatRequest\x12\x1d\n" +
 	"\n" +
 	"cluster_id\x18\x01 \x01(\tR\tclusterId\x12#\n" +
 	"\rsession_token\x18\x02 \x01(\tR\fsessionToken\x125\n" +
-	"\x06status\x18\x03 \x01(\v2\x1d.mckma.agent.v1.ClusterStatusR\x06status\"G\n" +
+	"\x06status\x18\x03 \x01(\v2\x1d.mckma.agent.v1.ClusterStatusR\x06status\x129\n" +
+	"\n" +
+	"agent_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tagentTime\x12\x14\n" +
+	"\x05ready\x18\x05 \x01(\bR\x05ready\"\x8a\x01\n" +
 	"\x11HeartbeatResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"]\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x12\n" +
+	"\x04busy\x18\x03 \x01(\bR\x04busy\x12-\n" +
+	"\x12suggested_interval\x18\x04 \x01(\x03R\x11suggestedInterval\"]\n" +
 	"\x17StreamOperationsRequest\x12\x1d\n" +
 	"\n" +
 	"cluster_id\x18\x01 \x01(\tR\tclusterId\x12#\n" +
-	"\rsession_token\x18\x02 \x01(\tR\fsessionToken\"\xe2\x01\n" +
+	"\rsession_token\x18\x02 \x01(\tR\fsessionToken\"\xfa\x01\n" +
 	"\tOperation\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
 	"\n" +
@@ -1124,7 +1373,8 @@ const file_api_proto_agent_v1_agent_proto_rawDesc = "" +
 	"\apayload\x18\x04 \x01(\v2\x14.google.protobuf.AnyR\apayload\x129\n" +
 	"\n" +
 	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12'\n" +
-	"\x0ftimeout_seconds\x18\x06 \x01(\x05R\x0etimeoutSeconds\"\xf8\x01\n" +
+	"\x0ftimeout_seconds\x18\x06 \x01(\x05R\x0etimeoutSeconds\x12\x16\n" +
+	"\x06cancel\x18\a \x01(\bR\x06cancel\"\x9d\x02\n" +
 	"\x13ReportResultRequest\x12!\n" +
 	"\foperation_id\x18\x01 \x01(\tR\voperationId\x12\x1d\n" +
 	"\n" +
@@ -1132,9 +1382,19 @@ const file_api_proto_agent_v1_agent_proto_rawDesc = "" +
 	"\asuccess\x18\x03 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x04 \x01(\tR\amessage\x12,\n" +
 	"\x06result\x18\x05 \x01(\v2\x14.google.protobuf.AnyR\x06result\x12=\n" +
-	"\fcompleted_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\vcompletedAt\"J\n" +
+	"\fcompleted_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\vcompletedAt\x12#\n" +
+	"\rsession_token\x18\a \x01(\tR\fsessionToken\"J\n" +
 	"\x14ReportResultResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x88\x01\n" +
+	"\vOutputChunk\x12!\n" +
+	"\foperation_id\x18\x01 \x01(\tR\voperationId\x12\x1d\n" +
+	"\n" +
+	"cluster_id\x18\x02 \x01(\tR\tclusterId\x12#\n" +
+	"\rsession_token\x18\x03 \x01(\tR\fsessionToken\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\"J\n" +
+	"\x14ReportOutputResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\"\x85\x02\n" +
 	"\bLogEntry\x12\x14\n" +
 	"\x05level\x18\x01 \x01(\tR\x05level\x12\x18\n" +
@@ -1186,12 +1446,13 @@ const file_api_proto_agent_v1_agent_proto_rawDesc = "" +
 	"\x06reason\x18\x04 \x01(\tR\x06reason\"M\n" +
 	"\x17CancelOperationResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2\xeb\x04\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage2\xbe\x05\n" +
 	"\fAgentService\x12M\n" +
 	"\bRegister\x12\x1f.mckma.agent.v1.RegisterRequest\x1a .mckma.agent.v1.RegisterResponse\x12P\n" +
 	"\tHeartbeat\x12 .mckma.agent.v1.HeartbeatRequest\x1a!.mckma.agent.v1.HeartbeatResponse\x12X\n" +
 	"\x10StreamOperations\x12'.mckma.agent.v1.StreamOperationsRequest\x1a\x19.mckma.agent.v1.Operation0\x01\x12Y\n" +
-	"\fReportResult\x12#.mckma.agent.v1.ReportResultRequest\x1a$.mckma.agent.v1.ReportResultResponse\x12K\n" +
+	"\fReportResult\x12#.mckma.agent.v1.ReportResultRequest\x1a$.mckma.agent.v1.ReportResultResponse\x12Q\n" +
+	"\fReportOutput\x12\x1b.mckma.agent.v1.OutputChunk\x1a$.mckma.agent.v1.ReportOutputResponse\x12K\n" +
 	"\n" +
 	"StreamLogs\x12\x18.mckma.agent.v1.LogEntry\x1a!.mckma.agent.v1.LogStreamResponse(\x01\x12T\n" +
 	"\rStreamMetrics\x12\x1b.mckma.agent.v1.MetricEntry\x1a$.mckma.agent.v1.MetricStreamResponse(\x01\x12b\n" +
@@ -1209,62 +1470,69 @@ func file_api_proto_agent_v1_agent_proto_rawDescGZIP() []byte {
 	return file_api_proto_agent_v1_agent_proto_rawDescData
 }
 
-var file_api_proto_agent_v1_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_api_proto_agent_v1_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
 var file_api_proto_agent_v1_agent_proto_goTypes = []any{
 	(*RegisterRequest)(nil),         // 0: mckma.agent.v1.RegisterRequest
-	(*RegisterResponse)(nil),        // 1: mckma.agent.v1.RegisterResponse
-	(*HeartbeatRequest)(nil),        // 2: mckma.agent.v1.HeartbeatRequest
-	(*HeartbeatResponse)(nil),       // 3: mckma.agent.v1.HeartbeatResponse
-	(*StreamOperationsRequest)(nil), // 4: mckma.agent.v1.StreamOperationsRequest
-	(*Operation)(nil),               // 5: mckma.agent.v1.Operation
-	(*ReportResultRequest)(nil),     // 6: mckma.agent.v1.ReportResultRequest
-	(*ReportResultResponse)(nil),    // 7: mckma.agent.v1.ReportResultResponse
-	(*LogEntry)(nil),                // 8: mckma.agent.v1.LogEntry
-	(*LogStreamResponse)(nil),       // 9: mckma.agent.v1.LogStreamResponse
-	(*MetricEntry)(nil),             // 10: mckma.agent.v1.MetricEntry
-	(*MetricStreamResponse)(nil),    // 11: mckma.agent.v1.MetricStreamResponse
-	(*ClusterInfo)(nil),             // 12: mckma.agent.v1.ClusterInfo
-	(*ClusterStatus)(nil),           // 13: mckma.agent.v1.ClusterStatus
-	(*CancelOperationRequest)(nil),  // 14: mckma.agent.v1.CancelOperationRequest
-	(*CancelOperationResponse)(nil), // 15: mckma.agent.v1.CancelOperationResponse
-	nil,                             // 16: mckma.agent.v1.LogEntry.FieldsEntry
-	nil,                             // 17: mckma.agent.v1.MetricEntry.LabelsEntry
-	nil,                             // 18: mckma.agent.v1.ClusterInfo.LabelsEntry
-	(*anypb.Any)(nil),               // 19: google.protobuf.Any
-	(*timestamppb.Timestamp)(nil),   // 20: google.protobuf.Timestamp
+	(*AgentCapabilities)(nil),       // 1: mckma.agent.v1.AgentCapabilities
+	(*RegisterResponse)(nil),        // 2: mckma.agent.v1.RegisterResponse
+	(*HeartbeatRequest)(nil),        // 3: mckma.agent.v1.HeartbeatRequest
+	(*HeartbeatResponse)(nil),       // 4: mckma.agent.v1.HeartbeatResponse
+	(*StreamOperationsRequest)(nil), // 5: mckma.agent.v1.StreamOperationsRequest
+	(*Operation)(nil),               // 6: mckma.agent.v1.Operation
+	(*ReportResultRequest)(nil),     // 7: mckma.agent.v1.ReportResultRequest
+	(*ReportResultResponse)(nil),    // 8: mckma.agent.v1.ReportResultResponse
+	(*OutputChunk)(nil),             // 9: mckma.agent.v1.OutputChunk
+	(*ReportOutputResponse)(nil),    // 10: mckma.agent.v1.ReportOutputResponse
+	(*LogEntry)(nil),                // 11: mckma.agent.v1.LogEntry
+	(*LogStreamResponse)(nil),       // 12: mckma.agent.v1.LogStreamResponse
+	(*MetricEntry)(nil),             // 13: mckma.agent.v1.MetricEntry
+	(*MetricStreamResponse)(nil),    // 14: mckma.agent.v1.MetricStreamResponse
+	(*ClusterInfo)(nil),             // 15: mckma.agent.v1.ClusterInfo
+	(*ClusterStatus)(nil),           // 16: mckma.agent.v1.ClusterStatus
+	(*CancelOperationRequest)(nil),  // 17: mckma.agent.v1.CancelOperationRequest
+	(*CancelOperationResponse)(nil), // 18: mckma.agent.v1.CancelOperationResponse
+	nil,                             // 19: mckma.agent.v1.LogEntry.FieldsEntry
+	nil,                             // 20: mckma.agent.v1.MetricEntry.LabelsEntry
+	nil,                             // 21: mckma.agent.v1.ClusterInfo.LabelsEntry
+	(*timestamppb.Timestamp)(nil),   // 22: google.protobuf.Timestamp
+	(*anypb.Any)(nil),               // 23: google.protobuf.Any
 }
 var file_api_proto_agent_v1_agent_proto_depIdxs = []int32{
-	12, // 0: mckma.agent.v1.RegisterRequest.cluster_info:type_name -> mckma.agent.v1.ClusterInfo
-	13, // 1: mckma.agent.v1.HeartbeatRequest.status:type_name -> mckma.agent.v1.ClusterStatus
-	19, // 2: mckma.agent.v1.Operation.payload:type_name -> google.protobuf.Any
-	20, // 3: mckma.agent.v1.Operation.created_at:type_name -> google.protobuf.Timestamp
-	19, // 4: mckma.agent.v1.ReportResultRequest.result:type_name -> google.protobuf.Any
-	20, // 5: mckma.agent.v1.ReportResultRequest.completed_at:type_name -> google.protobuf.Timestamp
-	20, // 6: mckma.agent.v1.LogEntry.timestamp:type_name -> google.protobuf.Timestamp
-	16, // 7: mckma.agent.v1.LogEntry.fields:type_name -> mckma.agent.v1.LogEntry.FieldsEntry
-	17, // 8: mckma.agent.v1.MetricEntry.labels:type_name -> mckma.agent.v1.MetricEntry.LabelsEntry
-	20, // 9: mckma.agent.v1.MetricEntry.timestamp:type_name -> google.protobuf.Timestamp
-	18, // 10: mckma.agent.v1.ClusterInfo.labels:type_name -> mckma.agent.v1.ClusterInfo.LabelsEntry
-	20, // 11: mckma.agent.v1.ClusterStatus.last_check:type_name -> google.protobuf.Timestamp
-	0,  // 12: mckma.agent.v1.AgentService.Register:input_type -> mckma.agent.v1.RegisterRequest
-	2,  // 13: mckma.agent.v1.AgentService.Heartbeat:input_type -> mckma.agent.v1.HeartbeatRequest
-	4,  // 14: mckma.agent.v1.AgentService.StreamOperations:input_type -> mckma.agent.v1.StreamOperationsRequest
-	6,  // 15: mckma.agent.v1.AgentService.ReportResult:input_type -> mckma.agent.v1.ReportResultRequest
-	8,  // 16: mckma.agent.v1.AgentService.StreamLogs:input_type -> mckma.agent.v1.LogEntry
-	10, // 17: mckma.agent.v1.AgentService.StreamMetrics:input_type -> mckma.agent.v1.MetricEntry
-	14, // 18: mckma.agent.v1.AgentService.CancelOperation:input_type -> mckma.agent.v1.CancelOperationRequest
-	1,  // 19: mckma.agent.v1.AgentService.Register:output_type -> mckma.agent.v1.RegisterResponse
-	3,  // 20: mckma.agent.v1.AgentService.Heartbeat:output_type -> mckma.agent.v1.HeartbeatResponse
-	5,  // 21: mckma.agent.v1.AgentService.StreamOperations:output_type -> mckma.agent.v1.Operation
-	7,  // 22: mckma.agent.v1.AgentService.ReportResult:output_type -> mckma.agent.v1.ReportResultResponse
-	9,  // 23: mckma.agent.v1.AgentService.StreamLogs:output_type -> mckma.agent.v1.LogStreamResponse
-	11, // 24: mckma.agent.v1.AgentService.StreamMetrics:output_type -> mckma.agent.v1.MetricStreamResponse
-	15, // 25: mckma.agent.v1.AgentService.CancelOperation:output_type -> mckma.agent.v1.CancelOperationResponse
-	19, // [19:26] is the sub-list for method output_type
-	12, // [12:19] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	15, // 0: mckma.agent.v1.RegisterRequest.cluster_info:type_name -> mckma.agent.v1.ClusterInfo
+	1,  // 1: mckma.agent.v1.RegisterRequest.capabilities:type_name -> mckma.agent.v1.AgentCapabilities
+	16, // 2: mckma.agent.v1.HeartbeatRequest.status:type_name -> mckma.agent.v1.ClusterStatus
+	22, // 3: mckma.agent.v1.HeartbeatRequest.agent_time:type_name -> google.protobuf.Timestamp
+	23, // 4: mckma.agent.v1.Operation.payload:type_name -> google.protobuf.Any
+	22, // 5: mckma.agent.v1.Operation.created_at:type_name -> google.protobuf.Timestamp
+	23, // 6: mckma.agent.v1.ReportResultRequest.result:type_name -> google.protobuf.Any
+	22, // 7: mckma.agent.v1.ReportResultRequest.completed_at:type_name -> google.protobuf.Timestamp
+	22, // 8: mckma.agent.v1.LogEntry.timestamp:type_name -> google.protobuf.Timestamp
+	19, // 9: mckma.agent.v1.LogEntry.fields:type_name -> mckma.agent.v1.LogEntry.FieldsEntry
+	20, // 10: mckma.agent.v1.MetricEntry.labels:type_name -> mckma.agent.v1.MetricEntry.LabelsEntry
+	22, // 11: mckma.agent.v1.MetricEntry.timestamp:type_name -> google.protobuf.Timestamp
+	21, // 12: mckma.agent.v1.ClusterInfo.labels:type_name -> mckma.agent.v1.ClusterInfo.LabelsEntry
+	22, // 13: mckma.agent.v1.ClusterStatus.last_check:type_name -> google.protobuf.Timestamp
+	0,  // 14: mckma.agent.v1.AgentService.Register:input_type -> mckma.agent.v1.RegisterRequest
+	3,  // 15: mckma.agent.v1.AgentService.Heartbeat:input_type -> mckma.agent.v1.HeartbeatRequest
+	5,  // 16: mckma.agent.v1.AgentService.StreamOperations:input_type -> mckma.agent.v1.StreamOperationsRequest
+	7,  // 17: mckma.agent.v1.AgentService.ReportResult:input_type -> mckma.agent.v1.ReportResultRequest
+	9,  // 18: mckma.agent.v1.AgentService.ReportOutput:input_type -> mckma.agent.v1.OutputChunk
+	11, // 19: mckma.agent.v1.AgentService.StreamLogs:input_type -> mckma.agent.v1.LogEntry
+	13, // 20: mckma.agent.v1.AgentService.StreamMetrics:input_type -> mckma.agent.v1.MetricEntry
+	17, // 21: mckma.agent.v1.AgentService.CancelOperation:input_type -> mckma.agent.v1.CancelOperationRequest
+	2,  // 22: mckma.agent.v1.AgentService.Register:output_type -> mckma.agent.v1.RegisterResponse
+	4,  // 23: mckma.agent.v1.AgentService.Heartbeat:output_type -> mckma.agent.v1.HeartbeatResponse
+	6,  // 24: mckma.agent.v1.AgentService.StreamOperations:output_type -> mckma.agent.v1.Operation
+	8,  // 25: mckma.agent.v1.AgentService.ReportResult:output_type -> mckma.agent.v1.ReportResultResponse
+	10, // 26: mckma.agent.v1.AgentService.ReportOutput:output_type -> mckma.agent.v1.ReportOutputResponse
+	12, // 27: mckma.agent.v1.AgentService.StreamLogs:output_type -> mckma.agent.v1.LogStreamResponse
+	14, // 28: mckma.agent.v1.AgentService.StreamMetrics:output_type -> mckma.agent.v1.MetricStreamResponse
+	18, // 29: mckma.agent.v1.AgentService.CancelOperation:output_type -> mckma.agent.v1.CancelOperationResponse
+	22, // [22:30] is the sub-list for method output_type
+	14, // [14:22] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_agent_v1_agent_proto_init() }
@@ -1278,7 +1546,7 @@ func file_api_proto_agent_v1_agent_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_agent_v1_agent_proto_rawDesc), len(file_api_proto_agent_v1_agent_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   19,
+			NumMessages:   22,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
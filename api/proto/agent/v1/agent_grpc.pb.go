@@ -23,6 +23,7 @@ const (
 	AgentService_Heartbeat_FullMethodName        = "/mckma.agent.v1.AgentService/Heartbeat"
 	AgentService_StreamOperations_FullMethodName = "/mckma.agent.v1.AgentService/StreamOperations"
 	AgentService_ReportResult_FullMethodName     = "/mckma.agent.v1.AgentService/ReportResult"
+	AgentService_ReportOutput_FullMethodName     = "/mckma.agent.v1.AgentService/ReportOutput"
 	AgentService_StreamLogs_FullMethodName       = "/mckma.agent.v1.AgentService/StreamLogs"
 	AgentService_StreamMetrics_FullMethodName    = "/mckma.agent.v1.AgentService/StreamMetrics"
 	AgentService_CancelOperation_FullMethodName  = "/mckma.agent.v1.AgentService/CancelOperation"
@@ -42,6 +43,8 @@ type AgentServiceClient interface {
 	StreamOperations(ctx context.Context, in *StreamOperationsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Operation], error)
 	// Report operation result
 	ReportResult(ctx context.Context, in *ReportResultRequest, opts ...grpc.CallOption) (*ReportResultResponse, error)
+	// Report output produced by a running operation
+	ReportOutput(ctx context.Context, in *OutputChunk, opts ...grpc.CallOption) (*ReportOutputResponse, error)
 	// Stream logs to hub
 	StreamLogs(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[LogEntry, LogStreamResponse], error)
 	// Stream metrics to hub
@@ -107,6 +110,16 @@ func (c *agentServiceClient) ReportResult(ctx context.Context, in *ReportResultR
 	return out, nil
 }
 
+func (c *agentServiceClient) ReportOutput(ctx context.Context, in *OutputChunk, opts ...grpc.CallOption) (*ReportOutputResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportOutputResponse)
+	err := c.cc.Invoke(ctx, AgentService_ReportOutput_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *agentServiceClient) StreamLogs(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[LogEntry, LogStreamResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[1], AgentService_StreamLogs_FullMethodName, cOpts...)
@@ -157,6 +170,8 @@ type AgentServiceServer interface {
 	StreamOperations(*StreamOperationsRequest, grpc.ServerStreamingServer[Operation]) error
 	// Report operation result
 	ReportResult(context.Context, *ReportResultRequest) (*ReportResultResponse, error)
+	// Report output produced by a running operation
+	ReportOutput(context.Context, *OutputChunk) (*ReportOutputResponse, error)
 	// Stream logs to hub
 	StreamLogs(grpc.ClientStreamingServer[LogEntry, LogStreamResponse]) error
 	// Stream metrics to hub
@@ -185,6 +200,9 @@ func (UnimplementedAgentServiceServer) StreamOperations(*StreamOperationsRequest
 func (UnimplementedAgentServiceServer) ReportResult(context.Context, *ReportResultRequest) (*ReportResultResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ReportResult not implemented")
 }
+func (UnimplementedAgentServiceServer) ReportOutput(context.Context, *OutputChunk) (*ReportOutputResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportOutput not implemented")
+}
 func (UnimplementedAgentServiceServer) StreamLogs(grpc.ClientStreamingServer[LogEntry, LogStreamResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
 }
@@ -280,6 +298,24 @@ func _AgentService_ReportResult_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentService_ReportOutput_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OutputChunk)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportOutput(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentService_ReportOutput_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportOutput(ctx, req.(*OutputChunk))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AgentService_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(AgentServiceServer).StreamLogs(&grpc.GenericServerStream[LogEntry, LogStreamResponse]{ServerStream: stream})
 }
@@ -331,6 +367,10 @@ var AgentService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReportResult",
 			Handler:    _AgentService_ReportResult_Handler,
 		},
+		{
+			MethodName: "ReportOutput",
+			Handler:    _AgentService_ReportOutput_Handler,
+		},
 		{
 			MethodName: "CancelOperation",
 			Handler:    _AgentService_CancelOperation_Handler,
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// listClustersResponse is the response of GET /clusters
+type listClustersResponse struct {
+	Clusters   []repo.Cluster `json:"clusters"`
+	TotalCount int            `json:"total_count"`
+	HasMore    bool           `json:"has_more"`
+}
+
+// runListClusters lists the clusters registered with the hub
+func runListClusters(cmd *cobra.Command, _ []string) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+	offset, _ := cmd.Flags().GetInt("offset")
+
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	var resp listClustersResponse
+	if err := apiClient().do(cmd.Context(), "GET", "/clusters", query, nil, &resp); err != nil {
+		return err
+	}
+
+	if outputFormat == outputJSON {
+		return writeJSON(cmd.OutOrStdout(), resp.Clusters)
+	}
+	return writeClusterTable(cmd.OutOrStdout(), resp.Clusters)
+}
+
+// createClusterRequest is the request body of POST /clusters
+type createClusterRequest struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Labels      repo.Labels `json:"labels,omitempty"`
+}
+
+// runCreateCluster creates a cluster record and prints its ID
+func runCreateCluster(cmd *cobra.Command, args []string) error {
+	description, _ := cmd.Flags().GetString("description")
+	rawLabels, _ := cmd.Flags().GetStringArray("label")
+
+	labels, err := parseLabels(rawLabels)
+	if err != nil {
+		return err
+	}
+
+	body := createClusterRequest{Name: args[0], Description: description, Labels: labels}
+	var created repo.Cluster
+	if err := apiClient().do(cmd.Context(), "POST", "/clusters", nil, body, &created); err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict {
+			return fmt.Errorf("cluster %q already exists", args[0])
+		}
+		return err
+	}
+
+	if outputFormat == outputJSON {
+		return writeJSON(cmd.OutOrStdout(), created)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), created.ID)
+	return nil
+}
+
+// parseLabels parses key=value label flags
+func parseLabels(values []string) (repo.Labels, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	labels := make(repo.Labels, len(values))
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", value)
+		}
+		labels[key] = val
+	}
+	return labels, nil
+}
+
+// writeClusterTable renders clusters as a table
+func writeClusterTable(w io.Writer, clusters []repo.Cluster) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tLAST SEEN")
+	for _, cluster := range clusters {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", cluster.ID, cluster.Name, cluster.Status, formatTime(cluster.LastSeenAt))
+	}
+	return tw.Flush()
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// formatTime formats an optional timestamp for tables, or "-" when unset
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// runCommand executes the CLI with args and returns its output
+func runCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs(args)
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+func TestListClusters(t *testing.T) {
+	lastSeen := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clusters := []repo.Cluster{
+		{ID: uuid.New(), Name: "prod", Status: "connected", LastSeenAt: &lastSeen},
+		{ID: uuid.New(), Name: "staging", Status: "disconnected"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/clusters" {
+			t.Errorf("Expected path /api/v1/clusters but got %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Expected bearer token but got %q", auth)
+		}
+		if limit, offset := r.URL.Query().Get("limit"), r.URL.Query().Get("offset"); limit != "5" || offset != "10" {
+			t.Errorf("Expected limit 5 and offset 10 but got %q and %q", limit, offset)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"clusters": clusters, "total_count": 12})
+	}))
+	defer server.Close()
+
+	t.Run("table", func(t *testing.T) {
+		out, err := runCommand(t, "clusters", "list", "--hub-url", server.URL, "--token", "secret", "--limit", "5", "--offset", "10", "-o", "table")
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected a header and 2 rows but got:\n%s", out)
+		}
+		if fields := strings.Fields(lines[0]); len(fields) != 5 || fields[0] != "ID" || fields[1] != "NAME" {
+			t.Errorf("Unexpected header %q", lines[0])
+		}
+		if !strings.Contains(lines[1], clusters[0].ID.String()) || !strings.Contains(lines[1], "prod") {
+			t.Errorf("Expected the prod cluster in %q", lines[1])
+		}
+		if !strings.HasSuffix(lines[2], "-") {
+			t.Errorf("Expected a placeholder for a cluster never seen in %q", lines[2])
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, err := runCommand(t, "clusters", "list", "--hub-url", server.URL, "--token", "secret", "--limit", "5", "--offset", "10", "-o", "json")
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+
+		var decoded []repo.Cluster
+		if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+			t.Fatalf("Expected JSON output but got: %v\n%s", err, out)
+		}
+		if len(decoded) != 2 || decoded[1].Name != "staging" {
+			t.Errorf("Expected both clusters but got %+v", decoded)
+		}
+	})
+}
+
+func TestListClustersServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":"Insufficient permissions","status":403}`))
+	}))
+	defer server.Close()
+
+	_, err := runCommand(t, "clusters", "list", "--hub-url", server.URL, "-o", "table")
+
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an API error but got: %v", err)
+	}
+	if apiErr.Status != http.StatusForbidden || apiErr.Message != "Insufficient permissions" {
+		t.Errorf("Expected the server's error message but got %d %q", apiErr.Status, apiErr.Message)
+	}
+}
+
+func TestCreateCluster(t *testing.T) {
+	id := uuid.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/clusters" {
+			t.Errorf("Expected POST /api/v1/clusters but got %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Expected bearer token but got %q", auth)
+		}
+
+		var req createClusterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Expected a JSON body but got: %v", err)
+		}
+		if req.Name != "prod" || req.Description != "Production" {
+			t.Errorf("Expected name prod and description Production but got %q and %q", req.Name, req.Description)
+		}
+		if len(req.Labels) != 2 || req.Labels["env"] != "prod" || req.Labels["team"] != "a=b" {
+			t.Errorf("Expected labels env=prod and team=a=b but got %v", req.Labels)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "name": req.Name})
+	}))
+	defer server.Close()
+
+	out, err := runCommand(t, "clusters", "create", "prod", "--hub-url", server.URL, "--token", "secret", "-o", "table",
+		"--description", "Production", "--label", "env=prod", "--label", "team=a=b")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if strings.TrimSpace(out) != id.String() {
+		t.Errorf("Expected the cluster ID %s but got %q", id, out)
+	}
+}
+
+func TestCreateClusterConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"cluster already exists: prod","status":409}`))
+	}))
+	defer server.Close()
+
+	_, err := runCommand(t, "clusters", "create", "prod", "--hub-url", server.URL, "-o", "table")
+	if err == nil || !strings.Contains(err.Error(), `cluster "prod" already exists`) {
+		t.Errorf("Expected an already exists error but got: %v", err)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	for _, value := range []string{"env", "=prod"} {
+		if _, err := parseLabels([]string{value}); err == nil {
+			t.Errorf("Expected an error for label %q", value)
+		}
+	}
+}
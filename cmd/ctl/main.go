@@ -7,10 +7,31 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Output formats of the --output flag
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var (
+	hubURL       string
+	token        string
+	outputFormat string
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "mckma-ctl",
 	Short: "MCKMA CLI tool for managing clusters",
 	Long:  `A command-line tool for managing multi-cluster Kubernetes environments.`,
+	// Errors are printed by main; usage is only useful for invalid arguments
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if outputFormat != outputTable && outputFormat != outputJSON {
+			return fmt.Errorf("invalid output format %q, expected table or json", outputFormat)
+		}
+		return nil
+	},
 }
 
 var versionCmd = &cobra.Command{
@@ -32,9 +53,8 @@ var listClustersCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all clusters",
 	Long:  `List all clusters registered with MCKMA.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("TODO: Implement cluster listing")
-	},
+	Args:  cobra.NoArgs,
+	RunE:  runListClusters,
 }
 
 var createClusterCmd = &cobra.Command{
@@ -42,22 +62,72 @@ var createClusterCmd = &cobra.Command{
 	Short: "Create a new cluster",
 	Long:  `Create a new cluster registration.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("TODO: Implement cluster creation for %s\n", args[0])
-	},
+	RunE:  runCreateCluster,
+}
+
+var operationsCmd = &cobra.Command{
+	Use:   "operations",
+	Short: "Inspect operations",
+	Long:  `Inspect operations queued for clusters registered with MCKMA.`,
+}
+
+var listOperationsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the operations of a cluster",
+	Long:  `List the operations of a cluster, newest first.`,
+	Args:  cobra.NoArgs,
+	RunE:  runListOperations,
+}
+
+var getOperationCmd = &cobra.Command{
+	Use:   "get [operation-id]",
+	Short: "Show an operation",
+	Long:  `Show an operation's status, timestamps and result. With --watch, poll until the operation succeeds, fails or is cancelled.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runGetOperation,
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&hubURL, "hub-url", envOr("MCKMT_HUB_URL", defaultHubURL), "Hub API URL (env MCKMT_HUB_URL)")
+	rootCmd.PersistentFlags().StringVar(&token, "token", os.Getenv("MCKMT_TOKEN"), "Bearer token (env MCKMT_TOKEN)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format: table or json")
+
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(clustersCmd)
-	
+	rootCmd.AddCommand(operationsCmd)
+
+	listClustersCmd.Flags().Int("limit", 0, "Maximum number of clusters to list; 0 uses the server default")
+	listClustersCmd.Flags().Int("offset", 0, "Number of clusters to skip")
 	clustersCmd.AddCommand(listClustersCmd)
+	createClusterCmd.Flags().String("description", "", "Description of the cluster")
+	createClusterCmd.Flags().StringArray("label", nil, "Label as key=value; may be repeated")
 	clustersCmd.AddCommand(createClusterCmd)
+
+	listOperationsCmd.Flags().String("cluster", "", "ID of the cluster whose operations to list")
+	listOperationsCmd.MarkFlagRequired("cluster")
+	listOperationsCmd.Flags().Int("limit", 0, "Maximum number of operations to list; 0 uses the server default")
+	listOperationsCmd.Flags().Int("offset", 0, "Number of operations to skip")
+	operationsCmd.AddCommand(listOperationsCmd)
+	getOperationCmd.Flags().Bool("watch", false, "Poll every 2 seconds until the operation reaches a terminal state")
+	operationsCmd.AddCommand(getOperationCmd)
+}
+
+// apiClient returns a client for the configured hub
+func apiClient() *client {
+	return newClient(hubURL, token)
+}
+
+// envOr returns the value of the environment variable key, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// watchInterval is how often --watch polls an operation
+var watchInterval = 2 * time.Second
+
+// listOperationsResponse is the response of GET /clusters/{id}/operations
+type listOperationsResponse struct {
+	Operations []repo.Operation `json:"operations"`
+	TotalCount int              `json:"total_count"`
+	HasMore    bool             `json:"has_more"`
+}
+
+// runListOperations lists the operations of a cluster
+func runListOperations(cmd *cobra.Command, _ []string) error {
+	clusterID, _ := cmd.Flags().GetString("cluster")
+	limit, _ := cmd.Flags().GetInt("limit")
+	offset, _ := cmd.Flags().GetInt("offset")
+
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	var resp listOperationsResponse
+	if err := apiClient().do(cmd.Context(), "GET", "/clusters/"+url.PathEscape(clusterID)+"/operations", query, nil, &resp); err != nil {
+		return err
+	}
+
+	if outputFormat == outputJSON {
+		return writeJSON(cmd.OutOrStdout(), resp.Operations)
+	}
+	return writeOperationTable(cmd.OutOrStdout(), resp.Operations)
+}
+
+// runGetOperation shows an operation, with --watch polling it until it finishes
+func runGetOperation(cmd *cobra.Command, args []string) error {
+	watch, _ := cmd.Flags().GetBool("watch")
+	path := "/operations/" + url.PathEscape(args[0])
+
+	var operation repo.Operation
+	lastStatus := ""
+	for {
+		if err := apiClient().do(cmd.Context(), "GET", path, nil, nil, &operation); err != nil {
+			return err
+		}
+		if !watch || isTerminalStatus(operation.Status) {
+			break
+		}
+
+		// Progress goes to stderr so the final output can still be piped
+		if operation.Status != lastStatus {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s\t%s\n", time.Now().Format(time.RFC3339), operation.Status)
+			lastStatus = operation.Status
+		}
+
+		select {
+		case <-cmd.Context().Done():
+			return cmd.Context().Err()
+		case <-time.After(watchInterval):
+		}
+	}
+
+	if outputFormat == outputJSON {
+		return writeJSON(cmd.OutOrStdout(), operation)
+	}
+	return writeOperationDetail(cmd.OutOrStdout(), &operation)
+}
+
+// isTerminalStatus reports whether an operation with status will not change anymore
+func isTerminalStatus(status string) bool {
+	switch status {
+	case repo.OperationStatusSuccess, repo.OperationStatusFailed, repo.OperationStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// writeOperationTable renders operations as a table
+func writeOperationTable(w io.Writer, operations []repo.Operation) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tSTATUS\tSTARTED\tFINISHED")
+	for _, op := range operations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", op.ID, op.Type, op.Status, formatTime(op.StartedAt), formatTime(op.FinishedAt))
+	}
+	return tw.Flush()
+}
+
+// writeOperationDetail renders a single operation followed by its result
+func writeOperationDetail(w io.Writer, operation *repo.Operation) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", operation.ID)
+	fmt.Fprintf(tw, "Cluster:\t%s\n", operation.ClusterID)
+	fmt.Fprintf(tw, "Type:\t%s\n", operation.Type)
+	fmt.Fprintf(tw, "Status:\t%s\n", operation.Status)
+	fmt.Fprintf(tw, "Created:\t%s\n", formatTime(&operation.CreatedAt))
+	fmt.Fprintf(tw, "Started:\t%s\n", formatTime(operation.StartedAt))
+	fmt.Fprintf(tw, "Finished:\t%s\n", formatTime(operation.FinishedAt))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if operation.Result == nil {
+		return nil
+	}
+	fmt.Fprintln(w, "Result:")
+	return writeJSON(w, operation.Result)
+}
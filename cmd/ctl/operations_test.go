@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+func TestListOperations(t *testing.T) {
+	clusterID := uuid.New()
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	operations := []repo.Operation{
+		{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: repo.OperationStatusRunning, StartedAt: &started},
+		{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: repo.OperationStatusPending},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := "/api/v1/clusters/" + clusterID.String() + "/operations"; r.URL.Path != want {
+			t.Errorf("Expected path %s but got %s", want, r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"operations": operations, "total_count": 2})
+	}))
+	defer server.Close()
+
+	out, err := runCommand(t, "operations", "list", "--cluster", clusterID.String(), "--hub-url", server.URL, "-o", "table")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows but got:\n%s", out)
+	}
+	if fields := strings.Fields(lines[0]); len(fields) != 5 || fields[2] != "STATUS" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if !strings.Contains(lines[1], operations[0].ID.String()) || !strings.Contains(lines[1], "running") {
+		t.Errorf("Expected the running operation in %q", lines[1])
+	}
+}
+
+func TestGetOperation(t *testing.T) {
+	id := uuid.New()
+	result := repo.Payload{"applied": float64(2)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/operations/"+id.String() {
+			t.Errorf("Expected path /api/v1/operations/%s but got %s", id, r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(repo.Operation{ID: id, Type: repo.OperationTypeApply, Status: repo.OperationStatusSuccess, Result: &result})
+	}))
+	defer server.Close()
+
+	out, err := runCommand(t, "operations", "get", id.String(), "--watch=false", "--hub-url", server.URL, "-o", "table")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !strings.Contains(out, "Status:   success") {
+		t.Errorf("Expected the status in the output but got:\n%s", out)
+	}
+	if !strings.Contains(out, "Result:\n{\n  \"applied\": 2\n}") {
+		t.Errorf("Expected the pretty-printed result in the output but got:\n%s", out)
+	}
+}
+
+func TestGetOperationWatch(t *testing.T) {
+	defer func(interval time.Duration) { watchInterval = interval }(watchInterval)
+	watchInterval = time.Millisecond
+
+	// Progress is written to stderr
+	var progress bytes.Buffer
+	rootCmd.SetErr(&progress)
+	defer rootCmd.SetErr(nil)
+
+	id := uuid.New()
+	statuses := []string{repo.OperationStatusPending, repo.OperationStatusRunning, repo.OperationStatusRunning, repo.OperationStatusFailed}
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := int(polls.Add(1)) - 1
+		if n >= len(statuses) {
+			t.Errorf("Expected polling to stop at a terminal state")
+			n = len(statuses) - 1
+		}
+		json.NewEncoder(w).Encode(repo.Operation{ID: id, Status: statuses[n]})
+	}))
+	defer server.Close()
+
+	out, err := runCommand(t, "operations", "get", id.String(), "--watch", "--hub-url", server.URL, "-o", "json")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if got := polls.Load(); got != int32(len(statuses)) {
+		t.Errorf("Expected %d polls but got %d", len(statuses), got)
+	}
+	if lines := strings.Count(progress.String(), "\n"); lines != 2 {
+		t.Errorf("Expected a progress line per status change but got:\n%s", progress.String())
+	}
+
+	var operation repo.Operation
+	if err := json.Unmarshal([]byte(out), &operation); err != nil {
+		t.Fatalf("Expected JSON output but got: %v\n%s", err, out)
+	}
+	if operation.Status != repo.OperationStatusFailed {
+		t.Errorf("Expected the final status failed but got %s", operation.Status)
+	}
+}
@@ -0,0 +1,26 @@
+# Example admission policy for mckmt operations, loaded into OPA with e.g.
+#   opa run --server configs/policies/admission.rego
+#
+# The hub posts {"operation": ..., "manifests": [...]} as input, manifests
+# holding the decoded documents of an apply, and rejects the operation with
+# the messages of deny.
+package mckmt.admission
+
+import rego.v1
+
+workload_kinds := {"Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job"}
+
+pod_spec(manifest) := manifest.spec if manifest.kind == "Pod"
+
+pod_spec(manifest) := manifest.spec.template.spec if manifest.kind in workload_kinds
+
+pod_spec(manifest) := manifest.spec.jobTemplate.spec.template.spec if manifest.kind == "CronJob"
+
+deny contains msg if {
+	input.operation.type == "apply"
+	some manifest in input.manifests
+	spec := pod_spec(manifest)
+	some container in array.concat(object.get(spec, "initContainers", []), object.get(spec, "containers", []))
+	container.securityContext.privileged == true
+	msg := sprintf("%s %s runs privileged container %s", [manifest.kind, manifest.metadata.name, container.name])
+}
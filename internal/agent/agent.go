@@ -3,23 +3,35 @@ package agent
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/resolver/manual"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
 	"github.com/rizesky/mckmt/internal/config"
 	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/repo"
 )
 
+// supportedOperationTypes are the operation types processOperation handles,
+// advertised to the hub on registration
+var supportedOperationTypes = []string{"apply", "exec", "sync", "list_namespaces", "list_nodes", "list_resources", "diagnostics", "pod_logs"}
+
 // Agent represents a cluster agent
 type Agent struct {
 	config       *config.AgentConfig
@@ -31,17 +43,40 @@ type Agent struct {
 	sessionToken string
 	stopCh       chan struct{}
 	cancelOps    map[string]context.CancelFunc // operation_id -> cancel function
+	opsMu        sync.Mutex                    // guards cancelOps
+	httpClient   *http.Client                  // used to fetch manifests referenced by URL
+	ready        atomic.Bool                   // set once the readiness check passed
+	resolver     srvResolver                   // looks up the hub SRV record
+	hubResolver  *manual.Resolver              // feeds hub endpoints to gRPC; nil for a single static hub_url
+	resolveNow   chan struct{}                 // requests an early refresh of the hub endpoints
+	sessionMu    sync.Mutex                    // serializes re-registration
+
+	// creds secures the connection to the hub
+	creds credentials.TransportCredentials
+
+	// logShipper collects the logs streamed to the hub; nil when log
+	// streaming is disabled
+	logShipper *logShipper
 }
 
 // NewAgent creates a new cluster agent
 func NewAgent(cfg *config.AgentConfig, kubeClient *kube.Client, logger *zap.Logger) *Agent {
-	return &Agent{
+	agent := &Agent{
 		config:     cfg,
 		kubeClient: kubeClient,
 		logger:     logger,
 		stopCh:     make(chan struct{}),
 		cancelOps:  make(map[string]context.CancelFunc),
+		httpClient: &http.Client{Timeout: manifestFetchTimeout},
+		resolver:   net.DefaultResolver,
+		resolveNow: make(chan struct{}, 1),
+		creds: credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: true, // TODO: Configure proper TLS
+		}),
 	}
+	agent.httpClient.CheckRedirect = agent.checkManifestRedirect
+	agent.setupLogStream()
+	return agent
 }
 
 // SetClusterID sets the cluster ID for the agent
@@ -53,6 +88,12 @@ func (a *Agent) SetClusterID(clusterID string) {
 func (a *Agent) Start(ctx context.Context) error {
 	a.logger.Info("Starting cluster agent")
 
+	// Make sure the cluster is usable before announcing the agent to the hub
+	if err := a.selfTest(ctx); err != nil {
+		return err
+	}
+	a.preflightPermissions(ctx)
+
 	// Connect to hub
 	if err := a.connect(ctx); err != nil {
 		return fmt.Errorf("failed to connect to hub: %w", err)
@@ -63,19 +104,16 @@ func (a *Agent) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to register with hub: %w", err)
 	}
 
-	// Start heartbeat
-	go a.heartbeat(ctx)
-
-	// Start operation stream
-	go a.streamOperations(ctx)
-
-	a.logger.Info("Agent started successfully")
+	if a.hubResolver != nil {
+		go a.refreshHubEndpoints(ctx)
+	}
 
-	// Start log streaming
-	go a.streamLogs(ctx)
+	// Run heartbeats and the operation, log and metric streams, reconnecting
+	// whenever the connection to the hub is lost
+	go a.supervise(ctx)
 
-	// Start metrics streaming
-	go a.streamMetrics(ctx)
+	// Periodically refresh API discovery so new CRDs can be applied
+	go a.kubeClient.StartRESTMapperRefresh(ctx, a.config.RESTMapperRefresh)
 
 	a.logger.Info("Agent started successfully")
 	return nil
@@ -86,6 +124,9 @@ func (a *Agent) Stop() {
 	a.logger.Info("Stopping cluster agent")
 	close(a.stopCh)
 
+	// Tell the hub about unfinished operations while the connection is still up
+	a.reportInterrupted()
+
 	if a.conn != nil {
 		if err := a.conn.Close(); err != nil {
 			a.logger.Warn("failed to close connection", zap.Error(err))
@@ -95,14 +136,9 @@ func (a *Agent) Stop() {
 
 // connect establishes connection to the hub
 func (a *Agent) connect(ctx context.Context) error {
-	// Create TLS credentials
-	creds := credentials.NewTLS(&tls.Config{
-		InsecureSkipVerify: true, // TODO: Configure proper TLS
-	})
-
 	// Set up connection options
 	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(creds),
+		grpc.WithTransportCredentials(a.creds),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                10 * time.Second,
 			Timeout:             3 * time.Second,
@@ -110,8 +146,19 @@ func (a *Agent) connect(ctx context.Context) error {
 		}),
 	}
 
+	target, err := a.hubTarget(ctx)
+	if err != nil {
+		return err
+	}
+	if a.hubResolver != nil {
+		opts = append(opts,
+			grpc.WithResolvers(a.hubResolver),
+			grpc.WithDefaultServiceConfig(roundRobinServiceConfig),
+		)
+	}
+
 	// Connect to hub
-	conn, err := grpc.DialContext(ctx, a.config.HubURL, opts...)
+	conn, err := grpc.DialContext(ctx, target, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to dial hub: %w", err)
 	}
@@ -132,22 +179,50 @@ func (a *Agent) register(ctx context.Context) error {
 	// Generate cluster name from kubeconfig context or use default
 	clusterName := a.getClusterName()
 
+	// The hub only lets the cluster that first registered a name register it again
+	fingerprint, err := a.kubeClient.ClusterUID(ctx)
+	if err != nil {
+		a.logger.Warn("Failed to get cluster fingerprint", zap.Error(err))
+	}
+
+	// Report detected provider and distribution alongside the cluster labels
+	labels := make(map[string]string, len(clusterInfo.Labels)+2)
+	for k, v := range clusterInfo.Labels {
+		labels[k] = v
+	}
+	labels["provider"] = clusterInfo.Provider
+	labels["distribution"] = clusterInfo.Distribution
+
+	region := clusterInfo.Region
+	if region == "" {
+		region = "unknown"
+	}
+
 	// Create registration request with cluster name
 	req := &agentv1.RegisterRequest{
 		ClusterName:  clusterName,
 		AgentVersion: "1.0.0",
-		Fingerprint:  "agent-fingerprint", // TODO: Generate proper fingerprint
+		Fingerprint:  fingerprint,
 		ClusterInfo: &agentv1.ClusterInfo{
 			KubernetesVersion: clusterInfo.KubernetesVersion,
 			Platform:          clusterInfo.Platform,
 			NodeCount:         int32(clusterInfo.NodeCount),
-			Region:            "unknown", // TODO: Detect region
-			Labels:            clusterInfo.Labels,
+			Region:            region,
+			Labels:            labels,
+		},
+		Capabilities: &agentv1.AgentCapabilities{
+			OperationTypes: supportedOperationTypes,
+			Features:       []string{repo.FeatureReadiness},
 		},
 	}
 
-	// Send registration request
-	resp, err := a.client.Register(ctx, req)
+	// Send registration request, retrying while the hub reports transient failures
+	var resp *agentv1.RegisterResponse
+	err = a.withRetry(ctx, "register", func() error {
+		var err error
+		resp, err = a.client.Register(ctx, req)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("registration failed: %w", err)
 	}
@@ -162,7 +237,6 @@ func (a *Agent) register(ctx context.Context) error {
 
 	a.logger.Info("Agent registered successfully",
 		zap.String("cluster_id", a.clusterID),
-		zap.String("session_token", a.sessionToken),
 		zap.Int64("heartbeat_interval", resp.HeartbeatInterval),
 	)
 
@@ -183,7 +257,8 @@ func (a *Agent) heartbeat(ctx context.Context) {
 			zap.Duration("default_interval", heartbeatInterval))
 	}
 
-	ticker := time.NewTicker(heartbeatInterval)
+	current := heartbeatInterval
+	ticker := time.NewTicker(current)
 	defer ticker.Stop()
 
 	for {
@@ -193,37 +268,56 @@ func (a *Agent) heartbeat(ctx context.Context) {
 		case <-a.stopCh:
 			return
 		case <-ticker.C:
-			if err := a.sendHeartbeat(ctx); err != nil {
+			resp, err := a.sendHeartbeat(ctx)
+			if err != nil {
 				a.logger.Error("Failed to send heartbeat", zap.Error(err))
+				a.restoreSession(ctx, err)
+				continue
+			}
+
+			// Back off while the hub reports it is overloaded
+			if next := nextHeartbeatInterval(resp, heartbeatInterval); next != current {
+				a.logger.Info("Adjusting heartbeat interval",
+					zap.Bool("hub_busy", resp.Busy),
+					zap.Duration("heartbeat_interval", next))
+				current = next
+				ticker.Reset(current)
 			}
 		}
 	}
 }
 
 // sendHeartbeat sends a heartbeat to the hub
-func (a *Agent) sendHeartbeat(ctx context.Context) error {
+func (a *Agent) sendHeartbeat(ctx context.Context) (*agentv1.HeartbeatResponse, error) {
 	// Get cluster status
 	status, err := a.getClusterStatus(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get cluster status: %w", err)
+		return nil, fmt.Errorf("failed to get cluster status: %w", err)
 	}
 
 	req := &agentv1.HeartbeatRequest{
 		ClusterId:    a.clusterID,
 		SessionToken: a.sessionToken,
 		Status:       status,
+		AgentTime:    timestamppb.Now(),
+		Ready:        a.ready.Load(),
 	}
 
-	resp, err := a.client.Heartbeat(ctx, req)
+	var resp *agentv1.HeartbeatResponse
+	err = a.withRetry(ctx, "heartbeat", func() error {
+		var err error
+		resp, err = a.client.Heartbeat(ctx, req)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("heartbeat failed: %w", err)
+		return nil, fmt.Errorf("heartbeat failed: %w", err)
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("heartbeat failed: %s", resp.Message)
+		return nil, fmt.Errorf("heartbeat failed: %s", resp.Message)
 	}
 
-	return nil
+	return resp, nil
 }
 
 // getClusterStatus gets the current cluster status
@@ -260,50 +354,65 @@ func (a *Agent) getClusterStatus(ctx context.Context) (*agentv1.ClusterStatus, e
 	}, nil
 }
 
-// streamOperations streams operations from the hub
-func (a *Agent) streamOperations(ctx context.Context) {
+// streamOperations receives operations from the hub until the session ends or
+// the stream is lost, returning why it was lost. The stream is opened under
+// session while the operations run under ctx, so they outlive the session.
+func (a *Agent) streamOperations(ctx, session context.Context) error {
 	a.logger.Debug("Starting operation stream")
 
-	req := &agentv1.StreamOperationsRequest{
-		ClusterId:    a.clusterID,
-		SessionToken: a.sessionToken,
-	}
-
-	stream, err := a.client.StreamOperations(ctx, req)
+	stream, err := a.openOperationStream(session)
 	if err != nil {
-		a.logger.Error("Failed to start operation stream", zap.Error(err))
-		return
+		return fmt.Errorf("failed to start operation stream: %w", err)
 	}
 
 	a.logger.Debug("Operation stream started successfully")
 
 	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-a.stopCh:
-			return
-		default:
-			operation, err := stream.Recv()
-			if err != nil {
-				if err == io.EOF {
-					a.logger.Info("Operation stream closed")
-					return
-				}
-				a.logger.Error("Failed to receive operation", zap.Error(err))
-				time.Sleep(5 * time.Second)
-				continue
+		operation, err := stream.Recv()
+		if err != nil {
+			if session.Err() != nil || a.stopped() {
+				return nil
+			}
+			if errors.Is(err, io.EOF) {
+				return errors.New("operation stream closed by hub")
 			}
 
-			// Only process operation if we successfully received one
-			if operation != nil {
-				// Process operation in a goroutine
-				go a.processOperation(ctx, operation)
+			// A hub instance the agent hasn't registered with rejects the
+			// stream; register with it and open a new one
+			if !a.restoreSession(session, err) {
+				return fmt.Errorf("failed to receive operation: %w", err)
+			}
+			if stream, err = a.openOperationStream(session); err != nil {
+				return fmt.Errorf("failed to restart operation stream: %w", err)
 			}
+			continue
 		}
+
+		a.dispatchOperation(ctx, operation)
 	}
 }
 
+// openOperationStream opens the stream the hub sends operations on
+func (a *Agent) openOperationStream(ctx context.Context) (grpc.ServerStreamingClient[agentv1.Operation], error) {
+	return a.client.StreamOperations(ctx, &agentv1.StreamOperationsRequest{
+		ClusterId:    a.clusterID,
+		SessionToken: a.sessionToken,
+	})
+}
+
+// dispatchOperation starts processing a streamed operation in a goroutine, or
+// stops the running operation it refers to when the hub sent a cancellation
+func (a *Agent) dispatchOperation(ctx context.Context, operation *agentv1.Operation) {
+	if operation.Cancel {
+		if err := a.CancelOperation(operation.Id); err != nil {
+			a.logger.Warn("Failed to cancel operation", zap.Error(err))
+		}
+		return
+	}
+
+	go a.processOperation(ctx, operation)
+}
+
 // processOperation processes a single operation
 func (a *Agent) processOperation(ctx context.Context, operation *agentv1.Operation) {
 	if operation == nil {
@@ -316,15 +425,29 @@ func (a *Agent) processOperation(ctx context.Context, operation *agentv1.Operati
 		zap.String("type", operation.Type),
 	)
 
-	// Create cancellable context for this operation
-	opCtx, cancel := context.WithCancel(ctx)
+	timeout, err := a.operationTimeout(operation)
+	if err != nil {
+		a.logger.Error("Rejecting operation with invalid timeout", zap.String("operation_id", operation.Id), zap.Error(err))
+		if err := a.reportResult(ctx, operation.Id, false, err.Error(), &anypb.Any{}); err != nil {
+			a.logger.Error("Failed to report operation result", zap.Error(err))
+		}
+		return
+	}
+
+	// Create cancellable context for this operation, bounded by its timeout
+	var opCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		opCtx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		opCtx, cancel = context.WithCancel(ctx)
+	}
 	defer cancel()
 
 	// Store cancel function for potential cancellation
+	a.opsMu.Lock()
 	a.cancelOps[operation.Id] = cancel
-	defer func() {
-		delete(a.cancelOps, operation.Id)
-	}()
+	a.opsMu.Unlock()
 
 	// Set operation as started
 	// TODO: Report operation started
@@ -333,35 +456,56 @@ func (a *Agent) processOperation(ctx context.Context, operation *agentv1.Operati
 	var success bool
 	var message string
 
+	// Written by the processing goroutine only, and read once it is done, so a
+	// cancellation never races with the outcome of the abandoned work
+	var opResult *anypb.Any
+	var opSuccess bool
+	var opMessage string
+
 	// Process based on operation type with cancellation support
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
 		switch operation.Type {
 		case "apply":
-			result, success, message = a.processApplyOperation(opCtx, operation)
+			opResult, opSuccess, opMessage = a.processApplyOperation(opCtx, operation)
 		case "exec":
-			result, success, message = a.processExecOperation(opCtx, operation)
+			opResult, opSuccess, opMessage = a.processExecOperation(opCtx, operation)
 		case "sync":
-			result, success, message = a.processSyncOperation(opCtx, operation)
+			opResult, opSuccess, opMessage = a.processSyncOperation(opCtx, operation)
+		case "list_namespaces":
+			opResult, opSuccess, opMessage = a.processListNamespacesOperation(opCtx, operation)
+		case "list_nodes":
+			opResult, opSuccess, opMessage = a.processListNodesOperation(opCtx, operation)
+		case "list_resources":
+			opResult, opSuccess, opMessage = a.processListResourcesOperation(opCtx, operation)
+		case "diagnostics":
+			opResult, opSuccess, opMessage = a.processDiagnosticsOperation(opCtx, operation)
+		case "pod_logs":
+			opResult, opSuccess, opMessage = a.processPodLogsOperation(opCtx, operation)
 		default:
-			success = false
-			message = fmt.Sprintf("unknown operation type: %s", operation.Type)
+			opSuccess = false
+			opMessage = fmt.Sprintf("unknown operation type: %s", operation.Type)
 		}
 	}()
 
 	// Wait for operation completion or cancellation
 	select {
 	case <-opCtx.Done():
-		// Operation was cancelled
+		// Operation was cancelled or ran out of time
 		success = false
 		message = "Operation was cancelled"
+		if errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+			message = fmt.Sprintf("Operation timed out after %s", timeout)
+		}
 		result = &anypb.Any{} // Empty result for cancelled operations
 		a.logger.Info("Operation cancelled",
 			zap.String("operation_id", operation.Id),
+			zap.String("message", message),
 		)
 	case <-done:
 		// Operation completed normally
+		result, success, message = opResult, opSuccess, opMessage
 		a.logger.Info("Operation completed",
 			zap.String("operation_id", operation.Id),
 			zap.Bool("success", success),
@@ -369,6 +513,11 @@ func (a *Agent) processOperation(ctx context.Context, operation *agentv1.Operati
 		)
 	}
 
+	// An operation released by Stop has already been reported as interrupted
+	if !a.releaseOperation(operation.Id) {
+		return
+	}
+
 	// Report result
 	if err := a.reportResult(ctx, operation.Id, success, message, result); err != nil {
 		a.logger.Error("Failed to report operation result", zap.Error(err))
@@ -377,36 +526,161 @@ func (a *Agent) processOperation(ctx context.Context, operation *agentv1.Operati
 
 // processApplyOperation processes an apply operation
 func (a *Agent) processApplyOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
-	// TODO: Extract manifest from operation payload
-	// TODO: Apply manifest using kubeClient
-	return nil, false, "not implemented"
+	payload, err := operationPayload(operation)
+	if err != nil {
+		return failure(err)
+	}
+
+	manifests, err := a.resolveManifests(ctx, payload)
+	if err != nil {
+		a.reportOutput(ctx, operation.Id, fmt.Sprintf("failed to resolve manifests: %v", err))
+		return failure(err)
+	}
+	a.reportOutput(ctx, operation.Id, fmt.Sprintf("resolved %d bytes of manifests", len(manifests)))
+
+	client, err := a.operationClient(payload)
+	if err != nil {
+		return failure(err)
+	}
+
+	namespace, _ := payload["namespace"].(string)
+	dryRun, _ := payload[repo.PayloadDryRun].(bool)
+
+	// Pruning deletes objects, so it needs both the explicit flag and a selector
+	var pruneSelector string
+	if prune, _ := payload[repo.PayloadPrune].(bool); prune {
+		pruneSelector, _ = payload[repo.PayloadPruneSelector].(string)
+		if _, err := kube.ParsePruneSelector(pruneSelector); err != nil {
+			return failure(err)
+		}
+	}
+
+	applied, err := a.applyDocuments(ctx, client, operation.Id, manifests, namespace, dryRun, pruneSelector)
+	if err != nil {
+		return failure(err)
+	}
+
+	message := fmt.Sprintf("applied %d documents: %d created, %d updated, %d unchanged, %d failed",
+		len(applied.Resources), applied.Created, applied.Updated, applied.Unchanged, applied.Failed)
+	if pruneSelector != "" {
+		message = fmt.Sprintf("%s, %d pruned", message, len(applied.Pruned))
+	}
+	if dryRun {
+		message = "dry run " + message
+	}
+	if applied.Skipped > 0 {
+		message = fmt.Sprintf("%s, %d skipped after cancellation", message, applied.Skipped)
+	}
+
+	result, err := newResult(applied)
+	if err != nil {
+		return failure(err)
+	}
+	return result, applied.Success, message
 }
 
-// processExecOperation processes an exec operation
-func (a *Agent) processExecOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
-	// TODO: Extract command from operation payload
-	// TODO: Execute command using kubeClient
-	return nil, false, "not implemented"
+// processListNamespacesOperation lists the namespaces in the cluster
+func (a *Agent) processListNamespacesOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
+	namespaces, err := a.kubeClient.ListNamespaces(ctx)
+	if err != nil {
+		return failure(err)
+	}
+
+	result, err := newResult(map[string]interface{}{
+		"namespaces": namespaces,
+	})
+	if err != nil {
+		return failure(err)
+	}
+
+	return result, true, fmt.Sprintf("listed %d namespaces", len(namespaces))
 }
 
-// processSyncOperation processes a sync operation
-func (a *Agent) processSyncOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
-	// TODO: Sync cluster resources
-	return nil, false, "not implemented"
+// processListNodesOperation lists the nodes in the cluster
+func (a *Agent) processListNodesOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
+	nodes, err := a.kubeClient.ListNodes(ctx)
+	if err != nil {
+		return failure(err)
+	}
+
+	result, err := newResult(map[string]interface{}{
+		"nodes": nodes,
+	})
+	if err != nil {
+		return failure(err)
+	}
+
+	return result, true, fmt.Sprintf("listed %d nodes", len(nodes))
+}
+
+// processListResourcesOperation lists the resources of a kind, in a namespace
+// or, for an empty one, across all namespaces. The REST mapper decides the
+// scope of the kind; a namespace given for a cluster-scoped kind fails with
+// kube.ErrNamespaceNotAllowed.
+func (a *Agent) processListResourcesOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
+	payload, err := operationPayload(operation)
+	if err != nil {
+		return failure(err)
+	}
+
+	kind, _ := payload[repo.PayloadResourceKind].(string)
+	namespace, _ := payload[repo.PayloadResourceNamespace].(string)
+	if kind == "" {
+		return failure(errors.New("list_resources operation requires a kind"))
+	}
+
+	// The kind may be qualified as Kind.group or Kind.version.group
+	gvk, groupKind := schema.ParseKindArg(kind)
+	if gvk == nil {
+		gvk = &schema.GroupVersionKind{Group: groupKind.Group, Kind: groupKind.Kind}
+	}
+
+	list, err := a.kubeClient.ListResources(ctx, *gvk, namespace)
+	if err != nil {
+		return failure(err)
+	}
+
+	resources := make([]map[string]interface{}, 0, len(list.Items))
+	for _, item := range list.Items {
+		resources = append(resources, map[string]interface{}{
+			"kind":       item.GetKind(),
+			"name":       item.GetName(),
+			"namespace":  item.GetNamespace(),
+			"labels":     item.GetLabels(),
+			"created_at": item.GetCreationTimestamp().Time,
+		})
+	}
+
+	result, err := newResult(map[string]interface{}{
+		"resources": resources,
+	})
+	if err != nil {
+		return failure(err)
+	}
+
+	return result, true, fmt.Sprintf("listed %d resources", len(resources))
 }
 
 // reportResult reports the result of an operation
 func (a *Agent) reportResult(ctx context.Context, operationID string, success bool, message string, result *anypb.Any) error {
 	req := &agentv1.ReportResultRequest{
-		OperationId: operationID,
-		ClusterId:   a.clusterID,
-		Success:     success,
-		Message:     message,
-		Result:      result,
-		CompletedAt: timestamppb.New(time.Now()),
+		OperationId:  operationID,
+		ClusterId:    a.clusterID,
+		SessionToken: a.sessionToken,
+		Success:      success,
+		Message:      message,
+		Result:       result,
+		CompletedAt:  timestamppb.New(time.Now()),
 	}
 
 	resp, err := a.client.ReportResult(ctx, req)
+
+	// Results of operations that outlived the session, e.g. across a hub
+	// restart, are reported with the session of the new registration
+	if err != nil && a.restoreSession(ctx, err) {
+		req.SessionToken = a.sessionToken
+		resp, err = a.client.ReportResult(ctx, req)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to report result: %w", err)
 	}
@@ -418,16 +692,21 @@ func (a *Agent) reportResult(ctx context.Context, operationID string, success bo
 	return nil
 }
 
-// streamLogs streams logs to the hub
-func (a *Agent) streamLogs(ctx context.Context) {
-	// TODO: Implement log streaming
-	a.logger.Info("Log streaming not implemented yet")
-}
-
-// streamMetrics streams metrics to the hub
-func (a *Agent) streamMetrics(ctx context.Context) {
-	// TODO: Implement metrics streaming
-	a.logger.Info("Metrics streaming not implemented yet")
+// reportOutput sends a line of operation output to the hub. Output is best
+// effort: failures are logged and never fail the operation.
+func (a *Agent) reportOutput(ctx context.Context, operationID, line string) {
+	_, err := a.client.ReportOutput(ctx, &agentv1.OutputChunk{
+		OperationId:  operationID,
+		ClusterId:    a.clusterID,
+		SessionToken: a.sessionToken,
+		Data:         []byte(line + "\n"),
+	})
+	if err != nil {
+		a.logger.Warn("Failed to report operation output",
+			zap.Error(err),
+			zap.String("operation_id", operationID),
+		)
+	}
 }
 
 // CancelOperation cancels a running operation
@@ -436,7 +715,11 @@ func (a *Agent) CancelOperation(operationID string) error {
 		zap.String("operation_id", operationID),
 	)
 
-	if cancel, exists := a.cancelOps[operationID]; exists {
+	a.opsMu.Lock()
+	cancel, exists := a.cancelOps[operationID]
+	a.opsMu.Unlock()
+
+	if exists {
 		cancel()
 		a.logger.Info("Operation cancelled",
 			zap.String("operation_id", operationID),
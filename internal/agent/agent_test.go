@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// newTestAgent creates an agent backed by a fake clientset with the given objects
+func newTestAgent(objects ...runtime.Object) *Agent {
+	clientset := kubefake.NewSimpleClientset(objects...)
+	kubeClient := kube.NewClientWithInterfaces(clientset, nil, nil, zap.NewNop())
+	return NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+}
+
+func TestAgent_ProcessListNamespacesOperation(t *testing.T) {
+	agent := newTestAgent(
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+		},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-system", Labels: map[string]string{"tier": "system"}},
+			Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+		},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "old-team"},
+			Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating},
+		},
+	)
+
+	operation := &agentv1.Operation{Id: "op-1", Type: "list_namespaces"}
+	result, success, message := agent.processListNamespacesOperation(context.Background(), operation)
+	if !success {
+		t.Fatalf("Expected success but got failure: %s", message)
+	}
+
+	var st structpb.Struct
+	if err := result.UnmarshalTo(&st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	namespaces, ok := st.AsMap()["namespaces"].([]interface{})
+	if !ok {
+		t.Fatalf("Expected namespaces list in result: %+v", st.AsMap())
+	}
+	if len(namespaces) != 3 {
+		t.Fatalf("Expected 3 namespaces but got %d", len(namespaces))
+	}
+
+	byName := make(map[string]map[string]interface{})
+	for _, ns := range namespaces {
+		entry := ns.(map[string]interface{})
+		byName[entry["name"].(string)] = entry
+	}
+
+	if byName["old-team"]["status"] != string(corev1.NamespaceTerminating) {
+		t.Errorf("Expected old-team to be Terminating but got %v", byName["old-team"]["status"])
+	}
+	labels, _ := byName["kube-system"]["labels"].(map[string]interface{})
+	if labels["tier"] != "system" {
+		t.Errorf("Expected kube-system labels to be reported but got %v", byName["kube-system"]["labels"])
+	}
+}
+
+func TestAgent_ProcessListNodesOperation(t *testing.T) {
+	agent := newTestAgent(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "control-plane-1",
+				Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+			},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("3500m"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+				},
+				NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.28.4"},
+			},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "worker-1",
+				Labels: map[string]string{"kubernetes.io/role": "worker"},
+			},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2"),
+					corev1.ResourceMemory: resource.MustParse("4096Mi"),
+				},
+				NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.27.9"},
+			},
+		},
+	)
+
+	result, success, message := agent.processListNodesOperation(context.Background(), &agentv1.Operation{Id: "op-1", Type: "list_nodes"})
+	if !success {
+		t.Fatalf("Expected success but got failure: %s", message)
+	}
+
+	var st structpb.Struct
+	if err := result.UnmarshalTo(&st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	nodes, ok := st.AsMap()["nodes"].([]interface{})
+	if !ok || len(nodes) != 2 {
+		t.Fatalf("Expected 2 nodes in result: %+v", st.AsMap())
+	}
+
+	byName := make(map[string]map[string]interface{})
+	for _, n := range nodes {
+		entry := n.(map[string]interface{})
+		byName[entry["name"].(string)] = entry
+	}
+
+	tests := []struct {
+		name           string
+		ready          bool
+		role           string
+		kubeletVersion string
+		cpuMillis      float64
+		memoryBytes    float64
+	}{
+		{"control-plane-1", true, "control-plane", "v1.28.4", 3500, 8 * 1024 * 1024 * 1024},
+		{"worker-1", false, "worker", "v1.27.9", 2000, 4096 * 1024 * 1024},
+	}
+
+	for _, tt := range tests {
+		node := byName[tt.name]
+		if node == nil {
+			t.Errorf("Expected node %s in result", tt.name)
+			continue
+		}
+		if node["ready"] != tt.ready {
+			t.Errorf("Expected %s ready=%v but got %v", tt.name, tt.ready, node["ready"])
+		}
+		roles, _ := node["roles"].([]interface{})
+		if len(roles) != 1 || roles[0] != tt.role {
+			t.Errorf("Expected %s roles [%s] but got %v", tt.name, tt.role, node["roles"])
+		}
+		if node["kubelet_version"] != tt.kubeletVersion {
+			t.Errorf("Expected %s kubelet %s but got %v", tt.name, tt.kubeletVersion, node["kubelet_version"])
+		}
+		if node["allocatable_cpu_millis"] != tt.cpuMillis {
+			t.Errorf("Expected %s cpu %v but got %v", tt.name, tt.cpuMillis, node["allocatable_cpu_millis"])
+		}
+		if node["allocatable_memory_bytes"] != tt.memoryBytes {
+			t.Errorf("Expected %s memory %v but got %v", tt.name, tt.memoryBytes, node["allocatable_memory_bytes"])
+		}
+	}
+}
+
+func TestAgent_ProcessListResourcesOperation(t *testing.T) {
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	nodeGVK := schema.GroupVersionKind{Version: "v1", Kind: "Node"}
+
+	// Kinds are given without a version, so the mapper needs a preferred one
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Version: "v1"}})
+	mapper.Add(podGVK, meta.RESTScopeNamespace)
+	mapper.Add(nodeGVK, meta.RESTScopeRoot)
+
+	newObject := func(gvk schema.GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		return obj
+	}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Version: "v1", Resource: "pods"}:  "PodList",
+			{Version: "v1", Resource: "nodes"}: "NodeList",
+		},
+		newObject(podGVK, "default", "web"),
+		newObject(podGVK, "kube-system", "dns"),
+		newObject(nodeGVK, "", "worker-1"),
+	)
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), dynamicClient, mapper, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+
+	tests := []struct {
+		name      string
+		kind      string
+		namespace string
+		names     []string
+		reason    string
+	}{
+		{name: "namespaced kind in a namespace", kind: "Pod", namespace: "default", names: []string{"web"}},
+		{name: "namespaced kind across all namespaces", kind: "Pod", names: []string{"dns", "web"}},
+		{name: "cluster-scoped kind", kind: "Node", names: []string{"worker-1"}},
+		{name: "namespace for cluster-scoped kind", kind: "Node", namespace: "default", reason: kube.ReasonNamespaceNotAllowed},
+		{name: "kind required", reason: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := structpb.NewStruct(map[string]interface{}{
+				repo.PayloadResourceKind:      tt.kind,
+				repo.PayloadResourceNamespace: tt.namespace,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			operation := &agentv1.Operation{Id: "op-1", Type: "list_resources"}
+			if operation.Payload, err = anypb.New(st); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			result, success, message := agent.processListResourcesOperation(context.Background(), operation)
+
+			var out structpb.Struct
+			if err := result.UnmarshalTo(&out); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if tt.names == nil {
+				if success {
+					t.Fatalf("Expected failure but got success: %s", message)
+				}
+				opErr, _ := out.AsMap()["error"].(map[string]interface{})
+				if opErr["reason"] != tt.reason {
+					t.Errorf("Expected reason %q but got %v", tt.reason, opErr["reason"])
+				}
+				return
+			}
+
+			if !success {
+				t.Fatalf("Expected success but got failure: %s", message)
+			}
+			resources, _ := out.AsMap()["resources"].([]interface{})
+			var names []string
+			for _, r := range resources {
+				names = append(names, r.(map[string]interface{})["name"].(string))
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("Expected resources %v but got %v", tt.names, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// applyResult is the result of an apply operation. Resources lists every
+// manifest document in order with its api_version, kind, namespace and name,
+// its status (created, updated, unchanged, failed or skipped) and the error of
+// failed documents; the counts summarize them. Pruned lists the objects a
+// pruning apply deleted or failed to delete. On a dry run nothing was
+// persisted and the statuses preview what applying the manifests would do.
+type applyResult struct {
+	Resources []kube.AppliedObject `json:"resources"`
+	Created   int                  `json:"created"`
+	Updated   int                  `json:"updated"`
+	Unchanged int                  `json:"unchanged"`
+	Failed    int                  `json:"failed"`
+	Skipped   int                  `json:"skipped"`
+	Pruned    []kube.PrunedObject  `json:"pruned,omitempty"`
+	DryRun    bool                 `json:"dry_run"`
+	Success   bool                 `json:"success"`
+}
+
+// applyDocuments applies manifests and reports the outcome of each document.
+// A failing document doesn't stop the others, but once ctx is cancelled the
+// remaining documents are skipped. With dryRun nothing is persisted. A non-empty
+// pruneSelector prunes objects of earlier pruning applies that are missing from
+// manifests, once every document was applied.
+func (a *Agent) applyDocuments(ctx context.Context, client *kube.Client, operationID string, manifests []byte, namespace string, dryRun bool, pruneSelector string) (*applyResult, error) {
+	var applied *kube.ApplyResult
+	var err error
+	if pruneSelector != "" {
+		applied, err = client.ApplyManifestToSet(ctx, manifests, namespace, pruneSelector, dryRun)
+	} else {
+		applied, err = client.ApplyManifest(ctx, manifests, namespace, dryRun)
+	}
+	if applied == nil {
+		return nil, err
+	}
+
+	for _, obj := range applied.Objects {
+		switch obj.Status {
+		case kube.ApplyFailed:
+			a.reportOutput(ctx, operationID, fmt.Sprintf("document %d: %s", obj.Index, obj.Error))
+		case kube.ApplySkipped:
+		default:
+			a.reportOutput(ctx, operationID, fmt.Sprintf("%s/%s %s", obj.Kind, obj.Name, obj.Status))
+		}
+	}
+
+	result := &applyResult{
+		Resources: applied.Objects,
+		Created:   applied.Count(kube.ApplyCreated),
+		Updated:   applied.Count(kube.ApplyUpdated),
+		Unchanged: applied.Count(kube.ApplyUnchanged),
+		Failed:    applied.Count(kube.ApplyFailed),
+		Skipped:   applied.Count(kube.ApplySkipped),
+		DryRun:    applied.DryRun,
+	}
+	result.Success = err == nil
+
+	if pruneSelector != "" && result.Success {
+		pruned, pruneErr := client.Prune(ctx, applied, namespace, pruneSelector, dryRun)
+		for _, obj := range pruned {
+			if obj.Error != "" {
+				a.reportOutput(ctx, operationID, fmt.Sprintf("%s/%s prune failed: %s", obj.Kind, obj.Name, obj.Error))
+				continue
+			}
+			a.reportOutput(ctx, operationID, fmt.Sprintf("%s/%s pruned", obj.Kind, obj.Name))
+		}
+		result.Pruned = pruned
+		result.Success = pruneErr == nil
+		if pruneErr != nil {
+			a.reportOutput(ctx, operationID, fmt.Sprintf("prune failed: %v", pruneErr))
+		}
+	}
+
+	return result, nil
+}
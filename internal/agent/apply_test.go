@@ -0,0 +1,313 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+const applyTestManifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: existing
+data:
+  key: v2
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: fresh
+data:
+  key: v1
+---
+apiVersion: v1
+kind: Widget
+metadata:
+  name: unknown
+`
+
+// newApplyTestAgent returns an agent whose fake dynamic client holds the
+// "existing" ConfigMap and records the names of applied resources
+func newApplyTestAgent(t *testing.T, onApply func(name string)) *Agent {
+	t.Helper()
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapGVR: "ConfigMapList"},
+		newConfigMap("existing", "v1"),
+	)
+	// The fake tracker doesn't support server-side apply
+	dynamicClient.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		onApply(patch.GetName())
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(configMapGVK)
+		obj.SetNamespace(patch.GetNamespace())
+		obj.SetName(patch.GetName())
+		return true, obj, nil
+	})
+
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), dynamicClient, mapper, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+	agent.client = &outputClient{}
+	return agent
+}
+
+// newApplyOperation builds an apply operation for manifests
+func newApplyOperation(t *testing.T, manifests string) *agentv1.Operation {
+	t.Helper()
+	return newApplyOperationWithPayload(t, map[string]interface{}{"manifests": manifests, "namespace": "default"})
+}
+
+// newApplyOperationWithPayload builds an apply operation for payload
+func newApplyOperationWithPayload(t *testing.T, payload map[string]interface{}) *agentv1.Operation {
+	t.Helper()
+
+	st, err := structpb.NewStruct(payload)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	operation := &agentv1.Operation{Id: "op-1", Type: "apply"}
+	if operation.Payload, err = anypb.New(st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return operation
+}
+
+// applyResultFields decodes the result of an apply operation
+func applyResultFields(t *testing.T, result *anypb.Any) map[string]interface{} {
+	t.Helper()
+
+	var st structpb.Struct
+	if err := result.UnmarshalTo(&st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return st.AsMap()
+}
+
+func TestAgent_ProcessApplyOperationAppliesEveryDocument(t *testing.T) {
+	var applied []string
+	agent := newApplyTestAgent(t, func(name string) { applied = append(applied, name) })
+
+	result, success, message := agent.processApplyOperation(context.Background(), newApplyOperation(t, applyTestManifests))
+	if success {
+		t.Errorf("Expected failure with an unknown kind but got success: %s", message)
+	}
+
+	// The failing document doesn't stop the others
+	if len(applied) != 2 || applied[0] != "existing" || applied[1] != "fresh" {
+		t.Errorf("Expected [existing fresh] to be applied but got %v", applied)
+	}
+
+	fields := applyResultFields(t, result)
+	for key, expected := range map[string]float64{"created": 1, "updated": 1, "failed": 1, "skipped": 0} {
+		if fields[key] != expected {
+			t.Errorf("Expected %s to be %v but got %v", key, expected, fields[key])
+		}
+	}
+
+	resources, _ := fields["resources"].([]interface{})
+	if len(resources) != 3 {
+		t.Fatalf("Expected 3 resources but got %v", fields["resources"])
+	}
+	for i, expected := range []kube.ApplyStatus{kube.ApplyUpdated, kube.ApplyCreated, kube.ApplyFailed} {
+		resource := resources[i].(map[string]interface{})
+		if resource["status"] != string(expected) {
+			t.Errorf("Expected document %d to be %s but got %v", i, expected, resource["status"])
+		}
+	}
+	if resources[1].(map[string]interface{})["namespace"] != "default" {
+		t.Errorf("Expected the payload namespace to be used but got %v", resources[1])
+	}
+}
+
+func TestAgent_ProcessApplyOperationStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var applied []string
+	agent := newApplyTestAgent(t, func(name string) {
+		applied = append(applied, name)
+		// Cancelled while the first document is applied
+		cancel()
+	})
+
+	result, success, _ := agent.processApplyOperation(ctx, newApplyOperation(t, applyTestManifests))
+	if success {
+		t.Error("Expected a cancelled apply to fail")
+	}
+	if len(applied) != 1 {
+		t.Errorf("Expected no documents to be applied after cancellation but got %v", applied)
+	}
+
+	fields := applyResultFields(t, result)
+	if fields["skipped"] != float64(2) {
+		t.Errorf("Expected 2 skipped documents but got %v", fields["skipped"])
+	}
+}
+
+func TestAgent_ProcessApplyOperationDryRun(t *testing.T) {
+	var applied []string
+	agent := newApplyTestAgent(t, func(name string) { applied = append(applied, name) })
+
+	operation := newApplyOperationWithPayload(t, map[string]interface{}{
+		"manifests": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: fresh\n",
+		"namespace": "default",
+		"dry_run":   true,
+	})
+	result, success, message := agent.processApplyOperation(context.Background(), operation)
+	if !success {
+		t.Fatalf("Expected success but got failure: %s", message)
+	}
+	if !strings.HasPrefix(message, "dry run") {
+		t.Errorf("Expected the message to report a dry run but got %q", message)
+	}
+
+	fields := applyResultFields(t, result)
+	if fields["dry_run"] != true || fields["created"] != float64(1) {
+		t.Errorf("Expected a dry run previewing 1 creation but got %v", fields)
+	}
+}
+
+// newPruneTestAgent returns an agent whose fake dynamic client holds the
+// given ConfigMaps. Applied objects are stored as they are applied.
+func newPruneTestAgent(t *testing.T, objects ...runtime.Object) (*Agent, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapGVR: "ConfigMapList"},
+		objects...,
+	)
+	dynamicClient.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		tracker := dynamicClient.Tracker()
+		if _, err := tracker.Get(configMapGVR, patch.GetNamespace(), patch.GetName()); err == nil {
+			return true, obj, tracker.Update(configMapGVR, obj, patch.GetNamespace())
+		}
+		return true, obj, tracker.Create(configMapGVR, obj, patch.GetNamespace())
+	})
+
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), dynamicClient, mapper, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+	agent.client = &outputClient{}
+	return agent, dynamicClient
+}
+
+// labeledConfigMapManifest returns the manifest of a ConfigMap labeled app=demo
+func labeledConfigMapManifest(name string) string {
+	return "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n  labels:\n    app: demo\ndata:\n  key: v1\n"
+}
+
+func TestAgent_ProcessApplyOperationPrunesRemovedResources(t *testing.T) {
+	// Neither was created by mckmt
+	objects := make([]runtime.Object, 0, 2)
+	for _, name := range []string{"adopted", "foreign"} {
+		obj := newConfigMap(name, "v1")
+		obj.SetLabels(map[string]string{"app": "demo"})
+		objects = append(objects, obj)
+	}
+	agent, dynamicClient := newPruneTestAgent(t, objects...)
+	configMaps := dynamicClient.Resource(configMapGVR).Namespace("default")
+
+	prunePayload := func(manifests string) map[string]interface{} {
+		return map[string]interface{}{
+			"manifests":      manifests,
+			"namespace":      "default",
+			"prune":          true,
+			"prune_selector": "app=demo",
+		}
+	}
+
+	// The first pruning apply only records what it applied
+	manifests := labeledConfigMapManifest("web") + "---\n" + labeledConfigMapManifest("db") + "---\n" + labeledConfigMapManifest("adopted")
+	result, success, message := agent.processApplyOperation(context.Background(),
+		newApplyOperationWithPayload(t, prunePayload(manifests)))
+	if !success {
+		t.Fatalf("Expected success but got: %s", message)
+	}
+	if pruned, ok := applyResultFields(t, result)["pruned"]; ok {
+		t.Fatalf("Expected nothing to be pruned but got %v", pruned)
+	}
+
+	// db and adopted were removed from the manifests
+	result, success, message = agent.processApplyOperation(context.Background(),
+		newApplyOperationWithPayload(t, prunePayload(labeledConfigMapManifest("web"))))
+	if !success {
+		t.Fatalf("Expected success but got: %s", message)
+	}
+	if !strings.Contains(message, "1 pruned") {
+		t.Errorf("Expected message to report the pruned resource but got: %s", message)
+	}
+
+	pruned, _ := applyResultFields(t, result)["pruned"].([]interface{})
+	if len(pruned) != 1 || pruned[0].(map[string]interface{})["name"] != "db" {
+		t.Fatalf("Expected db to be pruned but got %v", pruned)
+	}
+
+	if _, err := configMaps.Get(context.Background(), "db", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected db to be deleted but got: %v", err)
+	}
+
+	web, err := configMaps.Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if web.GetLabels()[kube.ManagedByLabel] != kube.ManagedByValue || web.GetLabels()[kube.ApplySetLabel] == "" {
+		t.Errorf("Expected created resources to be labeled as owned by mckmt but got %v", web.GetLabels())
+	}
+
+	// Only resources created by a pruning apply are pruned; those it didn't
+	// create are left alone even when they match the selector
+	for _, name := range []string{"web", "adopted", "foreign"} {
+		if _, err := configMaps.Get(context.Background(), name, metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected %s to be kept but got: %v", name, err)
+		}
+	}
+}
+
+func TestAgent_ProcessApplyOperationPruneRequiresSelector(t *testing.T) {
+	var applied []string
+	agent := newApplyTestAgent(t, func(name string) { applied = append(applied, name) })
+
+	for _, selector := range []interface{}{nil, "", "app in (", "!"} {
+		payload := map[string]interface{}{"manifests": applyTestManifests, "namespace": "default", "prune": true}
+		if selector != nil {
+			payload["prune_selector"] = selector
+		}
+
+		_, success, message := agent.processApplyOperation(context.Background(), newApplyOperationWithPayload(t, payload))
+		if success || !strings.Contains(message, "prune selector") {
+			t.Errorf("Expected prune selector %v to be rejected but got: %s", selector, message)
+		}
+	}
+
+	if len(applied) != 0 {
+		t.Errorf("Expected nothing to be applied but got %v", applied)
+	}
+}
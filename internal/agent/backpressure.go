@@ -0,0 +1,20 @@
+package agent
+
+import (
+	"time"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+)
+
+// nextHeartbeatInterval returns the interval to heartbeat at after resp. A busy
+// hub's suggested interval is adopted when it is longer than the configured
+// one, so the hub can slow agents down but never speed them up.
+func nextHeartbeatInterval(resp *agentv1.HeartbeatResponse, configured time.Duration) time.Duration {
+	if resp == nil || !resp.Busy {
+		return configured
+	}
+	if suggested := time.Duration(resp.SuggestedInterval) * time.Second; suggested > configured {
+		return suggested
+	}
+	return configured
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// busyHubClient answers heartbeats as an overloaded hub
+type busyHubClient struct {
+	agentv1.AgentServiceClient
+}
+
+func (c *busyHubClient) Heartbeat(_ context.Context, _ *agentv1.HeartbeatRequest, _ ...grpc.CallOption) (*agentv1.HeartbeatResponse, error) {
+	return &agentv1.HeartbeatResponse{Success: true, Busy: true, SuggestedInterval: 120}, nil
+}
+
+func TestAgent_AdoptsSuggestedHeartbeatInterval(t *testing.T) {
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{HeartbeatInterval: 30 * time.Second}, kubeClient, zap.NewNop())
+	agent.client = &busyHubClient{}
+
+	resp, err := agent.sendHeartbeat(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if got := nextHeartbeatInterval(resp, 30*time.Second); got != 2*time.Minute {
+		t.Errorf("Expected the suggested interval of 2m but got %v", got)
+	}
+}
+
+func TestNextHeartbeatInterval(t *testing.T) {
+	configured := 30 * time.Second
+
+	tests := []struct {
+		name     string
+		resp     *agentv1.HeartbeatResponse
+		expected time.Duration
+	}{
+		{name: "not busy", resp: &agentv1.HeartbeatResponse{Success: true}, expected: configured},
+		{name: "busy without suggestion", resp: &agentv1.HeartbeatResponse{Busy: true}, expected: configured},
+		{name: "busy with shorter suggestion", resp: &agentv1.HeartbeatResponse{Busy: true, SuggestedInterval: 10}, expected: configured},
+		{name: "busy with longer suggestion", resp: &agentv1.HeartbeatResponse{Busy: true, SuggestedInterval: 90}, expected: 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextHeartbeatInterval(tt.resp, configured); got != tt.expected {
+				t.Errorf("Expected %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	hubgrpc "github.com/rizesky/mckmt/internal/api/grpc"
+	hubhttp "github.com/rizesky/mckmt/internal/api/http"
+	"github.com/rizesky/mckmt/internal/auth"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/metrics"
+	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/orchestrator"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+// reportingClient captures the results reported by the agent
+type reportingClient struct {
+	agentv1.AgentServiceClient
+	results chan *agentv1.ReportResultRequest
+}
+
+func (c *reportingClient) ReportResult(_ context.Context, req *agentv1.ReportResultRequest, _ ...grpc.CallOption) (*agentv1.ReportResultResponse, error) {
+	c.results <- req
+	return &agentv1.ReportResultResponse{Success: true}, nil
+}
+
+func (c *reportingClient) ReportOutput(_ context.Context, _ *agentv1.OutputChunk, _ ...grpc.CallOption) (*agentv1.ReportOutputResponse, error) {
+	return &agentv1.ReportOutputResponse{Success: true}, nil
+}
+
+func TestAgent_CancellationStopsRunningOperation(t *testing.T) {
+	// The manifest download blocks until the operation is cancelled
+	server, stopped := blockingManifestServer(t)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	client := &reportingClient{results: make(chan *agentv1.ReportResultRequest, 1)}
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{ManifestURLAllowedHosts: []string{serverURL.Host}}, kubeClient, zap.NewNop())
+	agent.client = client
+
+	payload, err := structpb.NewStruct(map[string]interface{}{
+		"manifest_url":    server.URL + "/app.yaml",
+		"manifest_sha256": "0000",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	operation := &agentv1.Operation{Id: "op-1", Type: "apply"}
+	if operation.Payload, err = anypb.New(payload); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	agent.dispatchOperation(context.Background(), operation)
+
+	// Wait for the operation to start before cancelling it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		agent.opsMu.Lock()
+		_, running := agent.cancelOps["op-1"]
+		agent.opsMu.Unlock()
+		if running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Operation did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	agent.dispatchOperation(context.Background(), &agentv1.Operation{Id: "op-1", Cancel: true})
+
+	select {
+	case result := <-client.results:
+		if result.Success || result.Message != "Operation was cancelled" {
+			t.Fatalf("Expected cancelled result, got success=%v message=%q", result.Success, result.Message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the cancelled operation to report a result")
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the in-flight manifest download to be stopped")
+	}
+}
+
+// hubMetrics is shared because metrics register with the default Prometheus registry
+var hubMetrics = metrics.NewMetrics()
+
+// blockingManifestServer serves manifests that never finish downloading,
+// closing stopped once the download is abandoned
+func blockingManifestServer(t *testing.T) (*httptest.Server, chan struct{}) {
+	t.Helper()
+	stopped := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(stopped)
+	}))
+	t.Cleanup(server.Close)
+	return server, stopped
+}
+
+func TestAgent_UserCancellationReachesAgent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	manifests, stopped := blockingManifestServer(t)
+	manifestsURL, err := url.Parse(manifests.URL)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// The operation store is shared by the hub's gRPC server, orchestrator and API
+	ctrl := gomock.NewController(t)
+	operationID := uuid.New()
+	var mu sync.Mutex
+	stored := &repo.Operation{ID: operationID, Type: repo.OperationTypeApply, Status: string(repo.OperationStatusRunning)}
+	operations := repomocks.NewMockOperationRepository(ctrl)
+	operations.EXPECT().GetByID(gomock.Any(), operationID).DoAndReturn(func(context.Context, uuid.UUID) (*repo.Operation, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		operation := *stored
+		return &operation, nil
+	}).AnyTimes()
+	operations.EXPECT().UpdateStatus(gomock.Any(), operationID, gomock.Any()).DoAndReturn(func(_ context.Context, _ uuid.UUID, status string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		stored.Status = status
+		return nil
+	}).AnyTimes()
+
+	clusters := repomocks.NewMockClusterRepository(ctrl)
+	clusters.EXPECT().GetByName(gomock.Any(), gomock.Any()).Return(nil, repo.ErrNotFound)
+	clusters.EXPECT().GetByID(gomock.Any(), gomock.Any()).Return(nil, repo.ErrNotFound)
+	clusters.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+	cache := repomocks.NewMockCache(ctrl)
+	cache.EXPECT().OperationKey(gomock.Any()).DoAndReturn(func(id string) string { return "operation:" + id }).AnyTimes()
+	cache.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(repo.ErrCacheMiss).AnyTimes()
+	cache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	cache.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	// Hub: gRPC server for agents, orchestrator and HTTP API
+	hub := hubgrpc.NewServer(clusters, operations, repomocks.NewMockOperationOutputRepository(ctrl), hubMetrics, zap.NewNop())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	agentv1.RegisterAgentServiceServer(grpcServer, hub)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	orch := orchestrator.NewOrchestrator(operations, hubMetrics, zap.NewNop(), 1)
+	orch.SetAgentCanceller(hub)
+	if err := orch.Start(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	t.Cleanup(orch.Stop)
+
+	jwtManager := auth.NewJWTManager("secret", time.Hour)
+	authService := auth.NewAuthService(nil, nil, nil, nil, jwtManager, auth.NewPasswordManager(nil), nil, nil, "viewer", zap.NewNop())
+	operationService := operation.NewService(operations, nil, nil, cache, zap.NewNop(), orch)
+	routes := hubhttp.NewRouter(nil, operationService, authService, zap.NewNop(), auth.NewAuthMiddleware(jwtManager, zap.NewNop()),
+		&config.HubConfig{}, nil, auth.NewAuthorizationService(auth.NewNoAuthStrategy(zap.NewNop()), zap.NewNop()), nil).SetupRoutes()
+
+	// Agent: registers with the hub and receives operations on its stream
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{
+		HubURL:                  listener.Addr().String(),
+		ManifestURLAllowedHosts: []string{manifestsURL.Host},
+	}, kubeClient, zap.NewNop())
+	agent.creds = insecure.NewCredentials()
+	if err := agent.connect(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	defer agent.conn.Close()
+	if err := agent.register(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	go agent.streamOperations(ctx, ctx)
+
+	mu.Lock()
+	stored.ClusterID = uuid.MustParse(agent.clusterID)
+	mu.Unlock()
+
+	payload, err := structpb.NewStruct(map[string]interface{}{
+		"manifest_url":    manifests.URL + "/app.yaml",
+		"manifest_sha256": "0000",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	running := &agentv1.Operation{Id: operationID.String(), ClusterId: agent.clusterID, Type: "apply"}
+	if running.Payload, err = anypb.New(payload); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	agent.dispatchOperation(ctx, running)
+
+	// Record when the agent stops the running operation
+	fired := make(chan struct{})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		agent.opsMu.Lock()
+		cancelOp, started := agent.cancelOps[operationID.String()]
+		if started {
+			var once sync.Once
+			agent.cancelOps[operationID.String()] = func() {
+				once.Do(func() { close(fired) })
+				cancelOp()
+			}
+		}
+		agent.opsMu.Unlock()
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Operation did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	token, err := jwtManager.GenerateToken(uuid.New().String(), "alice", "alice@example.com", []string{"operator"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/api/v1/operations/"+operationID.String()+"/cancel", strings.NewReader(`{"reason":"user request"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	routes.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	select {
+	case <-fired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the cancellation to reach the agent running the operation")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the in-flight manifest download to be stopped")
+	}
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// hubResolverScheme is the gRPC target scheme of hub endpoints listed in
+// hub_urls or discovered via DNS SRV
+const hubResolverScheme = "mckmt-hub"
+
+// defaultHubSRVRefresh is used when hub_srv_refresh is not configured
+const defaultHubSRVRefresh = time.Minute
+
+// errNoHubEndpoints is returned when the hub SRV record lists no targets
+var errNoHubEndpoints = errors.New("no hub endpoints found")
+
+// srvResolver looks up DNS SRV records; *net.Resolver implements it
+type srvResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// discoverHub resolves the hub SRV record into the addresses of the hub
+// instances, in the order the resolver returned them: by priority, randomized
+// by weight within a priority
+func (a *Agent) discoverHub(ctx context.Context) ([]string, error) {
+	_, records, err := a.resolver.LookupSRV(ctx, "", "", a.config.HubSRV)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up hub SRV record %s: %w", a.config.HubSRV, err)
+	}
+
+	addresses := make([]string, 0, len(records))
+	for _, record := range records {
+		// A target of "." means the service is explicitly unavailable
+		host := strings.TrimSuffix(record.Target, ".")
+		if host == "" {
+			continue
+		}
+		addresses = append(addresses, net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("%w: %s", errNoHubEndpoints, a.config.HubSRV)
+	}
+	return addresses, nil
+}
+
+// roundRobinServiceConfig balances calls across all reachable hub instances
+const roundRobinServiceConfig = `{"loadBalancingConfig": [{"round_robin": {}}]}`
+
+// hubTarget returns the gRPC target to dial: the static hub_url, or a manual
+// resolver fed with the hub instances listed in hub_urls or discovered via
+// hub_srv. Calls to several instances are balanced round-robin, skipping
+// instances that can't be reached.
+func (a *Agent) hubTarget(ctx context.Context) (string, error) {
+	var addresses []string
+	switch {
+	case a.config.HubSRV != "":
+		discovered, err := a.discoverHub(ctx)
+		if err != nil {
+			return "", err
+		}
+		addresses = discovered
+		a.logger.Info("Discovered hub endpoints",
+			zap.String("hub_srv", a.config.HubSRV),
+			zap.Strings("addresses", addresses))
+	case len(a.config.HubURLs) > 0:
+		addresses = a.config.HubURLs
+	default:
+		return a.config.HubURL, nil
+	}
+
+	a.hubResolver = manual.NewBuilderWithScheme(hubResolverScheme)
+	// gRPC asks for a new resolution when connections fail
+	a.hubResolver.ResolveNowCallback = func(resolver.ResolveNowOptions) {
+		select {
+		case a.resolveNow <- struct{}{}:
+		default:
+		}
+	}
+	a.hubResolver.InitialState(hubState(addresses))
+	return hubResolverScheme + ":///hub", nil
+}
+
+// refreshHubEndpoints periodically re-resolves the hub SRV record, and on
+// request by gRPC, so hub instances that come and go are picked up
+func (a *Agent) refreshHubEndpoints(ctx context.Context) {
+	interval := a.config.HubSRVRefresh
+	if interval <= 0 {
+		interval = defaultHubSRVRefresh
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stopCh:
+			return
+		case <-ticker.C:
+		case <-a.resolveNow:
+		}
+
+		addresses, err := a.discoverHub(ctx)
+		if err != nil {
+			// Keep the last known endpoints
+			a.logger.Warn("Failed to refresh hub endpoints", zap.Error(err))
+			continue
+		}
+		a.hubResolver.UpdateState(hubState(addresses))
+	}
+}
+
+// hubState is the resolver state listing the given hub addresses
+func hubState(addresses []string) resolver.State {
+	state := resolver.State{Addresses: make([]resolver.Address, 0, len(addresses))}
+	for _, address := range addresses {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: address})
+	}
+	return state
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/config"
+)
+
+// fakeSRVResolver returns fixed SRV records for a single name
+type fakeSRVResolver struct {
+	name    string
+	records []*net.SRV
+	err     error
+}
+
+func (r *fakeSRVResolver) LookupSRV(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if service != "" || proto != "" || name != r.name {
+		return "", nil, errors.New("unexpected lookup")
+	}
+	return name, r.records, r.err
+}
+
+func TestAgent_DiscoverHub(t *testing.T) {
+	const record = "_mckmt-grpc._tcp.hub.example.com"
+
+	tests := []struct {
+		name     string
+		records  []*net.SRV
+		expected []string
+		wantErr  error
+	}{
+		{
+			name: "multiple targets",
+			records: []*net.SRV{
+				{Target: "hub-0.hub.example.com.", Port: 8081, Priority: 10},
+				{Target: "hub-1.hub.example.com.", Port: 8081, Priority: 10},
+				{Target: "hub-dr.example.com.", Port: 9081, Priority: 20},
+			},
+			expected: []string{"hub-0.hub.example.com:8081", "hub-1.hub.example.com:8081", "hub-dr.example.com:9081"},
+		},
+		{
+			name:     "unavailable target",
+			records:  []*net.SRV{{Target: ".", Port: 0}},
+			wantErr:  errNoHubEndpoints,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := NewAgent(&config.AgentConfig{HubSRV: record}, nil, zap.NewNop())
+			agent.resolver = &fakeSRVResolver{name: record, records: tt.records}
+
+			addresses, err := agent.discoverHub(context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected error %v but got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(addresses, tt.expected) {
+				t.Errorf("Expected addresses %v but got %v", tt.expected, addresses)
+			}
+		})
+	}
+}
+
+func TestAgent_HubTarget(t *testing.T) {
+	t.Run("static hub_url by default", func(t *testing.T) {
+		agent := NewAgent(&config.AgentConfig{HubURL: "hub.example.com:8081"}, nil, zap.NewNop())
+		agent.resolver = &fakeSRVResolver{}
+
+		target, err := agent.hubTarget(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if target != "hub.example.com:8081" || agent.hubResolver != nil {
+			t.Errorf("Expected the static hub_url but got %q", target)
+		}
+	})
+
+	t.Run("SRV record", func(t *testing.T) {
+		const record = "_mckmt-grpc._tcp.hub.example.com"
+		agent := NewAgent(&config.AgentConfig{HubURL: "ignored:8081", HubSRV: record}, nil, zap.NewNop())
+		agent.resolver = &fakeSRVResolver{name: record, records: []*net.SRV{
+			{Target: "hub-0.hub.example.com.", Port: 8081},
+			{Target: "hub-1.hub.example.com.", Port: 8081},
+		}}
+
+		target, err := agent.hubTarget(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if target != hubResolverScheme+":///hub" || agent.hubResolver == nil {
+			t.Fatalf("Expected the discovery resolver target but got %q", target)
+		}
+	})
+
+	t.Run("failed lookup", func(t *testing.T) {
+		const record = "_mckmt-grpc._tcp.hub.example.com"
+		agent := NewAgent(&config.AgentConfig{HubSRV: record}, nil, zap.NewNop())
+		agent.resolver = &fakeSRVResolver{name: record, err: errors.New("no such host")}
+
+		if _, err := agent.hubTarget(context.Background()); err == nil {
+			t.Error("Expected an error when the SRV record can't be resolved")
+		}
+	})
+}
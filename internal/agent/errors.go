@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// operationError is the structured form of a failed operation, reported under
+// the "error" key of the operation result so the hub and its clients can tell
+// Kubernetes failures apart without parsing messages
+type operationError struct {
+	// Code is the HTTP status code returned by the Kubernetes API, or 0 when
+	// the failure did not come from the API
+	Code int32 `json:"code"`
+	// Reason is the Kubernetes status reason, e.g. "Forbidden" or "Conflict",
+	// kube.ReasonNamespaceNotAllowed for a namespace given for a cluster-scoped
+	// kind, and empty for other failures not coming from the API
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+}
+
+// newOperationError extracts the Kubernetes API status from err, if any
+func newOperationError(err error) operationError {
+	opErr := operationError{Message: err.Error()}
+
+	if errors.Is(err, kube.ErrNamespaceNotAllowed) {
+		opErr.Code = http.StatusBadRequest
+		opErr.Reason = kube.ReasonNamespaceNotAllowed
+		return opErr
+	}
+
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return opErr
+	}
+
+	opErr.Code = status.Status().Code
+	opErr.Reason = string(apierrors.ReasonForError(err))
+	opErr.Retryable = apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		opErr.Code == http.StatusInternalServerError
+
+	return opErr
+}
+
+// failure returns the result, success flag and message of an operation that
+// failed with err
+func failure(err error) (*anypb.Any, bool, string) {
+	result, encodeErr := newResult(map[string]interface{}{
+		"error": newOperationError(err),
+	})
+	if encodeErr != nil {
+		return nil, false, err.Error()
+	}
+	return result, false, err.Error()
+}
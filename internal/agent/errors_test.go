@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+func TestNewOperationError(t *testing.T) {
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	deployment := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+
+	tests := []struct {
+		name      string
+		err       error
+		code      int32
+		reason    string
+		retryable bool
+	}{
+		{
+			name:   "forbidden",
+			err:    apierrors.NewForbidden(deployments, "web", errors.New("denied")),
+			code:   403,
+			reason: "Forbidden",
+		},
+		{
+			name:   "not found",
+			err:    apierrors.NewNotFound(deployments, "web"),
+			code:   404,
+			reason: "NotFound",
+		},
+		{
+			name:      "conflict",
+			err:       apierrors.NewConflict(deployments, "web", errors.New("object was modified")),
+			code:      409,
+			reason:    "Conflict",
+			retryable: true,
+		},
+		{
+			name:   "invalid",
+			err:    apierrors.NewInvalid(deployment, "web", nil),
+			code:   422,
+			reason: "Invalid",
+		},
+		{
+			name:   "wrapped forbidden",
+			err:    fmt.Errorf("failed to apply manifest: %w", apierrors.NewForbidden(deployments, "web", errors.New("denied"))),
+			code:   403,
+			reason: "Forbidden",
+		},
+		{
+			name:   "namespace for cluster-scoped kind",
+			err:    fmt.Errorf("failed to list Node: %w", kube.ErrNamespaceNotAllowed),
+			code:   400,
+			reason: kube.ReasonNamespaceNotAllowed,
+		},
+		{
+			name:   "not an API error",
+			err:    errors.New("failed to decode manifest"),
+			code:   0,
+			reason: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opErr := newOperationError(tt.err)
+
+			if opErr.Code != tt.code {
+				t.Errorf("Expected code %d, got %d", tt.code, opErr.Code)
+			}
+			if opErr.Reason != tt.reason {
+				t.Errorf("Expected reason %q, got %q", tt.reason, opErr.Reason)
+			}
+			if opErr.Retryable != tt.retryable {
+				t.Errorf("Expected retryable %v, got %v", tt.retryable, opErr.Retryable)
+			}
+			if opErr.Message != tt.err.Error() {
+				t.Errorf("Expected message %q, got %q", tt.err.Error(), opErr.Message)
+			}
+		})
+	}
+}
+
+func TestFailureReportsStructuredError(t *testing.T) {
+	err := apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "web", errors.New("denied"))
+
+	result, success, message := failure(err)
+	if success {
+		t.Fatal("Expected failure")
+	}
+	if message != err.Error() {
+		t.Errorf("Expected message %q, got %q", err.Error(), message)
+	}
+
+	var st structpb.Struct
+	if err := result.UnmarshalTo(&st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	opErr, ok := st.AsMap()["error"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected error in result: %+v", st.AsMap())
+	}
+	if opErr["reason"] != "Forbidden" || opErr["code"] != float64(403) {
+		t.Errorf("Unexpected error: %+v", opErr)
+	}
+}
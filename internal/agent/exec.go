@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/anypb"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+)
+
+// execRequest is the command an exec operation runs
+type execRequest struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+}
+
+// parseExecRequest reads the command of an exec operation from its payload
+func parseExecRequest(payload map[string]interface{}) (*execRequest, error) {
+	request := &execRequest{Namespace: "default"}
+	if namespace, _ := payload["namespace"].(string); namespace != "" {
+		request.Namespace = namespace
+	}
+	request.Container, _ = payload["container"].(string)
+
+	request.Pod, _ = payload["pod"].(string)
+	if request.Pod == "" {
+		return nil, errors.New("exec payload requires a pod")
+	}
+
+	values, _ := payload["command"].([]interface{})
+	for _, value := range values {
+		arg, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("exec command must be a list of strings, got %v", value)
+		}
+		request.Command = append(request.Command, arg)
+	}
+	if len(request.Command) == 0 {
+		return nil, errors.New("exec payload requires a command")
+	}
+
+	return request, nil
+}
+
+// processExecOperation runs a command in a pod and reports its output and exit
+// code. Cancelling the operation terminates the exec stream.
+func (a *Agent) processExecOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
+	payload, err := operationPayload(operation)
+	if err != nil {
+		return failure(err)
+	}
+
+	request, err := parseExecRequest(payload)
+	if err != nil {
+		return failure(err)
+	}
+
+	client, err := a.operationClient(payload)
+	if err != nil {
+		return failure(err)
+	}
+
+	output, err := client.ExecCommand(ctx, request.Namespace, request.Pod, request.Container, request.Command)
+	if err != nil {
+		return failure(err)
+	}
+
+	result, err := newResult(map[string]interface{}{
+		"stdout":    string(output.Stdout),
+		"stderr":    string(output.Stderr),
+		"exit_code": output.ExitCode,
+	})
+	if err != nil {
+		return failure(err)
+	}
+
+	if output.ExitCode != 0 {
+		return result, false, fmt.Sprintf("command exited with status %d", output.ExitCode)
+	}
+	return result, true, "command executed"
+}
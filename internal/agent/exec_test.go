@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+func TestParseExecRequest(t *testing.T) {
+	request, err := parseExecRequest(map[string]interface{}{
+		"pod":       "web-1",
+		"container": "main",
+		"command":   []interface{}{"ls", "-l"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	expected := &execRequest{Namespace: "default", Pod: "web-1", Container: "main", Command: []string{"ls", "-l"}}
+	if !reflect.DeepEqual(request, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, request)
+	}
+}
+
+func TestAgent_ProcessExecOperationRejectsInvalidPayload(t *testing.T) {
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+	}{
+		{"missing pod", map[string]interface{}{"command": []interface{}{"ls"}}},
+		{"missing command", map[string]interface{}{"pod": "web-1"}},
+		{"empty command", map[string]interface{}{"pod": "web-1", "command": []interface{}{}}},
+		{"command not a list of strings", map[string]interface{}{"pod": "web-1", "command": []interface{}{"ls", 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, success, message := agent.processExecOperation(context.Background(), newTimeoutOperation(t, "exec", tt.payload))
+			if success {
+				t.Fatal("Expected failure but got success")
+			}
+			if message == "" || result == nil {
+				t.Errorf("Expected a failure message and result but got %q", message)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// Sources of the logs the agent streams to the hub
+const (
+	logSourceSelf = "self"
+	logSourcePods = "pods"
+)
+
+// defaultLogBufferSize is used when log_stream.buffer_size is not configured
+const defaultLogBufferSize = 1000
+
+// podLogStreamTailLines limits the lines already logged by a pod that are
+// streamed once the agent starts tailing it
+const podLogStreamTailLines = 100
+
+// logShipper holds the log entries waiting to be streamed to the hub. Entries
+// are dropped while the buffer is full, e.g. while the hub is unreachable, so
+// collecting logs never blocks the agent.
+type logShipper struct {
+	entries chan *agentv1.LogEntry
+	pending *agentv1.LogEntry // entry a broken stream failed to send, sent first on the next one
+}
+
+// newLogShipper creates a shipper buffering up to size entries
+func newLogShipper(size int) *logShipper {
+	if size <= 0 {
+		size = defaultLogBufferSize
+	}
+	return &logShipper{entries: make(chan *agentv1.LogEntry, size)}
+}
+
+// ship queues an entry for the hub, dropping it if the buffer is full
+func (s *logShipper) ship(entry *agentv1.LogEntry) {
+	select {
+	case s.entries <- entry:
+	default:
+	}
+}
+
+// logShipperCore is a zap core handing the agent's own log entries to a shipper
+type logShipperCore struct {
+	zapcore.LevelEnabler
+	shipper *logShipper
+	fields  []zapcore.Field
+}
+
+func (c *logShipperCore) With(fields []zapcore.Field) zapcore.Core {
+	return &logShipperCore{
+		LevelEnabler: c.LevelEnabler,
+		shipper:      c.shipper,
+		fields:       append(append([]zapcore.Field{}, c.fields...), fields...),
+	}
+}
+
+func (c *logShipperCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *logShipperCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(encoder)
+	}
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+
+	values := make(map[string]string, len(encoder.Fields))
+	for key, value := range encoder.Fields {
+		values[key] = fmt.Sprint(value)
+	}
+
+	c.shipper.ship(&agentv1.LogEntry{
+		Level:     entry.Level.String(),
+		Message:   entry.Message,
+		Source:    "agent",
+		Timestamp: timestamppb.New(entry.Time),
+		Fields:    values,
+	})
+	return nil
+}
+
+func (c *logShipperCore) Sync() error { return nil }
+
+// setupLogStream prepares collecting the logs of the configured source. The
+// agent's own logs are collected by teeing its logger from level on.
+func (a *Agent) setupLogStream() {
+	cfg := a.config.LogStream
+	if cfg.Source == "" {
+		return
+	}
+	a.logShipper = newLogShipper(cfg.BufferSize)
+	if cfg.Source != logSourceSelf {
+		return
+	}
+
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		a.logger.Warn("Invalid log stream level, shipping info logs", zap.String("level", cfg.Level))
+		level = zapcore.InfoLevel
+	}
+	core := &logShipperCore{LevelEnabler: level, shipper: a.logShipper}
+	a.logger = a.logger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, core)
+	}))
+}
+
+// streamLogs streams the logs of the configured source to the hub until the
+// agent stops, reopening the stream whenever it breaks
+func (a *Agent) streamLogs(ctx context.Context) {
+	switch source := a.config.LogStream.Source; source {
+	case "":
+		a.logger.Info("Log streaming disabled")
+		return
+	case logSourceSelf:
+	case logSourcePods:
+		if a.config.LogStream.Selector == "" {
+			a.logger.Error("Log streaming of pods needs a selector")
+			return
+		}
+	default:
+		a.logger.Error("Unknown log stream source", zap.String("source", source))
+		return
+	}
+
+	a.keepStreaming(ctx, "logs", a.sendLogs)
+}
+
+// sendLogs sends the shipped entries over a new log stream until the agent
+// stops, returning nil, or the stream breaks
+func (a *Agent) sendLogs(ctx context.Context) error {
+	stream, err := a.client.StreamLogs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		entry := a.logShipper.pending
+		if entry == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-a.stopCh:
+				_, _ = stream.CloseAndRecv()
+				return nil
+			case entry = <-a.logShipper.entries:
+			}
+		}
+
+		if err := stream.Send(entry); err != nil {
+			a.logShipper.pending = entry
+			return sendFailed("log", err, stream.CloseAndRecv)
+		}
+		a.logShipper.pending = nil
+	}
+}
+
+// tailsPods reports whether the log stream ships the logs of pods
+func (a *Agent) tailsPods() bool {
+	return a.config.LogStream.Source == logSourcePods && a.config.LogStream.Selector != ""
+}
+
+// tailPodLogs ships the logs of the pods matching the configured selector
+// until the agent stops, picking up pods that start matching it. The pods are
+// tailed for the agent's lifetime rather than per session, so reconnecting
+// doesn't ship their recent lines again.
+func (a *Agent) tailPodLogs(ctx context.Context) {
+	ctx, cancel := a.untilStopped(ctx)
+	defer cancel()
+
+	cfg := a.config.LogStream
+	spec := &repo.PodLogsRequest{
+		Selector:  cfg.Selector,
+		Namespace: cfg.Namespace,
+		Container: cfg.Container,
+		TailLines: podLogStreamTailLines,
+		Follow:    true,
+	}
+	tailer := newPodLogTailer(a.kubeClient, spec, func(_ context.Context, pod kube.PodInfo, source, line string) {
+		a.logShipper.ship(&agentv1.LogEntry{
+			Level:     zapcore.InfoLevel.String(),
+			Message:   line,
+			Source:    "pod/" + source,
+			Timestamp: timestamppb.New(time.Now()),
+			Fields:    map[string]string{"namespace": pod.Namespace, "pod": pod.Name},
+		})
+	})
+
+	if err := tailer.refresh(ctx); err != nil && ctx.Err() == nil {
+		a.logger.Warn("Failed to list pods for log streaming", zap.Error(err))
+	}
+	tailer.follow(ctx, a.logger)
+	tailer.wg.Wait()
+}
@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// logStreamClient opens log streams that hand sent entries to a channel.
+// Streams listed in broken fail on their first send, as if the hub went away.
+type logStreamClient struct {
+	agentv1.AgentServiceClient
+	entries chan *agentv1.LogEntry
+	opened  chan metadata.MD
+	broken  int
+}
+
+func newLogStreamClient(broken int) *logStreamClient {
+	return &logStreamClient{
+		entries: make(chan *agentv1.LogEntry, 10),
+		opened:  make(chan metadata.MD, 10),
+		broken:  broken,
+	}
+}
+
+func (c *logStreamClient) StreamLogs(ctx context.Context, _ ...grpc.CallOption) (grpc.ClientStreamingClient[agentv1.LogEntry, agentv1.LogStreamResponse], error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	c.opened <- md
+
+	broken := c.broken > 0
+	c.broken--
+	return &testLogStream{client: c, broken: broken}, nil
+}
+
+// testLogStream is a log stream of a logStreamClient
+type testLogStream struct {
+	grpc.ClientStreamingClient[agentv1.LogEntry, agentv1.LogStreamResponse]
+	client *logStreamClient
+	broken bool
+}
+
+func (s *testLogStream) Send(entry *agentv1.LogEntry) error {
+	if s.broken {
+		return io.EOF
+	}
+	s.client.entries <- entry
+	return nil
+}
+
+func (s *testLogStream) CloseAndRecv() (*agentv1.LogStreamResponse, error) {
+	if s.broken {
+		return nil, status.Error(codes.Unavailable, "hub restarting")
+	}
+	return &agentv1.LogStreamResponse{Success: true}, nil
+}
+
+// newLogStreamAgent returns a registered agent streaming logs as configured
+func newLogStreamAgent(logStream config.AgentLogStreamConfig, client agentv1.AgentServiceClient, clientset *kubefake.Clientset) *Agent {
+	kubeClient := kube.NewClientWithInterfaces(clientset, nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{ReconnectWait: time.Millisecond, LogStream: logStream}, kubeClient, zap.NewNop())
+	agent.client = client
+	agent.clusterID = "cluster-1"
+	agent.sessionToken = "session-1"
+	return agent
+}
+
+// receiveLogEntry waits for the next entry sent to the hub
+func receiveLogEntry(t *testing.T, client *logStreamClient) *agentv1.LogEntry {
+	t.Helper()
+	select {
+	case entry := <-client.entries:
+		return entry
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a log entry to be streamed")
+		return nil
+	}
+}
+
+func TestAgent_StreamLogsShipsOwnLogs(t *testing.T) {
+	client := newLogStreamClient(0)
+	agent := newLogStreamAgent(config.AgentLogStreamConfig{Source: logSourceSelf, Level: "info"}, client, kubefake.NewSimpleClientset())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agent.streamLogs(context.Background())
+	}()
+
+	// The stream carries the agent's session
+	md := <-client.opened
+	if got := md.Get(clusterIDMetadataKey); len(got) != 1 || got[0] != "cluster-1" {
+		t.Errorf("Expected the cluster ID in the stream metadata but got %v", md)
+	}
+	if got := md.Get(sessionTokenMetadataKey); len(got) != 1 || got[0] != "session-1" {
+		t.Errorf("Expected the session token in the stream metadata but got %v", md)
+	}
+
+	agent.logger.Debug("Below the configured level")
+	agent.logger.Warn("Disk pressure", zap.String("node", "node-1"))
+
+	entry := receiveLogEntry(t, client)
+	if entry.Level != "warn" || entry.Message != "Disk pressure" || entry.Source != "agent" || entry.Timestamp == nil {
+		t.Errorf("Expected the warning to be streamed but got %v", entry)
+	}
+	if entry.Fields["node"] != "node-1" {
+		t.Errorf("Expected the log fields to be streamed but got %v", entry.Fields)
+	}
+
+	agent.Stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected log streaming to stop with the agent")
+	}
+}
+
+func TestAgent_StreamLogsReopensBrokenStream(t *testing.T) {
+	client := newLogStreamClient(1)
+	agent := newLogStreamAgent(config.AgentLogStreamConfig{Source: logSourceSelf, Level: "error"}, client, kubefake.NewSimpleClientset())
+
+	go agent.streamLogs(context.Background())
+	defer agent.Stop()
+
+	agent.logger.Error("Failed to apply manifest")
+
+	// The entry the broken stream failed to send goes out on the next one
+	entry := receiveLogEntry(t, client)
+	if entry.Message != "Failed to apply manifest" {
+		t.Errorf("Expected the entry to be resent but got %v", entry)
+	}
+	if opened := len(client.opened); opened != 2 {
+		t.Errorf("Expected the stream to be opened twice but got %d", opened)
+	}
+}
+
+func TestAgent_StreamLogsTailsPods(t *testing.T) {
+	client := newLogStreamClient(0)
+	clientset := kubefake.NewSimpleClientset(newPod("web-1", "web"), newPod("db-1", "db"))
+	agent := newLogStreamAgent(config.AgentLogStreamConfig{Source: logSourcePods, Namespace: "default", Selector: "app=web"}, client, clientset)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tailed := make(chan struct{})
+	go func() {
+		defer close(tailed)
+		agent.tailPodLogs(ctx)
+	}()
+	go agent.streamLogs(ctx)
+	defer agent.Stop()
+
+	// The fake clientset serves "fake logs" for every pod
+	entry := receiveLogEntry(t, client)
+	if entry.Message != "fake logs" || entry.Source != "pod/web-1" || entry.Fields["namespace"] != "default" {
+		t.Errorf("Expected the logs of web-1 to be streamed but got %v", entry)
+	}
+
+	cancel()
+	select {
+	case <-tailed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected tailing to stop with its context")
+	}
+}
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// podLogsRefreshInterval is how often a followed pod_logs operation looks for
+// pods that started matching its selector
+var podLogsRefreshInterval = 5 * time.Second
+
+// maxLogLineSize bounds a single log line; longer lines are split
+const maxLogLineSize = 64 * 1024
+
+// processPodLogsOperation tails the logs of every pod matching a label
+// selector, reporting each line as operation output prefixed with the pod
+// name. Followed operations keep picking up matching pods until cancelled;
+// otherwise the operation finishes once the current logs have been read.
+func (a *Agent) processPodLogsOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
+	payload, err := operationPayload(operation)
+	if err != nil {
+		return failure(err)
+	}
+
+	spec, err := repo.ParsePodLogsRequest(payload)
+	if err != nil {
+		return failure(err)
+	}
+
+	client, err := a.operationClient(payload)
+	if err != nil {
+		return failure(err)
+	}
+
+	tailer := newPodLogTailer(client, spec, func(ctx context.Context, _ kube.PodInfo, source, line string) {
+		a.reportOutput(ctx, operation.Id, "["+source+"] "+line)
+	})
+
+	if err := tailer.refresh(ctx); err != nil {
+		return failure(err)
+	}
+
+	if spec.Follow {
+		tailer.follow(ctx, a.logger.With(zap.String("operation_id", operation.Id)))
+	}
+
+	tailer.wg.Wait()
+
+	pods := tailer.podNames()
+	result, err := newResult(map[string]interface{}{
+		"selector": spec.Selector,
+		"pods":     pods,
+	})
+	if err != nil {
+		return failure(err)
+	}
+
+	if len(pods) == 0 {
+		return result, true, fmt.Sprintf("no pods match selector %q", spec.Selector)
+	}
+	return result, true, fmt.Sprintf("streamed logs of %d pods", len(pods))
+}
+
+// podLogTailer multiplexes the logs of the pods matching a selector, handing
+// each line to report along with the pod and its source: the pod name,
+// followed by the container for pods tailed per container
+type podLogTailer struct {
+	client *kube.Client
+	spec   *repo.PodLogsRequest
+	report func(ctx context.Context, pod kube.PodInfo, source, line string)
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex      // guards seen and pods
+	seen map[string]bool // pod UIDs already tailed
+	pods []string
+}
+
+// newPodLogTailer creates a tailer of the pods matching spec
+func newPodLogTailer(client *kube.Client, spec *repo.PodLogsRequest, report func(ctx context.Context, pod kube.PodInfo, source, line string)) *podLogTailer {
+	return &podLogTailer{
+		client: client,
+		spec:   spec,
+		report: report,
+		seen:   make(map[string]bool),
+	}
+}
+
+// follow picks up newly matching pods every podLogsRefreshInterval until ctx
+// is cancelled
+func (t *podLogTailer) follow(ctx context.Context, logger *zap.Logger) {
+	ticker := time.NewTicker(podLogsRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.refresh(ctx); err != nil && ctx.Err() == nil {
+				logger.Warn("Failed to refresh pods for log streaming", zap.Error(err))
+			}
+		}
+	}
+}
+
+// refresh starts tailing the matching pods that are not tailed yet. Pods are
+// tracked by UID, so a pod recreated under the same name is tailed again while
+// a pod whose stream ended is not.
+func (t *podLogTailer) refresh(ctx context.Context) error {
+	pods, err := t.client.ListPods(ctx, t.spec.Namespace, t.spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, pod := range pods {
+		if t.seen[pod.UID] {
+			continue
+		}
+		t.seen[pod.UID] = true
+		t.pods = append(t.pods, pod.Name)
+
+		// Without a container, multi-container pods are tailed per container
+		containers := []string{t.spec.Container}
+		if t.spec.Container == "" && len(pod.Containers) > 1 {
+			containers = pod.Containers
+		}
+
+		for _, container := range containers {
+			source := pod.Name
+			if container != t.spec.Container {
+				source = pod.Name + "/" + container
+			}
+
+			t.wg.Add(1)
+			go func(pod kube.PodInfo, container, source string) {
+				defer t.wg.Done()
+				t.tail(ctx, pod, container, source)
+			}(pod, container, source)
+		}
+	}
+
+	return nil
+}
+
+// tail reports the log lines of one pod container until its stream ends,
+// e.g. because the pod went away, or ctx is cancelled
+func (t *podLogTailer) tail(ctx context.Context, pod kube.PodInfo, container, source string) {
+	stream, err := t.client.StreamPodLogs(ctx, pod.Namespace, pod.Name, kube.LogOptions{
+		Container:    container,
+		TailLines:    t.spec.TailLines,
+		SinceSeconds: t.spec.SinceSeconds,
+		Follow:       t.spec.Follow,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			t.report(ctx, pod, source, err.Error())
+		}
+		return
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLogLineSize)
+	for scanner.Scan() {
+		t.report(ctx, pod, source, scanner.Text())
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		t.report(ctx, pod, source, "log stream failed: "+err.Error())
+		return
+	}
+	if t.spec.Follow {
+		t.report(ctx, pod, source, "log stream ended")
+	}
+}
+
+// podNames returns the names of the tailed pods in order
+func (t *podLogTailer) podNames() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := append([]string{}, t.pods...)
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// outputClient captures the output lines reported by the agent
+type outputClient struct {
+	agentv1.AgentServiceClient
+	mu    sync.Mutex
+	lines []string
+}
+
+func (c *outputClient) ReportOutput(_ context.Context, chunk *agentv1.OutputChunk, _ ...grpc.CallOption) (*agentv1.ReportOutputResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, strings.TrimSuffix(string(chunk.Data), "\n"))
+	return &agentv1.ReportOutputResponse{Success: true}, nil
+}
+
+func (c *outputClient) hasLine(line string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, l := range c.lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+// newPod builds a single-container pod with the given app label
+func newPod(name, app string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(name + "-uid"),
+			Labels:    map[string]string{"app": app},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main"}}},
+	}
+}
+
+// newPodLogsOperation builds a pod_logs operation for payload
+func newPodLogsOperation(t *testing.T, payload map[string]interface{}) *agentv1.Operation {
+	t.Helper()
+
+	st, err := structpb.NewStruct(payload)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	operation := &agentv1.Operation{Id: "op-1", Type: "pod_logs"}
+	if operation.Payload, err = anypb.New(st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return operation
+}
+
+func TestAgent_ProcessPodLogsOperationMultiplexesMatchingPods(t *testing.T) {
+	clientset := kubefake.NewSimpleClientset(newPod("web-1", "web"), newPod("web-2", "web"), newPod("db-1", "db"))
+	kubeClient := kube.NewClientWithInterfaces(clientset, nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+	client := &outputClient{}
+	agent.client = client
+
+	operation := newPodLogsOperation(t, map[string]interface{}{"selector": "app=web"})
+
+	result, success, message := agent.processPodLogsOperation(context.Background(), operation)
+	if !success {
+		t.Fatalf("Expected success but got failure: %s", message)
+	}
+
+	// The fake clientset serves "fake logs" for every pod
+	for _, line := range []string{"[web-1] fake logs", "[web-2] fake logs"} {
+		if !client.hasLine(line) {
+			t.Errorf("Expected output line %q but got %v", line, client.lines)
+		}
+	}
+	if client.hasLine("[db-1] fake logs") {
+		t.Errorf("Expected no output of pods not matching the selector but got %v", client.lines)
+	}
+
+	var st structpb.Struct
+	if err := result.UnmarshalTo(&st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	pods, _ := st.AsMap()["pods"].([]interface{})
+	if len(pods) != 2 || pods[0] != "web-1" || pods[1] != "web-2" {
+		t.Errorf("Expected pods [web-1 web-2] but got %v", st.AsMap()["pods"])
+	}
+}
+
+func TestAgent_ProcessPodLogsOperationFollowsNewPods(t *testing.T) {
+	interval := podLogsRefreshInterval
+	podLogsRefreshInterval = 10 * time.Millisecond
+	defer func() { podLogsRefreshInterval = interval }()
+
+	clientset := kubefake.NewSimpleClientset(newPod("web-1", "web"))
+	kubeClient := kube.NewClientWithInterfaces(clientset, nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+	client := &outputClient{}
+	agent.client = client
+
+	operation := newPodLogsOperation(t, map[string]interface{}{"selector": "app=web", "follow": true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agent.processPodLogsOperation(ctx, operation)
+	}()
+
+	waitForLine := func(line string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !client.hasLine(line) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected output line %q but got %v", line, client.lines)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitForLine("[web-1] fake logs")
+
+	// A pod appearing during the stream is picked up
+	if _, err := clientset.CoreV1().Pods("default").Create(context.Background(), newPod("web-2", "web"), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	waitForLine("[web-2] fake logs")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the operation to stop once cancelled")
+	}
+}
+
+func TestAgent_ProcessPodLogsOperationRequiresSelector(t *testing.T) {
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+
+	_, success, _ := agent.processPodLogsOperation(context.Background(), newPodLogsOperation(t, map[string]interface{}{}))
+	if success {
+		t.Error("Expected failure without a selector")
+	}
+}
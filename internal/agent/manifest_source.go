@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxManifestSize bounds manifests fetched by URL
+	maxManifestSize = 10 << 20
+
+	// manifestFetchTimeout bounds a single manifest download
+	manifestFetchTimeout = 30 * time.Second
+
+	// maxManifestRedirects bounds the redirects followed by a manifest download
+	maxManifestRedirects = 5
+)
+
+// Payload keys of apply operations. Exactly one manifest source is expected:
+// inline manifests, a URL with its SHA-256 checksum, or a ConfigMap reference
+// of the form {"namespace": ..., "name": ..., "key": ...}.
+const (
+	payloadManifests      = "manifests"
+	payloadManifestURL    = "manifest_url"
+	payloadManifestSHA256 = "manifest_sha256"
+	payloadManifestConfig = "manifest_configmap"
+)
+
+// errManifestURLNotAllowed is returned when a manifest URL's host is not on the allowlist
+var errManifestURLNotAllowed = errors.New("manifest URL host is not allowed")
+
+// resolveManifests returns the manifests an apply operation refers to
+func (a *Agent) resolveManifests(ctx context.Context, payload map[string]interface{}) ([]byte, error) {
+	if manifests, ok := payload[payloadManifests].(string); ok && manifests != "" {
+		return []byte(manifests), nil
+	}
+
+	if rawURL, ok := payload[payloadManifestURL].(string); ok && rawURL != "" {
+		checksum, _ := payload[payloadManifestSHA256].(string)
+		return a.fetchManifestURL(ctx, rawURL, checksum)
+	}
+
+	if ref, ok := payload[payloadManifestConfig].(map[string]interface{}); ok {
+		return a.manifestFromConfigMap(ctx, ref)
+	}
+
+	return nil, fmt.Errorf("apply payload has no manifests")
+}
+
+// fetchManifestURL downloads a manifest from an allowlisted host and verifies
+// it against the expected SHA-256 checksum
+func (a *Agent) fetchManifestURL(ctx context.Context, rawURL, checksum string) ([]byte, error) {
+	if checksum == "" {
+		return nil, fmt.Errorf("%s is required when fetching manifests by URL", payloadManifestSHA256)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid manifest URL: %s", rawURL)
+	}
+	if !a.manifestHostAllowed(u) {
+		return nil, fmt.Errorf("%w: %s", errManifestURLNotAllowed, u.Host)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, manifestFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create manifest request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch manifest: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("manifest exceeds %d bytes", maxManifestSize)
+	}
+
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), strings.TrimPrefix(checksum, "sha256:")) {
+		return nil, fmt.Errorf("manifest checksum mismatch")
+	}
+
+	return data, nil
+}
+
+// checkManifestRedirect only follows redirects of a manifest download to
+// allowlisted hosts, so an allowed host can't send the agent anywhere else
+func (a *Agent) checkManifestRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxManifestRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxManifestRedirects)
+	}
+	if (req.URL.Scheme != "https" && req.URL.Scheme != "http") || !a.manifestHostAllowed(req.URL) {
+		return fmt.Errorf("%w: redirected to %s", errManifestURLNotAllowed, req.URL.Host)
+	}
+	return nil
+}
+
+// manifestHostAllowed reports whether u's host, with or without port, is allowlisted
+func (a *Agent) manifestHostAllowed(u *url.URL) bool {
+	for _, host := range a.config.ManifestURLAllowedHosts {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestFromConfigMap reads a manifest stored in an in-cluster ConfigMap
+func (a *Agent) manifestFromConfigMap(ctx context.Context, ref map[string]interface{}) ([]byte, error) {
+	namespace, _ := ref["namespace"].(string)
+	name, _ := ref["name"].(string)
+	key, _ := ref["key"].(string)
+	if namespace == "" || name == "" || key == "" {
+		return nil, fmt.Errorf("%s requires namespace, name and key", payloadManifestConfig)
+	}
+
+	manifest, err := a.kubeClient.GetConfigMapData(ctx, namespace, name, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(manifest), nil
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: fast
+`
+
+func TestAgent_ResolveManifestsFromURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manifests/app.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testManifest))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	sum := sha256.Sum256([]byte(testManifest))
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name         string
+		allowedHosts []string
+		path         string
+		checksum     string
+		expectErr    bool
+		notAllowed   bool
+	}{
+		{name: "allowed host with matching checksum", allowedHosts: []string{serverURL.Hostname()}, path: "/manifests/app.yaml", checksum: checksum},
+		{name: "prefixed checksum", allowedHosts: []string{serverURL.Host}, path: "/manifests/app.yaml", checksum: "sha256:" + checksum},
+		{name: "checksum mismatch", allowedHosts: []string{serverURL.Host}, path: "/manifests/app.yaml", checksum: hex.EncodeToString(make([]byte, 32)), expectErr: true},
+		{name: "missing checksum", allowedHosts: []string{serverURL.Host}, path: "/manifests/app.yaml", expectErr: true},
+		{name: "host not allowlisted", allowedHosts: []string{"manifests.example.com"}, path: "/manifests/app.yaml", checksum: checksum, expectErr: true, notAllowed: true},
+		{name: "empty allowlist", path: "/manifests/app.yaml", checksum: checksum, expectErr: true, notAllowed: true},
+		{name: "not found", allowedHosts: []string{serverURL.Host}, path: "/missing.yaml", checksum: checksum, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := newTestAgent()
+			agent.config.ManifestURLAllowedHosts = tt.allowedHosts
+
+			manifests, err := agent.resolveManifests(context.Background(), map[string]interface{}{
+				"manifest_url":    server.URL + tt.path,
+				"manifest_sha256": tt.checksum,
+			})
+
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				if tt.notAllowed && !errors.Is(err, errManifestURLNotAllowed) {
+					t.Errorf("Expected a host allowlist error but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if string(manifests) != testManifest {
+				t.Errorf("Expected fetched manifest to match, got %q", manifests)
+			}
+		})
+	}
+}
+
+func TestAgent_ResolveManifestsFromURLRedirects(t *testing.T) {
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testManifest))
+	}))
+	defer elsewhere.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manifests/app.yaml":
+			w.Write([]byte(testManifest))
+		case "/moved.yaml":
+			http.Redirect(w, r, "/manifests/app.yaml", http.StatusFound)
+		case "/elsewhere.yaml":
+			http.Redirect(w, r, elsewhere.URL+"/manifests/app.yaml", http.StatusFound)
+		case "/loop.yaml":
+			http.Redirect(w, r, "/loop.yaml", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	sum := sha256.Sum256([]byte(testManifest))
+	checksum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name       string
+		path       string
+		expectErr  bool
+		notAllowed bool
+	}{
+		{name: "redirect within an allowed host", path: "/moved.yaml"},
+		{name: "redirect to a host not allowlisted", path: "/elsewhere.yaml", expectErr: true, notAllowed: true},
+		{name: "redirect loop", path: "/loop.yaml", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := newTestAgent()
+			agent.config.ManifestURLAllowedHosts = []string{serverURL.Host}
+
+			manifests, err := agent.resolveManifests(context.Background(), map[string]interface{}{
+				"manifest_url":    server.URL + tt.path,
+				"manifest_sha256": checksum,
+			})
+
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("Expected an error but got none")
+				}
+				if tt.notAllowed && !errors.Is(err, errManifestURLNotAllowed) {
+					t.Errorf("Expected a host allowlist error but got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if string(manifests) != testManifest {
+				t.Errorf("Expected fetched manifest to match, got %q", manifests)
+			}
+		})
+	}
+}
+
+func TestAgent_ResolveManifestsFromConfigMap(t *testing.T) {
+	agent := newTestAgent(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-manifests", Namespace: "mckmt"},
+		Data:       map[string]string{"app.yaml": testManifest},
+	})
+
+	manifests, err := agent.resolveManifests(context.Background(), map[string]interface{}{
+		"manifest_configmap": map[string]interface{}{"namespace": "mckmt", "name": "app-manifests", "key": "app.yaml"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if string(manifests) != testManifest {
+		t.Errorf("Expected ConfigMap manifest to match, got %q", manifests)
+	}
+
+	_, err = agent.resolveManifests(context.Background(), map[string]interface{}{
+		"manifest_configmap": map[string]interface{}{"namespace": "mckmt", "name": "app-manifests", "key": "missing.yaml"},
+	})
+	if err == nil {
+		t.Errorf("Expected an error for a missing ConfigMap key")
+	}
+}
+
+func TestAgent_ResolveManifestsInline(t *testing.T) {
+	agent := newTestAgent()
+
+	manifests, err := agent.resolveManifests(context.Background(), map[string]interface{}{"manifests": testManifest})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if string(manifests) != testManifest {
+		t.Errorf("Expected inline manifest to be returned, got %q", manifests)
+	}
+
+	if _, err := agent.resolveManifests(context.Background(), map[string]interface{}{}); err == nil {
+		t.Errorf("Expected an error when no manifest source is given")
+	}
+}
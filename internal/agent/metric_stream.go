@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+)
+
+// Names of the cluster metrics the agent streams to the hub. They carry the
+// "cluster_" prefix the hub's metric allowlist admits by default.
+const (
+	metricNodes         = "cluster_nodes"
+	metricNodesReady    = "cluster_nodes_ready"
+	metricPods          = "cluster_pods"
+	metricPodsRunning   = "cluster_pods_running"
+	metricPodsPending   = "cluster_pods_pending"
+	metricPodsFailed    = "cluster_pods_failed"
+	metricPodsSucceeded = "cluster_pods_succeeded"
+)
+
+// clusterMetric is a metric value collected from the cluster
+type clusterMetric struct {
+	name  string
+	value float64
+}
+
+// streamMetrics streams cluster metrics to the hub every metrics_interval
+// until the agent stops, reopening the stream whenever it breaks
+func (a *Agent) streamMetrics(ctx context.Context) {
+	if a.config.MetricsInterval <= 0 {
+		a.logger.Info("Metrics streaming disabled")
+		return
+	}
+
+	a.keepStreaming(ctx, "metrics", a.sendMetrics)
+}
+
+// sendMetrics sends the cluster metrics over a new metric stream, right away
+// and then every metrics_interval, until the agent stops, returning nil, or
+// the stream breaks
+func (a *Agent) sendMetrics(ctx context.Context) error {
+	stream, err := a.client.StreamMetrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(a.config.MetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		metrics, err := a.collectMetrics(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			a.logger.Warn("Failed to collect cluster metrics", zap.Error(err))
+		}
+
+		now := timestamppb.Now()
+		for _, metric := range metrics {
+			entry := &agentv1.MetricEntry{Name: metric.name, Value: metric.value, Timestamp: now}
+			if err := stream.Send(entry); err != nil {
+				return sendFailed("metric", err, stream.CloseAndRecv)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-a.stopCh:
+			_, _ = stream.CloseAndRecv()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectMetrics counts the cluster's nodes, its ready nodes and its pods by
+// phase
+func (a *Agent) collectMetrics(ctx context.Context) ([]clusterMetric, error) {
+	nodes, err := a.kubeClient.ListNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ready := 0
+	for _, node := range nodes {
+		if node.Ready {
+			ready++
+		}
+	}
+
+	pods, err := a.kubeClient.ListPods(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	phases := make(map[string]int)
+	for _, pod := range pods {
+		phases[pod.Phase]++
+	}
+
+	return []clusterMetric{
+		{name: metricNodes, value: float64(len(nodes))},
+		{name: metricNodesReady, value: float64(ready)},
+		{name: metricPods, value: float64(len(pods))},
+		{name: metricPodsRunning, value: float64(phases["Running"])},
+		{name: metricPodsPending, value: float64(phases["Pending"])},
+		{name: metricPodsFailed, value: float64(phases["Failed"])},
+		{name: metricPodsSucceeded, value: float64(phases["Succeeded"])},
+	}, nil
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// metricStreamClient opens metric streams that hand sent entries to a channel
+type metricStreamClient struct {
+	agentv1.AgentServiceClient
+	entries chan *agentv1.MetricEntry
+}
+
+func (c *metricStreamClient) StreamMetrics(_ context.Context, _ ...grpc.CallOption) (grpc.ClientStreamingClient[agentv1.MetricEntry, agentv1.MetricStreamResponse], error) {
+	return &testMetricStream{client: c}, nil
+}
+
+// testMetricStream is a metric stream of a metricStreamClient
+type testMetricStream struct {
+	grpc.ClientStreamingClient[agentv1.MetricEntry, agentv1.MetricStreamResponse]
+	client *metricStreamClient
+}
+
+func (s *testMetricStream) Send(entry *agentv1.MetricEntry) error {
+	s.client.entries <- entry
+	return nil
+}
+
+func (s *testMetricStream) CloseAndRecv() (*agentv1.MetricStreamResponse, error) {
+	return &agentv1.MetricStreamResponse{Success: true}, nil
+}
+
+// newMetricsNode builds a node with the given readiness
+func newMetricsNode(name string, ready corev1.ConditionStatus) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+		},
+	}
+}
+
+// newMetricsPod builds a pod in the given phase
+func newMetricsPod(name string, phase corev1.PodPhase) *corev1.Pod {
+	pod := newPod(name, "web")
+	pod.Status.Phase = phase
+	return pod
+}
+
+func TestAgent_StreamMetricsSendsClusterMetrics(t *testing.T) {
+	clientset := kubefake.NewSimpleClientset(
+		newMetricsNode("node-1", corev1.ConditionTrue),
+		newMetricsNode("node-2", corev1.ConditionFalse),
+		newMetricsPod("web-1", corev1.PodRunning),
+		newMetricsPod("web-2", corev1.PodRunning),
+		newMetricsPod("web-3", corev1.PodPending),
+	)
+	kubeClient := kube.NewClientWithInterfaces(clientset, nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{ReconnectWait: time.Millisecond, MetricsInterval: time.Hour}, kubeClient, zap.NewNop())
+	client := &metricStreamClient{entries: make(chan *agentv1.MetricEntry, 10)}
+	agent.client = client
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agent.streamMetrics(context.Background())
+	}()
+
+	expected := map[string]float64{
+		"cluster_nodes":          2,
+		"cluster_nodes_ready":    1,
+		"cluster_pods":           3,
+		"cluster_pods_running":   2,
+		"cluster_pods_pending":   1,
+		"cluster_pods_failed":    0,
+		"cluster_pods_succeeded": 0,
+	}
+	received := make(map[string]float64)
+	for len(received) < len(expected) {
+		select {
+		case entry := <-client.entries:
+			if entry.Timestamp == nil {
+				t.Errorf("Expected %s to carry a timestamp", entry.Name)
+			}
+			received[entry.Name] = entry.Value
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected %d metrics to be streamed but got %v", len(expected), received)
+		}
+	}
+
+	for name, value := range expected {
+		got, ok := received[name]
+		if !ok {
+			t.Errorf("Expected metric %s to be streamed", name)
+			continue
+		}
+		if got != value {
+			t.Errorf("Expected %s to be %v but got %v", name, value, got)
+		}
+	}
+
+	agent.Stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected metrics streaming to stop with the agent")
+	}
+}
+
+func TestAgent_StreamMetricsDisabled(t *testing.T) {
+	agent := newTestAgent()
+	agent.config.MetricsInterval = 0
+
+	// Returns right away without opening a stream on the nil client
+	agent.streamMetrics(context.Background())
+}
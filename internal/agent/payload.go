@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// operationPayload decodes the operation payload sent by the hub as a Struct
+func operationPayload(operation *agentv1.Operation) (map[string]interface{}, error) {
+	if operation.Payload == nil || len(operation.Payload.GetValue()) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	var st structpb.Struct
+	if err := operation.Payload.UnmarshalTo(&st); err != nil {
+		return nil, fmt.Errorf("failed to decode operation payload: %w", err)
+	}
+
+	return st.AsMap(), nil
+}
+
+// newResult encodes an operation result as a Struct wrapped in an Any.
+// The value is normalized through JSON so typed structs can be reported directly.
+func newResult(value interface{}) (*anypb.Any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to normalize result: %w", err)
+	}
+
+	st, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert result: %w", err)
+	}
+
+	return anypb.New(st)
+}
+
+// operationClient returns the kube client to run an operation with. When the
+// hub asks for impersonation the client acts as the requesting user; agents
+// whose service account may not impersonate fail the operation rather than
+// silently running it as themselves.
+func (a *Agent) operationClient(payload map[string]interface{}) (*kube.Client, error) {
+	spec, ok := payload[repo.PayloadImpersonate].(map[string]interface{})
+	if !ok {
+		return a.kubeClient, nil
+	}
+
+	user, _ := spec["user"].(string)
+	var groups []string
+	if values, ok := spec["groups"].([]interface{}); ok {
+		for _, value := range values {
+			if group, ok := value.(string); ok {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	client, err := a.kubeClient.Impersonate(user, groups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate %q: %w", user, err)
+	}
+	return client, nil
+}
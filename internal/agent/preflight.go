@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// operationPermissions lists the Kubernetes permissions each operation type
+// needs across all namespaces. Types without an entry need no permissions.
+var operationPermissions = map[string][]kube.AccessCheck{
+	"apply": {
+		{Verb: "get", Group: "*", Resource: "*"},
+		{Verb: "create", Group: "*", Resource: "*"},
+		{Verb: "patch", Group: "*", Resource: "*"},
+		// Manifests may be referenced from a ConfigMap
+		{Verb: "get", Resource: "configmaps"},
+	},
+	"exec": {
+		{Verb: "get", Resource: "pods"},
+		{Verb: "create", Resource: "pods", Subresource: "exec"},
+	},
+	"sync": {
+		{Verb: "list", Group: "*", Resource: "*"},
+	},
+	"list_namespaces": {
+		{Verb: "list", Resource: "namespaces"},
+	},
+	"list_nodes": {
+		{Verb: "list", Resource: "nodes"},
+	},
+	"list_resources": {
+		{Verb: "list", Group: "*", Resource: "*"},
+	},
+	"pod_logs": {
+		{Verb: "list", Resource: "pods"},
+		{Verb: "get", Resource: "pods", Subresource: "log"},
+	},
+}
+
+// checkPermissions reviews the permissions needed by the given operation types
+func (a *Agent) checkPermissions(ctx context.Context, operationTypes []string) (kube.PermissionReport, error) {
+	report := make(kube.PermissionReport, len(operationTypes))
+	for _, opType := range operationTypes {
+		checks, ok := operationPermissions[opType]
+		if !ok {
+			continue
+		}
+
+		results, err := a.kubeClient.CheckAccess(ctx, checks)
+		if err != nil {
+			return nil, err
+		}
+		report[opType] = results
+	}
+	return report, nil
+}
+
+// preflightPermissions logs the operation types the agent lacks permissions
+// for, so RBAC gaps surface at startup rather than when operations fail
+func (a *Agent) preflightPermissions(ctx context.Context) {
+	report, err := a.checkPermissions(ctx, supportedOperationTypes)
+	if err != nil {
+		a.logger.Warn("RBAC preflight check failed", zap.Error(err))
+		return
+	}
+
+	for _, opType := range report.MissingOperationTypes() {
+		var denied []string
+		for _, result := range report[opType] {
+			if !result.Allowed {
+				denied = append(denied, result.String())
+			}
+		}
+		a.logger.Warn("Agent lacks permissions for operation type",
+			zap.String("operation_type", opType),
+			zap.String("missing", strings.Join(denied, ", ")))
+	}
+}
+
+// processDiagnosticsOperation reports the agent's effective permissions for the
+// operation types listed in the payload, or for every supported type
+func (a *Agent) processDiagnosticsOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
+	payload, err := operationPayload(operation)
+	if err != nil {
+		return failure(err)
+	}
+
+	operationTypes := supportedOperationTypes
+	if requested, ok := payload["operation_types"].([]interface{}); ok && len(requested) > 0 {
+		operationTypes = make([]string, 0, len(requested))
+		for _, opType := range requested {
+			if s, ok := opType.(string); ok {
+				operationTypes = append(operationTypes, s)
+			}
+		}
+	}
+
+	report, err := a.checkPermissions(ctx, operationTypes)
+	if err != nil {
+		return failure(err)
+	}
+
+	missing := report.MissingOperationTypes()
+
+	result, err := newResult(map[string]interface{}{
+		"permissions": report,
+	})
+	if err != nil {
+		return failure(err)
+	}
+
+	if len(missing) > 0 {
+		return result, true, fmt.Sprintf("missing permissions for: %s", strings.Join(missing, ", "))
+	}
+	return result, true, "all permissions granted"
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// newLimitedAccessAgent creates an agent whose identity may only perform the given
+// verb/resource pairs, e.g. "list namespaces"
+func newLimitedAccessAgent(allowed ...string) *Agent {
+	clientset := kubefake.NewSimpleClientset()
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+
+		resource := attrs.Resource
+		if attrs.Subresource != "" {
+			resource += "/" + attrs.Subresource
+		}
+		for _, permission := range allowed {
+			if permission == attrs.Verb+" "+resource {
+				review.Status.Allowed = true
+			}
+		}
+		return true, review, nil
+	})
+
+	kubeClient := kube.NewClientWithInterfaces(clientset, nil, nil, zap.NewNop())
+	return NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+}
+
+func TestAgent_CheckPermissionsWithLimitedAccess(t *testing.T) {
+	agent := newLimitedAccessAgent("list namespaces", "list nodes", "get pods")
+
+	report, err := agent.checkPermissions(context.Background(), supportedOperationTypes)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	missing := report.MissingOperationTypes()
+	expected := []string{"apply", "exec", "list_resources", "pod_logs", "sync"}
+	if strings.Join(missing, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected missing operation types %v, got %v", expected, missing)
+	}
+
+	// Only the exec subresource is denied for exec
+	for _, result := range report["exec"] {
+		if result.Resource != "pods" {
+			t.Fatalf("Unexpected exec check: %+v", result)
+		}
+		if result.Allowed != (result.Subresource == "") {
+			t.Errorf("Unexpected result for %s: allowed=%v", result, result.Allowed)
+		}
+	}
+}
+
+func TestAgent_ProcessDiagnosticsOperation(t *testing.T) {
+	agent := newLimitedAccessAgent("list namespaces")
+
+	payload, err := structpb.NewStruct(map[string]interface{}{
+		"operation_types": []interface{}{"list_namespaces", "list_nodes"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	operation := &agentv1.Operation{Id: "op-1", Type: "diagnostics"}
+	if operation.Payload, err = anypb.New(payload); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	result, success, message := agent.processDiagnosticsOperation(context.Background(), operation)
+	if !success {
+		t.Fatalf("Expected success but got failure: %s", message)
+	}
+	if message != "missing permissions for: list_nodes" {
+		t.Errorf("Unexpected message: %s", message)
+	}
+
+	var st structpb.Struct
+	if err := result.UnmarshalTo(&st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	permissions, ok := st.AsMap()["permissions"].(map[string]interface{})
+	if !ok || len(permissions) != 2 {
+		t.Fatalf("Expected permissions for 2 operation types: %+v", st.AsMap())
+	}
+}
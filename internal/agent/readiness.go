@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultReadinessInterval is how often the readiness check is retried when
+// readiness_interval is not configured
+const defaultReadinessInterval = 5 * time.Second
+
+// waitReady runs the cluster health check until it passes, then marks the
+// agent ready and heartbeats right away so the hub releases held operations
+// without waiting for the next heartbeat
+func (a *Agent) waitReady(ctx context.Context) {
+	interval := a.config.ReadinessInterval
+	if interval <= 0 {
+		interval = defaultReadinessInterval
+	}
+
+	for {
+		err := a.kubeClient.HealthCheck(ctx)
+		if err == nil {
+			break
+		}
+		a.logger.Warn("Cluster not ready, holding operations",
+			zap.Error(err),
+			zap.Duration("retry_in", interval))
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-a.stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	a.ready.Store(true)
+	a.logger.Info("Agent is ready")
+
+	if _, err := a.sendHeartbeat(ctx); err != nil {
+		a.logger.Error("Failed to report readiness", zap.Error(err))
+	}
+}
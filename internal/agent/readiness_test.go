@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// heartbeatClient captures the heartbeats sent by the agent
+type heartbeatClient struct {
+	agentv1.AgentServiceClient
+	heartbeats chan *agentv1.HeartbeatRequest
+}
+
+func (c *heartbeatClient) Heartbeat(_ context.Context, req *agentv1.HeartbeatRequest, _ ...grpc.CallOption) (*agentv1.HeartbeatResponse, error) {
+	c.heartbeats <- req
+	return &agentv1.HeartbeatResponse{Success: true}, nil
+}
+
+func TestAgent_WaitReadyReportsReadinessOnceHealthy(t *testing.T) {
+	// The cluster API fails the first health checks, as during a brief outage
+	var checks atomic.Int32
+	clientset := kubefake.NewSimpleClientset()
+	clientset.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if checks.Add(1) <= 2 {
+			return true, nil, errors.New("connection refused")
+		}
+		return false, nil, nil
+	})
+
+	client := &heartbeatClient{heartbeats: make(chan *agentv1.HeartbeatRequest, 1)}
+	kubeClient := kube.NewClientWithInterfaces(clientset, nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{ReadinessInterval: 10 * time.Millisecond}, kubeClient, zap.NewNop())
+	agent.client = client
+
+	// Heartbeats sent while warming up don't claim readiness
+	if _, err := agent.sendHeartbeat(context.Background()); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if req := <-client.heartbeats; req.Ready {
+		t.Fatalf("Expected the agent not to be ready before its readiness check passed")
+	}
+
+	go agent.waitReady(context.Background())
+
+	select {
+	case req := <-client.heartbeats:
+		if !req.Ready {
+			t.Errorf("Expected the heartbeat to report readiness")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a heartbeat once the cluster became healthy")
+	}
+	if checks.Load() < 3 {
+		t.Errorf("Expected readiness only after the health check passed, got %d checks", checks.Load())
+	}
+}
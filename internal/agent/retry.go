@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryableMetadataKey is the ErrorInfo metadata key the hub sets to "true"
+// on errors that repeating the call may fix
+const retryableMetadataKey = "retryable"
+
+// defaultRetryBackoff is the first retry delay when retry_backoff is not configured
+const defaultRetryBackoff = time.Second
+
+// retryable reports whether a failed hub call may succeed when repeated, and
+// the delay the hub suggested, if any. Errors with an ErrorInfo follow the
+// hub's verdict; others are retried when their code denotes a transient
+// failure, such as the hub being unreachable.
+func retryable(err error) (bool, time.Duration) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false, 0
+	}
+
+	var delay time.Duration
+	var info *errdetails.ErrorInfo
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.RetryInfo:
+			delay = d.GetRetryDelay().AsDuration()
+		}
+	}
+	if info != nil {
+		return info.GetMetadata()[retryableMetadataKey] == "true", delay
+	}
+
+	switch st.Code() {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+		return true, delay
+	default:
+		return false, delay
+	}
+}
+
+// withRetry calls fn until it succeeds, fails permanently, or max_retries
+// retries are spent. Retries back off exponentially from retry_backoff, waiting
+// at least as long as the hub suggested.
+func (a *Agent) withRetry(ctx context.Context, call string, fn func() error) error {
+	backoff := a.config.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		retry, suggested := retryable(err)
+		if !retry || attempt >= a.config.MaxRetries {
+			return err
+		}
+
+		delay := max(backoff<<attempt, suggested)
+		a.logger.Warn("Hub call failed, retrying",
+			zap.String("call", call),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-a.stopCh:
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// hubError builds a hub error carrying an ErrorInfo with the given retryability
+func hubError(t *testing.T, code codes.Code, retryable bool) error {
+	t.Helper()
+
+	value := "false"
+	if retryable {
+		value = "true"
+	}
+	st, err := status.New(code, "hub error").WithDetails(
+		&errdetails.ErrorInfo{Reason: "TEST", Metadata: map[string]string{retryableMetadataKey: value}},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Millisecond)},
+	)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return st.Err()
+}
+
+// failingHubClient fails the first heartbeats with err
+type failingHubClient struct {
+	agentv1.AgentServiceClient
+	failures int
+	err      error
+	calls    int
+}
+
+func (c *failingHubClient) Heartbeat(_ context.Context, _ *agentv1.HeartbeatRequest, _ ...grpc.CallOption) (*agentv1.HeartbeatResponse, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		return nil, c.err
+	}
+	return &agentv1.HeartbeatResponse{Success: true}, nil
+}
+
+func TestAgent_HeartbeatRetriesOnlyRetryableErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		retryable     bool
+		expectedCalls int
+		expectError   bool
+	}{
+		{name: "retryable error is retried", retryable: true, expectedCalls: 2, expectError: false},
+		{name: "permanent error is not retried", retryable: false, expectedCalls: 1, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &failingHubClient{failures: 1, err: hubError(t, codes.Internal, tt.retryable)}
+			kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+			agent := NewAgent(&config.AgentConfig{MaxRetries: 3, RetryBackoff: time.Millisecond}, kubeClient, zap.NewNop())
+			agent.client = client
+
+			_, err := agent.sendHeartbeat(context.Background())
+			if (err != nil) != tt.expectError {
+				t.Errorf("Expected error %v but got: %v", tt.expectError, err)
+			}
+			if client.calls != tt.expectedCalls {
+				t.Errorf("Expected %d heartbeat calls but got %d", tt.expectedCalls, client.calls)
+			}
+		})
+	}
+}
+
+func TestAgent_WithRetryStopsAfterMaxRetries(t *testing.T) {
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{MaxRetries: 2, RetryBackoff: time.Millisecond}, kubeClient, zap.NewNop())
+
+	calls := 0
+	err := agent.withRetry(context.Background(), "test", func() error {
+		calls++
+		return hubError(t, codes.Internal, true)
+	})
+	if err == nil {
+		t.Fatalf("Expected the last error to be returned")
+	}
+	if calls != 3 {
+		t.Errorf("Expected 1 call and 2 retries but got %d calls", calls)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "retryable detail", err: hubError(t, codes.Internal, true), expected: true},
+		{name: "permanent detail", err: hubError(t, codes.Unavailable, false), expected: false},
+		{name: "unavailable without details", err: status.Error(codes.Unavailable, "connection refused"), expected: true},
+		{name: "invalid argument without details", err: status.Error(codes.InvalidArgument, "bad request"), expected: false},
+		{name: "not a gRPC error", err: errors.New("boom"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := retryable(tt.err); got != tt.expected {
+				t.Errorf("Expected retryable %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
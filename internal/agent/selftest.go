@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// selfTestTimeout bounds the startup self-test against the Kubernetes API
+const selfTestTimeout = 30 * time.Second
+
+// selfTest verifies the agent can reach the Kubernetes API with sufficient
+// permissions before it registers, so a non-functional agent never shows up
+// on the hub as connected
+func (a *Agent) selfTest(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	if err := a.kubeClient.HealthCheck(ctx); err != nil {
+		return selfTestError("health check", err)
+	}
+
+	info, err := a.kubeClient.GetClusterInfo(ctx)
+	if err != nil {
+		return selfTestError("cluster info", err)
+	}
+
+	a.logger.Info("Kubernetes API self-test passed",
+		zap.String("kubernetes_version", info.KubernetesVersion),
+		zap.Int("node_count", info.NodeCount))
+	return nil
+}
+
+// selfTestError describes a failed self-test step, calling out missing permissions
+func selfTestError(step string, err error) error {
+	if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) {
+		return fmt.Errorf("kubernetes self-test failed (%s): insufficient permissions: %w", step, err)
+	}
+	return fmt.Errorf("kubernetes self-test failed (%s): cluster unreachable: %w", step, err)
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+func TestAgent_StartAbortsWhenSelfTestFails(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "insufficient permissions",
+			err:      apierrors.NewForbidden(schema.GroupResource{Resource: "nodes"}, "", errors.New("denied")),
+			expected: "insufficient permissions",
+		},
+		{
+			name:     "cluster unreachable",
+			err:      errors.New("connection refused"),
+			expected: "cluster unreachable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := kubefake.NewSimpleClientset()
+			clientset.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, tt.err
+			})
+			kubeClient := kube.NewClientWithInterfaces(clientset, nil, nil, zap.NewNop())
+			agent := NewAgent(&config.AgentConfig{HubURL: "127.0.0.1:0"}, kubeClient, zap.NewNop())
+
+			err := agent.Start(context.Background())
+			if err == nil {
+				t.Fatal("Expected startup to fail")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error to mention %q, got: %v", tt.expected, err)
+			}
+			if agent.conn != nil {
+				t.Error("Expected agent not to connect to the hub")
+			}
+		})
+	}
+}
+
+func TestAgent_SelfTestPasses(t *testing.T) {
+	agent := newTestAgent()
+
+	if err := agent.selfTest(context.Background()); err != nil {
+		t.Fatalf("Expected self-test to pass, got: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// reasonAgentNotRegistered is the ErrorInfo reason of hub errors for calls
+// from an agent the hub instance has no session for
+const reasonAgentNotRegistered = "AGENT_NOT_REGISTERED"
+
+// notRegistered reports whether a hub call failed because the hub instance
+// has no session for the agent. Sessions are kept by each hub instance, so
+// this happens when calls are balanced onto, or fail over to, an instance the
+// agent hasn't registered with, or after a hub restart.
+func notRegistered(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.NotFound {
+		return false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.GetReason() == reasonAgentNotRegistered
+		}
+	}
+	return false
+}
+
+// restoreSession registers the agent again when err shows the hub lost or
+// never had its session, reporting whether it did so successfully
+func (a *Agent) restoreSession(ctx context.Context, err error) bool {
+	if !notRegistered(err) {
+		return false
+	}
+
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+
+	a.logger.Info("Hub has no session for the agent, registering again")
+	if err := a.register(ctx); err != nil {
+		a.logger.Error("Failed to register again", zap.Error(err))
+		return false
+	}
+	return true
+}
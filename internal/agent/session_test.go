@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// fakeHub is a hub instance that, like the real hub, keeps agent sessions in
+// memory and rejects heartbeats from agents that haven't registered with it
+type fakeHub struct {
+	agentv1.UnimplementedAgentServiceServer
+
+	mu            sync.Mutex
+	registrations int
+	heartbeats    int
+
+	server  *grpc.Server
+	address string
+}
+
+func startFakeHub(t *testing.T) *fakeHub {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	hub := &fakeHub{server: grpc.NewServer(), address: listener.Addr().String()}
+	agentv1.RegisterAgentServiceServer(hub.server, hub)
+	go hub.server.Serve(listener)
+	t.Cleanup(hub.server.Stop)
+	return hub
+}
+
+func (h *fakeHub) Register(_ context.Context, _ *agentv1.RegisterRequest) (*agentv1.RegisterResponse, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.registrations++
+	return &agentv1.RegisterResponse{Success: true, ClusterId: "cluster-1", SessionToken: "session-cluster-1"}, nil
+}
+
+func (h *fakeHub) Heartbeat(_ context.Context, _ *agentv1.HeartbeatRequest) (*agentv1.HeartbeatResponse, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.registrations == 0 {
+		st, _ := status.New(codes.NotFound, "Agent not registered").WithDetails(
+			&errdetails.ErrorInfo{Reason: reasonAgentNotRegistered, Metadata: map[string]string{retryableMetadataKey: "false"}},
+		)
+		return nil, st.Err()
+	}
+	h.heartbeats++
+	return &agentv1.HeartbeatResponse{Success: true}, nil
+}
+
+func (h *fakeHub) counts() (registrations, heartbeats int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.registrations, h.heartbeats
+}
+
+func TestAgent_FailsOverToHealthyHub(t *testing.T) {
+	first, second := startFakeHub(t), startFakeHub(t)
+
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{
+		HubURLs:      []string{first.address, second.address},
+		MaxRetries:   5,
+		RetryBackoff: 10 * time.Millisecond,
+	}, kubeClient, zap.NewNop())
+	agent.creds = insecure.NewCredentials()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := agent.connect(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	defer agent.conn.Close()
+
+	// Register with the first hub only, then take it down
+	first.mu.Lock()
+	first.registrations++
+	first.mu.Unlock()
+	first.server.Stop()
+
+	// Heartbeats land on the second hub, which the agent registers with once
+	// it learns the hub has no session for it
+	var err error
+	for attempt := 0; attempt < 5; attempt++ {
+		if _, err = agent.sendHeartbeat(ctx); err == nil {
+			break
+		}
+		if !agent.restoreSession(ctx, err) {
+			t.Fatalf("Expected the session to be restored after: %v", err)
+		}
+	}
+	if err != nil {
+		t.Fatalf("Expected heartbeats to reach the healthy hub but got: %v", err)
+	}
+
+	registrations, heartbeats := second.counts()
+	if registrations != 1 || heartbeats != 1 {
+		t.Errorf("Expected 1 registration and 1 heartbeat on the healthy hub but got %d and %d", registrations, heartbeats)
+	}
+}
+
+func TestNotRegistered(t *testing.T) {
+	st, err := status.New(codes.NotFound, "Agent not registered").WithDetails(&errdetails.ErrorInfo{Reason: reasonAgentNotRegistered})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !notRegistered(st.Err()) {
+		t.Error("Expected an AGENT_NOT_REGISTERED error to be recognized")
+	}
+	if notRegistered(hubError(t, codes.NotFound, false)) {
+		t.Error("Expected other NotFound errors not to be treated as a lost session")
+	}
+	if notRegistered(status.Error(codes.Unavailable, "hub down")) {
+		t.Error("Expected an unavailable hub not to be treated as a lost session")
+	}
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultShutdownTimeout is used when shutdown_timeout is not configured
+const defaultShutdownTimeout = 10 * time.Second
+
+// interruptedMessage is reported for operations still running when the agent stops
+const interruptedMessage = "Operation interrupted by agent shutdown"
+
+// releaseOperation removes a running operation, reporting whether it was
+// still registered. Whoever releases an operation reports its result, so an
+// operation interrupted by Stop is not reported twice.
+func (a *Agent) releaseOperation(operationID string) bool {
+	a.opsMu.Lock()
+	defer a.opsMu.Unlock()
+
+	_, ok := a.cancelOps[operationID]
+	delete(a.cancelOps, operationID)
+	return ok
+}
+
+// reportInterrupted cancels the operations still running and reports them to
+// the hub as failed, so they don't stay running there until they time out.
+// Reporting is best effort and bounded by shutdown_timeout.
+func (a *Agent) reportInterrupted() {
+	a.opsMu.Lock()
+	inFlight := a.cancelOps
+	a.cancelOps = make(map[string]context.CancelFunc)
+	a.opsMu.Unlock()
+
+	if len(inFlight) == 0 {
+		return
+	}
+
+	timeout := a.config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := newResult(map[string]interface{}{"interrupted": true})
+	if err != nil {
+		a.logger.Warn("Failed to encode interrupted result", zap.Error(err))
+	}
+
+	for operationID, cancelOp := range inFlight {
+		cancelOp()
+		if a.client == nil {
+			continue
+		}
+		if err := a.reportResult(ctx, operationID, false, interruptedMessage, result); err != nil {
+			a.logger.Warn("Failed to report interrupted operation",
+				zap.String("operation_id", operationID),
+				zap.Error(err),
+			)
+			continue
+		}
+		a.logger.Info("Reported interrupted operation", zap.String("operation_id", operationID))
+	}
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+func TestAgent_StopReportsInFlightOperations(t *testing.T) {
+	// The manifest download blocks until the operation is cancelled
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	client := &reportingClient{results: make(chan *agentv1.ReportResultRequest, 2)}
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{ManifestURLAllowedHosts: []string{serverURL.Host}}, kubeClient, zap.NewNop())
+	agent.client = client
+
+	payload, err := structpb.NewStruct(map[string]interface{}{
+		"manifest_url":    server.URL + "/app.yaml",
+		"manifest_sha256": "0000",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	operation := &agentv1.Operation{Id: "op-1", Type: "apply"}
+	if operation.Payload, err = anypb.New(payload); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	agent.dispatchOperation(context.Background(), operation)
+
+	// Wait for the operation to start before stopping the agent
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		agent.opsMu.Lock()
+		_, running := agent.cancelOps["op-1"]
+		agent.opsMu.Unlock()
+		if running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Operation did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	agent.Stop()
+
+	select {
+	case result := <-client.results:
+		if result.OperationId != "op-1" || result.Success || result.Message != interruptedMessage {
+			t.Fatalf("Expected op-1 to be reported interrupted, got id=%s success=%v message=%q",
+				result.OperationId, result.Success, result.Message)
+		}
+	default:
+		t.Fatal("Expected Stop to report the in-flight operation")
+	}
+
+	// The cancelled operation doesn't report a second result
+	select {
+	case result := <-client.results:
+		t.Fatalf("Expected a single result but also got %q", result.Message)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
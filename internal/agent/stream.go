@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys the hub authenticates client streams, such as the log
+// stream, with
+const (
+	clusterIDMetadataKey    = "x-cluster-id"
+	sessionTokenMetadataKey = "x-session-token"
+)
+
+// defaultReconnectWait is the first delay before reopening a broken stream
+// when reconnect_wait is not configured
+const defaultReconnectWait = 5 * time.Second
+
+// maxStreamBackoff caps the delay before reopening a broken stream
+const maxStreamBackoff = 2 * time.Minute
+
+// keepStreaming runs a client stream until the agent stops, opening it again
+// whenever it breaks. Attempts back off exponentially from reconnect_wait up
+// to maxStreamBackoff; a stream that stayed up that long starts over from
+// reconnect_wait. run returns nil once the agent stops.
+func (a *Agent) keepStreaming(ctx context.Context, name string, run func(ctx context.Context) error) {
+	initial := a.config.ReconnectWait
+	if initial <= 0 {
+		initial = defaultReconnectWait
+	}
+	backoff := initial
+
+	for {
+		started := time.Now()
+		err := run(a.streamContext(ctx))
+		if err == nil {
+			return
+		}
+		if time.Since(started) >= maxStreamBackoff {
+			backoff = initial
+		}
+
+		a.logger.Warn("Stream to hub broke, reopening",
+			zap.String("stream", name),
+			zap.Duration("retry_in", backoff),
+			zap.Error(err))
+		a.restoreSession(ctx, err)
+
+		if !a.wait(ctx, backoff) {
+			return
+		}
+		backoff = min(backoff*2, maxStreamBackoff)
+	}
+}
+
+// sendFailed returns why sending on a client stream failed. When the hub ended
+// the stream, its status, returned by closeAndRecv, tells why.
+func sendFailed[T any](name string, err error, closeAndRecv func() (T, error)) error {
+	if errors.Is(err, io.EOF) {
+		_, err = closeAndRecv()
+	}
+	if err == nil {
+		err = fmt.Errorf("%s stream closed by hub", name)
+	}
+	return err
+}
+
+// streamContext returns ctx carrying the agent's session for client streams
+func (a *Agent) streamContext(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		clusterIDMetadataKey, a.clusterID,
+		sessionTokenMetadataKey, a.sessionToken,
+	)
+}
+
+// untilStopped returns a context cancelled once the agent stops
+func (a *Agent) untilStopped(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-a.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// errConnectionFailed ends a session whose connection can no longer reach the hub
+var errConnectionFailed = errors.New("connection to hub failed")
+
+// supervise keeps the agent connected to the hub until it stops. It runs a
+// session until the connection is lost, then dials the hub again, backing off
+// exponentially with jitter from reconnect_wait up to maxStreamBackoff,
+// registers again and starts a new session.
+func (a *Agent) supervise(ctx context.Context) {
+	initial := a.config.ReconnectWait
+	if initial <= 0 {
+		initial = defaultReconnectWait
+	}
+	backoff := initial
+
+	if a.tailsPods() {
+		go a.tailPodLogs(ctx)
+	}
+
+	for {
+		started := time.Now()
+		err := a.runSession(ctx)
+		if ctx.Err() != nil || a.stopped() {
+			return
+		}
+		if time.Since(started) >= maxStreamBackoff {
+			backoff = initial
+		}
+		a.logger.Warn("Lost connection to hub", zap.Error(err))
+
+		for {
+			wait := withJitter(backoff)
+			a.logger.Info("Reconnecting to hub", zap.Duration("retry_in", wait))
+			if !a.wait(ctx, wait) {
+				return
+			}
+			backoff = min(backoff*2, maxStreamBackoff)
+
+			if err := a.reconnect(ctx); err != nil {
+				a.logger.Error("Failed to reconnect to hub", zap.Error(err))
+				continue
+			}
+			a.logger.Info("Reconnected to hub", zap.String("cluster_id", a.clusterID))
+			break
+		}
+	}
+}
+
+// runSession runs the heartbeat, the readiness check and the log and metric
+// streams alongside the operation stream until the connection to the hub is
+// lost or the agent stops, and returns once all of them have stopped
+func (a *Agent) runSession(ctx context.Context) error {
+	session, cancel := context.WithCancelCause(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel(context.Canceled)
+		wg.Wait()
+	}()
+
+	run := func(fn func(ctx context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(session)
+		}()
+	}
+
+	run(a.heartbeat)
+	run(a.waitReady)
+	run(a.streamLogs)
+	run(a.streamMetrics)
+	if conn := a.conn; conn != nil {
+		run(func(session context.Context) {
+			if connectionFailed(session, conn) {
+				cancel(errConnectionFailed)
+			}
+		})
+	}
+
+	// Operations run under ctx so they carry on while the agent reconnects
+	if err := a.streamOperations(ctx, session); err != nil {
+		return err
+	}
+	return context.Cause(session)
+}
+
+// reconnect dials the hub again, closing the previous connection, and
+// registers with it
+func (a *Agent) reconnect(ctx context.Context) error {
+	previous := a.conn
+	if err := a.connect(ctx); err != nil {
+		return err
+	}
+	if previous != nil {
+		if err := previous.Close(); err != nil {
+			a.logger.Debug("Failed to close previous connection", zap.Error(err))
+		}
+	}
+	return a.register(ctx)
+}
+
+// connectionFailed waits until conn can no longer reach the hub, reporting
+// true, or ctx is done
+func connectionFailed(ctx context.Context, conn *grpc.ClientConn) bool {
+	for {
+		state := conn.GetState()
+		if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			return true
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return false
+		}
+	}
+}
+
+// withJitter picks a delay between half of d and d, so agents dropped by the
+// same hub restart don't reconnect in lockstep
+func withJitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(d-half)
+}
+
+// wait waits for d, reporting false when ctx is done or the agent stops first
+func (a *Agent) wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-a.stopCh:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// stopped reports whether the agent was stopped
+func (a *Agent) stopped() bool {
+	select {
+	case <-a.stopCh:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// streamingHub is a fake hub that also serves operation streams, reporting
+// each stream it opens and holding it until the hub or the agent drops it
+type streamingHub struct {
+	*fakeHub
+	streams chan string // session tokens of opened operation streams
+
+	mu     sync.Mutex
+	active int
+}
+
+// startStreamingHub starts a hub listening on address, e.g. the address of a
+// hub that went down
+func startStreamingHub(t *testing.T, address string) *streamingHub {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	hub := &streamingHub{
+		fakeHub: &fakeHub{server: grpc.NewServer(), address: listener.Addr().String()},
+		streams: make(chan string, 10),
+	}
+	agentv1.RegisterAgentServiceServer(hub.server, hub)
+	go hub.server.Serve(listener)
+	t.Cleanup(hub.server.Stop)
+	return hub
+}
+
+func (h *streamingHub) StreamOperations(req *agentv1.StreamOperationsRequest, stream grpc.ServerStreamingServer[agentv1.Operation]) error {
+	h.mu.Lock()
+	h.active++
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		h.active--
+		h.mu.Unlock()
+	}()
+
+	h.streams <- req.SessionToken
+	<-stream.Context().Done()
+	return nil
+}
+
+func (h *streamingHub) activeStreams() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.active
+}
+
+// waitStream waits for the agent to open an operation stream on hub
+func waitStream(t *testing.T, hub *streamingHub) string {
+	t.Helper()
+	select {
+	case token := <-hub.streams:
+		return token
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the agent to open an operation stream")
+		return ""
+	}
+}
+
+func TestAgent_ReconnectsAfterHubRestart(t *testing.T) {
+	first := startStreamingHub(t, "127.0.0.1:0")
+
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{
+		HubURL:            first.address,
+		HeartbeatInterval: time.Hour,
+		ReconnectWait:     10 * time.Millisecond,
+	}, kubeClient, zap.NewNop())
+	agent.creds = insecure.NewCredentials()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := agent.Start(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if token := waitStream(t, first); token != "session-cluster-1" {
+		t.Errorf("Expected the stream to carry the agent's session but got %q", token)
+	}
+
+	// The hub restarts on the same address, losing the agent's session
+	first.server.Stop()
+	second := startStreamingHub(t, first.address)
+
+	waitStream(t, second)
+	if registrations, _ := second.counts(); registrations != 1 {
+		t.Errorf("Expected the agent to register with the restarted hub once but got %d registrations", registrations)
+	}
+
+	// Stopping the agent closes the stream of its last session
+	agent.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for second.activeStreams() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the operation stream to close once the agent stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-second.streams:
+		t.Error("Expected no stream to be opened after the agent stopped")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAgent_RunSessionStopsItsGoroutines(t *testing.T) {
+	hub := startStreamingHub(t, "127.0.0.1:0")
+
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{HubURL: hub.address, HeartbeatInterval: time.Hour}, kubeClient, zap.NewNop())
+	agent.creds = insecure.NewCredentials()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := agent.connect(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	defer agent.conn.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- agent.runSession(ctx) }()
+	waitStream(t, hub)
+
+	// Dropping the hub ends the session once all its goroutines returned
+	hub.server.Stop()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected the session to report the lost connection")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the session to end when the hub went away")
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	for range 100 {
+		if wait := withJitter(time.Second); wait < 500*time.Millisecond || wait > time.Second {
+			t.Fatalf("Expected a delay between 500ms and 1s but got %v", wait)
+		}
+	}
+	if wait := withJitter(time.Nanosecond); wait != time.Nanosecond {
+		t.Errorf("Expected a delay too short to jitter to be kept but got %v", wait)
+	}
+}
@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// Payload keys of sync operations
+const (
+	// payloadSyncKinds lists the kinds to inventory as "apiVersion/Kind", e.g. "apps/v1/Deployment"
+	payloadSyncKinds = "kinds"
+	// payloadSyncNamespaces limits the inventory of namespaced kinds; empty means all namespaces
+	payloadSyncNamespaces = "namespaces"
+	// payloadSyncMaxItems caps the number of inventory entries returned; zero or absent means no cap
+	payloadSyncMaxItems = "max_items"
+)
+
+// defaultSyncKinds are inventoried when a sync operation names no kinds
+var defaultSyncKinds = []string{
+	"apps/v1/Deployment",
+	"apps/v1/StatefulSet",
+	"apps/v1/DaemonSet",
+	"v1/Service",
+	"v1/ConfigMap",
+}
+
+// processSyncOperation builds an inventory of the cluster's resources and,
+// when the payload carries a desired inventory, reports drift against it
+func (a *Agent) processSyncOperation(ctx context.Context, operation *agentv1.Operation) (*anypb.Any, bool, string) {
+	payload, err := operationPayload(operation)
+	if err != nil {
+		return failure(err)
+	}
+
+	kinds, err := syncKinds(payload)
+	if err != nil {
+		return failure(err)
+	}
+	namespaces := stringList(payload[payloadSyncNamespaces])
+	maxItems, err := syncMaxItems(payload)
+	if err != nil {
+		return failure(err)
+	}
+
+	inventory, err := a.inventory(ctx, kinds, namespaces)
+	if err != nil {
+		return failure(err)
+	}
+
+	fields := map[string]interface{}{
+		"inventory": inventory,
+	}
+	message := fmt.Sprintf("synced %d resources", len(inventory))
+
+	if raw, ok := payload[repo.PayloadDesiredInventory]; ok {
+		desired, err := desiredInventory(raw)
+		if err != nil {
+			return failure(err)
+		}
+
+		drift := kube.DetectDrift(desired, inventory)
+		fields["drift"] = drift
+		fields["drift_detected"] = drift.Detected()
+		if drift.Detected() {
+			message = fmt.Sprintf("%s, drift detected: %d added, %d removed, %d modified",
+				message, len(drift.Added), len(drift.Removed), len(drift.Modified))
+		}
+	}
+
+	// Drift is detected against the whole inventory, only the response is capped
+	if maxItems > 0 && len(inventory) > maxItems {
+		fields["inventory"] = inventory[:maxItems]
+		fields["truncated"] = true
+		fields["total_items"] = len(inventory)
+		message = fmt.Sprintf("%s, returning the first %d", message, maxItems)
+	}
+
+	result, err := newResult(fields)
+	if err != nil {
+		return failure(err)
+	}
+
+	return result, true, message
+}
+
+// inventory lists the resources of the given kinds, limited to namespaces when
+// any are given. Cluster-scoped kinds are always listed cluster-wide.
+func (a *Agent) inventory(ctx context.Context, kinds []schema.GroupVersionKind, namespaces []string) ([]kube.InventoryEntry, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	inventory := []kube.InventoryEntry{}
+	for _, gvk := range kinds {
+		// Listing a kind can take a while on large clusters, so a cancelled
+		// sync stops before the next one
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, namespace := range namespaces {
+			list, err := a.kubeClient.ListResources(ctx, gvk, namespace)
+			clusterScoped := errors.Is(err, kube.ErrNamespaceNotAllowed)
+			if clusterScoped {
+				list, err = a.kubeClient.ListResources(ctx, gvk, "")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s: %w", gvk.Kind, err)
+			}
+
+			for i := range list.Items {
+				item := &list.Items[i]
+				// Lists don't always carry the kind of their items
+				item.SetGroupVersionKind(gvk)
+				inventory = append(inventory, kube.NewInventoryEntry(item))
+			}
+
+			if clusterScoped {
+				break
+			}
+		}
+	}
+
+	return inventory, nil
+}
+
+// syncKinds parses the kinds a sync operation inventories
+func syncKinds(payload map[string]interface{}) ([]schema.GroupVersionKind, error) {
+	names := stringList(payload[payloadSyncKinds])
+	if len(names) == 0 {
+		names = defaultSyncKinds
+	}
+
+	kinds := make([]schema.GroupVersionKind, 0, len(names))
+	for _, name := range names {
+		i := strings.LastIndex(name, "/")
+		if i <= 0 || i == len(name)-1 {
+			return nil, fmt.Errorf("invalid kind %q, expected apiVersion/Kind", name)
+		}
+		gv, err := schema.ParseGroupVersion(name[:i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid kind %q: %w", name, err)
+		}
+		kinds = append(kinds, gv.WithKind(name[i+1:]))
+	}
+	return kinds, nil
+}
+
+// syncMaxItems parses the cap on the inventory entries a sync operation returns
+func syncMaxItems(payload map[string]interface{}) (int, error) {
+	raw, ok := payload[payloadSyncMaxItems]
+	if !ok {
+		return 0, nil
+	}
+	value, ok := raw.(float64)
+	if !ok || value < 0 || value != float64(int(value)) {
+		return 0, fmt.Errorf("invalid %s %v: expected a non-negative integer", payloadSyncMaxItems, raw)
+	}
+	return int(value), nil
+}
+
+// desiredInventory decodes the desired inventory of a sync payload
+func desiredInventory(raw interface{}) ([]kube.InventoryEntry, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid desired inventory: %w", err)
+	}
+
+	var desired []kube.InventoryEntry
+	if err := json.Unmarshal(data, &desired); err != nil {
+		return nil, fmt.Errorf("invalid desired inventory: %w", err)
+	}
+	return desired, nil
+}
+
+// stringList returns the strings in a payload list value
+func stringList(value interface{}) []string {
+	values, _ := value.([]interface{})
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok && s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
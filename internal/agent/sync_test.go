@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+var (
+	configMapGVK = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	configMapGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+)
+
+// newConfigMap builds an unstructured ConfigMap for the fake dynamic client
+func newConfigMap(name, value string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(configMapGVK)
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.Object["data"] = map[string]interface{}{"key": value}
+	return obj
+}
+
+func TestAgent_ProcessSyncOperationReportsDrift(t *testing.T) {
+	unchanged := newConfigMap("unchanged", "v1")
+	modified := newConfigMap("modified", "v2")
+	added := newConfigMap("added", "v1")
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapGVR: "ConfigMapList"},
+		unchanged, modified, added,
+	)
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), dynamicClient, mapper, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+
+	// The baseline expects the modified ConfigMap's previous content and a
+	// ConfigMap that has since been deleted
+	desiredEntry := func(name, hash string) map[string]interface{} {
+		return map[string]interface{}{"api_version": "v1", "kind": "ConfigMap", "namespace": "default", "name": name, "hash": hash}
+	}
+	payload, err := structpb.NewStruct(map[string]interface{}{
+		"kinds": []interface{}{"v1/ConfigMap"},
+		"desired": []interface{}{
+			desiredEntry("unchanged", kube.ContentHash(unchanged)),
+			desiredEntry("modified", kube.ContentHash(newConfigMap("modified", "v1"))),
+			desiredEntry("removed", ""),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	operation := &agentv1.Operation{Id: "op-1", Type: "sync"}
+	if operation.Payload, err = anypb.New(payload); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	result, success, message := agent.processSyncOperation(context.Background(), operation)
+	if !success {
+		t.Fatalf("Expected success but got failure: %s", message)
+	}
+
+	var st structpb.Struct
+	if err := result.UnmarshalTo(&st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	fields := st.AsMap()
+
+	if inventory, _ := fields["inventory"].([]interface{}); len(inventory) != 3 {
+		t.Errorf("Expected 3 resources in the inventory but got %v", fields["inventory"])
+	}
+	if fields["drift_detected"] != true {
+		t.Errorf("Expected drift to be detected: %+v", fields)
+	}
+
+	drift, ok := fields["drift"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected drift in result: %+v", fields)
+	}
+	for category, want := range map[string]string{"added": "added", "removed": "removed", "modified": "modified"} {
+		entries, _ := drift[category].([]interface{})
+		if len(entries) != 1 {
+			t.Errorf("Expected 1 %s resource but got %v", category, drift[category])
+			continue
+		}
+		if name := entries[0].(map[string]interface{})["name"]; name != want {
+			t.Errorf("Expected %s resource %q but got %v", category, want, name)
+		}
+	}
+}
+
+func TestAgent_ProcessSyncOperationWithoutBaseline(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapGVR: "ConfigMapList"},
+		newConfigMap("app", "v1"),
+	)
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), dynamicClient, mapper, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+
+	payload, err := structpb.NewStruct(map[string]interface{}{"kinds": []interface{}{"v1/ConfigMap"}})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	operation := &agentv1.Operation{Id: "op-1", Type: "sync"}
+	if operation.Payload, err = anypb.New(payload); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	result, success, message := agent.processSyncOperation(context.Background(), operation)
+	if !success {
+		t.Fatalf("Expected success but got failure: %s", message)
+	}
+
+	var st structpb.Struct
+	if err := result.UnmarshalTo(&st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, ok := st.AsMap()["drift"]; ok {
+		t.Errorf("Expected no drift report without a baseline: %+v", st.AsMap())
+	}
+}
+
+// newSyncTestAgent returns an agent whose cluster holds the given ConfigMaps
+func newSyncTestAgent(objects ...runtime.Object) *Agent {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapGVR: "ConfigMapList"},
+		objects...,
+	)
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), dynamicClient, mapper, zap.NewNop())
+	return NewAgent(&config.AgentConfig{}, kubeClient, zap.NewNop())
+}
+
+// newSyncOperation builds a sync operation for payload
+func newSyncOperation(t *testing.T, payload map[string]interface{}) *agentv1.Operation {
+	t.Helper()
+
+	st, err := structpb.NewStruct(payload)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	operation := &agentv1.Operation{Id: "op-1", Type: "sync"}
+	if operation.Payload, err = anypb.New(st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return operation
+}
+
+func TestAgent_ProcessSyncOperationCapsItems(t *testing.T) {
+	agent := newSyncTestAgent(newConfigMap("a", "v1"), newConfigMap("b", "v1"), newConfigMap("c", "v1"))
+
+	operation := newSyncOperation(t, map[string]interface{}{"kinds": []interface{}{"v1/ConfigMap"}, "max_items": 2})
+	result, success, message := agent.processSyncOperation(context.Background(), operation)
+	if !success {
+		t.Fatalf("Expected success but got failure: %s", message)
+	}
+
+	var st structpb.Struct
+	if err := result.UnmarshalTo(&st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	fields := st.AsMap()
+	if inventory, _ := fields["inventory"].([]interface{}); len(inventory) != 2 {
+		t.Errorf("Expected 2 resources in the inventory but got %v", fields["inventory"])
+	}
+	if fields["truncated"] != true || fields["total_items"] != float64(3) {
+		t.Errorf("Expected the inventory to be reported as truncated from 3 items: %+v", fields)
+	}
+
+	operation = newSyncOperation(t, map[string]interface{}{"max_items": -1})
+	if _, success, _ := agent.processSyncOperation(context.Background(), operation); success {
+		t.Error("Expected failure with a negative max_items")
+	}
+}
+
+func TestAgent_ProcessSyncOperationStopsWhenCancelled(t *testing.T) {
+	agent := newSyncTestAgent(newConfigMap("a", "v1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	operation := newSyncOperation(t, map[string]interface{}{"kinds": []interface{}{"v1/ConfigMap"}})
+	if _, success, _ := agent.processSyncOperation(ctx, operation); success {
+		t.Error("Expected a cancelled sync to fail")
+	}
+}
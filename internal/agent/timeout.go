@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// operationTimeout returns how long an operation may run. A timeout in the
+// payload wins over the timeout the hub set on the operation, then over the
+// configured default for the operation's type, then over the agent-wide
+// default. Zero means no timeout.
+func (a *Agent) operationTimeout(operation *agentv1.Operation) (time.Duration, error) {
+	payload, err := operationPayload(operation)
+	if err != nil {
+		return 0, err
+	}
+
+	if raw, ok := payload[repo.PayloadTimeout]; ok {
+		timeout, err := parseTimeout(raw)
+		if err != nil {
+			return 0, err
+		}
+		return timeout, nil
+	}
+
+	if operation.TimeoutSeconds > 0 {
+		return time.Duration(operation.TimeoutSeconds) * time.Second, nil
+	}
+
+	if a.config == nil {
+		return 0, nil
+	}
+	if timeout, ok := a.config.OperationTimeouts[operation.Type]; ok {
+		return timeout, nil
+	}
+	return a.config.OperationTimeout, nil
+}
+
+// parseTimeout reads a payload timeout, given as a duration string or a
+// number of seconds
+func parseTimeout(raw interface{}) (time.Duration, error) {
+	var timeout time.Duration
+	switch value := raw.(type) {
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid operation timeout %q: %w", value, err)
+		}
+		timeout = parsed
+	case float64:
+		timeout = time.Duration(value * float64(time.Second))
+	default:
+		return 0, fmt.Errorf("invalid operation timeout %v: expected a duration or seconds", raw)
+	}
+
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid operation timeout %v: must be positive", raw)
+	}
+	return timeout, nil
+}
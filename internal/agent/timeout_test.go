@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/kube"
+)
+
+// newTimeoutOperation builds an operation of the given type with payload
+func newTimeoutOperation(t *testing.T, opType string, payload map[string]interface{}) *agentv1.Operation {
+	t.Helper()
+
+	operation := &agentv1.Operation{Id: "op-1", Type: opType}
+	if payload == nil {
+		return operation
+	}
+	st, err := structpb.NewStruct(payload)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if operation.Payload, err = anypb.New(st); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return operation
+}
+
+func TestAgent_OperationTimeoutDefaultsPerType(t *testing.T) {
+	agent := NewAgent(&config.AgentConfig{
+		OperationTimeout: 5 * time.Minute,
+		OperationTimeouts: map[string]time.Duration{
+			"apply":           2 * time.Minute,
+			"sync":            15 * time.Minute,
+			"list_namespaces": 30 * time.Second,
+		},
+	}, nil, zap.NewNop())
+
+	tests := []struct {
+		name     string
+		opType   string
+		payload  map[string]interface{}
+		expected time.Duration
+	}{
+		{name: "apply uses its default", opType: "apply", expected: 2 * time.Minute},
+		{name: "sync uses its default", opType: "sync", expected: 15 * time.Minute},
+		{name: "query uses its default", opType: "list_namespaces", expected: 30 * time.Second},
+		{name: "type without a default uses the agent default", opType: "exec", expected: 5 * time.Minute},
+		{name: "payload duration overrides the type default", opType: "sync", payload: map[string]interface{}{"timeout": "90s"}, expected: 90 * time.Second},
+		{name: "payload seconds override the type default", opType: "apply", payload: map[string]interface{}{"timeout": 45}, expected: 45 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout, err := agent.operationTimeout(newTimeoutOperation(t, tt.opType, tt.payload))
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if timeout != tt.expected {
+				t.Errorf("Expected timeout %s but got %s", tt.expected, timeout)
+			}
+		})
+	}
+}
+
+func TestAgent_OperationTimeoutFromOperation(t *testing.T) {
+	agent := NewAgent(&config.AgentConfig{OperationTimeout: 5 * time.Minute}, nil, zap.NewNop())
+
+	// The hub's timeout wins over the agent default
+	operation := newTimeoutOperation(t, "exec", nil)
+	operation.TimeoutSeconds = 30
+	timeout, err := agent.operationTimeout(operation)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if timeout != 30*time.Second {
+		t.Errorf("Expected timeout 30s but got %s", timeout)
+	}
+
+	// A payload timeout still wins over it
+	operation = newTimeoutOperation(t, "exec", map[string]interface{}{"timeout": "10s"})
+	operation.TimeoutSeconds = 30
+	if timeout, _ = agent.operationTimeout(operation); timeout != 10*time.Second {
+		t.Errorf("Expected timeout 10s but got %s", timeout)
+	}
+}
+
+func TestAgent_OperationTimeoutRejectsInvalidPayload(t *testing.T) {
+	agent := NewAgent(&config.AgentConfig{OperationTimeout: time.Minute}, nil, zap.NewNop())
+
+	for _, raw := range []interface{}{"soon", "-1s", 0, true} {
+		if _, err := agent.operationTimeout(newTimeoutOperation(t, "apply", map[string]interface{}{"timeout": raw})); err == nil {
+			t.Errorf("Expected an error for timeout %v", raw)
+		}
+	}
+}
+
+func TestAgent_OperationTimesOut(t *testing.T) {
+	// The manifest download blocks until the operation's context ends
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	client := &reportingClient{results: make(chan *agentv1.ReportResultRequest, 1)}
+	kubeClient := kube.NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+	agent := NewAgent(&config.AgentConfig{
+		ManifestURLAllowedHosts: []string{serverURL.Host},
+		OperationTimeouts:       map[string]time.Duration{"apply": 50 * time.Millisecond},
+	}, kubeClient, zap.NewNop())
+	agent.client = client
+
+	agent.processOperation(context.Background(), newTimeoutOperation(t, "apply", map[string]interface{}{
+		"manifest_url":    server.URL + "/app.yaml",
+		"manifest_sha256": "0000",
+	}))
+
+	select {
+	case result := <-client.results:
+		if result.Success || !strings.Contains(result.Message, "timed out") {
+			t.Fatalf("Expected timed out result, got success=%v message=%q", result.Success, result.Message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the timed out operation to report a result")
+	}
+}
@@ -0,0 +1,61 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+)
+
+// registrationLimit bounds how many registrations are processed at once
+type registrationLimit struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// SetRegistrationLimit bounds how many Register calls the hub processes
+// concurrently, so a fleet-wide agent restart can't stampede the cluster
+// store. A registration beyond the limit waits up to wait for a slot and is
+// then rejected with a retryable ResourceExhausted error, telling the agent to
+// back off. A zero limit disables it.
+func (s *Server) SetRegistrationLimit(limit int, wait time.Duration) {
+	if limit <= 0 {
+		s.registrations = nil
+		return
+	}
+	s.registrations = &registrationLimit{slots: make(chan struct{}, limit), wait: wait}
+}
+
+// admitRegistration takes a registration slot, returning the function that
+// releases it, or a retryable error when none frees up in time
+func (s *Server) admitRegistration(ctx context.Context, clusterName string) (func(), error) {
+	limit := s.registrations
+	if limit == nil {
+		return func() {}, nil
+	}
+	release := func() { <-limit.slots }
+
+	select {
+	case limit.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := time.NewTimer(limit.wait)
+	defer timer.Stop()
+
+	select {
+	case limit.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	s.logger.Warn("Shedding agent registration, too many registrations in progress",
+		zap.String("cluster_name", clusterName),
+		zap.Int("limit", cap(limit.slots)),
+	)
+	return nil, retryableError(codes.ResourceExhausted, ReasonTooManyRegistrations,
+		"Too many agent registrations in progress, retry later")
+}
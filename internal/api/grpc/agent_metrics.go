@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"errors"
+	"io"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/metrics"
+)
+
+// SetAgentMetrics exposes the metrics agents stream through agentMetrics.
+// Without it, streamed metrics are only logged by the hub.
+func (s *Server) SetAgentMetrics(agentMetrics *metrics.AgentMetrics) {
+	s.agentMetrics = agentMetrics
+}
+
+// StreamMetrics handles metrics streaming from agents
+func (s *Server) StreamMetrics(stream grpc.ClientStreamingServer[agentv1.MetricEntry, agentv1.MetricStreamResponse]) error {
+	clusterID, err := s.authenticateStream(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	for {
+		metricEntry, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return stream.SendAndClose(&agentv1.MetricStreamResponse{Success: true, Message: "Metrics received"})
+			}
+			s.logger.Error("Failed to receive metric entry", zap.Error(err))
+			return err
+		}
+
+		s.logger.Debug("Metric received from agent",
+			zap.String("cluster_id", clusterID.String()),
+			zap.String("name", metricEntry.Name),
+			zap.Float64("value", metricEntry.Value),
+		)
+		if s.agentMetrics == nil {
+			continue
+		}
+		if err := s.agentMetrics.Record(clusterID.String(), metricEntry.Name, metricEntry.Value); err != nil {
+			s.logger.Debug("Dropped agent metric",
+				zap.String("cluster_id", clusterID.String()),
+				zap.String("name", metricEntry.Name),
+				zap.Error(err),
+			)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/metrics"
+)
+
+// testMetricStream is a metric stream sending preset entries
+type testMetricStream struct {
+	grpc.ClientStreamingServer[agentv1.MetricEntry, agentv1.MetricStreamResponse]
+	ctx      context.Context
+	entries  []*agentv1.MetricEntry
+	response *agentv1.MetricStreamResponse
+}
+
+func newTestMetricStream(clusterID uuid.UUID, token string, entries ...*agentv1.MetricEntry) *testMetricStream {
+	md := metadata.Pairs(clusterIDMetadataKey, clusterID.String(), sessionTokenMetadataKey, token)
+	return &testMetricStream{ctx: metadata.NewIncomingContext(context.Background(), md), entries: entries}
+}
+
+func (s *testMetricStream) Context() context.Context { return s.ctx }
+
+func (s *testMetricStream) Recv() (*agentv1.MetricEntry, error) {
+	if len(s.entries) == 0 {
+		return nil, io.EOF
+	}
+	entry := s.entries[0]
+	s.entries = s.entries[1:]
+	return entry, nil
+}
+
+func (s *testMetricStream) SendAndClose(response *agentv1.MetricStreamResponse) error {
+	s.response = response
+	return nil
+}
+
+func TestServer_StreamMetricsRecordsAllowedMetrics(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	registry := prometheus.NewRegistry()
+	server.SetAgentMetrics(metrics.NewAgentMetrics(registry, []string{"agent_"}, 10))
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now())
+
+	stream := newTestMetricStream(clusterID, testSessionToken,
+		&agentv1.MetricEntry{Name: "agent_goroutines", Value: 12},
+		&agentv1.MetricEntry{Name: "agent_goroutines", Value: 15},
+		&agentv1.MetricEntry{Name: "node_cpu_seconds", Value: 3},
+	)
+	if err := server.StreamMetrics(stream); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if stream.response == nil || !stream.response.Success {
+		t.Errorf("Expected a successful response but got %v", stream.response)
+	}
+
+	// The latest value is kept and the metric outside the allowlist is dropped
+	expected := `
+# HELP mckmt_cluster_agent_goroutines Agent reported metric agent_goroutines
+# TYPE mckmt_cluster_agent_goroutines gauge
+mckmt_cluster_agent_goroutines{cluster_id="` + clusterID.String() + `",metric="agent_goroutines"} 15
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected metrics: %v", err)
+	}
+
+	// The cluster's metrics go away with its agent
+	server.mu.Lock()
+	server.disconnectAgent(clusterID.String())
+	server.mu.Unlock()
+	if count := testutil.CollectAndCount(registry); count != 0 {
+		t.Errorf("Expected no metrics after the agent disconnected but got %d", count)
+	}
+}
+
+func TestServer_StreamMetricsRequiresSession(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	server.SetAgentMetrics(metrics.NewAgentMetrics(prometheus.NewRegistry(), []string{"agent_"}, 10))
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now())
+
+	err := server.StreamMetrics(newTestMetricStream(clusterID, "invalid"))
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated but got: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"context"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+// ReasonAddressNotAllowed is reported when an agent connects from outside the allowed networks
+const ReasonAddressNotAllowed = "ADDRESS_NOT_ALLOWED"
+
+// forwardedForMetadataKey carries the original client address when agents
+// connect through a proxy, like the X-Forwarded-For HTTP header
+const forwardedForMetadataKey = "x-forwarded-for"
+
+// AllowlistUnaryInterceptor rejects unary calls from agents whose IP isn't in
+// allowlist. The x-forwarded-for header is only honoured from proxies.
+func AllowlistUnaryInterceptor(allowlist *auth.IPAllowlist, proxies *auth.TrustedProxies, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := checkAllowlist(ctx, allowlist, proxies, info.FullMethod, logger); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AllowlistStreamInterceptor rejects streams from agents whose IP isn't in
+// allowlist. The x-forwarded-for header is only honoured from proxies.
+func AllowlistStreamInterceptor(allowlist *auth.IPAllowlist, proxies *auth.TrustedProxies, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := checkAllowlist(stream.Context(), allowlist, proxies, info.FullMethod, logger); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// checkAllowlist returns a PermissionDenied error unless the caller's IP is allowed
+func checkAllowlist(ctx context.Context, allowlist *auth.IPAllowlist, proxies *auth.TrustedProxies, method string, logger *zap.Logger) error {
+	if !allowlist.Enabled() {
+		return nil
+	}
+
+	ip := callerIP(ctx, proxies)
+	if allowlist.Allows(ip) {
+		return nil
+	}
+
+	logger.Warn("Agent call from disallowed address", zap.String("ip", ip), zap.String("method", method))
+	return permanentError(codes.PermissionDenied, ReasonAddressNotAllowed, "access from this address is not allowed")
+}
+
+// callerIP returns the caller's IP: the connection's peer address, or the
+// client a trusted proxy reports in an x-forwarded-for header
+func callerIP(ctx context.Context, proxies *auth.TrustedProxies) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	peerIP, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		peerIP = p.Addr.String()
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	return proxies.ClientIP(peerIP, md.Get(forwardedForMetadataKey))
+}
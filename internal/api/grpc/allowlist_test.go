@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+func TestAllowlistUnaryInterceptor(t *testing.T) {
+	allowlist, err := auth.NewIPAllowlist([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	proxies, err := auth.NewTrustedProxies([]string{"203.0.113.1", "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	interceptor := AllowlistUnaryInterceptor(allowlist, proxies, zap.NewNop())
+	info := &grpc.UnaryServerInfo{FullMethod: "/mckmt.agent.v1.AgentService/Register"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	tests := []struct {
+		name         string
+		peerAddr     string
+		forwardedFor string
+		expectedCode codes.Code
+	}{
+		{name: "allowed agent", peerAddr: "10.1.2.3", expectedCode: codes.OK},
+		{name: "blocked agent", peerAddr: "203.0.113.7", expectedCode: codes.PermissionDenied},
+		{name: "allowed agent behind a proxy", peerAddr: "203.0.113.1", forwardedFor: "10.1.2.3", expectedCode: codes.OK},
+		{name: "blocked agent behind a proxy", peerAddr: "10.0.0.1", forwardedFor: "203.0.113.7, 10.0.0.1", expectedCode: codes.PermissionDenied},
+		{name: "spoofed header from an untrusted peer", peerAddr: "203.0.113.7", forwardedFor: "10.1.2.3", expectedCode: codes.PermissionDenied},
+		{name: "spoofed hop relayed by a proxy", peerAddr: "203.0.113.1", forwardedFor: "10.1.2.3, 203.0.113.7", expectedCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tt.peerAddr), Port: 52000}})
+			if tt.forwardedFor != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", tt.forwardedFor))
+			}
+
+			_, err := interceptor(ctx, nil, info, handler)
+			if code := status.Code(err); code != tt.expectedCode {
+				t.Errorf("Expected code %s but got %s", tt.expectedCode, code)
+			}
+		})
+	}
+}
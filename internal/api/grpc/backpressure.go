@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"time"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+)
+
+// QueueMonitor reports how many operations are waiting in the hub's queue
+type QueueMonitor interface {
+	QueueDepth() int
+}
+
+// backpressure decides when agents are told to slow down
+type backpressure struct {
+	queue     QueueMonitor
+	threshold int
+	interval  time.Duration
+}
+
+// SetBackpressure makes heartbeat responses flag the hub as busy once the
+// operation queue holds at least threshold operations, suggesting agents
+// heartbeat every interval until it drains. A zero threshold disables it.
+func (s *Server) SetBackpressure(queue QueueMonitor, threshold int, interval time.Duration) {
+	if queue == nil || threshold <= 0 {
+		s.backpressure = nil
+		return
+	}
+	s.backpressure = &backpressure{queue: queue, threshold: threshold, interval: interval}
+}
+
+// applyBackpressure flags resp as busy when the queue is saturated
+func (s *Server) applyBackpressure(resp *agentv1.HeartbeatResponse) {
+	if s.backpressure == nil || s.backpressure.queue.QueueDepth() < s.backpressure.threshold {
+		return
+	}
+	resp.Busy = true
+	resp.SuggestedInterval = int64(s.backpressure.interval / time.Second)
+}
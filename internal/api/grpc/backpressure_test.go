@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+)
+
+// fixedQueue reports a constant queue depth
+type fixedQueue int
+
+func (q fixedQueue) QueueDepth() int { return int(q) }
+
+func TestServer_HeartbeatSignalsBackpressure(t *testing.T) {
+	tests := []struct {
+		name         string
+		depth        int
+		wantBusy     bool
+		wantInterval int64
+	}{
+		{name: "queue below threshold", depth: 10, wantBusy: false, wantInterval: 0},
+		{name: "queue saturated", depth: 500, wantBusy: true, wantInterval: 120},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, clusters, _ := newTestServer(t)
+			server.SetBackpressure(fixedQueue(tt.depth), 100, 2*time.Minute)
+
+			clusterID := uuid.New()
+			connectTestAgent(server, clusterID, time.Now())
+			clusters.EXPECT().UpdateLastSeen(gomock.Any(), clusterID).Return(nil)
+
+			resp, err := server.Heartbeat(context.Background(), &agentv1.HeartbeatRequest{ClusterId: clusterID.String(), SessionToken: testSessionToken})
+			if err != nil {
+				t.Fatalf("Heartbeat failed: %v", err)
+			}
+			if resp.Busy != tt.wantBusy {
+				t.Errorf("Expected busy %v, got %v", tt.wantBusy, resp.Busy)
+			}
+			if resp.SuggestedInterval != tt.wantInterval {
+				t.Errorf("Expected suggested interval %d, got %d", tt.wantInterval, resp.SuggestedInterval)
+			}
+		})
+	}
+}
+
+func TestServer_BackpressureDisabled(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	server.SetBackpressure(fixedQueue(1000), 0, time.Minute)
+
+	resp := &agentv1.HeartbeatResponse{Success: true}
+	server.applyBackpressure(resp)
+	if resp.Busy {
+		t.Errorf("Expected no backpressure with a zero threshold")
+	}
+}
@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// payloadToAny converts an operation payload into a google.protobuf.Any wrapping a Struct.
+// The payload is normalized through JSON first so that typed values (e.g. map[string]string)
+// are accepted by structpb.
+func payloadToAny(payload map[string]interface{}) (*anypb.Any, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("failed to normalize payload: %w", err)
+	}
+
+	st, err := structpb.NewStruct(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert payload: %w", err)
+	}
+
+	return anypb.New(st)
+}
+
+// anyToMap decodes a google.protobuf.Any wrapping a Struct into a map
+func anyToMap(value *anypb.Any) (map[string]interface{}, error) {
+	if value == nil || len(value.GetValue()) == 0 {
+		return nil, nil
+	}
+
+	var st structpb.Struct
+	if err := value.UnmarshalTo(&st); err != nil {
+		return nil, fmt.Errorf("failed to decode result: %w", err)
+	}
+
+	return st.AsMap(), nil
+}
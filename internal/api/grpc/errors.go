@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is the ErrorInfo domain of errors returned to agents
+const errorDomain = "mckmt.agent.v1"
+
+// retryableMetadataKey is the ErrorInfo metadata key telling agents whether
+// repeating the call may succeed, "true" or "false"
+const retryableMetadataKey = "retryable"
+
+// defaultRetryDelay is the RetryInfo delay suggested for retryable errors
+const defaultRetryDelay = time.Second
+
+// Reasons reported in the ErrorInfo of errors returned to agents
+const (
+	ReasonClusterNameRequired     = "CLUSTER_NAME_REQUIRED"
+	ReasonAgentVersionUnsupported = "AGENT_VERSION_UNSUPPORTED"
+	ReasonClusterStoreFailed      = "CLUSTER_STORE_FAILED"
+	ReasonAgentNotRegistered      = "AGENT_NOT_REGISTERED"
+	ReasonInvalidClusterID        = "INVALID_CLUSTER_ID"
+	ReasonInvalidOperationID      = "INVALID_OPERATION_ID"
+	ReasonOperationNotFound       = "OPERATION_NOT_FOUND"
+	ReasonOperationFinished       = "OPERATION_FINISHED"
+	ReasonOutputStoreFailed       = "OUTPUT_STORE_FAILED"
+	ReasonOperationNotCancellable = "OPERATION_NOT_CANCELLABLE"
+	ReasonOperationCancelFailed   = "OPERATION_CANCEL_FAILED"
+	ReasonDuplicateRegistration   = "DUPLICATE_REGISTRATION"
+	ReasonTooManyRegistrations    = "TOO_MANY_REGISTRATIONS"
+	ReasonInvalidSessionToken     = "INVALID_SESSION_TOKEN"
+	ReasonOperationNotOwned       = "OPERATION_NOT_OWNED"
+)
+
+// permanentError returns a gRPC error that repeating the call won't fix
+func permanentError(code codes.Code, reason, message string) error {
+	return statusError(code, reason, message, false)
+}
+
+// retryableError returns a gRPC error for a transient failure; agents may
+// repeat the call after the suggested delay
+func retryableError(code codes.Code, reason, message string) error {
+	return statusError(code, reason, message, true)
+}
+
+// statusError builds a gRPC status carrying an ErrorInfo with the reason and
+// retryability and, for retryable errors, a RetryInfo with the suggested delay
+func statusError(code codes.Code, reason, message string, retryable bool) error {
+	st := status.New(code, message)
+
+	info := &errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   errorDomain,
+		Metadata: map[string]string{retryableMetadataKey: "false"},
+	}
+	details := []protoadapt.MessageV1{info}
+	if retryable {
+		info.Metadata[retryableMetadataKey] = "true"
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(defaultRetryDelay)})
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		// Details only fail to attach if they can't be marshalled; the plain
+		// status still carries the code and message
+		return st.Err()
+	}
+	return withDetails.Err()
+}
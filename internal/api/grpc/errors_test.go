@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+)
+
+// errorDetails returns the ErrorInfo and RetryInfo attached to a gRPC error
+func errorDetails(t *testing.T, err error) (*errdetails.ErrorInfo, *errdetails.RetryInfo) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("Expected a gRPC status error but got: %v", err)
+	}
+
+	var info *errdetails.ErrorInfo
+	var retry *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.RetryInfo:
+			retry = d
+		}
+	}
+	if info == nil {
+		t.Fatalf("Expected an ErrorInfo detail in %v", st.Details())
+	}
+	return info, retry
+}
+
+func TestStatusErrorDetails(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		code      codes.Code
+		reason    string
+		retryable string
+	}{
+		{
+			name:      "permanent",
+			err:       permanentError(codes.InvalidArgument, ReasonInvalidClusterID, "Invalid cluster ID"),
+			code:      codes.InvalidArgument,
+			reason:    ReasonInvalidClusterID,
+			retryable: "false",
+		},
+		{
+			name:      "retryable",
+			err:       retryableError(codes.Internal, ReasonClusterStoreFailed, "Failed to update cluster"),
+			code:      codes.Internal,
+			reason:    ReasonClusterStoreFailed,
+			retryable: "true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.err); code != tt.code {
+				t.Errorf("Expected code %v but got %v", tt.code, code)
+			}
+
+			info, retry := errorDetails(t, tt.err)
+			if info.Reason != tt.reason || info.Domain != errorDomain {
+				t.Errorf("Expected reason %s in domain %s but got %s in %s", tt.reason, errorDomain, info.Reason, info.Domain)
+			}
+			if got := info.Metadata[retryableMetadataKey]; got != tt.retryable {
+				t.Errorf("Expected retryable %q but got %q", tt.retryable, got)
+			}
+			if (retry != nil) != (tt.retryable == "true") {
+				t.Errorf("Expected a RetryInfo only for retryable errors, got %v", retry)
+			}
+		})
+	}
+}
+
+func TestServer_HeartbeatUnregisteredAgentIsPermanent(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	_, err := server.Heartbeat(context.Background(), &agentv1.HeartbeatRequest{ClusterId: uuid.New().String()})
+	info, _ := errorDetails(t, err)
+	if info.Reason != ReasonAgentNotRegistered || info.Metadata[retryableMetadataKey] != "false" {
+		t.Errorf("Expected a permanent %s error but got %+v", ReasonAgentNotRegistered, info)
+	}
+}
@@ -0,0 +1,26 @@
+package grpc
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// SetEventBus sets the bus operation failures and cluster disconnects are
+// published on, e.g. for notifications
+func (s *Server) SetEventBus(bus repo.EventBus) {
+	s.events = bus
+}
+
+// publish sends an event on the event bus, if one is set. Failures are only
+// logged since events are informational.
+func (s *Server) publish(ctx context.Context, topic string, event interface{}) {
+	if s.events == nil {
+		return
+	}
+	if err := s.events.Publish(ctx, topic, event); err != nil {
+		s.logger.Warn("Failed to publish event", zap.String("topic", topic), zap.Error(err))
+	}
+}
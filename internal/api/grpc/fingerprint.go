@@ -0,0 +1,34 @@
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// legacyFingerprint is the placeholder older agents send instead of a cluster
+// fingerprint; it identifies nothing, so it is neither recorded nor checked
+const legacyFingerprint = "agent-fingerprint"
+
+// checkFingerprint rejects an agent registering an existing cluster with a
+// fingerprint other than the one recorded for it. Without it a second cluster
+// using the same name would take over the cluster's ID and operations.
+// Clusters without a recorded fingerprint adopt the first one presented.
+func checkFingerprint(cluster *repo.Cluster, fingerprint string) error {
+	recorded := cluster.Labels[repo.AgentFingerprintLabel]
+	if recorded == "" || recorded == fingerprint {
+		return nil
+	}
+	return fmt.Errorf("cluster name %q is already registered by another cluster; choose another name or delete the existing cluster", cluster.Name)
+}
+
+// recordFingerprint records the fingerprint of the agent that registered cluster
+func recordFingerprint(cluster *repo.Cluster, fingerprint string) {
+	if fingerprint == "" || fingerprint == legacyFingerprint {
+		return
+	}
+	if cluster.Labels == nil {
+		cluster.Labels = make(repo.Labels)
+	}
+	cluster.Labels[repo.AgentFingerprintLabel] = fingerprint
+}
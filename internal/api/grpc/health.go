@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// clusterHealthTTL bounds how long a reported cluster health is trusted; a
+// cluster whose agent stopped reporting has no known health after it
+const clusterHealthTTL = 5 * time.Minute
+
+// SetHealthCache sets the cache the cluster health reported in heartbeats is
+// stored in, where operation preconditions are checked against it
+func (s *Server) SetHealthCache(cache repo.Cache) {
+	s.healthCache = cache
+}
+
+// storeClusterHealth records the health an agent reported in a heartbeat
+func (s *Server) storeClusterHealth(ctx context.Context, clusterID string, status *agentv1.ClusterStatus, reportedAt time.Time) {
+	if s.healthCache == nil || status == nil {
+		return
+	}
+
+	health := repo.ClusterHealth{
+		Status:     status.Status,
+		ReadyNodes: int(status.ReadyNodes),
+		TotalNodes: int(status.TotalNodes),
+		Issues:     status.Issues,
+		ReportedAt: reportedAt,
+	}
+	if err := s.healthCache.Set(ctx, s.healthCache.ClusterStatusKey(clusterID), health, clusterHealthTTL); err != nil {
+		s.logger.Warn("Failed to store cluster health", zap.String("cluster_id", clusterID), zap.Error(err))
+	}
+}
@@ -0,0 +1,27 @@
+package grpc
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/rizesky/mckmt/internal/config"
+)
+
+// KeepaliveServerOptions returns the server options enforcing transport
+// keepalive. The hub pings agents idle for cfg.Time and closes connections
+// whose ping isn't acknowledged within cfg.Timeout, so half-open connections
+// of dead agents end their operation stream well before the heartbeat sweeper
+// would notice. Agents pinging more often than cfg.MinTime are disconnected.
+func KeepaliveServerOptions(cfg config.GRPCKeepaliveConfig) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:              cfg.Time,
+			Timeout:           cfg.Timeout,
+			MaxConnectionIdle: cfg.MaxConnectionIdle,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.MinTime,
+			PermitWithoutStream: cfg.PermitWithoutStream,
+		}),
+	}
+}
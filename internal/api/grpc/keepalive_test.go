@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/config"
+)
+
+func TestKeepaliveServerOptionsAreApplied(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	// An idle limit is the quickest keepalive setting to observe: the server
+	// closes the connection once no RPC has been active for it
+	grpcServer := grpc.NewServer(KeepaliveServerOptions(config.GRPCKeepaliveConfig{
+		Time:                time.Minute,
+		Timeout:             10 * time.Second,
+		MaxConnectionIdle:   100 * time.Millisecond,
+		MinTime:             5 * time.Second,
+		PermitWithoutStream: true,
+	})...)
+	agentv1.RegisterAgentServiceServer(grpcServer, server)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	defer conn.Close()
+
+	// The heartbeat of an unregistered agent fails, but opens the connection
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	agentv1.NewAgentServiceClient(conn).Heartbeat(ctx, &agentv1.HeartbeatRequest{ClusterId: "unknown"})
+	if state := conn.GetState(); state != connectivity.Ready {
+		t.Fatalf("Expected the connection to be ready but got %s", state)
+	}
+
+	for state := conn.GetState(); state != connectivity.Idle; state = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, state) {
+			t.Fatalf("Expected the server to close the idle connection, still %s", state)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// Defaults of the log batching when SetLogRepository is given none
+const (
+	defaultLogBatchSize     = 100
+	defaultLogFlushInterval = 2 * time.Second
+)
+
+// logStore batches the logs agents stream into the log repository
+type logStore struct {
+	repo          repo.LogRepository
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// SetLogRepository stores the logs agents stream in logs. Each stream buffers
+// its entries and writes them in batches of up to batchSize, at least every
+// flushInterval, so chatty agents don't cost a database write per line.
+// Without a repository, streamed logs are only logged by the hub.
+func (s *Server) SetLogRepository(logs repo.LogRepository, batchSize int, flushInterval time.Duration) {
+	if logs == nil {
+		s.logs = nil
+		return
+	}
+	if batchSize <= 0 {
+		batchSize = defaultLogBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = defaultLogFlushInterval
+	}
+	s.logs = &logStore{repo: logs, batchSize: batchSize, flushInterval: flushInterval}
+}
+
+// StreamLogs handles log streaming from agents
+func (s *Server) StreamLogs(stream grpc.ClientStreamingServer[agentv1.LogEntry, agentv1.LogStreamResponse]) error {
+	clusterID, err := s.authenticateStream(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	// Receive in the background so buffered entries are flushed on time even
+	// while the agent sends nothing
+	received := make(chan *agentv1.LogEntry)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			entry, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case received <- entry:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	var flushes <-chan time.Time
+	if s.logs != nil {
+		ticker := time.NewTicker(s.logs.flushInterval)
+		defer ticker.Stop()
+		flushes = ticker.C
+	}
+
+	var buffer []*repo.LogEntry
+	for {
+		select {
+		case entry := <-received:
+			s.logger.Debug("Log received from agent",
+				zap.String("cluster_id", clusterID.String()),
+				zap.String("level", entry.Level),
+				zap.String("message", entry.Message),
+				zap.String("source", entry.Source),
+			)
+			if s.logs == nil {
+				continue
+			}
+			buffer = append(buffer, logEntryFromProto(clusterID, entry))
+			if len(buffer) >= s.logs.batchSize {
+				buffer = s.flushLogs(stream.Context(), buffer)
+			}
+
+		case <-flushes:
+			buffer = s.flushLogs(stream.Context(), buffer)
+
+		case <-stream.Context().Done():
+			// The receiver gives up without reporting once the stream is
+			// cancelled; keep what the agent sent before
+			s.flushLogs(context.WithoutCancel(stream.Context()), buffer)
+			return status.FromContextError(stream.Context().Err()).Err()
+
+		case err := <-recvErr:
+			// Keep what the agent sent before the stream ended, even if the
+			// stream was cancelled
+			s.flushLogs(context.WithoutCancel(stream.Context()), buffer)
+			if errors.Is(err, io.EOF) {
+				return stream.SendAndClose(&agentv1.LogStreamResponse{Success: true, Message: "Logs received"})
+			}
+			s.logger.Error("Failed to receive log entry", zap.Error(err))
+			return err
+		}
+	}
+}
+
+// flushLogs writes the buffered entries, returning the emptied buffer. Logs
+// are best effort: a batch that fails to be written is dropped.
+func (s *Server) flushLogs(ctx context.Context, buffer []*repo.LogEntry) []*repo.LogEntry {
+	if len(buffer) == 0 {
+		return buffer
+	}
+	if err := s.logs.repo.CreateBatch(ctx, buffer); err != nil {
+		s.logger.Error("Failed to store agent logs",
+			zap.String("cluster_id", buffer[0].ClusterID.String()),
+			zap.Int("entries", len(buffer)),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// logEntryFromProto converts a streamed log entry of the agent of clusterID
+func logEntryFromProto(clusterID uuid.UUID, entry *agentv1.LogEntry) *repo.LogEntry {
+	timestamp := time.Now().UTC()
+	if entry.Timestamp != nil {
+		timestamp = entry.Timestamp.AsTime()
+	}
+	return &repo.LogEntry{
+		ID:        uuid.New(),
+		ClusterID: clusterID,
+		Level:     strings.ToLower(entry.Level),
+		Message:   entry.Message,
+		Source:    entry.Source,
+		Fields:    entry.Fields,
+		Timestamp: timestamp,
+	}
+}
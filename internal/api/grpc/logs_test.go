@@ -0,0 +1,182 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+// testLogStream is a log stream receiving entries from a channel; closing
+// the channel ends the stream like an agent closing its side
+type testLogStream struct {
+	grpc.ClientStreamingServer[agentv1.LogEntry, agentv1.LogStreamResponse]
+	ctx      context.Context
+	entries  chan *agentv1.LogEntry
+	response *agentv1.LogStreamResponse
+}
+
+func newTestLogStream(clusterID uuid.UUID, token string) *testLogStream {
+	md := metadata.Pairs(clusterIDMetadataKey, clusterID.String(), sessionTokenMetadataKey, token)
+	return &testLogStream{
+		ctx:     metadata.NewIncomingContext(context.Background(), md),
+		entries: make(chan *agentv1.LogEntry, 10),
+	}
+}
+
+func (s *testLogStream) Context() context.Context { return s.ctx }
+
+func (s *testLogStream) Recv() (*agentv1.LogEntry, error) {
+	entry, ok := <-s.entries
+	if !ok {
+		return nil, io.EOF
+	}
+	return entry, nil
+}
+
+func (s *testLogStream) SendAndClose(response *agentv1.LogStreamResponse) error {
+	s.response = response
+	return nil
+}
+
+func TestServer_StreamLogsStoresEntriesInBatches(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	logs := mocks.NewMockLogRepository(gomock.NewController(t))
+	server.SetLogRepository(logs, 2, time.Hour)
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now())
+
+	var batches [][]*repo.LogEntry
+	logs.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entries []*repo.LogEntry) error {
+		batches = append(batches, entries)
+		return nil
+	}).Times(3)
+
+	stream := newTestLogStream(clusterID, testSessionToken)
+	sentAt := time.Now().Add(-time.Minute).UTC()
+	for _, message := range []string{"one", "two", "three", "four", "five"} {
+		stream.entries <- &agentv1.LogEntry{Level: "INFO", Message: message, Source: "agent", Timestamp: timestamppb.New(sentAt)}
+	}
+	close(stream.entries)
+
+	if err := server.StreamLogs(stream); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if stream.response == nil || !stream.response.Success {
+		t.Errorf("Expected a successful response but got %v", stream.response)
+	}
+
+	// The last partial batch is written when the stream ends
+	if len(batches) != 3 || len(batches[0]) != 2 || len(batches[1]) != 2 || len(batches[2]) != 1 {
+		t.Fatalf("Expected batches of 2, 2 and 1 entries but got %v", batches)
+	}
+	entry := batches[2][0]
+	if entry.ClusterID != clusterID || entry.Level != "info" || entry.Message != "five" || !entry.Timestamp.Equal(sentAt) {
+		t.Errorf("Expected the entry to be stored as sent by the agent but got %+v", entry)
+	}
+}
+
+func TestServer_StreamLogsFlushesOnInterval(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	logs := mocks.NewMockLogRepository(gomock.NewController(t))
+	server.SetLogRepository(logs, 100, 20*time.Millisecond)
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now())
+
+	flushed := make(chan int, 1)
+	logs.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entries []*repo.LogEntry) error {
+		flushed <- len(entries)
+		return nil
+	})
+
+	stream := newTestLogStream(clusterID, testSessionToken)
+	stream.entries <- &agentv1.LogEntry{Level: "warn", Message: "disk pressure"}
+	done := make(chan error, 1)
+	go func() { done <- server.StreamLogs(stream) }()
+
+	// The entry is written while the stream stays open
+	select {
+	case n := <-flushed:
+		if n != 1 {
+			t.Errorf("Expected 1 entry to be flushed but got %d", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the buffered entry to be flushed on the interval")
+	}
+
+	close(stream.entries)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
+
+func TestServer_StreamLogsStopsWhenCancelled(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	logs := mocks.NewMockLogRepository(gomock.NewController(t))
+	server.SetLogRepository(logs, 100, time.Hour)
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now())
+
+	var flushed []*repo.LogEntry
+	logs.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, entries []*repo.LogEntry) error {
+		if ctx.Err() != nil {
+			t.Errorf("Expected the flush to outlive the stream but got: %v", ctx.Err())
+		}
+		flushed = entries
+		return nil
+	})
+
+	// The stream is cancelled while the agent keeps it open
+	stream := newTestLogStream(clusterID, testSessionToken)
+	stream.entries = make(chan *agentv1.LogEntry)
+	defer close(stream.entries)
+	ctx, cancel := context.WithCancel(stream.ctx)
+	stream.ctx = ctx
+	done := make(chan error, 1)
+	go func() { done <- server.StreamLogs(stream) }()
+
+	// Once the second entry is taken, the first one was handed over
+	stream.entries <- &agentv1.LogEntry{Level: "info", Message: "one"}
+	stream.entries <- &agentv1.LogEntry{Level: "info", Message: "two"}
+	cancel()
+
+	select {
+	case err := <-done:
+		if code := status.Code(err); code != codes.Canceled {
+			t.Errorf("Expected code %s but got %s", codes.Canceled, code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the stream to stop once cancelled")
+	}
+	if len(flushed) == 0 || flushed[0].Message != "one" {
+		t.Errorf("Expected the received entries to be flushed but got %v", flushed)
+	}
+}
+
+func TestServer_StreamLogsRequiresSession(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	server.SetLogRepository(mocks.NewMockLogRepository(gomock.NewController(t)), 10, time.Hour)
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now())
+
+	err := server.StreamLogs(newTestLogStream(clusterID, "invalid"))
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated but got: %v", err)
+	}
+}
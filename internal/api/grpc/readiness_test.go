@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+func TestServer_HoldsOperationsUntilAgentReady(t *testing.T) {
+	server, clusters, _ := newTestServer(t)
+	ctx := context.Background()
+
+	cluster := &repo.Cluster{ID: uuid.New(), Name: "test-cluster", Status: "connected"}
+	clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).Return(cluster, nil)
+	clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil)
+	clusters.EXPECT().Update(gomock.Any(), cluster).Return(nil)
+	clusters.EXPECT().UpdateLastSeen(gomock.Any(), cluster.ID).Return(nil).Times(2)
+
+	registration, err := server.Register(ctx, &agentv1.RegisterRequest{
+		ClusterName:  cluster.Name,
+		AgentVersion: "test",
+		Capabilities: &agentv1.AgentCapabilities{Features: []string{repo.FeatureReadiness}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	connection := server.agents[cluster.ID.String()]
+
+	operation := &Operation{ID: uuid.New().String(), ClusterID: cluster.ID.String(), Type: "apply"}
+	if err := server.QueueOperation(cluster.ID.String(), operation); err != nil {
+		t.Fatalf("Expected the operation to be held but got: %v", err)
+	}
+
+	heartbeat := func(ready bool) {
+		t.Helper()
+		if _, err := server.Heartbeat(ctx, &agentv1.HeartbeatRequest{ClusterId: cluster.ID.String(), SessionToken: registration.SessionToken, Ready: ready}); err != nil {
+			t.Fatalf("Heartbeat failed: %v", err)
+		}
+	}
+
+	// Heartbeats of an agent still warming up don't release the operation
+	heartbeat(false)
+	if len(connection.Stream) != 0 {
+		t.Fatalf("Expected the operation to be held until the agent is ready")
+	}
+
+	heartbeat(true)
+	select {
+	case sent := <-connection.Stream:
+		if sent.ID != operation.ID {
+			t.Errorf("Expected operation %s to be sent, got %s", operation.ID, sent.ID)
+		}
+	default:
+		t.Fatalf("Expected the held operation to be sent once the agent is ready")
+	}
+}
+
+func TestServer_AgentWithoutReadinessIsReadyOnRegister(t *testing.T) {
+	server, clusters, _ := newTestServer(t)
+
+	cluster := &repo.Cluster{ID: uuid.New(), Name: "test-cluster", Status: "connected"}
+	clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).Return(cluster, nil)
+	clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil)
+	clusters.EXPECT().Update(gomock.Any(), cluster).Return(nil)
+
+	if _, err := server.Register(context.Background(), &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "test"}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	operation := &Operation{ID: uuid.New().String(), ClusterID: cluster.ID.String(), Type: "apply"}
+	if err := server.QueueOperation(cluster.ID.String(), operation); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(server.agents[cluster.ID.String()].Stream) != 1 {
+		t.Errorf("Expected the operation to be sent right away")
+	}
+}
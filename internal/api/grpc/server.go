@@ -3,6 +3,7 @@ package grpc
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -10,6 +11,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/util/version"
 
 	"github.com/google/uuid"
 	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
@@ -22,17 +24,56 @@ type Server struct {
 	agentv1.UnimplementedAgentServiceServer
 	clusters   repo.ClusterRepository
 	operations repo.OperationRepository
+	outputs    repo.OperationOutputRepository
 	metrics    *metrics.Metrics
 	logger     *zap.Logger
-	agents     map[string]*AgentConnection // cluster_id -> connection
+
+	// mu guards the connection maps and the connections in them, which RPC
+	// handlers, the hub and the heartbeat sweeper access concurrently
+	mu        sync.RWMutex
+	agents    map[string]*AgentConnection // cluster_id -> connection
+	redeliver map[string][]*Operation     // cluster_id -> operations to resend on reconnect
+	held      map[string]*heldOperations  // cluster_id -> running operations of a disconnected agent
+
+	clockSkewThreshold time.Duration
+
+	// minAgentVersion is the oldest agent version allowed to register, if any
+	minAgentVersion *version.Version
+
+	// backpressure slows agents down while the hub is overloaded, if set
+	backpressure *backpressure
+
+	// healthCache stores the cluster health reported in heartbeats, if set
+	healthCache repo.Cache
+
+	// events receives operation failures and cluster disconnects, if set
+	events repo.EventBus
+
+	// registrations bounds concurrent agent registrations, if set
+	registrations *registrationLimit
+
+	// logs stores the logs agents stream, if set
+	logs *logStore
+
+	// agentMetrics exposes the metrics agents stream, if set
+	agentMetrics *metrics.AgentMetrics
 }
 
+// defaultClockSkewThreshold is the agent clock skew above which a warning is logged
+const defaultClockSkewThreshold = 30 * time.Second
+
 // AgentConnection represents a connected agent
 type AgentConnection struct {
 	ClusterID     string
 	AgentVersion  string
 	LastHeartbeat time.Time
 	Stream        chan *Operation
+	// SessionToken is issued at registration; the agent's later calls must
+	// present it
+	SessionToken string
+	// Ready is false until an agent that gates on readiness reports it passed
+	// its readiness check; operations for it are held until then
+	Ready bool
 }
 
 // Operation represents a task for the agent
@@ -43,16 +84,29 @@ type Operation struct {
 	Payload   map[string]interface{} `json:"payload"`
 	CreatedAt time.Time              `json:"created_at"`
 	Timeout   int32                  `json:"timeout_seconds"`
+	Cancel    bool                   `json:"cancel,omitempty"` // stop the running operation with this ID
 }
 
 // NewServer creates a new gRPC server
-func NewServer(clusters repo.ClusterRepository, operations repo.OperationRepository, metrics *metrics.Metrics, logger *zap.Logger) *Server {
+func NewServer(clusters repo.ClusterRepository, operations repo.OperationRepository, outputs repo.OperationOutputRepository, metrics *metrics.Metrics, logger *zap.Logger) *Server {
 	return &Server{
 		clusters:   clusters,
 		operations: operations,
+		outputs:    outputs,
 		metrics:    metrics,
 		logger:     logger,
 		agents:     make(map[string]*AgentConnection),
+		redeliver:  make(map[string][]*Operation),
+		held:       make(map[string]*heldOperations),
+
+		clockSkewThreshold: defaultClockSkewThreshold,
+	}
+}
+
+// SetClockSkewThreshold sets the agent clock skew above which a warning is logged
+func (s *Server) SetClockSkewThreshold(threshold time.Duration) {
+	if threshold > 0 {
+		s.clockSkewThreshold = threshold
 	}
 }
 
@@ -69,9 +123,32 @@ func (s *Server) Register(ctx context.Context, req *agentv1.RegisterRequest) (*a
 		return &agentv1.RegisterResponse{
 			Success: false,
 			Message: "Cluster name is required",
-		}, status.Error(codes.InvalidArgument, "Cluster name is required")
+		}, permanentError(codes.InvalidArgument, ReasonClusterNameRequired, "Cluster name is required")
+	}
+
+	// Reject agents the hub is no longer compatible with
+	if err := s.checkAgentVersion(req.AgentVersion); err != nil {
+		s.logger.Warn("Rejecting outdated agent",
+			zap.String("cluster_name", req.ClusterName),
+			zap.String("agent_version", req.AgentVersion),
+			zap.Error(err),
+		)
+		return &agentv1.RegisterResponse{
+			Success: false,
+			Message: err.Error(),
+		}, permanentError(codes.FailedPrecondition, ReasonAgentVersionUnsupported, err.Error())
 	}
 
+	// Shed registrations beyond the concurrency limit so agents back off
+	release, err := s.admitRegistration(ctx, req.ClusterName)
+	if err != nil {
+		return &agentv1.RegisterResponse{
+			Success: false,
+			Message: "Too many agent registrations in progress, retry later",
+		}, err
+	}
+	defer release()
+
 	// Check if cluster with this name already exists
 	var clusterID uuid.UUID
 	existingCluster, err := s.clusters.GetByName(ctx, req.ClusterName)
@@ -110,26 +187,45 @@ func (s *Server) Register(ctx context.Context, req *agentv1.RegisterRequest) (*a
 				labels[k] = v
 			}
 		}
+		labels[repo.AgentVersionLabel] = req.AgentVersion
 
 		// Create new cluster
 		cluster = &repo.Cluster{
-			ID:          clusterID,
-			Name:        req.ClusterName, // Use provided cluster name
-			Description: fmt.Sprintf("Cluster managed by agent %s", req.AgentVersion),
-			Labels:      labels,
-			Status:      "connected",
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+			ID:           clusterID,
+			Name:         req.ClusterName, // Use provided cluster name
+			Description:  fmt.Sprintf("Cluster managed by agent %s", req.AgentVersion),
+			Labels:       labels,
+			Capabilities: capabilitiesFromProto(req.Capabilities),
+			Status:       "connected",
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
 		}
 
+		recordFingerprint(cluster, req.Fingerprint)
+
 		if err := s.clusters.Create(ctx, cluster); err != nil {
 			s.logger.Error("Failed to create cluster", zap.Error(err))
 			return &agentv1.RegisterResponse{
 				Success: false,
 				Message: "Failed to create cluster",
-			}, status.Error(codes.Internal, "Failed to create cluster")
+			}, retryableError(codes.Internal, ReasonClusterStoreFailed, "Failed to create cluster")
 		}
 	} else {
+		// Another cluster registering the same name must not take this one over
+		if err := checkFingerprint(cluster, req.Fingerprint); err != nil {
+			s.logger.Warn("Rejecting duplicate cluster registration",
+				zap.String("cluster_name", req.ClusterName),
+				zap.String("cluster_id", clusterID.String()),
+				zap.String("fingerprint", req.Fingerprint),
+				zap.String("registered_fingerprint", cluster.Labels[repo.AgentFingerprintLabel]),
+			)
+			s.metrics.RecordDuplicateRegistration(clusterID.String(), req.ClusterName)
+			return &agentv1.RegisterResponse{
+				Success: false,
+				Message: err.Error(),
+			}, permanentError(codes.AlreadyExists, ReasonDuplicateRegistration, err.Error())
+		}
+
 		// Cluster exists, update it with new info
 		s.logger.Info("Updating existing cluster", zap.String("cluster_id", clusterID.String()))
 
@@ -150,6 +246,14 @@ func (s *Server) Register(ctx context.Context, req *agentv1.RegisterRequest) (*a
 			}
 		}
 
+		// Version and capabilities follow the connecting agent, which may have been upgraded
+		if cluster.Labels == nil {
+			cluster.Labels = make(repo.Labels)
+		}
+		cluster.Labels[repo.AgentVersionLabel] = req.AgentVersion
+		cluster.Capabilities = capabilitiesFromProto(req.Capabilities)
+		recordFingerprint(cluster, req.Fingerprint)
+
 		// Update cluster status and timestamp
 		cluster.Status = "connected"
 		cluster.UpdatedAt = time.Now()
@@ -159,19 +263,31 @@ func (s *Server) Register(ctx context.Context, req *agentv1.RegisterRequest) (*a
 			return &agentv1.RegisterResponse{
 				Success: false,
 				Message: "Failed to update cluster",
-			}, status.Error(codes.Internal, "Failed to update cluster")
+			}, retryableError(codes.Internal, ReasonClusterStoreFailed, "Failed to update cluster")
 		}
 	}
 
 	// Create agent connection
+	// Older agents don't report readiness and are ready as soon as they register
 	connection := &AgentConnection{
 		ClusterID:     clusterID.String(),
 		AgentVersion:  req.AgentVersion,
 		LastHeartbeat: time.Now(),
 		Stream:        make(chan *Operation, 100),
+		SessionToken:  newSessionToken(),
+		Ready:         !capabilitiesFromProto(req.Capabilities).HasFeature(repo.FeatureReadiness),
 	}
+	s.mu.Lock()
 	s.agents[clusterID.String()] = connection
 
+	// Resend operations that were interrupted by a previous disconnect. Running
+	// operations stay held: an agent that restarted has lost track of them, so
+	// those it doesn't report on before the grace period ends are failed.
+	if connection.Ready {
+		s.flushRedeliveries(clusterID.String(), connection)
+	}
+	s.mu.Unlock()
+
 	// Update metrics
 	s.metrics.SetAgentsConnected(clusterID.String(), req.AgentVersion, 1)
 
@@ -185,23 +301,34 @@ func (s *Server) Register(ctx context.Context, req *agentv1.RegisterRequest) (*a
 		Success:           true,
 		Message:           "Registration successful",
 		ClusterId:         cluster.ID.String(),
-		SessionToken:      "session-" + cluster.ID.String(),
+		SessionToken:      connection.SessionToken,
 		HeartbeatInterval: 30,
 	}, nil
 }
 
 // Heartbeat handles agent heartbeats
 func (s *Server) Heartbeat(ctx context.Context, req *agentv1.HeartbeatRequest) (*agentv1.HeartbeatResponse, error) {
-	connection, exists := s.agents[req.ClusterId]
-	if !exists {
+	s.mu.Lock()
+	connection, err := s.authenticate(req.ClusterId, req.SessionToken)
+	if err != nil {
+		s.mu.Unlock()
 		return &agentv1.HeartbeatResponse{
 			Success: false,
-			Message: "Agent not registered",
-		}, status.Error(codes.NotFound, "Agent not registered")
+			Message: status.Convert(err).Message(),
+		}, err
 	}
 
 	// Update heartbeat
-	connection.LastHeartbeat = time.Now()
+	now := time.Now()
+	connection.LastHeartbeat = now
+
+	// Send the operations held while the agent was warming up
+	if req.Ready && !connection.Ready {
+		connection.Ready = true
+		s.logger.Info("Agent is ready", zap.String("cluster_id", req.ClusterId))
+		s.flushRedeliveries(req.ClusterId, connection)
+	}
+	s.mu.Unlock()
 
 	// Update cluster status
 	clusterStatus := "connected"
@@ -215,28 +342,54 @@ func (s *Server) Heartbeat(ctx context.Context, req *agentv1.HeartbeatRequest) (
 		return &agentv1.HeartbeatResponse{
 			Success: false,
 			Message: "Invalid cluster ID",
-		}, status.Error(codes.InvalidArgument, "Invalid cluster ID")
+		}, permanentError(codes.InvalidArgument, ReasonInvalidClusterID, "Invalid cluster ID")
 	}
 
 	if err := s.clusters.UpdateLastSeen(ctx, clusterID); err != nil {
 		s.logger.Error("Failed to update cluster last seen", zap.Error(err))
 	}
+	s.storeClusterHealth(ctx, req.ClusterId, req.Status, now)
 
 	// Update metrics
 	s.metrics.RecordAgentHeartbeat(req.ClusterId, clusterStatus)
-	s.metrics.SetAgentLastHeartbeat(req.ClusterId, float64(time.Now().Unix()))
+	s.metrics.SetAgentLastHeartbeat(req.ClusterId, float64(now.Unix()))
+	if req.AgentTime != nil {
+		s.recordClockSkew(req.ClusterId, req.AgentTime.AsTime(), now)
+	}
 
-	return &agentv1.HeartbeatResponse{
+	resp := &agentv1.HeartbeatResponse{
 		Success: true,
 		Message: "Heartbeat received",
-	}, nil
+	}
+	s.applyBackpressure(resp)
+	return resp, nil
+}
+
+// recordClockSkew records how far the agent clock is behind the hub clock and
+// warns when the difference exceeds the threshold. Network latency makes small
+// positive values normal.
+func (s *Server) recordClockSkew(clusterID string, agentTime, hubTime time.Time) time.Duration {
+	skew := hubTime.Sub(agentTime)
+	s.metrics.SetAgentClockSkew(clusterID, skew.Seconds())
+
+	if skew > s.clockSkewThreshold || skew < -s.clockSkewThreshold {
+		s.logger.Warn("Agent clock skew exceeds threshold",
+			zap.String("cluster_id", clusterID),
+			zap.Duration("skew", skew),
+			zap.Duration("threshold", s.clockSkewThreshold),
+		)
+	}
+
+	return skew
 }
 
 // StreamOperations streams operations to agents
 func (s *Server) StreamOperations(req *agentv1.StreamOperationsRequest, stream grpc.ServerStreamingServer[agentv1.Operation]) error {
-	connection, exists := s.agents[req.ClusterId]
-	if !exists {
-		return status.Error(codes.NotFound, "Agent not registered")
+	s.mu.RLock()
+	connection, err := s.authenticate(req.ClusterId, req.SessionToken)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
 	}
 
 	s.logger.Info("Starting operation stream",
@@ -250,21 +403,39 @@ func (s *Server) StreamOperations(req *agentv1.StreamOperationsRequest, stream g
 			s.logger.Info("Operation stream closed",
 				zap.String("cluster_id", req.ClusterId),
 			)
+			s.expireConnection(req.ClusterId, connection)
 			return nil
 
-		case operation := <-connection.Stream:
+		case operation, ok := <-connection.Stream:
+			// The agent was disconnected, e.g. by the heartbeat sweeper, and
+			// has to register again
+			if !ok {
+				s.logger.Info("Operation stream closed, agent disconnected",
+					zap.String("cluster_id", req.ClusterId),
+				)
+				return retryableError(codes.Unavailable, ReasonAgentNotRegistered, "Agent disconnected")
+			}
+
 			// Convert custom Operation to generated Operation
 			protoOp := &agentv1.Operation{
 				Id:             operation.ID,
 				ClusterId:      operation.ClusterID,
 				Type:           operation.Type,
 				TimeoutSeconds: operation.Timeout,
+				Cancel:         operation.Cancel,
 			}
 
 			// Convert payload if present
 			if operation.Payload != nil {
-				// For now, we'll skip payload conversion as it requires more complex handling
-				// TODO: Implement proper payload conversion
+				payload, err := payloadToAny(operation.Payload)
+				if err != nil {
+					s.logger.Error("Failed to convert operation payload",
+						zap.Error(err),
+						zap.String("operation_id", operation.ID),
+					)
+					continue
+				}
+				protoOp.Payload = payload
 			}
 
 			// Convert timestamp
@@ -298,6 +469,16 @@ func (s *Server) ReportResult(ctx context.Context, req *agentv1.ReportResultRequ
 		zap.Bool("success", req.Success),
 	)
 
+	s.mu.RLock()
+	_, err := s.authenticate(req.ClusterId, req.SessionToken)
+	s.mu.RUnlock()
+	if err != nil {
+		return &agentv1.ReportResultResponse{
+			Success: false,
+			Message: status.Convert(err).Message(),
+		}, err
+	}
+
 	// Update operation in database
 	operationID, err := uuid.Parse(req.OperationId)
 	if err != nil {
@@ -305,7 +486,7 @@ func (s *Server) ReportResult(ctx context.Context, req *agentv1.ReportResultRequ
 		return &agentv1.ReportResultResponse{
 			Success: false,
 			Message: "Invalid operation ID",
-		}, status.Error(codes.InvalidArgument, "Invalid operation ID")
+		}, permanentError(codes.InvalidArgument, ReasonInvalidOperationID, "Invalid operation ID")
 	}
 
 	operation, err := s.operations.GetByID(ctx, operationID)
@@ -314,7 +495,41 @@ func (s *Server) ReportResult(ctx context.Context, req *agentv1.ReportResultRequ
 		return &agentv1.ReportResultResponse{
 			Success: false,
 			Message: "Operation not found",
-		}, status.Error(codes.NotFound, "Operation not found")
+		}, permanentError(codes.NotFound, ReasonOperationNotFound, "Operation not found")
+	}
+	if err := s.authorizeOperation(operation, req.ClusterId); err != nil {
+		return &agentv1.ReportResultResponse{
+			Success: false,
+			Message: status.Convert(err).Message(),
+		}, err
+	}
+
+	s.mu.Lock()
+	s.markReported(req.ClusterId, req.OperationId)
+	s.mu.Unlock()
+
+	// Results that arrive after the disconnect grace period are no longer accepted
+	if isFailedByDisconnect(operation) {
+		s.logger.Warn("Rejecting result for operation failed by agent disconnect",
+			zap.String("operation_id", req.OperationId),
+			zap.String("cluster_id", req.ClusterId),
+		)
+		return &agentv1.ReportResultResponse{
+			Success: false,
+			Message: "Operation already failed: " + agentDisconnectedReason,
+		}, permanentError(codes.FailedPrecondition, ReasonOperationFinished, "Operation already failed")
+	}
+
+	// The operation was cancelled while the agent was still working on it
+	if operation.Status == string(repo.OperationStatusCancelled) {
+		s.logger.Info("Ignoring result for cancelled operation",
+			zap.String("operation_id", req.OperationId),
+			zap.String("cluster_id", req.ClusterId),
+		)
+		return &agentv1.ReportResultResponse{
+			Success: false,
+			Message: "Operation already cancelled",
+		}, permanentError(codes.FailedPrecondition, ReasonOperationFinished, "Operation already cancelled")
 	}
 
 	// Update operation status
@@ -334,6 +549,23 @@ func (s *Server) ReportResult(ctx context.Context, req *agentv1.ReportResultRequ
 		"completed": req.CompletedAt,
 	}
 
+	// Keep the structured data reported by the agent
+	data, err := anyToMap(req.Result)
+	if err != nil {
+		s.logger.Warn("Failed to decode operation result data",
+			zap.Error(err),
+			zap.String("operation_id", req.OperationId),
+		)
+	} else if data != nil {
+		result["data"] = data
+
+		// Surface the structured error of failed operations, e.g. its Kubernetes
+		// status reason, next to the message
+		if opErr, ok := data["error"]; ok && !req.Success {
+			result["error"] = opErr
+		}
+	}
+
 	if err := s.operations.UpdateResult(ctx, operation.ID, result); err != nil {
 		s.logger.Error("Failed to update operation result", zap.Error(err))
 	}
@@ -341,62 +573,113 @@ func (s *Server) ReportResult(ctx context.Context, req *agentv1.ReportResultRequ
 	// Update metrics
 	s.metrics.RecordOperation(req.ClusterId, operation.Type, operationStatus, 0)
 
+	if !req.Success {
+		s.publish(ctx, repo.TopicOperationFailed, repo.OperationFailedEvent{
+			OperationID: operation.ID,
+			ClusterID:   operation.ClusterID,
+			Type:        operation.Type,
+			Message:     req.Message,
+			FailedAt:    time.Now(),
+			Retryable:   retryableResult(data),
+		})
+	}
+
 	return &agentv1.ReportResultResponse{
 		Success: true,
 		Message: "Result recorded",
 	}, nil
 }
 
-// StreamLogs handles log streaming from agents
-func (s *Server) StreamLogs(stream grpc.ClientStreamingServer[agentv1.LogEntry, agentv1.LogStreamResponse]) error {
-	for {
-		logEntry, err := stream.Recv()
-		if err != nil {
-			if err.Error() == "EOF" {
-				return nil
-			}
-			s.logger.Error("Failed to receive log entry", zap.Error(err))
-			return err
-		}
+// retryableResult reports whether the structured error of a failed result is
+// marked retryable by the agent
+func retryableResult(data map[string]interface{}) bool {
+	opErr, ok := data["error"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	retryable, _ := opErr["retryable"].(bool)
+	return retryable
+}
 
-		s.logger.Info("Log received from agent",
-			zap.String("level", logEntry.Level),
-			zap.String("message", logEntry.Message),
-			zap.String("source", logEntry.Source),
-		)
+// ReportOutput handles output reported by agents while an operation runs
+func (s *Server) ReportOutput(ctx context.Context, req *agentv1.OutputChunk) (*agentv1.ReportOutputResponse, error) {
+	operationID, err := uuid.Parse(req.OperationId)
+	if err != nil {
+		s.logger.Error("Invalid operation ID", zap.String("operation_id", req.OperationId))
+		return &agentv1.ReportOutputResponse{
+			Success: false,
+			Message: "Invalid operation ID",
+		}, permanentError(codes.InvalidArgument, ReasonInvalidOperationID, "Invalid operation ID")
+	}
 
-		// TODO: Store logs in database or forward to logging system
+	s.mu.RLock()
+	_, err = s.authenticate(req.ClusterId, req.SessionToken)
+	s.mu.RUnlock()
+	if err != nil {
+		return &agentv1.ReportOutputResponse{
+			Success: false,
+			Message: status.Convert(err).Message(),
+		}, err
 	}
-}
 
-// StreamMetrics handles metrics streaming from agents
-func (s *Server) StreamMetrics(stream grpc.ClientStreamingServer[agentv1.MetricEntry, agentv1.MetricStreamResponse]) error {
-	for {
-		metricEntry, err := stream.Recv()
-		if err != nil {
-			if err.Error() == "EOF" {
-				return nil
-			}
-			s.logger.Error("Failed to receive metric entry", zap.Error(err))
-			return err
-		}
+	operation, err := s.operations.GetByID(ctx, operationID)
+	if err != nil {
+		s.logger.Error("Operation not found", zap.Error(err))
+		return &agentv1.ReportOutputResponse{
+			Success: false,
+			Message: "Operation not found",
+		}, permanentError(codes.NotFound, ReasonOperationNotFound, "Operation not found")
+	}
+	if err := s.authorizeOperation(operation, req.ClusterId); err != nil {
+		return &agentv1.ReportOutputResponse{
+			Success: false,
+			Message: status.Convert(err).Message(),
+		}, err
+	}
 
-		s.logger.Debug("Metric received from agent",
-			zap.String("name", metricEntry.Name),
-			zap.Float64("value", metricEntry.Value),
-		)
+	// Output shows the agent still works on the operation after a reconnect
+	s.mu.Lock()
+	s.markReported(req.ClusterId, req.OperationId)
+	s.mu.Unlock()
+
+	if len(req.Data) == 0 {
+		return &agentv1.ReportOutputResponse{Success: true, Message: "No output"}, nil
+	}
 
-		// TODO: Store metrics in Prometheus or forward to metrics system
+	if err := s.outputs.Append(ctx, operationID, req.Data); err != nil {
+		s.logger.Error("Failed to store operation output",
+			zap.Error(err),
+			zap.String("operation_id", req.OperationId),
+		)
+		return &agentv1.ReportOutputResponse{
+			Success: false,
+			Message: "Failed to store output",
+		}, retryableError(codes.Internal, ReasonOutputStoreFailed, "Failed to store output")
 	}
+
+	return &agentv1.ReportOutputResponse{Success: true, Message: "Output stored"}, nil
 }
 
 // QueueOperation queues an operation for an agent
 func (s *Server) QueueOperation(clusterID string, operation *Operation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	connection, exists := s.agents[clusterID]
 	if !exists {
 		return fmt.Errorf("agent not connected: %s", clusterID)
 	}
 
+	// Hold the operation until the agent reports ready
+	if !connection.Ready {
+		s.redeliver[clusterID] = append(s.redeliver[clusterID], operation)
+		s.logger.Info("Operation held until agent is ready",
+			zap.String("cluster_id", clusterID),
+			zap.String("operation_id", operation.ID),
+		)
+		return nil
+	}
+
 	select {
 	case connection.Stream <- operation:
 		s.logger.Info("Operation queued for agent",
@@ -409,8 +692,33 @@ func (s *Server) QueueOperation(clusterID string, operation *Operation) error {
 	}
 }
 
+// SignalCancellation tells the connected agent of a cluster to stop a running operation
+func (s *Server) SignalCancellation(clusterID, operationID string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	connection, exists := s.agents[clusterID]
+	if !exists {
+		return fmt.Errorf("agent not connected: %s", clusterID)
+	}
+
+	select {
+	case connection.Stream <- &Operation{ID: operationID, ClusterID: clusterID, Cancel: true}:
+		s.logger.Info("Operation cancellation sent to agent",
+			zap.String("cluster_id", clusterID),
+			zap.String("operation_id", operationID),
+		)
+		return nil
+	default:
+		return fmt.Errorf("agent operation queue full: %s", clusterID)
+	}
+}
+
 // GetConnectedAgents returns list of connected agents
 func (s *Server) GetConnectedAgents() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	var agents []string
 	for clusterID := range s.agents {
 		agents = append(agents, clusterID)
@@ -426,6 +734,16 @@ func (s *Server) CancelOperation(ctx context.Context, req *agentv1.CancelOperati
 		zap.String("reason", req.Reason),
 	)
 
+	s.mu.RLock()
+	_, err := s.authenticate(req.ClusterId, req.SessionToken)
+	s.mu.RUnlock()
+	if err != nil {
+		return &agentv1.CancelOperationResponse{
+			Success: false,
+			Message: status.Convert(err).Message(),
+		}, err
+	}
+
 	// Validate operation ID
 	operationID, err := uuid.Parse(req.OperationId)
 	if err != nil {
@@ -433,7 +751,7 @@ func (s *Server) CancelOperation(ctx context.Context, req *agentv1.CancelOperati
 		return &agentv1.CancelOperationResponse{
 			Success: false,
 			Message: "Invalid operation ID",
-		}, status.Error(codes.InvalidArgument, "Invalid operation ID")
+		}, permanentError(codes.InvalidArgument, ReasonInvalidOperationID, "Invalid operation ID")
 	}
 
 	// Get operation to check if it can be cancelled
@@ -443,7 +761,14 @@ func (s *Server) CancelOperation(ctx context.Context, req *agentv1.CancelOperati
 		return &agentv1.CancelOperationResponse{
 			Success: false,
 			Message: "Operation not found",
-		}, status.Error(codes.NotFound, "Operation not found")
+		}, permanentError(codes.NotFound, ReasonOperationNotFound, "Operation not found")
+	}
+
+	if err := s.authorizeOperation(operation, req.ClusterId); err != nil {
+		return &agentv1.CancelOperationResponse{
+			Success: false,
+			Message: status.Convert(err).Message(),
+		}, err
 	}
 
 	// Check if operation can be cancelled
@@ -453,7 +778,7 @@ func (s *Server) CancelOperation(ctx context.Context, req *agentv1.CancelOperati
 		return &agentv1.CancelOperationResponse{
 			Success: false,
 			Message: fmt.Sprintf("Operation cannot be cancelled, current status: %s", operation.Status),
-		}, status.Error(codes.FailedPrecondition, "Operation cannot be cancelled")
+		}, permanentError(codes.FailedPrecondition, ReasonOperationNotCancellable, "Operation cannot be cancelled")
 	}
 
 	// Update operation status to cancelled
@@ -462,7 +787,7 @@ func (s *Server) CancelOperation(ctx context.Context, req *agentv1.CancelOperati
 		return &agentv1.CancelOperationResponse{
 			Success: false,
 			Message: "Failed to cancel operation",
-		}, status.Error(codes.Internal, "Failed to cancel operation")
+		}, retryableError(codes.Internal, ReasonOperationCancelFailed, "Failed to cancel operation")
 	}
 
 	// Update operation result with cancellation info
@@ -476,6 +801,16 @@ func (s *Server) CancelOperation(ctx context.Context, req *agentv1.CancelOperati
 		s.logger.Error("Failed to update operation result", zap.Error(err))
 	}
 
+	// Stop the work on the agent too, in case it already received the operation
+	if s.agentConnected(operation.ClusterID.String()) {
+		if err := s.SignalCancellation(operation.ClusterID.String(), req.OperationId); err != nil {
+			s.logger.Warn("Failed to send operation cancellation to agent",
+				zap.Error(err),
+				zap.String("operation_id", req.OperationId),
+			)
+		}
+	}
+
 	s.logger.Info("Operation cancelled successfully",
 		zap.String("operation_id", req.OperationId),
 		zap.String("cluster_id", req.ClusterId),
@@ -487,12 +822,55 @@ func (s *Server) CancelOperation(ctx context.Context, req *agentv1.CancelOperati
 	}, nil
 }
 
+// expireConnection marks a connection whose operation stream ended, e.g.
+// because transport keepalive found it dead, as stale so the next heartbeat
+// sweep disconnects it. A live agent keeps it with its next heartbeat.
+func (s *Server) expireConnection(clusterID string, connection *AgentConnection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.agents[clusterID] == connection {
+		connection.LastHeartbeat = time.Time{}
+	}
+}
+
+// agentConnected reports whether the agent of a cluster is connected
+func (s *Server) agentConnected(clusterID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, exists := s.agents[clusterID]
+	return exists
+}
+
 // DisconnectAgent removes an agent connection
 func (s *Server) DisconnectAgent(clusterID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disconnectAgent(clusterID)
+}
+
+// disconnectAgent removes an agent connection, ending its operation stream,
+// and clears its metrics; callers must hold mu
+func (s *Server) disconnectAgent(clusterID string) {
 	if connection, exists := s.agents[clusterID]; exists {
 		close(connection.Stream)
 		delete(s.agents, clusterID)
 		s.metrics.SetAgentsConnected(clusterID, connection.AgentVersion, 0)
+		s.metrics.ClearAgent(clusterID)
+		if s.agentMetrics != nil {
+			s.agentMetrics.ClearCluster(clusterID)
+		}
 		s.logger.Info("Agent disconnected", zap.String("cluster_id", clusterID))
 	}
 }
+
+// capabilitiesFromProto converts advertised agent capabilities for storage.
+// Agents that advertise nothing are stored without capabilities.
+func capabilitiesFromProto(caps *agentv1.AgentCapabilities) *repo.Capabilities {
+	if caps == nil {
+		return nil
+	}
+	return &repo.Capabilities{
+		OperationTypes: caps.OperationTypes,
+		Features:       caps.Features,
+	}
+}
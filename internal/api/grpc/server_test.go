@@ -0,0 +1,643 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/metrics"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+// testMetrics is shared because metrics register with the default Prometheus registry
+var testMetrics = metrics.NewMetrics()
+
+// newTestServer creates a server with mocked repositories
+func newTestServer(t *testing.T) (*Server, *mocks.MockClusterRepository, *mocks.MockOperationRepository) {
+	ctrl := gomock.NewController(t)
+	clusters := mocks.NewMockClusterRepository(ctrl)
+	operations := mocks.NewMockOperationRepository(ctrl)
+	return NewServer(clusters, operations, mocks.NewMockOperationOutputRepository(ctrl), testMetrics, zap.NewNop()), clusters, operations
+}
+
+// testSessionToken is the session token of agents connected by connectTestAgent
+const testSessionToken = "test-session-token"
+
+// connectTestAgent registers a connection for clusterID with the given last heartbeat
+func connectTestAgent(s *Server, clusterID uuid.UUID, lastHeartbeat time.Time) {
+	s.agents[clusterID.String()] = &AgentConnection{
+		ClusterID:     clusterID.String(),
+		AgentVersion:  "test",
+		LastHeartbeat: lastHeartbeat,
+		Stream:        make(chan *Operation, 10),
+		SessionToken:  testSessionToken,
+		Ready:         true,
+	}
+}
+
+func TestServer_SweepStaleAgentsFailsRunningOperations(t *testing.T) {
+	server, clusters, operations := newTestServer(t)
+	ctx := context.Background()
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now().Add(-5*time.Minute))
+
+	running := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: "running"}
+	finished := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: "success"}
+
+	clusters.EXPECT().UpdateStatus(gomock.Any(), clusterID, "disconnected").Return(nil)
+	operations.EXPECT().ListByCluster(gomock.Any(), clusterID, sweepPageSize, 0).
+		Return([]*repo.Operation{running, finished}, nil)
+	operations.EXPECT().UpdateStatus(gomock.Any(), running.ID, "failed").Return(nil)
+	operations.EXPECT().UpdateResult(gomock.Any(), running.ID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, result repo.Payload) error {
+			if result["reason"] != agentDisconnectedReason {
+				t.Errorf("Expected reason %q, got %v", agentDisconnectedReason, result["reason"])
+			}
+			return nil
+		})
+	operations.EXPECT().SetFinished(gomock.Any(), running.ID).Return(nil)
+
+	disconnected := server.SweepStaleAgents(ctx, time.Minute, 0)
+
+	if len(disconnected) != 1 || disconnected[0] != clusterID.String() {
+		t.Fatalf("Expected cluster %s to be disconnected, got %v", clusterID, disconnected)
+	}
+	if _, exists := server.agents[clusterID.String()]; exists {
+		t.Fatalf("Expected agent connection to be removed")
+	}
+}
+
+// testOperationStream is an operation stream that only carries a context
+type testOperationStream struct {
+	grpc.ServerStreamingServer[agentv1.Operation]
+	ctx context.Context
+}
+
+func (s *testOperationStream) Context() context.Context { return s.ctx }
+
+func TestServer_HeartbeatSweeperDisconnectsStaleAgents(t *testing.T) {
+	server, clusters, operations := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now().Add(-5*time.Minute))
+	testMetrics.SetAgentLastHeartbeat(clusterID.String(), float64(time.Now().Add(-5*time.Minute).Unix()))
+
+	swept := make(chan struct{})
+	clusters.EXPECT().UpdateStatus(gomock.Any(), clusterID, "disconnected").DoAndReturn(func(context.Context, uuid.UUID, string) error {
+		close(swept)
+		return nil
+	})
+	operations.EXPECT().ListByCluster(gomock.Any(), clusterID, sweepPageSize, 0).Return(nil, nil).AnyTimes()
+
+	// The agent's operation stream ends once it is disconnected
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- server.StreamOperations(&agentv1.StreamOperationsRequest{ClusterId: clusterID.String(), SessionToken: testSessionToken}, &testOperationStream{ctx: ctx})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	go server.StartHeartbeatSweeper(ctx, 10*time.Millisecond, time.Minute, 0)
+
+	select {
+	case <-swept:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the sweeper to disconnect the stale agent")
+	}
+
+	select {
+	case err := <-streamErr:
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("Expected the stream to end with Unavailable but got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the operation stream to end")
+	}
+
+	if agents := server.GetConnectedAgents(); len(agents) != 0 {
+		t.Errorf("Expected no connected agents but got %v", agents)
+	}
+	if testMetrics.AgentLastHeartbeat.DeleteLabelValues(clusterID.String()) {
+		t.Error("Expected the agent's heartbeat metric to be cleared")
+	}
+}
+
+func TestServer_ClosedStreamIsSweptAtNextSweep(t *testing.T) {
+	server, clusters, operations := newTestServer(t)
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now())
+
+	// The transport of the agent closed its stream, e.g. on a keepalive timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := server.StreamOperations(&agentv1.StreamOperationsRequest{ClusterId: clusterID.String(), SessionToken: testSessionToken}, &testOperationStream{ctx: ctx}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	clusters.EXPECT().UpdateStatus(gomock.Any(), clusterID, "disconnected").Return(nil)
+	operations.EXPECT().ListByCluster(gomock.Any(), clusterID, sweepPageSize, 0).Return(nil, nil)
+
+	if disconnected := server.SweepStaleAgents(context.Background(), time.Minute, 0); len(disconnected) != 1 {
+		t.Fatalf("Expected the agent to be disconnected without waiting for the heartbeat timeout, got %v", disconnected)
+	}
+}
+
+func TestServer_SweepStaleAgentsKeepsHealthyAgents(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now())
+
+	if disconnected := server.SweepStaleAgents(context.Background(), time.Minute, 0); len(disconnected) != 0 {
+		t.Fatalf("Expected no agents to be disconnected, got %v", disconnected)
+	}
+	if _, exists := server.agents[clusterID.String()]; !exists {
+		t.Fatalf("Expected agent connection to be kept")
+	}
+}
+
+func TestServer_SweepStaleAgentsRequeuesRetrySafeOperations(t *testing.T) {
+	server, clusters, operations := newTestServer(t)
+	ctx := context.Background()
+
+	cluster := &repo.Cluster{ID: uuid.New(), Name: "test-cluster", Status: "connected"}
+	connectTestAgent(server, cluster.ID, time.Now().Add(-5*time.Minute))
+
+	query := &repo.Operation{ID: uuid.New(), ClusterID: cluster.ID, Type: repo.OperationTypeListNodes, Status: "running"}
+
+	clusters.EXPECT().UpdateStatus(gomock.Any(), cluster.ID, "disconnected").Return(nil)
+	operations.EXPECT().ListByCluster(gomock.Any(), cluster.ID, sweepPageSize, 0).
+		Return([]*repo.Operation{query}, nil)
+	operations.EXPECT().UpdateStatus(gomock.Any(), query.ID, "queued").Return(nil)
+
+	server.SweepStaleAgents(ctx, time.Minute, 0)
+
+	// The requeued operation is resent when the agent registers again
+	clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).Return(cluster, nil)
+	clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil)
+	clusters.EXPECT().Update(gomock.Any(), cluster).Return(nil)
+
+	if _, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "test"}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	select {
+	case operation := <-server.agents[cluster.ID.String()].Stream:
+		if operation.ID != query.ID.String() {
+			t.Fatalf("Expected operation %s to be resent, got %s", query.ID, operation.ID)
+		}
+	default:
+		t.Fatalf("Expected requeued operation to be resent after reconnect")
+	}
+}
+
+func TestServer_DisconnectGracePeriod(t *testing.T) {
+	t.Run("agent reconnects and reports within the grace window", func(t *testing.T) {
+		server, clusters, operations := newTestServer(t)
+		ctx := context.Background()
+
+		cluster := &repo.Cluster{ID: uuid.New(), Name: "test-cluster", Status: "connected"}
+		connectTestAgent(server, cluster.ID, time.Now().Add(-5*time.Minute))
+		running := &repo.Operation{ID: uuid.New(), ClusterID: cluster.ID, Type: repo.OperationTypeApply, Status: "running"}
+
+		// Running operations are held, not failed, when the agent disconnects
+		clusters.EXPECT().UpdateStatus(gomock.Any(), cluster.ID, "disconnected").Return(nil)
+		server.SweepStaleAgents(ctx, time.Minute, time.Hour)
+
+		clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).Return(cluster, nil)
+		clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil)
+		clusters.EXPECT().Update(gomock.Any(), cluster).Return(nil)
+		registration, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "test"})
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+
+		operations.EXPECT().GetByID(gomock.Any(), running.ID).Return(running, nil)
+		operations.EXPECT().UpdateStatus(gomock.Any(), running.ID, "success").Return(nil)
+		operations.EXPECT().UpdateResult(gomock.Any(), running.ID, gomock.Any()).Return(nil)
+
+		resp, err := server.ReportResult(ctx, &agentv1.ReportResultRequest{
+			OperationId:  running.ID.String(),
+			ClusterId:    cluster.ID.String(),
+			SessionToken: registration.SessionToken,
+			Success:      true,
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("Expected result to be accepted, got %v (%v)", resp, err)
+		}
+
+		// The reported operation is left alone once the grace window expires
+		held, ok := server.held[cluster.ID.String()]
+		if !ok || !held.reported[running.ID.String()] {
+			t.Fatalf("Expected the reported operation to be recorded while held")
+		}
+		held.deadline = time.Now().Add(-time.Second)
+		operations.EXPECT().ListByCluster(gomock.Any(), cluster.ID, sweepPageSize, 0).
+			Return([]*repo.Operation{running}, nil)
+		server.SweepStaleAgents(ctx, time.Minute, time.Hour)
+	})
+
+	t.Run("agent restarts within the grace window and forgets the operation", func(t *testing.T) {
+		server, clusters, operations := newTestServer(t)
+		ctx := context.Background()
+
+		cluster := &repo.Cluster{ID: uuid.New(), Name: "test-cluster", Status: "connected"}
+		connectTestAgent(server, cluster.ID, time.Now().Add(-5*time.Minute))
+		running := &repo.Operation{ID: uuid.New(), ClusterID: cluster.ID, Type: repo.OperationTypeApply, Status: "running"}
+
+		clusters.EXPECT().UpdateStatus(gomock.Any(), cluster.ID, "disconnected").Return(nil)
+		server.SweepStaleAgents(ctx, time.Minute, time.Hour)
+
+		// The restarted agent registers again but never reports the operation
+		clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).Return(cluster, nil)
+		clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil)
+		clusters.EXPECT().Update(gomock.Any(), cluster).Return(nil)
+		if _, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "test"}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+
+		held, ok := server.held[cluster.ID.String()]
+		if !ok {
+			t.Fatalf("Expected the hold to survive the reconnect")
+		}
+		held.deadline = time.Now().Add(-time.Second)
+
+		operations.EXPECT().ListByCluster(gomock.Any(), cluster.ID, sweepPageSize, 0).
+			Return([]*repo.Operation{running}, nil)
+		operations.EXPECT().UpdateStatus(gomock.Any(), running.ID, "failed").Return(nil)
+		operations.EXPECT().UpdateResult(gomock.Any(), running.ID, gomock.Any()).Return(nil)
+		operations.EXPECT().SetFinished(gomock.Any(), running.ID).Return(nil)
+		server.SweepStaleAgents(ctx, time.Minute, time.Hour)
+
+		if _, held := server.held[cluster.ID.String()]; held {
+			t.Errorf("Expected the hold to be released once the grace window expired")
+		}
+	})
+
+	t.Run("agent reports after the grace window expired", func(t *testing.T) {
+		server, clusters, operations := newTestServer(t)
+		ctx := context.Background()
+
+		clusterID := uuid.New()
+		connectTestAgent(server, clusterID, time.Now().Add(-5*time.Minute))
+		running := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: "running"}
+
+		clusters.EXPECT().UpdateStatus(gomock.Any(), clusterID, "disconnected").Return(nil)
+		server.SweepStaleAgents(ctx, time.Minute, time.Hour)
+
+		// Simulate the grace window passing without a reconnect
+		server.held[clusterID.String()].deadline = time.Now().Add(-time.Second)
+
+		var failedResult repo.Payload
+		operations.EXPECT().ListByCluster(gomock.Any(), clusterID, sweepPageSize, 0).
+			Return([]*repo.Operation{running}, nil)
+		operations.EXPECT().UpdateStatus(gomock.Any(), running.ID, "failed").Return(nil)
+		operations.EXPECT().UpdateResult(gomock.Any(), running.ID, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ uuid.UUID, result repo.Payload) error {
+				failedResult = result
+				return nil
+			})
+		operations.EXPECT().SetFinished(gomock.Any(), running.ID).Return(nil)
+		server.SweepStaleAgents(ctx, time.Minute, time.Hour)
+
+		// A late report, made once the agent registered again, does not
+		// overwrite the failure
+		connectTestAgent(server, clusterID, time.Now())
+		failed := &repo.Operation{ID: running.ID, ClusterID: clusterID, Type: running.Type, Status: "failed", Result: &failedResult}
+		operations.EXPECT().GetByID(gomock.Any(), running.ID).Return(failed, nil)
+
+		if _, err := server.ReportResult(ctx, &agentv1.ReportResultRequest{
+			OperationId:  running.ID.String(),
+			ClusterId:    clusterID.String(),
+			SessionToken: testSessionToken,
+			Success:      true,
+		}); err == nil {
+			t.Fatalf("Expected late result to be rejected")
+		}
+	})
+}
+
+func TestServer_HeartbeatRecordsClockSkew(t *testing.T) {
+	server, clusters, _ := newTestServer(t)
+
+	clusterID := uuid.New()
+	connectTestAgent(server, clusterID, time.Now().Add(-time.Minute))
+	clusters.EXPECT().UpdateLastSeen(gomock.Any(), clusterID).Return(nil)
+
+	// The agent clock runs two minutes behind the hub
+	agentTime := time.Now().Add(-2 * time.Minute)
+	_, err := server.Heartbeat(context.Background(), &agentv1.HeartbeatRequest{
+		ClusterId:    clusterID.String(),
+		SessionToken: testSessionToken,
+		AgentTime:    timestamppb.New(agentTime),
+	})
+	if err != nil {
+		t.Fatalf("Heartbeat failed: %v", err)
+	}
+
+	skew := testutil.ToFloat64(testMetrics.AgentClockSkew.WithLabelValues(clusterID.String()))
+	if skew < 120 || skew > 125 {
+		t.Errorf("Expected a skew of about 120 seconds, got %f", skew)
+	}
+}
+
+func TestServer_RecordClockSkew(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	hubTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		agentTime time.Time
+		expected  time.Duration
+	}{
+		{name: "agent behind", agentTime: hubTime.Add(-45 * time.Second), expected: 45 * time.Second},
+		{name: "agent ahead", agentTime: hubTime.Add(10 * time.Second), expected: -10 * time.Second},
+		{name: "in sync", agentTime: hubTime, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusterID := uuid.NewString()
+			if skew := server.recordClockSkew(clusterID, tt.agentTime, hubTime); skew != tt.expected {
+				t.Errorf("Expected skew %v, got %v", tt.expected, skew)
+			}
+			if value := testutil.ToFloat64(testMetrics.AgentClockSkew.WithLabelValues(clusterID)); value != tt.expected.Seconds() {
+				t.Errorf("Expected gauge %f, got %f", tt.expected.Seconds(), value)
+			}
+		})
+	}
+}
+
+func TestServer_ReportResultIgnoresCancelledOperation(t *testing.T) {
+	server, _, operations := newTestServer(t)
+
+	cancelled := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeApply, Status: "cancelled"}
+	connectTestAgent(server, cancelled.ClusterID, time.Now())
+	operations.EXPECT().GetByID(gomock.Any(), cancelled.ID).Return(cancelled, nil)
+	operations.EXPECT().UpdateStatus(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	resp, err := server.ReportResult(context.Background(), &agentv1.ReportResultRequest{
+		OperationId:  cancelled.ID.String(),
+		ClusterId:    cancelled.ClusterID.String(),
+		SessionToken: testSessionToken,
+		Success:      false,
+		Message:      "Operation was cancelled",
+	})
+	if err == nil || resp.Success {
+		t.Fatalf("Expected result to be rejected, got %v", resp)
+	}
+}
+
+// recordingBus is an event bus that records published events
+type recordingBus struct {
+	events map[string][]interface{}
+}
+
+func (b *recordingBus) Publish(ctx context.Context, topic string, event interface{}) error {
+	if b.events == nil {
+		b.events = make(map[string][]interface{})
+	}
+	b.events[topic] = append(b.events[topic], event)
+	return nil
+}
+
+func (b *recordingBus) Subscribe(ctx context.Context, topic string, handler func(event interface{}) error) error {
+	return nil
+}
+
+func TestServer_ReportResultPublishesFailedOperation(t *testing.T) {
+	server, _, operations := newTestServer(t)
+	bus := &recordingBus{}
+	server.SetEventBus(bus)
+
+	operation := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeApply, Status: "running"}
+	connectTestAgent(server, operation.ClusterID, time.Now())
+	operations.EXPECT().GetByID(gomock.Any(), operation.ID).Return(operation, nil).Times(2)
+	operations.EXPECT().UpdateStatus(gomock.Any(), operation.ID, gomock.Any()).Return(nil).Times(2)
+	operations.EXPECT().UpdateResult(gomock.Any(), operation.ID, gomock.Any()).Return(nil).Times(2)
+
+	for _, success := range []bool{true, false} {
+		if _, err := server.ReportResult(context.Background(), &agentv1.ReportResultRequest{
+			OperationId:  operation.ID.String(),
+			ClusterId:    operation.ClusterID.String(),
+			SessionToken: testSessionToken,
+			Success:      success,
+			Message:      "deployments.apps \"web\" is forbidden",
+		}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	// Only the failed result is published
+	published := bus.events[repo.TopicOperationFailed]
+	if len(published) != 1 {
+		t.Fatalf("Expected 1 failed operation event but got %d", len(published))
+	}
+	event, ok := published[0].(repo.OperationFailedEvent)
+	if !ok {
+		t.Fatalf("Expected OperationFailedEvent but got %T", published[0])
+	}
+	if event.OperationID != operation.ID || event.ClusterID != operation.ClusterID || event.Type != operation.Type {
+		t.Errorf("Expected event for operation %s, got %+v", operation.ID, event)
+	}
+	if event.Message != "deployments.apps \"web\" is forbidden" {
+		t.Errorf("Expected failure message in event but got %q", event.Message)
+	}
+}
+
+func TestServer_RegisterRejectsOutdatedAgent(t *testing.T) {
+	server, clusters, _ := newTestServer(t)
+	ctx := context.Background()
+	if err := server.SetMinAgentVersion("1.2.0"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// Outdated and unparseable versions never reach the cluster store
+	for _, agentVersion := range []string{"1.1.9", "dev"} {
+		resp, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: "test-cluster", AgentVersion: agentVersion})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Expected FailedPrecondition for agent %s, got: %v", agentVersion, err)
+		}
+		if resp.Success || !strings.Contains(resp.Message, "upgrade the agent") {
+			t.Errorf("Expected upgrade message for agent %s, got: %q", agentVersion, resp.Message)
+		}
+	}
+
+	cluster := &repo.Cluster{ID: uuid.New(), Name: "test-cluster", Status: "disconnected"}
+	clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).Return(cluster, nil)
+	clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil)
+	clusters.EXPECT().Update(gomock.Any(), cluster).Return(nil)
+
+	if _, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "v1.2.1"}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if cluster.Labels[repo.AgentVersionLabel] != "v1.2.1" {
+		t.Errorf("Expected agent version label to be recorded, got %v", cluster.Labels)
+	}
+}
+
+func TestServer_RegisterRejectsDuplicateFingerprint(t *testing.T) {
+	server, clusters, _ := newTestServer(t)
+	ctx := context.Background()
+
+	cluster := &repo.Cluster{ID: uuid.New(), Name: "prod", Status: "disconnected"}
+	clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).Return(cluster, nil).Times(3)
+	clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil).Times(3)
+	clusters.EXPECT().Update(gomock.Any(), cluster).Return(nil).Times(2)
+
+	// The first agent's fingerprint is recorded
+	if _, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "1.0.0", Fingerprint: "uid-a"}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if cluster.Labels[repo.AgentFingerprintLabel] != "uid-a" {
+		t.Fatalf("Expected the fingerprint to be recorded, got %v", cluster.Labels)
+	}
+
+	// Another cluster using the same name is rejected
+	before := testutil.ToFloat64(testMetrics.AgentDuplicates.WithLabelValues(cluster.ID.String(), cluster.Name))
+	resp, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "1.0.0", Fingerprint: "uid-b"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("Expected AlreadyExists, got: %v", err)
+	}
+	if resp.Success {
+		t.Error("Expected the registration to fail")
+	}
+	if cluster.Labels[repo.AgentFingerprintLabel] != "uid-a" {
+		t.Errorf("Expected the recorded fingerprint to be kept, got %v", cluster.Labels)
+	}
+	if after := testutil.ToFloat64(testMetrics.AgentDuplicates.WithLabelValues(cluster.ID.String(), cluster.Name)); after != before+1 {
+		t.Errorf("Expected the duplicate registration to be counted, got %v", after-before)
+	}
+
+	// The original cluster can still reconnect
+	if _, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "1.0.0", Fingerprint: "uid-a"}); err != nil {
+		t.Errorf("Expected the original cluster to register again but got: %v", err)
+	}
+}
+
+func TestServer_RegisterShedsRegistrationsBeyondLimit(t *testing.T) {
+	server, clusters, _ := newTestServer(t)
+	ctx := context.Background()
+	server.SetRegistrationLimit(1, 50*time.Millisecond)
+
+	// The first registration holds the only slot until it is unblocked
+	cluster := &repo.Cluster{ID: uuid.New(), Name: "first", Status: "disconnected"}
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).DoAndReturn(func(context.Context, string) (*repo.Cluster, error) {
+		close(entered)
+		<-unblock
+		return cluster, nil
+	})
+	clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil)
+	clusters.EXPECT().Update(gomock.Any(), cluster).Return(nil)
+
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "1.0.0"})
+		firstErr <- err
+	}()
+	<-entered
+
+	// Registrations beyond the limit never reach the cluster store
+	resp, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: "second", AgentVersion: "1.0.0"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got: %v", err)
+	}
+	if resp.Success {
+		t.Error("Expected the registration to fail")
+	}
+	info, retry := errorDetails(t, err)
+	if info.Reason != ReasonTooManyRegistrations || info.Metadata[retryableMetadataKey] != "true" {
+		t.Errorf("Expected a retryable %s error but got %+v", ReasonTooManyRegistrations, info)
+	}
+	if retry == nil {
+		t.Error("Expected a suggested retry delay")
+	}
+
+	close(unblock)
+	if err := <-firstErr; err != nil {
+		t.Fatalf("Expected the first registration to succeed but got: %v", err)
+	}
+
+	// The slot is released once the registration finishes
+	second := &repo.Cluster{ID: uuid.New(), Name: "second", Status: "disconnected"}
+	clusters.EXPECT().GetByName(gomock.Any(), second.Name).Return(second, nil)
+	clusters.EXPECT().GetByID(gomock.Any(), second.ID).Return(second, nil)
+	clusters.EXPECT().Update(gomock.Any(), second).Return(nil)
+	if _, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: second.Name, AgentVersion: "1.0.0"}); err != nil {
+		t.Errorf("Expected the retried registration to succeed but got: %v", err)
+	}
+}
+
+func TestServer_ConcurrentAgentConnections(t *testing.T) {
+	server, clusters, _ := newTestServer(t)
+	ctx := context.Background()
+
+	const agents = 8
+	registered := make([]*repo.Cluster, agents)
+	for i := range registered {
+		cluster := &repo.Cluster{ID: uuid.New(), Name: fmt.Sprintf("cluster-%d", i), Status: "connected"}
+		registered[i] = cluster
+		clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).Return(cluster, nil).AnyTimes()
+		clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil).AnyTimes()
+		clusters.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		clusters.EXPECT().UpdateLastSeen(gomock.Any(), cluster.ID).Return(nil).AnyTimes()
+	}
+
+	// Registrations, heartbeats and disconnects race with the hub queueing
+	// operations and listing agents; run with -race to catch unguarded access
+	var wg sync.WaitGroup
+	for _, cluster := range registered {
+		wg.Add(2)
+		go func(cluster *repo.Cluster) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				resp, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "1.0.0"})
+				if err != nil {
+					t.Errorf("Expected no error but got: %v", err)
+					return
+				}
+				server.Heartbeat(ctx, &agentv1.HeartbeatRequest{ClusterId: cluster.ID.String(), SessionToken: resp.SessionToken})
+				server.DisconnectAgent(cluster.ID.String())
+			}
+		}(cluster)
+		go func(cluster *repo.Cluster) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				server.QueueOperation(cluster.ID.String(), &Operation{ID: uuid.NewString(), ClusterID: cluster.ID.String(), Type: repo.OperationTypeListNodes})
+				server.SignalCancellation(cluster.ID.String(), uuid.NewString())
+				server.GetConnectedAgents()
+			}
+		}(cluster)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			server.SweepStaleAgents(ctx, time.Hour, time.Minute)
+		}
+	}()
+	wg.Wait()
+
+	if agents := server.GetConnectedAgents(); len(agents) != 0 {
+		t.Errorf("Expected every agent to be disconnected but got %v", agents)
+	}
+}
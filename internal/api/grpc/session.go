@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// Metadata keys identifying the agent on client streams, whose messages don't
+// carry the cluster ID and session token themselves
+const (
+	clusterIDMetadataKey    = "x-cluster-id"
+	sessionTokenMetadataKey = "x-session-token"
+)
+
+// newSessionToken returns an unpredictable token identifying an agent
+// session, so one agent can't act on another cluster's session
+func newSessionToken() string {
+	return rand.Text()
+}
+
+// authenticate returns the connection of the agent of clusterID if token is
+// the session token it was issued at registration. Callers must hold mu.
+func (s *Server) authenticate(clusterID, token string) (*AgentConnection, error) {
+	connection, exists := s.agents[clusterID]
+	if !exists {
+		return nil, permanentError(codes.NotFound, ReasonAgentNotRegistered, "Agent not registered")
+	}
+	if subtle.ConstantTimeCompare([]byte(connection.SessionToken), []byte(token)) != 1 {
+		s.logger.Warn("Rejected agent call with an invalid session token", zap.String("cluster_id", clusterID))
+		return nil, permanentError(codes.Unauthenticated, ReasonInvalidSessionToken, "Invalid session token")
+	}
+	return connection, nil
+}
+
+// authorizeOperation rejects calls of the authenticated agent of clusterID
+// about an operation of another cluster
+func (s *Server) authorizeOperation(operation *repo.Operation, clusterID string) error {
+	if operation.ClusterID.String() == clusterID {
+		return nil
+	}
+	s.logger.Warn("Rejected agent call for an operation of another cluster",
+		zap.String("cluster_id", clusterID),
+		zap.String("operation_id", operation.ID.String()),
+		zap.String("operation_cluster_id", operation.ClusterID.String()),
+	)
+	return permanentError(codes.PermissionDenied, ReasonOperationNotOwned, "Operation belongs to another cluster")
+}
+
+// authenticateStream authenticates the agent opening a client stream from the
+// metadata of its context, returning the agent's cluster ID
+func (s *Server) authenticateStream(ctx context.Context) (uuid.UUID, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	clusterID := firstMetadataValue(md, clusterIDMetadataKey)
+
+	s.mu.RLock()
+	_, err := s.authenticate(clusterID, firstMetadataValue(md, sessionTokenMetadataKey))
+	s.mu.RUnlock()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	id, err := uuid.Parse(clusterID)
+	if err != nil {
+		return uuid.Nil, permanentError(codes.InvalidArgument, ReasonInvalidClusterID, "Invalid cluster ID")
+	}
+	return id, nil
+}
+
+// firstMetadataValue returns the first value of key in md, if any
+func firstMetadataValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
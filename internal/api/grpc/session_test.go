@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	agentv1 "github.com/rizesky/mckmt/api/proto/agent/v1"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+func TestServer_ValidatesSessionToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "valid token", token: testSessionToken, valid: true},
+		{name: "invalid token", token: "session-" + testSessionToken},
+		{name: "missing token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, clusters, _ := newTestServer(t)
+			clusterID := uuid.New()
+			connectTestAgent(server, clusterID, time.Now())
+			clusters.EXPECT().UpdateLastSeen(gomock.Any(), clusterID).Return(nil).AnyTimes()
+
+			// A closed stream ends right after authenticating, and an
+			// authenticated result is rejected only for its operation ID
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			calls := map[string]error{
+				"Heartbeat": func() error {
+					_, err := server.Heartbeat(ctx, &agentv1.HeartbeatRequest{ClusterId: clusterID.String(), SessionToken: tt.token})
+					return err
+				}(),
+				"StreamOperations": server.StreamOperations(&agentv1.StreamOperationsRequest{ClusterId: clusterID.String(), SessionToken: tt.token}, &testOperationStream{ctx: ctx}),
+				"ReportResult": func() error {
+					_, err := server.ReportResult(ctx, &agentv1.ReportResultRequest{OperationId: "invalid", ClusterId: clusterID.String(), SessionToken: tt.token})
+					if status.Code(err) == codes.InvalidArgument {
+						return nil
+					}
+					return err
+				}(),
+			}
+
+			for rpc, err := range calls {
+				if tt.valid {
+					if err != nil {
+						t.Errorf("Expected %s to be authenticated but got: %v", rpc, err)
+					}
+					continue
+				}
+				if status.Code(err) != codes.Unauthenticated {
+					t.Errorf("Expected %s to fail with Unauthenticated but got: %v", rpc, err)
+					continue
+				}
+				if info, _ := errorDetails(t, err); info.Reason != ReasonInvalidSessionToken || info.Metadata[retryableMetadataKey] != "false" {
+					t.Errorf("Expected a permanent %s error from %s but got %+v", ReasonInvalidSessionToken, rpc, info)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_RegisterIssuesUniqueSessionTokens(t *testing.T) {
+	server, clusters, _ := newTestServer(t)
+	ctx := context.Background()
+
+	cluster := &repo.Cluster{ID: uuid.New(), Name: "test-cluster", Status: "connected"}
+	clusters.EXPECT().GetByName(gomock.Any(), cluster.Name).Return(cluster, nil).Times(2)
+	clusters.EXPECT().GetByID(gomock.Any(), cluster.ID).Return(cluster, nil).Times(2)
+	clusters.EXPECT().Update(gomock.Any(), cluster).Return(nil).Times(2)
+
+	first, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "test"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	second, err := server.Register(ctx, &agentv1.RegisterRequest{ClusterName: cluster.Name, AgentVersion: "test"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if first.SessionToken == "" || first.SessionToken == second.SessionToken {
+		t.Fatalf("Expected distinct session tokens but got %q and %q", first.SessionToken, second.SessionToken)
+	}
+
+	// Registering again replaces the session, so the old token is rejected
+	_, err = server.Heartbeat(ctx, &agentv1.HeartbeatRequest{ClusterId: cluster.ID.String(), SessionToken: first.SessionToken})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected the replaced session token to be rejected but got: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+const (
+	// agentDisconnectedReason is recorded on operations failed because their agent went away
+	agentDisconnectedReason = "agent disconnected"
+
+	// sweepPageSize is the page size used when scanning a cluster's operations
+	sweepPageSize = 100
+)
+
+// retrySafeOperationTypes lists read-only operation types that can be resent to
+// the agent after a disconnect without risking a duplicated side effect
+var retrySafeOperationTypes = map[string]bool{
+	repo.OperationTypeListNamespaces: true,
+	repo.OperationTypeListNodes:      true,
+}
+
+// StartHeartbeatSweeper periodically disconnects agents whose last heartbeat is
+// older than timeout, until the context is cancelled. Running operations of a
+// disconnected agent are held for gracePeriod before they are failed.
+func (s *Server) StartHeartbeatSweeper(ctx context.Context, interval, timeout, gracePeriod time.Duration) {
+	if interval <= 0 || timeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SweepStaleAgents(ctx, timeout, gracePeriod)
+		}
+	}
+}
+
+// SweepStaleAgents disconnects agents that have not sent a heartbeat within
+// timeout and returns the cluster IDs of the agents that were disconnected.
+// Their running operations are failed once gracePeriod has passed without the
+// agent registering again; a zero grace period fails them immediately.
+func (s *Server) SweepStaleAgents(ctx context.Context, timeout, gracePeriod time.Duration) []string {
+	now := time.Now()
+	cutoff := now.Add(-timeout)
+
+	// Agents are disconnected in the same critical section they are found
+	// stale in, so a heartbeat arriving meanwhile can't be lost
+	var stale []string
+	s.mu.Lock()
+	for clusterID, connection := range s.agents {
+		if connection.LastHeartbeat.Before(cutoff) {
+			stale = append(stale, clusterID)
+			s.disconnectAgent(clusterID)
+			// An agent dropping again within its grace period doesn't extend it
+			if _, held := s.held[clusterID]; !held {
+				s.held[clusterID] = &heldOperations{deadline: now.Add(gracePeriod), reported: make(map[string]bool)}
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, clusterID := range stale {
+		s.logger.Warn("Agent heartbeat timed out",
+			zap.String("cluster_id", clusterID),
+			zap.Duration("timeout", timeout),
+		)
+		s.markClusterDisconnected(ctx, clusterID)
+	}
+
+	s.releaseHeldOperations(ctx, now)
+
+	return stale
+}
+
+// markClusterDisconnected marks the cluster of a dropped agent connection as disconnected
+func (s *Server) markClusterDisconnected(ctx context.Context, clusterID string) {
+	id, err := uuid.Parse(clusterID)
+	if err != nil {
+		s.logger.Error("Invalid cluster ID", zap.String("cluster_id", clusterID))
+		return
+	}
+
+	if err := s.clusters.UpdateStatus(ctx, id, "disconnected"); err != nil {
+		s.logger.Error("Failed to mark cluster as disconnected", zap.Error(err))
+	}
+
+	s.publish(ctx, repo.TopicClusterDisconnected, repo.ClusterDisconnectedEvent{
+		ClusterID:      id,
+		DisconnectedAt: time.Now(),
+	})
+}
+
+// heldOperations tracks the running operations of a disconnected agent until
+// its grace period ends
+type heldOperations struct {
+	deadline time.Time
+	reported map[string]bool // operations the agent reported on since it disconnected
+}
+
+// markReported records that the agent of a cluster reported on an operation
+// while its running operations are held. The caller must hold s.mu.
+func (s *Server) markReported(clusterID, operationID string) {
+	if held, ok := s.held[clusterID]; ok {
+		held.reported[operationID] = true
+	}
+}
+
+// releaseHeldOperations settles the running operations of disconnected agents
+// whose grace period has expired. Operations the agent reported on after
+// registering again are left to it; the others are failed, since an agent
+// that restarted has no memory of them.
+func (s *Server) releaseHeldOperations(ctx context.Context, now time.Time) {
+	expired := make(map[string]*heldOperations)
+	s.mu.Lock()
+	for clusterID, held := range s.held {
+		if now.Before(held.deadline) {
+			continue
+		}
+		delete(s.held, clusterID)
+		expired[clusterID] = held
+	}
+	s.mu.Unlock()
+
+	for clusterID, held := range expired {
+		id, err := uuid.Parse(clusterID)
+		if err != nil {
+			s.logger.Error("Invalid cluster ID", zap.String("cluster_id", clusterID))
+			continue
+		}
+		s.failRunningOperations(ctx, id, held.reported)
+	}
+}
+
+// isFailedByDisconnect reports whether an operation was failed because its agent disconnected
+func isFailedByDisconnect(operation *repo.Operation) bool {
+	if operation.Status != string(repo.OperationStatusFailed) || operation.Result == nil {
+		return false
+	}
+	return (*operation.Result)["reason"] == agentDisconnectedReason
+}
+
+// failRunningOperations fails the running operations of a cluster whose agent
+// disconnected, except those in reported. Read-only operations are requeued
+// and resent when the agent registers again instead.
+func (s *Server) failRunningOperations(ctx context.Context, clusterID uuid.UUID, reported map[string]bool) {
+	var running []*repo.Operation
+	for offset := 0; ; offset += sweepPageSize {
+		operations, err := s.operations.ListByCluster(ctx, clusterID, sweepPageSize, offset)
+		if err != nil {
+			s.logger.Error("Failed to list cluster operations", zap.Error(err))
+			return
+		}
+		for _, operation := range operations {
+			if operation.Status == string(repo.OperationStatusRunning) && !reported[operation.ID.String()] {
+				running = append(running, operation)
+			}
+		}
+		if len(operations) < sweepPageSize {
+			break
+		}
+	}
+
+	for _, operation := range running {
+		if retrySafeOperationTypes[operation.Type] {
+			s.requeueOperation(ctx, operation)
+			continue
+		}
+
+		if err := s.operations.UpdateStatus(ctx, operation.ID, string(repo.OperationStatusFailed)); err != nil {
+			s.logger.Error("Failed to update operation status", zap.Error(err))
+			continue
+		}
+
+		result := repo.Payload{
+			"success": false,
+			"message": "Operation failed: " + agentDisconnectedReason,
+			"reason":  agentDisconnectedReason,
+		}
+		if err := s.operations.UpdateResult(ctx, operation.ID, result); err != nil {
+			s.logger.Error("Failed to update operation result", zap.Error(err))
+		}
+
+		if err := s.operations.SetFinished(ctx, operation.ID); err != nil {
+			s.logger.Error("Failed to mark operation as finished", zap.Error(err))
+		}
+
+		s.metrics.RecordOperation(clusterID.String(), operation.Type, string(repo.OperationStatusFailed), 0)
+
+		s.publish(ctx, repo.TopicOperationFailed, repo.OperationFailedEvent{
+			OperationID: operation.ID,
+			ClusterID:   clusterID,
+			Type:        operation.Type,
+			Message:     "Operation failed: " + agentDisconnectedReason,
+			FailedAt:    time.Now(),
+		})
+
+		s.logger.Warn("Operation failed because its agent disconnected",
+			zap.String("operation_id", operation.ID.String()),
+			zap.String("cluster_id", clusterID.String()),
+			zap.String("type", operation.Type),
+		)
+	}
+}
+
+// requeueOperation puts a retry-safe operation back into the queued state and
+// schedules it to be resent once the agent reconnects
+func (s *Server) requeueOperation(ctx context.Context, operation *repo.Operation) {
+	if err := s.operations.UpdateStatus(ctx, operation.ID, "queued"); err != nil {
+		s.logger.Error("Failed to requeue operation", zap.Error(err))
+		return
+	}
+
+	clusterID := operation.ClusterID.String()
+	s.mu.Lock()
+	s.redeliver[clusterID] = append(s.redeliver[clusterID], &Operation{
+		ID:        operation.ID.String(),
+		ClusterID: clusterID,
+		Type:      operation.Type,
+		Payload:   operation.Payload,
+		CreatedAt: operation.CreatedAt,
+	})
+	s.mu.Unlock()
+
+	s.logger.Info("Operation requeued after agent disconnect",
+		zap.String("operation_id", operation.ID.String()),
+		zap.String("cluster_id", clusterID),
+		zap.String("type", operation.Type),
+	)
+}
+
+// flushRedeliveries sends operations requeued by a disconnect, or held until
+// the agent was ready, to a registered agent; callers must hold mu
+func (s *Server) flushRedeliveries(clusterID string, connection *AgentConnection) {
+	pending := s.redeliver[clusterID]
+	delete(s.redeliver, clusterID)
+
+	for i, operation := range pending {
+		select {
+		case connection.Stream <- operation:
+		default:
+			s.logger.Warn("Agent operation queue full, keeping operations for redelivery",
+				zap.String("cluster_id", clusterID),
+				zap.Int("remaining", len(pending)-i),
+			)
+			s.redeliver[clusterID] = pending[i:]
+			return
+		}
+	}
+}
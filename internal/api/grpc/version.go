@@ -0,0 +1,42 @@
+package grpc
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// SetMinAgentVersion sets the oldest agent version allowed to register, as a
+// semantic version such as "1.2.0". An empty version disables the check.
+func (s *Server) SetMinAgentVersion(minVersion string) error {
+	if minVersion == "" {
+		s.minAgentVersion = nil
+		return nil
+	}
+
+	parsed, err := version.ParseSemantic(minVersion)
+	if err != nil {
+		return fmt.Errorf("invalid minimum agent version %q: %w", minVersion, err)
+	}
+	s.minAgentVersion = parsed
+	return nil
+}
+
+// checkAgentVersion returns an error describing the required upgrade when the
+// agent version is older than the minimum supported version or not a semantic version
+func (s *Server) checkAgentVersion(agentVersion string) error {
+	if s.minAgentVersion == nil {
+		return nil
+	}
+
+	parsed, err := version.ParseSemantic(agentVersion)
+	if err != nil {
+		return fmt.Errorf("agent version %q is not a semantic version; upgrade the agent to %s or later",
+			agentVersion, s.minAgentVersion)
+	}
+	if parsed.LessThan(s.minAgentVersion) {
+		return fmt.Errorf("agent version %s is older than the minimum supported version %s; upgrade the agent",
+			parsed, s.minAgentVersion)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/orchestrator"
+)
+
+// AdminHandler handles administrative HTTP requests for debugging the hub
+type AdminHandler struct {
+	orchestrator OrchestratorAdmin
+	deadLetters  DeadLetterAdmin
+	pagination   Pagination
+	logger       *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(orchestrator OrchestratorAdmin, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		orchestrator: orchestrator,
+		pagination:   DefaultPagination,
+		logger:       logger,
+	}
+}
+
+// GetOrchestratorState handles inspecting the orchestrator's internal state
+// @Summary Get orchestrator state
+// @Description Get the orchestrator's queue depth, worker count, running operations, cancellation queue depth and whether it is paused. Requires admin access.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} OrchestratorStateDTO
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/orchestrator [get]
+func (h *AdminHandler) GetOrchestratorState(w http.ResponseWriter, r *http.Request) {
+	if h.orchestrator == nil {
+		WriteErrorResponse(w, http.StatusServiceUnavailable, "Orchestrator is not running")
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, orchestratorStateDTO(h.orchestrator.State()))
+}
+
+// PauseOrchestrator handles pausing the orchestrator for maintenance
+// @Summary Pause orchestrator
+// @Description Stop workers from taking new operations off the queue. Queued operations are kept and in-flight operations run to completion. Requires admin access.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} OrchestratorStateDTO
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/orchestrator/pause [post]
+func (h *AdminHandler) PauseOrchestrator(w http.ResponseWriter, r *http.Request) {
+	if h.orchestrator == nil {
+		WriteErrorResponse(w, http.StatusServiceUnavailable, "Orchestrator is not running")
+		return
+	}
+
+	h.orchestrator.Pause()
+	h.logger.Info("Orchestrator paused via admin API")
+	WriteJSONResponse(w, http.StatusOK, orchestratorStateDTO(h.orchestrator.State()))
+}
+
+// ResumeOrchestrator handles resuming a paused orchestrator
+// @Summary Resume orchestrator
+// @Description Let workers take operations off the queue again after a pause. Requires admin access.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} OrchestratorStateDTO
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/orchestrator/resume [post]
+func (h *AdminHandler) ResumeOrchestrator(w http.ResponseWriter, r *http.Request) {
+	if h.orchestrator == nil {
+		WriteErrorResponse(w, http.StatusServiceUnavailable, "Orchestrator is not running")
+		return
+	}
+
+	h.orchestrator.Resume()
+	h.logger.Info("Orchestrator resumed via admin API")
+	WriteJSONResponse(w, http.StatusOK, orchestratorStateDTO(h.orchestrator.State()))
+}
+
+// ListDeadLetters handles listing the dead-lettered operations
+// @Summary List dead-lettered operations
+// @Description List the operations that failed for good or ran out of retries, most recent first. Requires admin access.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Maximum number of operations, capped at the server's max_limit"
+// @Param offset query int false "Number of operations to skip" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/dead-letters [get]
+func (h *AdminHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if h.deadLetters == nil {
+		WriteErrorResponse(w, http.StatusServiceUnavailable, "Dead-letter queue is not enabled")
+		return
+	}
+
+	limit, offset, ok := h.pagination.parse(w, r)
+	if !ok {
+		return
+	}
+
+	deadLetters, err := h.deadLetters.List(r.Context(), limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list dead-lettered operations", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list dead-lettered operations")
+		return
+	}
+
+	total, err := h.deadLetters.Count(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to count dead-lettered operations", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to count dead-lettered operations")
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, listResponse(h.pagination.page(limit, offset, len(deadLetters), total), map[string]interface{}{
+		"dead_letters": deadLetters,
+	}))
+}
+
+// RedriveDeadLetter handles re-queueing a dead-lettered operation
+// @Summary Re-drive a dead-lettered operation
+// @Description Queue a dead-lettered operation again and take it off the dead-letter queue. It is dead-lettered again if it fails. Requires admin access.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Operation ID"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /admin/dead-letters/{id}/redrive [post]
+func (h *AdminHandler) RedriveDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if h.deadLetters == nil {
+		WriteErrorResponse(w, http.StatusServiceUnavailable, "Dead-letter queue is not enabled")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	if err := h.deadLetters.Redrive(r.Context(), id); err != nil {
+		if errors.Is(err, operation.ErrOperationNotDeadLettered) {
+			WriteErrorResponse(w, http.StatusNotFound, "Operation is not dead-lettered")
+			return
+		}
+		if errors.Is(err, operation.ErrOperationSecretsMasked) {
+			WriteErrorResponse(w, http.StatusConflict, "Operation cannot be re-driven: "+err.Error())
+			return
+		}
+		h.logger.Error("Failed to re-drive operation", zap.Error(err), zap.String("operation_id", id.String()))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to re-drive operation")
+		return
+	}
+
+	h.logger.Info("Operation re-driven via admin API", zap.String("operation_id", id.String()))
+	WriteJSONResponse(w, http.StatusAccepted, map[string]interface{}{
+		"operation_id": id,
+		"status":       "queued",
+	})
+}
+
+// orchestratorStateDTO converts an orchestrator state snapshot for responses
+func orchestratorStateDTO(state orchestrator.State) OrchestratorStateDTO {
+	running := make([]string, 0, len(state.RunningOperations))
+	for _, id := range state.RunningOperations {
+		running = append(running, id.String())
+	}
+
+	return OrchestratorStateDTO{
+		QueueDepth:        state.QueueDepth,
+		QueueCapacity:     state.QueueCapacity,
+		Workers:           state.Workers,
+		RunningOperations: running,
+		CancelQueueDepth:  state.CancelQueueDepth,
+		Paused:            state.Paused,
+		HeldOperations:    state.HeldOperations,
+	}
+}
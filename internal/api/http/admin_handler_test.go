@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/api/http/mocks"
+	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/orchestrator"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+func TestAdminHandler_GetOrchestratorState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	running := []uuid.UUID{uuid.New(), uuid.New()}
+	mockOrchestrator := mocks.NewMockOrchestratorAdmin(ctrl)
+	mockOrchestrator.EXPECT().State().Return(orchestrator.State{
+		QueueDepth:        7,
+		QueueCapacity:     1000,
+		Workers:           5,
+		RunningOperations: running,
+		CancelQueueDepth:  1,
+	})
+
+	handler := NewAdminHandler(mockOrchestrator, zap.NewNop())
+	req := httptest.NewRequest(http.MethodGet, "/admin/orchestrator", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetOrchestratorState(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var state OrchestratorStateDTO
+	if err := json.NewDecoder(w.Body).Decode(&state); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if state.QueueDepth != 7 || state.QueueCapacity != 1000 || state.Workers != 5 || state.CancelQueueDepth != 1 {
+		t.Errorf("Unexpected orchestrator state: %+v", state)
+	}
+	if len(state.RunningOperations) != 2 || state.RunningOperations[0] != running[0].String() || state.RunningOperations[1] != running[1].String() {
+		t.Errorf("Expected running operations %v but got %v", running, state.RunningOperations)
+	}
+}
+
+func TestAdminHandler_GetOrchestratorStateWithoutOrchestrator(t *testing.T) {
+	handler := NewAdminHandler(nil, zap.NewNop())
+	req := httptest.NewRequest(http.MethodGet, "/admin/orchestrator", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetOrchestratorState(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestAdminHandler_PauseAndResumeOrchestrator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrchestrator := mocks.NewMockOrchestratorAdmin(ctrl)
+	gomock.InOrder(
+		mockOrchestrator.EXPECT().Pause(),
+		mockOrchestrator.EXPECT().State().Return(orchestrator.State{QueueDepth: 3, Paused: true}),
+		mockOrchestrator.EXPECT().Resume(),
+		mockOrchestrator.EXPECT().State().Return(orchestrator.State{QueueDepth: 3}),
+	)
+
+	handler := NewAdminHandler(mockOrchestrator, zap.NewNop())
+
+	tests := []struct {
+		name   string
+		handle http.HandlerFunc
+		paused bool
+	}{
+		{name: "pause", handle: handler.PauseOrchestrator, paused: true},
+		{name: "resume", handle: handler.ResumeOrchestrator, paused: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handle(w, httptest.NewRequest(http.MethodPost, "/admin/orchestrator/"+tt.name, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			var state OrchestratorStateDTO
+			if err := json.NewDecoder(w.Body).Decode(&state); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if state.Paused != tt.paused || state.QueueDepth != 3 {
+				t.Errorf("Unexpected orchestrator state after %s: %+v", tt.name, state)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_ListDeadLetters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	deadLetter := &repo.DeadLetter{OperationID: uuid.New(), ClusterID: uuid.New(), Type: "apply", Reason: "conflict", Attempts: 4}
+	mockDeadLetters := mocks.NewMockDeadLetterAdmin(ctrl)
+	mockDeadLetters.EXPECT().List(gomock.Any(), 5, 0).Return([]*repo.DeadLetter{deadLetter}, nil)
+	mockDeadLetters.EXPECT().Count(gomock.Any()).Return(1, nil)
+
+	handler := NewAdminHandler(nil, zap.NewNop())
+	handler.deadLetters = mockDeadLetters
+	req := httptest.NewRequest(http.MethodGet, "/admin/dead-letters?limit=5", nil)
+	w := httptest.NewRecorder()
+
+	handler.ListDeadLetters(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		PageDTO
+		DeadLetters []repo.DeadLetter `json:"dead_letters"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.DeadLetters) != 1 || response.DeadLetters[0].OperationID != deadLetter.OperationID || response.DeadLetters[0].Attempts != 4 {
+		t.Errorf("Expected the dead-lettered operation but got %+v", response.DeadLetters)
+	}
+	if response.TotalCount != 1 || response.Limit != 5 || response.HasMore {
+		t.Errorf("Unexpected pagination: %+v", response.PageDTO)
+	}
+}
+
+func TestAdminHandler_RedriveDeadLetter(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name           string
+		operationID    string
+		redriveErr     error
+		expectRedrive  bool
+		expectedStatus int
+	}{
+		{name: "re-driven", operationID: id.String(), expectRedrive: true, expectedStatus: http.StatusAccepted},
+		{name: "not dead-lettered", operationID: id.String(), redriveErr: operation.ErrOperationNotDeadLettered, expectRedrive: true, expectedStatus: http.StatusNotFound},
+		{name: "secrets masked", operationID: id.String(), redriveErr: operation.ErrOperationSecretsMasked, expectRedrive: true, expectedStatus: http.StatusConflict},
+		{name: "queue full", operationID: id.String(), redriveErr: errors.New("operation queue is full"), expectRedrive: true, expectedStatus: http.StatusInternalServerError},
+		{name: "invalid ID", operationID: "invalid", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeadLetters := mocks.NewMockDeadLetterAdmin(ctrl)
+			if tt.expectRedrive {
+				mockDeadLetters.EXPECT().Redrive(gomock.Any(), id).Return(tt.redriveErr)
+			}
+
+			handler := NewAdminHandler(nil, zap.NewNop())
+			handler.deadLetters = mockDeadLetters
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/dead-letters/"+tt.operationID+"/redrive", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.operationID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.RedriveDeadLetter(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminHandler_DeadLettersWithoutQueue(t *testing.T) {
+	handler := NewAdminHandler(nil, zap.NewNop())
+	w := httptest.NewRecorder()
+
+	handler.ListDeadLetters(w, httptest.NewRequest(http.MethodGet, "/admin/dead-letters", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d but got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
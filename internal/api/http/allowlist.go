@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+// SetAdminAllowlist restricts the admin API to clients in allowlist. It must
+// be called before SetupRoutes.
+func (r *Router) SetAdminAllowlist(allowlist *auth.IPAllowlist) {
+	r.adminAllowlist = allowlist
+}
+
+// SetTrustedProxies sets the reverse proxies whose X-Forwarded-For and
+// X-Real-IP headers give the client address. It must be called before
+// SetupRoutes.
+func (r *Router) SetTrustedProxies(proxies *auth.TrustedProxies) {
+	r.trustedProxies = proxies
+}
+
+// realIPMiddleware replaces the remote address of requests relayed by a
+// trusted proxy with the client address it reports, so the allowlist, rate
+// limits and logs see the original client. Headers from other peers are
+// ignored.
+func realIPMiddleware(proxies *auth.TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peerIP := clientIP(r)
+			if !proxies.Trusts(peerIP) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			forwardedFor := r.Header.Values("X-Forwarded-For")
+			if len(forwardedFor) == 0 {
+				forwardedFor = r.Header.Values("X-Real-IP")
+			}
+			if ip := proxies.ClientIP(peerIP, forwardedFor); net.ParseIP(ip) != nil {
+				r.RemoteAddr = net.JoinHostPort(ip, "0")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowlistMiddleware rejects clients whose IP isn't in allowlist with a 403
+func allowlistMiddleware(allowlist *auth.IPAllowlist, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := clientIP(r); !allowlist.Allows(ip) {
+				logger.Warn("Request from disallowed address", zap.String("ip", ip), zap.String("path", r.URL.Path))
+				WriteErrorResponse(w, http.StatusForbidden, "Access from this address is not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+func TestAllowlistMiddleware(t *testing.T) {
+	allowlist, err := auth.NewIPAllowlist([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	proxies, err := auth.NewTrustedProxies([]string{"203.0.113.1", "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	handler := realIPMiddleware(proxies)(allowlistMiddleware(allowlist, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		forwardedFor   string
+		expectedStatus int
+	}{
+		{name: "allowed client", remoteAddr: "10.1.2.3:52000", expectedStatus: http.StatusOK},
+		{name: "blocked client", remoteAddr: "203.0.113.7:52000", expectedStatus: http.StatusForbidden},
+		{name: "allowed client behind a proxy", remoteAddr: "203.0.113.1:443", forwardedFor: "10.1.2.3, 203.0.113.1", expectedStatus: http.StatusOK},
+		{name: "blocked client behind a proxy", remoteAddr: "10.0.0.1:443", forwardedFor: "203.0.113.7", expectedStatus: http.StatusForbidden},
+		{name: "spoofed header from an untrusted peer", remoteAddr: "203.0.113.7:52000", forwardedFor: "10.1.2.3", expectedStatus: http.StatusForbidden},
+		{name: "spoofed hop relayed by a proxy", remoteAddr: "203.0.113.1:443", forwardedFor: "10.1.2.3, 203.0.113.7", expectedStatus: http.StatusForbidden},
+		{name: "blocked IPv6 client", remoteAddr: "[2001:db8::1]:52000", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/admin/orchestrator", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+// CreateAPIToken issues a personal access token for the authenticated user
+// @Summary Create personal access token
+// @Description Issue a named, scoped and optionally expiring token usable as a bearer token by non-interactive clients. Tokens can be limited to some clusters and can't be scoped beyond the caller's permissions. The token is only returned in this response.
+// @Tags authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body auth.CreateAPITokenRequest true "Token name, scopes and expiry"
+// @Success 201 {object} auth.CreateAPITokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/tokens [post]
+func (h *AuthHandler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.tokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var req auth.CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.scopesGranted(w, r, user, &req) {
+		return
+	}
+
+	response, err := h.authService.CreateAPIToken(r.Context(), user.ID, &req, h.getClientIP(r), r.Header.Get("User-Agent"))
+	if err != nil {
+		h.writeAPITokenError(w, user, "Failed to create personal access token", err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusCreated, response)
+}
+
+// ListAPITokens lists the authenticated user's personal access tokens
+// @Summary List personal access tokens
+// @Description List the authenticated user's personal access tokens, including revoked and expired ones. Token values are never returned.
+// @Tags authentication
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/tokens [get]
+func (h *AuthHandler) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.tokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(r.Context(), user.ID)
+	if err != nil {
+		h.writeAPITokenError(w, user, "Failed to list personal access tokens", err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+// RevokeAPIToken revokes one of the authenticated user's personal access tokens
+// @Summary Revoke personal access token
+// @Description Revoke a personal access token; requests using it are rejected from then on
+// @Tags authentication
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Token ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/tokens/{id} [delete]
+func (h *AuthHandler) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.tokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	tokenID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid token ID")
+		return
+	}
+
+	err = h.authService.RevokeAPIToken(r.Context(), user.ID, tokenID, h.getClientIP(r), r.Header.Get("User-Agent"))
+	if err != nil {
+		if errors.Is(err, auth.ErrAPITokenNotFound) {
+			h.writeErrorResponse(w, http.StatusNotFound, "Personal access token not found")
+			return
+		}
+		h.writeAPITokenError(w, user, "Failed to revoke personal access token", err)
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, SuccessResponse{Message: "Personal access token revoked"})
+}
+
+// tokenOwner returns the user managing their tokens. Tokens can't be managed
+// with a personal access token, so a leaked token can't be used to mint more.
+func (h *AuthHandler) tokenOwner(w http.ResponseWriter, r *http.Request) (*auth.AuthenticatedUser, bool) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return nil, false
+	}
+	if user.ViaAPIToken() {
+		h.writeErrorResponse(w, http.StatusForbidden, "Personal access tokens can't manage tokens")
+		return nil, false
+	}
+	return user, true
+}
+
+// scopesGranted checks that the user holds every permission the token is
+// scoped to, so a token can't be issued with more than its owner may do.
+// Wildcard scopes are narrowed to the owner's permissions on every request.
+func (h *AuthHandler) scopesGranted(w http.ResponseWriter, r *http.Request, user *auth.AuthenticatedUser, req *auth.CreateAPITokenRequest) bool {
+	if h.authzService == nil {
+		return true
+	}
+
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return false
+	}
+
+	for _, permission := range req.Permissions() {
+		allowed, err := h.authzService.CheckPermission(r.Context(), userID, permission.Resource, permission.Action)
+		if err != nil {
+			h.logger.Error("Failed to check permission", zap.String("user_id", user.ID), zap.Error(err))
+			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to check permissions")
+			return false
+		}
+		if !allowed {
+			h.writeErrorResponse(w, http.StatusForbidden, fmt.Sprintf("Scope %s:%s exceeds your permissions", permission.Resource, permission.Action))
+			return false
+		}
+	}
+	return true
+}
+
+// writeAPITokenError maps personal access token errors to responses
+func (h *AuthHandler) writeAPITokenError(w http.ResponseWriter, user *auth.AuthenticatedUser, message string, err error) {
+	if errors.Is(err, auth.ErrAPITokensDisabled) {
+		h.writeErrorResponse(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	h.logger.Error(message, zap.String("user_id", user.ID), zap.Error(err))
+	h.writeErrorResponse(w, http.StatusInternalServerError, message)
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/auth"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func newTokenTestHandler(ctrl *gomock.Controller) (*AuthHandler, *repomocks.MockAPITokenRepository) {
+	tokenRepo := repomocks.NewMockAPITokenRepository(ctrl)
+	service := auth.NewAuthService(repomocks.NewMockUserRepository(ctrl), nil, nil, nil, auth.NewJWTManager("secret", time.Hour), auth.NewPasswordManager(nil), nil, nil, "viewer", zap.NewNop())
+	service.SetAPITokenRepository(tokenRepo)
+	return NewAuthHandler(service, nil, nil, zap.NewNop()), tokenRepo
+}
+
+func TestAuthHandler_CreateAPIToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler, tokenRepo := newTokenTestHandler(ctrl)
+	tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+	owner := &auth.AuthenticatedUser{ID: uuid.New().String(), Username: "alice"}
+	body := []byte(`{"name":"ci","scopes":["clusters:read"]}`)
+	req := httptest.NewRequest("POST", "/api/v1/auth/tokens", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, owner))
+	w := httptest.NewRecorder()
+
+	handler.CreateAPIToken(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if token, _ := response["token"].(string); len(token) <= len(auth.APITokenPrefix) {
+		t.Errorf("Expected the token in the response but got %v", response["token"])
+	}
+	if _, ok := response["token_hash"]; ok {
+		t.Errorf("Expected the token digest to be left out of the response: %+v", response)
+	}
+}
+
+func TestAuthHandler_APITokenCannotManageTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No token is created or revoked on behalf of a personal access token
+	handler, _ := newTokenTestHandler(ctrl)
+	tokenUser := &auth.AuthenticatedUser{ID: uuid.New().String(), TokenID: uuid.New().String(), Scopes: []string{"*"}}
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/tokens", bytes.NewReader([]byte(`{"name":"ci","scopes":["*"]}`)))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, tokenUser))
+	w := httptest.NewRecorder()
+
+	handler.CreateAPIToken(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d but got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestAuthHandler_RevokeAPIToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler, tokenRepo := newTokenTestHandler(ctrl)
+	owner := &auth.AuthenticatedUser{ID: uuid.New().String(), Username: "alice"}
+	ownerID := uuid.MustParse(owner.ID)
+	revoked, unknown := uuid.New(), uuid.New()
+
+	tokenRepo.EXPECT().Revoke(gomock.Any(), ownerID, revoked).Return(nil)
+	tokenRepo.EXPECT().Revoke(gomock.Any(), ownerID, unknown).Return(repo.ErrNotFound)
+
+	tests := []struct {
+		name           string
+		tokenID        string
+		expectedStatus int
+	}{
+		{name: "own token", tokenID: revoked.String(), expectedStatus: http.StatusOK},
+		{name: "unknown token", tokenID: unknown.String(), expectedStatus: http.StatusNotFound},
+		{name: "invalid ID", tokenID: "not-a-uuid", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", "/api/v1/auth/tokens/"+tt.tokenID, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.tokenID)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(context.WithValue(ctx, auth.UserContextKey, owner))
+			w := httptest.NewRecorder()
+
+			handler.RevokeAPIToken(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthHandler_CreateAPITokenBeyondPermissions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The owner may read clusters but not apply manifests to them
+	handler, tokenRepo := newTokenTestHandler(ctrl)
+	handler.authzService = auth.NewAuthorizationService(&staticStrategy{granted: map[string]bool{"clusters:read": true}}, zap.NewNop())
+	owner := &auth.AuthenticatedUser{ID: uuid.New().String(), Username: "alice"}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "granted scope", body: `{"name":"ci","scopes":["clusters:read"]}`, expectedStatus: http.StatusCreated},
+		{name: "scope beyond permissions", body: `{"name":"ci","scopes":["clusters:read","clusters:manage"]}`, expectedStatus: http.StatusForbidden},
+		{name: "wildcard scope is narrowed on use", body: `{"name":"ci","scopes":["clusters:*"],"cluster_ids":["` + uuid.New().String() + `"]}`, expectedStatus: http.StatusCreated},
+	}
+
+	tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/auth/tokens", bytes.NewReader([]byte(tt.body)))
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, owner))
+			w := httptest.NewRecorder()
+
+			handler.CreateAPIToken(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/auth"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// apiPrefix is stripped from route patterns to find the resource type of a request
+const apiPrefix = "/api/v1/"
+
+// AuditOptions controls what is recorded for audited requests
+type AuditOptions struct {
+	IncludeRequestPayload  bool
+	IncludeResponsePayload bool
+	// MaxPayloadSize bounds recorded payloads; larger ones are left out. Zero means no limit.
+	MaxPayloadSize int
+	// ExcludeRoutes lists route patterns that are never audited
+	ExcludeRoutes []string
+}
+
+// excluded reports whether a route pattern is excluded from auditing. Patterns
+// match case-insensitively because configuration values may be lower-cased.
+func (o AuditOptions) excluded(pattern string) bool {
+	for _, route := range o.ExcludeRoutes {
+		if strings.EqualFold(route, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditDetailsKey is the context key of the details handlers add to the
+// audit entry of a request
+type auditDetailsKey struct{}
+
+// addAuditDetail records a detail, such as the provenance of an operation a
+// request creates, in the request's audit entry. Details are kept with the
+// request payload even when the payload itself is not recorded. It does nothing
+// for requests that are not audited.
+func addAuditDetail(r *http.Request, key string, value interface{}) {
+	if details, ok := r.Context().Value(auditDetailsKey{}).(repo.Payload); ok {
+		details[key] = value
+	}
+}
+
+// isMutating reports whether a request method changes state
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// auditMiddleware records an audit entry for every mutating request. It must
+// run after authentication so the acting user is known.
+func auditMiddleware(auditRepo repo.AuditLogRepository, opts AuditOptions, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !isMutating(req.Method) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			var requestBody []byte
+			if opts.IncludeRequestPayload && req.Body != nil {
+				requestBody, req.Body = peekBody(req.Body, opts.MaxPayloadSize)
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, req.ProtoMajor)
+			var responseBody *boundedBuffer
+			if opts.IncludeResponsePayload {
+				responseBody = &boundedBuffer{limit: opts.MaxPayloadSize}
+				ww.Tee(responseBody)
+			}
+
+			details := repo.Payload{}
+			next.ServeHTTP(ww, req.WithContext(context.WithValue(req.Context(), auditDetailsKey{}, details)))
+
+			// The route pattern and URL parameters are only known once chi has routed the request
+			rctx := chi.RouteContext(req.Context())
+			pattern := req.URL.Path
+			if rctx != nil && rctx.RoutePattern() != "" {
+				pattern = rctx.RoutePattern()
+			}
+			if opts.excluded(pattern) {
+				return
+			}
+
+			resourceType, action := auditAction(req.Method, pattern)
+			entry := &repo.AuditLog{
+				ID:           uuid.New(),
+				UserID:       "anonymous",
+				Action:       action,
+				ResourceType: resourceType,
+				ResourceID:   auditResourceID(rctx),
+				Method:       req.Method,
+				Path:         req.URL.Path,
+				StatusCode:   ww.Status(),
+				IPAddress:    req.RemoteAddr,
+				UserAgent:    req.UserAgent(),
+				CreatedAt:    time.Now().UTC(),
+			}
+			if user, ok := auth.GetUserFromContext(req.Context()); ok {
+				entry.UserID = user.ID
+			}
+			if requestBody != nil {
+				entry.RequestPayload = decodePayload(requestBody)
+			}
+			if len(details) > 0 {
+				if entry.RequestPayload == nil {
+					entry.RequestPayload = &repo.Payload{}
+				}
+				for key, value := range details {
+					(*entry.RequestPayload)[key] = value
+				}
+			}
+			if responseBody != nil && !responseBody.overflow {
+				entry.ResponsePayload = decodePayload(responseBody.Bytes())
+			}
+
+			// Record the entry even if the client went away once the response was written
+			if err := auditRepo.Create(context.WithoutCancel(req.Context()), entry); err != nil {
+				logger.Error("Failed to record audit entry",
+					zap.Error(err),
+					zap.String("action", entry.Action),
+					zap.String("path", entry.Path),
+				)
+			}
+		})
+	}
+}
+
+// auditAction derives the resource type and action of a request from its route
+// pattern. Routes ending in a verb, such as "/operations/{id}/cancel", use it as
+// the action; other routes use the verb implied by the method, so
+// "PUT /api/v1/clusters/{id}" is "clusters.update".
+func auditAction(method, pattern string) (resourceType, action string) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(pattern, apiPrefix), "/"), "/")
+	resourceType = segments[0]
+
+	verb := ""
+	if last := segments[len(segments)-1]; !strings.HasPrefix(last, "{") {
+		if i := strings.LastIndex(last, ":"); i >= 0 {
+			// Custom methods such as "/clusters/{id}/operations:cancelAll"
+			verb = last[i+1:]
+		} else if len(segments) > 1 {
+			verb = last
+		}
+	}
+	if verb == "" {
+		switch method {
+		case http.MethodPost:
+			verb = "create"
+		case http.MethodPut, http.MethodPatch:
+			verb = "update"
+		case http.MethodDelete:
+			verb = "delete"
+		}
+	}
+
+	return resourceType, resourceType + "." + verb
+}
+
+// auditResourceID returns the ID of the resource a request targets, if any
+func auditResourceID(rctx *chi.Context) string {
+	if rctx == nil {
+		return ""
+	}
+	for _, key := range []string{"id", "clusterId"} {
+		if id := rctx.URLParam(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// peekBody reads up to limit bytes of a request body for auditing and returns
+// a replacement body that still yields the full content to the handler. The
+// returned payload is nil when the body exceeds the limit.
+func peekBody(body io.ReadCloser, limit int) ([]byte, io.ReadCloser) {
+	reader := io.Reader(body)
+	if limit > 0 {
+		reader = io.LimitReader(body, int64(limit)+1)
+	}
+
+	data, err := io.ReadAll(reader)
+	replacement := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), body), body}
+
+	if err != nil || (limit > 0 && len(data) > limit) {
+		return nil, replacement
+	}
+	return data, replacement
+}
+
+// decodePayload decodes a JSON object for storage; other bodies, such as
+// multipart uploads, are not recorded
+func decodePayload(data []byte) *repo.Payload {
+	var payload repo.Payload
+	if err := json.Unmarshal(data, &payload); err != nil || payload == nil {
+		return nil
+	}
+	return &payload
+}
+
+// boundedBuffer captures a response body up to limit bytes, remembering
+// whether it had to drop anything. A zero limit captures everything.
+type boundedBuffer struct {
+	bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	}
+	if b.limit > 0 && b.Len()+len(p) > b.limit {
+		b.overflow = true
+		b.Reset()
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
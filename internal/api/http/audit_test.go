@@ -0,0 +1,118 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/api/http/mocks"
+	"github.com/rizesky/mckmt/internal/auth"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestAuditMiddleware_ClusterUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockClusterManager(ctrl)
+	mockAuditRepo := repomocks.NewMockAuditLogRepository(ctrl)
+	handler := NewClusterHandler(mockService, zap.NewNop())
+	clusterID := uuid.New()
+	user := &auth.AuthenticatedUser{ID: uuid.New().String(), Username: "admin"}
+
+	mockService.EXPECT().UpdateCluster(gomock.Any(), clusterID, "prod", "", gomock.Any()).Return(nil)
+
+	var entry *repo.AuditLog
+	mockAuditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, log *repo.AuditLog) error {
+		entry = log
+		return nil
+	})
+
+	router := chi.NewRouter()
+	router.Route("/api/v1", func(api chi.Router) {
+		api.Group(func(protected chi.Router) {
+			// Stand in for RequireAuth
+			protected.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user)))
+				})
+			})
+			protected.Use(auditMiddleware(mockAuditRepo, AuditOptions{IncludeRequestPayload: true}, zap.NewNop()))
+			protected.Route("/clusters", func(clusters chi.Router) {
+				clusters.Get("/{id}", func(w http.ResponseWriter, _ *http.Request) {
+					WriteJSONResponse(w, http.StatusOK, map[string]string{})
+				})
+				clusters.Put("/{id}", handler.UpdateCluster)
+			})
+		})
+	})
+
+	// Reads are not audited
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/clusters/"+clusterID.String(), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"name": "prod"})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PUT", "/api/v1/clusters/"+clusterID.String(), bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	if entry == nil {
+		t.Fatal("Expected an audit entry for the cluster update")
+	}
+	if entry.Action != "clusters.update" {
+		t.Errorf("Expected action clusters.update but got %s", entry.Action)
+	}
+	if entry.ResourceType != "clusters" || entry.ResourceID != clusterID.String() {
+		t.Errorf("Expected resource clusters/%s but got %s/%s", clusterID, entry.ResourceType, entry.ResourceID)
+	}
+	if entry.UserID != user.ID {
+		t.Errorf("Expected user %s but got %s", user.ID, entry.UserID)
+	}
+	if entry.Method != "PUT" || entry.Path != "/api/v1/clusters/"+clusterID.String() || entry.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected request details: %s %s %d", entry.Method, entry.Path, entry.StatusCode)
+	}
+	if entry.RequestPayload == nil || (*entry.RequestPayload)["name"] != "prod" {
+		t.Errorf("Expected the request payload to be recorded, got %v", entry.RequestPayload)
+	}
+	if entry.ResponsePayload != nil {
+		t.Errorf("Expected no response payload, got %v", entry.ResponsePayload)
+	}
+}
+
+func TestAuditAction(t *testing.T) {
+	tests := []struct {
+		method       string
+		pattern      string
+		resourceType string
+		action       string
+	}{
+		{method: "POST", pattern: "/api/v1/clusters/", resourceType: "clusters", action: "clusters.create"},
+		{method: "PUT", pattern: "/api/v1/clusters/{id}", resourceType: "clusters", action: "clusters.update"},
+		{method: "DELETE", pattern: "/api/v1/clusters/{id}", resourceType: "clusters", action: "clusters.delete"},
+		{method: "POST", pattern: "/api/v1/clusters/{id}/operations:cancelAll", resourceType: "clusters", action: "clusters.cancelAll"},
+		{method: "POST", pattern: "/api/v1/operations/{id}/cancel", resourceType: "operations", action: "operations.cancel"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.pattern, func(t *testing.T) {
+			resourceType, action := auditAction(tt.method, tt.pattern)
+			if resourceType != tt.resourceType || action != tt.action {
+				t.Errorf("Expected %s/%s but got %s/%s", tt.resourceType, tt.action, resourceType, action)
+			}
+		})
+	}
+}
@@ -2,9 +2,14 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	"github.com/rizesky/mckmt/internal/api"
@@ -16,17 +21,50 @@ type AuthMethodsResponse struct {
 	Methods []auth.AuthMethod `json:"methods"`
 }
 
+// maxPermissionChecks bounds the number of checks in a single batch request
+const maxPermissionChecks = 100
+
+// PermissionCheck is a single resource/action pair to evaluate
+type PermissionCheck struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// CheckPermissionsRequest represents a batch permission check request
+type CheckPermissionsRequest struct {
+	Checks []PermissionCheck `json:"checks"`
+}
+
+// PermissionCheckResult is the outcome of a single permission check
+type PermissionCheckResult struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Allowed  bool   `json:"allowed"`
+}
+
+// CheckPermissionsResponse represents a batch permission check response, in request order
+type CheckPermissionsResponse struct {
+	Results []PermissionCheckResult `json:"results"`
+}
+
+// oidcRedirectCookie holds the validated post-login redirect target between login and callback
+const oidcRedirectCookie = "mckmt_oidc_redirect"
+
 // AuthHandler handles authentication HTTP requests
 type AuthHandler struct {
-	authService *auth.Service
-	logger      *zap.Logger
+	authService       *auth.Service
+	authzService      *auth.AuthorizationService
+	redirectValidator *auth.RedirectValidator
+	logger            *zap.Logger
 }
 
 // NewAuthHandler creates a new authentication handler
-func NewAuthHandler(authService *auth.Service, logger *zap.Logger) *AuthHandler {
+func NewAuthHandler(authService *auth.Service, authzService *auth.AuthorizationService, redirectValidator *auth.RedirectValidator, logger *zap.Logger) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		logger:      logger,
+		authService:       authService,
+		authzService:      authzService,
+		redirectValidator: redirectValidator,
+		logger:            logger,
 	}
 }
 
@@ -43,6 +81,8 @@ func NewAuthHandler(authService *auth.Service, logger *zap.Logger) *AuthHandler
 // @Success 200 {object} auth.LoginResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -69,7 +109,9 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 		// Return more specific error messages for better user experience
 		errorMsg := err.Error()
-		if strings.Contains(errorMsg, "invalid credentials") ||
+		if errors.Is(err, auth.ErrAccountLocked) {
+			h.writeErrorResponse(w, http.StatusForbidden, "Account temporarily locked after repeated failed logins")
+		} else if strings.Contains(errorMsg, "invalid credentials") ||
 			strings.Contains(errorMsg, "user not found") ||
 			strings.Contains(errorMsg, "invalid password") {
 			h.writeErrorResponse(w, http.StatusUnauthorized, "Invalid credentials")
@@ -95,6 +137,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 // @Success 201 {object} auth.RegisterResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/register [post]
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -149,6 +192,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} auth.RefreshTokenResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/refresh [post]
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
@@ -181,10 +225,11 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 
 // Logout handles user logout
 // @Summary Logout user
-// @Description Logout user and invalidate session
+// @Description Logout user and revoke the access token until it expires
 // @Tags authentication
 // @Security BearerAuth
 // @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/logout [post]
@@ -200,8 +245,15 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	ipAddress := h.getClientIP(r)
 	userAgent := r.Header.Get("User-Agent")
 
+	// Personal access tokens are revoked through their own endpoint
+	token, err := auth.ExtractTokenFromHeader(r.Header.Get("Authorization"))
+	if err != nil || strings.HasPrefix(token, auth.APITokenPrefix) {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Logout requires a session token")
+		return
+	}
+
 	// Perform logout
-	err := h.authService.Logout(r.Context(), user.ID, ipAddress, userAgent)
+	err = h.authService.Logout(r.Context(), user.ID, token, ipAddress, userAgent)
 	if err != nil {
 		h.logger.Error("Logout failed", zap.String("user_id", user.ID), zap.Error(err))
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Logout failed")
@@ -290,6 +342,76 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	h.writeJSONResponse(w, http.StatusOK, userDTO)
 }
 
+// CheckPermissions evaluates a batch of permissions for the authenticated user
+// @Summary Check permissions in batch
+// @Description Evaluate several resource/action pairs for the authenticated user at once, e.g. to gate UI controls
+// @Tags authentication
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CheckPermissionsRequest true "Permissions to check"
+// @Success 200 {object} CheckPermissionsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/permissions:check [post]
+func (h *AuthHandler) CheckPermissions(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeErrorResponse(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req CheckPermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Checks) == 0 {
+		h.writeErrorResponse(w, http.StatusBadRequest, "At least one permission check is required")
+		return
+	}
+	if len(req.Checks) > maxPermissionChecks {
+		h.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("At most %d permission checks are allowed per request", maxPermissionChecks))
+		return
+	}
+
+	results := make([]PermissionCheckResult, len(req.Checks))
+	for i, check := range req.Checks {
+		if check.Resource == "" || check.Action == "" {
+			h.writeErrorResponse(w, http.StatusBadRequest, "Resource and action are required for every check")
+			return
+		}
+
+		allowed, err := h.authzService.CheckPermission(r.Context(), userID, check.Resource, check.Action)
+		if err != nil {
+			h.logger.Error("Failed to check permission",
+				zap.Error(err),
+				zap.String("user_id", user.ID),
+				zap.String("resource", check.Resource),
+				zap.String("action", check.Action),
+			)
+			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to check permissions")
+			return
+		}
+
+		results[i] = PermissionCheckResult{
+			Resource: check.Resource,
+			Action:   check.Action,
+			Allowed:  allowed,
+		}
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, CheckPermissionsResponse{Results: results})
+}
+
 // GetAuthMethods returns available authentication methods
 // @Summary Get available auth methods
 // @Description Returns list of available authentication methods
@@ -304,9 +426,11 @@ func (h *AuthHandler) GetAuthMethods(w http.ResponseWriter, r *http.Request) {
 
 // OIDCLogin initiates OIDC login flow
 // @Summary Initiate OIDC login
-// @Description Redirects to OIDC provider for authentication
+// @Description Redirects to OIDC provider for authentication. An optional post-login
+// @Description redirect target must be a relative path or on an allowed origin.
 // @Tags authentication
 // @Produce json
+// @Param redirect_uri query string false "Post-login redirect target"
 // @Success 302 {string} string "Redirect to OIDC provider"
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -316,6 +440,19 @@ func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
 	ipAddress := h.getClientIP(r)
 	userAgent := r.Header.Get("User-Agent")
 
+	// Reject post-login redirect targets outside the allowlist
+	redirectURI := r.URL.Query().Get("redirect_uri")
+	if redirectURI != "" {
+		if err := h.redirectValidator.Validate(redirectURI); err != nil {
+			h.logger.Warn("Rejected OIDC post-login redirect",
+				zap.String("redirect_uri", redirectURI),
+				zap.String("ip", ipAddress),
+			)
+			h.writeErrorResponse(w, http.StatusBadRequest, "Redirect target is not allowed")
+			return
+		}
+	}
+
 	// Initiate OIDC login
 	authURL, err := h.authService.OIDCInitiateLogin(r.Context(), ipAddress, userAgent)
 	if err != nil {
@@ -324,18 +461,32 @@ func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if redirectURI != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcRedirectCookie,
+			Value:    url.QueryEscape(redirectURI),
+			Path:     "/",
+			MaxAge:   600,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
 	// Redirect to OIDC provider
 	http.Redirect(w, r, authURL, http.StatusFound)
 }
 
 // OIDCCallback handles OIDC callback
 // @Summary Handle OIDC callback
-// @Description Processes OIDC callback and returns JWT tokens
+// @Description Processes OIDC callback and returns JWT tokens. If the login was started
+// @Description with an allowed redirect target, redirects there with the tokens in the URL fragment.
 // @Tags authentication
 // @Produce json
 // @Param code query string true "Authorization code"
 // @Param state query string true "State parameter"
 // @Success 200 {object} auth.LoginResponse
+// @Success 302 {string} string "Redirect to the post-login target"
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/oidc/callback [get]
@@ -361,9 +512,50 @@ func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if target := h.postLoginRedirect(w, r); target != "" {
+		fragment := url.Values{
+			"access_token":  {response.AccessToken},
+			"refresh_token": {response.RefreshToken},
+			"token_type":    {response.TokenType},
+			"expires_at":    {response.ExpiresAt.Format(time.RFC3339)},
+		}
+		http.Redirect(w, r, target+"#"+fragment.Encode(), http.StatusFound)
+		return
+	}
+
 	h.writeJSONResponse(w, http.StatusOK, response)
 }
 
+// postLoginRedirect returns the redirect target stored at login, re-validated
+// against the allowlist, and clears it. It returns "" if there is none.
+func (h *AuthHandler) postLoginRedirect(w http.ResponseWriter, r *http.Request) string {
+	cookie, err := r.Cookie(oidcRedirectCookie)
+	if err != nil {
+		return ""
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcRedirectCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	target, err := url.QueryUnescape(cookie.Value)
+	if err != nil || h.redirectValidator.Validate(target) != nil {
+		h.logger.Warn("Ignoring invalid OIDC post-login redirect", zap.String("redirect_uri", cookie.Value))
+		return ""
+	}
+
+	// Tokens are appended as a fragment, so drop any fragment already present
+	if i := strings.Index(target, "#"); i >= 0 {
+		target = target[:i]
+	}
+
+	return target
+}
+
 // OIDCLogout handles OIDC logout
 // @Summary OIDC logout
 // @Description Logs out user and redirects to OIDC provider logout
@@ -437,25 +629,7 @@ func (h *AuthHandler) validateChangePasswordRequest(req *auth.ChangePasswordRequ
 
 // getClientIP extracts the client IP address from the request
 func (h *AuthHandler) getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-
-	// Fall back to RemoteAddr
-	ip := r.RemoteAddr
-	if colon := strings.LastIndex(ip, ":"); colon != -1 {
-		ip = ip[:colon]
-	}
-	return ip
+	return clientIP(r)
 }
 
 // writeJSONResponse writes a JSON response
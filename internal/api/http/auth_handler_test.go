@@ -0,0 +1,189 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+func newTestAuthHandler() *AuthHandler {
+	return NewAuthHandler(nil, nil, auth.NewRedirectValidator([]string{"https://ui.example.com"}), zap.NewNop())
+}
+
+// staticStrategy grants exactly the listed "resource:action" permissions
+type staticStrategy struct {
+	granted map[string]bool
+}
+
+func (s *staticStrategy) CheckPermission(_ context.Context, _ uuid.UUID, resource, action string) (bool, error) {
+	return s.granted[resource+":"+action], nil
+}
+
+func (s *staticStrategy) IsEnabled() bool { return true }
+
+func (s *staticStrategy) GetName() string { return "static" }
+
+func TestAuthHandler_CheckPermissions(t *testing.T) {
+	strategy := &staticStrategy{granted: map[string]bool{
+		"clusters:read":     true,
+		"operations:cancel": true,
+	}}
+	handler := NewAuthHandler(nil, auth.NewAuthorizationService(strategy, zap.NewNop()), nil, zap.NewNop())
+	user := &auth.AuthenticatedUser{ID: uuid.New().String(), Username: "viewer"}
+
+	tooMany := make([]PermissionCheck, maxPermissionChecks+1)
+	for i := range tooMany {
+		tooMany[i] = PermissionCheck{Resource: "clusters", Action: fmt.Sprintf("action-%d", i)}
+	}
+
+	tests := []struct {
+		name            string
+		checks          []PermissionCheck
+		expectedStatus  int
+		expectedAllowed []bool
+	}{
+		{
+			name: "mix of granted and denied checks",
+			checks: []PermissionCheck{
+				{Resource: "clusters", Action: "read"},
+				{Resource: "clusters", Action: "delete"},
+				{Resource: "operations", Action: "cancel"},
+				{Resource: "users", Action: "write"},
+			},
+			expectedStatus:  http.StatusOK,
+			expectedAllowed: []bool{true, false, true, false},
+		},
+		{
+			name:           "empty list",
+			checks:         []PermissionCheck{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too many checks",
+			checks:         tooMany,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing action",
+			checks:         []PermissionCheck{{Resource: "clusters"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(CheckPermissionsRequest{Checks: tt.checks})
+			req := httptest.NewRequest("POST", "/api/v1/auth/permissions:check", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+			w := httptest.NewRecorder()
+
+			handler.CheckPermissions(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response CheckPermissionsResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Results) != len(tt.expectedAllowed) {
+				t.Fatalf("Expected %d results, got %d", len(tt.expectedAllowed), len(response.Results))
+			}
+			for i, result := range response.Results {
+				if result.Resource != tt.checks[i].Resource || result.Action != tt.checks[i].Action {
+					t.Errorf("Result %d is for %s:%s, expected %s:%s", i, result.Resource, result.Action, tt.checks[i].Resource, tt.checks[i].Action)
+				}
+				if result.Allowed != tt.expectedAllowed[i] {
+					t.Errorf("Expected %s:%s allowed=%v, got %v", result.Resource, result.Action, tt.expectedAllowed[i], result.Allowed)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_CheckPermissionsRequiresUser(t *testing.T) {
+	handler := newTestAuthHandler()
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/permissions:check", bytes.NewReader([]byte(`{"checks":[]}`)))
+	w := httptest.NewRecorder()
+
+	handler.CheckPermissions(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestAuthHandler_OIDCLoginRejectsDisallowedRedirect(t *testing.T) {
+	handler := newTestAuthHandler()
+
+	req := httptest.NewRequest("GET", "/api/v1/auth/oidc/login?redirect_uri="+url.QueryEscape("https://evil.example.com/"), nil)
+	w := httptest.NewRecorder()
+
+	handler.OIDCLogin(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("Expected no redirect cookie to be set for a rejected target")
+	}
+}
+
+func TestAuthHandler_PostLoginRedirect(t *testing.T) {
+	tests := []struct {
+		name     string
+		cookie   string
+		expected string
+	}{
+		{
+			name:     "allowed origin",
+			cookie:   "https://ui.example.com/clusters#old",
+			expected: "https://ui.example.com/clusters",
+		},
+		{
+			name:     "relative path",
+			cookie:   "/dashboard",
+			expected: "/dashboard",
+		},
+		{
+			name:     "disallowed origin is ignored",
+			cookie:   "https://evil.example.com/",
+			expected: "",
+		},
+		{
+			name:     "no cookie",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestAuthHandler()
+
+			req := httptest.NewRequest("GET", "/api/v1/auth/oidc/callback", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcRedirectCookie, Value: url.QueryEscape(tt.cookie)})
+			}
+			w := httptest.NewRecorder()
+
+			if target := handler.postLoginRedirect(w, req); target != tt.expected {
+				t.Errorf("Expected target %q, got %q", tt.expected, target)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -11,12 +12,15 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/rizesky/mckmt/internal/cluster"
+	"github.com/rizesky/mckmt/internal/kube"
 	"github.com/rizesky/mckmt/internal/repo"
 )
 
 // ClusterHandler handles cluster-related HTTP requests
 type ClusterHandler struct {
 	clusterService ClusterManager
+	pagination     Pagination
 	logger         *zap.Logger
 }
 
@@ -24,6 +28,7 @@ type ClusterHandler struct {
 func NewClusterHandler(clusterService ClusterManager, logger *zap.Logger) *ClusterHandler {
 	return &ClusterHandler{
 		clusterService: clusterService,
+		pagination:     DefaultPagination,
 		logger:         logger,
 	}
 }
@@ -38,56 +43,86 @@ func NewClusterHandler(clusterService ClusterManager, logger *zap.Logger) *Clust
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} ClusterDTO
+// @Param limit query int false "Maximum number of clusters, capped at the server's max_limit"
+// @Param offset query int false "Number of clusters to skip" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /clusters [get]
 func (h *ClusterHandler) ListClusters(w http.ResponseWriter, r *http.Request) {
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
+	limit, offset, ok := h.pagination.parse(w, r)
+	if !ok {
+		return
+	}
 
-	limit := 10
-	offset := 0
+	clusters, err := h.clusterService.ListClusters(r.Context(), limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list clusters", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list clusters")
+		return
+	}
 
-	if limitStr != "" {
-		var err error
-		limit, err = strconv.Atoi(limitStr)
-		if err != nil {
-			WriteErrorResponse(w, http.StatusBadRequest, "Invalid limit parameter")
-			return
-		}
-		if limit <= 0 {
-			WriteErrorResponse(w, http.StatusBadRequest, "Limit must be positive")
-			return
-		}
+	total, err := h.clusterService.CountClusters(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to count clusters", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list clusters")
+		return
 	}
 
-	if offsetStr != "" {
-		var err error
-		offset, err = strconv.Atoi(offsetStr)
-		if err != nil {
-			WriteErrorResponse(w, http.StatusBadRequest, "Invalid offset parameter")
-			return
-		}
-		if offset < 0 {
-			WriteErrorResponse(w, http.StatusBadRequest, "Offset must be non-negative")
-			return
-		}
+	WriteJSONResponse(w, http.StatusOK, listResponse(h.pagination.page(limit, offset, len(clusters), total), map[string]interface{}{
+		"clusters": clusters,
+	}))
+}
+
+// ListAgents handles listing the agents of registered clusters
+// @Summary List cluster agents
+// @Description Get the version and connection status of the agent of each registered cluster
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Maximum number of agents, capped at the server's max_limit"
+// @Param offset query int false "Number of agents to skip" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /agents [get]
+func (h *ClusterHandler) ListAgents(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := h.pagination.parse(w, r)
+	if !ok {
+		return
 	}
 
 	clusters, err := h.clusterService.ListClusters(r.Context(), limit, offset)
 	if err != nil {
-		h.logger.Error("Failed to list clusters", zap.Error(err))
-		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list clusters")
+		h.logger.Error("Failed to list agents", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list agents")
 		return
 	}
 
-	WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"clusters":    clusters,
-		"total_count": len(clusters),
-		"limit":       limit,
-		"offset":      offset,
-	})
+	agents := make([]AgentDTO, 0, len(clusters))
+	for _, c := range clusters {
+		agents = append(agents, AgentDTO{
+			ClusterID:    c.ID.String(),
+			ClusterName:  c.Name,
+			AgentVersion: c.Labels[repo.AgentVersionLabel],
+			Status:       c.Status,
+			LastSeen:     c.LastSeenAt,
+		})
+	}
+
+	total, err := h.clusterService.CountClusters(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to count agents", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list agents")
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, listResponse(h.pagination.page(limit, offset, len(agents), total), map[string]interface{}{
+		"agents": agents,
+	}))
 }
 
 // GetCluster handles getting a single cluster
@@ -98,7 +133,9 @@ func (h *ClusterHandler) ListClusters(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Cluster ID"
+// @Param If-None-Match header string false "ETag of a previously fetched representation"
 // @Success 200 {object} ClusterDTO
+// @Success 304 "Not modified since the given ETag"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
@@ -125,9 +162,51 @@ func (h *ClusterHandler) GetCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if notModified(w, r, resourceETag(cluster.ID, cluster.UpdatedAt)) {
+		return
+	}
+
 	WriteJSONResponse(w, http.StatusOK, cluster)
 }
 
+// CreateCluster handles creating a cluster
+// @Summary Create cluster
+// @Description Create a cluster record ahead of its agent registering under the same name
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param cluster body CreateClusterRequest true "Cluster data"
+// @Success 201 {object} ClusterDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clusters [post]
+func (h *ClusterHandler) CreateCluster(w http.ResponseWriter, r *http.Request) {
+	var req CreateClusterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	created, err := h.clusterService.CreateCluster(r.Context(), req.Name, req.Description, req.Labels)
+	if err != nil {
+		switch {
+		case errors.Is(err, cluster.ErrClusterNameRequired), errors.Is(err, cluster.ErrClusterLabelsInvalid):
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, cluster.ErrClusterAlreadyExists):
+			WriteErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			h.logger.Error("Failed to create cluster", zap.Error(err))
+			WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create cluster")
+		}
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusCreated, ToClusterDTO(created))
+}
+
 // UpdateCluster handles updating a cluster
 // @Summary Update cluster
 // @Description Update an existing cluster
@@ -165,7 +244,7 @@ func (h *ClusterHandler) UpdateCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cluster := &repo.Cluster{
+	updated := &repo.Cluster{
 		ID:          id,
 		Name:        req.Name,
 		Description: req.Description,
@@ -174,13 +253,17 @@ func (h *ClusterHandler) UpdateCluster(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:   time.Now(),
 	}
 
-	if err := h.clusterService.UpdateCluster(r.Context(), cluster.ID, cluster.Name, cluster.Description, map[string]string(cluster.Labels)); err != nil {
+	if err := h.clusterService.UpdateCluster(r.Context(), updated.ID, updated.Name, updated.Description, map[string]string(updated.Labels)); err != nil {
+		if errors.Is(err, cluster.ErrClusterLabelsInvalid) {
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.logger.Error("Failed to update cluster", zap.Error(err))
 		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update cluster")
 		return
 	}
 
-	WriteJSONResponse(w, http.StatusOK, cluster)
+	WriteJSONResponse(w, http.StatusOK, updated)
 }
 
 // DeleteCluster handles deleting a cluster
@@ -218,18 +301,23 @@ func (h *ClusterHandler) DeleteCluster(w http.ResponseWriter, r *http.Request) {
 
 // ListClusterResources handles listing cluster resources
 // @Summary List cluster resources
-// @Description Get a list of resources in a specific cluster
+// @Description Get the resources of a kind in a specific cluster as listed by its agent. Returns 202 with an operation ID when a fresh listing has been queued. Listing needs a connected agent and returns 424 without one, unless allow_stale is set and the last known resources are available; these are returned with stale and as_of set.
 // @Tags clusters
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Cluster ID"
-// @Param namespace query string false "Namespace filter"
-// @Param kind query string false "Resource kind filter"
-// @Success 200 {array} map[string]interface{}
+// @Param namespace query string false "Namespace filter (empty lists all namespaces, must be empty for cluster-scoped kinds)"
+// @Param kind query string true "Resource kind, optionally qualified with its group, e.g. Deployment.apps"
+// @Param allow_stale query boolean false "Serve the last known resources, marked stale, when the cluster agent is not connected"
+// @Success 200 {object} cluster.GetClusterResourcesResponse
+// @Success 202 {object} map[string]interface{}
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 424 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /clusters/{id}/resources [get]
 func (h *ClusterHandler) ListClusterResources(w http.ResponseWriter, r *http.Request) {
@@ -245,35 +333,403 @@ func (h *ClusterHandler) ListClusterResources(w http.ResponseWriter, r *http.Req
 	kind := r.URL.Query().Get("kind")
 	namespace := r.URL.Query().Get("namespace")
 
-	resources, err := h.clusterService.GetClusterResources(r.Context(), id, kind, namespace)
+	allowStale := false
+	if raw := r.URL.Query().Get("allow_stale"); raw != "" {
+		allowStale, err = strconv.ParseBool(raw)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "allow_stale must be a boolean")
+			return
+		}
+	}
+
+	resources, pending, err := h.clusterService.GetClusterResources(r.Context(), id, kind, namespace, allowStale)
 	if err != nil {
+		if errors.Is(err, kube.ErrNamespaceNotAllowed) || errors.Is(err, cluster.ErrResourceKindRequired) || errors.Is(err, cluster.ErrOperationNotSupported) {
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrClusterNotFound) {
+			WriteErrorResponse(w, http.StatusNotFound, "Cluster not found")
+			return
+		}
+		if errors.Is(err, cluster.ErrClusterNotConnected) {
+			WriteErrorResponse(w, http.StatusFailedDependency, "Cluster agent not connected")
+			return
+		}
+		if errors.Is(err, cluster.ErrClusterQuarantined) {
+			WriteErrorResponse(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationRejected) {
+			WriteErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
 		h.logger.Error("Failed to get cluster resources", zap.Error(err))
 		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get cluster resources")
 		return
 	}
 
+	if pending != nil {
+		WriteJSONResponse(w, http.StatusAccepted, map[string]interface{}{
+			"operation_id": pending.ID.String(),
+			"status":       pending.Status,
+			"message":      "Resource listing queued",
+		})
+		return
+	}
+
+	// Flag last known resources of a cluster without agent for clients and caches
+	if resources.Stale {
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+	}
+	WriteJSONResponse(w, http.StatusOK, resources)
+}
+
+// ListNamespaces handles listing the namespaces of a cluster
+// @Summary List cluster namespaces
+// @Description Get the namespaces of a specific cluster as reported by its agent. Returns 202 with an operation ID when a fresh listing has been queued.
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Success 200 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clusters/{id}/namespaces [get]
+func (h *ClusterHandler) ListNamespaces(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path using Chi
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	namespaces, pending, err := h.clusterService.ListNamespaces(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, cluster.ErrClusterNotFound) {
+			WriteErrorResponse(w, http.StatusNotFound, "Cluster not found")
+			return
+		}
+		if errors.Is(err, cluster.ErrClusterQuarantined) {
+			WriteErrorResponse(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationRejected) {
+			WriteErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationNotSupported) {
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to list namespaces", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list namespaces")
+		return
+	}
+
+	if pending != nil {
+		WriteJSONResponse(w, http.StatusAccepted, map[string]interface{}{
+			"operation_id": pending.ID.String(),
+			"status":       pending.Status,
+			"message":      "Namespace listing queued",
+		})
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"cluster_id":  id,
+		"namespaces":  namespaces,
+		"total_count": len(namespaces),
+	})
+}
+
+// ListNodes handles listing the nodes of a cluster
+// @Summary List cluster nodes
+// @Description Get the nodes of a specific cluster with readiness, roles and allocatable capacity. Returns 202 with an operation ID when a fresh listing has been queued.
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Success 200 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clusters/{id}/nodes [get]
+func (h *ClusterHandler) ListNodes(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path using Chi
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	nodes, pending, err := h.clusterService.ListNodes(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, cluster.ErrClusterNotFound) {
+			WriteErrorResponse(w, http.StatusNotFound, "Cluster not found")
+			return
+		}
+		if errors.Is(err, cluster.ErrClusterQuarantined) {
+			WriteErrorResponse(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationRejected) {
+			WriteErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationNotSupported) {
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to list nodes", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list nodes")
+		return
+	}
+
+	if pending != nil {
+		WriteJSONResponse(w, http.StatusAccepted, map[string]interface{}{
+			"operation_id": pending.ID.String(),
+			"status":       pending.Status,
+			"message":      "Node listing queued",
+		})
+		return
+	}
+
 	WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
 		"cluster_id":  id,
-		"resources":   resources,
-		"total_count": len(resources),
-		"kind":        kind,
-		"namespace":   namespace,
+		"nodes":       nodes,
+		"total_count": len(nodes),
+	})
+}
+
+// GetClusterDiagnostics handles reporting a cluster agent's effective permissions
+// @Summary Get cluster diagnostics
+// @Description Get the agent's effective Kubernetes permissions for each operation type, and the operation types it lacks permissions for. Returns 202 with an operation ID when a fresh report has been queued.
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Success 200 {object} map[string]interface{}
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clusters/{id}/diagnostics [get]
+func (h *ClusterHandler) GetClusterDiagnostics(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path using Chi
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	report, pending, err := h.clusterService.GetClusterDiagnostics(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, cluster.ErrClusterNotFound) {
+			WriteErrorResponse(w, http.StatusNotFound, "Cluster not found")
+			return
+		}
+		if errors.Is(err, cluster.ErrClusterQuarantined) {
+			WriteErrorResponse(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationRejected) {
+			WriteErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationNotSupported) {
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to get cluster diagnostics", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get cluster diagnostics")
+		return
+	}
+
+	if pending != nil {
+		WriteJSONResponse(w, http.StatusAccepted, map[string]interface{}{
+			"operation_id": pending.ID.String(),
+			"status":       pending.Status,
+			"message":      "Diagnostics queued",
+		})
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"cluster_id":              id,
+		"permissions":             report,
+		"missing_operation_types": report.MissingOperationTypes(),
 	})
 }
 
+// StreamClusterLogs handles streaming the aggregated logs of the pods matching
+// a label selector
+// @Summary Stream cluster pod logs
+// @Description Stream the logs of every pod matching a label selector as plain text, each line prefixed with its pod name. The cluster agent tails the pods in a pod_logs operation, which is cancelled when the client disconnects. With follow, pods starting to match during the stream are picked up.
+// @Tags clusters
+// @Produce plain
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Param selector query string true "Label selector, e.g. app=web"
+// @Param namespace query string false "Namespace of the pods; all namespaces when empty"
+// @Param container query string false "Container of multi-container pods; all containers when empty"
+// @Param tail_lines query int false "Number of recent lines to start from per pod"
+// @Param since_seconds query int false "Only return logs newer than this many seconds"
+// @Param follow query bool false "Keep streaming new lines (default true)"
+// @Success 200 {string} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /clusters/{id}/logs [get]
+func (h *ClusterHandler) StreamClusterLogs(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	request, err := podLogsRequest(r)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	operation, err := h.clusterService.StartPodLogs(r.Context(), id, request)
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrInvalidPodLogsRequest), errors.Is(err, cluster.ErrOperationNotSupported):
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, cluster.ErrClusterNotFound):
+			WriteErrorResponse(w, http.StatusNotFound, "Cluster not found")
+		case errors.Is(err, cluster.ErrClusterQuarantined):
+			WriteErrorResponse(w, http.StatusConflict, err.Error())
+		case errors.Is(err, cluster.ErrOperationRejected):
+			WriteErrorResponse(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, cluster.ErrOutputUnavailable):
+			WriteErrorResponse(w, http.StatusServiceUnavailable, "Log streaming is not available")
+		default:
+			h.logger.Error("Failed to start pod log streaming", zap.Error(err))
+			WriteErrorResponse(w, http.StatusInternalServerError, "Failed to stream logs")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Operation-ID", operation.ID.String())
+	w.WriteHeader(http.StatusOK)
+
+	// The response is already committed, so failures can only be logged
+	if err := h.clusterService.StreamOperationOutput(r.Context(), operation.ID, newFlushWriter(w)); err != nil {
+		h.logger.Warn("Pod log stream ended with an error",
+			zap.String("operation_id", operation.ID.String()),
+			zap.Error(err))
+	}
+}
+
+// podLogsRequest parses the query parameters of a log streaming request
+func podLogsRequest(r *http.Request) (*repo.PodLogsRequest, error) {
+	query := r.URL.Query()
+	request := &repo.PodLogsRequest{
+		Selector:  query.Get("selector"),
+		Namespace: query.Get("namespace"),
+		Container: query.Get("container"),
+		Follow:    true,
+	}
+	if request.Selector == "" {
+		return nil, errors.New("selector is required")
+	}
+
+	var err error
+	if value := query.Get("tail_lines"); value != "" {
+		if request.TailLines, err = strconv.ParseInt(value, 10, 64); err != nil || request.TailLines < 0 {
+			return nil, errors.New("tail_lines must be a non-negative integer")
+		}
+	}
+	if value := query.Get("since_seconds"); value != "" {
+		if request.SinceSeconds, err = strconv.ParseInt(value, 10, 64); err != nil || request.SinceSeconds < 0 {
+			return nil, errors.New("since_seconds must be a non-negative integer")
+		}
+	}
+	if value := query.Get("follow"); value != "" {
+		if request.Follow, err = strconv.ParseBool(value); err != nil {
+			return nil, errors.New("follow must be a boolean")
+		}
+	}
+	return request, nil
+}
+
+// flushWriter flushes every write so streamed output reaches the client
+// without waiting for the response buffer to fill
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func newFlushWriter(w http.ResponseWriter) *flushWriter {
+	flusher, _ := w.(http.Flusher)
+	return &flushWriter{w: w, flusher: flusher}
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if f.flusher != nil {
+		f.flusher.Flush()
+	}
+	return n, err
+}
+
 // ApplyManifests handles applying Kubernetes manifests
 // @Summary Apply manifests to cluster
-// @Description Apply Kubernetes manifests to a specific cluster
+// @Description Apply Kubernetes manifests to a specific cluster. The result of the apply operation lists every manifest document with its status (created, updated, unchanged, failed or skipped); with dry_run nothing is persisted and the result previews the changes. With prune, objects created by earlier pruning applies that are missing from the manifests and match prune_selector are deleted; objects mckmt did not create are never pruned.
 // @Tags clusters
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Cluster ID"
 // @Param manifests body map[string]interface{} true "Kubernetes manifests"
+// @Param manifest_url formData string false "URL the agent fetches the manifests from"
+// @Param manifest_sha256 formData string false "SHA-256 checksum of the manifests at manifest_url"
+// @Param configmap_namespace formData string false "Namespace of a ConfigMap holding the manifests"
+// @Param configmap_name formData string false "Name of a ConfigMap holding the manifests"
+// @Param configmap_key formData string false "ConfigMap key holding the manifests"
+// @Param render formData string false "How the upload is rendered: kustomize for a gzipped Kustomize directory tarball in the kustomization field, helm for pre-rendered Helm output in the manifests field"
+// @Param kustomization formData file false "Gzipped tarball of a Kustomize directory, with render=kustomize"
+// @Param initiated_via formData string false "Channel the request is made through: http_api (default), cli or webhook"
+// @Param reason formData string false "Why the manifests are applied"
+// @Param dry_run formData boolean false "Validate the manifests against the cluster without persisting them"
+// @Param prune formData boolean false "Delete objects created by earlier pruning applies that are missing from the manifests"
+// @Param prune_selector formData string false "Label selector pruned objects must match, required with prune"
+// @Param preconditions formData string false "JSON preconditions checked against the cluster's latest reported health, e.g. {\"cluster_healthy\":true,\"min_ready_nodes\":3}; the operation is skipped when they aren't met"
+// @Param execution_window formData string false "JSON window the operation may run in, e.g. {\"not_before\":\"2026-01-01T22:00:00Z\",\"not_after\":\"2026-01-02T02:00:00Z\"}; it is held until not_before and fails once not_after has passed"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /clusters/{id}/manifests [post]
 func (h *ClusterHandler) ApplyManifests(w http.ResponseWriter, r *http.Request) {
@@ -293,41 +749,188 @@ func (h *ClusterHandler) ApplyManifests(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get the manifests file
-	file, _, err := r.FormFile("manifests")
-	if err != nil {
-		WriteErrorResponse(w, http.StatusBadRequest, "No manifests file provided")
-		return
+	// Manifests are uploaded inline or referenced by URL or ConfigMap, in which
+	// case the agent fetches them itself
+	payload := repo.Payload{}
+	switch {
+	case r.FormValue("manifest_url") != "":
+		checksum := r.FormValue("manifest_sha256")
+		if checksum == "" {
+			WriteErrorResponse(w, http.StatusBadRequest, "manifest_sha256 is required with manifest_url")
+			return
+		}
+		payload[repo.PayloadManifestURL] = r.FormValue("manifest_url")
+		payload["manifest_sha256"] = checksum
+
+	case r.FormValue("configmap_name") != "":
+		namespace, key := r.FormValue("configmap_namespace"), r.FormValue("configmap_key")
+		if namespace == "" || key == "" {
+			WriteErrorResponse(w, http.StatusBadRequest, "configmap_namespace and configmap_key are required with configmap_name")
+			return
+		}
+		payload[repo.PayloadManifestConfigMap] = map[string]interface{}{
+			"namespace": namespace,
+			"name":      r.FormValue("configmap_name"),
+			"key":       key,
+		}
+
+	case r.FormValue("render") == "kustomize":
+		file, _, err := r.FormFile("kustomization")
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "No kustomization archive provided")
+			return
+		}
+		defer file.Close()
+
+		manifests, err := kube.RenderKustomize(file)
+		if err != nil {
+			if errors.Is(err, kube.ErrInvalidKustomization) {
+				WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			h.logger.Error("Failed to render kustomization", zap.Error(err))
+			WriteErrorResponse(w, http.StatusInternalServerError, "Failed to render kustomization")
+			return
+		}
+		payload["manifests"] = string(manifests)
+		payload["render"] = "kustomize"
+
+	default:
+		// Plain manifests, or Helm output rendered by the client
+		switch render := r.FormValue("render"); render {
+		case "":
+		case "helm":
+			payload["render"] = render
+		default:
+			WriteErrorResponse(w, http.StatusBadRequest, "render must be kustomize or helm")
+			return
+		}
+
+		// Get the manifests file
+		file, _, err := r.FormFile("manifests")
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "No manifests file provided")
+			return
+		}
+		defer file.Close()
+
+		// Read the manifests content
+		manifests, err := io.ReadAll(file)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusInternalServerError, "Failed to read manifests file")
+			return
+		}
+		payload["manifests"] = string(manifests)
 	}
-	defer file.Close()
 
-	// Read the manifests content
-	manifests, err := io.ReadAll(file)
-	if err != nil {
-		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to read manifests file")
+	if raw := r.FormValue("dry_run"); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "dry_run must be a boolean")
+			return
+		}
+		if dryRun {
+			payload[repo.PayloadDryRun] = true
+		}
+	}
+
+	if raw := r.FormValue("prune"); raw != "" {
+		prune, err := strconv.ParseBool(raw)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "prune must be a boolean")
+			return
+		}
+		if prune {
+			selector := r.FormValue("prune_selector")
+			if _, err := kube.ParsePruneSelector(selector); err != nil {
+				WriteErrorResponse(w, http.StatusBadRequest, "prune requires a valid prune_selector: "+err.Error())
+				return
+			}
+			payload[repo.PayloadPrune] = true
+			payload[repo.PayloadPruneSelector] = selector
+		}
+	}
+
+	if raw := r.FormValue("preconditions"); raw != "" {
+		var preconditions map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &preconditions); err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "preconditions must be a JSON object")
+			return
+		}
+		payload[repo.PayloadPreconditions] = preconditions
+	}
+
+	if raw := r.FormValue("execution_window"); raw != "" {
+		var window map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &window); err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "execution_window must be a JSON object")
+			return
+		}
+		payload[repo.PayloadExecutionWindow] = window
+	}
+
+	// Clients may name the channel they act for, e.g. the CLI or a webhook
+	// relay; reconciler and agent updates are only initiated by the hub itself
+	initiatedVia := r.FormValue("initiated_via")
+	switch initiatedVia {
+	case "":
+		initiatedVia = repo.InitiatedViaHTTPAPI
+	case repo.InitiatedViaHTTPAPI, repo.InitiatedViaCLI, repo.InitiatedViaWebhook:
+	default:
+		WriteErrorResponse(w, http.StatusBadRequest, "initiated_via must be http_api, cli or webhook")
 		return
 	}
 
 	// Create operation
 	operation := &repo.Operation{
-		ID:        uuid.New(),
-		ClusterID: clusterID,
-		Type:      "apply",
-		Status:    "queued",
-		Payload: repo.Payload{
-			"manifests": string(manifests),
-			"source":    "http_api",
-		},
+		ID:           uuid.New(),
+		ClusterID:    clusterID,
+		Type:         "apply",
+		Status:       "queued",
+		Payload:      payload,
+		InitiatedVia: initiatedVia,
+		Reason:       r.FormValue("reason"),
 	}
 
 	// Create operation in database
 	err = h.clusterService.CreateOperation(r.Context(), operation)
 	if err != nil {
+		if errors.Is(err, cluster.ErrClusterNotFound) {
+			WriteErrorResponse(w, http.StatusNotFound, "Cluster not found")
+			return
+		}
+		if errors.Is(err, cluster.ErrClusterQuarantined) {
+			WriteErrorResponse(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationRejected) {
+			WriteErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationNotSupported) {
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrOperationPayloadTooLarge) {
+			WriteErrorResponse(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		if errors.Is(err, cluster.ErrInvalidManifests) || errors.Is(err, cluster.ErrInvalidProvenance) ||
+			errors.Is(err, cluster.ErrTooManyManifestDocuments) || errors.Is(err, repo.ErrInvalidPreconditions) ||
+			errors.Is(err, repo.ErrInvalidExecutionWindow) {
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.logger.Error("Failed to create operation", zap.Error(err))
 		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create operation")
 		return
 	}
 
+	addAuditDetail(r, "initiated_via", operation.InitiatedVia)
+	if operation.Reason != "" {
+		addAuditDetail(r, "reason", operation.Reason)
+	}
+
 	// Queue operation for processing
 	err = h.clusterService.QueueOperation(r.Context(), operation)
 	if err != nil {
@@ -344,3 +947,339 @@ func (h *ClusterHandler) ApplyManifests(w http.ResponseWriter, r *http.Request)
 
 	WriteJSONResponse(w, http.StatusAccepted, response)
 }
+
+// ListClusterOperations handles listing the operations of a cluster
+// @Summary List cluster operations
+// @Description Get a list of operations for a specific cluster, newest first
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Param limit query int false "Maximum number of operations, capped at the server's max_limit"
+// @Param offset query int false "Number of operations to skip" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clusters/{id}/operations [get]
+func (h *ClusterHandler) ListClusterOperations(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	limit, offset, ok := h.pagination.parse(w, r)
+	if !ok {
+		return
+	}
+
+	operations, err := h.clusterService.ListClusterOperations(r.Context(), id, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list cluster operations", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list operations")
+		return
+	}
+
+	total, err := h.clusterService.CountClusterOperations(r.Context(), id)
+	if err != nil {
+		h.logger.Error("Failed to count cluster operations", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list operations")
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, listResponse(h.pagination.page(limit, offset, len(operations), total), map[string]interface{}{
+		"operations": operations,
+		"cluster_id": id,
+	}))
+}
+
+// ListClusterLogs handles listing the logs the agent of a cluster streamed
+// @Summary List cluster agent logs
+// @Description Get the log entries the cluster agent streamed to the hub, newest first. With level, only entries at least that severe are returned.
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Param level query string false "Minimum level: debug, info, warn, error, dpanic, panic or fatal"
+// @Param limit query int false "Maximum number of entries, capped at the server's max_limit"
+// @Param offset query int false "Number of entries to skip" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /clusters/{id}/agent-logs [get]
+func (h *ClusterHandler) ListClusterLogs(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	limit, offset, ok := h.pagination.parse(w, r)
+	if !ok {
+		return
+	}
+	level := r.URL.Query().Get("level")
+
+	logs, err := h.clusterService.ListClusterLogs(r.Context(), id, level, limit, offset)
+	if err != nil {
+		h.writeClusterLogsError(w, err)
+		return
+	}
+
+	total, err := h.clusterService.CountClusterLogs(r.Context(), id, level)
+	if err != nil {
+		h.writeClusterLogsError(w, err)
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, listResponse(h.pagination.page(limit, offset, len(logs), total), map[string]interface{}{
+		"logs":       logs,
+		"cluster_id": id,
+	}))
+}
+
+// writeClusterLogsError writes the response of a failed cluster logs query
+func (h *ClusterHandler) writeClusterLogsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, cluster.ErrInvalidLogLevel):
+		WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, cluster.ErrLogsUnavailable):
+		WriteErrorResponse(w, http.StatusServiceUnavailable, "Cluster logs are not available")
+	default:
+		h.logger.Error("Failed to list cluster logs", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list cluster logs")
+	}
+}
+
+// CancelAllOperations handles cancelling every non-terminal operation for a cluster
+// @Summary Cancel all cluster operations
+// @Description Cancel all queued and running operations for a specific cluster
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Param request body operation.CancelOperationRequest false "Cancellation reason"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clusters/{id}/operations:cancelAll [post]
+func (h *ClusterHandler) CancelAllOperations(w http.ResponseWriter, r *http.Request) {
+	// Extract ID from URL path using Chi
+	idStr := chi.URLParam(r, "id")
+
+	clusterID, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	// The request body is optional and only carries the cancellation reason
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if req.Reason == "" {
+		req.Reason = "cancelled by cluster-wide cancellation"
+	}
+
+	cancelled, err := h.clusterService.CancelClusterOperations(r.Context(), clusterID, req.Reason)
+	if err != nil {
+		h.logger.Error("Failed to cancel cluster operations", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to cancel cluster operations")
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"cluster_id":              clusterID,
+		"cancelled_operation_ids": cancelled,
+		"cancelled_count":         len(cancelled),
+	})
+}
+
+// GetClusterBaseline handles getting the desired inventory of a cluster
+// @Summary Get cluster baseline
+// @Description Get the desired inventory sync operations of the cluster detect drift against
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Success 200 {object} repo.ClusterBaseline
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clusters/{id}/baseline [get]
+func (h *ClusterHandler) GetClusterBaseline(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	baseline, err := h.clusterService.GetClusterBaseline(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, cluster.ErrBaselineNotFound) {
+			WriteErrorResponse(w, http.StatusNotFound, "Cluster baseline not found")
+			return
+		}
+		h.logger.Error("Failed to get cluster baseline", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get cluster baseline")
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, baseline)
+}
+
+// SetClusterBaseline handles replacing the desired inventory of a cluster
+// @Summary Set cluster baseline
+// @Description Replace the desired inventory of the cluster. Sync operations report resources added, removed or modified relative to it. Entries without a hash are only checked for presence.
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Param request body SetClusterBaselineRequest true "Desired inventory"
+// @Success 200 {object} repo.ClusterBaseline
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clusters/{id}/baseline [put]
+func (h *ClusterHandler) SetClusterBaseline(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	var req SetClusterBaselineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Resources == nil {
+		req.Resources = []repo.InventoryEntry{}
+	}
+
+	baseline, err := h.clusterService.SetClusterBaseline(r.Context(), id, req.Resources)
+	if err != nil {
+		switch {
+		case errors.Is(err, cluster.ErrBaselineInvalid):
+			WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, cluster.ErrClusterNotFound):
+			WriteErrorResponse(w, http.StatusNotFound, "Cluster not found")
+		default:
+			h.logger.Error("Failed to set cluster baseline", zap.Error(err))
+			WriteErrorResponse(w, http.StatusInternalServerError, "Failed to set cluster baseline")
+		}
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, baseline)
+}
+
+// QuarantineCluster handles quarantining a misbehaving cluster
+// @Summary Quarantine cluster
+// @Description Flag a cluster as quarantined so that no new operations are accepted for it, without deleting it
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Param request body QuarantineRequest false "Quarantine reason"
+// @Success 200 {object} ClusterDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/clusters/{id}/quarantine [post]
+func (h *ClusterHandler) QuarantineCluster(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	// The body is optional
+	var req QuarantineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	quarantined, err := h.clusterService.QuarantineCluster(r.Context(), id, req.Reason)
+	if err != nil {
+		h.writeQuarantineError(w, err, "Failed to quarantine cluster")
+		return
+	}
+
+	h.logger.Info("Cluster quarantined",
+		zap.String("cluster_id", id.String()),
+		zap.String("reason", req.Reason))
+	WriteJSONResponse(w, http.StatusOK, ToClusterDTO(quarantined))
+}
+
+// ReleaseCluster handles lifting the quarantine of a cluster
+// @Summary Release cluster from quarantine
+// @Description Lift the quarantine of a cluster so that operations are accepted for it again
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Success 200 {object} ClusterDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/clusters/{id}/quarantine [delete]
+func (h *ClusterHandler) ReleaseCluster(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	released, err := h.clusterService.ReleaseCluster(r.Context(), id)
+	if err != nil {
+		h.writeQuarantineError(w, err, "Failed to release cluster")
+		return
+	}
+
+	h.logger.Info("Cluster released from quarantine", zap.String("cluster_id", id.String()))
+	WriteJSONResponse(w, http.StatusOK, ToClusterDTO(released))
+}
+
+// writeQuarantineError maps errors of quarantine changes to responses
+func (h *ClusterHandler) writeQuarantineError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, cluster.ErrClusterNotFound):
+		WriteErrorResponse(w, http.StatusNotFound, "Cluster not found")
+	case errors.Is(err, cluster.ErrInvalidQuarantine):
+		WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		h.logger.Error(message, zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, message)
+	}
+}
@@ -1,15 +1,20 @@
 package http
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -17,7 +22,11 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/rizesky/mckmt/internal/api/http/mocks"
+	"github.com/rizesky/mckmt/internal/cluster"
+	clustermocks "github.com/rizesky/mckmt/internal/cluster/mocks"
+	"github.com/rizesky/mckmt/internal/kube"
 	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
 )
 
 func TestClusterHandler_GetCluster(t *testing.T) {
@@ -189,6 +198,7 @@ func TestClusterHandler_ListClusters(t *testing.T) {
 							{ID: uuid.New(), Name: "cluster1"},
 							{ID: uuid.New(), Name: "cluster2"},
 						}, nil)
+					mockClusterService.EXPECT().CountClusters(gomock.Any()).Return(2, nil)
 				}
 			}
 
@@ -406,6 +416,7 @@ func TestClusterHandler_ListClusterResources(t *testing.T) {
 		clusterID      string
 		queryParams    string
 		serviceError   error
+		pending        *repo.Operation
 		expectedStatus int
 		expectedError  bool
 	}{
@@ -433,6 +444,45 @@ func TestClusterHandler_ListClusterResources(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedError:  true,
 		},
+		{
+			name:           "kind required",
+			clusterID:      uuid.New().String(),
+			queryParams:    "?namespace=default",
+			serviceError:   cluster.ErrResourceKindRequired,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  true,
+		},
+		{
+			name:           "listing queued",
+			clusterID:      uuid.New().String(),
+			queryParams:    "?kind=Pod",
+			pending:        &repo.Operation{ID: uuid.New(), Type: repo.OperationTypeListResources, Status: "queued"},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "namespace for cluster-scoped kind",
+			clusterID:      uuid.New().String(),
+			queryParams:    "?kind=Node&namespace=default",
+			serviceError:   fmt.Errorf("%w: Node", kube.ErrNamespaceNotAllowed),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  true,
+		},
+		{
+			name:           "cluster not found",
+			clusterID:      uuid.New().String(),
+			queryParams:    "",
+			serviceError:   cluster.ErrClusterNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  true,
+		},
+		{
+			name:           "no agent connected",
+			clusterID:      uuid.New().String(),
+			queryParams:    "?kind=Pod",
+			serviceError:   fmt.Errorf("%w: cluster is disconnected", cluster.ErrClusterNotConnected),
+			expectedStatus: http.StatusFailedDependency,
+			expectedError:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -453,25 +503,33 @@ func TestClusterHandler_ListClusterResources(t *testing.T) {
 
 				if tt.serviceError != nil {
 					mockClusterService.EXPECT().
-						GetClusterResources(gomock.Any(), clusterID, gomock.Any(), gomock.Any()).
-						Return(nil, tt.serviceError)
+						GetClusterResources(gomock.Any(), clusterID, gomock.Any(), gomock.Any(), false).
+						Return(nil, nil, tt.serviceError)
+				} else if tt.pending != nil {
+					mockClusterService.EXPECT().
+						GetClusterResources(gomock.Any(), clusterID, gomock.Any(), gomock.Any(), false).
+						Return(nil, tt.pending, nil)
 				} else {
 					mockClusterService.EXPECT().
-						GetClusterResources(gomock.Any(), clusterID, gomock.Any(), gomock.Any()).
-						Return([]map[string]interface{}{
-							{
-								"kind":      "Pod",
-								"name":      "test-pod",
-								"namespace": "default",
-								"status":    "Running",
+						GetClusterResources(gomock.Any(), clusterID, gomock.Any(), gomock.Any(), false).
+						Return(&cluster.GetClusterResourcesResponse{
+							ClusterID: clusterID,
+							Resources: []map[string]interface{}{
+								{
+									"kind":      "Pod",
+									"name":      "test-pod",
+									"namespace": "default",
+									"status":    "Running",
+								},
+								{
+									"kind":      "Service",
+									"name":      "test-service",
+									"namespace": "default",
+									"type":      "ClusterIP",
+								},
 							},
-							{
-								"kind":      "Service",
-								"name":      "test-service",
-								"namespace": "default",
-								"type":      "ClusterIP",
-							},
-						}, nil)
+							TotalCount: 2,
+						}, nil, nil)
 				}
 			}
 
@@ -512,10 +570,1120 @@ func TestClusterHandler_ListClusterResources(t *testing.T) {
 				if err != nil {
 					t.Errorf("Expected no error but got: %v", err)
 				}
-				if _, ok := response["resources"]; !ok {
+				if tt.pending != nil {
+					if response["operation_id"] != tt.pending.ID.String() {
+						t.Errorf("Expected the queued operation %s but got %+v", tt.pending.ID, response)
+					}
+				} else if _, ok := response["resources"]; !ok {
 					t.Errorf("Expected response to contain 'resources' field: %+v", response)
 				}
 			}
 		})
 	}
 }
+
+func TestClusterHandler_ListClusterResourcesServesStaleInventory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterService := mocks.NewMockClusterManager(ctrl)
+	clusterID := uuid.New()
+	listedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	mockClusterService.EXPECT().
+		GetClusterResources(gomock.Any(), clusterID, "Pod", "", true).
+		Return(&cluster.GetClusterResourcesResponse{
+			ClusterID:  clusterID,
+			Resources:  []map[string]interface{}{{"kind": "Pod", "name": "web"}},
+			TotalCount: 1,
+			Kind:       "Pod",
+			Cached:     true,
+			Stale:      true,
+			AsOf:       &listedAt,
+		}, nil, nil)
+
+	handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/clusters/%s/resources?kind=Pod&allow_stale=true", clusterID), nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	handler.ListClusterResources(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d", http.StatusOK, w.Code)
+	}
+	if warning := w.Header().Get("Warning"); !strings.HasPrefix(warning, "110") {
+		t.Errorf("Expected a stale response warning but got %q", warning)
+	}
+
+	var response cluster.GetClusterResourcesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !response.Stale || response.AsOf == nil || !response.AsOf.Equal(listedAt) {
+		t.Errorf("Expected a stale listing from %v but got %+v", listedAt, response)
+	}
+}
+
+func TestClusterHandler_CancelAllOperations(t *testing.T) {
+	// Setup
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterRepo := repomocks.NewMockClusterRepository(ctrl)
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockCache := repomocks.NewMockCache(ctrl)
+	mockOrchestrator := clustermocks.NewMockOrchestratorInterface(ctrl)
+	logger := zap.NewNop()
+
+	clusterID := uuid.New()
+	queuedOp := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: "apply", Status: "queued"}
+	runningOp := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: "sync", Status: "running"}
+	succeededOp := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: "apply", Status: "success"}
+	failedOp := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: "exec", Status: "failed"}
+
+	mockOpRepo.EXPECT().
+		ListByCluster(gomock.Any(), clusterID, gomock.Any(), 0).
+		Return([]*repo.Operation{queuedOp, runningOp, succeededOp, failedOp}, nil)
+
+	// Only the non-terminal operations are cancelled
+	for _, op := range []*repo.Operation{queuedOp, runningOp} {
+		mockOpRepo.EXPECT().
+			CancelOperation(gomock.Any(), op.ID, "incident response").
+			Return(nil)
+		mockOrchestrator.EXPECT().
+			CancelOperation(op.ID).
+			Return(nil)
+		mockCache.EXPECT().
+			OperationKey(op.ID.String()).
+			Return("operation:" + op.ID.String())
+		mockCache.EXPECT().
+			Delete(gomock.Any(), "operation:"+op.ID.String()).
+			Return(nil)
+	}
+
+	service := cluster.NewService(mockClusterRepo, mockOpRepo, mockCache, logger, mockOrchestrator)
+	handler := NewClusterHandler(service, logger)
+
+	// Create request with chi router context
+	body := bytes.NewBufferString(`{"reason": "incident response"}`)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/clusters/%s/operations:cancelAll", clusterID), body)
+	req.Header.Set("Content-Type", "application/json")
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+
+	// Execute
+	handler.CancelAllOperations(w, req)
+
+	// Verify
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		ClusterID             uuid.UUID   `json:"cluster_id"`
+		CancelledOperationIDs []uuid.UUID `json:"cancelled_operation_ids"`
+		CancelledCount        int         `json:"cancelled_count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if response.ClusterID != clusterID {
+		t.Errorf("Expected cluster ID %s but got %s", clusterID, response.ClusterID)
+	}
+	if response.CancelledCount != 2 {
+		t.Errorf("Expected 2 cancelled operations but got %d", response.CancelledCount)
+	}
+
+	cancelled := make(map[uuid.UUID]bool)
+	for _, id := range response.CancelledOperationIDs {
+		cancelled[id] = true
+	}
+	if !cancelled[queuedOp.ID] || !cancelled[runningOp.ID] {
+		t.Errorf("Expected queued and running operations to be cancelled: %+v", response.CancelledOperationIDs)
+	}
+	if cancelled[succeededOp.ID] || cancelled[failedOp.ID] {
+		t.Errorf("Expected finished operations to be left untouched: %+v", response.CancelledOperationIDs)
+	}
+}
+
+func TestClusterHandler_CancelAllOperations_InvalidClusterID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterService := mocks.NewMockClusterManager(ctrl)
+	handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+	req := httptest.NewRequest("POST", "/clusters/invalid-uuid/operations:cancelAll", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "invalid-uuid")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.CancelAllOperations(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestClusterHandler_UpdateClusterLabelLimits(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= cluster.DefaultLabelLimits().MaxCount; i++ {
+		tooMany[fmt.Sprintf("label-%d", i)] = "value"
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+	}{
+		{
+			name:   "too many labels",
+			labels: tooMany,
+		},
+		{
+			name:   "value too long",
+			labels: map[string]string{"env": strings.Repeat("x", cluster.DefaultLabelLimits().MaxValueLength+1)},
+		},
+		{
+			name:   "key too long",
+			labels: map[string]string{strings.Repeat("k", cluster.DefaultLabelLimits().MaxKeyLength+1): "prod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Validation happens before the repositories are touched, so no calls are expected
+			service := cluster.NewService(
+				repomocks.NewMockClusterRepository(ctrl),
+				repomocks.NewMockOperationRepository(ctrl),
+				repomocks.NewMockCache(ctrl),
+				zap.NewNop(),
+				clustermocks.NewMockOrchestratorInterface(ctrl),
+			)
+			handler := NewClusterHandler(service, zap.NewNop())
+
+			clusterID := uuid.New()
+			body, _ := json.Marshal(map[string]interface{}{"name": "prod", "labels": tt.labels})
+			req := httptest.NewRequest("PUT", "/clusters/"+clusterID.String(), bytes.NewReader(body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", clusterID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.UpdateCluster(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d but got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), cluster.ErrClusterLabelsInvalid.Error()) {
+				t.Errorf("Expected error message to mention invalid labels, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestClusterHandler_ApplyManifestsUnknownCluster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterRepo := repomocks.NewMockClusterRepository(ctrl)
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	clusterID := uuid.New()
+
+	// No operation is created or queued for a cluster that does not exist
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).Return(nil, repo.ErrNotFound)
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	service := cluster.NewService(mockClusterRepo, mockOpRepo, repomocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+	handler := NewClusterHandler(service, zap.NewNop())
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	fileWriter, err := writer.CreateFormFile("manifests", "manifests.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	fileWriter.Write([]byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: demo\n"))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/clusters/%s/manifests", clusterID), &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.ApplyManifests(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d but got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestClusterHandler_ApplyManifestsKustomize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterService := mocks.NewMockClusterManager(ctrl)
+	clusterID := uuid.New()
+
+	// The rendered manifests, not the archive, are applied
+	var applied *repo.Operation
+	mockClusterService.EXPECT().
+		CreateOperation(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, op *repo.Operation) error {
+			applied = op
+			return nil
+		})
+	mockClusterService.EXPECT().QueueOperation(gomock.Any(), gomock.Any()).Return(nil)
+
+	handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"kustomization.yaml": "namePrefix: demo-\nresources:\n- configmap.yaml\n",
+		"configmap.yaml":     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  key: value\n",
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("render", "kustomize")
+	fileWriter, err := writer.CreateFormFile("kustomization", "app.tar.gz")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	fileWriter.Write(archive.Bytes())
+	writer.Close()
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/clusters/%s/manifests", clusterID), &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.ApplyManifests(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	if applied.Payload["render"] != "kustomize" {
+		t.Errorf("Expected render kustomize but got %v", applied.Payload["render"])
+	}
+	manifests, _ := applied.Payload["manifests"].(string)
+	if !strings.Contains(manifests, "name: demo-settings") {
+		t.Errorf("Expected rendered manifests but got:\n%s", manifests)
+	}
+}
+
+func TestClusterHandler_ApplyManifestsInvalidRender(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler := NewClusterHandler(mocks.NewMockClusterManager(ctrl), zap.NewNop())
+	clusterID := uuid.New()
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("render", "jsonnet")
+	fileWriter, err := writer.CreateFormFile("manifests", "manifests.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	fileWriter.Write([]byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: demo\n"))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/clusters/%s/manifests", clusterID), &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.ApplyManifests(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestClusterHandler_ApplyManifestsProvenance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterService := mocks.NewMockClusterManager(ctrl)
+	mockAuditRepo := repomocks.NewMockAuditLogRepository(ctrl)
+	handler := NewClusterHandler(mockClusterService, zap.NewNop())
+	clusterID := uuid.New()
+
+	var applied *repo.Operation
+	mockClusterService.EXPECT().
+		CreateOperation(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, op *repo.Operation) error {
+			applied = op
+			return nil
+		})
+	mockClusterService.EXPECT().QueueOperation(gomock.Any(), gomock.Any()).Return(nil)
+
+	var entry *repo.AuditLog
+	mockAuditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, log *repo.AuditLog) error {
+		entry = log
+		return nil
+	}).Times(2)
+
+	router := chi.NewRouter()
+	router.Use(auditMiddleware(mockAuditRepo, AuditOptions{}, zap.NewNop()))
+	router.Post("/api/v1/clusters/{id}/manifests", handler.ApplyManifests)
+
+	newRequest := func(initiatedVia, reason string) *http.Request {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		writer.WriteField("initiated_via", initiatedVia)
+		writer.WriteField("reason", reason)
+		fileWriter, err := writer.CreateFormFile("manifests", "manifests.yaml")
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		fileWriter.Write([]byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: demo\n"))
+		writer.Close()
+
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/clusters/%s/manifests", clusterID), &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newRequest("cli", "roll out the demo namespace"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+
+	if applied.InitiatedVia != repo.InitiatedViaCLI || applied.Reason != "roll out the demo namespace" {
+		t.Errorf("Expected provenance cli/%q but got %s/%q", "roll out the demo namespace", applied.InitiatedVia, applied.Reason)
+	}
+	if _, ok := applied.Payload["source"]; ok {
+		t.Errorf("Expected no ad hoc source in the payload but got %v", applied.Payload)
+	}
+
+	dto := ToOperationDTO(applied)
+	if dto.InitiatedVia != repo.InitiatedViaCLI || dto.Reason != applied.Reason {
+		t.Errorf("Expected provenance in the DTO but got %s/%q", dto.InitiatedVia, dto.Reason)
+	}
+
+	if entry == nil || entry.RequestPayload == nil {
+		t.Fatal("Expected the provenance in the audit entry")
+	}
+	if (*entry.RequestPayload)["initiated_via"] != repo.InitiatedViaCLI || (*entry.RequestPayload)["reason"] != applied.Reason {
+		t.Errorf("Expected provenance in the audit entry but got %v", *entry.RequestPayload)
+	}
+
+	// The reconciler is internal to the hub, so clients can't claim it
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newRequest("reconciler", ""))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestClusterHandler_ApplyManifestsDryRun(t *testing.T) {
+	tests := []struct {
+		name           string
+		dryRun         string
+		expectedStatus int
+		expectedDryRun bool
+	}{
+		{name: "dry run", dryRun: "true", expectedStatus: http.StatusAccepted, expectedDryRun: true},
+		{name: "explicit apply", dryRun: "false", expectedStatus: http.StatusAccepted},
+		{name: "invalid flag", dryRun: "maybe", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClusterService := mocks.NewMockClusterManager(ctrl)
+			handler := NewClusterHandler(mockClusterService, zap.NewNop())
+			clusterID := uuid.New()
+
+			var applied *repo.Operation
+			if tt.expectedStatus == http.StatusAccepted {
+				mockClusterService.EXPECT().CreateOperation(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, op *repo.Operation) error {
+					applied = op
+					return nil
+				})
+				mockClusterService.EXPECT().QueueOperation(gomock.Any(), gomock.Any()).Return(nil)
+			}
+
+			var buf bytes.Buffer
+			writer := multipart.NewWriter(&buf)
+			writer.WriteField("dry_run", tt.dryRun)
+			fileWriter, err := writer.CreateFormFile("manifests", "manifests.yaml")
+			if err != nil {
+				t.Fatalf("Failed to create form file: %v", err)
+			}
+			fileWriter.Write([]byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: demo\n"))
+			writer.Close()
+
+			req := httptest.NewRequest("POST", fmt.Sprintf("/clusters/%s/manifests", clusterID), &buf)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", clusterID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.ApplyManifests(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if applied == nil {
+				return
+			}
+			if dryRun, _ := applied.Payload[repo.PayloadDryRun].(bool); dryRun != tt.expectedDryRun {
+				t.Errorf("Expected dry run %v in the payload but got %v", tt.expectedDryRun, applied.Payload)
+			}
+		})
+	}
+}
+
+func TestClusterHandler_ApplyManifestsPrune(t *testing.T) {
+	tests := []struct {
+		name             string
+		prune            string
+		selector         string
+		expectedStatus   int
+		expectedSelector string
+	}{
+		{name: "prune with selector", prune: "true", selector: "app=demo", expectedStatus: http.StatusAccepted, expectedSelector: "app=demo"},
+		{name: "no prune ignores selector", prune: "false", selector: "app=demo", expectedStatus: http.StatusAccepted},
+		{name: "prune without selector", prune: "true", expectedStatus: http.StatusBadRequest},
+		{name: "prune with invalid selector", prune: "true", selector: "app in (", expectedStatus: http.StatusBadRequest},
+		{name: "invalid flag", prune: "maybe", selector: "app=demo", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClusterService := mocks.NewMockClusterManager(ctrl)
+			handler := NewClusterHandler(mockClusterService, zap.NewNop())
+			clusterID := uuid.New()
+
+			var applied *repo.Operation
+			if tt.expectedStatus == http.StatusAccepted {
+				mockClusterService.EXPECT().CreateOperation(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, op *repo.Operation) error {
+					applied = op
+					return nil
+				})
+				mockClusterService.EXPECT().QueueOperation(gomock.Any(), gomock.Any()).Return(nil)
+			}
+
+			var buf bytes.Buffer
+			writer := multipart.NewWriter(&buf)
+			writer.WriteField("prune", tt.prune)
+			if tt.selector != "" {
+				writer.WriteField("prune_selector", tt.selector)
+			}
+			fileWriter, err := writer.CreateFormFile("manifests", "manifests.yaml")
+			if err != nil {
+				t.Fatalf("Failed to create form file: %v", err)
+			}
+			fileWriter.Write([]byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: demo\n"))
+			writer.Close()
+
+			req := httptest.NewRequest("POST", fmt.Sprintf("/clusters/%s/manifests", clusterID), &buf)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", clusterID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.ApplyManifests(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if applied == nil {
+				return
+			}
+			prune, _ := applied.Payload[repo.PayloadPrune].(bool)
+			selector, _ := applied.Payload[repo.PayloadPruneSelector].(string)
+			if prune != (tt.expectedSelector != "") || selector != tt.expectedSelector {
+				t.Errorf("Expected prune selector %q in the payload but got %v", tt.expectedSelector, applied.Payload)
+			}
+		})
+	}
+}
+
+func TestClusterHandler_ApplyManifestsTooManyDocuments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterRepo := repomocks.NewMockClusterRepository(ctrl)
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	clusterID := uuid.New()
+
+	// The upload is rejected before the cluster is looked up or anything is stored
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), gomock.Any()).Times(0)
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	service := cluster.NewService(mockClusterRepo, mockOpRepo, repomocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+	service.SetMaxManifestDocuments(2)
+	handler := NewClusterHandler(service, zap.NewNop())
+
+	var manifests strings.Builder
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(&manifests, "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings-%d\n", i)
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	fileWriter, err := writer.CreateFormFile("manifests", "manifests.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	fileWriter.Write([]byte(manifests.String()))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/clusters/%s/manifests", clusterID), &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.ApplyManifests(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "3 documents exceeds the maximum of 2") {
+		t.Errorf("Expected the document count in the error but got: %s", w.Body.String())
+	}
+}
+
+func TestClusterHandler_QuarantineCluster(t *testing.T) {
+	clusterID := uuid.New()
+	quarantinedAt := time.Now().UTC()
+
+	tests := []struct {
+		name           string
+		clusterID      string
+		body           string
+		setup          func(*mocks.MockClusterManager)
+		expectedStatus int
+	}{
+		{
+			name:      "quarantine with reason",
+			clusterID: clusterID.String(),
+			body:      `{"reason":"agent floods errors"}`,
+			setup: func(m *mocks.MockClusterManager) {
+				m.EXPECT().QuarantineCluster(gomock.Any(), clusterID, "agent floods errors").
+					Return(&repo.Cluster{ID: clusterID, QuarantinedAt: &quarantinedAt, QuarantineReason: "agent floods errors"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "quarantine without body",
+			clusterID: clusterID.String(),
+			setup: func(m *mocks.MockClusterManager) {
+				m.EXPECT().QuarantineCluster(gomock.Any(), clusterID, "").
+					Return(&repo.Cluster{ID: clusterID, QuarantinedAt: &quarantinedAt}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "cluster not found",
+			clusterID: clusterID.String(),
+			setup: func(m *mocks.MockClusterManager) {
+				m.EXPECT().QuarantineCluster(gomock.Any(), clusterID, "").Return(nil, cluster.ErrClusterNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:      "reason too long",
+			clusterID: clusterID.String(),
+			body:      `{"reason":"x"}`,
+			setup: func(m *mocks.MockClusterManager) {
+				m.EXPECT().QuarantineCluster(gomock.Any(), clusterID, "x").Return(nil, cluster.ErrInvalidQuarantine)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid cluster ID",
+			clusterID:      "invalid",
+			setup:          func(*mocks.MockClusterManager) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClusterService := mocks.NewMockClusterManager(ctrl)
+			tt.setup(mockClusterService)
+			handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+			req := httptest.NewRequest("POST", "/admin/clusters/"+tt.clusterID+"/quarantine", strings.NewReader(tt.body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.clusterID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.QuarantineCluster(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var dto ClusterDTO
+			if err := json.Unmarshal(w.Body.Bytes(), &dto); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if !dto.Quarantined || dto.QuarantinedAt == nil {
+				t.Errorf("Expected the cluster to be reported quarantined but got %+v", dto)
+			}
+		})
+	}
+}
+
+func TestClusterHandler_ReleaseCluster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterService := mocks.NewMockClusterManager(ctrl)
+	clusterID := uuid.New()
+	mockClusterService.EXPECT().ReleaseCluster(gomock.Any(), clusterID).Return(&repo.Cluster{ID: clusterID}, nil)
+
+	handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+	req := httptest.NewRequest("DELETE", "/admin/clusters/"+clusterID.String()+"/quarantine", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.ReleaseCluster(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var dto ClusterDTO
+	if err := json.Unmarshal(w.Body.Bytes(), &dto); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if dto.Quarantined {
+		t.Errorf("Expected the quarantine to be lifted but got %+v", dto)
+	}
+}
+
+func TestClusterHandler_ApplyManifestsQuarantined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterRepo := repomocks.NewMockClusterRepository(ctrl)
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	clusterID := uuid.New()
+	quarantinedAt := time.Now().UTC()
+
+	// Nothing is stored or queued for a quarantined cluster
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).
+		Return(&repo.Cluster{ID: clusterID, QuarantinedAt: &quarantinedAt, QuarantineReason: "repeated failures"}, nil)
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	service := cluster.NewService(mockClusterRepo, mockOpRepo, repomocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+	handler := NewClusterHandler(service, zap.NewNop())
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	fileWriter, err := writer.CreateFormFile("manifests", "manifests.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	fileWriter.Write([]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n"))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/clusters/%s/manifests", clusterID), &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.ApplyManifests(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "cluster is quarantined") || !strings.Contains(w.Body.String(), "repeated failures") {
+		t.Errorf("Expected the quarantine and its reason in the error but got: %s", w.Body.String())
+	}
+}
+
+func TestClusterHandler_CreateCluster(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		serviceError   error
+		callService    bool
+		expectedStatus int
+	}{
+		{
+			name:           "successful create",
+			body:           `{"name":"prod","description":"Production","labels":{"env":"prod"}}`,
+			callService:    true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "duplicate name",
+			body:           `{"name":"prod"}`,
+			serviceError:   cluster.ErrClusterAlreadyExists,
+			callService:    true,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "missing name",
+			body:           `{"description":"Production"}`,
+			serviceError:   cluster.ErrClusterNameRequired,
+			callService:    true,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid body",
+			body:           `{`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClusterService := mocks.NewMockClusterManager(ctrl)
+			if tt.callService {
+				mockClusterService.EXPECT().CreateCluster(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, name, description string, labels map[string]string) (*repo.Cluster, error) {
+						if tt.serviceError != nil {
+							return nil, tt.serviceError
+						}
+						return &repo.Cluster{ID: uuid.New(), Name: name, Description: description, Labels: labels, Status: "pending"}, nil
+					})
+			}
+			handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+			req := httptest.NewRequest("POST", "/clusters", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			handler.CreateCluster(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var dto ClusterDTO
+			if err := json.Unmarshal(w.Body.Bytes(), &dto); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if dto.ID == "" || dto.Name != "prod" || dto.Labels["env"] != "prod" {
+				t.Errorf("Expected the created cluster but got %+v", dto)
+			}
+		})
+	}
+}
+
+func TestClusterHandler_StreamClusterLogs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterService := mocks.NewMockClusterManager(ctrl)
+	clusterID := uuid.New()
+	operation := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypePodLogs}
+
+	mockClusterService.EXPECT().StartPodLogs(gomock.Any(), clusterID, &repo.PodLogsRequest{
+		Selector:  "app=web",
+		Namespace: "shop",
+		TailLines: 10,
+		Follow:    true,
+	}).Return(operation, nil)
+	mockClusterService.EXPECT().StreamOperationOutput(gomock.Any(), operation.ID, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ uuid.UUID, w io.Writer) error {
+			_, err := w.Write([]byte("[web-1] started\n[web-2] started\n"))
+			return err
+		})
+
+	handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+	req := httptest.NewRequest("GET", "/clusters/"+clusterID.String()+"/logs?selector=app%3Dweb&namespace=shop&tail_lines=10", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.StreamClusterLogs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-Operation-ID"); got != operation.ID.String() {
+		t.Errorf("Expected operation ID header %s but got %q", operation.ID, got)
+	}
+	if !w.Flushed {
+		t.Error("Expected the streamed output to be flushed")
+	}
+	if w.Body.String() != "[web-1] started\n[web-2] started\n" {
+		t.Errorf("Expected the multiplexed pod logs but got %q", w.Body.String())
+	}
+}
+
+func TestClusterHandler_StreamClusterLogsErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		startErr   error
+		wantStatus int
+	}{
+		{name: "missing selector", query: "", wantStatus: http.StatusBadRequest},
+		{name: "invalid tail lines", query: "selector=app%3Dweb&tail_lines=-1", wantStatus: http.StatusBadRequest},
+		{name: "invalid follow", query: "selector=app%3Dweb&follow=maybe", wantStatus: http.StatusBadRequest},
+		{name: "cluster not found", query: "selector=app%3Dweb", startErr: cluster.ErrClusterNotFound, wantStatus: http.StatusNotFound},
+		{name: "unsupported agent", query: "selector=app%3Dweb", startErr: cluster.ErrOperationNotSupported, wantStatus: http.StatusBadRequest},
+		{name: "output unavailable", query: "selector=app%3Dweb", startErr: cluster.ErrOutputUnavailable, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClusterService := mocks.NewMockClusterManager(ctrl)
+			if tt.startErr != nil {
+				mockClusterService.EXPECT().StartPodLogs(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, tt.startErr)
+			}
+
+			handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+			clusterID := uuid.New()
+			req := httptest.NewRequest("GET", "/clusters/"+clusterID.String()+"/logs?"+tt.query, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", clusterID.String())
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.StreamClusterLogs(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d but got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestClusterHandler_ListClusterOperations(t *testing.T) {
+	clusterID := uuid.New()
+
+	tests := []struct {
+		name           string
+		path           string
+		listError      error
+		countError     error
+		expectList     bool
+		expectCount    bool
+		expectedStatus int
+	}{
+		{
+			name:           "successful list operations",
+			path:           "/clusters/" + clusterID.String() + "/operations?limit=10&offset=0",
+			expectList:     true,
+			expectCount:    true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid cluster ID",
+			path:           "/clusters/invalid/operations",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit parameter",
+			path:           "/clusters/" + clusterID.String() + "/operations?limit=invalid",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "list error",
+			path:           "/clusters/" + clusterID.String() + "/operations?limit=10",
+			listError:      errors.New("database error"),
+			expectList:     true,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "count error",
+			path:           "/clusters/" + clusterID.String() + "/operations?limit=10",
+			countError:     errors.New("database error"),
+			expectList:     true,
+			expectCount:    true,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClusterService := mocks.NewMockClusterManager(ctrl)
+			operations := []*repo.Operation{
+				{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: repo.OperationStatusSuccess},
+			}
+			if tt.expectList {
+				mockClusterService.EXPECT().ListClusterOperations(gomock.Any(), clusterID, 10, 0).Return(operations, tt.listError)
+			}
+			if tt.expectCount {
+				mockClusterService.EXPECT().CountClusterOperations(gomock.Any(), clusterID).Return(1, tt.countError)
+			}
+
+			handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+			// Routed next to the export endpoint, as in the API router
+			router := chi.NewRouter()
+			router.Get("/clusters/{id}/operations", handler.ListClusterOperations)
+			router.Get("/clusters/{id}/operations:export", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Operations []repo.Operation `json:"operations"`
+				ClusterID  uuid.UUID        `json:"cluster_id"`
+				TotalCount int              `json:"total_count"`
+				Limit      int              `json:"limit"`
+				Offset     int              `json:"offset"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(resp.Operations) != 1 || resp.TotalCount != 1 || resp.ClusterID != clusterID {
+				t.Errorf("Expected 1 operation of cluster %s but got %+v", clusterID, resp)
+			}
+			if resp.Limit != 10 || resp.Offset != 0 {
+				t.Errorf("Expected limit 10 and offset 0 but got %d and %d", resp.Limit, resp.Offset)
+			}
+		})
+	}
+}
+
+func TestClusterHandler_ListClusterLogs(t *testing.T) {
+	clusterID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		level          string
+		listError      error
+		expectCount    bool
+		expectedStatus int
+	}{
+		{
+			name:           "successful list logs",
+			query:          "?limit=10&level=warn",
+			level:          "warn",
+			expectCount:    true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid level",
+			query:          "?level=verbose",
+			level:          "verbose",
+			listError:      cluster.ErrInvalidLogLevel,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "logs not configured",
+			listError:      cluster.ErrLogsUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "list error",
+			listError:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClusterService := mocks.NewMockClusterManager(ctrl)
+			logs := []*repo.LogEntry{{ID: uuid.New(), ClusterID: clusterID, Level: "error", Message: "apply failed"}}
+			mockClusterService.EXPECT().ListClusterLogs(gomock.Any(), clusterID, tt.level, 10, 0).Return(logs, tt.listError)
+			if tt.expectCount {
+				mockClusterService.EXPECT().CountClusterLogs(gomock.Any(), clusterID, tt.level).Return(1, nil)
+			}
+
+			handler := NewClusterHandler(mockClusterService, zap.NewNop())
+			router := chi.NewRouter()
+			router.Get("/clusters/{id}/agent-logs", handler.ListClusterLogs)
+
+			req := httptest.NewRequest("GET", "/clusters/"+clusterID.String()+"/agent-logs"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Logs       []repo.LogEntry `json:"logs"`
+				TotalCount int             `json:"total_count"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(resp.Logs) != 1 || resp.TotalCount != 1 || resp.Logs[0].Message != "apply failed" {
+				t.Errorf("Expected the cluster's log entry but got %+v", resp)
+			}
+		})
+	}
+}
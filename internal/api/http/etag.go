@@ -0,0 +1,47 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// resourceETag derives an ETag from a resource's ID and last update time. Every
+// change to clusters and operations bumps updated_at, so the tag changes
+// whenever the representation does.
+func resourceETag(id uuid.UUID, updatedAt time.Time) string {
+	return fmt.Sprintf(`"%s-%d"`, id, updatedAt.UnixNano())
+}
+
+// notModified sets the ETag header and reports whether the request's
+// If-None-Match already holds it, in which case a 304 has been written and the
+// caller should stop
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+// If-None-Match uses weak comparison, so a W/ prefix is ignored.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
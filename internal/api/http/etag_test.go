@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/api/http/mocks"
+	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+// conditionalGet calls handler for the resource with the given ID, sending
+// ifNoneMatch when it is not empty
+func conditionalGet(handler http.HandlerFunc, param string, id uuid.UUID, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/"+id.String(), nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(param, id.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func TestClusterHandler_GetClusterETag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cluster := &repo.Cluster{ID: uuid.New(), Name: "cluster1", UpdatedAt: time.Now()}
+	staleETag := resourceETag(cluster.ID, cluster.UpdatedAt.Add(-time.Minute))
+
+	mockClusterService := mocks.NewMockClusterManager(ctrl)
+	mockClusterService.EXPECT().GetCluster(gomock.Any(), cluster.ID).Return(cluster, nil).Times(4)
+	handler := NewClusterHandler(mockClusterService, zap.NewNop())
+
+	w := conditionalGet(handler.GetCluster, "id", cluster.ID, "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected status 200 with an ETag but got %d, ETag %q", w.Code, etag)
+	}
+
+	tests := []struct {
+		name           string
+		ifNoneMatch    string
+		expectedStatus int
+	}{
+		{name: "matching ETag", ifNoneMatch: etag, expectedStatus: http.StatusNotModified},
+		{name: "matching weak ETag in a list", ifNoneMatch: staleETag + ", W/" + etag, expectedStatus: http.StatusNotModified},
+		{name: "stale ETag", ifNoneMatch: staleETag, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := conditionalGet(handler.GetCluster, "id", cluster.ID, tt.ifNoneMatch)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tt.expectedStatus, w.Code)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("Expected ETag %q but got %q", etag, got)
+			}
+			if tt.expectedStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("Expected an empty body with 304 but got %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOperationHandler_GetOperationETag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	op := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Status: repo.OperationStatusRunning, UpdatedAt: time.Now()}
+	etag := resourceETag(op.ID, op.UpdatedAt)
+
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockEventRepo := repomocks.NewMockOperationEventRepository(ctrl)
+	mockCache := repomocks.NewMockCache(ctrl)
+	mockCache.EXPECT().OperationKey(op.ID.String()).Return("operation:" + op.ID.String()).AnyTimes()
+	mockCache.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(repo.ErrCacheMiss).AnyTimes()
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockOpRepo.EXPECT().GetByID(gomock.Any(), op.ID).Return(op, nil).AnyTimes()
+	// The history is only loaded for the full response
+	mockEventRepo.EXPECT().ListByOperation(gomock.Any(), op.ID).Return([]*repo.OperationEvent{}, nil).Times(1)
+
+	service := operation.NewService(mockOpRepo, mockEventRepo, nil, mockCache, zap.NewNop(), nil)
+	handler := NewOperationHandler(service, zap.NewNop())
+
+	if w := conditionalGet(handler.GetOperation, "id", op.ID, etag); w.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d for a matching ETag but got %d", http.StatusNotModified, w.Code)
+	}
+
+	w := conditionalGet(handler.GetOperation, "id", op.ID, resourceETag(op.ID, op.UpdatedAt.Add(-time.Second)))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for a stale ETag but got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get("ETag"); got != etag {
+		t.Errorf("Expected ETag %q but got %q", etag, got)
+	}
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// Formats of an operation history export
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+)
+
+// exportCSVHeader names the CSV columns, in OperationExportDTO field order
+var exportCSVHeader = []string{"id", "cluster_id", "type", "status", "created_by", "created_at", "started_at", "finished_at", "result_summary", "manifest_hash", "initiated_via", "reason"}
+
+// ExportClusterOperations handles exporting a cluster's full operation history
+// @Summary Export cluster operation history
+// @Description Stream every operation of a cluster, oldest first, as CSV or NDJSON. The export is not paginated.
+// @Tags operations
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param id path string true "Cluster ID"
+// @Param format query string false "Export format" Enums(csv, ndjson) default(ndjson)
+// @Success 200 {array} OperationExportDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /clusters/{id}/operations:export [get]
+func (h *OperationHandler) ExportClusterOperations(w http.ResponseWriter, r *http.Request) {
+	clusterID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid cluster ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatNDJSON
+	}
+
+	var write func(OperationExportDTO) error
+	var csvWriter *csv.Writer
+	switch format {
+	case exportFormatCSV:
+		w.Header().Set("Content-Type", "text/csv")
+		csvWriter = csv.NewWriter(w)
+		write = func(record OperationExportDTO) error {
+			return csvWriter.Write([]string{
+				record.ID, record.ClusterID, record.Type, record.Status, record.CreatedBy,
+				record.CreatedAt, record.StartedAt, record.FinishedAt, record.ResultSummary, record.ManifestHash,
+				record.InitiatedVia, record.Reason,
+			})
+		}
+
+	case exportFormatNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		write = func(record OperationExportDTO) error { return encoder.Encode(record) }
+
+	default:
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid format, expected csv or ndjson")
+		return
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename=\"operations-"+clusterID.String()+"."+format+"\"")
+
+	// Rows are written as they are read from the database. Once any has been
+	// sent the status can't change, so a later failure ends the export early.
+	if csvWriter != nil {
+		csvWriter.Write(exportCSVHeader)
+	}
+	exported := 0
+	err = h.operationService.EachOperationByCluster(r.Context(), clusterID, func(operation *repo.Operation) error {
+		exported++
+		return write(operationExportRecord(operation))
+	})
+	if err != nil && exported == 0 {
+		h.logger.Error("Failed to export operations", zap.Error(err))
+		w.Header().Del("Content-Disposition")
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to export operations")
+		return
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		if err == nil {
+			err = csvWriter.Error()
+		}
+	}
+	if err != nil {
+		h.logger.Error("Operation export ended early",
+			zap.String("cluster_id", clusterID.String()),
+			zap.Int("exported", exported),
+			zap.Error(err))
+	}
+}
+
+// operationExportRecord summarizes an operation for export
+func operationExportRecord(operation *repo.Operation) OperationExportDTO {
+	record := OperationExportDTO{
+		ID:         operation.ID.String(),
+		ClusterID:  operation.ClusterID.String(),
+		Type:       operation.Type,
+		Status:     operation.Status,
+		CreatedAt:  exportTime(&operation.CreatedAt),
+		StartedAt:  exportTime(operation.StartedAt),
+		FinishedAt: exportTime(operation.FinishedAt),
+
+		InitiatedVia: operation.InitiatedVia,
+		Reason:       operation.Reason,
+	}
+	record.CreatedBy, _ = operation.Payload[repo.PayloadCreatedBy].(string)
+	record.ManifestHash, _ = operation.Payload[repo.PayloadManifestHash].(string)
+
+	if operation.Result != nil {
+		result := *operation.Result
+		if message, ok := result["message"].(string); ok {
+			record.ResultSummary = message
+		}
+		if reason, ok := result["reason"].(string); ok && reason != "" {
+			record.ResultSummary += " (" + reason + ")"
+		}
+	}
+
+	return record
+}
+
+// exportTime formats an optional timestamp as RFC 3339, or empty when unset
+func exportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+// newExportTestHandler returns a handler whose repository streams operations
+func newExportTestHandler(t *testing.T, clusterID uuid.UUID, operations []*repo.Operation, err error) *OperationHandler {
+	ctrl := gomock.NewController(t)
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockOpRepo.EXPECT().EachByCluster(gomock.Any(), clusterID, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ uuid.UUID, fn func(*repo.Operation) error) error {
+			for _, op := range operations {
+				if err := fn(op); err != nil {
+					return err
+				}
+			}
+			return err
+		})
+
+	service := operation.NewService(mockOpRepo, nil, nil, repomocks.NewMockCache(ctrl), zap.NewNop(), nil)
+	return NewOperationHandler(service, zap.NewNop())
+}
+
+// exportRequest builds an export request for clusterID in the given format
+func exportRequest(clusterID uuid.UUID, format string) *http.Request {
+	req := httptest.NewRequest("GET", "/clusters/"+clusterID.String()+"/operations:export?format="+format, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", clusterID.String())
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+// exportTestOperations returns three operations of clusterID in various states
+func exportTestOperations(clusterID uuid.UUID) []*repo.Operation {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	finished := created.Add(time.Minute)
+	result := repo.Payload{"success": true, "message": "manifests applied"}
+
+	return []*repo.Operation{
+		{ID: uuid.New(), ClusterID: clusterID, Type: "apply", Status: "success", CreatedAt: created,
+			StartedAt: &created, FinishedAt: &finished, Result: &result,
+			Payload: repo.Payload{repo.PayloadCreatedBy: "alice"}},
+		{ID: uuid.New(), ClusterID: clusterID, Type: "sync", Status: "running", CreatedAt: created.Add(time.Hour),
+			StartedAt: &finished, Payload: repo.Payload{}},
+		{ID: uuid.New(), ClusterID: clusterID, Type: "exec", Status: "queued", CreatedAt: created.Add(2 * time.Hour)},
+	}
+}
+
+func TestOperationHandler_ExportClusterOperationsNDJSON(t *testing.T) {
+	clusterID := uuid.New()
+	operations := exportTestOperations(clusterID)
+	handler := newExportTestHandler(t, clusterID, operations, nil)
+
+	w := httptest.NewRecorder()
+	handler.ExportClusterOperations(w, exportRequest(clusterID, "ndjson"))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON content type but got %q", contentType)
+	}
+
+	var records []OperationExportDTO
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var record OperationExportDTO
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to decode line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != len(operations) {
+		t.Fatalf("Expected %d operations but got %d", len(operations), len(records))
+	}
+	for i, op := range operations {
+		if records[i].ID != op.ID.String() || records[i].Status != op.Status {
+			t.Errorf("Expected operation %s (%s) but got %+v", op.ID, op.Status, records[i])
+		}
+	}
+
+	first := records[0]
+	if first.CreatedBy != "alice" {
+		t.Errorf("Expected creator alice but got %q", first.CreatedBy)
+	}
+	if first.ResultSummary != "manifests applied" {
+		t.Errorf("Expected result summary but got %q", first.ResultSummary)
+	}
+	if first.FinishedAt != "2024-01-01T12:01:00Z" {
+		t.Errorf("Expected finish time but got %q", first.FinishedAt)
+	}
+	if records[2].StartedAt != "" {
+		t.Errorf("Expected no start time for a queued operation but got %q", records[2].StartedAt)
+	}
+}
+
+func TestOperationHandler_ExportClusterOperationsCSV(t *testing.T) {
+	clusterID := uuid.New()
+	operations := exportTestOperations(clusterID)
+	handler := newExportTestHandler(t, clusterID, operations, nil)
+
+	w := httptest.NewRecorder()
+	handler.ExportClusterOperations(w, exportRequest(clusterID, "csv"))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(rows) != len(operations)+1 {
+		t.Fatalf("Expected a header and %d rows but got %d rows", len(operations), len(rows))
+	}
+	if rows[0][0] != "id" || rows[0][4] != "created_by" {
+		t.Errorf("Unexpected header %v", rows[0])
+	}
+	for i, op := range operations {
+		if rows[i+1][0] != op.ID.String() {
+			t.Errorf("Expected row %d to be operation %s but got %v", i+1, op.ID, rows[i+1])
+		}
+	}
+}
+
+func TestOperationHandler_ExportClusterOperationsErrors(t *testing.T) {
+	clusterID := uuid.New()
+
+	t.Run("invalid format", func(t *testing.T) {
+		handler := NewOperationHandler(nil, zap.NewNop())
+		w := httptest.NewRecorder()
+		handler.ExportClusterOperations(w, exportRequest(clusterID, "xml"))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d but got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("database failure before any row", func(t *testing.T) {
+		handler := newExportTestHandler(t, clusterID, nil, errors.New("connection refused"))
+		w := httptest.NewRecorder()
+		handler.ExportClusterOperations(w, exportRequest(clusterID, "csv"))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d but got %d", http.StatusInternalServerError, w.Code)
+		}
+	})
+}
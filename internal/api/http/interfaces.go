@@ -2,12 +2,16 @@ package http
 
 import (
 	"context"
+	"io"
 
 	"github.com/google/uuid"
+	"github.com/rizesky/mckmt/internal/cluster"
+	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/orchestrator"
 	"github.com/rizesky/mckmt/internal/repo"
 )
 
-//go:generate mockgen -destination=./mocks/mock_http.go -package=mocks github.com/rizesky/mckmt/internal/api/http ClusterManager
+//go:generate mockgen -destination=./mocks/mock_http.go -package=mocks github.com/rizesky/mckmt/internal/api/http ClusterManager,OrchestratorAdmin,DeadLetterAdmin
 
 // ClusterManager defines the interface for cluster management operations.
 //
@@ -26,9 +30,41 @@ import (
 type ClusterManager interface {
 	GetCluster(ctx context.Context, id uuid.UUID) (*repo.Cluster, error)
 	ListClusters(ctx context.Context, limit, offset int) ([]*repo.Cluster, error)
+	CountClusters(ctx context.Context) (int, error)
+	CreateCluster(ctx context.Context, name, description string, labels map[string]string) (*repo.Cluster, error)
 	UpdateCluster(ctx context.Context, id uuid.UUID, name, description string, labels map[string]string) error
 	DeleteCluster(ctx context.Context, id uuid.UUID) error
-	GetClusterResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string) ([]map[string]interface{}, error)
+	GetClusterResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string, allowStale bool) (*cluster.GetClusterResourcesResponse, *repo.Operation, error)
 	CreateOperation(ctx context.Context, operation *repo.Operation) error
 	QueueOperation(ctx context.Context, operation *repo.Operation) error
+	ListClusterOperations(ctx context.Context, clusterID uuid.UUID, limit, offset int) ([]*repo.Operation, error)
+	CountClusterOperations(ctx context.Context, clusterID uuid.UUID) (int, error)
+	ListClusterLogs(ctx context.Context, clusterID uuid.UUID, level string, limit, offset int) ([]*repo.LogEntry, error)
+	CountClusterLogs(ctx context.Context, clusterID uuid.UUID, level string) (int, error)
+	CancelClusterOperations(ctx context.Context, clusterID uuid.UUID, reason string) ([]uuid.UUID, error)
+	ListNamespaces(ctx context.Context, clusterID uuid.UUID) ([]kube.NamespaceInfo, *repo.Operation, error)
+	ListNodes(ctx context.Context, clusterID uuid.UUID) ([]kube.NodeInfo, *repo.Operation, error)
+	GetClusterDiagnostics(ctx context.Context, clusterID uuid.UUID) (kube.PermissionReport, *repo.Operation, error)
+	GetClusterBaseline(ctx context.Context, clusterID uuid.UUID) (*repo.ClusterBaseline, error)
+	SetClusterBaseline(ctx context.Context, clusterID uuid.UUID, resources []repo.InventoryEntry) (*repo.ClusterBaseline, error)
+	QuarantineCluster(ctx context.Context, id uuid.UUID, reason string) (*repo.Cluster, error)
+	ReleaseCluster(ctx context.Context, id uuid.UUID) (*repo.Cluster, error)
+	StartPodLogs(ctx context.Context, clusterID uuid.UUID, request *repo.PodLogsRequest) (*repo.Operation, error)
+	StreamOperationOutput(ctx context.Context, operationID uuid.UUID, w io.Writer) error
+}
+
+// OrchestratorAdmin exposes the orchestrator's internal state and controls to
+// the admin endpoints
+type OrchestratorAdmin interface {
+	State() orchestrator.State
+	Pause()
+	Resume()
+}
+
+// DeadLetterAdmin lists and re-drives dead-lettered operations for the admin
+// endpoints
+type DeadLetterAdmin interface {
+	List(ctx context.Context, limit, offset int) ([]*repo.DeadLetter, error)
+	Count(ctx context.Context) (int, error)
+	Redrive(ctx context.Context, operationID uuid.UUID) error
 }
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/rizesky/mckmt/internal/api/http (interfaces: ClusterManager)
+// Source: github.com/rizesky/mckmt/internal/api/http (interfaces: ClusterManager,OrchestratorAdmin,DeadLetterAdmin)
 //
 // Generated by this command:
 //
-//	mockgen -destination=./mocks/mock_http.go -package=mocks github.com/rizesky/mckmt/internal/api/http ClusterManager
+//	mockgen -destination=./mocks/mock_http.go -package=mocks github.com/rizesky/mckmt/internal/api/http ClusterManager,OrchestratorAdmin,DeadLetterAdmin
 //
 
 // Package mocks is a generated GoMock package.
@@ -11,9 +11,13 @@ package mocks
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
 
 	uuid "github.com/google/uuid"
+	cluster "github.com/rizesky/mckmt/internal/cluster"
+	kube "github.com/rizesky/mckmt/internal/kube"
+	orchestrator "github.com/rizesky/mckmt/internal/orchestrator"
 	repo "github.com/rizesky/mckmt/internal/repo"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -42,6 +46,81 @@ func (m *MockClusterManager) EXPECT() *MockClusterManagerMockRecorder {
 	return m.recorder
 }
 
+// CancelClusterOperations mocks base method.
+func (m *MockClusterManager) CancelClusterOperations(ctx context.Context, clusterID uuid.UUID, reason string) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelClusterOperations", ctx, clusterID, reason)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelClusterOperations indicates an expected call of CancelClusterOperations.
+func (mr *MockClusterManagerMockRecorder) CancelClusterOperations(ctx, clusterID, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelClusterOperations", reflect.TypeOf((*MockClusterManager)(nil).CancelClusterOperations), ctx, clusterID, reason)
+}
+
+// CountClusterLogs mocks base method.
+func (m *MockClusterManager) CountClusterLogs(ctx context.Context, clusterID uuid.UUID, level string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountClusterLogs", ctx, clusterID, level)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountClusterLogs indicates an expected call of CountClusterLogs.
+func (mr *MockClusterManagerMockRecorder) CountClusterLogs(ctx, clusterID, level any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountClusterLogs", reflect.TypeOf((*MockClusterManager)(nil).CountClusterLogs), ctx, clusterID, level)
+}
+
+// CountClusterOperations mocks base method.
+func (m *MockClusterManager) CountClusterOperations(ctx context.Context, clusterID uuid.UUID) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountClusterOperations", ctx, clusterID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountClusterOperations indicates an expected call of CountClusterOperations.
+func (mr *MockClusterManagerMockRecorder) CountClusterOperations(ctx, clusterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountClusterOperations", reflect.TypeOf((*MockClusterManager)(nil).CountClusterOperations), ctx, clusterID)
+}
+
+// CountClusters mocks base method.
+func (m *MockClusterManager) CountClusters(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountClusters", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountClusters indicates an expected call of CountClusters.
+func (mr *MockClusterManagerMockRecorder) CountClusters(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountClusters", reflect.TypeOf((*MockClusterManager)(nil).CountClusters), ctx)
+}
+
+// CreateCluster mocks base method.
+func (m *MockClusterManager) CreateCluster(ctx context.Context, name, description string, labels map[string]string) (*repo.Cluster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCluster", ctx, name, description, labels)
+	ret0, _ := ret[0].(*repo.Cluster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCluster indicates an expected call of CreateCluster.
+func (mr *MockClusterManagerMockRecorder) CreateCluster(ctx, name, description, labels any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCluster", reflect.TypeOf((*MockClusterManager)(nil).CreateCluster), ctx, name, description, labels)
+}
+
 // CreateOperation mocks base method.
 func (m *MockClusterManager) CreateOperation(ctx context.Context, operation *repo.Operation) error {
 	m.ctrl.T.Helper()
@@ -85,19 +164,81 @@ func (mr *MockClusterManagerMockRecorder) GetCluster(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCluster", reflect.TypeOf((*MockClusterManager)(nil).GetCluster), ctx, id)
 }
 
-// GetClusterResources mocks base method.
-func (m *MockClusterManager) GetClusterResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string) ([]map[string]any, error) {
+// GetClusterBaseline mocks base method.
+func (m *MockClusterManager) GetClusterBaseline(ctx context.Context, clusterID uuid.UUID) (*repo.ClusterBaseline, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetClusterResources", ctx, clusterID, kind, namespace)
-	ret0, _ := ret[0].([]map[string]any)
+	ret := m.ctrl.Call(m, "GetClusterBaseline", ctx, clusterID)
+	ret0, _ := ret[0].(*repo.ClusterBaseline)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// GetClusterBaseline indicates an expected call of GetClusterBaseline.
+func (mr *MockClusterManagerMockRecorder) GetClusterBaseline(ctx, clusterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClusterBaseline", reflect.TypeOf((*MockClusterManager)(nil).GetClusterBaseline), ctx, clusterID)
+}
+
+// GetClusterDiagnostics mocks base method.
+func (m *MockClusterManager) GetClusterDiagnostics(ctx context.Context, clusterID uuid.UUID) (kube.PermissionReport, *repo.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClusterDiagnostics", ctx, clusterID)
+	ret0, _ := ret[0].(kube.PermissionReport)
+	ret1, _ := ret[1].(*repo.Operation)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetClusterDiagnostics indicates an expected call of GetClusterDiagnostics.
+func (mr *MockClusterManagerMockRecorder) GetClusterDiagnostics(ctx, clusterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClusterDiagnostics", reflect.TypeOf((*MockClusterManager)(nil).GetClusterDiagnostics), ctx, clusterID)
+}
+
+// GetClusterResources mocks base method.
+func (m *MockClusterManager) GetClusterResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string, allowStale bool) (*cluster.GetClusterResourcesResponse, *repo.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClusterResources", ctx, clusterID, kind, namespace, allowStale)
+	ret0, _ := ret[0].(*cluster.GetClusterResourcesResponse)
+	ret1, _ := ret[1].(*repo.Operation)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // GetClusterResources indicates an expected call of GetClusterResources.
-func (mr *MockClusterManagerMockRecorder) GetClusterResources(ctx, clusterID, kind, namespace any) *gomock.Call {
+func (mr *MockClusterManagerMockRecorder) GetClusterResources(ctx, clusterID, kind, namespace, allowStale any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClusterResources", reflect.TypeOf((*MockClusterManager)(nil).GetClusterResources), ctx, clusterID, kind, namespace, allowStale)
+}
+
+// ListClusterLogs mocks base method.
+func (m *MockClusterManager) ListClusterLogs(ctx context.Context, clusterID uuid.UUID, level string, limit, offset int) ([]*repo.LogEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListClusterLogs", ctx, clusterID, level, limit, offset)
+	ret0, _ := ret[0].([]*repo.LogEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListClusterLogs indicates an expected call of ListClusterLogs.
+func (mr *MockClusterManagerMockRecorder) ListClusterLogs(ctx, clusterID, level, limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClusterLogs", reflect.TypeOf((*MockClusterManager)(nil).ListClusterLogs), ctx, clusterID, level, limit, offset)
+}
+
+// ListClusterOperations mocks base method.
+func (m *MockClusterManager) ListClusterOperations(ctx context.Context, clusterID uuid.UUID, limit, offset int) ([]*repo.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListClusterOperations", ctx, clusterID, limit, offset)
+	ret0, _ := ret[0].([]*repo.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListClusterOperations indicates an expected call of ListClusterOperations.
+func (mr *MockClusterManagerMockRecorder) ListClusterOperations(ctx, clusterID, limit, offset any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClusterResources", reflect.TypeOf((*MockClusterManager)(nil).GetClusterResources), ctx, clusterID, kind, namespace)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClusterOperations", reflect.TypeOf((*MockClusterManager)(nil).ListClusterOperations), ctx, clusterID, limit, offset)
 }
 
 // ListClusters mocks base method.
@@ -115,6 +256,53 @@ func (mr *MockClusterManagerMockRecorder) ListClusters(ctx, limit, offset any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListClusters", reflect.TypeOf((*MockClusterManager)(nil).ListClusters), ctx, limit, offset)
 }
 
+// ListNamespaces mocks base method.
+func (m *MockClusterManager) ListNamespaces(ctx context.Context, clusterID uuid.UUID) ([]kube.NamespaceInfo, *repo.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNamespaces", ctx, clusterID)
+	ret0, _ := ret[0].([]kube.NamespaceInfo)
+	ret1, _ := ret[1].(*repo.Operation)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListNamespaces indicates an expected call of ListNamespaces.
+func (mr *MockClusterManagerMockRecorder) ListNamespaces(ctx, clusterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNamespaces", reflect.TypeOf((*MockClusterManager)(nil).ListNamespaces), ctx, clusterID)
+}
+
+// ListNodes mocks base method.
+func (m *MockClusterManager) ListNodes(ctx context.Context, clusterID uuid.UUID) ([]kube.NodeInfo, *repo.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodes", ctx, clusterID)
+	ret0, _ := ret[0].([]kube.NodeInfo)
+	ret1, _ := ret[1].(*repo.Operation)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListNodes indicates an expected call of ListNodes.
+func (mr *MockClusterManagerMockRecorder) ListNodes(ctx, clusterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodes", reflect.TypeOf((*MockClusterManager)(nil).ListNodes), ctx, clusterID)
+}
+
+// QuarantineCluster mocks base method.
+func (m *MockClusterManager) QuarantineCluster(ctx context.Context, id uuid.UUID, reason string) (*repo.Cluster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuarantineCluster", ctx, id, reason)
+	ret0, _ := ret[0].(*repo.Cluster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuarantineCluster indicates an expected call of QuarantineCluster.
+func (mr *MockClusterManagerMockRecorder) QuarantineCluster(ctx, id, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuarantineCluster", reflect.TypeOf((*MockClusterManager)(nil).QuarantineCluster), ctx, id, reason)
+}
+
 // QueueOperation mocks base method.
 func (m *MockClusterManager) QueueOperation(ctx context.Context, operation *repo.Operation) error {
 	m.ctrl.T.Helper()
@@ -129,6 +317,65 @@ func (mr *MockClusterManagerMockRecorder) QueueOperation(ctx, operation any) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueueOperation", reflect.TypeOf((*MockClusterManager)(nil).QueueOperation), ctx, operation)
 }
 
+// ReleaseCluster mocks base method.
+func (m *MockClusterManager) ReleaseCluster(ctx context.Context, id uuid.UUID) (*repo.Cluster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseCluster", ctx, id)
+	ret0, _ := ret[0].(*repo.Cluster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseCluster indicates an expected call of ReleaseCluster.
+func (mr *MockClusterManagerMockRecorder) ReleaseCluster(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseCluster", reflect.TypeOf((*MockClusterManager)(nil).ReleaseCluster), ctx, id)
+}
+
+// SetClusterBaseline mocks base method.
+func (m *MockClusterManager) SetClusterBaseline(ctx context.Context, clusterID uuid.UUID, resources []repo.InventoryEntry) (*repo.ClusterBaseline, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetClusterBaseline", ctx, clusterID, resources)
+	ret0, _ := ret[0].(*repo.ClusterBaseline)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetClusterBaseline indicates an expected call of SetClusterBaseline.
+func (mr *MockClusterManagerMockRecorder) SetClusterBaseline(ctx, clusterID, resources any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetClusterBaseline", reflect.TypeOf((*MockClusterManager)(nil).SetClusterBaseline), ctx, clusterID, resources)
+}
+
+// StartPodLogs mocks base method.
+func (m *MockClusterManager) StartPodLogs(ctx context.Context, clusterID uuid.UUID, request *repo.PodLogsRequest) (*repo.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartPodLogs", ctx, clusterID, request)
+	ret0, _ := ret[0].(*repo.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartPodLogs indicates an expected call of StartPodLogs.
+func (mr *MockClusterManagerMockRecorder) StartPodLogs(ctx, clusterID, request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartPodLogs", reflect.TypeOf((*MockClusterManager)(nil).StartPodLogs), ctx, clusterID, request)
+}
+
+// StreamOperationOutput mocks base method.
+func (m *MockClusterManager) StreamOperationOutput(ctx context.Context, operationID uuid.UUID, w io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamOperationOutput", ctx, operationID, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamOperationOutput indicates an expected call of StreamOperationOutput.
+func (mr *MockClusterManagerMockRecorder) StreamOperationOutput(ctx, operationID, w any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamOperationOutput", reflect.TypeOf((*MockClusterManager)(nil).StreamOperationOutput), ctx, operationID, w)
+}
+
 // UpdateCluster mocks base method.
 func (m *MockClusterManager) UpdateCluster(ctx context.Context, id uuid.UUID, name, description string, labels map[string]string) error {
 	m.ctrl.T.Helper()
@@ -142,3 +389,133 @@ func (mr *MockClusterManagerMockRecorder) UpdateCluster(ctx, id, name, descripti
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCluster", reflect.TypeOf((*MockClusterManager)(nil).UpdateCluster), ctx, id, name, description, labels)
 }
+
+// MockOrchestratorAdmin is a mock of OrchestratorAdmin interface.
+type MockOrchestratorAdmin struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrchestratorAdminMockRecorder
+	isgomock struct{}
+}
+
+// MockOrchestratorAdminMockRecorder is the mock recorder for MockOrchestratorAdmin.
+type MockOrchestratorAdminMockRecorder struct {
+	mock *MockOrchestratorAdmin
+}
+
+// NewMockOrchestratorAdmin creates a new mock instance.
+func NewMockOrchestratorAdmin(ctrl *gomock.Controller) *MockOrchestratorAdmin {
+	mock := &MockOrchestratorAdmin{ctrl: ctrl}
+	mock.recorder = &MockOrchestratorAdminMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOrchestratorAdmin) EXPECT() *MockOrchestratorAdminMockRecorder {
+	return m.recorder
+}
+
+// Pause mocks base method.
+func (m *MockOrchestratorAdmin) Pause() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Pause")
+}
+
+// Pause indicates an expected call of Pause.
+func (mr *MockOrchestratorAdminMockRecorder) Pause() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pause", reflect.TypeOf((*MockOrchestratorAdmin)(nil).Pause))
+}
+
+// Resume mocks base method.
+func (m *MockOrchestratorAdmin) Resume() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Resume")
+}
+
+// Resume indicates an expected call of Resume.
+func (mr *MockOrchestratorAdminMockRecorder) Resume() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockOrchestratorAdmin)(nil).Resume))
+}
+
+// State mocks base method.
+func (m *MockOrchestratorAdmin) State() orchestrator.State {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "State")
+	ret0, _ := ret[0].(orchestrator.State)
+	return ret0
+}
+
+// State indicates an expected call of State.
+func (mr *MockOrchestratorAdminMockRecorder) State() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockOrchestratorAdmin)(nil).State))
+}
+
+// MockDeadLetterAdmin is a mock of DeadLetterAdmin interface.
+type MockDeadLetterAdmin struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeadLetterAdminMockRecorder
+	isgomock struct{}
+}
+
+// MockDeadLetterAdminMockRecorder is the mock recorder for MockDeadLetterAdmin.
+type MockDeadLetterAdminMockRecorder struct {
+	mock *MockDeadLetterAdmin
+}
+
+// NewMockDeadLetterAdmin creates a new mock instance.
+func NewMockDeadLetterAdmin(ctrl *gomock.Controller) *MockDeadLetterAdmin {
+	mock := &MockDeadLetterAdmin{ctrl: ctrl}
+	mock.recorder = &MockDeadLetterAdminMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeadLetterAdmin) EXPECT() *MockDeadLetterAdminMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockDeadLetterAdmin) Count(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockDeadLetterAdminMockRecorder) Count(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockDeadLetterAdmin)(nil).Count), ctx)
+}
+
+// List mocks base method.
+func (m *MockDeadLetterAdmin) List(ctx context.Context, limit, offset int) ([]*repo.DeadLetter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, limit, offset)
+	ret0, _ := ret[0].([]*repo.DeadLetter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockDeadLetterAdminMockRecorder) List(ctx, limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDeadLetterAdmin)(nil).List), ctx, limit, offset)
+}
+
+// Redrive mocks base method.
+func (m *MockDeadLetterAdmin) Redrive(ctx context.Context, operationID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redrive", ctx, operationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Redrive indicates an expected call of Redrive.
+func (mr *MockDeadLetterAdminMockRecorder) Redrive(ctx, operationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redrive", reflect.TypeOf((*MockDeadLetterAdmin)(nil).Redrive), ctx, operationID)
+}
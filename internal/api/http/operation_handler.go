@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -10,11 +11,13 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/repo"
 )
 
 // OperationHandler handles operation-related HTTP requests
 type OperationHandler struct {
 	operationService *operation.Service
+	pagination       Pagination
 	logger           *zap.Logger
 }
 
@@ -22,19 +25,22 @@ type OperationHandler struct {
 func NewOperationHandler(operationService *operation.Service, logger *zap.Logger) *OperationHandler {
 	return &OperationHandler{
 		operationService: operationService,
+		pagination:       DefaultPagination,
 		logger:           logger,
 	}
 }
 
 // GetOperation handles getting a single operation
 // @Summary Get operation by ID
-// @Description Get a specific operation by its ID
+// @Description Get a specific operation by its ID, including its state transition history
 // @Tags operations
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Operation ID"
-// @Success 200 {object} OperationDTO
+// @Param If-None-Match header string false "ETag of a previously fetched representation"
+// @Success 200 {object} OperationDetailDTO
+// @Success 304 "Not modified since the given ETag"
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
@@ -52,12 +58,99 @@ func (h *OperationHandler) GetOperation(w http.ResponseWriter, r *http.Request)
 
 	operation, err := h.operationService.GetOperation(r.Context(), id)
 	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			WriteErrorResponse(w, http.StatusNotFound, "Operation not found")
+			return
+		}
+		h.logger.Error("Failed to get operation", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get operation")
+		return
+	}
+
+	// Status changes bump updated_at along with the history, so the history
+	// doesn't need to be loaded to answer a conditional request
+	if notModified(w, r, resourceETag(operation.ID, operation.UpdatedAt)) {
+		return
+	}
+
+	// The history is best effort, the operation itself is still returned without it
+	events, err := h.operationService.GetOperationHistory(r.Context(), id)
+	if err != nil {
+		h.logger.Warn("Failed to get operation history", zap.Error(err))
+		events = []*repo.OperationEvent{}
+	}
+
+	WriteJSONResponse(w, http.StatusOK, OperationDetailDTO{
+		Operation: operation,
+		Events:    events,
+	})
+}
+
+// GetOperationOutput handles reading an operation's output from an offset
+// @Summary Get operation output
+// @Description Get the output of an operation starting at a byte offset. Poll again from next_offset to follow a running operation until complete is true and no data is returned.
+// @Tags operations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Operation ID"
+// @Param offset query int false "Byte offset to read from"
+// @Param limit query int false "Maximum number of bytes to return"
+// @Success 200 {object} OperationOutputDTO
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /operations/{id}/output [get]
+func (h *OperationHandler) GetOperationOutput(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WriteErrorResponse(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	var offset int64
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil || offset < 0 {
+			WriteErrorResponse(w, http.StatusBadRequest, "Offset must be a non-negative integer")
+			return
+		}
+	}
+
+	var limit int
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			WriteErrorResponse(w, http.StatusBadRequest, "Limit must be a positive integer")
+			return
+		}
+	}
+
+	// Make sure the operation exists before reading its output
+	if _, err := h.operationService.GetOperation(r.Context(), id); err != nil {
 		h.logger.Error("Failed to get operation", zap.Error(err))
 		WriteErrorResponse(w, http.StatusNotFound, "Operation not found")
 		return
 	}
 
-	WriteJSONResponse(w, http.StatusOK, operation)
+	chunk, err := h.operationService.GetOperationOutput(r.Context(), id, offset, limit)
+	if err != nil {
+		h.logger.Error("Failed to get operation output", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get operation output")
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, OperationOutputDTO{
+		OperationID: id.String(),
+		Offset:      chunk.Offset,
+		NextOffset:  chunk.Offset + int64(len(chunk.Data)),
+		Size:        chunk.Size,
+		Data:        string(chunk.Data),
+		Complete:    chunk.Complete,
+	})
 }
 
 // ListOperationsByCluster handles listing operations for a cluster
@@ -69,9 +162,9 @@ func (h *OperationHandler) GetOperation(w http.ResponseWriter, r *http.Request)
 // @Security BearerAuth
 // @Param clusterId path string true "Cluster ID"
 // @Param status query string false "Operation status filter"
-// @Param limit query int false "Limit number of results"
-// @Param offset query int false "Offset for pagination"
-// @Success 200 {array} OperationDTO
+// @Param limit query int false "Maximum number of operations, capped at the server's max_limit"
+// @Param offset query int false "Number of operations to skip" default(0)
+// @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
@@ -86,14 +179,9 @@ func (h *OperationHandler) ListOperationsByCluster(w http.ResponseWriter, r *htt
 		return
 	}
 
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-
-	if limit <= 0 {
-		limit = 10
-	}
-	if offset < 0 {
-		offset = 0
+	limit, offset, ok := h.pagination.parse(w, r)
+	if !ok {
+		return
 	}
 
 	operations, err := h.operationService.ListOperationsByCluster(r.Context(), clusterID, limit, offset)
@@ -103,13 +191,17 @@ func (h *OperationHandler) ListOperationsByCluster(w http.ResponseWriter, r *htt
 		return
 	}
 
-	WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"operations":  operations,
-		"total_count": len(operations),
-		"cluster_id":  clusterID,
-		"limit":       limit,
-		"offset":      offset,
-	})
+	total, err := h.operationService.CountOperationsByCluster(r.Context(), clusterID)
+	if err != nil {
+		h.logger.Error("Failed to count operations", zap.Error(err))
+		WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list operations")
+		return
+	}
+
+	WriteJSONResponse(w, http.StatusOK, listResponse(h.pagination.page(limit, offset, len(operations), total), map[string]interface{}{
+		"operations": operations,
+		"cluster_id": clusterID,
+	}))
 }
 
 // CancelOperation handles cancelling an operation
@@ -0,0 +1,216 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestOperationHandler_GetOperationOutputInChunks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockOutputRepo := repomocks.NewMockOperationOutputRepository(ctrl)
+	mockCache := repomocks.NewMockCache(ctrl)
+	operationID := uuid.New()
+	output := []byte("resolved 42 bytes of manifests\nmanifests applied\n")
+
+	mockCache.EXPECT().OperationKey(operationID.String()).Return("operation:" + operationID.String()).AnyTimes()
+	mockCache.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(repo.ErrCacheMiss).AnyTimes()
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockOpRepo.EXPECT().GetByID(gomock.Any(), operationID).Return(&repo.Operation{
+		ID:     operationID,
+		Status: repo.OperationStatusSuccess,
+	}, nil).AnyTimes()
+	mockOutputRepo.EXPECT().Read(gomock.Any(), operationID, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ uuid.UUID, offset int64, limit int) ([]byte, int64, error) {
+			end := offset + int64(limit)
+			if end > int64(len(output)) {
+				end = int64(len(output))
+			}
+			if offset > end {
+				offset = end
+			}
+			return output[offset:end], int64(len(output)), nil
+		}).AnyTimes()
+
+	service := operation.NewService(mockOpRepo, nil, mockOutputRepo, mockCache, zap.NewNop(), nil)
+	handler := NewOperationHandler(service, zap.NewNop())
+
+	fetch := func(offset int64) OperationOutputDTO {
+		t.Helper()
+
+		req := httptest.NewRequest("GET", fmt.Sprintf("/operations/%s/output?offset=%d&limit=20", operationID, offset), nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", operationID.String())
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		w := httptest.NewRecorder()
+		handler.GetOperationOutput(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var chunk OperationOutputDTO
+		if err := json.NewDecoder(w.Body).Decode(&chunk); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return chunk
+	}
+
+	// Read the output back in chunks, following next_offset until no data is left
+	var got []byte
+	var offset int64
+	for i := 0; i < 10; i++ {
+		chunk := fetch(offset)
+		if chunk.Offset != offset {
+			t.Errorf("Expected offset %d but got %d", offset, chunk.Offset)
+		}
+		if chunk.Size != int64(len(output)) {
+			t.Errorf("Expected size %d but got %d", len(output), chunk.Size)
+		}
+		if !chunk.Complete {
+			t.Error("Expected output of a finished operation to be complete")
+		}
+		if chunk.Data == "" {
+			break
+		}
+		got = append(got, chunk.Data...)
+		offset = chunk.NextOffset
+	}
+
+	if string(got) != string(output) {
+		t.Errorf("Expected output %q but got %q", output, got)
+	}
+}
+
+func TestOperationHandler_GetOperationOutputInvalidOffset(t *testing.T) {
+	handler := NewOperationHandler(nil, zap.NewNop())
+	operationID := uuid.New()
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/operations/%s/output?offset=-1", operationID), nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", operationID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.GetOperationOutput(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestOperationHandler_GetOperation(t *testing.T) {
+	operationID := uuid.New()
+	startedAt := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	result := repo.Payload{"message": "applied"}
+
+	tests := []struct {
+		name           string
+		id             string
+		repoOperation  *repo.Operation
+		repoError      error
+		expectedStatus int
+	}{
+		{
+			name: "successful get operation",
+			id:   operationID.String(),
+			repoOperation: &repo.Operation{
+				ID:        operationID,
+				Type:      repo.OperationTypeApply,
+				Status:    repo.OperationStatusSuccess,
+				Result:    &result,
+				StartedAt: &startedAt,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid operation ID",
+			id:             "invalid",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "operation not found",
+			id:             operationID.String(),
+			repoError:      repo.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "repository error",
+			id:             operationID.String(),
+			repoError:      errors.New("database error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+			mockEventRepo := repomocks.NewMockOperationEventRepository(ctrl)
+			mockCache := repomocks.NewMockCache(ctrl)
+
+			mockCache.EXPECT().OperationKey(gomock.Any()).Return("operation:" + tt.id).AnyTimes()
+			mockCache.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(repo.ErrCacheMiss).AnyTimes()
+			mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			mockOpRepo.EXPECT().GetByID(gomock.Any(), gomock.Any()).Return(tt.repoOperation, tt.repoError).AnyTimes()
+			mockEventRepo.EXPECT().ListByOperation(gomock.Any(), gomock.Any()).Return([]*repo.OperationEvent{}, nil).AnyTimes()
+
+			service := operation.NewService(mockOpRepo, mockEventRepo, nil, mockCache, zap.NewNop(), nil)
+			handler := NewOperationHandler(service, zap.NewNop())
+
+			req := httptest.NewRequest("GET", "/operations/"+tt.id, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.GetOperation(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				ID        uuid.UUID    `json:"id"`
+				Status    string       `json:"status"`
+				Result    repo.Payload `json:"result"`
+				StartedAt *time.Time   `json:"started_at"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if resp.ID != operationID || resp.Status != repo.OperationStatusSuccess {
+				t.Errorf("Expected operation %s with status %s but got %+v", operationID, repo.OperationStatusSuccess, resp)
+			}
+			if resp.Result["message"] != "applied" {
+				t.Errorf("Expected the operation result but got %v", resp.Result)
+			}
+			if resp.StartedAt == nil || !resp.StartedAt.Equal(startedAt) {
+				t.Errorf("Expected started_at %v but got %v", startedAt, resp.StartedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Pagination holds the page sizes of list endpoints
+type Pagination struct {
+	// DefaultLimit is used when a request doesn't give a limit
+	DefaultLimit int
+	// MaxLimit caps the limit a request may ask for; larger limits are clamped
+	MaxLimit int
+}
+
+// DefaultPagination applies when no page sizes are configured
+var DefaultPagination = Pagination{DefaultLimit: 10, MaxLimit: 100}
+
+// NewPagination creates page size settings, falling back to DefaultPagination
+// for non-positive values
+func NewPagination(defaultLimit, maxLimit int) Pagination {
+	p := DefaultPagination
+	if maxLimit > 0 {
+		p.MaxLimit = maxLimit
+	}
+	if defaultLimit > 0 {
+		p.DefaultLimit = defaultLimit
+	}
+	if p.DefaultLimit > p.MaxLimit {
+		p.DefaultLimit = p.MaxLimit
+	}
+	return p
+}
+
+// parse reads the limit and offset query parameters, writing a 400 response
+// and returning false when they are invalid. The returned limit is the
+// effective one, after defaulting and clamping.
+func (p Pagination) parse(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit = p.DefaultLimit
+	offset = 0
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid limit parameter")
+			return 0, 0, false
+		}
+		if limit <= 0 {
+			WriteErrorResponse(w, http.StatusBadRequest, "Limit must be positive")
+			return 0, 0, false
+		}
+	}
+	if limit > p.MaxLimit {
+		limit = p.MaxLimit
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		var err error
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil {
+			WriteErrorResponse(w, http.StatusBadRequest, "Invalid offset parameter")
+			return 0, 0, false
+		}
+		if offset < 0 {
+			WriteErrorResponse(w, http.StatusBadRequest, "Offset must be non-negative")
+			return 0, 0, false
+		}
+	}
+
+	return limit, offset, true
+}
+
+// page builds the pagination envelope of a list response holding returned
+// items out of total
+func (p Pagination) page(limit, offset, returned, total int) PageDTO {
+	return PageDTO{
+		Limit:      limit,
+		Offset:     offset,
+		TotalCount: total,
+		HasMore:    offset+returned < total,
+		MaxLimit:   p.MaxLimit,
+	}
+}
+
+// listResponse adds the pagination envelope to the fields of a list response
+func listResponse(page PageDTO, fields map[string]interface{}) map[string]interface{} {
+	fields["limit"] = page.Limit
+	fields["offset"] = page.Offset
+	fields["total_count"] = page.TotalCount
+	fields["has_more"] = page.HasMore
+	fields["max_limit"] = page.MaxLimit
+	return fields
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/api/http/mocks"
+	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+// decodePage reads the pagination envelope of a list response
+func decodePage(t *testing.T, w *httptest.ResponseRecorder) PageDTO {
+	t.Helper()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var page PageDTO
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return page
+}
+
+func TestClusterHandler_ListClustersPagination(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clusters := make([]*repo.Cluster, 5)
+	for i := range clusters {
+		clusters[i] = &repo.Cluster{ID: uuid.New(), Name: fmt.Sprintf("cluster%d", i)}
+	}
+
+	mockClusterService := mocks.NewMockClusterManager(ctrl)
+	mockClusterService.EXPECT().ListClusters(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, limit, offset int) ([]*repo.Cluster, error) {
+			end := min(offset+limit, len(clusters))
+			return clusters[min(offset, end):end], nil
+		}).AnyTimes()
+	mockClusterService.EXPECT().CountClusters(gomock.Any()).Return(len(clusters), nil).AnyTimes()
+
+	handler := NewClusterHandler(mockClusterService, zap.NewNop())
+	handler.pagination = NewPagination(2, 3)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected PageDTO
+	}{
+		{
+			name:     "default limit on the first page",
+			query:    "",
+			expected: PageDTO{Limit: 2, Offset: 0, TotalCount: 5, HasMore: true, MaxLimit: 3},
+		},
+		{
+			name:     "middle page",
+			query:    "?limit=2&offset=2",
+			expected: PageDTO{Limit: 2, Offset: 2, TotalCount: 5, HasMore: true, MaxLimit: 3},
+		},
+		{
+			name:     "last page",
+			query:    "?limit=2&offset=4",
+			expected: PageDTO{Limit: 2, Offset: 4, TotalCount: 5, HasMore: false, MaxLimit: 3},
+		},
+		{
+			name:     "limit clamped to the maximum",
+			query:    "?limit=50",
+			expected: PageDTO{Limit: 3, Offset: 0, TotalCount: 5, HasMore: true, MaxLimit: 3},
+		},
+		{
+			name:     "offset past the end",
+			query:    "?offset=10",
+			expected: PageDTO{Limit: 2, Offset: 10, TotalCount: 5, HasMore: false, MaxLimit: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ListClusters(w, httptest.NewRequest("GET", "/clusters"+tt.query, nil))
+
+			if page := decodePage(t, w); page != tt.expected {
+				t.Errorf("Expected page %+v but got %+v", tt.expected, page)
+			}
+		})
+	}
+}
+
+func TestOperationHandler_ListOperationsByClusterPagination(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clusterID := uuid.New()
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockOpRepo.EXPECT().ListByCluster(gomock.Any(), clusterID, 2, 2).Return([]*repo.Operation{
+		{ID: uuid.New(), ClusterID: clusterID},
+		{ID: uuid.New(), ClusterID: clusterID},
+	}, nil)
+	mockOpRepo.EXPECT().CountByCluster(gomock.Any(), clusterID).Return(4, nil)
+
+	service := operation.NewService(mockOpRepo, nil, nil, repomocks.NewMockCache(ctrl), zap.NewNop(), nil)
+	handler := NewOperationHandler(service, zap.NewNop())
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/operations/cluster/%s?limit=2&offset=2", clusterID), nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("clusterId", clusterID.String())
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler.ListOperationsByCluster(w, req)
+
+	expected := PageDTO{Limit: 2, Offset: 2, TotalCount: 4, HasMore: false, MaxLimit: DefaultPagination.MaxLimit}
+	if page := decodePage(t, w); page != expected {
+		t.Errorf("Expected page %+v but got %+v", expected, page)
+	}
+}
+
+func TestNewPagination(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultLimit int
+		maxLimit     int
+		expected     Pagination
+	}{
+		{name: "unset values use the defaults", expected: DefaultPagination},
+		{name: "configured values", defaultLimit: 20, maxLimit: 500, expected: Pagination{DefaultLimit: 20, MaxLimit: 500}},
+		{name: "default limit above the maximum", defaultLimit: 50, maxLimit: 25, expected: Pagination{DefaultLimit: 25, MaxLimit: 25}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewPagination(tt.defaultLimit, tt.maxLimit); got != tt.expected {
+				t.Errorf("Expected %+v but got %+v", tt.expected, got)
+			}
+		})
+	}
+}
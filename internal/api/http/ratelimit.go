@@ -0,0 +1,162 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/auth"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// rateLimitKeyPrefix prefixes the cache keys of rate limit counters
+const rateLimitKeyPrefix = "ratelimit:"
+
+// RateLimitOptions controls per-client API rate limiting. Each user, or each
+// personal access token, may make Burst requests per window, the time it
+// takes to make them at RequestsPerSecond. A window starts with the first
+// request after the previous one ended.
+type RateLimitOptions struct {
+	RequestsPerSecond float64
+	Burst             int
+	// ExemptRoles and ExemptTokens list the roles and personal access token
+	// IDs that are never limited
+	ExemptRoles  []string
+	ExemptTokens []string
+}
+
+// exempt reports whether user is never rate limited
+func (o RateLimitOptions) exempt(user *auth.AuthenticatedUser) bool {
+	for _, id := range o.ExemptTokens {
+		if user.TokenID != "" && user.TokenID == id {
+			return true
+		}
+	}
+	for _, role := range o.ExemptRoles {
+		for _, userRole := range user.Roles {
+			if role == userRole {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// window returns the period Burst requests are allowed in
+func (o RateLimitOptions) window() time.Duration {
+	return time.Duration(float64(max(o.Burst, 1)) / o.RequestsPerSecond * float64(time.Second))
+}
+
+// SetRateLimitCache sets the cache rate limit counters are kept in, so limits
+// hold across hub instances. Rate limiting is off without it. It must be
+// called before SetupRoutes.
+func (r *Router) SetRateLimitCache(cache repo.Cache) {
+	r.rateLimitCache = cache
+}
+
+// rateLimitMiddleware limits requests per user when rate limiting is enabled
+func (r *Router) rateLimitMiddleware(next http.Handler) http.Handler {
+	if r.rateLimitCache == nil || r.cfg == nil || !r.cfg.RateLimit.Enabled {
+		return next
+	}
+	return rateLimitMiddleware(r.rateLimitCache, RateLimitOptions{
+		RequestsPerSecond: r.cfg.RateLimit.RequestsPerSecond,
+		Burst:             r.cfg.RateLimit.Burst,
+		ExemptRoles:       r.cfg.RateLimit.ExemptRoles,
+		ExemptTokens:      r.cfg.RateLimit.ExemptTokens,
+	}, r.logger)(next)
+}
+
+// authRateLimitMiddleware limits requests to the unauthenticated auth
+// endpoints per client IP, against credential stuffing on login
+func (r *Router) authRateLimitMiddleware(next http.Handler) http.Handler {
+	if r.rateLimitCache == nil || r.cfg == nil {
+		return next
+	}
+	limit := r.cfg.RateLimit.Auth
+	if !limit.Enabled || limit.Requests <= 0 || limit.Window <= 0 {
+		return next
+	}
+	return ipRateLimitMiddleware(r.rateLimitCache, RateLimitOptions{
+		RequestsPerSecond: float64(limit.Requests) / limit.Window.Seconds(),
+		Burst:             limit.Requests,
+	}, r.logger)(next)
+}
+
+// ipRateLimitMiddleware is like rateLimitMiddleware for unauthenticated
+// requests, counting the requests of each client IP. Exemptions don't apply.
+func ipRateLimitMiddleware(cache repo.Cache, opts RateLimitOptions, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if opts.RequestsPerSecond <= 0 {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			ip := clientIP(req)
+			allowed, retryAfter, err := allowRequest(req.Context(), cache, rateLimitKeyPrefix+"ip:"+ip, opts)
+			if err != nil {
+				logger.Warn("Rate limiting unavailable", zap.Error(err))
+			} else if !allowed {
+				logger.Warn("Auth rate limit exceeded", zap.String("ip", ip), zap.String("path", req.URL.Path))
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				WriteErrorResponse(w, http.StatusTooManyRequests, "Too many requests, try again later")
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// rateLimitMiddleware rejects requests of clients that used up their window
+// with a 429 and a Retry-After telling when the next request is allowed. It
+// must run after authentication; requests made with a personal access token
+// are limited per token, others per user. Requests are let through when the
+// cache is unavailable.
+func rateLimitMiddleware(cache repo.Cache, opts RateLimitOptions, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			user, ok := auth.GetUserFromContext(req.Context())
+			if !ok || opts.RequestsPerSecond <= 0 || opts.exempt(user) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			key := rateLimitKeyPrefix + "user:" + user.ID
+			if user.ViaAPIToken() {
+				key = rateLimitKeyPrefix + "token:" + user.TokenID
+			}
+
+			allowed, retryAfter, err := allowRequest(req.Context(), cache, key, opts)
+			if err != nil {
+				logger.Warn("Rate limiting unavailable", zap.Error(err))
+			} else if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				WriteErrorResponse(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// allowRequest counts a request against the window at key, reporting whether
+// it is within the limit and, if not, how long until the window ends. The
+// count is incremented atomically, so concurrent requests can't get past the
+// limit.
+func allowRequest(ctx context.Context, cache repo.Cache, key string, opts RateLimitOptions) (bool, time.Duration, error) {
+	count, ttl, err := cache.IncrementWithExpiry(ctx, key, opts.window())
+	if err != nil {
+		return false, 0, err
+	}
+	if count <= int64(max(opts.Burst, 1)) {
+		return true, 0, nil
+	}
+	return false, ttl, nil
+}
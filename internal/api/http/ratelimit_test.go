@@ -0,0 +1,276 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/auth"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// memoryCache is an in-memory repo.Cache storing values as JSON. Only
+// counters expire.
+type memoryCache struct {
+	repo.Cache
+	mu       sync.Mutex
+	data     map[string][]byte
+	counters map[string]*memoryCounter
+}
+
+// memoryCounter is a counter of memoryCache
+type memoryCounter struct {
+	count   int64
+	expires time.Time
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{data: make(map[string][]byte), counters: make(map[string]*memoryCounter)}
+}
+
+func (c *memoryCache) IncrementWithExpiry(_ context.Context, key string, expiration time.Duration) (int64, time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	counter, ok := c.counters[key]
+	if !ok || !now.Before(counter.expires) {
+		counter = &memoryCounter{expires: now.Add(expiration)}
+		c.counters[key] = counter
+	}
+	counter.count++
+	return counter.count, counter.expires.Sub(now), nil
+}
+
+func (c *memoryCache) Get(_ context.Context, key string, dest interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.data[key]
+	if !ok {
+		return repo.ErrCacheMiss
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = data
+	return nil
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limited := rateLimitMiddleware(newMemoryCache(), RateLimitOptions{
+		RequestsPerSecond: 0.5,
+		Burst:             2,
+		ExemptRoles:       []string{"admin"},
+		ExemptTokens:      []string{"ci-token"},
+	}, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(user *auth.AuthenticatedUser) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/clusters", nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+		w := httptest.NewRecorder()
+		limited.ServeHTTP(w, req)
+		return w
+	}
+
+	noisy := &auth.AuthenticatedUser{ID: "noisy", Roles: []string{"operator"}}
+	quiet := &auth.AuthenticatedUser{ID: "quiet", Roles: []string{"operator"}}
+
+	// The burst is allowed, then the noisy user is throttled
+	for i := 0; i < 2; i++ {
+		if w := request(noisy); w.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to pass but got status %d", i+1, w.Code)
+		}
+	}
+	w := request(noisy)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d but got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "4" {
+		t.Errorf("Expected Retry-After 4 but got %q", retryAfter)
+	}
+
+	// Other users have their own window
+	if w := request(quiet); w.Code != http.StatusOK {
+		t.Errorf("Expected another user to pass but got status %d", w.Code)
+	}
+
+	// A personal access token is limited separately from its owner
+	if w := request(&auth.AuthenticatedUser{ID: "noisy", TokenID: "token-1"}); w.Code != http.StatusOK {
+		t.Errorf("Expected the user's token to have its own window but got status %d", w.Code)
+	}
+
+	// Exempt roles and tokens are never limited
+	for i := 0; i < 5; i++ {
+		if w := request(&auth.AuthenticatedUser{ID: "root", Roles: []string{"admin"}}); w.Code != http.StatusOK {
+			t.Fatalf("Expected an admin to be exempt but got status %d", w.Code)
+		}
+		if w := request(&auth.AuthenticatedUser{ID: "ci", TokenID: "ci-token"}); w.Code != http.StatusOK {
+			t.Fatalf("Expected an exempt token to pass but got status %d", w.Code)
+		}
+	}
+}
+
+func TestAllowRequestWindow(t *testing.T) {
+	cache := newMemoryCache()
+	opts := RateLimitOptions{RequestsPerSecond: 20, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if allowed, _, err := allowRequest(context.Background(), cache, "k", opts); err != nil || !allowed {
+			t.Fatalf("Expected request %d to pass but got allowed=%v err=%v", i+1, allowed, err)
+		}
+	}
+	allowed, retryAfter, err := allowRequest(context.Background(), cache, "k", opts)
+	if err != nil || allowed {
+		t.Fatalf("Expected the third request to be limited but got allowed=%v err=%v", allowed, err)
+	}
+	if retryAfter <= 0 || retryAfter > 100*time.Millisecond {
+		t.Errorf("Expected to retry within the 100ms window but got %s", retryAfter)
+	}
+
+	time.Sleep(retryAfter)
+	if allowed, _, _ := allowRequest(context.Background(), cache, "k", opts); !allowed {
+		t.Error("Expected a new window once the previous one ended")
+	}
+}
+
+func TestAllowRequestConcurrently(t *testing.T) {
+	cache := newMemoryCache()
+	opts := RateLimitOptions{RequestsPerSecond: 1, Burst: 5}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	passed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if allowed, _, _ := allowRequest(context.Background(), cache, "k", opts); allowed {
+				mu.Lock()
+				passed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if passed != 5 {
+		t.Errorf("Expected exactly 5 concurrent requests to pass but got %d", passed)
+	}
+}
+
+func TestIPRateLimitMiddleware(t *testing.T) {
+	limited := ipRateLimitMiddleware(newMemoryCache(), RateLimitOptions{
+		RequestsPerSecond: 0.1,
+		Burst:             3,
+	}, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/auth/login", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		limited.ServeHTTP(w, req)
+		return w
+	}
+
+	// A burst from one IP is cut off after the allowed requests
+	passed := 0
+	for i := 0; i < 10; i++ {
+		w := request("203.0.113.7:40000", "")
+		switch w.Code {
+		case http.StatusOK:
+			passed++
+		case http.StatusTooManyRequests:
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != "30" {
+				t.Fatalf("Expected Retry-After 30 but got %q", retryAfter)
+			}
+		default:
+			t.Fatalf("Unexpected status %d", w.Code)
+		}
+	}
+	if passed != 3 {
+		t.Errorf("Expected 3 requests to pass but got %d", passed)
+	}
+
+	// Changing the source port doesn't get a new window
+	if w := request("203.0.113.7:40001", ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d but got %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	// A forged X-Forwarded-For doesn't get a new window either
+	if w := request("203.0.113.7:40000", "198.51.100.9"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a spoofed header to be ignored but got status %d", w.Code)
+	}
+
+	// Other clients have their own window
+	if w := request("203.0.113.8:40000", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected another IP to pass but got status %d", w.Code)
+	}
+}
+
+func TestRouter_AuthRateLimitOnlyOnAuthRoutes(t *testing.T) {
+	cfg := &config.HubConfig{}
+	cfg.RateLimit.Auth = config.AuthRateLimitConfig{Enabled: true, Requests: 2, Window: time.Minute}
+
+	r := &Router{
+		authHandler:    NewAuthHandler(nil, nil, nil, zap.NewNop()),
+		cfg:            cfg,
+		logger:         zap.NewNop(),
+		rateLimitCache: newMemoryCache(),
+	}
+	router := chi.NewRouter()
+	r.registerAuthRoutes(router)
+	router.Get("/clusters", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader("{"))
+		req.RemoteAddr = "203.0.113.7:40000"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Malformed logins reach the handler until the IP is throttled
+	for i := 0; i < 2; i++ {
+		if w := request("POST", "/auth/login"); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected login %d to reach the handler but got status %d", i+1, w.Code)
+		}
+	}
+	w := request("POST", "/auth/login")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d but got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	// Routes outside /auth are not limited per IP
+	for i := 0; i < 5; i++ {
+		if w := request("GET", "/clusters"); w.Code != http.StatusOK {
+			t.Fatalf("Expected other routes to pass but got status %d", w.Code)
+		}
+	}
+}
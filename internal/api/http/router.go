@@ -14,19 +14,37 @@ import (
 	"github.com/rizesky/mckmt/internal/config"
 	"github.com/rizesky/mckmt/internal/metrics"
 	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/repo"
 )
 
+// defaultRequestTimeout applies to routes without a configured timeout
+const defaultRequestTimeout = 60 * time.Second
+
 // Router composes all handlers and sets up routes
 type Router struct {
 	clusterHandler   *ClusterHandler
 	operationHandler *OperationHandler
 	systemHandler    *SystemHandler
 	authHandler      *AuthHandler
+	adminHandler     *AdminHandler
 	logger           *zap.Logger
 	authMiddleware   *auth.Middleware
 	cfg              *config.HubConfig
 	metrics          *metrics.Metrics
 	authzService     *auth.AuthorizationService
+	auditRepo        repo.AuditLogRepository
+
+	// watchLimiter caps concurrent watch connections; wrap watch routes with its Middleware
+	watchLimiter *WatchLimiter
+
+	// adminAllowlist restricts the client networks reaching the admin API
+	adminAllowlist *auth.IPAllowlist
+
+	// trustedProxies are the reverse proxies whose forwarded client addresses are honoured
+	trustedProxies *auth.TrustedProxies
+
+	// rateLimitCache holds the per-client rate limit counters; nil disables rate limiting
+	rateLimitCache repo.Cache
 }
 
 // NewRouter creates a new router with all handlers
@@ -39,20 +57,45 @@ func NewRouter(
 	cfg *config.HubConfig,
 	metricsMgr *metrics.Metrics,
 	authzService *auth.AuthorizationService,
+	auditRepo repo.AuditLogRepository,
 ) *Router {
+	pagination := NewPagination(cfg.Server.DefaultPageSize, cfg.Server.MaxPageSize)
+	clusterHandler := NewClusterHandler(clusterService, logger)
+	clusterHandler.pagination = pagination
+	operationHandler := NewOperationHandler(operationService, logger)
+	operationHandler.pagination = pagination
+	adminHandler := NewAdminHandler(nil, logger)
+	adminHandler.pagination = pagination
+	if auditRepo != nil && cfg.Audit.Enabled && cfg.Audit.PermissionDenials {
+		authMiddleware.SetDecisionAudit(auditRepo, cfg.Audit.PermissionAllows)
+	}
+
 	return &Router{
-		clusterHandler:   NewClusterHandler(clusterService, logger),
-		operationHandler: NewOperationHandler(operationService, logger),
+		clusterHandler:   clusterHandler,
+		operationHandler: operationHandler,
 		systemHandler:    NewSystemHandler(logger),
-		authHandler:      NewAuthHandler(authService, logger),
+		authHandler:      NewAuthHandler(authService, authzService, auth.NewRedirectValidator(cfg.Auth.OIDC.AllowedRedirectOrigins), logger),
+		adminHandler:     adminHandler,
 		logger:           logger,
 		authMiddleware:   authMiddleware,
 		cfg:              cfg,
 		metrics:          metricsMgr,
 		authzService:     authzService,
+		auditRepo:        auditRepo,
+		watchLimiter:     NewWatchLimiter(cfg.Server.MaxWatchers, cfg.Server.MaxWatchersPerUser),
 	}
 }
 
+// SetOrchestrator sets the orchestrator inspected by the admin endpoints
+func (r *Router) SetOrchestrator(orchestrator OrchestratorAdmin) {
+	r.adminHandler.orchestrator = orchestrator
+}
+
+// SetDeadLetterQueue sets the dead-letter queue managed by the admin endpoints
+func (r *Router) SetDeadLetterQueue(deadLetters DeadLetterAdmin) {
+	r.adminHandler.deadLetters = deadLetters
+}
+
 // SetupRoutes configures all routes using Chi with proper grouping
 func (r *Router) SetupRoutes() chi.Router {
 	router := chi.NewRouter()
@@ -74,6 +117,8 @@ func (r *Router) SetupRoutes() chi.Router {
 		// Protected routes (require authentication)
 		api.Group(func(protected chi.Router) {
 			protected.Use(r.authMiddleware.RequireAuth)
+			protected.Use(r.rateLimitMiddleware)
+			protected.Use(r.auditMiddleware)
 			r.registerProtectedRoutes(protected)
 		})
 	})
@@ -82,19 +127,44 @@ func (r *Router) SetupRoutes() chi.Router {
 }
 
 // applyCommonMiddlewares applies common middleware to all routes
-func (r *Router) applyCommonMiddlewares(router chi.Router) {
+func (r *Router) applyCommonMiddlewares(router *chi.Mux) {
 	// Chi built-in middleware
 	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
+	router.Use(realIPMiddleware(r.trustedProxies))
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.Timeout(60 * time.Second))
+	router.Use(routeTimeoutMiddleware(router, r.routeTimeouts()))
 
 	// Custom middleware
 	router.Use(r.corsMiddleware)
 	router.Use(r.metricsMiddleware)
 }
 
+// routeTimeouts returns the configured per-route request timeouts
+func (r *Router) routeTimeouts() RouteTimeouts {
+	timeouts := RouteTimeouts{Default: defaultRequestTimeout, Routes: map[string]time.Duration{}}
+	// Streaming routes run for as long as the client reads
+	for _, route := range streamingRoutes {
+		timeouts.Routes[route] = 0
+	}
+	if r.cfg != nil {
+		if r.cfg.Server.RequestTimeout > 0 {
+			timeouts.Default = r.cfg.Server.RequestTimeout
+		}
+		for route, timeout := range r.cfg.Server.RouteTimeouts {
+			timeouts.Routes[route] = timeout
+		}
+	}
+	return timeouts
+}
+
+// streamingRoutes are the routes whose responses are streamed, unbounded by
+// the default request timeout unless one is configured for them
+var streamingRoutes = []string{
+	"/api/v1/clusters/{id}/logs",
+	"/api/v1/clusters/{id}/operations:export",
+}
+
 // registerSystemRoutes registers system routes that don't require authentication
 func (r *Router) registerSystemRoutes(router chi.Router) {
 	router.Route("/", func(system chi.Router) {
@@ -106,20 +176,19 @@ func (r *Router) registerSystemRoutes(router chi.Router) {
 // registerAuthRoutes registers authentication routes that don't require authentication
 func (r *Router) registerAuthRoutes(router chi.Router) {
 	router.Route("/auth", func(auth chi.Router) {
+		auth.Use(r.authRateLimitMiddleware)
+
 		// Auth methods discovery
 		auth.Get("/methods", r.authHandler.GetAuthMethods)
 
 		// OIDC routes
 		auth.Get("/oidc/login", r.authHandler.OIDCLogin)
 		auth.Get("/oidc/callback", r.authHandler.OIDCCallback)
-		auth.Post("/oidc/logout", r.authHandler.OIDCLogout)
 
 		// Custom auth routes
 		auth.Post("/login", r.authHandler.Login)
 		auth.Post("/register", r.authHandler.Register)
 		auth.Post("/refresh", r.authHandler.RefreshToken)
-		auth.Post("/logout", r.authHandler.Logout)
-		auth.Post("/change-password", r.authHandler.ChangePassword)
 	})
 }
 
@@ -127,22 +196,65 @@ func (r *Router) registerAuthRoutes(router chi.Router) {
 func (r *Router) registerProtectedRoutes(router chi.Router) {
 	// Protected auth routes (user profile)
 	router.Get("/auth/profile", r.authHandler.GetProfile)
+	router.Post("/auth/logout", r.authHandler.Logout)
+	router.Post("/auth/oidc/logout", r.authHandler.OIDCLogout)
+	router.Post("/auth/change-password", r.authHandler.ChangePassword)
+	router.Post("/auth/permissions:check", r.authHandler.CheckPermissions)
+	router.Post("/auth/tokens", r.authHandler.CreateAPIToken)
+	router.Get("/auth/tokens", r.authHandler.ListAPITokens)
+	router.Delete("/auth/tokens/{id}", r.authHandler.RevokeAPIToken)
 
 	// Cluster routes with Casbin permissions
 	router.Route("/clusters", func(clusters chi.Router) {
-		clusters.Get("/", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.ListClusters))
-		clusters.Get("/{id}", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.GetCluster))
-		clusters.Put("/{id}", r.authMiddleware.RequirePermission(r.authzService, "clusters", "write")(r.clusterHandler.UpdateCluster))
-		clusters.Delete("/{id}", r.authMiddleware.RequirePermission(r.authzService, "clusters", "delete")(r.clusterHandler.DeleteCluster))
-		clusters.Get("/{id}/resources", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.ListClusterResources))
-		clusters.Post("/{id}/manifests", r.authMiddleware.RequirePermission(r.authzService, "clusters", "manage")(r.clusterHandler.ApplyManifests))
+		clusters.With(fleetScope).Get("/", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.ListClusters))
+		clusters.With(fleetScope).Post("/", r.authMiddleware.RequirePermission(r.authzService, "clusters", "write")(r.clusterHandler.CreateCluster))
+
+		clusters.Route("/{id}", func(cluster chi.Router) {
+			cluster.Use(clusterScope("id"))
+			cluster.Get("/", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.GetCluster))
+			cluster.Put("/", r.authMiddleware.RequirePermission(r.authzService, "clusters", "write")(r.clusterHandler.UpdateCluster))
+			cluster.Delete("/", r.authMiddleware.RequirePermission(r.authzService, "clusters", "delete")(r.clusterHandler.DeleteCluster))
+			cluster.Get("/resources", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.ListClusterResources))
+			cluster.Get("/namespaces", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.ListNamespaces))
+			cluster.Get("/nodes", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.ListNodes))
+			cluster.Get("/diagnostics", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.GetClusterDiagnostics))
+			cluster.Get("/baseline", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.GetClusterBaseline))
+			cluster.Put("/baseline", r.authMiddleware.RequirePermission(r.authzService, "clusters", "write")(r.clusterHandler.SetClusterBaseline))
+			cluster.With(r.watchLimiter.Middleware).Get("/logs", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.StreamClusterLogs))
+			cluster.Get("/agent-logs", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.ListClusterLogs))
+			cluster.Post("/manifests", r.authMiddleware.RequirePermission(r.authzService, "clusters", "manage")(r.clusterHandler.ApplyManifests))
+			cluster.Get("/operations", r.authMiddleware.RequirePermission(r.authzService, "operations", "read")(r.clusterHandler.ListClusterOperations))
+			cluster.Get("/operations:export", r.authMiddleware.RequirePermission(r.authzService, "operations", "read")(r.operationHandler.ExportClusterOperations))
+			cluster.Post("/operations:cancelAll", r.authMiddleware.RequirePermission(r.authzService, "operations", "cancel")(r.clusterHandler.CancelAllOperations))
+		})
 	})
 
+	// Agent routes with Casbin permissions
+	router.With(fleetScope).Get("/agents", r.authMiddleware.RequirePermission(r.authzService, "clusters", "read")(r.clusterHandler.ListAgents))
+
 	// Operation routes with Casbin permissions
 	router.Route("/operations", func(operations chi.Router) {
-		operations.Get("/{id}", r.authMiddleware.RequirePermission(r.authzService, "operations", "read")(r.operationHandler.GetOperation))
-		operations.Get("/cluster/{clusterId}", r.authMiddleware.RequirePermission(r.authzService, "operations", "read")(r.operationHandler.ListOperationsByCluster))
-		operations.Post("/{id}/cancel", r.authMiddleware.RequirePermission(r.authzService, "operations", "cancel")(r.operationHandler.CancelOperation))
+		operations.With(clusterScope("clusterId")).Get("/cluster/{clusterId}", r.authMiddleware.RequirePermission(r.authzService, "operations", "read")(r.operationHandler.ListOperationsByCluster))
+
+		operations.Group(func(operation chi.Router) {
+			operation.Use(r.operationHandler.operationScope)
+			operation.Get("/{id}", r.authMiddleware.RequirePermission(r.authzService, "operations", "read")(r.operationHandler.GetOperation))
+			operation.Get("/{id}/output", r.authMiddleware.RequirePermission(r.authzService, "operations", "read")(r.operationHandler.GetOperationOutput))
+			operation.Post("/{id}/cancel", r.authMiddleware.RequirePermission(r.authzService, "operations", "cancel")(r.operationHandler.CancelOperation))
+		})
+	})
+
+	// Admin routes, for debugging the hub
+	router.Route("/admin", func(admin chi.Router) {
+		admin.Use(allowlistMiddleware(r.adminAllowlist, r.logger))
+		admin.Use(fleetScope)
+		admin.Get("/orchestrator", r.authMiddleware.RequirePermission(r.authzService, "system", "admin")(r.adminHandler.GetOrchestratorState))
+		admin.Post("/orchestrator/pause", r.authMiddleware.RequirePermission(r.authzService, "system", "admin")(r.adminHandler.PauseOrchestrator))
+		admin.Post("/orchestrator/resume", r.authMiddleware.RequirePermission(r.authzService, "system", "admin")(r.adminHandler.ResumeOrchestrator))
+		admin.Get("/dead-letters", r.authMiddleware.RequirePermission(r.authzService, "system", "admin")(r.adminHandler.ListDeadLetters))
+		admin.Post("/dead-letters/{id}/redrive", r.authMiddleware.RequirePermission(r.authzService, "system", "admin")(r.adminHandler.RedriveDeadLetter))
+		admin.Post("/clusters/{id}/quarantine", r.authMiddleware.RequirePermission(r.authzService, "system", "admin")(r.clusterHandler.QuarantineCluster))
+		admin.Delete("/clusters/{id}/quarantine", r.authMiddleware.RequirePermission(r.authzService, "system", "admin")(r.clusterHandler.ReleaseCluster))
 	})
 }
 
@@ -151,7 +263,8 @@ func (r *Router) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-CSRF-Token")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, If-None-Match, X-CSRF-Token")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
 		if req.Method == "OPTIONS" {
 			return
 		}
@@ -159,6 +272,19 @@ func (r *Router) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// auditMiddleware records mutating requests in the audit log when auditing is enabled
+func (r *Router) auditMiddleware(next http.Handler) http.Handler {
+	if r.auditRepo == nil || r.cfg == nil || !r.cfg.Audit.Enabled {
+		return next
+	}
+	return auditMiddleware(r.auditRepo, AuditOptions{
+		IncludeRequestPayload:  r.cfg.Audit.IncludeRequestPayload,
+		IncludeResponsePayload: r.cfg.Audit.IncludeResponsePayload,
+		MaxPayloadSize:         r.cfg.Audit.MaxPayloadSize,
+		ExcludeRoutes:          r.cfg.Audit.ExcludeRoutes,
+	}, r.logger)(next)
+}
+
 func (r *Router) metricsMiddleware(next http.Handler) http.Handler {
 	if r.metrics != nil {
 		return metrics.HTTPMiddlewareFactory(r.metrics, r.logger)(next)
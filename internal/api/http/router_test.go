@@ -0,0 +1,116 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/auth"
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/operation"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+// newTestRouter returns the routes of a router authenticating JWTs of
+// jwtManager. Services left nil must not be reached.
+func newTestRouter(jwtManager *auth.JWTManager, operationService *operation.Service, authzService *auth.AuthorizationService, cfg *config.HubConfig) http.Handler {
+	authService := auth.NewAuthService(nil, nil, nil, nil, jwtManager, auth.NewPasswordManager(nil), nil, nil, "viewer", zap.NewNop())
+	router := NewRouter(nil, operationService, authService, zap.NewNop(), auth.NewAuthMiddleware(jwtManager, zap.NewNop()), cfg, nil, authzService, nil)
+	return router.SetupRoutes()
+}
+
+func TestRouter_SessionRoutesRequireAuth(t *testing.T) {
+	jwtManager := auth.NewJWTManager("secret", time.Hour)
+	jwtManager.SetRevocationStore(newMemoryCache())
+	routes := newTestRouter(jwtManager, nil, nil, &config.HubConfig{})
+
+	token, err := jwtManager.GenerateToken(uuid.New().String(), "alice", "alice@example.com", []string{"viewer"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	request := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader("{}"))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		routes.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/api/v1/auth/logout", "/api/v1/auth/change-password", "/api/v1/auth/oidc/logout"} {
+		if w := request(path, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d for %s without a token but got %d", http.StatusUnauthorized, path, w.Code)
+		}
+	}
+
+	// The authenticated user reaches the handler, which validates the body
+	if w := request("/api/v1/auth/change-password", token); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d but got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	if w := request("/api/v1/auth/logout", token); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := request("/api/v1/auth/logout", token); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked token to be rejected but got status %d", w.Code)
+	}
+}
+
+func TestRouter_ExportOutlivesRequestTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	clusterID := uuid.New()
+	operations := exportTestOperations(clusterID)
+
+	// Each operation takes longer to read than the whole request may
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockOpRepo.EXPECT().EachByCluster(gomock.Any(), clusterID, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ uuid.UUID, fn func(*repo.Operation) error) error {
+			for _, op := range operations {
+				time.Sleep(30 * time.Millisecond)
+				if err := fn(op); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	operationService := operation.NewService(mockOpRepo, nil, nil, repomocks.NewMockCache(ctrl), zap.NewNop(), nil)
+
+	strategy := &staticStrategy{granted: map[string]bool{"operations:read": true}}
+	cfg := &config.HubConfig{}
+	cfg.Server.RequestTimeout = 20 * time.Millisecond
+
+	jwtManager := auth.NewJWTManager("secret", time.Hour)
+	routes := newTestRouter(jwtManager, operationService, auth.NewAuthorizationService(strategy, zap.NewNop()), cfg)
+	token, err := jwtManager.GenerateToken(uuid.New().String(), "alice", "alice@example.com", []string{"viewer"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/clusters/"+clusterID.String()+"/operations:export?format=ndjson", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	routes.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d but got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	lines := 0
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		lines++
+	}
+	if lines != len(operations) {
+		t.Errorf("Expected %d exported operations but got %d", len(operations), lines)
+	}
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+// Personal access tokens may be limited to some clusters. Routes addressing a
+// cluster check it against the token, routes addressing an operation check the
+// operation's cluster, and routes spanning the whole fleet are closed to
+// cluster-scoped tokens altogether.
+
+// clusterScope rejects requests for a cluster outside the caller's token
+// scope; param names the URL parameter holding the cluster ID
+func clusterScope(param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, ok := auth.GetUserFromContext(r.Context()); ok && !user.InClusterScope(chi.URLParam(r, param)) {
+				WriteErrorResponse(w, http.StatusForbidden, "Token is not scoped to this cluster")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// fleetScope rejects cluster-scoped tokens on routes that span every cluster
+func fleetScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := auth.GetUserFromContext(r.Context()); ok && user.ClusterScoped() {
+			WriteErrorResponse(w, http.StatusForbidden, "Token is scoped to specific clusters")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// operationScope rejects requests for an operation of a cluster outside the
+// caller's token scope. Operations that can't be loaded are left to the
+// handler, which reports them missing.
+func (h *OperationHandler) operationScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok || !user.ClusterScoped() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		operation, err := h.operationService.GetOperation(r.Context(), id)
+		if err == nil && !user.InClusterScope(operation.ClusterID.String()) {
+			WriteErrorResponse(w, http.StatusForbidden, "Token is not scoped to this cluster")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
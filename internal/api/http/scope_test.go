@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+// scopeTestRouter serves the given routes for the given user, answering 200
+// when a request gets past the scope checks
+func scopeTestRouter(user *auth.AuthenticatedUser) chi.Router {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.UserContextKey, user)))
+		})
+	})
+	router.With(fleetScope).Get("/clusters", ok)
+	router.Route("/clusters/{id}", func(cluster chi.Router) {
+		cluster.Use(clusterScope("id"))
+		cluster.Get("/", ok)
+		cluster.Post("/manifests", ok)
+	})
+	return router
+}
+
+func TestClusterScope(t *testing.T) {
+	scoped, other := uuid.New(), uuid.New()
+	ciToken := &auth.AuthenticatedUser{ID: uuid.New().String(), TokenID: uuid.New().String(), Scopes: []string{"*"}, Clusters: []string{scoped.String()}}
+	unscopedToken := &auth.AuthenticatedUser{ID: uuid.New().String(), TokenID: uuid.New().String(), Scopes: []string{"*"}}
+
+	tests := []struct {
+		name           string
+		user           *auth.AuthenticatedUser
+		method, path   string
+		expectedStatus int
+	}{
+		{name: "apply to the scoped cluster", user: ciToken, method: "POST", path: "/clusters/" + scoped.String() + "/manifests", expectedStatus: http.StatusOK},
+		{name: "apply to another cluster", user: ciToken, method: "POST", path: "/clusters/" + other.String() + "/manifests", expectedStatus: http.StatusForbidden},
+		{name: "get another cluster", user: ciToken, method: "GET", path: "/clusters/" + other.String(), expectedStatus: http.StatusForbidden},
+		{name: "list the fleet", user: ciToken, method: "GET", path: "/clusters", expectedStatus: http.StatusForbidden},
+		{name: "token without cluster scope", user: unscopedToken, method: "POST", path: "/clusters/" + other.String() + "/manifests", expectedStatus: http.StatusOK},
+		{name: "token without cluster scope lists the fleet", user: unscopedToken, method: "GET", path: "/clusters", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			scopeTestRouter(tt.user).ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d but got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
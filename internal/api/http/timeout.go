@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// timeoutResponseBody is returned with a 503 when a request exceeds its route timeout
+const timeoutResponseBody = `{"error":"Request timed out","status":503}`
+
+// RouteTimeouts holds the request timeout for each route pattern, e.g.
+// "/api/v1/clusters/{id}/resources", and the default for all other routes.
+// A zero timeout disables the limit for that route.
+type RouteTimeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// For returns the timeout for a route pattern. Patterns match case-insensitively
+// because configuration keys are lower-cased when loaded.
+func (t RouteTimeouts) For(pattern string) time.Duration {
+	if timeout, ok := t.Routes[pattern]; ok {
+		return timeout
+	}
+	for route, timeout := range t.Routes {
+		if strings.EqualFold(route, pattern) {
+			return timeout
+		}
+	}
+	return t.Default
+}
+
+// routeTimeoutMiddleware bounds each request by the timeout of the route it
+// resolves to on mux. The request context is cancelled at the deadline, so
+// handlers watching it stop early; requests that run over get a 503 unless
+// their response was already started. Handlers run on the request goroutine,
+// as they must not outlive the request and its pooled chi context.
+func routeTimeoutMiddleware(mux *chi.Mux, timeouts RouteTimeouts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// Route patterns are only known once chi has routed the request, so
+			// resolve it up front on a scratch routing context
+			pattern := mux.Find(chi.NewRouteContext(), req.Method, req.URL.Path)
+
+			timeout := timeouts.For(pattern)
+			if timeout <= 0 {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
+			next.ServeHTTP(tw, req.WithContext(ctx))
+			if tw.timedOut || (!tw.wroteHeader && tw.expired()) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(timeoutResponseBody))
+			}
+		})
+	}
+}
+
+// timeoutWriter discards responses started after the request deadline,
+// leaving the middleware to answer with a 503 instead
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	wroteHeader bool
+	timedOut    bool
+}
+
+// expired reports whether the request ran past its deadline
+func (tw *timeoutWriter) expired() bool {
+	return errors.Is(tw.ctx.Err(), context.DeadlineExceeded)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	if tw.wroteHeader || tw.timedOut {
+		return
+	}
+	if tw.expired() {
+		tw.timedOut = true
+		return
+	}
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
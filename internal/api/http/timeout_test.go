@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRouteTimeoutMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(routeTimeoutMiddleware(router, RouteTimeouts{
+		Default: time.Second,
+		Routes: map[string]time.Duration{
+			"/clusters/{id}/resources": 20 * time.Millisecond,
+			"/clusters/{id}/nodes":     20 * time.Millisecond,
+			"/watch":                   0,
+		},
+	}))
+
+	slow := func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+			WriteJSONResponse(w, http.StatusOK, map[string]string{"status": "done"})
+		}
+	}
+	// Handlers reporting the cancellation themselves still answer with a 503
+	cancelled := func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+		WriteErrorResponse(w, http.StatusInternalServerError, req.Context().Err().Error())
+	}
+	router.Route("/clusters", func(clusters chi.Router) {
+		clusters.Get("/{id}/resources", slow)
+		clusters.Get("/{id}/nodes", cancelled)
+		clusters.Get("/{id}", slow)
+	})
+	router.Get("/watch", slow)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "route exceeding its timeout", path: "/clusters/abc/resources", expectedStatus: http.StatusServiceUnavailable},
+		{name: "handler writing after its timeout", path: "/clusters/abc/nodes", expectedStatus: http.StatusServiceUnavailable},
+		{name: "route within the default timeout", path: "/clusters/abc", expectedStatus: http.StatusOK},
+		{name: "route with timeout disabled", path: "/watch", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouteTimeouts_For(t *testing.T) {
+	timeouts := RouteTimeouts{
+		Default: time.Minute,
+		Routes:  map[string]time.Duration{"/api/v1/operations/cluster/{clusterid}": 5 * time.Second},
+	}
+
+	if got := timeouts.For("/api/v1/operations/cluster/{clusterId}"); got != 5*time.Second {
+		t.Errorf("Expected lower-cased config key to match, got %v", got)
+	}
+	if got := timeouts.For("/api/v1/clusters"); got != time.Minute {
+		t.Errorf("Expected default timeout, got %v", got)
+	}
+}
@@ -18,6 +18,16 @@ type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
+// PageDTO is the pagination envelope included in every list response. Limit
+// is the effective page size, after the server's default and maximum apply.
+type PageDTO struct {
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	TotalCount int  `json:"total_count"`
+	HasMore    bool `json:"has_more"`
+	MaxLimit   int  `json:"max_limit"`
+}
+
 // ClusterDTO represents a cluster in HTTP responses
 type ClusterDTO struct {
 	ID          string            `json:"id"`
@@ -29,6 +39,19 @@ type ClusterDTO struct {
 	LastSeen    *time.Time        `json:"last_seen,omitempty"`
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
+	// Quarantined clusters accept no new operations
+	Quarantined      bool       `json:"quarantined"`
+	QuarantinedAt    *time.Time `json:"quarantined_at,omitempty"`
+	QuarantineReason string     `json:"quarantine_reason,omitempty"`
+}
+
+// AgentDTO represents the agent of a cluster in HTTP responses
+type AgentDTO struct {
+	ClusterID    string     `json:"cluster_id"`
+	ClusterName  string     `json:"cluster_name"`
+	AgentVersion string     `json:"agent_version"`
+	Status       string     `json:"status"`
+	LastSeen     *time.Time `json:"last_seen,omitempty"`
 }
 
 // OperationDTO represents an operation in HTTP responses
@@ -41,10 +64,74 @@ type OperationDTO struct {
 	Parameters  map[string]interface{} `json:"parameters"`
 	Result      map[string]interface{} `json:"result,omitempty"`
 	Error       string                 `json:"error,omitempty"`
-	StartedAt   *time.Time             `json:"started_at,omitempty"`
-	FinishedAt  *time.Time             `json:"finished_at,omitempty"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
+	// InitiatedVia is the channel the operation was requested through and
+	// Reason the requester's explanation for it
+	InitiatedVia string     `json:"initiated_via,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// OperationDetailDTO represents an operation together with its state transition history
+type OperationDetailDTO struct {
+	*repo.Operation
+	Events []*repo.OperationEvent `json:"events"`
+}
+
+// OperationOutputDTO represents a chunk of an operation's output
+type OperationOutputDTO struct {
+	OperationID string `json:"operation_id"`
+	Offset      int64  `json:"offset"`
+	NextOffset  int64  `json:"next_offset"`
+	Size        int64  `json:"size"`
+	Data        string `json:"data"`
+	Complete    bool   `json:"complete"`
+}
+
+// OperationExportDTO represents an operation in a cluster's history export
+type OperationExportDTO struct {
+	ID            string `json:"id"`
+	ClusterID     string `json:"cluster_id"`
+	Type          string `json:"type"`
+	Status        string `json:"status"`
+	CreatedBy     string `json:"created_by"`
+	CreatedAt     string `json:"created_at"`
+	StartedAt     string `json:"started_at"`
+	FinishedAt    string `json:"finished_at"`
+	ResultSummary string `json:"result_summary"`
+	ManifestHash  string `json:"manifest_hash"`
+	InitiatedVia  string `json:"initiated_via"`
+	Reason        string `json:"reason"`
+}
+
+// OrchestratorStateDTO represents the orchestrator's internal state
+type OrchestratorStateDTO struct {
+	QueueDepth        int      `json:"queue_depth"`
+	QueueCapacity     int      `json:"queue_capacity"`
+	Workers           int      `json:"workers"`
+	RunningOperations []string `json:"running_operations"`
+	CancelQueueDepth  int      `json:"cancel_queue_depth"`
+	Paused            bool     `json:"paused"`
+	HeldOperations    int      `json:"held_operations"`
+}
+
+// SetClusterBaselineRequest represents the desired inventory of a cluster
+type SetClusterBaselineRequest struct {
+	Resources []repo.InventoryEntry `json:"resources"`
+}
+
+// CreateClusterRequest represents a cluster to create ahead of its agent registering
+type CreateClusterRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// QuarantineRequest represents the reason a cluster is quarantined
+type QuarantineRequest struct {
+	Reason string `json:"reason"`
 }
 
 // UserDTO represents a user in HTTP responses
@@ -73,6 +160,10 @@ func ToClusterDTO(cluster *repo.Cluster) *ClusterDTO {
 		LastSeen:    cluster.LastSeenAt,
 		CreatedAt:   cluster.CreatedAt,
 		UpdatedAt:   cluster.UpdatedAt,
+
+		Quarantined:      cluster.Quarantined(),
+		QuarantinedAt:    cluster.QuarantinedAt,
+		QuarantineReason: cluster.QuarantineReason,
 	}
 }
 
@@ -93,18 +184,20 @@ func ToOperationDTO(operation *repo.Operation) *OperationDTO {
 	}
 
 	return &OperationDTO{
-		ID:          operation.ID.String(),
-		ClusterID:   operation.ClusterID.String(),
-		Type:        operation.Type,
-		Status:      operation.Status,
-		Description: "", // Operation doesn't have description field
-		Parameters:  map[string]interface{}(operation.Payload),
-		Result:      result,
-		Error:       "", // Operation doesn't have error field
-		StartedAt:   operation.StartedAt,
-		FinishedAt:  operation.FinishedAt,
-		CreatedAt:   operation.CreatedAt,
-		UpdatedAt:   operation.UpdatedAt,
+		ID:           operation.ID.String(),
+		ClusterID:    operation.ClusterID.String(),
+		Type:         operation.Type,
+		Status:       operation.Status,
+		Description:  "", // Operation doesn't have description field
+		Parameters:   map[string]interface{}(operation.Payload),
+		Result:       result,
+		Error:        "", // Operation doesn't have error field
+		InitiatedVia: operation.InitiatedVia,
+		Reason:       operation.Reason,
+		StartedAt:    operation.StartedAt,
+		FinishedAt:   operation.FinishedAt,
+		CreatedAt:    operation.CreatedAt,
+		UpdatedAt:    operation.UpdatedAt,
 	}
 }
 
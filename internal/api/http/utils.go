@@ -3,6 +3,7 @@ package http
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
 // WriteJSONResponse writes a JSON response with the given status code and data
@@ -19,3 +20,13 @@ func WriteErrorResponse(w http.ResponseWriter, status int, message string) {
 		"status": status,
 	})
 }
+
+// clientIP returns the client IP address of the request. realIPMiddleware
+// has already replaced the address of a trusted proxy with the client's.
+func clientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if colon := strings.LastIndex(ip, ":"); colon != -1 {
+		ip = ip[:colon]
+	}
+	return strings.Trim(ip, "[]")
+}
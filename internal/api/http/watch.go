@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+// WatchLimiter caps the number of concurrent long-lived watch connections,
+// such as WebSocket or SSE streams, globally and per user. A zero limit
+// disables the corresponding cap.
+type WatchLimiter struct {
+	mu         sync.Mutex
+	maxTotal   int
+	maxPerUser int
+	total      int
+	perUser    map[string]int
+}
+
+// NewWatchLimiter creates a new watch limiter
+func NewWatchLimiter(maxTotal, maxPerUser int) *WatchLimiter {
+	return &WatchLimiter{
+		maxTotal:   maxTotal,
+		maxPerUser: maxPerUser,
+		perUser:    make(map[string]int),
+	}
+}
+
+// Acquire reserves a watch slot for user, reporting false when a cap is reached
+func (l *WatchLimiter) Acquire(user string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxTotal > 0 && l.total >= l.maxTotal {
+		return false
+	}
+	if l.maxPerUser > 0 && l.perUser[user] >= l.maxPerUser {
+		return false
+	}
+
+	l.total++
+	l.perUser[user]++
+	return true
+}
+
+// Release frees a slot reserved by Acquire
+func (l *WatchLimiter) Release(user string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total--
+	if l.perUser[user]--; l.perUser[user] <= 0 {
+		delete(l.perUser, user)
+	}
+}
+
+// Active returns the number of open watch connections
+func (l *WatchLimiter) Active() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total
+}
+
+// Middleware limits the watch handlers it wraps. Connections over the cap get
+// a 429; a slot is freed as soon as the handler returns, which happens when the
+// client disconnects and the request context is cancelled.
+func (l *WatchLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := "anonymous"
+		if authUser, ok := auth.GetUserFromContext(r.Context()); ok {
+			user = authUser.ID
+		}
+
+		if !l.Acquire(user) {
+			WriteErrorResponse(w, http.StatusTooManyRequests, "Too many concurrent watch connections")
+			return
+		}
+		defer l.Release(user)
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rizesky/mckmt/internal/auth"
+)
+
+func TestWatchLimiter_EnforcesCapAndFreesSlots(t *testing.T) {
+	limiter := NewWatchLimiter(3, 2)
+
+	// Watch handlers block until their client disconnects
+	started := make(chan struct{}, 10)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+
+	type watch struct {
+		cancel context.CancelFunc
+		done   chan *httptest.ResponseRecorder
+	}
+	open := func(userID string) watch {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		ctx = context.WithValue(ctx, auth.UserContextKey, &auth.AuthenticatedUser{ID: userID})
+		req := httptest.NewRequest("GET", "/watch", nil).WithContext(ctx)
+
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			done <- w
+		}()
+		return watch{cancel: cancel, done: done}
+	}
+	expectRejected := func(userID string) {
+		t.Helper()
+		w := open(userID)
+		defer w.cancel()
+		select {
+		case rec := <-w.done:
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("Expected status %d but got %d", http.StatusTooManyRequests, rec.Code)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the watch to be rejected")
+		}
+	}
+	expectAccepted := func(userID string) watch {
+		t.Helper()
+		w := open(userID)
+		select {
+		case <-started:
+		case rec := <-w.done:
+			t.Fatalf("Expected the watch to be accepted but got status %d", rec.Code)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the watch to start")
+		}
+		return w
+	}
+
+	alice1 := expectAccepted("alice")
+	expectAccepted("alice")
+	// Per-user cap
+	expectRejected("alice")
+
+	expectAccepted("bob")
+	// Global cap
+	expectRejected("carol")
+
+	if active := limiter.Active(); active != 3 {
+		t.Fatalf("Expected 3 active watches but got %d", active)
+	}
+
+	// Disconnecting frees the slot for new watches
+	alice1.cancel()
+	<-alice1.done
+	if active := limiter.Active(); active != 2 {
+		t.Fatalf("Expected 2 active watches after a disconnect but got %d", active)
+	}
+	expectAccepted("alice")
+}
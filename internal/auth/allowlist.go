@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IPAllowlist restricts access to clients whose IP is in one of a set of
+// CIDRs. An empty allowlist allows every client.
+type IPAllowlist struct {
+	networks []*net.IPNet
+}
+
+// NewIPAllowlist parses the allowed CIDRs. Bare IPs are accepted as
+// single-address networks.
+func NewIPAllowlist(cidrs []string) (*IPAllowlist, error) {
+	networks, err := parseNetworks(cidrs, "allowlist")
+	if err != nil {
+		return nil, err
+	}
+	return &IPAllowlist{networks: networks}, nil
+}
+
+// parseNetworks parses CIDRs and bare IPs, naming the list they come from in
+// errors
+func parseNetworks(cidrs []string, list string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid %s entry %q", list, cidr)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			cidr = fmt.Sprintf("%s/%d", cidr, bits)
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", list, cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// containsIP reports whether ip is in one of networks
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled reports whether the allowlist restricts any client
+func (l *IPAllowlist) Enabled() bool {
+	return l != nil && len(l.networks) > 0
+}
+
+// Allows reports whether a client IP may access the restricted endpoints.
+// Unparseable IPs are only allowed when the allowlist is disabled.
+func (l *IPAllowlist) Allows(clientIP string) bool {
+	if !l.Enabled() {
+		return true
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	return containsIP(l.networks, ip)
+}
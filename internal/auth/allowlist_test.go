@@ -0,0 +1,51 @@
+package auth
+
+import "testing"
+
+func TestIPAllowlist_Allows(t *testing.T) {
+	allowlist, err := NewIPAllowlist([]string{"10.0.0.0/8", "192.168.1.5", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{"10.1.2.3", true},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"fd12::1", true},
+		{"203.0.113.7", false},
+		{"not-an-ip", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := allowlist.Allows(tt.ip); got != tt.expected {
+			t.Errorf("Expected Allows(%q) to be %v but got %v", tt.ip, tt.expected, got)
+		}
+	}
+}
+
+func TestIPAllowlist_EmptyAllowsAll(t *testing.T) {
+	allowlist, err := NewIPAllowlist(nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if allowlist.Enabled() {
+		t.Errorf("Expected an empty allowlist to be disabled")
+	}
+	if !allowlist.Allows("203.0.113.7") {
+		t.Errorf("Expected an empty allowlist to allow every client")
+	}
+}
+
+func TestNewIPAllowlist_InvalidEntry(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "example.com"} {
+		if _, err := NewIPAllowlist([]string{entry}); err == nil {
+			t.Errorf("Expected an error for %q", entry)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/user"
+)
+
+// APITokenPrefix starts every personal access token, telling them apart from
+// JWTs in the Authorization header
+const APITokenPrefix = "mckmt_pat_"
+
+// ScopeAll grants a token every permission its owner has
+const ScopeAll = "*"
+
+// Personal access token errors
+var (
+	ErrAPITokensDisabled = errors.New("personal access tokens are not enabled")
+	ErrInvalidAPIToken   = errors.New("invalid personal access token")
+	ErrAPITokenNotFound  = errors.New("personal access token not found")
+)
+
+// CreateAPITokenRequest represents a personal access token request
+type CreateAPITokenRequest struct {
+	Name string `json:"name" validate:"required"`
+	// Scopes are "resource:action" permissions, "resource:*" for every action
+	// on a resource, or "*" for all of the owner's permissions
+	Scopes []string `json:"scopes" validate:"required"`
+	// ClusterIDs restricts the token to the given clusters; empty means all
+	ClusterIDs []uuid.UUID `json:"cluster_ids,omitempty"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
+}
+
+// CreateAPITokenResponse carries a newly created token. Token is only ever
+// returned here; the hub keeps just its digest.
+type CreateAPITokenResponse struct {
+	*user.APIToken
+	Token string `json:"token"`
+}
+
+// Validate validates the personal access token request
+func (r *CreateAPITokenRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(r.Name) > 255 {
+		return fmt.Errorf("name must be at most 255 characters")
+	}
+	if len(r.Scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range r.Scopes {
+		if !validScope(scope) {
+			return fmt.Errorf("invalid scope %q, expected resource:action, resource:* or *", scope)
+		}
+	}
+	for _, clusterID := range r.ClusterIDs {
+		if clusterID == uuid.Nil {
+			return fmt.Errorf("invalid cluster ID %q", clusterID)
+		}
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now().UTC()) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	return nil
+}
+
+// validScope reports whether scope is "*" or a "resource:action" pair
+func validScope(scope string) bool {
+	if scope == ScopeAll {
+		return true
+	}
+	resource, action, ok := strings.Cut(scope, ":")
+	return ok && resource != "" && action != "" && !strings.Contains(action, ":")
+}
+
+// Permissions returns the explicit "resource:action" scopes of the request.
+// Wildcard scopes grant nothing of their own and are left out.
+func (r *CreateAPITokenRequest) Permissions() []Permission {
+	var permissions []Permission
+	for _, scope := range r.Scopes {
+		resource, action, ok := strings.Cut(scope, ":")
+		if ok && action != "*" {
+			permissions = append(permissions, NewPermission(resource, action))
+		}
+	}
+	return permissions
+}
+
+// scopeAllows reports whether any of the scopes covers resource and action
+func scopeAllows(scopes []string, resource, action string) bool {
+	for _, scope := range scopes {
+		if scope == ScopeAll || scope == resource+":"+action || scope == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAPITokenRepository enables personal access tokens, stored in tokenRepo
+func (s *Service) SetAPITokenRepository(tokenRepo repo.APITokenRepository) {
+	s.apiTokenRepo = tokenRepo
+}
+
+// CreateAPIToken issues a personal access token for a user
+func (s *Service) CreateAPIToken(ctx context.Context, userID string, req *CreateAPITokenRequest, ipAddress, userAgent string) (*CreateAPITokenResponse, error) {
+	if s.apiTokenRepo == nil {
+		return nil, ErrAPITokensDisabled
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := APITokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	apiToken := &user.APIToken{
+		ID:         uuid.New(),
+		UserID:     userUUID,
+		Name:       strings.TrimSpace(req.Name),
+		TokenHash:  hashAPIToken(token),
+		Scopes:     req.Scopes,
+		ClusterIDs: req.ClusterIDs,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if err := s.apiTokenRepo.Create(ctx, apiToken); err != nil {
+		return nil, fmt.Errorf("failed to create token: %w", err)
+	}
+
+	s.logAuditEvent(ctx, userID, "create_api_token", "api_token", apiToken.ID.String(), nil, nil, ipAddress, userAgent)
+
+	return &CreateAPITokenResponse{APIToken: apiToken, Token: token}, nil
+}
+
+// ListAPITokens lists a user's personal access tokens, newest first
+func (s *Service) ListAPITokens(ctx context.Context, userID string) ([]*user.APIToken, error) {
+	if s.apiTokenRepo == nil {
+		return nil, ErrAPITokensDisabled
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	return s.apiTokenRepo.ListByUser(ctx, userUUID)
+}
+
+// RevokeAPIToken revokes one of a user's personal access tokens. Revoked
+// tokens are kept so their use can still be traced in the audit log.
+func (s *Service) RevokeAPIToken(ctx context.Context, userID string, tokenID uuid.UUID, ipAddress, userAgent string) error {
+	if s.apiTokenRepo == nil {
+		return ErrAPITokensDisabled
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	if err := s.apiTokenRepo.Revoke(ctx, userUUID, tokenID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return ErrAPITokenNotFound
+		}
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	s.logAuditEvent(ctx, userID, "revoke_api_token", "api_token", tokenID.String(), nil, nil, ipAddress, userAgent)
+	return nil
+}
+
+// AuthenticateAPIToken resolves a personal access token to the user it acts
+// for, limited to the token's scopes
+func (s *Service) AuthenticateAPIToken(ctx context.Context, token string) (*AuthenticatedUser, error) {
+	if s.apiTokenRepo == nil {
+		return nil, ErrAPITokensDisabled
+	}
+
+	apiToken, err := s.apiTokenRepo.GetByHash(ctx, hashAPIToken(token))
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	if !apiToken.Usable(time.Now().UTC()) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	owner, err := s.userRepo.GetByID(ctx, apiToken.UserID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !owner.Active {
+		return nil, ErrInvalidAPIToken
+	}
+
+	if err := s.apiTokenRepo.UpdateLastUsed(ctx, apiToken.ID); err != nil {
+		s.logger.Warn("Failed to record token use", zap.String("token_id", apiToken.ID.String()), zap.Error(err))
+	}
+
+	clusters := make([]string, len(apiToken.ClusterIDs))
+	for i, clusterID := range apiToken.ClusterIDs {
+		clusters[i] = clusterID.String()
+	}
+
+	return &AuthenticatedUser{
+		ID:       owner.ID.String(),
+		Username: owner.Username,
+		Email:    owner.Email,
+		Roles:    rolesToStrings(owner.Roles),
+		TokenID:  apiToken.ID.String(),
+		Scopes:   apiToken.Scopes,
+		Clusters: clusters,
+	}, nil
+}
+
+// hashAPIToken digests a token for storage. Tokens carry 256 random bits, so
+// an unsalted SHA-256 is enough and lets a token be looked up by its digest.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+	"github.com/rizesky/mckmt/internal/user"
+)
+
+func newTokenTestService(ctrl *gomock.Controller) (*Service, *repomocks.MockUserRepository, *repomocks.MockAPITokenRepository) {
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	tokenRepo := repomocks.NewMockAPITokenRepository(ctrl)
+	service := NewAuthService(userRepo, nil, nil, nil, NewJWTManager("secret", time.Hour), NewPasswordManager(nil), nil, nil, "viewer", zap.NewNop())
+	service.SetAPITokenRepository(tokenRepo)
+	return service, userRepo, tokenRepo
+}
+
+func TestService_CreateAPIToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, _, tokenRepo := newTokenTestService(ctrl)
+	userID := uuid.New()
+
+	var stored *user.APIToken
+	tokenRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, token *user.APIToken) error {
+		stored = token
+		return nil
+	})
+
+	req := &CreateAPITokenRequest{Name: "ci", Scopes: []string{"clusters:read"}}
+	response, err := service.CreateAPIToken(context.Background(), userID.String(), req, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if !strings.HasPrefix(response.Token, APITokenPrefix) {
+		t.Errorf("Expected token to start with %q but got %q", APITokenPrefix, response.Token)
+	}
+	if stored.UserID != userID || stored.Name != "ci" {
+		t.Errorf("Expected token for user %s named ci but got %+v", userID, stored)
+	}
+	if stored.TokenHash == "" || strings.Contains(stored.TokenHash, response.Token) {
+		t.Errorf("Expected only a digest of the token to be stored but got %q", stored.TokenHash)
+	}
+	if stored.TokenHash != hashAPIToken(response.Token) {
+		t.Errorf("Expected stored digest to match the issued token")
+	}
+}
+
+func TestService_AuthenticateAPIToken(t *testing.T) {
+	const token = APITokenPrefix + "secret"
+	past := time.Now().UTC().Add(-time.Hour)
+	future := time.Now().UTC().Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		token       *user.APIToken
+		lookupErr   error
+		owner       *user.User
+		expectedErr error
+	}{
+		{
+			name:  "valid token",
+			token: &user.APIToken{Scopes: []string{"clusters:read"}, ExpiresAt: &future},
+			owner: &user.User{Username: "ci", Active: true, Roles: []*user.Role{{Name: "operator"}}},
+		},
+		{
+			name:        "unknown token",
+			lookupErr:   repo.ErrNotFound,
+			expectedErr: ErrInvalidAPIToken,
+		},
+		{
+			name:        "revoked token",
+			token:       &user.APIToken{Scopes: []string{"*"}, RevokedAt: &past},
+			expectedErr: ErrInvalidAPIToken,
+		},
+		{
+			name:        "expired token",
+			token:       &user.APIToken{Scopes: []string{"*"}, ExpiresAt: &past},
+			expectedErr: ErrInvalidAPIToken,
+		},
+		{
+			name:        "inactive owner",
+			token:       &user.APIToken{Scopes: []string{"*"}},
+			owner:       &user.User{Username: "ci", Active: false},
+			expectedErr: ErrInvalidAPIToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			service, userRepo, tokenRepo := newTokenTestService(ctrl)
+			if tt.token != nil {
+				tt.token.ID = uuid.New()
+				tt.token.UserID = uuid.New()
+			}
+			tokenRepo.EXPECT().GetByHash(gomock.Any(), hashAPIToken(token)).Return(tt.token, tt.lookupErr)
+			if tt.owner != nil {
+				tt.owner.ID = tt.token.UserID
+				userRepo.EXPECT().GetByID(gomock.Any(), tt.token.UserID).Return(tt.owner, nil)
+			}
+			if tt.expectedErr == nil {
+				tokenRepo.EXPECT().UpdateLastUsed(gomock.Any(), tt.token.ID).Return(nil)
+			}
+
+			authUser, err := service.AuthenticateAPIToken(context.Background(), token)
+			if err != tt.expectedErr {
+				t.Fatalf("Expected error %v but got: %v", tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if authUser.ID != tt.owner.ID.String() || authUser.TokenID != tt.token.ID.String() {
+				t.Errorf("Expected token %s of user %s but got %+v", tt.token.ID, tt.owner.ID, authUser)
+			}
+			if !authUser.HasRole("operator") {
+				t.Errorf("Expected the owner's roles but got %v", authUser.Roles)
+			}
+			if !authUser.ViaAPIToken() {
+				t.Errorf("Expected user to be authenticated via a personal access token")
+			}
+		})
+	}
+}
+
+func TestService_RevokeAPIToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, _, tokenRepo := newTokenTestService(ctrl)
+	userID, tokenID, otherID := uuid.New(), uuid.New(), uuid.New()
+
+	tokenRepo.EXPECT().Revoke(gomock.Any(), userID, tokenID).Return(nil)
+	tokenRepo.EXPECT().Revoke(gomock.Any(), userID, otherID).Return(repo.ErrNotFound)
+
+	if err := service.RevokeAPIToken(context.Background(), userID.String(), tokenID, "", ""); err != nil {
+		t.Errorf("Expected no error but got: %v", err)
+	}
+	if err := service.RevokeAPIToken(context.Background(), userID.String(), otherID, "", ""); err != ErrAPITokenNotFound {
+		t.Errorf("Expected ErrAPITokenNotFound but got: %v", err)
+	}
+}
+
+func TestCreateAPITokenRequest_Validate(t *testing.T) {
+	past := time.Now().UTC().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		req     CreateAPITokenRequest
+		wantErr bool
+	}{
+		{name: "valid", req: CreateAPITokenRequest{Name: "ci", Scopes: []string{"clusters:read", "operations:*"}}},
+		{name: "all permissions", req: CreateAPITokenRequest{Name: "ci", Scopes: []string{"*"}}},
+		{name: "missing name", req: CreateAPITokenRequest{Scopes: []string{"*"}}, wantErr: true},
+		{name: "no scopes", req: CreateAPITokenRequest{Name: "ci"}, wantErr: true},
+		{name: "malformed scope", req: CreateAPITokenRequest{Name: "ci", Scopes: []string{"clusters"}}, wantErr: true},
+		{name: "expired", req: CreateAPITokenRequest{Name: "ci", Scopes: []string{"*"}, ExpiresAt: &past}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v but got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAuthorizationService_CheckPermissionWithinTokenScope(t *testing.T) {
+	// The strategy grants everything; the token's scope narrows it
+	authz := NewAuthorizationService(NewNoAuthStrategy(zap.NewNop()), zap.NewNop())
+	tokenUser := &AuthenticatedUser{ID: uuid.New().String(), TokenID: uuid.New().String(), Scopes: []string{"clusters:read", "operations:*"}}
+	ctx := context.WithValue(context.Background(), UserContextKey, tokenUser)
+
+	tests := []struct {
+		resource, action string
+		expected         bool
+	}{
+		{"clusters", "read", true},
+		{"clusters", "manage", false},
+		{"operations", "cancel", true},
+		{"users", "read", false},
+	}
+
+	for _, tt := range tests {
+		allowed, err := authz.CheckPermission(ctx, uuid.New(), tt.resource, tt.action)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if allowed != tt.expected {
+			t.Errorf("Expected %s:%s allowed=%v but got %v", tt.resource, tt.action, tt.expected, allowed)
+		}
+	}
+}
+
+// staticTokenAuthenticator accepts a single personal access token
+type staticTokenAuthenticator struct {
+	token string
+	user  *AuthenticatedUser
+}
+
+func (a *staticTokenAuthenticator) AuthenticateAPIToken(_ context.Context, token string) (*AuthenticatedUser, error) {
+	if token != a.token {
+		return nil, ErrInvalidAPIToken
+	}
+	return a.user, nil
+}
+
+func TestMiddleware_RequireAuthWithAPIToken(t *testing.T) {
+	jwtManager := NewJWTManager("secret", time.Hour)
+	middleware := NewAuthMiddleware(jwtManager, zap.NewNop())
+	tokenUser := &AuthenticatedUser{ID: uuid.New().String(), Username: "ci", TokenID: uuid.New().String(), Scopes: []string{"*"}}
+	middleware.SetAPITokenAuthenticator(&staticTokenAuthenticator{token: APITokenPrefix + "valid", user: tokenUser})
+
+	jwt, err := jwtManager.GenerateToken(uuid.New().String(), "alice", "alice@example.com", []string{"admin"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	refreshJWT, _, err := jwtManager.GenerateRefreshToken(uuid.New().String(), "alice", "alice@example.com", []string{"admin"}, "")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		token            string
+		expectedStatus   int
+		expectedUsername string
+	}{
+		{name: "personal access token", token: APITokenPrefix + "valid", expectedStatus: http.StatusOK, expectedUsername: "ci"},
+		{name: "unknown personal access token", token: APITokenPrefix + "revoked", expectedStatus: http.StatusUnauthorized},
+		{name: "JWT", token: jwt, expectedStatus: http.StatusOK, expectedUsername: "alice"},
+		{name: "refresh JWT", token: refreshJWT, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var username string
+			handler := middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, _ := GetUserFromContext(r.Context())
+				username = user.Username
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/clusters", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d but got %d", tt.expectedStatus, rr.Code)
+			}
+			if username != tt.expectedUsername {
+				t.Errorf("Expected user %q but got %q", tt.expectedUsername, username)
+			}
+		})
+	}
+}
+
+func TestService_AuthenticateAPITokenWithClusterScope(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, userRepo, tokenRepo := newTokenTestService(ctrl)
+	scoped := uuid.New()
+	apiToken := &user.APIToken{ID: uuid.New(), UserID: uuid.New(), Scopes: []string{"clusters:manage"}, ClusterIDs: []uuid.UUID{scoped}}
+	tokenRepo.EXPECT().GetByHash(gomock.Any(), gomock.Any()).Return(apiToken, nil)
+	tokenRepo.EXPECT().UpdateLastUsed(gomock.Any(), apiToken.ID).Return(nil)
+	userRepo.EXPECT().GetByID(gomock.Any(), apiToken.UserID).Return(&user.User{ID: apiToken.UserID, Active: true}, nil)
+
+	authUser, err := service.AuthenticateAPIToken(context.Background(), APITokenPrefix+"secret")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if !authUser.InClusterScope(scoped.String()) {
+		t.Errorf("Expected cluster %s to be in scope", scoped)
+	}
+	if authUser.InClusterScope(uuid.New().String()) {
+		t.Errorf("Expected other clusters to be out of scope")
+	}
+}
+
+func TestAuthorizationService_TokenScopeCannotExceedPermissions(t *testing.T) {
+	// The user may only read clusters; an all-permission token doesn't change that
+	authz := NewAuthorizationService(&grantedStrategy{granted: map[string]bool{"clusters:read": true}}, zap.NewNop())
+	tokenUser := &AuthenticatedUser{ID: uuid.New().String(), TokenID: uuid.New().String(), Scopes: []string{"*"}}
+	ctx := context.WithValue(context.Background(), UserContextKey, tokenUser)
+
+	if allowed, _ := authz.CheckPermission(ctx, uuid.New(), "clusters", "read"); !allowed {
+		t.Errorf("Expected clusters:read to be allowed")
+	}
+	if allowed, _ := authz.CheckPermission(ctx, uuid.New(), "clusters", "manage"); allowed {
+		t.Errorf("Expected clusters:manage to be denied despite the wildcard scope")
+	}
+}
+
+// grantedStrategy grants exactly the listed "resource:action" permissions
+type grantedStrategy struct {
+	granted map[string]bool
+}
+
+func (s *grantedStrategy) CheckPermission(_ context.Context, _ uuid.UUID, resource, action string) (bool, error) {
+	return s.granted[resource+":"+action], nil
+}
+
+func (s *grantedStrategy) IsEnabled() bool { return true }
+
+func (s *grantedStrategy) GetName() string { return "granted" }
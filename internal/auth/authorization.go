@@ -33,8 +33,19 @@ func NewAuthorizationService(strategy AuthorizationStrategy, logger *zap.Logger)
 	}
 }
 
-// CheckPermission delegates to the current strategy
+// CheckPermission delegates to the current strategy. Users authenticated with
+// a personal access token are also limited to the token's scopes.
 func (a *AuthorizationService) CheckPermission(ctx context.Context, userID uuid.UUID, resource, action string) (bool, error) {
+	if user, ok := GetUserFromContext(ctx); ok && !user.InScope(resource, action) {
+		a.logger.Debug("Action outside of token scope",
+			zap.String("user_id", userID.String()),
+			zap.String("token_id", user.TokenID),
+			zap.String("resource", resource),
+			zap.String("action", action),
+		)
+		return false, nil
+	}
+
 	if !a.strategy.IsEnabled() {
 		a.logger.Warn("Authorization strategy is disabled, allowing request",
 			zap.String("strategy", a.strategy.GetName()),
@@ -13,6 +13,24 @@ import (
 	"github.com/rizesky/mckmt/internal/user"
 )
 
+// defaultCasbinModel is the RBAC model used when no model file is configured
+const defaultCasbinModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && (p.obj == r.obj || p.obj == "*") && (p.act == r.act || p.act == "*")
+`
+
 // CasbinService provides RBAC/ABAC authorization using Casbin
 type CasbinService struct {
 	enforcer       *casbin.Enforcer
@@ -41,45 +59,63 @@ func NewCasbinAdapter(roleRepo repo.RoleRepository, permissionRepo repo.Permissi
 	}
 }
 
-// LoadPolicy loads all policy rules from the database
+// policyUserPageSize is the number of users listed per query by LoadPolicy
+const policyUserPageSize = 1000
+
+// LoadPolicy loads all policy rules from the database. Assignments are read
+// in bulk and joined in memory, so the number of queries depends on the number
+// of user pages rather than on the number of users and roles.
 func (a *CasbinAdapter) LoadPolicy(model model.Model) error {
 	ctx := context.Background()
 
-	// Load all users first
-	users, err := a.userRepo.List(ctx, 1000, 0)
+	usernames, err := a.usernames(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load users: %w", err)
+		return err
 	}
 
-	// For each user, load their roles and permissions
-	for _, user := range users {
-		// Get user roles
-		userRoles, err := a.roleRepo.GetUserRoles(ctx, user.ID)
-		if err != nil {
-			a.logger.Warn("Failed to load user roles", zap.String("user_id", user.ID.String()), zap.Error(err))
+	userRoles, err := a.roleRepo.ListUserRoleAssignments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load user roles: %w", err)
+	}
+
+	rolePermissions, err := a.roleRepo.ListRolePermissionAssignments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load role permissions: %w", err)
+	}
+
+	// Add user-role assignments: user, role
+	for _, assignment := range userRoles {
+		username, ok := usernames[assignment.UserID]
+		if !ok {
+			// Assigned to a user created after the users were listed
 			continue
 		}
+		model.AddPolicy("g", "g", []string{username, assignment.RoleName})
+	}
 
-		// For each role, add user-role assignment and role permissions
-		for _, role := range userRoles {
-			// Add user-role assignment: user, role
-			model.AddPolicy("g", "g", []string{user.Username, role.Name})
+	// Add role-permission assignments: role, resource, action
+	for _, assignment := range rolePermissions {
+		model.AddPolicy("p", "p", []string{assignment.RoleName, assignment.Resource, assignment.Action})
+	}
 
-			// Get permissions for this role
-			permissions, err := a.roleRepo.GetRolePermissions(ctx, role.ID)
-			if err != nil {
-				a.logger.Warn("Failed to load role permissions", zap.String("role", role.Name), zap.Error(err))
-				continue
-			}
+	return nil
+}
 
-			// Add role-permission assignments: role, resource, action
-			for _, permission := range permissions {
-				model.AddPolicy("p", "p", []string{role.Name, permission.Resource, permission.Action})
-			}
+// usernames returns the username of every user by ID
+func (a *CasbinAdapter) usernames(ctx context.Context) (map[uuid.UUID]string, error) {
+	usernames := make(map[uuid.UUID]string)
+	for offset := 0; ; offset += policyUserPageSize {
+		users, err := a.userRepo.List(ctx, policyUserPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load users: %w", err)
+		}
+		for _, u := range users {
+			usernames[u.ID] = u.Username
+		}
+		if len(users) < policyUserPageSize {
+			return usernames, nil
 		}
 	}
-
-	return nil
 }
 
 // SavePolicy saves all policy rules to the database
@@ -143,23 +179,7 @@ func NewCasbinService(roleRepo repo.RoleRepository, permissionRepo repo.Permissi
 		}
 	} else {
 		// Use default built-in model
-		modelText := `
-[request_definition]
-r = sub, obj, act
-
-[policy_definition]
-p = sub, obj, act
-
-[role_definition]
-g = _, _
-
-[policy_effect]
-e = some(where (p.eft == allow))
-
-[matchers]
-m = g(r.sub, p.sub) && (p.obj == r.obj || p.obj == "*") && (p.act == r.act || p.act == "*")
-`
-		m, err = model.NewModelFromString(modelText)
+		m, err = model.NewModelFromString(defaultCasbinModel)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Casbin model: %w", err)
 		}
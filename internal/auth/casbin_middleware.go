@@ -50,11 +50,13 @@ func (m *Middleware) RequirePermission(authzService *AuthorizationService, resou
 					zap.String("resource", resource),
 					zap.String("action", action),
 				)
+				m.auditDecision(r, user, resource, "", action, false)
 				m.writeErrorResponse(w, http.StatusForbidden, "Insufficient permissions")
 				return
 			}
 
 			// Permission granted, proceed to next handler
+			m.auditDecision(r, user, resource, "", action, true)
 			next(w, r)
 		}
 	}
@@ -104,6 +106,7 @@ func (m *Middleware) RequireAnyPermission(casbinService *CasbinService, permissi
 
 				if allowed {
 					// User has at least one required permission
+					m.auditDecision(r, user, perm.Resource, "", perm.Action, true)
 					next.ServeHTTP(w, r)
 					return
 				}
@@ -115,6 +118,8 @@ func (m *Middleware) RequireAnyPermission(casbinService *CasbinService, permissi
 				zap.String("username", user.Username),
 				zap.Int("required_permissions", len(permissions)),
 			)
+			resources, actions := joinPermissions(permissions)
+			m.auditDecision(r, user, resources, "", actions, false)
 			m.writeErrorResponse(w, http.StatusForbidden, "Insufficient permissions")
 		}
 	}
@@ -170,12 +175,15 @@ func (m *Middleware) RequireAllPermissions(casbinService *CasbinService, permiss
 						zap.String("resource", perm.Resource),
 						zap.String("action", perm.Action),
 					)
+					m.auditDecision(r, user, perm.Resource, "", perm.Action, false)
 					m.writeErrorResponse(w, http.StatusForbidden, "Insufficient permissions")
 					return
 				}
 			}
 
 			// User has all required permissions
+			resources, actions := joinPermissions(permissions)
+			m.auditDecision(r, user, resources, "", actions, true)
 			next(w, r)
 		}
 	}
@@ -252,12 +260,14 @@ func (m *Middleware) RequireResourceOwnership(casbinService *CasbinService, reso
 						zap.String("resource_type", resourceType),
 						zap.String("resource_id", resourceID),
 					)
+					m.auditDecision(r, user, resourceType, resourceID, "own", false)
 					m.writeErrorResponse(w, http.StatusForbidden, "Access denied to resource")
 					return
 				}
 			}
 
 			// Permission granted, proceed to next handler
+			m.auditDecision(r, user, resourceType, resourceID, "own", true)
 			next(w, r)
 		}
 	}
@@ -269,6 +279,18 @@ type Permission struct {
 	Action   string
 }
 
+// joinPermissions returns the comma separated resources and actions of
+// permissions, in the same order, for recording checks of several permissions
+func joinPermissions(permissions []Permission) (resources, actions string) {
+	resourceList := make([]string, len(permissions))
+	actionList := make([]string, len(permissions))
+	for i, perm := range permissions {
+		resourceList[i] = perm.Resource
+		actionList[i] = perm.Action
+	}
+	return strings.Join(resourceList, ","), strings.Join(actionList, ",")
+}
+
 // NewPermission creates a new permission requirement
 func NewPermission(resource, action string) Permission {
 	return Permission{
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+	"github.com/rizesky/mckmt/internal/user"
+)
+
+func TestCasbinAdapter_LoadPolicyBatchesQueries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// More users than fit in a single page
+	const userCount = 2*policyUserPageSize + 500
+	users := make([]*user.User, userCount)
+	assignments := make([]*repo.UserRoleAssignment, userCount)
+	for i := range users {
+		users[i] = &user.User{ID: uuid.New(), Username: fmt.Sprintf("user-%d", i)}
+		role := "viewer"
+		if i%2 == 0 {
+			role = "operator"
+		}
+		assignments[i] = &repo.UserRoleAssignment{UserID: users[i].ID, RoleName: role}
+	}
+
+	// The query count depends on the number of user pages only
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().List(gomock.Any(), policyUserPageSize, gomock.Any()).DoAndReturn(
+		func(_ context.Context, limit, offset int) ([]*user.User, error) {
+			end := offset + limit
+			if end > len(users) {
+				end = len(users)
+			}
+			return users[offset:end], nil
+		}).Times(3)
+
+	roleRepo := repomocks.NewMockRoleRepository(ctrl)
+	roleRepo.EXPECT().ListUserRoleAssignments(gomock.Any()).Return(assignments, nil).Times(1)
+	roleRepo.EXPECT().ListRolePermissionAssignments(gomock.Any()).Return([]*repo.RolePermissionAssignment{
+		{RoleName: "viewer", Resource: "clusters", Action: "read"},
+		{RoleName: "operator", Resource: "clusters", Action: "read"},
+		{RoleName: "operator", Resource: "operations", Action: "create"},
+	}, nil).Times(1)
+
+	m, err := model.NewModelFromString(defaultCasbinModel)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if err := NewCasbinAdapter(roleRepo, nil, userRepo, zap.NewNop()).LoadPolicy(m); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	enforcer, err := casbin.NewEnforcer(m)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if err := enforcer.BuildRoleLinks(); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		username string
+		resource string
+		action   string
+		expected bool
+	}{
+		{"user-0", "operations", "create", true},
+		{"user-1", "clusters", "read", true},
+		{"user-1", "operations", "create", false},
+		// Users beyond the first page are loaded too
+		{fmt.Sprintf("user-%d", userCount-1), "clusters", "read", true},
+		{fmt.Sprintf("user-%d", userCount-2), "operations", "create", true},
+	}
+	for _, tt := range tests {
+		allowed, err := enforcer.Enforce(tt.username, tt.resource, tt.action)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if allowed != tt.expected {
+			t.Errorf("Expected %s %s on %s to be %v but got %v", tt.username, tt.action, tt.resource, tt.expected, allowed)
+		}
+	}
+}
+
+func TestCasbinService_EnforcesRolesBeyondFirstUserPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	users := make([]*user.User, policyUserPageSize+1)
+	for i := range users {
+		users[i] = &user.User{ID: uuid.New(), Username: fmt.Sprintf("user-%d", i+1)}
+	}
+	last := users[len(users)-1]
+
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, limit, offset int) ([]*user.User, error) {
+			if offset >= len(users) {
+				return nil, nil
+			}
+			end := offset + limit
+			if end > len(users) {
+				end = len(users)
+			}
+			return users[offset:end], nil
+		}).AnyTimes()
+
+	// Only the 1001st user holds a role
+	roleRepo := repomocks.NewMockRoleRepository(ctrl)
+	roleRepo.EXPECT().ListUserRoleAssignments(gomock.Any()).Return([]*repo.UserRoleAssignment{
+		{UserID: last.ID, RoleName: "admin"},
+	}, nil).AnyTimes()
+	roleRepo.EXPECT().ListRolePermissionAssignments(gomock.Any()).Return([]*repo.RolePermissionAssignment{
+		{RoleName: "admin", Resource: "clusters", Action: "delete"},
+	}, nil).AnyTimes()
+
+	service, err := NewCasbinService(roleRepo, nil, userRepo, zap.NewNop(), true, true, "viewer", "")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		user     *user.User
+		expected bool
+	}{
+		{last, true},
+		{users[0], false},
+	}
+	for _, tt := range tests {
+		ctx := context.WithValue(context.Background(), UserContextKey, &AuthenticatedUser{ID: tt.user.ID.String(), Username: tt.user.Username})
+		allowed, err := service.CheckPermission(ctx, tt.user.ID, "clusters", "delete")
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if allowed != tt.expected {
+			t.Errorf("Expected %s to be allowed: %v but got %v", tt.user.Username, tt.expected, allowed)
+		}
+	}
+}
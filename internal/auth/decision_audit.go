@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// Audit log actions of the permission decisions of the permission middlewares
+const (
+	AuditActionPermissionDenied  = "permission_denied"
+	AuditActionPermissionGranted = "permission_granted"
+)
+
+// decisionAudit records permission decisions in the audit log
+type decisionAudit struct {
+	repo         repo.AuditLogRepository
+	recordAllows bool
+}
+
+// SetDecisionAudit records the permissions the permission middlewares deny in
+// the audit log, besides logging them, so authorization failures can be
+// reviewed. With recordAllows, granted permissions are recorded as well.
+func (a *Middleware) SetDecisionAudit(auditRepo repo.AuditLogRepository, recordAllows bool) {
+	if auditRepo == nil {
+		a.decisionAudit = nil
+		return
+	}
+	a.decisionAudit = &decisionAudit{repo: auditRepo, recordAllows: recordAllows}
+}
+
+// auditDecision records the decision on a permission check of user, if
+// decisions are audited
+func (a *Middleware) auditDecision(r *http.Request, user *AuthenticatedUser, resource, resourceID, action string, allowed bool) {
+	if a.decisionAudit == nil || (allowed && !a.decisionAudit.recordAllows) {
+		return
+	}
+
+	entry := &repo.AuditLog{
+		ID:             uuid.New(),
+		UserID:         user.ID,
+		Action:         AuditActionPermissionDenied,
+		ResourceType:   resource,
+		ResourceID:     resourceID,
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		StatusCode:     http.StatusForbidden,
+		RequestPayload: &repo.Payload{"username": user.Username, "action": action, "outcome": "denied"},
+		IPAddress:      r.RemoteAddr,
+		UserAgent:      r.UserAgent(),
+		CreatedAt:      time.Now().UTC(),
+	}
+	if allowed {
+		entry.Action = AuditActionPermissionGranted
+		entry.StatusCode = 0
+		(*entry.RequestPayload)["outcome"] = "allowed"
+	}
+
+	// Record the decision even if the client goes away
+	if err := a.decisionAudit.repo.Create(context.WithoutCancel(r.Context()), entry); err != nil {
+		a.logger.Error("Failed to record permission decision",
+			zap.Error(err),
+			zap.String("user_id", user.ID),
+			zap.String("resource", resource),
+			zap.String("action", action),
+		)
+	}
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestRequirePermission_AuditsDecisions(t *testing.T) {
+	tests := []struct {
+		name           string
+		recordAllows   bool
+		action         string
+		expectedStatus int
+		expectedAudit  string
+	}{
+		{name: "denied", action: "delete", expectedStatus: http.StatusForbidden, expectedAudit: AuditActionPermissionDenied},
+		{name: "allowed", action: "read", expectedStatus: http.StatusOK},
+		{name: "allowed and recorded", recordAllows: true, action: "read", expectedStatus: http.StatusOK, expectedAudit: AuditActionPermissionGranted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			auditRepo := repomocks.NewMockAuditLogRepository(ctrl)
+			var recorded *repo.AuditLog
+			if tt.expectedAudit != "" {
+				auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, entry *repo.AuditLog) error {
+					recorded = entry
+					return nil
+				})
+			}
+
+			middleware := NewAuthMiddleware(nil, zap.NewNop())
+			middleware.SetDecisionAudit(auditRepo, tt.recordAllows)
+			authz := NewAuthorizationService(&grantedStrategy{granted: map[string]bool{"clusters:read": true}}, zap.NewNop())
+
+			handler := middleware.RequirePermission(authz, "clusters", tt.action)(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			user := &AuthenticatedUser{ID: uuid.New().String(), Username: "alice"}
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/clusters/1", nil)
+			req = req.WithContext(context.WithValue(req.Context(), UserContextKey, user))
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d but got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedAudit == "" {
+				return
+			}
+			if recorded == nil {
+				t.Fatalf("Expected a %s audit entry", tt.expectedAudit)
+			}
+			if recorded.Action != tt.expectedAudit || recorded.UserID != user.ID || recorded.ResourceType != "clusters" {
+				t.Errorf("Expected a %s entry for clusters by %s but got %+v", tt.expectedAudit, user.ID, recorded)
+			}
+			if recorded.RequestPayload == nil || (*recorded.RequestPayload)["action"] != tt.action {
+				t.Errorf("Expected the entry to record action %s but got %v", tt.action, recorded.RequestPayload)
+			}
+		})
+	}
+}
+
+func TestRequirePermission_WithoutDecisionAudit(t *testing.T) {
+	middleware := NewAuthMiddleware(nil, zap.NewNop())
+	authz := NewAuthorizationService(&grantedStrategy{}, zap.NewNop())
+
+	handler := middleware.RequirePermission(authz, "clusters", "delete")(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/clusters/1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserContextKey, &AuthenticatedUser{ID: uuid.New().String()}))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 but got %d", rec.Code)
+	}
+}
@@ -1,58 +1,158 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+
+	"github.com/rizesky/mckmt/internal/repo"
 )
 
+// ErrTokenRevoked is returned when validating a token revoked on logout
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Token types, telling access tokens from the refresh tokens exchanged for them
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// defaultRefreshTokenDuration is the lifetime of refresh tokens unless set otherwise
+const defaultRefreshTokenDuration = 7 * 24 * time.Hour
+
 // Claims represents the JWT claims
 type Claims struct {
 	UserID   string   `json:"user_id"`
 	Username string   `json:"username"`
 	Email    string   `json:"email"`
 	Roles    []string `json:"roles"`
+	// TokenType is TokenTypeAccess or TokenTypeRefresh
+	TokenType string `json:"token_type,omitempty"`
+	// Family identifies the chain of rotated refresh tokens a refresh token
+	// belongs to; empty for access tokens
+	Family string `json:"family,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // JWTManager handles JWT token operations
 type JWTManager struct {
-	secretKey     string
-	tokenDuration time.Duration
+	secretKey            string
+	tokenDuration        time.Duration
+	refreshTokenDuration time.Duration
+
+	// revoked holds the IDs of tokens revoked before they expire; nil disables revocation
+	revoked repo.Cache
 }
 
 // NewJWTManager creates a new JWT manager
 func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	return &JWTManager{
-		secretKey:     secretKey,
-		tokenDuration: tokenDuration,
+		secretKey:            secretKey,
+		tokenDuration:        tokenDuration,
+		refreshTokenDuration: defaultRefreshTokenDuration,
 	}
 }
 
-// GenerateToken generates a new JWT token for a user
+// SetRefreshTokenDuration sets the lifetime of refresh tokens
+func (j *JWTManager) SetRefreshTokenDuration(duration time.Duration) {
+	if duration > 0 {
+		j.refreshTokenDuration = duration
+	}
+}
+
+// SetRevocationStore enables token revocation, keeping the IDs of revoked
+// tokens in cache until the tokens expire
+func (j *JWTManager) SetRevocationStore(cache repo.Cache) {
+	j.revoked = cache
+}
+
+// revokedTokenKey returns the cache key of a revoked token ID
+func revokedTokenKey(id string) string {
+	return "revoked_token:" + id
+}
+
+// RevokeToken rejects a token from now on, until it expires. It is a no-op
+// when revocation is disabled or the token has already expired.
+func (j *JWTManager) RevokeToken(ctx context.Context, claims *Claims) error {
+	if j.revoked == nil {
+		return nil
+	}
+	if claims.ID == "" {
+		return errors.New("token has no ID")
+	}
+	if claims.ExpiresAt == nil {
+		return errors.New("token has no expiration")
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := j.revoked.Set(ctx, revokedTokenKey(claims.ID), true, ttl); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// isRevoked reports whether a token ID has been revoked
+func (j *JWTManager) isRevoked(ctx context.Context, id string) (bool, error) {
+	if j.revoked == nil || id == "" {
+		return false, nil
+	}
+
+	var revoked bool
+	if err := j.revoked.Get(ctx, revokedTokenKey(id), &revoked); err != nil {
+		if errors.Is(err, repo.ErrCacheMiss) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// GenerateToken generates a new access token for a user
 func (j *JWTManager) GenerateToken(userID, username, email string, roles []string) (string, error) {
+	token, _, err := j.generate(userID, username, email, roles, TokenTypeAccess, "", j.tokenDuration)
+	return token, err
+}
+
+// GenerateRefreshToken generates a refresh token, optionally belonging to a
+// token family, and returns it along with its ID. Refresh tokens live for the
+// refresh token duration rather than the access token one.
+func (j *JWTManager) GenerateRefreshToken(userID, username, email string, roles []string, family string) (string, string, error) {
+	return j.generate(userID, username, email, roles, TokenTypeRefresh, family, j.refreshTokenDuration)
+}
+
+func (j *JWTManager) generate(userID, username, email string, roles []string, tokenType, family string, duration time.Duration) (string, string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Email:    email,
-		Roles:    roles,
+		UserID:    userID,
+		Username:  username,
+		Email:     email,
+		Roles:     roles,
+		TokenType: tokenType,
+		Family:    family,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.New().String(),
 			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(j.tokenDuration)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(duration)),
 			NotBefore: jwt.NewNumericDate(time.Now().UTC().Add(-1 * time.Second)), // Allow 1 second clock skew
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(j.secretKey))
+	if err != nil {
+		return "", "", err
+	}
+	return token, claims.ID, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
-func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
+// ValidateToken validates a JWT token and returns the claims. Revoked tokens
+// are rejected with ErrTokenRevoked.
+func (j *JWTManager) ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -69,12 +169,20 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	revoked, err := j.isRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
 	return claims, nil
 }
 
 // RefreshToken generates a new token with extended expiration
-func (j *JWTManager) RefreshToken(tokenString string) (string, error) {
-	claims, err := j.ValidateToken(tokenString)
+func (j *JWTManager) RefreshToken(ctx context.Context, tokenString string) (string, error) {
+	claims, err := j.ValidateToken(ctx, tokenString)
 	if err != nil {
 		return "", fmt.Errorf("failed to validate token: %w", err)
 	}
@@ -1,11 +1,17 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
 )
 
 func TestJWTManager_GenerateToken(t *testing.T) {
@@ -38,7 +44,7 @@ func TestJWTManager_ValidateToken(t *testing.T) {
 	require.NoError(t, err)
 
 	// Validate token
-	claims, err := jwtManager.ValidateToken(token)
+	claims, err := jwtManager.ValidateToken(context.Background(), token)
 	require.NoError(t, err)
 	assert.Equal(t, userID, claims.UserID)
 	assert.Equal(t, username, claims.Username)
@@ -55,16 +61,16 @@ func TestJWTManager_ValidateToken_InvalidToken(t *testing.T) {
 	jwtManager := NewJWTManager(secretKey, tokenDuration)
 
 	// Test invalid token
-	_, err := jwtManager.ValidateToken("invalid-token")
+	_, err := jwtManager.ValidateToken(context.Background(), "invalid-token")
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to parse token")
 
 	// Test empty token
-	_, err = jwtManager.ValidateToken("")
+	_, err = jwtManager.ValidateToken(context.Background(), "")
 	require.Error(t, err)
 
 	// Test malformed token
-	_, err = jwtManager.ValidateToken("invalid.token.format")
+	_, err = jwtManager.ValidateToken(context.Background(), "invalid.token.format")
 	require.Error(t, err)
 }
 
@@ -86,7 +92,7 @@ func TestJWTManager_ValidateToken_WrongSecret(t *testing.T) {
 	require.NoError(t, err)
 
 	// Try to validate with second manager (different secret)
-	_, err = jwtManager2.ValidateToken(token)
+	_, err = jwtManager2.ValidateToken(context.Background(), token)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to parse token")
 }
@@ -106,7 +112,7 @@ func TestJWTManager_ValidateToken_ExpiredToken(t *testing.T) {
 	require.NoError(t, err)
 
 	// Validate expired token
-	_, err = jwtManager.ValidateToken(token)
+	_, err = jwtManager.ValidateToken(context.Background(), token)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "expired")
 }
@@ -126,13 +132,13 @@ func TestJWTManager_RefreshToken(t *testing.T) {
 	require.NoError(t, err)
 
 	// Refresh token
-	refreshToken, err := jwtManager.RefreshToken(originalToken)
+	refreshToken, err := jwtManager.RefreshToken(context.Background(), originalToken)
 	require.NoError(t, err)
 	assert.NotEmpty(t, refreshToken)
 	assert.NotEqual(t, originalToken, refreshToken)
 
 	// Validate refresh token
-	claims, err := jwtManager.ValidateToken(refreshToken)
+	claims, err := jwtManager.ValidateToken(context.Background(), refreshToken)
 	require.NoError(t, err)
 	assert.Equal(t, userID, claims.UserID)
 	assert.Equal(t, username, claims.Username)
@@ -146,11 +152,30 @@ func TestJWTManager_RefreshToken_InvalidToken(t *testing.T) {
 	jwtManager := NewJWTManager(secretKey, tokenDuration)
 
 	// Test refresh with invalid token
-	_, err := jwtManager.RefreshToken("invalid-token")
+	_, err := jwtManager.RefreshToken(context.Background(), "invalid-token")
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to validate token")
 }
 
+func TestJWTManager_GenerateRefreshToken(t *testing.T) {
+	jwtManager := NewJWTManager("test-secret-key", time.Hour)
+
+	token, id, err := jwtManager.GenerateRefreshToken("user-123", "testuser", "test@example.com", []string{"user"}, "family-1")
+	require.NoError(t, err)
+
+	claims, err := jwtManager.ValidateToken(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "family-1", claims.Family)
+	assert.Equal(t, id, claims.ID)
+
+	// Access tokens belong to no family
+	accessToken, err := jwtManager.GenerateToken("user-123", "testuser", "test@example.com", []string{"user"})
+	require.NoError(t, err)
+	claims, err = jwtManager.ValidateToken(context.Background(), accessToken)
+	require.NoError(t, err)
+	assert.Empty(t, claims.Family)
+}
+
 func TestExtractTokenFromHeader(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -215,7 +240,7 @@ func TestJWTManager_TokenExpiration(t *testing.T) {
 	require.NoError(t, err)
 
 	// Validate immediately (should work)
-	claims, err := jwtManager.ValidateToken(token)
+	claims, err := jwtManager.ValidateToken(context.Background(), token)
 	require.NoError(t, err)
 	assert.Equal(t, userID, claims.UserID)
 
@@ -223,7 +248,7 @@ func TestJWTManager_TokenExpiration(t *testing.T) {
 	time.Sleep(1100 * time.Millisecond) // Wait slightly longer than token duration
 
 	// Validate after expiration (should fail)
-	_, err = jwtManager.ValidateToken(token)
+	_, err = jwtManager.ValidateToken(context.Background(), token)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "expired")
 }
@@ -243,7 +268,7 @@ func TestJWTManager_ClaimsStructure(t *testing.T) {
 	require.NoError(t, err)
 
 	// Validate token and check claims structure
-	claims, err := jwtManager.ValidateToken(token)
+	claims, err := jwtManager.ValidateToken(context.Background(), token)
 	require.NoError(t, err)
 
 	// Check custom claims
@@ -260,3 +285,101 @@ func TestJWTManager_ClaimsStructure(t *testing.T) {
 	assert.True(t, claims.ExpiresAt.After(claims.IssuedAt.Time))
 	assert.True(t, claims.NotBefore.Before(claims.ExpiresAt.Time) || claims.NotBefore.Equal(claims.ExpiresAt.Time))
 }
+
+func TestJWTManager_RevokeToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cache, entries := newMapCache(ctrl)
+	jwtManager := NewJWTManager("test-secret-key", time.Hour)
+	jwtManager.SetRevocationStore(cache)
+
+	token, err := jwtManager.GenerateToken("user-123", "testuser", "test@example.com", []string{"viewer"})
+	require.NoError(t, err)
+	other, err := jwtManager.GenerateToken("user-123", "testuser", "test@example.com", []string{"viewer"})
+	require.NoError(t, err)
+
+	claims, err := jwtManager.ValidateToken(context.Background(), token)
+	require.NoError(t, err)
+
+	require.NoError(t, jwtManager.RevokeToken(context.Background(), claims))
+	assert.Contains(t, entries, revokedTokenKey(claims.ID))
+
+	_, err = jwtManager.ValidateToken(context.Background(), token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// Other tokens of the same user stay valid
+	_, err = jwtManager.ValidateToken(context.Background(), other)
+	assert.NoError(t, err)
+}
+
+func TestJWTManager_RevokeTokenExpiresWithToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cache := repomocks.NewMockCache(ctrl)
+	jwtManager := NewJWTManager("test-secret-key", time.Hour)
+	jwtManager.SetRevocationStore(cache)
+
+	claims := &Claims{RegisteredClaims: jwt.RegisteredClaims{
+		ID:        "token-1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(10 * time.Minute)),
+	}}
+
+	// The revocation is kept for the remaining lifetime of the token
+	cache.EXPECT().Set(gomock.Any(), revokedTokenKey("token-1"), true, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, _ interface{}, ttl time.Duration) error {
+			assert.InDelta(t, float64(10*time.Minute), float64(ttl), float64(5*time.Second))
+			return nil
+		})
+	require.NoError(t, jwtManager.RevokeToken(context.Background(), claims))
+
+	// Expired tokens need no revocation
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	require.NoError(t, jwtManager.RevokeToken(context.Background(), claims))
+}
+
+func TestJWTManager_ValidateTokenFailsWhenRevocationUnknown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cache := repomocks.NewMockCache(ctrl)
+	cache.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("connection refused"))
+
+	jwtManager := NewJWTManager("test-secret-key", time.Hour)
+	jwtManager.SetRevocationStore(cache)
+
+	token, err := jwtManager.GenerateToken("user-123", "testuser", "test@example.com", nil)
+	require.NoError(t, err)
+
+	_, err = jwtManager.ValidateToken(context.Background(), token)
+	assert.Error(t, err)
+}
+
+func TestJWTManager_RefreshTokenLifetime(t *testing.T) {
+	jwtManager := NewJWTManager("test-secret-key", time.Hour)
+	jwtManager.SetRefreshTokenDuration(30 * 24 * time.Hour)
+
+	accessToken, err := jwtManager.GenerateToken("user-123", "testuser", "test@example.com", nil)
+	require.NoError(t, err)
+	refreshToken, _, err := jwtManager.GenerateRefreshToken("user-123", "testuser", "test@example.com", nil, "")
+	require.NoError(t, err)
+
+	access, err := jwtManager.ValidateToken(context.Background(), accessToken)
+	require.NoError(t, err)
+	refresh, err := jwtManager.ValidateToken(context.Background(), refreshToken)
+	require.NoError(t, err)
+
+	assert.Equal(t, TokenTypeAccess, access.TokenType)
+	assert.Equal(t, TokenTypeRefresh, refresh.TokenType)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), access.ExpiresAt.Time, 5*time.Second)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), refresh.ExpiresAt.Time, 5*time.Second)
+
+	// Refresh tokens outlive access tokens by default too
+	defaults := NewJWTManager("test-secret-key", time.Hour)
+	refreshToken, _, err = defaults.GenerateRefreshToken("user-123", "testuser", "test@example.com", nil, "")
+	require.NoError(t, err)
+	refresh, err = defaults.ValidateToken(context.Background(), refreshToken)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(defaultRefreshTokenDuration), refresh.ExpiresAt.Time, 5*time.Second)
+}
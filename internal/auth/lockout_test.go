@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+	"github.com/rizesky/mckmt/internal/user"
+)
+
+// newLockoutTestService returns a service locking usernames out after
+// threshold failed logins for window, with alice as its only user
+func newLockoutTestService(t *testing.T, ctrl *gomock.Controller, threshold int, window time.Duration) (*Service, map[string][]byte) {
+	t.Helper()
+
+	passwordManager := NewPasswordManager(nil)
+	hash, err := passwordManager.HashPassword("Sup3r-secret")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	account := &user.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", PasswordHash: hash, AuthSource: user.AuthSourcePassword, Active: true}
+
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().GetByUsername(gomock.Any(), "alice").Return(account, nil).AnyTimes()
+	userRepo.EXPECT().GetByUsername(gomock.Any(), gomock.Any()).Return(nil, repo.ErrNotFound).AnyTimes()
+
+	cache, entries := newMapCache(ctrl)
+	cache.EXPECT().Delete(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key string) error {
+		delete(entries, key)
+		return nil
+	}).AnyTimes()
+	cache.EXPECT().IncrementWithExpiry(gomock.Any(), gomock.Any(), window).DoAndReturn(func(_ context.Context, key string, expiration time.Duration) (int64, time.Duration, error) {
+		var count int64
+		if data, ok := entries[key]; ok {
+			if err := json.Unmarshal(data, &count); err != nil {
+				return 0, 0, err
+			}
+		}
+		count++
+		data, err := json.Marshal(count)
+		entries[key] = data
+		return count, expiration, err
+	}).AnyTimes()
+
+	service := NewAuthService(userRepo, nil, nil, nil, NewJWTManager("secret", time.Hour), passwordManager, nil, nil, "viewer", zap.NewNop())
+	service.SetLoginLockout(cache, threshold, window)
+	return service, entries
+}
+
+// login logs alice in with password
+func login(service *Service, password string) error {
+	_, err := service.Login(context.Background(), &LoginRequest{Username: "alice", Password: password}, "127.0.0.1", "test")
+	return err
+}
+
+func TestService_LoginLocksAccountAfterFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, _ := newLockoutTestService(t, ctrl, 3, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if err := login(service, "wrong"); err == nil || errors.Is(err, ErrAccountLocked) {
+			t.Fatalf("Expected attempt %d to fail with invalid credentials but got: %v", i, err)
+		}
+	}
+
+	// The right password no longer helps
+	if err := login(service, "Sup3r-secret"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Expected ErrAccountLocked but got: %v", err)
+	}
+
+	// Other usernames are not affected
+	if _, err := service.Login(context.Background(), &LoginRequest{Username: "bob", Password: "wrong"}, "127.0.0.1", "test"); err == nil || errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Expected invalid credentials for bob but got: %v", err)
+	}
+}
+
+func TestService_LoginUnlocksAfterWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The map cache ignores expirations, so the lockout ends by its own deadline
+	window := 200 * time.Millisecond
+	service, _ := newLockoutTestService(t, ctrl, 2, window)
+
+	for i := 0; i < 2; i++ {
+		_ = login(service, "wrong")
+	}
+	if err := login(service, "Sup3r-secret"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Expected ErrAccountLocked but got: %v", err)
+	}
+
+	time.Sleep(window)
+
+	if err := login(service, "Sup3r-secret"); err != nil {
+		t.Fatalf("Expected login after the lockout window but got: %v", err)
+	}
+}
+
+func TestService_LoginResetsFailuresOnSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, entries := newLockoutTestService(t, ctrl, 3, time.Hour)
+
+	// Failures that don't reach the threshold are forgotten after a login
+	for i := 0; i < 2; i++ {
+		_ = login(service, "wrong")
+	}
+	if err := login(service, "Sup3r-secret"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, ok := entries[loginFailuresKey("alice")]; ok {
+		t.Fatal("Expected failed logins to be reset")
+	}
+
+	for i := 0; i < 2; i++ {
+		_ = login(service, "wrong")
+	}
+	if err := login(service, "Sup3r-secret"); err != nil {
+		t.Errorf("Expected the counter to restart after a login but got: %v", err)
+	}
+}
+
+func TestService_LoginWithoutLockout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, _ := newLockoutTestService(t, ctrl, 1, time.Hour)
+	service.SetLoginLockout(nil, 0, 0)
+
+	for i := 0; i < 5; i++ {
+		_ = login(service, "wrong")
+	}
+	if err := login(service, "Sup3r-secret"); err != nil {
+		t.Errorf("Expected login without lockout but got: %v", err)
+	}
+}
@@ -2,7 +2,9 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -15,10 +17,17 @@ const (
 	UserContextKey ContextKey = "user"
 )
 
+// APITokenAuthenticator resolves personal access tokens to the user they act for
+type APITokenAuthenticator interface {
+	AuthenticateAPIToken(ctx context.Context, token string) (*AuthenticatedUser, error)
+}
+
 // Middleware  provides authentication middleware
 type Middleware struct {
-	jwtManager *JWTManager
-	logger     *zap.Logger
+	jwtManager         *JWTManager
+	tokenAuthenticator APITokenAuthenticator
+	decisionAudit      *decisionAudit
+	logger             *zap.Logger
 }
 
 // NewAuthMiddleware creates a new authentication middleware
@@ -29,6 +38,12 @@ func NewAuthMiddleware(jwtManager *JWTManager, logger *zap.Logger) *Middleware {
 	}
 }
 
+// SetAPITokenAuthenticator makes RequireAuth accept personal access tokens
+// besides JWTs
+func (a *Middleware) SetAPITokenAuthenticator(authenticator APITokenAuthenticator) {
+	a.tokenAuthenticator = authenticator
+}
+
 // RequireAuth middleware that requires authentication
 func (a *Middleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -44,13 +59,24 @@ func (a *Middleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		claims, err := a.jwtManager.ValidateToken(token)
+		if strings.HasPrefix(token, APITokenPrefix) {
+			a.requireAPIToken(w, r, token, next)
+			return
+		}
+
+		claims, err := a.jwtManager.ValidateToken(r.Context(), token)
 		if err != nil {
 			a.logger.Warn("Invalid token", zap.Error(err), zap.String("token", token[:50]+"..."))
 			a.writeErrorResponse(w, http.StatusUnauthorized, "Invalid token")
 			return
 		}
 
+		// Refresh tokens are only exchanged for access tokens
+		if claims.TokenType == TokenTypeRefresh {
+			a.writeErrorResponse(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+
 		user := &AuthenticatedUser{
 			ID:       claims.UserID,
 			Username: claims.Username,
@@ -63,6 +89,28 @@ func (a *Middleware) RequireAuth(next http.Handler) http.Handler {
 	})
 }
 
+// requireAPIToken authenticates a request carrying a personal access token
+func (a *Middleware) requireAPIToken(w http.ResponseWriter, r *http.Request, token string, next http.Handler) {
+	if a.tokenAuthenticator == nil {
+		a.writeErrorResponse(w, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+
+	user, err := a.tokenAuthenticator.AuthenticateAPIToken(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAPIToken) || errors.Is(err, ErrAPITokensDisabled) {
+			a.writeErrorResponse(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+		a.logger.Error("Failed to authenticate personal access token", zap.Error(err))
+		a.writeErrorResponse(w, http.StatusInternalServerError, "Failed to authenticate")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), UserContextKey, user)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // writeErrorResponse writes an error response
 func (a *Middleware) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
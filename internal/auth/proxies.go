@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"net"
+	"strings"
+)
+
+// TrustedProxies are the networks of the reverse proxies whose forwarded
+// client addresses are honoured. Forwarding headers from any other peer are
+// ignored, since clients can set them to anything.
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies parses the trusted proxy CIDRs. Bare IPs are accepted as
+// single-address networks. An empty list trusts no proxy.
+func NewTrustedProxies(cidrs []string) (*TrustedProxies, error) {
+	networks, err := parseNetworks(cidrs, "trusted proxy")
+	if err != nil {
+		return nil, err
+	}
+	return &TrustedProxies{networks: networks}, nil
+}
+
+// Trusts reports whether the proxy at ip may report client addresses
+func (p *TrustedProxies) Trusts(ip string) bool {
+	if p == nil {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	return parsed != nil && containsIP(p.networks, parsed)
+}
+
+// ClientIP returns the address of the client behind peerIP given the
+// X-Forwarded-For values it sent. Each proxy appends the address it got the
+// request from, so the hops are walked from the right and the first one not
+// trusted is the client; the leftmost hop is only reached when every proxy
+// is trusted. Unless peerIP is trusted, it is the client.
+func (p *TrustedProxies) ClientIP(peerIP string, forwardedFor []string) string {
+	if !p.Trusts(peerIP) {
+		return peerIP
+	}
+
+	var hops []string
+	for _, value := range forwardedFor {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	client := peerIP
+	for i := len(hops) - 1; i >= 0; i-- {
+		client = hops[i]
+		if !p.Trusts(client) {
+			break
+		}
+	}
+	return client
+}
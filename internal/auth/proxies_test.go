@@ -0,0 +1,46 @@
+package auth
+
+import "testing"
+
+func TestTrustedProxies_ClientIP(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		peerIP       string
+		forwardedFor []string
+		expected     string
+	}{
+		{name: "direct client", peerIP: "203.0.113.7", expected: "203.0.113.7"},
+		{name: "spoofed header from untrusted peer", peerIP: "203.0.113.7", forwardedFor: []string{"10.1.2.3"}, expected: "203.0.113.7"},
+		{name: "client behind trusted proxy", peerIP: "10.0.0.1", forwardedFor: []string{"198.51.100.4"}, expected: "198.51.100.4"},
+		{name: "client prepending a spoofed hop", peerIP: "10.0.0.1", forwardedFor: []string{"10.1.2.3, 198.51.100.4"}, expected: "198.51.100.4"},
+		{name: "chain of trusted proxies", peerIP: "10.0.0.1", forwardedFor: []string{"198.51.100.4, 10.0.0.2", "10.0.0.3"}, expected: "198.51.100.4"},
+		{name: "trusted proxy without header", peerIP: "10.0.0.1", expected: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := proxies.ClientIP(tt.peerIP, tt.forwardedFor); got != tt.expected {
+				t.Errorf("Expected client IP %s but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTrustedProxies_Empty(t *testing.T) {
+	proxies, err := NewTrustedProxies(nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if got := proxies.ClientIP("10.0.0.1", []string{"198.51.100.4"}); got != "10.0.0.1" {
+		t.Errorf("Expected forwarded addresses to be ignored but got %s", got)
+	}
+
+	if _, err := NewTrustedProxies([]string{"not-a-cidr"}); err == nil {
+		t.Error("Expected an error for an invalid entry")
+	}
+}
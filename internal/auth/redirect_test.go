@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectValidator_Validate(t *testing.T) {
+	validator := NewRedirectValidator([]string{"https://ui.example.com", "HTTP://localhost:3000/"})
+
+	tests := []struct {
+		name    string
+		target  string
+		allowed bool
+	}{
+		{name: "allowed origin", target: "https://ui.example.com/dashboard?tab=clusters", allowed: true},
+		{name: "allowed origin with different case", target: "https://UI.example.com/", allowed: true},
+		{name: "allowed origin with port", target: "http://localhost:3000/callback", allowed: true},
+		{name: "relative path", target: "/dashboard", allowed: true},
+		{name: "empty target", target: "", allowed: false},
+		{name: "unknown host", target: "https://evil.example.com/login", allowed: false},
+		{name: "allowed host as suffix", target: "https://ui.example.com.evil.com/", allowed: false},
+		{name: "different scheme", target: "http://ui.example.com/", allowed: false},
+		{name: "different port", target: "http://localhost:4000/", allowed: false},
+		{name: "protocol-relative URL", target: "//evil.example.com/", allowed: false},
+		{name: "backslash trick", target: "/\\evil.example.com", allowed: false},
+		{name: "javascript URL", target: "javascript:alert(1)", allowed: false},
+		{name: "userinfo on allowed origin", target: "https://attacker@ui.example.com/", allowed: false},
+		{name: "path without leading slash", target: "dashboard", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(tt.target)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrRedirectNotAllowed)
+			}
+		})
+	}
+}
+
+func TestRedirectValidator_EmptyAllowlist(t *testing.T) {
+	validator := NewRedirectValidator(nil)
+
+	assert.NoError(t, validator.Validate("/dashboard"))
+	assert.ErrorIs(t, validator.Validate("https://ui.example.com/"), ErrRedirectNotAllowed)
+}
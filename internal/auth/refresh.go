@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/user"
+)
+
+// Refresh token rotation errors
+var (
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrRefreshTokenRevoked = errors.New("refresh token has been revoked")
+)
+
+// refreshFamily tracks the refresh tokens rotated from a single login. Only
+// the latest token of a family may be exchanged; presenting an earlier one
+// means it was copied, so the whole family is revoked.
+type refreshFamily struct {
+	UserID  string `json:"user_id"`
+	Current string `json:"current"` // ID of the latest refresh token
+	Revoked bool   `json:"revoked"`
+}
+
+// refreshFamilyKey returns the cache key of a refresh token family
+func refreshFamilyKey(family string) string {
+	return "refresh_family:" + family
+}
+
+// SetRefreshTokenFamilies enables refresh token reuse detection, tracking
+// token families in cache. Without it refresh tokens are rotated but an
+// already rotated token stays usable until it expires.
+func (s *Service) SetRefreshTokenFamilies(cache repo.Cache) {
+	s.refreshFamilies = cache
+}
+
+// issueRefreshToken issues a refresh token for u starting a new family, as on
+// login.
+func (s *Service) issueRefreshToken(ctx context.Context, u *user.User) (string, error) {
+	if s.refreshFamilies == nil {
+		token, _, err := s.generateRefreshToken(u, "")
+		return token, err
+	}
+
+	family := uuid.New().String()
+	token, id, err := s.generateRefreshToken(u, family)
+	if err != nil {
+		return "", err
+	}
+
+	// The family outlives every token issued in it, since each expires within
+	// the refresh token duration of being issued
+	record := refreshFamily{UserID: u.ID.String(), Current: id}
+	created, err := s.refreshFamilies.CompareAndSwap(ctx, refreshFamilyKey(family), nil, record, s.jwtManager.refreshTokenDuration)
+	if err != nil {
+		return "", fmt.Errorf("failed to store refresh token family: %w", err)
+	}
+	if !created {
+		return "", fmt.Errorf("refresh token family %s already exists", family)
+	}
+	return token, nil
+}
+
+// rotateRefreshToken issues the refresh token following the one of claims in
+// its family. The family record is swapped only while the presented token is
+// still its latest, so of two concurrent refreshes with the same token only
+// one succeeds and the other is handled as reuse.
+func (s *Service) rotateRefreshToken(ctx context.Context, u *user.User, claims *Claims, ipAddress, userAgent string) (string, error) {
+	if s.refreshFamilies == nil {
+		token, _, err := s.generateRefreshToken(u, "")
+		return token, err
+	}
+
+	token, id, err := s.generateRefreshToken(u, claims.Family)
+	if err != nil {
+		return "", err
+	}
+
+	previous := refreshFamily{UserID: claims.UserID, Current: claims.ID}
+	next := refreshFamily{UserID: claims.UserID, Current: id}
+	rotated, err := s.refreshFamilies.CompareAndSwap(ctx, refreshFamilyKey(claims.Family), previous, next, s.jwtManager.refreshTokenDuration)
+	if err != nil {
+		return "", fmt.Errorf("failed to store refresh token family: %w", err)
+	}
+	if !rotated {
+		// The token was exchanged, or its family revoked, since it was checked
+		return "", s.revokeRefreshFamily(ctx, claims, ipAddress, userAgent)
+	}
+	return token, nil
+}
+
+// generateRefreshToken generates a refresh token for u in family, returning
+// the token and its ID
+func (s *Service) generateRefreshToken(u *user.User, family string) (string, string, error) {
+	return s.jwtManager.GenerateRefreshToken(u.ID.String(), u.Username, u.Email, rolesToStrings(u.Roles), family)
+}
+
+// checkRefreshToken verifies that a refresh token is the latest of its
+// family. A rotated token being presented again revokes its family, so both
+// the thief and the legitimate user have to authenticate again.
+func (s *Service) checkRefreshToken(ctx context.Context, claims *Claims, ipAddress, userAgent string) error {
+	if s.refreshFamilies == nil {
+		return nil
+	}
+	if claims.Family == "" {
+		return fmt.Errorf("%w: token has no family", ErrRefreshTokenRevoked)
+	}
+
+	var record refreshFamily
+	if err := s.refreshFamilies.Get(ctx, refreshFamilyKey(claims.Family), &record); err != nil {
+		if errors.Is(err, repo.ErrCacheMiss) {
+			return ErrRefreshTokenRevoked
+		}
+		return fmt.Errorf("failed to get refresh token family: %w", err)
+	}
+
+	if record.Revoked || record.UserID != claims.UserID {
+		return ErrRefreshTokenRevoked
+	}
+	if record.Current == claims.ID {
+		return nil
+	}
+	return s.revokeRefreshFamily(ctx, claims, ipAddress, userAgent)
+}
+
+// revokeRefreshFamily revokes the family of a reused refresh token and
+// returns ErrRefreshTokenReused
+func (s *Service) revokeRefreshFamily(ctx context.Context, claims *Claims, ipAddress, userAgent string) error {
+	// A revoked family stays revoked, so overwriting a newer rotation is safe
+	record := refreshFamily{UserID: claims.UserID, Revoked: true}
+	if err := s.refreshFamilies.Set(ctx, refreshFamilyKey(claims.Family), record, s.jwtManager.refreshTokenDuration); err != nil {
+		s.logger.Error("Failed to revoke refresh token family", zap.String("family", claims.Family), zap.Error(err))
+	}
+
+	s.logger.Warn("Refresh token reuse detected, revoked token family",
+		zap.String("user_id", claims.UserID),
+		zap.String("family", claims.Family),
+		zap.String("ip", ipAddress))
+	s.logAuditEvent(ctx, claims.UserID, "refresh_token_reuse", "user", claims.UserID, &repo.Payload{"family": claims.Family}, nil, ipAddress, userAgent)
+
+	return ErrRefreshTokenReused
+}
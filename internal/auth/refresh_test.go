@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+	"github.com/rizesky/mckmt/internal/user"
+)
+
+// newMapCache returns a cache mock storing values as JSON in a map
+func newMapCache(ctrl *gomock.Controller) (*repomocks.MockCache, map[string][]byte) {
+	var mu sync.Mutex
+	entries := map[string][]byte{}
+	cache := repomocks.NewMockCache(ctrl)
+	cache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, key string, value interface{}, _ time.Duration) error {
+			mu.Lock()
+			defer mu.Unlock()
+			data, err := json.Marshal(value)
+			entries[key] = data
+			return err
+		}).AnyTimes()
+	cache.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, key string, dest interface{}) error {
+			mu.Lock()
+			defer mu.Unlock()
+			data, ok := entries[key]
+			if !ok {
+				return repo.ErrCacheMiss
+			}
+			return json.Unmarshal(data, dest)
+		}).AnyTimes()
+	cache.EXPECT().CompareAndSwap(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, key string, expected, value interface{}, _ time.Duration) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			current, ok := entries[key]
+			if expected == nil {
+				if ok {
+					return false, nil
+				}
+			} else {
+				data, err := json.Marshal(expected)
+				if err != nil || !ok || string(data) != string(current) {
+					return false, err
+				}
+			}
+			data, err := json.Marshal(value)
+			entries[key] = data
+			return err == nil, err
+		}).AnyTimes()
+	return cache, entries
+}
+
+// newRefreshTestService returns a service with reuse detection enabled and
+// the refresh token of a fresh login of its only user
+func newRefreshTestService(t *testing.T, ctrl *gomock.Controller) (*Service, map[string][]byte, string) {
+	t.Helper()
+
+	passwordManager := NewPasswordManager(nil)
+	hash, err := passwordManager.HashPassword("Sup3r-secret")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	account := &user.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", PasswordHash: hash, AuthSource: user.AuthSourcePassword, Active: true}
+
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().GetByUsername(gomock.Any(), "alice").Return(account, nil).AnyTimes()
+	userRepo.EXPECT().GetByID(gomock.Any(), account.ID).Return(account, nil).AnyTimes()
+
+	cache, entries := newMapCache(ctrl)
+	service := NewAuthService(userRepo, nil, nil, nil, NewJWTManager("secret", time.Hour), passwordManager, nil, nil, "viewer", zap.NewNop())
+	service.SetRefreshTokenFamilies(cache)
+
+	login, err := service.Login(context.Background(), &LoginRequest{Username: "alice", Password: "Sup3r-secret"}, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return service, entries, login.RefreshToken
+}
+
+func TestService_RefreshTokenRotation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, entries, token := newRefreshTestService(t, ctrl)
+
+	// Every refresh returns a new token of the same family
+	for i := 0; i < 3; i++ {
+		resp, err := service.RefreshToken(context.Background(), &RefreshTokenRequest{RefreshToken: token}, "127.0.0.1", "test")
+		if err != nil {
+			t.Fatalf("Expected refresh %d to succeed but got: %v", i, err)
+		}
+		if resp.RefreshToken == token {
+			t.Fatalf("Expected refresh %d to rotate the refresh token", i)
+		}
+		token = resp.RefreshToken
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("Expected a single token family but got %d", len(entries))
+	}
+}
+
+func TestService_RefreshTokenReuseRevokesFamily(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, _, stolen := newRefreshTestService(t, ctrl)
+
+	// The legitimate client rotates the token
+	resp, err := service.RefreshToken(context.Background(), &RefreshTokenRequest{RefreshToken: stolen}, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	current := resp.RefreshToken
+
+	// The rotated token is replayed
+	_, err = service.RefreshToken(context.Background(), &RefreshTokenRequest{RefreshToken: stolen}, "10.0.0.1", "attacker")
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused but got: %v", err)
+	}
+
+	// The whole family is revoked, forcing the user to log in again
+	_, err = service.RefreshToken(context.Background(), &RefreshTokenRequest{RefreshToken: current}, "127.0.0.1", "test")
+	if !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Fatalf("Expected ErrRefreshTokenRevoked but got: %v", err)
+	}
+
+	// A new login starts a new family
+	login, err := service.Login(context.Background(), &LoginRequest{Username: "alice", Password: "Sup3r-secret"}, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := service.RefreshToken(context.Background(), &RefreshTokenRequest{RefreshToken: login.RefreshToken}, "127.0.0.1", "test"); err != nil {
+		t.Errorf("Expected the new family to refresh but got: %v", err)
+	}
+}
+
+func TestService_ConcurrentRefreshRotatesOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, entries, token := newRefreshTestService(t, ctrl)
+
+	claims, err := service.jwtManager.ValidateToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// The same token is presented by several requests at once
+	const refreshes = 8
+	var wg sync.WaitGroup
+	errs := make([]error, refreshes)
+	for i := 0; i < refreshes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = service.RefreshToken(context.Background(), &RefreshTokenRequest{RefreshToken: token}, "127.0.0.1", "test")
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrRefreshTokenReused) && !errors.Is(err, ErrRefreshTokenRevoked):
+			t.Errorf("Expected reuse to be detected but got: %v", err)
+		}
+	}
+	if succeeded > 1 {
+		t.Errorf("Expected at most one refresh to succeed but got %d", succeeded)
+	}
+
+	// The family ends up revoked and is never reset by a late rotation
+	var record refreshFamily
+	if err := json.Unmarshal(entries[refreshFamilyKey(claims.Family)], &record); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !record.Revoked {
+		t.Errorf("Expected the token family to be revoked but got %+v", record)
+	}
+}
+
+func TestService_RotateRefreshTokenLosingRaceRevokesFamily(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, entries, token := newRefreshTestService(t, ctrl)
+	claims, err := service.jwtManager.ValidateToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	account, err := service.userRepo.GetByID(context.Background(), uuid.MustParse(claims.UserID))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// Two requests checked the same token; the first one rotates it
+	if _, err := service.rotateRefreshToken(context.Background(), account, claims, "127.0.0.1", "test"); err != nil {
+		t.Fatalf("Expected the first rotation to succeed but got: %v", err)
+	}
+	_, err = service.rotateRefreshToken(context.Background(), account, claims, "10.0.0.1", "attacker")
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused but got: %v", err)
+	}
+
+	// A rotation of the revoked family never writes it back as usable
+	if _, err := service.rotateRefreshToken(context.Background(), account, claims, "127.0.0.1", "test"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused but got: %v", err)
+	}
+	var record refreshFamily
+	if err := json.Unmarshal(entries[refreshFamilyKey(claims.Family)], &record); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !record.Revoked {
+		t.Errorf("Expected the token family to stay revoked but got %+v", record)
+	}
+}
+
+func TestService_RefreshTokenRejectsAccessTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service, _, _ := newRefreshTestService(t, ctrl)
+
+	accessToken, err := service.jwtManager.GenerateToken(uuid.New().String(), "alice", "alice@example.com", nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	_, err = service.RefreshToken(context.Background(), &RefreshTokenRequest{RefreshToken: accessToken}, "127.0.0.1", "test")
+	if !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("Expected an access token to be rejected but got: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxRolesPerUser is the default cap on the roles of a single user
+const DefaultMaxRolesPerUser = 20
+
+// ErrTooManyRoles is returned when assigning a role would exceed the per-user cap
+var ErrTooManyRoles = errors.New("too many roles assigned to user")
+
+// SetMaxRolesPerUser caps the roles assignable to a single user, keeping
+// privileges reviewable and Casbin policies small. Zero disables the cap.
+func (s *Service) SetMaxRolesPerUser(max int) {
+	if max >= 0 {
+		s.maxRolesPerUser = max
+	}
+}
+
+// AssignRole assigns a role to a user. Assigning a role the user already has
+// is a no-op, so it never counts against the cap.
+func (s *Service) AssignRole(ctx context.Context, userID, roleID uuid.UUID, assignedBy *uuid.UUID) error {
+	roles, err := s.roleRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	for _, role := range roles {
+		if role.ID == roleID {
+			return nil
+		}
+	}
+
+	if s.maxRolesPerUser > 0 && len(roles) >= s.maxRolesPerUser {
+		s.logger.Warn("Rejected role assignment over the per-user cap",
+			zap.String("user_id", userID.String()),
+			zap.String("role_id", roleID.String()),
+			zap.Int("max_roles", s.maxRolesPerUser))
+		return fmt.Errorf("%w: user already has %d roles, the maximum is %d", ErrTooManyRoles, len(roles), s.maxRolesPerUser)
+	}
+
+	if err := s.roleRepo.AssignRoleToUser(ctx, userID, roleID, assignedBy); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+	"github.com/rizesky/mckmt/internal/user"
+)
+
+func TestService_AssignRoleEnforcesCap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	roleRepo := repomocks.NewMockRoleRepository(ctrl)
+	userID := uuid.New()
+
+	// Assigned roles are kept in memory
+	var assigned []*user.Role
+	roleRepo.EXPECT().GetUserRoles(gomock.Any(), userID).DoAndReturn(func(context.Context, uuid.UUID) ([]*user.Role, error) {
+		return assigned, nil
+	}).AnyTimes()
+	roleRepo.EXPECT().AssignRoleToUser(gomock.Any(), userID, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ uuid.UUID, roleID uuid.UUID, _ *uuid.UUID) error {
+			assigned = append(assigned, &user.Role{ID: roleID})
+			return nil
+		}).Times(2)
+
+	service := NewAuthService(nil, roleRepo, nil, nil, NewJWTManager("secret", time.Hour), NewPasswordManager(nil), nil, nil, "viewer", zap.NewNop())
+	service.SetMaxRolesPerUser(2)
+
+	// Assignments within the cap succeed
+	first, second := uuid.New(), uuid.New()
+	for _, roleID := range []uuid.UUID{first, second} {
+		if err := service.AssignRole(context.Background(), userID, roleID, nil); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	// Re-assigning a held role doesn't count against the cap
+	if err := service.AssignRole(context.Background(), userID, first, nil); err != nil {
+		t.Errorf("Expected re-assigning a role to succeed but got: %v", err)
+	}
+
+	err := service.AssignRole(context.Background(), userID, uuid.New(), nil)
+	if !errors.Is(err, ErrTooManyRoles) {
+		t.Fatalf("Expected ErrTooManyRoles but got: %v", err)
+	}
+	if len(assigned) != 2 {
+		t.Errorf("Expected 2 assigned roles but got %d", len(assigned))
+	}
+}
+
+func TestService_AssignRoleWithoutCap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	roleRepo := repomocks.NewMockRoleRepository(ctrl)
+	userID := uuid.New()
+
+	held := make([]*user.Role, DefaultMaxRolesPerUser)
+	for i := range held {
+		held[i] = &user.Role{ID: uuid.New()}
+	}
+	roleRepo.EXPECT().GetUserRoles(gomock.Any(), userID).Return(held, nil)
+	roleRepo.EXPECT().AssignRoleToUser(gomock.Any(), userID, gomock.Any(), gomock.Any()).Return(nil)
+
+	service := NewAuthService(nil, roleRepo, nil, nil, NewJWTManager("secret", time.Hour), NewPasswordManager(nil), nil, nil, "viewer", zap.NewNop())
+	service.SetMaxRolesPerUser(0)
+
+	if err := service.AssignRole(context.Background(), userID, uuid.New(), nil); err != nil {
+		t.Errorf("Expected no cap to allow the assignment but got: %v", err)
+	}
+}
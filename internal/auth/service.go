@@ -36,12 +36,22 @@ type Service struct {
 	roleRepo        repo.RoleRepository
 	permissionRepo  repo.PermissionRepository
 	auditRepo       repo.AuditLogRepository
+	apiTokenRepo    repo.APITokenRepository
 	jwtManager      *JWTManager
 	passwordManager *PasswordManager
 	oidcService     *OIDC
 	roleMapper      *RoleMapper
 	defaultRole     string
 	logger          *zap.Logger
+
+	// maxRolesPerUser caps the roles assignable to a user; zero means no cap
+	maxRolesPerUser int
+
+	// refreshFamilies tracks refresh token families for reuse detection; nil disables it
+	refreshFamilies repo.Cache
+
+	// lockout locks usernames out after repeated failed logins; nil disables it
+	lockout *loginLockout
 }
 
 // NewAuthService creates a new authentication service
@@ -68,6 +78,7 @@ func NewAuthService(
 		roleMapper:      roleMapper,
 		defaultRole:     defaultRole,
 		logger:          logger,
+		maxRolesPerUser: DefaultMaxRolesPerUser,
 	}
 }
 
@@ -147,11 +158,18 @@ func (r *RefreshTokenRequest) Validate() error {
 
 // Login authenticates a newUser and returns JWT tokens
 func (s *Service) Login(ctx context.Context, req *LoginRequest, ipAddress, userAgent string) (*LoginResponse, error) {
+	// Locked out usernames are rejected even with the right password
+	if err := s.checkLoginLockout(ctx, req.Username); err != nil {
+		s.logger.Warn("Login attempt for locked account", zap.String("username", req.Username), zap.String("ip", ipAddress))
+		return nil, err
+	}
+
 	// Get newUser by username
 	newUser, err := s.userRepo.GetByUsername(ctx, req.Username)
 	if err != nil {
 		if errors.Is(err, repo.ErrNotFound) {
 			s.logger.Warn("Login attempt with non-existent username", zap.String("username", req.Username), zap.String("ip", ipAddress))
+			s.recordLoginFailure(ctx, req.Username, ipAddress, userAgent)
 			return nil, fmt.Errorf("invalid credentials")
 		}
 		return nil, fmt.Errorf("failed to get newUser: %w", err)
@@ -182,8 +200,10 @@ func (s *Service) Login(ctx context.Context, req *LoginRequest, ipAddress, userA
 
 	if !valid {
 		s.logger.Warn("Login attempt with invalid password", zap.String("username", req.Username), zap.String("ip", ipAddress))
+		s.recordLoginFailure(ctx, req.Username, ipAddress, userAgent)
 		return nil, fmt.Errorf("invalid credentials")
 	}
+	s.resetLoginFailures(ctx, req.Username)
 
 	// Generate tokens
 	accessToken, err := s.jwtManager.GenerateToken(newUser.ID.String(), newUser.Username, newUser.Email, rolesToStrings(newUser.Roles))
@@ -191,7 +211,7 @@ func (s *Service) Login(ctx context.Context, req *LoginRequest, ipAddress, userA
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := s.jwtManager.GenerateToken(newUser.ID.String(), newUser.Username, newUser.Email, rolesToStrings(newUser.Roles))
+	refreshToken, err := s.issueRefreshToken(ctx, newUser)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -273,10 +293,16 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest, ipAddress,
 // RefreshToken generates new tokens using a refresh token
 func (s *Service) RefreshToken(ctx context.Context, req *RefreshTokenRequest, ipAddress, userAgent string) (*RefreshTokenResponse, error) {
 	// Validate refresh token
-	claims, err := s.jwtManager.ValidateToken(req.RefreshToken)
+	claims, err := s.jwtManager.ValidateToken(ctx, req.RefreshToken)
 	if err != nil {
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
+	if claims.TokenType != TokenTypeRefresh {
+		return nil, fmt.Errorf("%w: not a refresh token", ErrRefreshTokenRevoked)
+	}
+	if err := s.checkRefreshToken(ctx, claims, ipAddress, userAgent); err != nil {
+		return nil, err
+	}
 
 	// Get newUser to ensure they still exist and are active
 	userID, err := uuid.Parse(claims.UserID)
@@ -302,7 +328,7 @@ func (s *Service) RefreshToken(ctx context.Context, req *RefreshTokenRequest, ip
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	newRefreshToken, err := s.jwtManager.GenerateToken(newUser.ID.String(), newUser.Username, newUser.Email, rolesToStrings(newUser.Roles))
+	newRefreshToken, err := s.rotateRefreshToken(ctx, newUser, claims, ipAddress, userAgent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -318,8 +344,19 @@ func (s *Service) RefreshToken(ctx context.Context, req *RefreshTokenRequest, ip
 	}, nil
 }
 
-// Logout logs out a newUser (in a real implementation, you might blacklist the token)
-func (s *Service) Logout(ctx context.Context, userID, ipAddress, userAgent string) error {
+// Logout logs out a newUser, revoking their access token until it expires
+func (s *Service) Logout(ctx context.Context, userID, accessToken, ipAddress, userAgent string) error {
+	claims, err := s.jwtManager.ValidateToken(ctx, accessToken)
+	if err != nil {
+		return fmt.Errorf("invalid access token: %w", err)
+	}
+	if claims.UserID != userID {
+		return fmt.Errorf("access token belongs to another user")
+	}
+	if err := s.jwtManager.RevokeToken(ctx, claims); err != nil {
+		return err
+	}
+
 	// Log logout
 	s.logAuditEvent(ctx, userID, "logout", "newUser", userID, nil, nil, ipAddress, userAgent)
 	return nil
@@ -333,7 +370,7 @@ func (s *Service) ChangePassword(ctx context.Context, userID string, req *Change
 		return fmt.Errorf("invalid newUser ID: %w", err)
 	}
 
-	_, err = s.userRepo.GetByID(ctx, userUUID)
+	existingUser, err := s.userRepo.GetByID(ctx, userUUID)
 	if err != nil {
 		if err == repo.ErrNotFound {
 			return fmt.Errorf("newUser not found")
@@ -341,13 +378,38 @@ func (s *Service) ChangePassword(ctx context.Context, userID string, req *Change
 		return fmt.Errorf("failed to get newUser: %w", err)
 	}
 
+	// Users of an OIDC provider change their password there
+	if existingUser.AuthSource == user.AuthSourceOIDC {
+		return fmt.Errorf("password of OIDC users must be changed with the identity provider")
+	}
+	if existingUser.PasswordHash == "" {
+		return fmt.Errorf("password authentication not available for this user")
+	}
+
+	valid, err := s.passwordManager.VerifyPassword(req.CurrentPassword, existingUser.PasswordHash)
+	if err != nil {
+		s.logger.Error("Password verification failed", zap.Error(err), zap.String("user_id", userID))
+		return fmt.Errorf("password verification failed")
+	}
+	if !valid {
+		s.logger.Warn("Password change with invalid current password", zap.String("user_id", userID), zap.String("ip", ipAddress))
+		return fmt.Errorf("current password is incorrect")
+	}
+
 	// Validate new password strength
 	if err := s.passwordManager.ValidatePasswordStrength(req.NewPassword); err != nil {
 		return fmt.Errorf("password validation failed: %w", err)
 	}
 
-	// Note: In a real implementation, you would verify the current password here
-	// and update the password in the database
+	hash, err := s.passwordManager.HashPassword(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	existingUser.PasswordHash = hash
+	if err := s.userRepo.Update(ctx, existingUser); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
 
 	// Log password change
 	s.logAuditEvent(ctx, userID, "change_password", "newUser", userID, nil, nil, ipAddress, userAgent)
@@ -422,7 +484,7 @@ func (s *Service) OIDCCallback(ctx context.Context, code, state string, ipAddres
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := s.jwtManager.GenerateToken(newUser.ID.String(), newUser.Username, newUser.Email, rolesToStrings(newUser.Roles))
+	refreshToken, err := s.issueRefreshToken(ctx, newUser)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+	"github.com/rizesky/mckmt/internal/user"
+)
+
+func TestService_LogoutRevokesAccessToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	passwordManager := NewPasswordManager(nil)
+	hash, err := passwordManager.HashPassword("Sup3r-secret")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	account := &user.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", PasswordHash: hash, AuthSource: user.AuthSourcePassword, Active: true}
+
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().GetByUsername(gomock.Any(), "alice").Return(account, nil).AnyTimes()
+
+	cache, _ := newMapCache(ctrl)
+	jwtManager := NewJWTManager("secret", time.Hour)
+	jwtManager.SetRevocationStore(cache)
+	service := NewAuthService(userRepo, nil, nil, nil, jwtManager, passwordManager, nil, nil, "viewer", zap.NewNop())
+
+	ctx := context.Background()
+	login, err := service.Login(ctx, &LoginRequest{Username: "alice", Password: "Sup3r-secret"}, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if _, err := jwtManager.ValidateToken(ctx, login.AccessToken); err != nil {
+		t.Fatalf("Expected access token to validate before logout but got: %v", err)
+	}
+
+	// A token cannot be used to log out another user
+	if err := service.Logout(ctx, uuid.New().String(), login.AccessToken, "127.0.0.1", "test"); err == nil {
+		t.Fatal("Expected logout of another user to fail")
+	}
+	if _, err := jwtManager.ValidateToken(ctx, login.AccessToken); err != nil {
+		t.Fatalf("Expected access token to stay valid but got: %v", err)
+	}
+
+	if err := service.Logout(ctx, account.ID.String(), login.AccessToken, "127.0.0.1", "test"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if _, err := jwtManager.ValidateToken(ctx, login.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("Expected ErrTokenRevoked after logout but got: %v", err)
+	}
+
+	// Logging out again with the revoked token fails
+	if err := service.Logout(ctx, account.ID.String(), login.AccessToken, "127.0.0.1", "test"); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected ErrTokenRevoked but got: %v", err)
+	}
+}
+
+func TestService_ChangePassword(t *testing.T) {
+	passwordManager := NewPasswordManager(nil)
+	hash, err := passwordManager.HashPassword("Sup3r-secret")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		authSource user.AuthSource
+		current    string
+		newPass    string
+		wantErr    string
+	}{
+		{name: "wrong current password", authSource: user.AuthSourcePassword, current: "Wr0ng-secret", newPass: "N3w-secret!", wantErr: "current password is incorrect"},
+		{name: "OIDC user", authSource: user.AuthSourceOIDC, current: "Sup3r-secret", newPass: "N3w-secret!", wantErr: "identity provider"},
+		{name: "weak new password", authSource: user.AuthSourcePassword, current: "Sup3r-secret", newPass: "weak", wantErr: "password validation failed"},
+		{name: "success", authSource: user.AuthSourcePassword, current: "Sup3r-secret", newPass: "N3w-secret!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			account := &user.User{ID: uuid.New(), Username: "alice", PasswordHash: hash, AuthSource: tt.authSource, Active: true}
+			if tt.authSource == user.AuthSourceOIDC {
+				account.PasswordHash = ""
+			}
+
+			userRepo := repomocks.NewMockUserRepository(ctrl)
+			userRepo.EXPECT().GetByID(gomock.Any(), account.ID).Return(account, nil)
+
+			var updated *user.User
+			if tt.wantErr == "" {
+				userRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, u *user.User) error {
+					updated = u
+					return nil
+				})
+			} else {
+				userRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Times(0)
+			}
+
+			service := NewAuthService(userRepo, nil, nil, nil, NewJWTManager("secret", time.Hour), passwordManager, nil, nil, "viewer", zap.NewNop())
+			err := service.ChangePassword(context.Background(), account.ID.String(), &ChangePasswordRequest{CurrentPassword: tt.current, NewPassword: tt.newPass}, "127.0.0.1", "test")
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q but got: %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			// The new password is stored hashed and replaces the old one
+			if updated == nil || updated.PasswordHash == hash || updated.PasswordHash == tt.newPass {
+				t.Fatalf("Expected a new password hash to be stored, got %+v", updated)
+			}
+			valid, err := passwordManager.VerifyPassword(tt.newPass, updated.PasswordHash)
+			if err != nil || !valid {
+				t.Errorf("Expected stored hash to match the new password, got valid=%v err=%v", valid, err)
+			}
+		})
+	}
+}
+
+func TestService_RefreshTokenRequiresRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	passwordManager := NewPasswordManager(nil)
+	hash, err := passwordManager.HashPassword("Sup3r-secret")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	account := &user.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com", PasswordHash: hash, AuthSource: user.AuthSourcePassword, Active: true}
+
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().GetByUsername(gomock.Any(), "alice").Return(account, nil).AnyTimes()
+	userRepo.EXPECT().GetByID(gomock.Any(), account.ID).Return(account, nil).AnyTimes()
+
+	// Without refresh token families only the token type tells the tokens apart
+	jwtManager := NewJWTManager("secret", time.Hour)
+	jwtManager.SetRefreshTokenDuration(48 * time.Hour)
+	service := NewAuthService(userRepo, nil, nil, nil, jwtManager, passwordManager, nil, nil, "viewer", zap.NewNop())
+
+	ctx := context.Background()
+	login, err := service.Login(ctx, &LoginRequest{Username: "alice", Password: "Sup3r-secret"}, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	access, err := jwtManager.ValidateToken(ctx, login.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	refresh, err := jwtManager.ValidateToken(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !refresh.ExpiresAt.After(access.ExpiresAt.Add(24 * time.Hour)) {
+		t.Errorf("Expected the refresh token to outlive the access token, got %v and %v", refresh.ExpiresAt, access.ExpiresAt)
+	}
+
+	if _, err := service.RefreshToken(ctx, &RefreshTokenRequest{RefreshToken: login.AccessToken}, "127.0.0.1", "test"); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("Expected the access token to be rejected but got: %v", err)
+	}
+	if _, err := service.RefreshToken(ctx, &RefreshTokenRequest{RefreshToken: login.RefreshToken}, "127.0.0.1", "test"); err != nil {
+		t.Errorf("Expected the refresh token to be accepted but got: %v", err)
+	}
+}
@@ -1,11 +1,53 @@
 package auth
 
+import "strings"
+
 // AuthenticatedUser represents the authenticated user in the system
 type AuthenticatedUser struct {
 	ID       string   `json:"id"`
 	Username string   `json:"username"`
 	Email    string   `json:"email"`
 	Roles    []string `json:"roles"`
+
+	// TokenID, Scopes and Clusters are set when the user authenticated with a
+	// personal access token; nil Scopes leave the user's permissions
+	// unrestricted and empty Clusters allow every cluster
+	TokenID  string   `json:"token_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	Clusters []string `json:"clusters,omitempty"`
+}
+
+// ViaAPIToken reports whether the user authenticated with a personal access token
+func (u *AuthenticatedUser) ViaAPIToken() bool {
+	return u.TokenID != ""
+}
+
+// ClusterScoped reports whether the user's credential is limited to some clusters
+func (u *AuthenticatedUser) ClusterScoped() bool {
+	return len(u.Clusters) > 0
+}
+
+// InClusterScope reports whether the user's credential covers the cluster
+func (u *AuthenticatedUser) InClusterScope(clusterID string) bool {
+	if !u.ClusterScoped() {
+		return true
+	}
+	for _, allowed := range u.Clusters {
+		if strings.EqualFold(allowed, clusterID) {
+			return true
+		}
+	}
+	return false
+}
+
+// InScope reports whether the user's credential covers the action on the
+// resource. It only narrows what the user may do; permissions are still
+// checked against the user's roles.
+func (u *AuthenticatedUser) InScope(resource, action string) bool {
+	if u.Scopes == nil {
+		return true
+	}
+	return scopeAllows(u.Scopes, resource, action)
 }
 
 // HasRole checks if the user has a specific role
@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// AdmissionHook reviews operations before they are created, e.g. against the
+// policies of a policy engine
+type AdmissionHook interface {
+	// Review returns the reasons the operation is rejected for, none when it
+	// is admitted. An error means the operation could not be reviewed.
+	Review(ctx context.Context, operation *repo.Operation) ([]string, error)
+}
+
+// SetAdmissionHooks sets the hooks every operation must pass before it is
+// created and queued. Operations are admitted without any.
+func (s *Service) SetAdmissionHooks(hooks ...AdmissionHook) {
+	s.admissionHooks = hooks
+}
+
+// admit runs the operation through the admission hooks, rejecting it with the
+// reasons of the first hook that denies it
+func (s *Service) admit(ctx context.Context, operation *repo.Operation) error {
+	for _, hook := range s.admissionHooks {
+		reasons, err := hook.Review(ctx, operation)
+		if err != nil {
+			return fmt.Errorf("failed to review operation: %w", err)
+		}
+		if len(reasons) > 0 {
+			return fmt.Errorf("%w: %s", ErrOperationRejected, strings.Join(reasons, "; "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	clustermocks "github.com/rizesky/mckmt/internal/cluster/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+// reviewFunc is an admission hook calling a function
+type reviewFunc func(ctx context.Context, operation *repo.Operation) ([]string, error)
+
+func (f reviewFunc) Review(ctx context.Context, operation *repo.Operation) ([]string, error) {
+	return f(ctx, operation)
+}
+
+func TestClusterService_CreateOperationAdmissionHooks(t *testing.T) {
+	admit := reviewFunc(func(context.Context, *repo.Operation) ([]string, error) { return nil, nil })
+	deny := reviewFunc(func(context.Context, *repo.Operation) ([]string, error) {
+		return []string{"Pod debug runs privileged container shell"}, nil
+	})
+	fail := reviewFunc(func(context.Context, *repo.Operation) ([]string, error) {
+		return nil, errors.New("policy engine unavailable")
+	})
+
+	tests := []struct {
+		name          string
+		hooks         []AdmissionHook
+		expectCreate  bool
+		expectedError error
+	}{
+		{name: "no hooks", expectCreate: true},
+		{name: "admitted", hooks: []AdmissionHook{admit}, expectCreate: true},
+		{name: "rejected", hooks: []AdmissionHook{admit, deny}, expectedError: ErrOperationRejected},
+		{name: "review failed", hooks: []AdmissionHook{fail}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+			mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+
+			operation := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeApply, Status: "queued", Payload: repo.Payload{}}
+			mockClusterRepo.EXPECT().GetByID(gomock.Any(), operation.ClusterID).Return(&repo.Cluster{ID: operation.ClusterID}, nil)
+			if tt.expectCreate {
+				mockOpRepo.EXPECT().Create(gomock.Any(), operation).Return(nil)
+			}
+
+			service := NewService(mockClusterRepo, mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+			service.SetAdmissionHooks(tt.hooks...)
+
+			err := service.CreateOperation(context.Background(), operation)
+			switch {
+			case tt.expectCreate && err != nil:
+				t.Fatalf("Expected no error but got: %v", err)
+			case !tt.expectCreate && err == nil:
+				t.Fatal("Expected the operation not to be created")
+			case tt.expectedError != nil && !errors.Is(err, tt.expectedError):
+				t.Errorf("Expected error %v but got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// ListClusterLogs returns the logs the agent of a cluster streamed, newest
+// first. With a level, only entries at least that severe are returned.
+func (s *Service) ListClusterLogs(ctx context.Context, clusterID uuid.UUID, level string, limit, offset int) ([]*repo.LogEntry, error) {
+	filter, err := s.logFilter(level)
+	if err != nil {
+		return nil, err
+	}
+	return s.logRepo.List(ctx, clusterID, filter, limit, offset)
+}
+
+// CountClusterLogs counts the logs ListClusterLogs returns across all pages
+func (s *Service) CountClusterLogs(ctx context.Context, clusterID uuid.UUID, level string) (int, error) {
+	filter, err := s.logFilter(level)
+	if err != nil {
+		return 0, err
+	}
+	return s.logRepo.Count(ctx, clusterID, filter)
+}
+
+// logFilter builds the filter of a cluster logs query
+func (s *Service) logFilter(level string) (repo.LogFilter, error) {
+	if s.logRepo == nil {
+		return repo.LogFilter{}, ErrLogsUnavailable
+	}
+	if level == "" {
+		return repo.LogFilter{}, nil
+	}
+	levels, ok := repo.LogLevelsFrom(strings.ToLower(level))
+	if !ok {
+		return repo.LogFilter{}, fmt.Errorf("%w: %q, must be one of %s", ErrInvalidLogLevel, level, strings.Join(repo.LogLevels, ", "))
+	}
+	return repo.LogFilter{Levels: levels}, nil
+}
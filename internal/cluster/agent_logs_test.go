@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	clustermocks "github.com/rizesky/mckmt/internal/cluster/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestClusterService_ListClusterLogs(t *testing.T) {
+	clusterID := uuid.New()
+
+	tests := []struct {
+		name           string
+		level          string
+		expectedLevels []string
+		expectedErr    error
+	}{
+		{name: "all levels", level: ""},
+		{name: "minimum level", level: "WARN", expectedLevels: []string{"warn", "error", "dpanic", "panic", "fatal"}},
+		{name: "unknown level", level: "verbose", expectedErr: ErrInvalidLogLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockLogRepo := mocks.NewMockLogRepository(ctrl)
+			service := NewService(mocks.NewMockClusterRepository(ctrl), mocks.NewMockOperationRepository(ctrl), mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+			service.SetLogRepository(mockLogRepo)
+
+			if tt.expectedErr == nil {
+				filter := repo.LogFilter{Levels: tt.expectedLevels}
+				mockLogRepo.EXPECT().List(gomock.Any(), clusterID, filter, 10, 20).Return([]*repo.LogEntry{{Message: "started"}}, nil)
+			}
+
+			logs, err := service.ListClusterLogs(context.Background(), clusterID, tt.level, 10, 20)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected error %v but got: %v", tt.expectedErr, err)
+			}
+			if tt.expectedErr == nil && len(logs) != 1 {
+				t.Errorf("Expected 1 log entry but got %d", len(logs))
+			}
+		})
+	}
+}
+
+func TestClusterService_ListClusterLogsWithoutRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := NewService(mocks.NewMockClusterRepository(ctrl), mocks.NewMockOperationRepository(ctrl), mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+
+	if _, err := service.ListClusterLogs(context.Background(), uuid.New(), "", 10, 0); !errors.Is(err, ErrLogsUnavailable) {
+		t.Errorf("Expected ErrLogsUnavailable but got: %v", err)
+	}
+	if _, err := service.CountClusterLogs(context.Background(), uuid.New(), ""); !errors.Is(err, ErrLogsUnavailable) {
+		t.Errorf("Expected ErrLogsUnavailable but got: %v", err)
+	}
+}
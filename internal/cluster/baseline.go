@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// GetClusterBaseline returns the desired inventory of a cluster
+func (s *Service) GetClusterBaseline(ctx context.Context, clusterID uuid.UUID) (*repo.ClusterBaseline, error) {
+	if s.baselineRepo == nil {
+		return nil, ErrBaselineUnavailable
+	}
+
+	baseline, err := s.baselineRepo.Get(ctx, clusterID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, ErrBaselineNotFound
+	}
+	return baseline, err
+}
+
+// SetClusterBaseline replaces the desired inventory sync operations of a
+// cluster detect drift against
+func (s *Service) SetClusterBaseline(ctx context.Context, clusterID uuid.UUID, resources []repo.InventoryEntry) (*repo.ClusterBaseline, error) {
+	if s.baselineRepo == nil {
+		return nil, ErrBaselineUnavailable
+	}
+
+	for _, entry := range resources {
+		if entry.Kind == "" || entry.Name == "" || entry.APIVersion == "" {
+			return nil, fmt.Errorf("%w: resources need an api_version, kind and name", ErrBaselineInvalid)
+		}
+	}
+
+	if _, err := s.clusterRepo.GetByID(ctx, clusterID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, ErrClusterNotFound
+		}
+		return nil, err
+	}
+
+	baseline := &repo.ClusterBaseline{
+		ClusterID: clusterID,
+		Resources: resources,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if err := s.baselineRepo.Set(ctx, baseline); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// attachBaseline adds the cluster's desired inventory to sync operations that
+// don't carry one, so the agent reports drift against it
+func (s *Service) attachBaseline(ctx context.Context, operation *repo.Operation) error {
+	if operation.Type != repo.OperationTypeSync || s.baselineRepo == nil {
+		return nil
+	}
+	if _, ok := operation.Payload[repo.PayloadDesiredInventory]; ok {
+		return nil
+	}
+
+	baseline, err := s.baselineRepo.Get(ctx, operation.ClusterID)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get cluster baseline: %w", err)
+	}
+
+	if operation.Payload == nil {
+		operation.Payload = repo.Payload{}
+	}
+	operation.Payload[repo.PayloadDesiredInventory] = baseline.Resources
+	return nil
+}
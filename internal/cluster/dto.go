@@ -56,4 +56,8 @@ type GetClusterResourcesResponse struct {
 	Kind       string                   `json:"kind"`
 	Namespace  string                   `json:"namespace"`
 	Cached     bool                     `json:"cached"`
+	// Stale marks the last known resources, served on request while the
+	// cluster agent is not connected; AsOf tells when they were listed
+	Stale bool       `json:"stale,omitempty"`
+	AsOf  *time.Time `json:"as_of,omitempty"`
 }
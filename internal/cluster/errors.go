@@ -16,4 +16,19 @@ var (
 	ErrClusterLabelsInvalid        = errors.New("invalid cluster labels")
 	ErrClusterResourcesNotFound    = errors.New("cluster resources not found")
 	ErrClusterResourcesUnavailable = errors.New("cluster resources unavailable")
+	ErrOperationNotSupported       = errors.New("operation type not supported by cluster agent")
+	ErrOperationPayloadTooLarge    = errors.New("operation payload too large")
+	ErrInvalidManifests            = errors.New("invalid manifests")
+	ErrInvalidProvenance           = errors.New("invalid operation provenance")
+	ErrTooManyManifestDocuments    = errors.New("too many manifest documents")
+	ErrBaselineNotFound            = errors.New("cluster baseline not found")
+	ErrBaselineUnavailable         = errors.New("cluster baselines are not configured")
+	ErrBaselineInvalid             = errors.New("invalid cluster baseline")
+	ErrClusterQuarantined          = errors.New("cluster is quarantined")
+	ErrInvalidQuarantine           = errors.New("invalid cluster quarantine")
+	ErrOutputUnavailable           = errors.New("operation output is not configured")
+	ErrLogsUnavailable             = errors.New("cluster logs are not configured")
+	ErrInvalidLogLevel             = errors.New("invalid log level")
+	ErrOperationRejected           = errors.New("operation rejected by policy")
+	ErrResourceKindRequired        = errors.New("resource kind is required")
 )
@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/rizesky/mckmt/internal/auth"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// Impersonation returns the Kubernetes user and groups to impersonate for
+// requests made on behalf of the user in ctx. It reports false when the cluster
+// does not impersonate users or the request is not made by an mckmt user.
+func Impersonation(ctx context.Context, cluster *repo.Cluster) (string, []string, bool) {
+	if !cluster.ImpersonatesUsers() {
+		return "", nil, false
+	}
+
+	user, ok := auth.GetUserFromContext(ctx)
+	if !ok || user.Username == "" {
+		return "", nil, false
+	}
+
+	groups := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		groups = append(groups, repo.ImpersonationGroupPrefix+role)
+	}
+	return user.Username, groups, true
+}
+
+// setImpersonation records in the operation payload who the agent should
+// impersonate when running it, if the cluster impersonates users
+func setImpersonation(ctx context.Context, cluster *repo.Cluster, operation *repo.Operation) {
+	user, groups, ok := Impersonation(ctx, cluster)
+	if !ok {
+		return
+	}
+
+	if operation.Payload == nil {
+		operation.Payload = repo.Payload{}
+	}
+	operation.Payload[repo.PayloadImpersonate] = map[string]interface{}{
+		"user":   user,
+		"groups": groups,
+	}
+}
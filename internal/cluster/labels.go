@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// LabelLimits bounds the labels that can be set on a cluster. A zero limit
+// disables the corresponding check.
+type LabelLimits struct {
+	MaxCount          int
+	MaxKeyLength      int
+	MaxValueLength    int
+	EnforceKubeSyntax bool
+}
+
+// DefaultLabelLimits returns the limits used when none are configured
+func DefaultLabelLimits() LabelLimits {
+	return LabelLimits{
+		MaxCount:       64,
+		MaxKeyLength:   128,
+		MaxValueLength: 256,
+	}
+}
+
+// Validate checks labels against the limits, returning an error wrapping
+// ErrClusterLabelsInvalid that describes the first violation
+func (l LabelLimits) Validate(labels map[string]string) error {
+	if l.MaxCount > 0 && len(labels) > l.MaxCount {
+		return fmt.Errorf("%w: %d labels exceeds the maximum of %d", ErrClusterLabelsInvalid, len(labels), l.MaxCount)
+	}
+
+	// Check keys in a stable order so the reported violation is deterministic
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := labels[key]
+
+		if key == "" {
+			return fmt.Errorf("%w: label key must not be empty", ErrClusterLabelsInvalid)
+		}
+		if l.MaxKeyLength > 0 && len(key) > l.MaxKeyLength {
+			return fmt.Errorf("%w: label key %q exceeds the maximum length of %d", ErrClusterLabelsInvalid, key, l.MaxKeyLength)
+		}
+		if l.MaxValueLength > 0 && len(value) > l.MaxValueLength {
+			return fmt.Errorf("%w: value of label %q exceeds the maximum length of %d", ErrClusterLabelsInvalid, key, l.MaxValueLength)
+		}
+
+		if !l.EnforceKubeSyntax {
+			continue
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("%w: label key %q: %s", ErrClusterLabelsInvalid, key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("%w: value of label %q: %s", ErrClusterLabelsInvalid, key, strings.Join(errs, "; "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelLimits_Validate(t *testing.T) {
+	limits := LabelLimits{MaxCount: 2, MaxKeyLength: 10, MaxValueLength: 5}
+	kubeLimits := LabelLimits{EnforceKubeSyntax: true}
+
+	tests := []struct {
+		name   string
+		limits LabelLimits
+		labels map[string]string
+		valid  bool
+	}{
+		{name: "within limits", limits: limits, labels: map[string]string{"env": "prod", "team": "core"}, valid: true},
+		{name: "no labels", limits: limits, labels: nil, valid: true},
+		{name: "too many labels", limits: limits, labels: map[string]string{"a": "1", "b": "2", "c": "3"}},
+		{name: "key too long", limits: limits, labels: map[string]string{strings.Repeat("k", 11): "v"}},
+		{name: "value too long", limits: limits, labels: map[string]string{"env": "production"}},
+		{name: "empty key", limits: limits, labels: map[string]string{"": "v"}},
+		{name: "zero limits disable checks", limits: LabelLimits{}, labels: map[string]string{"free form key!": strings.Repeat("v", 1000)}, valid: true},
+		{name: "kube syntax with prefix", limits: kubeLimits, labels: map[string]string{"example.com/env": "prod-1"}, valid: true},
+		{name: "kube syntax invalid key", limits: kubeLimits, labels: map[string]string{"bad key": "prod"}},
+		{name: "kube syntax invalid value", limits: kubeLimits, labels: map[string]string{"env": "prod/eu"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Validate(tt.labels)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrClusterLabelsInvalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// podLogsPollInterval is how often StreamOperationOutput checks for new output
+var podLogsPollInterval = 500 * time.Millisecond
+
+// podLogsReadSize bounds the output read per poll
+const podLogsReadSize = 64 * 1024
+
+// StartPodLogs queues a pod_logs operation that has the cluster agent tail the
+// logs of the pods matching the request's selector
+func (s *Service) StartPodLogs(ctx context.Context, clusterID uuid.UUID, request *repo.PodLogsRequest) (*repo.Operation, error) {
+	if s.outputRepo == nil {
+		return nil, ErrOutputUnavailable
+	}
+	if _, err := repo.ParsePodLogsRequest(request.Payload()); err != nil {
+		return nil, err
+	}
+
+	operation := &repo.Operation{
+		ID:           uuid.New(),
+		ClusterID:    clusterID,
+		Type:         repo.OperationTypePodLogs,
+		Status:       "queued",
+		Payload:      request.Payload(),
+		InitiatedVia: repo.InitiatedViaHTTPAPI,
+	}
+
+	if err := s.CreateOperation(ctx, operation); err != nil {
+		return nil, err
+	}
+	if err := s.QueueOperation(ctx, operation); err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+// StreamOperationOutput copies the output of an operation to w as it is
+// reported, until the operation finishes. When ctx is cancelled first, e.g.
+// because the client went away, the operation is cancelled so the agent stops
+// producing output nobody reads.
+func (s *Service) StreamOperationOutput(ctx context.Context, operationID uuid.UUID, w io.Writer) error {
+	if s.outputRepo == nil {
+		return ErrOutputUnavailable
+	}
+
+	ticker := time.NewTicker(podLogsPollInterval)
+	defer ticker.Stop()
+
+	var offset int64
+	for {
+		// Read the status before the output so no output reported before the
+		// operation finished is missed
+		operation, err := s.operationRepo.GetByID(ctx, operationID)
+		if err != nil {
+			if ctx.Err() != nil {
+				s.stopOperation(operationID)
+				return nil
+			}
+			return fmt.Errorf("failed to get operation: %w", err)
+		}
+
+		for {
+			data, size, err := s.outputRepo.Read(ctx, operationID, offset, podLogsReadSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					s.stopOperation(operationID)
+					return nil
+				}
+				return fmt.Errorf("failed to read operation output: %w", err)
+			}
+			if len(data) > 0 {
+				if _, err := w.Write(data); err != nil {
+					s.stopOperation(operationID)
+					return err
+				}
+				offset += int64(len(data))
+			}
+			if offset >= size || len(data) == 0 {
+				break
+			}
+		}
+
+		if !isActiveOperation(operation.Status) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			s.stopOperation(operationID)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopOperation cancels an operation whose output is no longer streamed. It
+// runs detached from the request context, which is usually already cancelled.
+func (s *Service) stopOperation(operationID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.operationRepo.CancelOperation(ctx, operationID, "log stream closed"); err != nil {
+		// The operation may have finished in the meantime
+		s.logger.Warn("Failed to cancel operation", zap.String("operation_id", operationID.String()), zap.Error(err))
+		return
+	}
+	if err := s.orchestrator.CancelOperation(operationID); err != nil {
+		s.logger.Warn("Failed to request orchestrator cancellation",
+			zap.String("operation_id", operationID.String()),
+			zap.Error(err))
+	}
+	if err := s.cache.Delete(ctx, s.cache.OperationKey(operationID.String())); err != nil {
+		s.logger.Warn("Failed to invalidate operation cache", zap.Error(err))
+	}
+}
@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	clustermocks "github.com/rizesky/mckmt/internal/cluster/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestClusterService_StartPodLogs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockOrchestrator := clustermocks.NewMockOrchestratorInterface(ctrl)
+
+	clusterID := uuid.New()
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).Return(&repo.Cluster{ID: clusterID}, nil)
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+	mockOrchestrator.EXPECT().QueueOperation(gomock.Any()).Return(nil)
+
+	service := NewService(mockClusterRepo, mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), mockOrchestrator)
+
+	if _, err := service.StartPodLogs(context.Background(), clusterID, &repo.PodLogsRequest{Selector: "app=web"}); !errors.Is(err, ErrOutputUnavailable) {
+		t.Errorf("Expected ErrOutputUnavailable without an output repository but got: %v", err)
+	}
+
+	service.SetOutputRepository(mocks.NewMockOperationOutputRepository(ctrl))
+
+	if _, err := service.StartPodLogs(context.Background(), clusterID, &repo.PodLogsRequest{}); !errors.Is(err, repo.ErrInvalidPodLogsRequest) {
+		t.Errorf("Expected ErrInvalidPodLogsRequest without a selector but got: %v", err)
+	}
+
+	operation, err := service.StartPodLogs(context.Background(), clusterID, &repo.PodLogsRequest{Selector: "app=web", Follow: true})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if operation.Type != repo.OperationTypePodLogs {
+		t.Errorf("Expected operation type %s but got %s", repo.OperationTypePodLogs, operation.Type)
+	}
+	if operation.Payload["selector"] != "app=web" || operation.Payload["follow"] != true {
+		t.Errorf("Expected the selector and follow in the payload but got %v", operation.Payload)
+	}
+}
+
+func TestClusterService_StreamOperationOutput(t *testing.T) {
+	interval := podLogsPollInterval
+	podLogsPollInterval = time.Millisecond
+	defer func() { podLogsPollInterval = interval }()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockOutputRepo := mocks.NewMockOperationOutputRepository(ctrl)
+
+	operationID := uuid.New()
+	gomock.InOrder(
+		mockOpRepo.EXPECT().GetByID(gomock.Any(), operationID).Return(&repo.Operation{ID: operationID, Status: string(repo.OperationStatusRunning)}, nil),
+		mockOutputRepo.EXPECT().Read(gomock.Any(), operationID, int64(0), podLogsReadSize).Return([]byte("[web-1] a\n"), int64(10), nil),
+		mockOpRepo.EXPECT().GetByID(gomock.Any(), operationID).Return(&repo.Operation{ID: operationID, Status: string(repo.OperationStatusSuccess)}, nil),
+		mockOutputRepo.EXPECT().Read(gomock.Any(), operationID, int64(10), podLogsReadSize).Return([]byte("[web-2] b\n"), int64(20), nil),
+	)
+
+	service := NewService(mocks.NewMockClusterRepository(ctrl), mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+	service.SetOutputRepository(mockOutputRepo)
+
+	var out bytes.Buffer
+	if err := service.StreamOperationOutput(context.Background(), operationID, &out); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if out.String() != "[web-1] a\n[web-2] b\n" {
+		t.Errorf("Expected the output of both pods but got %q", out.String())
+	}
+}
+
+func TestClusterService_StreamOperationOutputCancelsOnDisconnect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockOutputRepo := mocks.NewMockOperationOutputRepository(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockOrchestrator := clustermocks.NewMockOrchestratorInterface(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	operationID := uuid.New()
+	mockOpRepo.EXPECT().GetByID(gomock.Any(), operationID).Return(&repo.Operation{ID: operationID, Status: string(repo.OperationStatusRunning)}, nil)
+	mockOutputRepo.EXPECT().Read(gomock.Any(), operationID, int64(0), podLogsReadSize).DoAndReturn(
+		func(context.Context, uuid.UUID, int64, int) ([]byte, int64, error) {
+			// The client goes away while the operation is still running
+			cancel()
+			return nil, 0, nil
+		})
+
+	// The abandoned operation is cancelled
+	mockOpRepo.EXPECT().CancelOperation(gomock.Any(), operationID, gomock.Any()).Return(nil)
+	mockOrchestrator.EXPECT().CancelOperation(operationID).Return(nil)
+	mockCache.EXPECT().OperationKey(operationID.String()).Return("operation:" + operationID.String())
+	mockCache.EXPECT().Delete(gomock.Any(), "operation:"+operationID.String()).Return(nil)
+
+	service := NewService(mocks.NewMockClusterRepository(ctrl), mockOpRepo, mockCache, zap.NewNop(), mockOrchestrator)
+	service.SetOutputRepository(mockOutputRepo)
+
+	if err := service.StreamOperationOutput(ctx, operationID, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
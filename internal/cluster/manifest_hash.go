@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// payloadManifestSHA256 is the apply payload key holding the checksum of
+// manifests fetched by URL
+const payloadManifestSHA256 = "manifest_sha256"
+
+// SetManifestHashing sets whether apply operations record the SHA-256 of their
+// manifests, so re-applies of identical content can be told apart from changes
+func (s *Service) SetManifestHashing(enabled bool) {
+	s.manifestHashing = enabled
+}
+
+// setManifestHash records the content hash of an apply operation's manifests.
+// Inline manifests are hashed; manifests fetched by URL use their verified
+// checksum. Manifests read from a ConfigMap are only known to the agent and
+// get no hash.
+func setManifestHash(operation *repo.Operation) {
+	if operation.Type != repo.OperationTypeApply || operation.Payload == nil {
+		return
+	}
+
+	var hash string
+	if manifests, ok := operation.Payload[repo.PayloadManifests].(string); ok && manifests != "" {
+		sum := sha256.Sum256([]byte(manifests))
+		hash = hex.EncodeToString(sum[:])
+	} else if checksum, ok := operation.Payload[payloadManifestSHA256].(string); ok {
+		hash = strings.ToLower(checksum)
+	}
+
+	if hash != "" {
+		operation.Payload[repo.PayloadManifestHash] = hash
+	}
+}
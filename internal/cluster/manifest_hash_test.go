@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	clustermocks "github.com/rizesky/mckmt/internal/cluster/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestClusterService_CreateOperationRecordsManifestHash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+
+	clusterID := uuid.New()
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).Return(&repo.Cluster{ID: clusterID}, nil).AnyTimes()
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	service := NewService(mockClusterRepo, mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+
+	apply := func(payload repo.Payload) string {
+		t.Helper()
+		operation := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: "queued", Payload: payload}
+		if err := service.CreateOperation(context.Background(), operation); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		hash, _ := operation.Payload[repo.PayloadManifestHash].(string)
+		return hash
+	}
+
+	v1 := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  version: v1\n"
+	v2 := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  version: v2\n"
+
+	first := apply(repo.Payload{repo.PayloadManifests: v1})
+	reapplied := apply(repo.Payload{repo.PayloadManifests: v1})
+	changed := apply(repo.Payload{repo.PayloadManifests: v2})
+
+	if first == "" {
+		t.Fatalf("Expected a manifest hash to be recorded")
+	}
+	if reapplied != first {
+		t.Errorf("Expected identical applies to share a hash, got %s and %s", first, reapplied)
+	}
+	if changed == first {
+		t.Errorf("Expected different content to hash differently")
+	}
+
+	// Manifests fetched by URL are identified by their verified checksum
+	checksum := "ABCDEF0123"
+	if hash := apply(repo.Payload{"manifest_url": "https://example.com/app.yaml", "manifest_sha256": checksum}); hash != "abcdef0123" {
+		t.Errorf("Expected the URL checksum as hash but got %q", hash)
+	}
+
+	service.SetManifestHashing(false)
+	if hash := apply(repo.Payload{repo.PayloadManifests: v1}); hash != "" {
+		t.Errorf("Expected no hash with hashing disabled but got %q", hash)
+	}
+}
@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// DefaultMaxManifestDocuments bounds the YAML documents of an apply operation
+// when no limit is configured
+const DefaultMaxManifestDocuments = 1000
+
+// SetMaxManifestDocuments sets the maximum number of YAML documents an apply
+// operation's inline manifests may hold, so a single apply can't overwhelm an
+// agent and the database. Zero disables the limit.
+func (s *Service) SetMaxManifestDocuments(max int) {
+	if max >= 0 {
+		s.maxManifestDocuments = max
+	}
+}
+
+// checkManifestDocuments rejects an apply operation whose inline manifests hold
+// more documents than allowed. Manifests fetched by the agent are not known
+// here and are not counted.
+func (s *Service) checkManifestDocuments(operation *repo.Operation) error {
+	if s.maxManifestDocuments == 0 || operation.Type != repo.OperationTypeApply {
+		return nil
+	}
+	manifests, ok := operation.Payload[repo.PayloadManifests].(string)
+	if !ok || manifests == "" {
+		return nil
+	}
+
+	count, err := kube.CountDocuments([]byte(manifests))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidManifests, err)
+	}
+	if count > s.maxManifestDocuments {
+		return fmt.Errorf("%w: %d documents exceeds the maximum of %d", ErrTooManyManifestDocuments, count, s.maxManifestDocuments)
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ package mocks
 import (
 	reflect "reflect"
 
+	uuid "github.com/google/uuid"
 	repo "github.com/rizesky/mckmt/internal/repo"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,6 +41,20 @@ func (m *MockOrchestratorInterface) EXPECT() *MockOrchestratorInterfaceMockRecor
 	return m.recorder
 }
 
+// CancelOperation mocks base method.
+func (m *MockOrchestratorInterface) CancelOperation(operationID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelOperation", operationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelOperation indicates an expected call of CancelOperation.
+func (mr *MockOrchestratorInterfaceMockRecorder) CancelOperation(operationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelOperation", reflect.TypeOf((*MockOrchestratorInterface)(nil).CancelOperation), operationID)
+}
+
 // QueueOperation mocks base method.
 func (m *MockOrchestratorInterface) QueueOperation(operation *repo.Operation) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// QuarantineCluster flags a misbehaving cluster so that no new operations are
+// accepted for it, without deleting it. Operations already queued are left
+// alone; they can be cancelled separately. Quarantining an already quarantined
+// cluster updates the reason.
+func (s *Service) QuarantineCluster(ctx context.Context, id uuid.UUID, reason string) (*repo.Cluster, error) {
+	if len(reason) > maxReasonLength {
+		return nil, fmt.Errorf("%w: reason exceeds %d bytes", ErrInvalidQuarantine, maxReasonLength)
+	}
+
+	now := time.Now().UTC()
+	return s.setQuarantine(ctx, id, &now, reason)
+}
+
+// ReleaseCluster lifts the quarantine of a cluster
+func (s *Service) ReleaseCluster(ctx context.Context, id uuid.UUID) (*repo.Cluster, error) {
+	return s.setQuarantine(ctx, id, nil, "")
+}
+
+func (s *Service) setQuarantine(ctx context.Context, id uuid.UUID, quarantinedAt *time.Time, reason string) (*repo.Cluster, error) {
+	if err := s.clusterRepo.SetQuarantine(ctx, id, quarantinedAt, reason); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrClusterNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to update cluster quarantine: %w", err)
+	}
+
+	// Remove from cache so the quarantine is enforced right away
+	key := s.cache.ClusterKey(id.String())
+	if err := s.cache.Delete(ctx, key); err != nil {
+		s.logger.Warn("Failed to remove cluster from cache", zap.Error(err))
+	}
+
+	return s.clusterRepo.GetByID(ctx, id)
+}
+
+// checkQuarantine rejects operations for a quarantined cluster
+func checkQuarantine(cluster *repo.Cluster) error {
+	if !cluster.Quarantined() {
+		return nil
+	}
+	if cluster.QuarantineReason != "" {
+		return fmt.Errorf("%w since %s: %s", ErrClusterQuarantined, cluster.QuarantinedAt.Format(time.RFC3339), cluster.QuarantineReason)
+	}
+	return fmt.Errorf("%w since %s", ErrClusterQuarantined, cluster.QuarantinedAt.Format(time.RFC3339))
+}
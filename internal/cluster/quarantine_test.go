@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	clustermocks "github.com/rizesky/mckmt/internal/cluster/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestClusterService_QuarantineCluster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+
+	stored := &repo.Cluster{ID: uuid.New(), Name: "flaky"}
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), stored.ID).DoAndReturn(func(context.Context, uuid.UUID) (*repo.Cluster, error) {
+		cluster := *stored
+		return &cluster, nil
+	}).AnyTimes()
+	mockClusterRepo.EXPECT().SetQuarantine(gomock.Any(), stored.ID, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ uuid.UUID, quarantinedAt *time.Time, reason string) error {
+			stored.QuarantinedAt = quarantinedAt
+			stored.QuarantineReason = reason
+			return nil
+		}).Times(2)
+
+	// The cached cluster is dropped on every change so the quarantine is enforced right away
+	mockCache.EXPECT().ClusterKey(stored.ID.String()).Return("cluster:" + stored.ID.String()).Times(2)
+	mockCache.EXPECT().Delete(gomock.Any(), "cluster:"+stored.ID.String()).Return(nil).Times(2)
+
+	// Only the operation created after the release is stored
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	service := NewService(mockClusterRepo, mockOpRepo, mockCache, zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+	newOperation := func() *repo.Operation {
+		return &repo.Operation{ID: uuid.New(), ClusterID: stored.ID, Type: repo.OperationTypeApply, Status: "queued", Payload: repo.Payload{}}
+	}
+
+	quarantined, err := service.QuarantineCluster(context.Background(), stored.ID, "floods errors")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !quarantined.Quarantined() || quarantined.QuarantineReason != "floods errors" {
+		t.Errorf("Expected the cluster to be quarantined for %q but got %v/%q", "floods errors", quarantined.QuarantinedAt, quarantined.QuarantineReason)
+	}
+
+	err = service.CreateOperation(context.Background(), newOperation())
+	if !errors.Is(err, ErrClusterQuarantined) {
+		t.Fatalf("Expected ErrClusterQuarantined but got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "floods errors") {
+		t.Errorf("Expected the quarantine reason in the error but got: %v", err)
+	}
+
+	released, err := service.ReleaseCluster(context.Background(), stored.ID)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if released.Quarantined() || released.QuarantineReason != "" {
+		t.Errorf("Expected the quarantine to be lifted but got %v/%q", released.QuarantinedAt, released.QuarantineReason)
+	}
+
+	if err := service.CreateOperation(context.Background(), newOperation()); err != nil {
+		t.Errorf("Expected no error after the release but got: %v", err)
+	}
+}
+
+func TestClusterService_QuarantineClusterErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	service := NewService(mockClusterRepo, mocks.NewMockOperationRepository(ctrl), mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+
+	missing := uuid.New()
+	mockClusterRepo.EXPECT().SetQuarantine(gomock.Any(), missing, gomock.Any(), gomock.Any()).Return(repo.ErrNotFound).Times(1)
+	if _, err := service.QuarantineCluster(context.Background(), missing, ""); !errors.Is(err, ErrClusterNotFound) {
+		t.Errorf("Expected ErrClusterNotFound but got: %v", err)
+	}
+
+	// An overly long reason is rejected before anything is stored
+	if _, err := service.QuarantineCluster(context.Background(), uuid.New(), strings.Repeat("x", maxReasonLength+1)); !errors.Is(err, ErrInvalidQuarantine) {
+		t.Errorf("Expected ErrInvalidQuarantine but got: %v", err)
+	}
+}
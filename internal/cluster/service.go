@@ -2,9 +2,15 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rizesky/mckmt/internal/auth"
+	"github.com/rizesky/mckmt/internal/kube"
 	"github.com/rizesky/mckmt/internal/repo"
 	"go.uber.org/zap"
 )
@@ -16,6 +22,35 @@ type Service struct {
 	cache         repo.Cache
 	logger        *zap.Logger
 	orchestrator  OrchestratorInterface
+	labelLimits   LabelLimits
+
+	// maxPayloadSize bounds the serialized payload of created operations
+	maxPayloadSize int
+
+	// maxManifestDocuments bounds the YAML documents of apply operations; zero means no limit
+	maxManifestDocuments int
+
+	// verifyClusterExists rejects operations for unknown clusters when they are
+	// created instead of letting them fail once an agent picks them up
+	verifyClusterExists bool
+
+	// baselineRepo stores the desired inventories sync operations detect drift against
+	baselineRepo repo.ClusterBaselineRepository
+
+	// outputRepo holds the output operations report while they run
+	outputRepo repo.OperationOutputRepository
+
+	// logRepo holds the logs agents stream to the hub
+	logRepo repo.LogRepository
+
+	// admissionHooks review operations before they are created
+	admissionHooks []AdmissionHook
+
+	// maskSecrets stores apply operations with the values of their Secrets masked
+	maskSecrets bool
+
+	// manifestHashing records the content hash of apply operations' manifests
+	manifestHashing bool
 }
 
 //go:generate mockgen -destination=./mocks/mock_cluster.go -package=mocks github.com/rizesky/mckmt/internal/cluster OrchestratorInterface
@@ -36,6 +71,7 @@ type Service struct {
 // defines what it needs, not what the orchestrator provides.
 type OrchestratorInterface interface {
 	QueueOperation(operation *repo.Operation) error
+	CancelOperation(operationID uuid.UUID) error
 }
 
 // NewService creates a new cluster service
@@ -46,9 +82,58 @@ func NewService(clusterRepo repo.ClusterRepository, operationRepo repo.Operation
 		cache:         cache,
 		logger:        logger,
 		orchestrator:  orchestrator,
+		labelLimits:   DefaultLabelLimits(),
+
+		maxPayloadSize:       repo.DefaultMaxPayloadSize,
+		maxManifestDocuments: DefaultMaxManifestDocuments,
+		verifyClusterExists:  true,
+		maskSecrets:          true,
+		manifestHashing:      true,
+	}
+}
+
+// SetVerifyClusterExists sets whether operations for unknown clusters are rejected on creation
+func (s *Service) SetVerifyClusterExists(enabled bool) {
+	s.verifyClusterExists = enabled
+}
+
+// SetMaxPayloadSize sets the maximum serialized size of operation payloads
+func (s *Service) SetMaxPayloadSize(size int) {
+	if size > 0 {
+		s.maxPayloadSize = size
 	}
 }
 
+// SetMaskSecrets sets whether the values of Secrets in inline manifests are
+// masked in stored apply operations. The queued operation always keeps the
+// real values so the agent applies them.
+func (s *Service) SetMaskSecrets(enabled bool) {
+	s.maskSecrets = enabled
+}
+
+// SetBaselineRepository sets the store of desired cluster inventories. Without
+// it sync operations only report the live inventory.
+func (s *Service) SetBaselineRepository(baselineRepo repo.ClusterBaselineRepository) {
+	s.baselineRepo = baselineRepo
+}
+
+// SetOutputRepository sets the store of operation output. Without it pod logs
+// cannot be streamed.
+func (s *Service) SetOutputRepository(outputRepo repo.OperationOutputRepository) {
+	s.outputRepo = outputRepo
+}
+
+// SetLogRepository sets the store of the logs agents stream. Without it
+// cluster logs cannot be listed.
+func (s *Service) SetLogRepository(logRepo repo.LogRepository) {
+	s.logRepo = logRepo
+}
+
+// SetLabelLimits sets the limits enforced on cluster labels
+func (s *Service) SetLabelLimits(limits LabelLimits) {
+	s.labelLimits = limits
+}
+
 // GetCluster retrieves a cluster by ID with caching
 func (s *Service) GetCluster(ctx context.Context, id uuid.UUID) (*repo.Cluster, error) {
 	// Try cache first
@@ -84,8 +169,36 @@ func (s *Service) ListClusters(ctx context.Context, limit, offset int) ([]*repo.
 	return s.clusterRepo.List(ctx, limit, offset)
 }
 
+// CountClusters returns the number of registered clusters
+func (s *Service) CountClusters(ctx context.Context) (int, error) {
+	counts, err := s.clusterRepo.CountByStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total, nil
+}
+
+// ListClusterOperations lists the operations of a cluster, newest first
+func (s *Service) ListClusterOperations(ctx context.Context, clusterID uuid.UUID, limit, offset int) ([]*repo.Operation, error) {
+	return s.operationRepo.ListByCluster(ctx, clusterID, limit, offset)
+}
+
+// CountClusterOperations returns the number of operations of a cluster
+func (s *Service) CountClusterOperations(ctx context.Context, clusterID uuid.UUID) (int, error) {
+	return s.operationRepo.CountByCluster(ctx, clusterID)
+}
+
 // RegisterCluster registers an existing cluster for management
 func (s *Service) RegisterCluster(ctx context.Context, cluster *repo.Cluster) error {
+	if err := s.labelLimits.Validate(cluster.Labels); err != nil {
+		return err
+	}
+
 	err := s.clusterRepo.Create(ctx, cluster)
 	if err != nil {
 		return err
@@ -100,8 +213,46 @@ func (s *Service) RegisterCluster(ctx context.Context, cluster *repo.Cluster) er
 	return nil
 }
 
+// CreateCluster creates the record of a cluster ahead of its agent
+// registering, so it can be labelled and granted access to beforehand. The
+// agent registering under the same name takes the record over.
+func (s *Service) CreateCluster(ctx context.Context, name, description string, labels map[string]string) (*repo.Cluster, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, ErrClusterNameRequired
+	}
+	if err := s.labelLimits.Validate(labels); err != nil {
+		return nil, err
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	now := time.Now().UTC()
+	cluster := &repo.Cluster{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: description,
+		Labels:      repo.Labels(labels),
+		Status:      "pending",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.RegisterCluster(ctx, cluster); err != nil {
+		if errors.Is(err, repo.ErrAlreadyExists) {
+			return nil, fmt.Errorf("%w: %s", ErrClusterAlreadyExists, name)
+		}
+		return nil, err
+	}
+	return cluster, nil
+}
+
 // UpdateCluster updates an existing cluster
 func (s *Service) UpdateCluster(ctx context.Context, id uuid.UUID, name, description string, labels map[string]string) error {
+	if err := s.labelLimits.Validate(labels); err != nil {
+		return err
+	}
+
 	// Get existing cluster
 	cluster, err := s.clusterRepo.GetByID(ctx, id)
 	if err != nil {
@@ -143,8 +294,34 @@ func (s *Service) DeleteCluster(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// GetClusterResources retrieves cluster resources with caching
-func (s *Service) GetClusterResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string) ([]map[string]interface{}, error) {
+// clusterResourcesTTL is how long listed cluster resources are served from the cache
+const clusterResourcesTTL = 5 * time.Minute
+
+// lastKnownResourcesTTL is how long the last listed resources of a cluster are
+// kept to be served, marked stale, while its agent is not connected
+const lastKnownResourcesTTL = 24 * time.Hour
+
+// lastKnownResources is the last resource listing of a cluster and when it was made
+type lastKnownResources struct {
+	Resources []map[string]interface{} `json:"resources"`
+	AsOf      time.Time                `json:"as_of"`
+}
+
+// GetClusterResources retrieves cluster resources of a kind with caching. The
+// resources are listed by the cluster agent: when no recent listing is
+// available, a list_resources operation is queued and returned instead so the
+// caller can poll it. Listing needs a connected agent and fails with
+// ErrClusterNotConnected without one, unless allowStale is set and the last
+// known resources are available; these are returned marked stale. An empty
+// namespace lists namespaced kinds across all namespaces. The agent's REST
+// mapper decides the scope of the kind, and a namespace given for a
+// cluster-scoped kind fails with kube.ErrNamespaceNotAllowed.
+func (s *Service) GetClusterResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string, allowStale bool) (*GetClusterResourcesResponse, *repo.Operation, error) {
+	if kind == "" {
+		return nil, nil, ErrResourceKindRequired
+	}
+	response := &GetClusterResourcesResponse{ClusterID: clusterID, Kind: kind, Namespace: namespace}
+
 	// Check cache first
 	cacheKey := s.cache.ClusterResourcesKey(clusterID.String(), kind, namespace)
 	var resources []map[string]interface{}
@@ -154,46 +331,445 @@ func (s *Service) GetClusterResources(ctx context.Context, clusterID uuid.UUID,
 			zap.String("cluster_id", clusterID.String()),
 			zap.String("kind", kind),
 			zap.String("namespace", namespace))
-		return resources, nil
+		response.Resources = resources
+		response.TotalCount = len(resources)
+		response.Cached = true
+		return response, nil, nil
 	}
 
 	if err != repo.ErrCacheMiss {
 		s.logger.Warn("Cache error, falling back to database", zap.Error(err))
 	}
 
-	// Cache miss - in a real implementation, this would query the cluster
-	// For now, return mock data
-	resources = []map[string]interface{}{
-		{
-			"kind":       "Pod",
-			"name":       "example-pod",
-			"namespace":  "default",
-			"status":     "Running",
-			"created_at": time.Now().Add(-1 * time.Hour),
-		},
-		{
-			"kind":       "Service",
-			"name":       "example-service",
-			"namespace":  "default",
-			"type":       "ClusterIP",
-			"created_at": time.Now().Add(-2 * time.Hour),
-		},
-	}
-
-	// Cache the result for 5 minutes
-	if err := s.cache.Set(ctx, cacheKey, resources, 5*time.Minute); err != nil {
+	cluster, err := s.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, nil, ErrClusterNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	lastKnownKey := cacheKey + ":last_known"
+	if !agentConnected(cluster) {
+		if !allowStale {
+			return nil, nil, fmt.Errorf("%w: cluster is %s", ErrClusterNotConnected, cluster.Status)
+		}
+
+		var last lastKnownResources
+		if err := s.cache.Get(ctx, lastKnownKey, &last); err != nil {
+			if err != repo.ErrCacheMiss {
+				s.logger.Warn("Failed to get last known cluster resources", zap.Error(err))
+			}
+			return nil, nil, fmt.Errorf("%w: cluster is %s", ErrClusterNotConnected, cluster.Status)
+		}
+
+		response.Resources = last.Resources
+		response.TotalCount = len(last.Resources)
+		response.Cached = true
+		response.Stale = true
+		response.AsOf = &last.AsOf
+		return response, nil, nil
+	}
+
+	params := repo.Payload{repo.PayloadResourceKind: kind, repo.PayloadResourceNamespace: namespace}
+	pending, err := s.askAgent(ctx, clusterID, repo.OperationTypeListResources, params, "resources", &resources)
+	if err != nil || pending != nil {
+		return nil, pending, err
+	}
+
+	if err := s.cache.Set(ctx, cacheKey, resources, clusterResourcesTTL); err != nil {
 		s.logger.Warn("Failed to cache cluster resources", zap.Error(err))
 	}
+	last := lastKnownResources{Resources: resources, AsOf: time.Now()}
+	if err := s.cache.Set(ctx, lastKnownKey, last, lastKnownResourcesTTL); err != nil {
+		s.logger.Warn("Failed to cache last known cluster resources", zap.Error(err))
+	}
+
+	response.Resources = resources
+	response.TotalCount = len(resources)
+	return response, nil, nil
+}
+
+// agentConnected reports whether the cluster has an agent connected to the hub
+func agentConnected(cluster *repo.Cluster) bool {
+	return cluster.Status != "disconnected" && cluster.Status != "pending"
+}
+
+// agentQueryMaxAge bounds how old an agent query result may be before a new query is queued
+const agentQueryMaxAge = 1 * time.Minute
+
+// agentQueryLookback is the number of recent operations inspected for a reusable query result
+const agentQueryLookback = 20
+
+// ListNamespaces returns the namespaces reported by the cluster agent.
+// When no recent listing is available, a list_namespaces operation is queued and
+// returned instead so the caller can poll it.
+func (s *Service) ListNamespaces(ctx context.Context, clusterID uuid.UUID) ([]kube.NamespaceInfo, *repo.Operation, error) {
+	var namespaces []kube.NamespaceInfo
+	pending, err := s.queryAgent(ctx, clusterID, repo.OperationTypeListNamespaces, "namespaces", &namespaces)
+	if err != nil || pending != nil {
+		return nil, pending, err
+	}
+	return namespaces, nil, nil
+}
+
+// ListNodes returns the nodes reported by the cluster agent.
+// When no recent listing is available, a list_nodes operation is queued and
+// returned instead so the caller can poll it.
+func (s *Service) ListNodes(ctx context.Context, clusterID uuid.UUID) ([]kube.NodeInfo, *repo.Operation, error) {
+	var nodes []kube.NodeInfo
+	pending, err := s.queryAgent(ctx, clusterID, repo.OperationTypeListNodes, "nodes", &nodes)
+	if err != nil || pending != nil {
+		return nil, pending, err
+	}
+	return nodes, nil, nil
+}
+
+// GetClusterDiagnostics returns the agent's effective Kubernetes permissions for
+// each operation type. When no recent report is available, a diagnostics
+// operation is queued and returned instead so the caller can poll it.
+func (s *Service) GetClusterDiagnostics(ctx context.Context, clusterID uuid.UUID) (kube.PermissionReport, *repo.Operation, error) {
+	var report kube.PermissionReport
+	pending, err := s.queryAgent(ctx, clusterID, repo.OperationTypeDiagnostics, "permissions", &report)
+	if err != nil || pending != nil {
+		return nil, pending, err
+	}
+	return report, nil, nil
+}
+
+// queryAgent resolves a read-only agent query. The answer is read from the cache or
+// from the latest successful query operation; otherwise a pending or newly queued
+// operation is returned. field names the key holding the answer in the reported data.
+func (s *Service) queryAgent(ctx context.Context, clusterID uuid.UUID, opType, field string, dest interface{}) (*repo.Operation, error) {
+	cacheKey := s.cache.ClusterResourcesKey(clusterID.String(), opType, "")
+	err := s.cache.Get(ctx, cacheKey, dest)
+	if err == nil {
+		return nil, nil
+	}
+	if err != repo.ErrCacheMiss {
+		s.logger.Warn("Cache error, falling back to database", zap.Error(err))
+	}
+
+	pending, err := s.askAgent(ctx, clusterID, opType, nil, field, dest)
+	if err != nil || pending != nil {
+		return pending, err
+	}
+
+	if err := s.cache.Set(ctx, cacheKey, dest, agentQueryMaxAge); err != nil {
+		s.logger.Warn("Failed to cache agent query result", zap.Error(err))
+	}
+	return nil, nil
+}
+
+// askAgent answers an agent query from the latest recent operation of opType
+// whose payload holds params. A pending operation, or a successful one the
+// agent has not answered yet, is returned as is; a successful one has its
+// answer decoded into dest, and one that failed because
+// of the query itself returns why. Otherwise a new operation is queued and
+// returned.
+func (s *Service) askAgent(ctx context.Context, clusterID uuid.UUID, opType string, params repo.Payload, field string, dest interface{}) (*repo.Operation, error) {
+	operations, err := s.operationRepo.ListByCluster(ctx, clusterID, agentQueryLookback, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster operations: %w", err)
+	}
+
+	for _, op := range operations {
+		if op.Type != opType || time.Since(op.CreatedAt) > agentQueryMaxAge || !hasParams(op.Payload, params) {
+			continue
+		}
+
+		if isActiveOperation(op.Status) {
+			return op, nil
+		}
+
+		if op.Status == string(repo.OperationStatusSuccess) {
+			if decodeQueryResult(op.Result, field, dest) {
+				return nil, nil
+			}
+			// The orchestrator marks a query successful once it is handed
+			// to the agent, so it holds no data until the agent reports
+			if _, reported := queryData(op.Result, field); !reported {
+				return op, nil
+			}
+		}
+
+		if op.Status == string(repo.OperationStatusFailed) {
+			if err := queryError(op.Result); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	payload := repo.Payload{}
+	for key, value := range params {
+		payload[key] = value
+	}
+	operation := &repo.Operation{
+		ID:           uuid.New(),
+		ClusterID:    clusterID,
+		Type:         opType,
+		Status:       "queued",
+		Payload:      payload,
+		InitiatedVia: repo.InitiatedViaHTTPAPI,
+	}
+
+	if err := s.CreateOperation(ctx, operation); err != nil {
+		return nil, err
+	}
+	if err := s.QueueOperation(ctx, operation); err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+// hasParams reports whether an operation payload holds all the query params
+func hasParams(payload, params repo.Payload) bool {
+	for key, value := range params {
+		if payload[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// queryError returns the error of a failed agent query that asking again
+// can't fix, e.g. a namespace given for a cluster-scoped kind, and nil for
+// failures worth another try
+func queryError(result *repo.Payload) error {
+	if result == nil {
+		return nil
+	}
+
+	opErr, ok := (*result)["error"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if reason, _ := opErr["reason"].(string); reason == kube.ReasonNamespaceNotAllowed {
+		message, _ := opErr["message"].(string)
+		return fmt.Errorf("%w: %s", kube.ErrNamespaceNotAllowed, strings.TrimPrefix(message, kube.ErrNamespaceNotAllowed.Error()+": "))
+	}
+	return nil
+}
+
+// queryData returns result.data[field] reported by an agent
+func queryData(result *repo.Payload, field string) (interface{}, bool) {
+	if result == nil {
+		return nil, false
+	}
+
+	data, ok := (*result)["data"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	value, ok := data[field]
+	return value, ok
+}
+
+// decodeQueryResult decodes result.data[field] reported by an agent into dest
+func decodeQueryResult(result *repo.Payload, field string, dest interface{}) bool {
+	value, ok := queryData(result, field)
+	if !ok {
+		return false
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
 
-	return resources, nil
+	return json.Unmarshal(encoded, dest) == nil
 }
 
 // CreateOperation creates a new operation
 func (s *Service) CreateOperation(ctx context.Context, operation *repo.Operation) error {
-	return s.operationRepo.Create(ctx, operation)
+	if err := validateProvenance(operation); err != nil {
+		return err
+	}
+
+	size, err := operation.Payload.EncodedSize()
+	if err != nil {
+		return fmt.Errorf("failed to encode operation payload: %w", err)
+	}
+	if size > s.maxPayloadSize {
+		return fmt.Errorf("%w: %d bytes exceeds the maximum of %d", ErrOperationPayloadTooLarge, size, s.maxPayloadSize)
+	}
+	if err := s.checkManifestDocuments(operation); err != nil {
+		return err
+	}
+	if _, err := repo.ParsePreconditions(operation.Payload); err != nil {
+		return err
+	}
+	if _, err := repo.ParseExecutionWindow(operation.Payload); err != nil {
+		return err
+	}
+
+	cluster, err := s.clusterRepo.GetByID(ctx, operation.ClusterID)
+	switch {
+	case err == nil:
+		if err := checkQuarantine(cluster); err != nil {
+			return err
+		}
+		if !cluster.Capabilities.SupportsOperation(operation.Type) {
+			return fmt.Errorf("%w: %s", ErrOperationNotSupported, operation.Type)
+		}
+		setImpersonation(ctx, cluster, operation)
+		if err := s.attachBaseline(ctx, operation); err != nil {
+			return err
+		}
+	case errors.Is(err, repo.ErrNotFound):
+		if s.verifyClusterExists {
+			return fmt.Errorf("%w: %s", ErrClusterNotFound, operation.ClusterID)
+		}
+	default:
+		return fmt.Errorf("failed to look up cluster: %w", err)
+	}
+	setCreator(ctx, operation)
+	if err := s.admit(ctx, operation); err != nil {
+		return err
+	}
+	if s.manifestHashing {
+		// Hashed before masking so the hash reflects the applied content
+		setManifestHash(operation)
+	}
+
+	stored, err := s.storedOperation(operation)
+	if err != nil {
+		return err
+	}
+	return s.operationRepo.Create(ctx, stored)
+}
+
+// storedOperation returns the representation of an operation to persist. With
+// secret masking enabled, an apply operation whose inline manifests contain
+// Secrets is stored as a copy with their values masked.
+func (s *Service) storedOperation(operation *repo.Operation) (*repo.Operation, error) {
+	if !s.maskSecrets || operation.Type != repo.OperationTypeApply {
+		return operation, nil
+	}
+	manifests, ok := operation.Payload[repo.PayloadManifests].(string)
+	if !ok || manifests == "" {
+		return operation, nil
+	}
+
+	masked, ok, err := kube.MaskSecrets([]byte(manifests))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidManifests, err)
+	}
+	if !ok {
+		return operation, nil
+	}
+
+	stored := *operation
+	stored.Payload = make(repo.Payload, len(operation.Payload)+1)
+	for key, value := range operation.Payload {
+		stored.Payload[key] = value
+	}
+	stored.Payload[repo.PayloadManifests] = string(masked)
+	stored.Payload[repo.PayloadSecretsMasked] = true
+	return &stored, nil
+}
+
+// maxReasonLength bounds the reason given for an operation, in bytes
+const maxReasonLength = 1024
+
+// validateProvenance checks the channel and reason recorded for an operation
+func validateProvenance(operation *repo.Operation) error {
+	if operation.InitiatedVia != "" && !repo.ValidInitiatedVia(operation.InitiatedVia) {
+		return fmt.Errorf("%w: unknown initiated_via %q", ErrInvalidProvenance, operation.InitiatedVia)
+	}
+	if len(operation.Reason) > maxReasonLength {
+		return fmt.Errorf("%w: reason exceeds %d bytes", ErrInvalidProvenance, maxReasonLength)
+	}
+	return nil
+}
+
+// setCreator records in the operation payload the user who requested it, if
+// the request is made by an mckmt user
+func setCreator(ctx context.Context, operation *repo.Operation) {
+	user, ok := auth.GetUserFromContext(ctx)
+	if !ok || user.Username == "" {
+		return
+	}
+
+	if operation.Payload == nil {
+		operation.Payload = repo.Payload{}
+	}
+	operation.Payload[repo.PayloadCreatedBy] = user.Username
 }
 
 // QueueOperation queues an operation for processing
 func (s *Service) QueueOperation(ctx context.Context, operation *repo.Operation) error {
 	return s.orchestrator.QueueOperation(operation)
 }
+
+// cancelAllPageSize is the page size used when scanning a cluster's operations for cancellation
+const cancelAllPageSize = 100
+
+// CancelClusterOperations cancels every non-terminal operation for a cluster and
+// returns the IDs of the operations that were cancelled
+func (s *Service) CancelClusterOperations(ctx context.Context, clusterID uuid.UUID, reason string) ([]uuid.UUID, error) {
+	// Collect the active operations first so that cancelling doesn't shift the pages
+	var active []*repo.Operation
+	for offset := 0; ; offset += cancelAllPageSize {
+		operations, err := s.operationRepo.ListByCluster(ctx, clusterID, cancelAllPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list cluster operations: %w", err)
+		}
+
+		for _, op := range operations {
+			if isActiveOperation(op.Status) {
+				active = append(active, op)
+			}
+		}
+
+		if len(operations) < cancelAllPageSize {
+			break
+		}
+	}
+
+	cancelled := make([]uuid.UUID, 0, len(active))
+	for _, op := range active {
+		if err := s.operationRepo.CancelOperation(ctx, op.ID, reason); err != nil {
+			// The operation may have finished in the meantime
+			s.logger.Warn("Failed to cancel operation",
+				zap.String("operation_id", op.ID.String()),
+				zap.String("cluster_id", clusterID.String()),
+				zap.Error(err))
+			continue
+		}
+
+		// Stop the operation if the orchestrator is currently running it
+		if err := s.orchestrator.CancelOperation(op.ID); err != nil {
+			s.logger.Warn("Failed to request orchestrator cancellation",
+				zap.String("operation_id", op.ID.String()),
+				zap.Error(err))
+		}
+
+		// Invalidate cached operation
+		if err := s.cache.Delete(ctx, s.cache.OperationKey(op.ID.String())); err != nil {
+			s.logger.Warn("Failed to invalidate operation cache", zap.Error(err))
+		}
+
+		cancelled = append(cancelled, op.ID)
+	}
+
+	s.logger.Info("Cancelled cluster operations",
+		zap.String("cluster_id", clusterID.String()),
+		zap.Int("cancelled", len(cancelled)),
+		zap.String("reason", reason))
+
+	return cancelled, nil
+}
+
+// isActiveOperation reports whether an operation status is non-terminal
+func isActiveOperation(status string) bool {
+	switch status {
+	case "queued", string(repo.OperationStatusPending), string(repo.OperationStatusRunning):
+		return true
+	default:
+		return false
+	}
+}
@@ -3,13 +3,17 @@ package cluster
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 
+	"github.com/rizesky/mckmt/internal/auth"
 	clustermocks "github.com/rizesky/mckmt/internal/cluster/mocks"
+	"github.com/rizesky/mckmt/internal/kube"
 	"github.com/rizesky/mckmt/internal/repo"
 	"github.com/rizesky/mckmt/internal/repo/mocks"
 )
@@ -18,6 +22,7 @@ func TestClusterService_CreateOperation(t *testing.T) {
 	tests := []struct {
 		name          string
 		operation     *repo.Operation
+		clusterError  error
 		repoError     error
 		expectedError bool
 	}{
@@ -45,6 +50,18 @@ func TestClusterService_CreateOperation(t *testing.T) {
 			repoError:     errors.New("database error"),
 			expectedError: true,
 		},
+		{
+			name: "unknown cluster",
+			operation: &repo.Operation{
+				ID:        uuid.New(),
+				ClusterID: uuid.New(),
+				Type:      "apply",
+				Status:    "queued",
+				Payload:   repo.Payload{"test": "data"},
+			},
+			clusterError:  repo.ErrNotFound,
+			expectedError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -60,14 +77,21 @@ func TestClusterService_CreateOperation(t *testing.T) {
 			logger := zap.NewNop()
 
 			// Setup expectations
-			if tt.repoError != nil {
-				mockOpRepo.EXPECT().
-					Create(gomock.Any(), tt.operation).
-					Return(tt.repoError)
+			if tt.clusterError != nil {
+				mockClusterRepo.EXPECT().
+					GetByID(gomock.Any(), tt.operation.ClusterID).
+					Return(nil, tt.clusterError)
 			} else {
+				mockClusterRepo.EXPECT().
+					GetByID(gomock.Any(), tt.operation.ClusterID).
+					Return(&repo.Cluster{ID: tt.operation.ClusterID}, nil)
+			}
+
+			// Operations for unknown clusters are rejected before they are stored
+			if tt.clusterError == nil {
 				mockOpRepo.EXPECT().
 					Create(gomock.Any(), tt.operation).
-					Return(nil)
+					Return(tt.repoError)
 			}
 
 			service := NewService(mockClusterRepo, mockOpRepo, mockCache, logger, mockOrchestrator)
@@ -80,6 +104,9 @@ func TestClusterService_CreateOperation(t *testing.T) {
 				if err == nil {
 					t.Errorf("Expected error but got nil")
 				}
+				if tt.clusterError != nil && !errors.Is(err, ErrClusterNotFound) {
+					t.Errorf("Expected ErrClusterNotFound but got: %v", err)
+				}
 			} else {
 				if err != nil {
 					t.Errorf("Expected no error but got: %v", err)
@@ -89,6 +116,280 @@ func TestClusterService_CreateOperation(t *testing.T) {
 	}
 }
 
+func TestClusterService_CreateOperationUnsupportedType(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockOrchestrator := clustermocks.NewMockOrchestratorInterface(ctrl)
+
+	clusterID := uuid.New()
+	mockClusterRepo.EXPECT().
+		GetByID(gomock.Any(), clusterID).
+		Return(&repo.Cluster{
+			ID:           clusterID,
+			Capabilities: &repo.Capabilities{OperationTypes: []string{"apply", "sync"}},
+		}, nil).
+		Times(2)
+
+	// Only the supported operation reaches the repository
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	service := NewService(mockClusterRepo, mockOpRepo, mockCache, zap.NewNop(), mockOrchestrator)
+
+	exec := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeExec, Status: "queued"}
+	err := service.CreateOperation(context.Background(), exec)
+	if !errors.Is(err, ErrOperationNotSupported) {
+		t.Fatalf("Expected ErrOperationNotSupported but got: %v", err)
+	}
+
+	apply := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: "queued"}
+	if err := service.CreateOperation(context.Background(), apply); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
+
+func TestClusterService_CreateOperationImpersonatesUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+
+	clusterID := uuid.New()
+	mockClusterRepo.EXPECT().
+		GetByID(gomock.Any(), clusterID).
+		Return(&repo.Cluster{ID: clusterID, Labels: repo.Labels{repo.ImpersonateUsersLabel: "true"}}, nil)
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+	service := NewService(mockClusterRepo, mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+
+	ctx := context.WithValue(context.Background(), auth.UserContextKey, &auth.AuthenticatedUser{Username: "alice", Roles: []string{"operator"}})
+	operation := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: "queued"}
+	if err := service.CreateOperation(ctx, operation); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	impersonate, ok := operation.Payload[repo.PayloadImpersonate].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected the payload to ask for impersonation, got %v", operation.Payload)
+	}
+	if impersonate["user"] != "alice" {
+		t.Errorf("Expected impersonated user alice but got %v", impersonate["user"])
+	}
+	if groups, _ := impersonate["groups"].([]string); len(groups) != 1 || groups[0] != "mckmt:operator" {
+		t.Errorf("Expected impersonated groups [mckmt:operator] but got %v", impersonate["groups"])
+	}
+}
+
+func TestClusterService_CreateOperationMasksSecrets(t *testing.T) {
+	manifests := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: credentials\ndata:\n  password: c3VwZXJzZWNyZXQ=\n"
+
+	tests := []struct {
+		name        string
+		maskSecrets bool
+	}{
+		{name: "masking enabled", maskSecrets: true},
+		{name: "masking disabled", maskSecrets: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+			mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+
+			clusterID := uuid.New()
+			mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).Return(&repo.Cluster{ID: clusterID}, nil)
+
+			var stored *repo.Operation
+			mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, op *repo.Operation) error {
+				stored = op
+				return nil
+			})
+
+			service := NewService(mockClusterRepo, mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+			service.SetMaskSecrets(tt.maskSecrets)
+
+			operation := &repo.Operation{
+				ID:        uuid.New(),
+				ClusterID: clusterID,
+				Type:      repo.OperationTypeApply,
+				Status:    "queued",
+				Payload:   repo.Payload{repo.PayloadManifests: manifests},
+			}
+			if err := service.CreateOperation(context.Background(), operation); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			// The operation handed on for queueing is what the agent applies
+			if operation.Payload[repo.PayloadManifests] != manifests {
+				t.Errorf("Expected the queued operation to keep the real manifests, got %v", operation.Payload[repo.PayloadManifests])
+			}
+
+			storedManifests, _ := stored.Payload[repo.PayloadManifests].(string)
+			masked, _ := stored.Payload[repo.PayloadSecretsMasked].(bool)
+			if tt.maskSecrets {
+				if strings.Contains(storedManifests, "c3VwZXJzZWNyZXQ=") || !strings.Contains(storedManifests, kube.MaskedSecretValue) {
+					t.Errorf("Expected the stored manifests to be masked, got %q", storedManifests)
+				}
+				if !masked {
+					t.Errorf("Expected the stored payload to be flagged as masked")
+				}
+			} else if storedManifests != manifests || masked {
+				t.Errorf("Expected the real manifests to be stored, got %v", stored.Payload)
+			}
+		})
+	}
+}
+
+func TestClusterService_CreateOperationRecordsCreator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+
+	clusterID := uuid.New()
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).Return(&repo.Cluster{ID: clusterID}, nil)
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+	service := NewService(mockClusterRepo, mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+
+	ctx := context.WithValue(context.Background(), auth.UserContextKey, &auth.AuthenticatedUser{Username: "alice"})
+	operation := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeApply, Status: "queued"}
+	if err := service.CreateOperation(ctx, operation); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if creator := operation.Payload[repo.PayloadCreatedBy]; creator != "alice" {
+		t.Errorf("Expected creator alice but got %v", creator)
+	}
+}
+
+func TestClusterService_CreateSyncOperationAttachesBaseline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	mockBaselineRepo := mocks.NewMockClusterBaselineRepository(ctrl)
+
+	clusterID := uuid.New()
+	resources := []repo.InventoryEntry{{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "web"}}
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).Return(&repo.Cluster{ID: clusterID}, nil)
+	mockBaselineRepo.EXPECT().Get(gomock.Any(), clusterID).Return(&repo.ClusterBaseline{ClusterID: clusterID, Resources: resources}, nil)
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+	service := NewService(mockClusterRepo, mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+	service.SetBaselineRepository(mockBaselineRepo)
+
+	operation := &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: repo.OperationTypeSync, Status: "queued"}
+	if err := service.CreateOperation(context.Background(), operation); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	desired, ok := operation.Payload[repo.PayloadDesiredInventory].([]repo.InventoryEntry)
+	if !ok || len(desired) != 1 || desired[0].Name != "web" {
+		t.Errorf("Expected the cluster baseline in the payload, got %v", operation.Payload)
+	}
+}
+
+func TestClusterService_CreateOperationPayloadTooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockOrchestrator := clustermocks.NewMockOrchestratorInterface(ctrl)
+
+	clusterID := uuid.New()
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).Return(&repo.Cluster{ID: clusterID}, nil).Times(1)
+
+	// Only the payload within the limit is stored
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	service := NewService(mockClusterRepo, mockOpRepo, mockCache, zap.NewNop(), mockOrchestrator)
+	service.SetMaxPayloadSize(1024)
+
+	oversized := &repo.Operation{
+		ID:        uuid.New(),
+		ClusterID: clusterID,
+		Type:      repo.OperationTypeExec,
+		Status:    "queued",
+		Payload:   repo.Payload{"env": strings.Repeat("x", 2048)},
+	}
+	err := service.CreateOperation(context.Background(), oversized)
+	if !errors.Is(err, ErrOperationPayloadTooLarge) {
+		t.Fatalf("Expected ErrOperationPayloadTooLarge but got: %v", err)
+	}
+
+	small := &repo.Operation{
+		ID:        uuid.New(),
+		ClusterID: clusterID,
+		Type:      repo.OperationTypeExec,
+		Status:    "queued",
+		Payload:   repo.Payload{"env": strings.Repeat("x", 512)},
+	}
+	if err := service.CreateOperation(context.Background(), small); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
+
+func TestClusterService_CreateOperationProvenance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	mockOrchestrator := clustermocks.NewMockOrchestratorInterface(ctrl)
+
+	clusterID := uuid.New()
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).Return(&repo.Cluster{ID: clusterID}, nil).Times(1)
+
+	// Provenance is stored as operation fields
+	var stored *repo.Operation
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, op *repo.Operation) error {
+		stored = op
+		return nil
+	}).Times(1)
+
+	service := NewService(mockClusterRepo, mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), mockOrchestrator)
+
+	newOperation := func(initiatedVia, reason string) *repo.Operation {
+		return &repo.Operation{
+			ID:           uuid.New(),
+			ClusterID:    clusterID,
+			Type:         repo.OperationTypeApply,
+			Status:       "queued",
+			Payload:      repo.Payload{},
+			InitiatedVia: initiatedVia,
+			Reason:       reason,
+		}
+	}
+
+	if err := service.CreateOperation(context.Background(), newOperation(repo.InitiatedViaWebhook, "image pushed")); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if stored.InitiatedVia != repo.InitiatedViaWebhook || stored.Reason != "image pushed" {
+		t.Errorf("Expected provenance webhook/%q but got %s/%q", "image pushed", stored.InitiatedVia, stored.Reason)
+	}
+
+	for name, op := range map[string]*repo.Operation{
+		"unknown channel": newOperation("email", ""),
+		"long reason":     newOperation(repo.InitiatedViaCLI, strings.Repeat("x", maxReasonLength+1)),
+	} {
+		if err := service.CreateOperation(context.Background(), op); !errors.Is(err, ErrInvalidProvenance) {
+			t.Errorf("%s: expected ErrInvalidProvenance but got: %v", name, err)
+		}
+	}
+}
+
 func TestClusterService_QueueOperation(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -402,35 +703,93 @@ func TestClusterService_ListClusters(t *testing.T) {
 }
 
 func TestClusterService_GetClusterResources(t *testing.T) {
+	// listing returns a recent list_resources operation of the agent
+	listing := func(kind, namespace, status string, result repo.Payload) *repo.Operation {
+		return &repo.Operation{
+			ID:        uuid.New(),
+			Type:      repo.OperationTypeListResources,
+			Status:    status,
+			Payload:   repo.Payload{repo.PayloadResourceKind: kind, repo.PayloadResourceNamespace: namespace},
+			Result:    &result,
+			CreatedAt: time.Now(),
+		}
+	}
+	listed := func(names ...string) repo.Payload {
+		resources := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			resources = append(resources, map[string]interface{}{"name": name})
+		}
+		return repo.Payload{"success": true, "data": map[string]interface{}{"resources": resources}}
+	}
+	notAllowed := repo.Payload{
+		"success": false,
+		"error": map[string]interface{}{
+			"code":    float64(400),
+			"reason":  kube.ReasonNamespaceNotAllowed,
+			"message": "namespace not allowed for cluster-scoped resource: Node",
+		},
+	}
+
 	tests := []struct {
 		name          string
-		clusterID     uuid.UUID
 		kind          string
 		namespace     string
-		expectedError bool
-		expectedCount int
+		operations    []*repo.Operation
+		expectedNames []string
+		expectQueued  bool
+		expectedError error
 	}{
 		{
-			name:          "successful resource list",
-			clusterID:     uuid.New(),
-			kind:          "Pod",
+			name:      "namespaced kind in a namespace",
+			kind:      "Pod",
+			namespace: "default",
+			operations: []*repo.Operation{
+				listing("Pod", "", "success", listed("web", "db")),
+				listing("Pod", "default", "success", listed("web")),
+			},
+			expectedNames: []string{"web"},
+		},
+		{
+			name:      "namespaced kind across all namespaces",
+			kind:      "Pod",
+			namespace: "",
+			operations: []*repo.Operation{
+				listing("Pod", "default", "success", listed("web")),
+				listing("Pod", "", "success", listed("web", "db")),
+			},
+			expectedNames: []string{"web", "db"},
+		},
+		{
+			name:          "cluster-scoped kind",
+			kind:          "Node",
+			namespace:     "",
+			operations:    []*repo.Operation{listing("Node", "", "success", listed("node-1"))},
+			expectedNames: []string{"node-1"},
+		},
+		{
+			name:          "namespace for cluster-scoped kind",
+			kind:          "Node",
 			namespace:     "default",
-			expectedError: false,
-			expectedCount: 2, // Based on mock implementation
+			operations:    []*repo.Operation{listing("Node", "default", "failed", notAllowed)},
+			expectedError: kube.ErrNamespaceNotAllowed,
 		},
 		{
-			name:          "empty kind filter",
-			clusterID:     uuid.New(),
+			name:         "no recent listing",
+			kind:         "Pod",
+			namespace:    "default",
+			operations:   []*repo.Operation{listing("Pod", "kube-system", "success", listed("dns"))},
+			expectQueued: true,
+		},
+		{
+			name:          "kind required",
 			kind:          "",
-			namespace:     "",
-			expectedError: false,
-			expectedCount: 2, // Based on mock implementation
+			namespace:     "default",
+			expectedError: ErrResourceKindRequired,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Setup
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 
@@ -438,59 +797,224 @@ func TestClusterService_GetClusterResources(t *testing.T) {
 			mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
 			mockCache := mocks.NewMockCache(ctrl)
 			mockOrchestrator := clustermocks.NewMockOrchestratorInterface(ctrl)
-			logger := zap.NewNop()
-
-			// Setup cache expectations
-			mockCache.EXPECT().
-				ClusterResourcesKey(gomock.Any(), gomock.Any(), gomock.Any()).
-				Return("resources:" + tt.clusterID.String() + ":" + tt.kind + ":" + tt.namespace).
-				AnyTimes()
-
-			mockCache.EXPECT().
-				Get(gomock.Any(), gomock.Any(), gomock.Any()).
-				Return(repo.ErrCacheMiss) // Always cache miss for this test
+			service := NewService(mockClusterRepo, mockOpRepo, mockCache, zap.NewNop(), mockOrchestrator)
+
+			clusterID := uuid.New()
+			if tt.kind != "" {
+				mockCache.EXPECT().ClusterResourcesKey(clusterID.String(), tt.kind, tt.namespace).Return("resources").AnyTimes()
+				mockCache.EXPECT().Get(gomock.Any(), "resources", gomock.Any()).Return(repo.ErrCacheMiss)
+				mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).
+					Return(&repo.Cluster{ID: clusterID, Status: "connected"}, nil).AnyTimes()
+				mockOpRepo.EXPECT().ListByCluster(gomock.Any(), clusterID, gomock.Any(), 0).Return(tt.operations, nil)
+			}
+			if tt.expectedNames != nil {
+				// Cached, and kept as last known
+				mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+			}
 
-			mockCache.EXPECT().
-				Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
-				Return(nil) // Cache set after getting resources
+			var queued *repo.Operation
+			if tt.expectQueued {
+				mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				mockOrchestrator.EXPECT().QueueOperation(gomock.Any()).DoAndReturn(func(operation *repo.Operation) error {
+					queued = operation
+					return nil
+				})
+			}
 
-			service := NewService(mockClusterRepo, mockOpRepo, mockCache, logger, mockOrchestrator)
+			response, pending, err := service.GetClusterResources(context.Background(), clusterID, tt.kind, tt.namespace, false)
 
-			// Execute
-			resources, err := service.GetClusterResources(context.Background(), tt.clusterID, tt.kind, tt.namespace)
-
-			// Verify
-			if tt.expectedError {
-				if err == nil {
-					t.Errorf("Expected error but got nil")
-				}
-				if resources != nil {
-					t.Errorf("Expected nil resources but got %+v", resources)
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("Expected %v but got: %v", tt.expectedError, err)
 				}
-			} else {
-				if err != nil {
-					t.Errorf("Expected no error but got: %v", err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if tt.expectQueued {
+				if pending == nil || pending != queued {
+					t.Fatalf("Expected the queued listing to be returned but got %+v", pending)
 				}
-				if len(resources) != tt.expectedCount {
-					t.Errorf("Expected %d resources but got %d", tt.expectedCount, len(resources))
+				if pending.Type != repo.OperationTypeListResources ||
+					pending.Payload[repo.PayloadResourceKind] != tt.kind ||
+					pending.Payload[repo.PayloadResourceNamespace] != tt.namespace {
+					t.Errorf("Expected a listing of %s in %q but got %+v", tt.kind, tt.namespace, pending)
 				}
+				return
+			}
 
-				// Verify resource structure
-				for _, resource := range resources {
-					if _, ok := resource["kind"]; !ok {
-						t.Errorf("Resource missing 'kind' field: %+v", resource)
-					}
-					if _, ok := resource["name"]; !ok {
-						t.Errorf("Resource missing 'name' field: %+v", resource)
-					}
-					if _, ok := resource["namespace"]; !ok {
-						t.Errorf("Resource missing 'namespace' field: %+v", resource)
-					}
-					if _, ok := resource["created_at"]; !ok {
-						t.Errorf("Resource missing 'created_at' field: %+v", resource)
-					}
-				}
+			if pending != nil {
+				t.Fatalf("Expected no pending listing but got %+v", pending)
+			}
+			var names []string
+			for _, resource := range response.Resources {
+				names = append(names, resource["name"].(string))
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expectedNames, ",") {
+				t.Errorf("Expected resources %v but got %v", tt.expectedNames, names)
+			}
+			if response.TotalCount != len(tt.expectedNames) {
+				t.Errorf("Expected total count %d but got %d", len(tt.expectedNames), response.TotalCount)
 			}
 		})
 	}
 }
+
+func TestClusterService_ListNamespacesReusesQueuedQuery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockOrchestrator := clustermocks.NewMockOrchestratorInterface(ctrl)
+	service := NewService(mockClusterRepo, mockOpRepo, mockCache, zap.NewNop(), mockOrchestrator)
+
+	clusterID := uuid.New()
+	mockCache.EXPECT().ClusterResourcesKey(clusterID.String(), repo.OperationTypeListNamespaces, "").Return("namespaces").AnyTimes()
+	mockCache.EXPECT().Get(gomock.Any(), "namespaces", gomock.Any()).Return(repo.ErrCacheMiss).AnyTimes()
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).
+		Return(&repo.Cluster{ID: clusterID, Status: "connected"}, nil).AnyTimes()
+
+	var operations []*repo.Operation
+	mockOpRepo.EXPECT().ListByCluster(gomock.Any(), clusterID, gomock.Any(), 0).
+		DoAndReturn(func(context.Context, uuid.UUID, int, int) ([]*repo.Operation, error) {
+			return operations, nil
+		}).AnyTimes()
+	mockOpRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, operation *repo.Operation) error {
+		operation.CreatedAt = time.Now()
+		operations = append(operations, operation)
+		return nil
+	}).Times(1)
+	// The orchestrator hands the query to the agent and marks it successful
+	// before the agent reports any data
+	mockOrchestrator.EXPECT().QueueOperation(gomock.Any()).DoAndReturn(func(operation *repo.Operation) error {
+		operation.Status = string(repo.OperationStatusSuccess)
+		operation.Result = &repo.Payload{"status": "queued", "message": "Operation queued for agent processing"}
+		return nil
+	}).Times(1)
+
+	_, first, err := service.ListNamespaces(context.Background(), clusterID)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	_, second, err := service.ListNamespaces(context.Background(), clusterID)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if first == nil || second == nil || second.ID != first.ID {
+		t.Fatalf("Expected both calls to return the queued operation but got %+v and %+v", first, second)
+	}
+	if len(operations) != 1 {
+		t.Errorf("Expected 1 operation but got %d", len(operations))
+	}
+}
+
+func TestClusterService_GetClusterResourcesWithoutAgent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+	service := NewService(mockClusterRepo, mocks.NewMockOperationRepository(ctrl), mockCache, zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+
+	clusterID := uuid.New()
+	listedAt := time.Now().Add(-time.Hour).UTC()
+	mockCache.EXPECT().ClusterResourcesKey(clusterID.String(), "Pod", "").Return("resources:pod").AnyTimes()
+	mockCache.EXPECT().Get(gomock.Any(), "resources:pod", gomock.Any()).Return(repo.ErrCacheMiss).AnyTimes()
+	mockClusterRepo.EXPECT().GetByID(gomock.Any(), clusterID).
+		Return(&repo.Cluster{ID: clusterID, Status: "disconnected"}, nil).AnyTimes()
+
+	// Stale resources are only served on request
+	if _, _, err := service.GetClusterResources(context.Background(), clusterID, "Pod", "", false); !errors.Is(err, ErrClusterNotConnected) {
+		t.Fatalf("Expected ErrClusterNotConnected but got: %v", err)
+	}
+
+	// Without a last known listing there is nothing to serve
+	mockCache.EXPECT().Get(gomock.Any(), "resources:pod:last_known", gomock.Any()).Return(repo.ErrCacheMiss)
+	if _, _, err := service.GetClusterResources(context.Background(), clusterID, "Pod", "", true); !errors.Is(err, ErrClusterNotConnected) {
+		t.Fatalf("Expected ErrClusterNotConnected but got: %v", err)
+	}
+
+	// The last known listing is served marked stale
+	mockCache.EXPECT().Get(gomock.Any(), "resources:pod:last_known", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, dest interface{}) error {
+			*dest.(*lastKnownResources) = lastKnownResources{
+				Resources: []map[string]interface{}{{"kind": "Pod", "name": "web"}},
+				AsOf:      listedAt,
+			}
+			return nil
+		})
+	response, _, err := service.GetClusterResources(context.Background(), clusterID, "Pod", "", true)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !response.Stale || response.AsOf == nil || !response.AsOf.Equal(listedAt) {
+		t.Errorf("Expected a stale listing from %v but got %+v", listedAt, response)
+	}
+	if response.TotalCount != 1 {
+		t.Errorf("Expected 1 resource but got %d", response.TotalCount)
+	}
+}
+
+func TestClusterService_CreateCluster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClusterRepo := mocks.NewMockClusterRepository(ctrl)
+	mockCache := mocks.NewMockCache(ctrl)
+	mockCache.EXPECT().ClusterKey(gomock.Any()).Return("cluster").AnyTimes()
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	service := NewService(mockClusterRepo, mocks.NewMockOperationRepository(ctrl), mockCache, zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+
+	mockClusterRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	created, err := service.CreateCluster(context.Background(), "prod", "Production", map[string]string{"env": "prod"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if created.ID == uuid.Nil || created.Name != "prod" || created.Status != "pending" || created.Labels["env"] != "prod" {
+		t.Errorf("Expected a pending cluster named prod but got %+v", created)
+	}
+
+	mockClusterRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(repo.ErrAlreadyExists).Times(1)
+	if _, err := service.CreateCluster(context.Background(), "prod", "", nil); !errors.Is(err, ErrClusterAlreadyExists) {
+		t.Errorf("Expected ErrClusterAlreadyExists but got: %v", err)
+	}
+
+	if _, err := service.CreateCluster(context.Background(), " ", "", nil); !errors.Is(err, ErrClusterNameRequired) {
+		t.Errorf("Expected ErrClusterNameRequired but got: %v", err)
+	}
+}
+
+func TestClusterService_ListClusterOperations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := mocks.NewMockOperationRepository(ctrl)
+	clusterID := uuid.New()
+	operations := []*repo.Operation{{ID: uuid.New(), ClusterID: clusterID}}
+	mockOpRepo.EXPECT().ListByCluster(gomock.Any(), clusterID, 20, 40).Return(operations, nil)
+	mockOpRepo.EXPECT().CountByCluster(gomock.Any(), clusterID).Return(41, nil)
+
+	service := NewService(mocks.NewMockClusterRepository(ctrl), mockOpRepo, mocks.NewMockCache(ctrl), zap.NewNop(), clustermocks.NewMockOrchestratorInterface(ctrl))
+
+	listed, err := service.ListClusterOperations(context.Background(), clusterID, 20, 40)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != operations[0].ID {
+		t.Errorf("Expected the repository's operations but got %v", listed)
+	}
+
+	total, err := service.CountClusterOperations(context.Background(), clusterID)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if total != 41 {
+		t.Errorf("Expected 41 operations but got %d", total)
+	}
+}
@@ -17,7 +17,51 @@ type AgentConfig struct {
 	OperationTimeout  time.Duration `mapstructure:"operation_timeout"`
 	MaxRetries        int           `mapstructure:"max_retries"`
 	RetryBackoff      time.Duration `mapstructure:"retry_backoff"`
-	Logging           LoggingConfig `mapstructure:"logging"`
+	// OperationTimeouts overrides OperationTimeout per operation type, e.g. a
+	// short timeout for queries and a long one for syncs
+	OperationTimeouts map[string]time.Duration `mapstructure:"operation_timeouts"`
+	// RESTMapperRefresh is how often the agent drops its cached API discovery
+	// so newly installed CRDs are picked up. Zero disables periodic refresh.
+	RESTMapperRefresh time.Duration `mapstructure:"rest_mapper_refresh"`
+	// ReadinessInterval is how often the agent retries its readiness check
+	// after registering; the hub holds operations until the check passes
+	ReadinessInterval time.Duration `mapstructure:"readiness_interval"`
+	// ManifestURLAllowedHosts lists the hosts apply operations may fetch
+	// manifests from by URL. Empty disables fetching by URL.
+	ManifestURLAllowedHosts []string `mapstructure:"manifest_url_allowed_hosts"`
+	// HubURLs lists several hub instances to balance across; when set it is
+	// used over HubURL
+	HubURLs []string `mapstructure:"hub_urls"`
+	// HubSRV names a DNS SRV record listing the hub instances, e.g.
+	// "_mckmt-grpc._tcp.hub.example.com". When set it is used over HubURL and
+	// HubURLs and re-resolved every HubSRVRefresh.
+	HubSRV        string        `mapstructure:"hub_srv"`
+	HubSRVRefresh time.Duration `mapstructure:"hub_srv_refresh"`
+	// ShutdownTimeout bounds how long the agent spends reporting in-flight
+	// operations as interrupted when it stops
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// MetricsInterval is how often the agent streams node and pod counts to
+	// the hub. Zero disables metrics streaming.
+	MetricsInterval time.Duration `mapstructure:"metrics_interval"`
+	// LogStream selects the logs the agent ships to the hub
+	LogStream AgentLogStreamConfig `mapstructure:"log_stream"`
+	Logging   LoggingConfig        `mapstructure:"logging"`
+}
+
+// AgentLogStreamConfig configures the logs the agent streams to the hub
+type AgentLogStreamConfig struct {
+	// Source is "self" to ship the agent's own logs, or "pods" to tail the
+	// pods matching Selector in Namespace. Empty disables log streaming.
+	Source string `mapstructure:"source"`
+	// Level is the minimum level of the agent's own logs to ship
+	Level     string `mapstructure:"level"`
+	Namespace string `mapstructure:"namespace"`
+	Selector  string `mapstructure:"selector"`
+	// Container selects the container of multi-container pods; empty tails all of them
+	Container string `mapstructure:"container"`
+	// BufferSize bounds the entries held while the hub is unreachable; newer
+	// entries are dropped once it is full
+	BufferSize int `mapstructure:"buffer_size"`
 }
 
 // LoadAgentConfig loads agent configuration from file and environment variables
@@ -61,12 +105,27 @@ func LoadAgentConfig() (*AgentConfig, error) {
 // setAgentDefaults sets default values for agent configuration
 func setAgentDefaults() {
 	viper.SetDefault("hub_url", "localhost:8081")
+	viper.SetDefault("hub_urls", []string{})
+	viper.SetDefault("hub_srv", "")
+	viper.SetDefault("hub_srv_refresh", "1m")
 	viper.SetDefault("token", "")
 	viper.SetDefault("heartbeat_interval", "30s")
 	viper.SetDefault("reconnect_wait", "5s")
 	viper.SetDefault("operation_timeout", "5m")
+	viper.SetDefault("operation_timeouts", map[string]string{})
 	viper.SetDefault("max_retries", 3)
 	viper.SetDefault("retry_backoff", "1s")
+	viper.SetDefault("rest_mapper_refresh", "10m")
+	viper.SetDefault("readiness_interval", "5s")
+	viper.SetDefault("manifest_url_allowed_hosts", []string{})
+	viper.SetDefault("shutdown_timeout", "10s")
+	viper.SetDefault("metrics_interval", "30s")
+	viper.SetDefault("log_stream.source", "self")
+	viper.SetDefault("log_stream.level", "info")
+	viper.SetDefault("log_stream.namespace", "")
+	viper.SetDefault("log_stream.selector", "")
+	viper.SetDefault("log_stream.container", "")
+	viper.SetDefault("log_stream.buffer_size", 1000)
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
 }
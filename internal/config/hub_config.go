@@ -11,34 +11,71 @@ import (
 
 // HubConfig holds hub-specific configuration
 type HubConfig struct {
-	Server       ServerConfig       `mapstructure:"server"`
-	GRPC         GRPCConfig         `mapstructure:"grpc"`
-	Database     DatabaseConfig     `mapstructure:"database"`
-	Redis        RedisConfig        `mapstructure:"redis"`
-	Auth         AuthConfig         `mapstructure:"auth"`
-	Orchestrator OrchestratorConfig `mapstructure:"orchestrator"`
-	Logging      LoggingConfig      `mapstructure:"logging"`
-	Metrics      MetricsConfig      `mapstructure:"metrics"`
+	Server          ServerConfig          `mapstructure:"server"`
+	GRPC            GRPCConfig            `mapstructure:"grpc"`
+	Database        DatabaseConfig        `mapstructure:"database"`
+	Redis           RedisConfig           `mapstructure:"redis"`
+	Auth            AuthConfig            `mapstructure:"auth"`
+	Orchestrator    OrchestratorConfig    `mapstructure:"orchestrator"`
+	Clusters        ClustersConfig        `mapstructure:"clusters"`
+	Operations      OperationsConfig      `mapstructure:"operations"`
+	KubeClientCache KubeClientCacheConfig `mapstructure:"kube_client_cache"`
+	Logging         LoggingConfig         `mapstructure:"logging"`
+	Metrics         MetricsConfig         `mapstructure:"metrics"`
+	Audit           AuditConfig           `mapstructure:"audit"`
+	RateLimit       RateLimitConfig       `mapstructure:"rate_limit"`
+	Notifications   NotificationsConfig   `mapstructure:"notifications"`
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Host         string        `mapstructure:"host"`
-	Port         int           `mapstructure:"port"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
-	TLS          TLSConfig     `mapstructure:"tls"`
+	Host               string                   `mapstructure:"host"`
+	Port               int                      `mapstructure:"port"`
+	ReadTimeout        time.Duration            `mapstructure:"read_timeout"`
+	WriteTimeout       time.Duration            `mapstructure:"write_timeout"`
+	IdleTimeout        time.Duration            `mapstructure:"idle_timeout"`
+	RequestTimeout     time.Duration            `mapstructure:"request_timeout"`
+	RouteTimeouts      map[string]time.Duration `mapstructure:"route_timeouts"`
+	MaxWatchers        int                      `mapstructure:"max_watchers"`          // concurrent watch connections, 0 for no cap
+	MaxWatchersPerUser int                      `mapstructure:"max_watchers_per_user"` // concurrent watch connections per user, 0 for no cap
+	DefaultPageSize    int                      `mapstructure:"default_page_size"`     // list page size when no limit is given
+	MaxPageSize        int                      `mapstructure:"max_page_size"`         // larger limits are clamped to this
+	AdminAllowedCIDRs  []string                 `mapstructure:"admin_allowed_cidrs"`   // client networks allowed on the admin API, empty allows all
+	TrustedProxies     []string                 `mapstructure:"trusted_proxies"`       // proxy networks whose X-Forwarded-For is honoured, empty trusts none
+	TLS                TLSConfig                `mapstructure:"tls"`
 }
 
 // GRPCConfig holds gRPC server configuration
 type GRPCConfig struct {
-	Host         string        `mapstructure:"host"`
-	Port         int           `mapstructure:"port"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
-	TLS          TLSConfig     `mapstructure:"tls"`
+	Host                       string              `mapstructure:"host"`
+	Port                       int                 `mapstructure:"port"`
+	ReadTimeout                time.Duration       `mapstructure:"read_timeout"`
+	WriteTimeout               time.Duration       `mapstructure:"write_timeout"`
+	IdleTimeout                time.Duration       `mapstructure:"idle_timeout"`
+	HeartbeatTimeout           time.Duration       `mapstructure:"heartbeat_timeout"`
+	HeartbeatSweepInterval     time.Duration       `mapstructure:"heartbeat_sweep_interval"`
+	DisconnectGracePeriod      time.Duration       `mapstructure:"disconnect_grace_period"`
+	ClockSkewThreshold         time.Duration       `mapstructure:"clock_skew_threshold"`
+	MinAgentVersion            string              `mapstructure:"min_agent_version"`
+	BackpressureQueueDepth     int                 `mapstructure:"backpressure_queue_depth"`
+	BackpressureInterval       time.Duration       `mapstructure:"backpressure_interval"`
+	AllowedCIDRs               []string            `mapstructure:"allowed_cidrs"`
+	TrustedProxies             []string            `mapstructure:"trusted_proxies"`
+	MaxConcurrentRegistrations int                 `mapstructure:"max_concurrent_registrations"`
+	RegistrationWait           time.Duration       `mapstructure:"registration_wait"`
+	LogBatchSize               int                 `mapstructure:"log_batch_size"`
+	LogFlushInterval           time.Duration       `mapstructure:"log_flush_interval"`
+	Keepalive                  GRPCKeepaliveConfig `mapstructure:"keepalive"`
+	TLS                        TLSConfig           `mapstructure:"tls"`
+}
+
+// GRPCKeepaliveConfig holds the transport keepalive the gRPC server enforces
+type GRPCKeepaliveConfig struct {
+	Time                time.Duration `mapstructure:"time"`                  // ping agents idle this long
+	Timeout             time.Duration `mapstructure:"timeout"`               // close the connection when a ping isn't acknowledged in time
+	MaxConnectionIdle   time.Duration `mapstructure:"max_connection_idle"`   // close connections without RPCs this long; 0 never does
+	MinTime             time.Duration `mapstructure:"min_time"`              // minimum interval agents may ping at
+	PermitWithoutStream bool          `mapstructure:"permit_without_stream"` // allow agent pings without active RPCs
 }
 
 // LoadHubConfig loads hub configuration from file and environment variables
@@ -91,6 +128,13 @@ func setHubDefaults() {
 	viper.SetDefault("server.read_timeout", "30s")
 	viper.SetDefault("server.write_timeout", "30s")
 	viper.SetDefault("server.idle_timeout", "120s")
+	viper.SetDefault("server.request_timeout", "60s")
+	viper.SetDefault("server.max_watchers", 1000)
+	viper.SetDefault("server.max_watchers_per_user", 10)
+	viper.SetDefault("server.default_page_size", 10)
+	viper.SetDefault("server.max_page_size", 100)
+	viper.SetDefault("server.admin_allowed_cidrs", []string{})
+	viper.SetDefault("server.trusted_proxies", []string{})
 	viper.SetDefault("server.tls.enabled", false)
 	viper.SetDefault("server.tls.cert_file", "")
 	viper.SetDefault("server.tls.key_file", "")
@@ -101,6 +145,24 @@ func setHubDefaults() {
 	viper.SetDefault("grpc.read_timeout", "30s")
 	viper.SetDefault("grpc.write_timeout", "30s")
 	viper.SetDefault("grpc.idle_timeout", "120s")
+	viper.SetDefault("grpc.heartbeat_timeout", "90s")
+	viper.SetDefault("grpc.heartbeat_sweep_interval", "30s")
+	viper.SetDefault("grpc.disconnect_grace_period", "2m")
+	viper.SetDefault("grpc.clock_skew_threshold", "30s")
+	viper.SetDefault("grpc.min_agent_version", "")
+	viper.SetDefault("grpc.backpressure_queue_depth", 0)
+	viper.SetDefault("grpc.backpressure_interval", "2m")
+	viper.SetDefault("grpc.allowed_cidrs", []string{})
+	viper.SetDefault("grpc.trusted_proxies", []string{})
+	viper.SetDefault("grpc.max_concurrent_registrations", 0)
+	viper.SetDefault("grpc.registration_wait", "5s")
+	viper.SetDefault("grpc.log_batch_size", 100)
+	viper.SetDefault("grpc.log_flush_interval", "2s")
+	viper.SetDefault("grpc.keepalive.time", "30s")
+	viper.SetDefault("grpc.keepalive.timeout", "10s")
+	viper.SetDefault("grpc.keepalive.max_connection_idle", "0s")
+	viper.SetDefault("grpc.keepalive.min_time", "5s")
+	viper.SetDefault("grpc.keepalive.permit_without_stream", true)
 	viper.SetDefault("grpc.tls.enabled", false)
 	viper.SetDefault("grpc.tls.cert_file", "")
 	viper.SetDefault("grpc.tls.key_file", "")
@@ -130,11 +192,18 @@ func setHubDefaults() {
 	viper.SetDefault("auth.oidc.client_secret", "your-oidc-client-secret")
 	viper.SetDefault("auth.oidc.redirect_url", "http://localhost:8080/api/v1/auth/oidc/callback")
 	viper.SetDefault("auth.oidc.scopes", []string{"openid", "profile", "email", "groups"})
+	viper.SetDefault("auth.oidc.allowed_redirect_origins", []string{})
 
 	viper.SetDefault("auth.jwt.secret", "your-super-secret-jwt-key-change-in-production")
 	viper.SetDefault("auth.jwt.expiration", "24h")
+	viper.SetDefault("auth.jwt.refresh_expiration", "168h")
 	viper.SetDefault("auth.jwt.issuer", "mckmt")
 	viper.SetDefault("auth.jwt.audience", "mckmt-users")
+	viper.SetDefault("auth.jwt.refresh_token_reuse_detection", true)
+
+	viper.SetDefault("auth.lockout.enabled", true)
+	viper.SetDefault("auth.lockout.threshold", 5)
+	viper.SetDefault("auth.lockout.window", "15m")
 
 	viper.SetDefault("auth.password.enabled", true)
 	viper.SetDefault("auth.password.min_length", 8)
@@ -146,6 +215,7 @@ func setHubDefaults() {
 	// RBAC defaults
 	viper.SetDefault("auth.rbac.strategy", "database-rbac")
 	viper.SetDefault("auth.rbac.default_role", "viewer")
+	viper.SetDefault("auth.rbac.max_roles_per_user", 20)
 	viper.SetDefault("auth.rbac.casbin.enabled", true)
 	viper.SetDefault("auth.rbac.casbin.model_file", "configs/casbin-model.conf")
 	viper.SetDefault("auth.rbac.casbin.policy_file", "")
@@ -154,6 +224,35 @@ func setHubDefaults() {
 
 	// Orchestrator defaults
 	viper.SetDefault("orchestrator.workers", 5)
+	viper.SetDefault("orchestrator.queue_ordering", "fifo")
+	viper.SetDefault("orchestrator.recovery_batch_size", 100)
+	viper.SetDefault("orchestrator.recovery_batch_delay", "1s")
+
+	// Cluster defaults
+	viper.SetDefault("clusters.labels.max_count", 64)
+	viper.SetDefault("clusters.labels.max_key_length", 128)
+	viper.SetDefault("clusters.labels.max_value_length", 256)
+	viper.SetDefault("clusters.labels.enforce_kube_syntax", false)
+	viper.SetDefault("clusters.verify_exists_on_queue", true)
+
+	// Operation defaults
+	viper.SetDefault("operations.cache_ttl", "10s")
+	viper.SetDefault("operations.result_cache_ttl", "5m")
+	viper.SetDefault("operations.max_payload_size", 10<<20)
+	viper.SetDefault("operations.max_manifest_documents", 1000)
+	viper.SetDefault("operations.mask_secrets", true)
+	viper.SetDefault("operations.manifest_hash", true)
+	viper.SetDefault("operations.policy.enabled", false)
+	viper.SetDefault("operations.policy.url", "http://localhost:8181")
+	viper.SetDefault("operations.policy.path", "mckmt/admission/deny")
+	viper.SetDefault("operations.policy.timeout", "5s")
+	viper.SetDefault("operations.policy.fail_open", false)
+	viper.SetDefault("operations.dead_letter.enabled", false)
+	viper.SetDefault("operations.dead_letter.max_retries", 3)
+
+	// Kube client cache defaults
+	viper.SetDefault("kube_client_cache.idle_timeout", "15m")
+	viper.SetDefault("kube_client_cache.eviction_interval", "1m")
 
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
@@ -167,6 +266,43 @@ func setHubDefaults() {
 	viper.SetDefault("metrics.enabled", true)
 	viper.SetDefault("metrics.path", "/metrics")
 	viper.SetDefault("metrics.port", 9091)
+	viper.SetDefault("metrics.cluster_reconcile_interval", "1m")
+	viper.SetDefault("metrics.agent_metric_prefixes", []string{"agent_", "cluster_"})
+	viper.SetDefault("metrics.agent_max_metrics", 100)
+
+	// Audit defaults
+	viper.SetDefault("audit.enabled", true)
+	viper.SetDefault("audit.include_request_payload", false)
+	viper.SetDefault("audit.include_response_payload", false)
+	viper.SetDefault("audit.max_payload_size", 65536)
+	viper.SetDefault("audit.exclude_routes", []string{})
+	viper.SetDefault("audit.permission_denials", true)
+	viper.SetDefault("audit.permission_allows", false)
+
+	// Rate limit defaults
+	viper.SetDefault("rate_limit.enabled", false)
+	viper.SetDefault("rate_limit.requests_per_second", 10)
+	viper.SetDefault("rate_limit.burst", 20)
+	viper.SetDefault("rate_limit.exempt_roles", []string{"admin"})
+	viper.SetDefault("rate_limit.exempt_tokens", []string{})
+	viper.SetDefault("rate_limit.auth.enabled", true)
+	viper.SetDefault("rate_limit.auth.requests", 10)
+	viper.SetDefault("rate_limit.auth.window", "1m")
+
+	// Notification defaults
+	viper.SetDefault("notifications.slack.enabled", false)
+	viper.SetDefault("notifications.slack.webhook_url", "")
+	viper.SetDefault("notifications.slack.events", []string{"operation.failed", "cluster.disconnected"})
+	viper.SetDefault("notifications.email.enabled", false)
+	viper.SetDefault("notifications.email.host", "localhost")
+	viper.SetDefault("notifications.email.port", 25)
+	viper.SetDefault("notifications.email.username", "")
+	viper.SetDefault("notifications.email.password", "")
+	viper.SetDefault("notifications.email.from", "")
+	viper.SetDefault("notifications.email.to", []string{})
+	viper.SetDefault("notifications.email.events", []string{"operation.failed", "cluster.disconnected"})
+	viper.SetDefault("notifications.templates.operation_failed", "")
+	viper.SetDefault("notifications.templates.cluster_disconnected", "")
 }
 
 // Addr returns the server address
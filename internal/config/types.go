@@ -46,34 +46,50 @@ type RedisConfig struct {
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	OIDC OIDCConfig `mapstructure:"oidc"`
-	JWT  JWTConfig  `mapstructure:"jwt"`
-	RBAC RBACConfig `mapstructure:"rbac"`
+	OIDC    OIDCConfig    `mapstructure:"oidc"`
+	JWT     JWTConfig     `mapstructure:"jwt"`
+	RBAC    RBACConfig    `mapstructure:"rbac"`
+	Lockout LockoutConfig `mapstructure:"lockout"`
+}
+
+// LockoutConfig holds the lockout of usernames after repeated failed logins
+type LockoutConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Threshold int           `mapstructure:"threshold"` // consecutive failed logins that lock a username
+	Window    time.Duration `mapstructure:"window"`    // how long a username stays locked, and failures are remembered
 }
 
 // OIDCConfig holds OIDC configuration
 type OIDCConfig struct {
-	Enabled      bool     `mapstructure:"enabled"`
-	Issuer       string   `mapstructure:"issuer"`
-	ClientID     string   `mapstructure:"client_id"`
-	ClientSecret string   `mapstructure:"client_secret"`
-	RedirectURL  string   `mapstructure:"redirect_url"`
-	Scopes       []string `mapstructure:"scopes"`
+	Enabled                bool     `mapstructure:"enabled"`
+	Issuer                 string   `mapstructure:"issuer"`
+	ClientID               string   `mapstructure:"client_id"`
+	ClientSecret           string   `mapstructure:"client_secret"`
+	RedirectURL            string   `mapstructure:"redirect_url"`
+	Scopes                 []string `mapstructure:"scopes"`
+	AllowedRedirectOrigins []string `mapstructure:"allowed_redirect_origins"`
 }
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
 	Secret     string        `mapstructure:"secret"`
-	Expiration time.Duration `mapstructure:"expiration"`
-	Issuer     string        `mapstructure:"issuer"`
-	Audience   string        `mapstructure:"audience"`
+	Expiration time.Duration `mapstructure:"expiration"` // access token lifetime
+	// RefreshExpiration is the lifetime of refresh tokens, normally longer
+	// than that of access tokens
+	RefreshExpiration time.Duration `mapstructure:"refresh_expiration"`
+	Issuer            string        `mapstructure:"issuer"`
+	Audience          string        `mapstructure:"audience"`
+	// RefreshTokenReuseDetection revokes a refresh token's whole family when an
+	// already rotated token of it is presented again
+	RefreshTokenReuseDetection bool `mapstructure:"refresh_token_reuse_detection"`
 }
 
 // RBACConfig holds RBAC configuration
 type RBACConfig struct {
-	Strategy    string       `mapstructure:"strategy"` // "no-auth", "database-rbac", "casbin"
-	DefaultRole string       `mapstructure:"default_role"`
-	Casbin      CasbinConfig `mapstructure:"casbin"`
+	Strategy        string       `mapstructure:"strategy"` // "no-auth", "database-rbac", "casbin"
+	DefaultRole     string       `mapstructure:"default_role"`
+	MaxRolesPerUser int          `mapstructure:"max_roles_per_user"` // roles assignable to a single user, 0 for no cap
+	Casbin          CasbinConfig `mapstructure:"casbin"`
 }
 
 // CasbinConfig holds Casbin-specific configuration
@@ -87,14 +103,149 @@ type CasbinConfig struct {
 
 // OrchestratorConfig holds orchestrator configuration
 type OrchestratorConfig struct {
-	Workers int `mapstructure:"workers"`
+	Workers              int                       `mapstructure:"workers"`
+	QueueOrdering        string                    `mapstructure:"queue_ordering"`         // fifo or lifo
+	ClusterQueueOrdering map[string]string         `mapstructure:"cluster_queue_ordering"` // cluster ID -> fifo or lifo
+	TypeWorkers          map[string]int            `mapstructure:"type_workers"`           // operation type -> dedicated workers
+	RecoveryBatchSize    int                       `mapstructure:"recovery_batch_size"`    // operations re-queued at a time on startup
+	RecoveryBatchDelay   time.Duration             `mapstructure:"recovery_batch_delay"`   // wait between recovery batches
+	MaintenanceWindows   []MaintenanceWindowConfig `mapstructure:"maintenance_windows"`
+}
+
+// MaintenanceWindowConfig restricts operation types to a daily window
+type MaintenanceWindowConfig struct {
+	Types    []string `mapstructure:"types"`    // operation types held until the window opens
+	Start    string   `mapstructure:"start"`    // HH:MM
+	End      string   `mapstructure:"end"`      // HH:MM, may be before start to run past midnight
+	Timezone string   `mapstructure:"timezone"` // IANA time zone, UTC when empty
+}
+
+// OperationsConfig holds operation service configuration
+type OperationsConfig struct {
+	CacheTTL       time.Duration `mapstructure:"cache_ttl"`
+	ResultCacheTTL time.Duration `mapstructure:"result_cache_ttl"`
+	MaxPayloadSize int           `mapstructure:"max_payload_size"`
+	MaskSecrets    bool          `mapstructure:"mask_secrets"`
+	ManifestHash   bool          `mapstructure:"manifest_hash"`
+
+	// MaxManifestDocuments bounds the YAML documents of an apply; zero means no limit
+	MaxManifestDocuments int `mapstructure:"max_manifest_documents"`
+
+	Policy     OperationPolicyConfig     `mapstructure:"policy"`
+	DeadLetter OperationDeadLetterConfig `mapstructure:"dead_letter"`
+}
+
+// OperationPolicyConfig holds the Open Policy Agent server operations are
+// reviewed by before they are queued
+type OperationPolicyConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	URL      string        `mapstructure:"url"`       // OPA server, e.g. http://localhost:8181
+	Path     string        `mapstructure:"path"`      // data path of the rule listing denial reasons
+	Timeout  time.Duration `mapstructure:"timeout"`   // bound on a policy query
+	FailOpen bool          `mapstructure:"fail_open"` // admit operations when OPA can't be queried
+}
+
+// OperationDeadLetterConfig holds the retries of failed operations before they
+// are moved to the dead-letter queue
+type OperationDeadLetterConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	MaxRetries int  `mapstructure:"max_retries"` // retries of a retryable failure; permanent failures aren't retried
+}
+
+// KubeClientCacheConfig holds configuration for the hub-side per-cluster kube client cache
+type KubeClientCacheConfig struct {
+	IdleTimeout      time.Duration `mapstructure:"idle_timeout"`
+	EvictionInterval time.Duration `mapstructure:"eviction_interval"`
+}
+
+// ClustersConfig holds cluster management configuration
+type ClustersConfig struct {
+	Labels              LabelsConfig `mapstructure:"labels"`
+	VerifyExistsOnQueue bool         `mapstructure:"verify_exists_on_queue"`
+}
+
+// LabelsConfig holds limits applied to cluster labels
+type LabelsConfig struct {
+	MaxCount          int  `mapstructure:"max_count"`
+	MaxKeyLength      int  `mapstructure:"max_key_length"`
+	MaxValueLength    int  `mapstructure:"max_value_length"`
+	EnforceKubeSyntax bool `mapstructure:"enforce_kube_syntax"`
 }
 
 // MetricsConfig holds metrics configuration
 type MetricsConfig struct {
-	Enabled bool   `mapstructure:"enabled"`
-	Path    string `mapstructure:"path"`
-	Port    int    `mapstructure:"port"`
+	Enabled                  bool          `mapstructure:"enabled"`
+	Path                     string        `mapstructure:"path"`
+	Port                     int           `mapstructure:"port"`
+	ClusterReconcileInterval time.Duration `mapstructure:"cluster_reconcile_interval"`
+	// AgentMetricPrefixes are the prefixes of the metric names agents may
+	// stream; other metrics are dropped. AgentMaxMetrics bounds the number of
+	// distinct agent metric names.
+	AgentMetricPrefixes []string `mapstructure:"agent_metric_prefixes"`
+	AgentMaxMetrics     int      `mapstructure:"agent_max_metrics"`
+}
+
+// AuditConfig holds audit logging configuration for mutating API calls
+type AuditConfig struct {
+	Enabled                bool     `mapstructure:"enabled"`
+	IncludeRequestPayload  bool     `mapstructure:"include_request_payload"`
+	IncludeResponsePayload bool     `mapstructure:"include_response_payload"`
+	MaxPayloadSize         int      `mapstructure:"max_payload_size"` // in bytes
+	ExcludeRoutes          []string `mapstructure:"exclude_routes"`   // route patterns, e.g. "/api/v1/auth/permissions:check"
+	// PermissionDenials records the permissions the permission middlewares
+	// deny; PermissionAllows also records the ones they grant
+	PermissionDenials bool `mapstructure:"permission_denials"`
+	PermissionAllows  bool `mapstructure:"permission_allows"`
+}
+
+// RateLimitConfig holds per-user API rate limiting configuration
+type RateLimitConfig struct {
+	Enabled           bool                `mapstructure:"enabled"`
+	RequestsPerSecond float64             `mapstructure:"requests_per_second"` // sustained rate per user or API token
+	Burst             int                 `mapstructure:"burst"`               // requests allowed at once after a quiet period
+	ExemptRoles       []string            `mapstructure:"exempt_roles"`        // roles that are never limited
+	ExemptTokens      []string            `mapstructure:"exempt_tokens"`       // personal access token IDs that are never limited
+	Auth              AuthRateLimitConfig `mapstructure:"auth"`
+}
+
+// AuthRateLimitConfig holds per-IP rate limiting of the unauthenticated auth endpoints
+type AuthRateLimitConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Requests int           `mapstructure:"requests"` // requests allowed per client IP within window
+	Window   time.Duration `mapstructure:"window"`
+}
+
+// NotificationsConfig holds the built-in notifiers of hub events
+type NotificationsConfig struct {
+	Slack     SlackNotificationConfig     `mapstructure:"slack"`
+	Email     EmailNotificationConfig     `mapstructure:"email"`
+	Templates NotificationTemplatesConfig `mapstructure:"templates"`
+}
+
+// NotificationTemplatesConfig holds message templates (Go text/template) that
+// replace the defaults. The first line of a rendered message is its subject.
+type NotificationTemplatesConfig struct {
+	OperationFailed     string `mapstructure:"operation_failed"`
+	ClusterDisconnected string `mapstructure:"cluster_disconnected"`
+}
+
+// SlackNotificationConfig holds the Slack incoming webhook notifier configuration
+type SlackNotificationConfig struct {
+	Enabled    bool     `mapstructure:"enabled"`
+	WebhookURL string   `mapstructure:"webhook_url"`
+	Events     []string `mapstructure:"events"` // e.g. "operation.failed", "cluster.disconnected"
+}
+
+// EmailNotificationConfig holds the SMTP email notifier configuration
+type EmailNotificationConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	Host     string   `mapstructure:"host"`
+	Port     int      `mapstructure:"port"`
+	Username string   `mapstructure:"username"` // SMTP authentication is skipped when empty
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+	Events   []string `mapstructure:"events"`
 }
 
 // DSN returns the database connection string
@@ -238,8 +238,9 @@ spec:
 
 		handler.ListClusterResources(w, req)
 
-		if w.Code != http.StatusOK {
-			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		// No agent has listed the resources yet, so the listing is queued
+		if w.Code != http.StatusAccepted {
+			t.Errorf("Expected status %d, got %d", http.StatusAccepted, w.Code)
 		}
 
 		var response map[string]interface{}
@@ -248,12 +249,8 @@ spec:
 			t.Fatalf("Failed to unmarshal response: %v", err)
 		}
 
-		if resources, ok := response["resources"].([]interface{}); !ok {
-			t.Error("Expected 'resources' field in response")
-		} else {
-			// In a real environment, we would verify the actual resources
-			// For this test, we just ensure the API call succeeds
-			_ = resources
+		if operationID, ok := response["operation_id"].(string); !ok || operationID == "" {
+			t.Error("Expected 'operation_id' field in response")
 		}
 	})
 
@@ -303,8 +300,8 @@ func TestEndToEndErrorHandlingIntegration(t *testing.T) {
 
 	handler := apihandler.NewClusterHandler(suite.ClusterService, suite.Logger)
 
-	// Test 1: Apply manifests to non-existent cluster
-	t.Run("apply_manifests_to_nonexistent_cluster", func(t *testing.T) {
+	// applyToNonexistentCluster posts manifests for a cluster that was never registered
+	applyToNonexistentCluster := func(t *testing.T) *httptest.ResponseRecorder {
 		nonexistentClusterID := uuid.New()
 
 		var buf bytes.Buffer
@@ -335,6 +332,25 @@ metadata:
 		req = addURLParams(req, "id", nonexistentClusterID.String())
 
 		handler.ApplyManifests(w, req)
+		return w
+	}
+
+	// Test 1: Apply manifests to non-existent cluster
+	t.Run("apply_manifests_to_nonexistent_cluster", func(t *testing.T) {
+		w := applyToNonexistentCluster(t)
+
+		// The cluster existence check rejects the operation up front
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	// Test 1b: Apply manifests to non-existent cluster with the existence check disabled
+	t.Run("apply_manifests_to_nonexistent_cluster_without_check", func(t *testing.T) {
+		suite.ClusterService.SetVerifyClusterExists(false)
+		defer suite.ClusterService.SetVerifyClusterExists(true)
+
+		w := applyToNonexistentCluster(t)
 
 		// Should still create operation even for non-existent cluster
 		// (the cluster existence check happens at operation execution time)
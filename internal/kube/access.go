@@ -0,0 +1,91 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AccessCheck describes a Kubernetes API permission. An empty namespace checks
+// the permission across all namespaces.
+type AccessCheck struct {
+	Verb        string `json:"verb"`
+	Group       string `json:"group"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+}
+
+// String formats the check like "create pods/exec"
+func (c AccessCheck) String() string {
+	resource := c.Resource
+	if c.Subresource != "" {
+		resource += "/" + c.Subresource
+	}
+	if c.Group != "" {
+		resource += "." + c.Group
+	}
+	return c.Verb + " " + resource
+}
+
+// AccessCheckResult is the outcome of an access check
+type AccessCheckResult struct {
+	AccessCheck
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// PermissionReport holds access check results keyed by the operation type that
+// needs the permissions
+type PermissionReport map[string][]AccessCheckResult
+
+// MissingOperationTypes returns the operation types with at least one denied
+// check, in sorted order
+func (r PermissionReport) MissingOperationTypes() []string {
+	missing := make([]string, 0)
+	for opType, results := range r {
+		for _, result := range results {
+			if !result.Allowed {
+				missing = append(missing, opType)
+				break
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// CheckAccess reports whether the client's own identity holds each permission,
+// using SelfSubjectAccessReviews
+func (c *Client) CheckAccess(ctx context.Context, checks []AccessCheck) ([]AccessCheckResult, error) {
+	results := make([]AccessCheckResult, 0, len(checks))
+	for _, check := range checks {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   check.Namespace,
+					Verb:        check.Verb,
+					Group:       check.Group,
+					Resource:    check.Resource,
+					Subresource: check.Subresource,
+				},
+			},
+		}
+
+		response, err := c.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review access for %s: %w", check, err)
+		}
+
+		results = append(results, AccessCheckResult{
+			AccessCheck: check,
+			Allowed:     response.Status.Allowed,
+			Reason:      response.Status.Reason,
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,191 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+)
+
+// ApplyStatus is the outcome of applying a single manifest document
+type ApplyStatus string
+
+// Apply outcomes
+const (
+	ApplyCreated ApplyStatus = "created"
+	ApplyUpdated ApplyStatus = "updated"
+	// ApplyUnchanged objects already matched the manifest
+	ApplyUnchanged ApplyStatus = "unchanged"
+	ApplyFailed    ApplyStatus = "failed"
+	// ApplySkipped documents were not applied because the apply was cancelled
+	ApplySkipped ApplyStatus = "skipped"
+)
+
+// AppliedObject reports the outcome of applying one manifest document. The
+// object fields are empty when the document could not be decoded.
+type AppliedObject struct {
+	Index      int         `json:"index"`
+	APIVersion string      `json:"api_version,omitempty"`
+	Kind       string      `json:"kind,omitempty"`
+	Namespace  string      `json:"namespace,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	Status     ApplyStatus `json:"status"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// ApplyResult lists the outcome of every document of an applied manifest, in
+// document order. For a dry run the outcomes are those the apply would have.
+type ApplyResult struct {
+	Objects []AppliedObject `json:"objects"`
+	DryRun  bool            `json:"dry_run"`
+}
+
+// Count returns the number of documents with the given outcome
+func (r *ApplyResult) Count(status ApplyStatus) int {
+	count := 0
+	for _, obj := range r.Objects {
+		if obj.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// ApplyManifest applies every document of a Kubernetes manifest in order using
+// server-side apply. A failing document doesn't stop the following ones; the
+// returned error joins the failures of all documents. Once ctx is cancelled the
+// remaining documents are skipped. With dryRun the API server validates and
+// admits the objects without persisting them. The result is nil only when the
+// manifest cannot be split into documents.
+func (c *Client) ApplyManifest(ctx context.Context, manifest []byte, namespace string, dryRun bool) (*ApplyResult, error) {
+	return c.applyManifest(ctx, manifest, namespace, "", dryRun)
+}
+
+// applyManifest applies manifest like ApplyManifest. A non-empty applySet
+// labels the objects it creates as owned by that apply-set.
+func (c *Client) applyManifest(ctx context.Context, manifest []byte, namespace, applySet string, dryRun bool) (*ApplyResult, error) {
+	documents, err := SplitDocuments(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ApplyResult{Objects: make([]AppliedObject, 0, len(documents)), DryRun: dryRun}
+	var errs []error
+	for i, document := range documents {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			for j := i; j < len(documents); j++ {
+				result.Objects = append(result.Objects, AppliedObject{Index: j, Status: ApplySkipped})
+			}
+			errs = append(errs, fmt.Errorf("skipped %d documents: %w", len(documents)-i, ctxErr))
+			break
+		}
+
+		applied := AppliedObject{Index: i}
+		if err := c.applyDocument(ctx, document, namespace, applySet, dryRun, &applied); err != nil {
+			applied.Status = ApplyFailed
+			applied.Error = err.Error()
+			errs = append(errs, fmt.Errorf("document %d: %w", i, err))
+		}
+		result.Objects = append(result.Objects, applied)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// applyDocument decodes and applies a single document, recording the object
+// and its outcome in applied. The object is looked up first to tell creations
+// and updates from objects left unchanged, since server-side apply reports
+// none of them. Objects created with a non-empty applySet, or already owned by
+// it, are labeled as owned by it.
+func (c *Client) applyDocument(ctx context.Context, document []byte, namespace, applySet string, dryRun bool, applied *AppliedObject) error {
+	obj, err := DecodeDocument(document)
+	if err != nil {
+		return err
+	}
+
+	applied.APIVersion = obj.GetAPIVersion()
+	applied.Kind = obj.GetKind()
+	applied.Namespace = obj.GetNamespace()
+	applied.Name = obj.GetName()
+
+	mapping, err := c.restMapping(obj.GroupVersionKind())
+	if err != nil {
+		return fmt.Errorf("failed to get REST mapping: %w", err)
+	}
+
+	// Default the namespace of namespaced objects
+	if obj.GetNamespace() == "" && namespace != "" && mapping.Scope.Name() != meta.RESTScopeNameRoot {
+		obj.SetNamespace(namespace)
+		applied.Namespace = namespace
+	}
+
+	resource := c.dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+
+	existing, err := resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		existing = nil
+	}
+
+	// Objects that existed before are never adopted, so pruning can't delete
+	// what mckmt didn't create. Owned objects keep the labels, which server-side
+	// apply would otherwise remove.
+	if applySet != "" && (existing == nil || existing.GetLabels()[ApplySetLabel] == applySet) {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[ManagedByLabel] = ManagedByValue
+		labels[ApplySetLabel] = applySet
+		obj.SetLabels(labels)
+	}
+
+	options := applyOptions(dryRun)
+
+	var result *unstructured.Unstructured
+	err = retry.OnError(retry.DefaultRetry, isRetryableError, func() error {
+		var applyErr error
+		result, applyErr = resource.Apply(ctx, obj.GetName(), obj, options)
+		return applyErr
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to apply manifest: %w", err)
+	}
+
+	switch {
+	case existing == nil:
+		applied.Status = ApplyCreated
+	case ContentHash(existing) == ContentHash(result):
+		applied.Status = ApplyUnchanged
+	default:
+		applied.Status = ApplyUpdated
+	}
+
+	c.logger.Info("Manifest applied successfully",
+		zap.String("kind", result.GetKind()),
+		zap.String("name", result.GetName()),
+		zap.String("namespace", result.GetNamespace()),
+		zap.String("status", string(applied.Status)),
+		zap.Bool("dry_run", dryRun),
+	)
+
+	return nil
+}
+
+// applyOptions returns the server-side apply options of the agent
+func applyOptions(dryRun bool) metav1.ApplyOptions {
+	options := metav1.ApplyOptions{FieldManager: "mckmt-agent"}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options
+}
@@ -0,0 +1,186 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var serviceGVK = schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Service"}
+
+// newApplyTestClient returns a client that knows pods, services and
+// namespaces and records the names of applied objects. The fake dynamic
+// client doesn't support server-side apply, so applies are answered with the
+// applied object.
+func newApplyTestClient(applied *[]string, objects ...runtime.Object) *Client {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(podGVK, meta.RESTScopeNamespace)
+	mapper.Add(serviceGVK, meta.RESTScopeNamespace)
+	mapper.Add(namespaceGVK, meta.RESTScopeRoot)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		*applied = append(*applied, patch.GetName())
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		return true, obj, nil
+	})
+
+	return &Client{dynamicClient: dynamicClient, restMapper: mapper, logger: zap.NewNop()}
+}
+
+func TestClient_ApplyManifestMultipleDocuments(t *testing.T) {
+	manifest := []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  labels:
+    app: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: other
+`)
+
+	var applied []string
+	client := newApplyTestClient(&applied, newTestObject(podGVK, "default", "web"))
+
+	result, err := client.ApplyManifest(context.Background(), manifest, "default", false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Fatalf("Expected both documents to be applied but got %v", applied)
+	}
+
+	expected := []AppliedObject{
+		{Index: 0, APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "web", Status: ApplyUpdated},
+		{Index: 1, APIVersion: "v1", Kind: "Service", Namespace: "other", Name: "web", Status: ApplyCreated},
+	}
+	if len(result.Objects) != len(expected) {
+		t.Fatalf("Expected %d objects but got %+v", len(expected), result.Objects)
+	}
+	for i := range expected {
+		if result.Objects[i] != expected[i] {
+			t.Errorf("Expected object %d to be %+v but got %+v", i, expected[i], result.Objects[i])
+		}
+	}
+}
+
+func TestClient_ApplyManifestLeadingComments(t *testing.T) {
+	manifest := []byte(`# Managed by mckmt
+# Do not edit
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: team-a
+`)
+
+	var applied []string
+	client := newApplyTestClient(&applied)
+
+	result, err := client.ApplyManifest(context.Background(), manifest, "default", false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Name != "team-a" {
+		t.Fatalf("Expected the namespace to be applied but got %+v", result.Objects)
+	}
+	// Cluster-scoped objects don't get the default namespace
+	if result.Objects[0].Namespace != "" {
+		t.Errorf("Expected no namespace on a Namespace but got %q", result.Objects[0].Namespace)
+	}
+}
+
+func TestClient_ApplyManifestAggregatesFailures(t *testing.T) {
+	manifest := []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: first
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: second
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: third
+`)
+
+	var applied []string
+	client := newApplyTestClient(&applied)
+
+	result, err := client.ApplyManifest(context.Background(), manifest, "default", false)
+	if err == nil {
+		t.Fatal("Expected an error for the unknown kind")
+	}
+
+	// The failure of the third document doesn't hide that the first two applied
+	if len(applied) != 2 {
+		t.Errorf("Expected the first two documents to be applied but got %v", applied)
+	}
+	if result.Count(ApplyCreated) != 2 || result.Count(ApplyFailed) != 1 {
+		t.Errorf("Expected 2 created and 1 failed but got %+v", result.Objects)
+	}
+	if third := result.Objects[2]; third.Kind != "Widget" || third.Error == "" {
+		t.Errorf("Expected the Widget to be reported with its error but got %+v", third)
+	}
+}
+
+func TestClient_ApplyManifestSkipsAfterCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var applied []string
+	client := newApplyTestClient(&applied)
+
+	result, err := client.ApplyManifest(ctx, []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n"), "default", false)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled but got: %v", err)
+	}
+	if len(applied) != 0 || result.Count(ApplySkipped) != 1 {
+		t.Errorf("Expected the document to be skipped but got %+v", result.Objects)
+	}
+}
+
+func TestClient_ApplyManifestReportsUnchangedObjects(t *testing.T) {
+	existing := newTestObject(podGVK, "default", "web")
+	existing.SetLabels(map[string]string{"app": "web"})
+
+	var applied []string
+	client := newApplyTestClient(&applied, existing)
+
+	manifest := []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n  labels:\n    app: web\n")
+	result, err := client.ApplyManifest(context.Background(), manifest, "default", true)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !result.DryRun || result.Objects[0].Status != ApplyUnchanged {
+		t.Errorf("Expected an unchanged dry run result but got %+v", result)
+	}
+}
+
+func TestApplyOptions(t *testing.T) {
+	if options := applyOptions(false); len(options.DryRun) != 0 || options.FieldManager != "mckmt-agent" {
+		t.Errorf("Expected a persisting apply by mckmt-agent but got %+v", options)
+	}
+	if options := applyOptions(true); len(options.DryRun) != 1 || options.DryRun[0] != metav1.DryRunAll {
+		t.Errorf("Expected a server-side dry run but got %+v", options)
+	}
+}
@@ -3,16 +3,20 @@ package kube
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
@@ -22,9 +26,17 @@ import (
 	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
-	"k8s.io/client-go/util/retry"
+	utilexec "k8s.io/client-go/util/exec"
 )
 
+// ErrNamespaceNotAllowed is returned when a namespace is given for a kind the
+// REST mapper reports as cluster-scoped
+var ErrNamespaceNotAllowed = errors.New("namespace not allowed for cluster-scoped resource")
+
+// ReasonNamespaceNotAllowed is the reason agents report operations failing
+// with ErrNamespaceNotAllowed under, so the hub can tell them apart
+const ReasonNamespaceNotAllowed = "NamespaceNotAllowed"
+
 // Client wraps Kubernetes clients for cluster operations
 type Client struct {
 	clientset     kubernetes.Interface
@@ -57,6 +69,11 @@ func NewClient(kubeconfig []byte, logger *zap.Logger) (*Client, error) {
 	// Set reasonable timeouts
 	config.Timeout = 30 * time.Second
 
+	return newClientForConfig(config, logger)
+}
+
+// newClientForConfig creates the Kubernetes clients for a REST config
+func newClientForConfig(config *rest.Config, logger *zap.Logger) (*Client, error) {
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -91,64 +108,85 @@ func NewClient(kubeconfig []byte, logger *zap.Logger) (*Client, error) {
 	}, nil
 }
 
-// ApplyManifest applies a Kubernetes manifest using server-side apply
-func (c *Client) ApplyManifest(ctx context.Context, manifest []byte, namespace string) error {
-	// Parse YAML manifest
-	decoder := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
-	obj := &unstructured.Unstructured{}
-
-	_, _, err := decoder.Decode(manifest, nil, obj)
-	if err != nil {
-		return fmt.Errorf("failed to decode manifest: %w", err)
+// NewClientWithInterfaces creates a client from already constructed Kubernetes clients.
+// It is used when the caller manages client construction itself, e.g. with fake clients in tests.
+func NewClientWithInterfaces(clientset kubernetes.Interface, dynamicClient dynamic.Interface, restMapper meta.RESTMapper, logger *zap.Logger) *Client {
+	return &Client{
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		restMapper:    restMapper,
+		discovery:     clientset.Discovery(),
+		logger:        logger,
 	}
+}
 
-	// Get GVR (GroupVersionResource)
-	gvk := obj.GroupVersionKind()
+// restMapping resolves the REST mapping for gvk. When the kind is unknown, the
+// discovery cache is reset once and the lookup retried so that CRDs installed
+// after the agent started become usable without a restart.
+func (c *Client) restMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
 	mapping, err := c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
-	if err != nil {
-		return fmt.Errorf("failed to get REST mapping: %w", err)
+	if err == nil || !meta.IsNoMatchError(err) {
+		return mapping, err
 	}
 
-	// Set namespace if not specified
-	if obj.GetNamespace() == "" && namespace != "" {
-		obj.SetNamespace(namespace)
+	if !c.ResetRESTMapper() {
+		return nil, err
 	}
 
-	// Apply the manifest
-	resource := c.dynamicClient.Resource(mapping.Resource)
+	c.logger.Debug("REST mapping miss, retrying after discovery reset",
+		zap.String("group", gvk.Group),
+		zap.String("version", gvk.Version),
+		zap.String("kind", gvk.Kind),
+	)
 
-	var result *unstructured.Unstructured
-	err = retry.OnError(retry.DefaultRetry, func(err error) bool {
-		return true // Retry on any error
-	}, func() error {
-		var applyErr error
-		result, applyErr = resource.Namespace(obj.GetNamespace()).Apply(
-			ctx,
-			obj.GetName(),
-			obj,
-			metav1.ApplyOptions{
-				FieldManager: "mckmt-agent",
-			},
-		)
-		return applyErr
-	})
+	return c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to apply manifest: %w", err)
+// ResetRESTMapper invalidates the cached discovery information behind the REST mapper.
+// It returns false if the mapper doesn't support resetting.
+func (c *Client) ResetRESTMapper() bool {
+	resettable, ok := c.restMapper.(meta.ResettableRESTMapper)
+	if !ok {
+		return false
 	}
+	resettable.Reset()
+	return true
+}
 
-	c.logger.Info("Manifest applied successfully",
-		zap.String("kind", result.GetKind()),
-		zap.String("name", result.GetName()),
-		zap.String("namespace", result.GetNamespace()),
-	)
+// StartRESTMapperRefresh periodically resets the REST mapper until ctx is done
+func (c *Client) StartRESTMapperRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
 
-	return nil
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.ResetRESTMapper() {
+				c.logger.Debug("REST mapper discovery cache reset")
+			}
+		}
+	}
+}
+
+// isRetryableError reports whether an API error is transient and worth retrying.
+// Permanent failures such as validation or authorization errors fail fast.
+func isRetryableError(err error) bool {
+	return apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err)
 }
 
 // GetResource retrieves a Kubernetes resource
 func (c *Client) GetResource(ctx context.Context, gvk schema.GroupVersionKind, name, namespace string) (*unstructured.Unstructured, error) {
-	mapping, err := c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	mapping, err := c.restMapping(gvk)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get REST mapping: %w", err)
 	}
@@ -157,23 +195,72 @@ func (c *Client) GetResource(ctx context.Context, gvk schema.GroupVersionKind, n
 	return resource.Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
 }
 
-// ListResources lists Kubernetes resources
+// ListResources lists Kubernetes resources.
+// For namespaced kinds an empty namespace lists across all namespaces, while
+// cluster-scoped kinds must be listed without a namespace.
 func (c *Client) ListResources(ctx context.Context, gvk schema.GroupVersionKind, namespace string) (*unstructured.UnstructuredList, error) {
-	mapping, err := c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	mapping, err := c.restMapping(gvk)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get REST mapping: %w", err)
 	}
 
 	resource := c.dynamicClient.Resource(mapping.Resource)
-	if namespace == "" {
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		if namespace != "" {
+			return nil, fmt.Errorf("%w: %s", ErrNamespaceNotAllowed, gvk.Kind)
+		}
 		return resource.List(ctx, metav1.ListOptions{})
 	}
+
+	if namespace == "" {
+		// Namespaced kind without a namespace lists across all namespaces
+		return resource.Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	}
 	return resource.Namespace(namespace).List(ctx, metav1.ListOptions{})
 }
 
+// ListNamespaces lists the namespaces in the cluster
+func (c *Client) ListNamespaces(ctx context.Context) ([]NamespaceInfo, error) {
+	namespaces, err := c.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	result := make([]NamespaceInfo, 0, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		labels := ns.Labels
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		result = append(result, NamespaceInfo{
+			Name:      ns.Name,
+			Status:    string(ns.Status.Phase),
+			Labels:    labels,
+			CreatedAt: ns.CreationTimestamp.Time,
+		})
+	}
+
+	return result, nil
+}
+
+// GetConfigMapData returns the value stored under key in a ConfigMap
+func (c *Client) GetConfigMapData(ctx context.Context, namespace, name, key string) (string, error) {
+	configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get configmap %s/%s: %w", namespace, name, err)
+	}
+
+	value, ok := configMap.Data[key]
+	if !ok {
+		return "", fmt.Errorf("configmap %s/%s has no key %q", namespace, name, key)
+	}
+
+	return value, nil
+}
+
 // DeleteResource deletes a Kubernetes resource
 func (c *Client) DeleteResource(ctx context.Context, gvk schema.GroupVersionKind, name, namespace string) error {
-	mapping, err := c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	mapping, err := c.restMapping(gvk)
 	if err != nil {
 		return fmt.Errorf("failed to get REST mapping: %w", err)
 	}
@@ -182,8 +269,21 @@ func (c *Client) DeleteResource(ctx context.Context, gvk schema.GroupVersionKind
 	return resource.Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
 }
 
-// ExecCommand executes a command in a pod
-func (c *Client) ExecCommand(ctx context.Context, namespace, pod, container string, command []string) ([]byte, error) {
+// ExecResult is the outcome of a command executed in a pod
+type ExecResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// ExecCommand executes a command in a pod. The command exiting with a non-zero
+// status is reported through the result's exit code rather than as an error.
+// Cancelling ctx terminates the stream.
+func (c *Client) ExecCommand(ctx context.Context, namespace, pod, container string, command []string) (*ExecResult, error) {
+	if c.restConfig == nil {
+		return nil, errors.New("exec requires a REST config")
+	}
+
 	req := c.clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Namespace(namespace).
@@ -203,16 +303,37 @@ func (c *Client) ExecCommand(ctx context.Context, namespace, pod, container stri
 	}
 
 	var stdout, stderr bytes.Buffer
-	err = exec.Stream(remotecommand.StreamOptions{
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
 		Stdout: &stdout,
 		Stderr: &stderr,
 	})
 
-	if err != nil {
-		return nil, fmt.Errorf("exec failed: %w, stderr: %s", err, stderr.String())
+	return execResult(stdout.Bytes(), stderr.Bytes(), err)
+}
+
+// execResult builds the result of an exec stream that ended with err
+func execResult(stdout, stderr []byte, err error) (*ExecResult, error) {
+	result := &ExecResult{Stdout: stdout, Stderr: stderr}
+	if err == nil {
+		return result, nil
 	}
 
-	return stdout.Bytes(), nil
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		result.ExitCode = exitErr.ExitStatus()
+		return result, nil
+	}
+	return nil, fmt.Errorf("exec failed: %w, stderr: %s", err, stderr)
+}
+
+// ClusterUID returns a stable identifier of the cluster, the UID of its
+// kube-system namespace, which only changes when the cluster is recreated
+func (c *Client) ClusterUID(ctx context.Context) (string, error) {
+	namespace, err := c.clientset.CoreV1().Namespaces().Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s namespace: %w", metav1.NamespaceSystem, err)
+	}
+	return string(namespace.UID), nil
 }
 
 // GetClusterInfo retrieves cluster information
@@ -232,11 +353,8 @@ func (c *Client) GetClusterInfo(ctx context.Context) (*ClusterInfo, error) {
 	// Count ready nodes
 	readyNodes := 0
 	for _, node := range nodes.Items {
-		for _, condition := range node.Status.Conditions {
-			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
-				readyNodes++
-				break
-			}
+		if isNodeReady(&node) {
+			readyNodes++
 		}
 	}
 
@@ -245,10 +363,64 @@ func (c *Client) GetClusterInfo(ctx context.Context) (*ClusterInfo, error) {
 		Platform:          version.Platform,
 		NodeCount:         len(nodes.Items),
 		ReadyNodes:        readyNodes,
+		Provider:          detectProvider(nodes.Items),
+		Distribution:      detectDistribution(version.GitVersion, nodes.Items),
+		Region:            detectRegion(nodes.Items),
 		Labels:            make(map[string]string),
 	}, nil
 }
 
+// ListNodes lists the nodes in the cluster with readiness and allocatable capacity
+func (c *Client) ListNodes(ctx context.Context) ([]NodeInfo, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	result := make([]NodeInfo, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		cpu := node.Status.Allocatable.Cpu()
+		memory := node.Status.Allocatable.Memory()
+		result = append(result, NodeInfo{
+			Name:                   node.Name,
+			Ready:                  isNodeReady(&node),
+			Roles:                  nodeRoles(&node),
+			KubeletVersion:         node.Status.NodeInfo.KubeletVersion,
+			AllocatableCPU:         cpu.String(),
+			AllocatableCPUMillis:   cpu.MilliValue(),
+			AllocatableMemory:      memory.String(),
+			AllocatableMemoryBytes: memory.Value(),
+		})
+	}
+
+	return result, nil
+}
+
+// isNodeReady reports whether the node's Ready condition is true
+func isNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeRoles returns the node roles from the node-role.kubernetes.io/<role> and kubernetes.io/role labels
+func nodeRoles(node *corev1.Node) []string {
+	roles := make([]string, 0)
+	for label := range node.Labels {
+		if role, ok := strings.CutPrefix(label, "node-role.kubernetes.io/"); ok && role != "" {
+			roles = append(roles, role)
+		}
+	}
+	if role := node.Labels["kubernetes.io/role"]; role != "" && !slices.Contains(roles, role) {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
 // HealthCheck checks cluster health
 func (c *Client) HealthCheck(ctx context.Context) error {
 	// Check if we can list nodes
@@ -266,5 +438,28 @@ type ClusterInfo struct {
 	Platform          string            `json:"platform"`
 	NodeCount         int               `json:"node_count"`
 	ReadyNodes        int               `json:"ready_nodes"`
+	Provider          string            `json:"provider"`
+	Distribution      string            `json:"distribution"`
+	Region            string            `json:"region"`
 	Labels            map[string]string `json:"labels"`
 }
+
+// NodeInfo contains summary information about a node
+type NodeInfo struct {
+	Name                   string   `json:"name"`
+	Ready                  bool     `json:"ready"`
+	Roles                  []string `json:"roles"`
+	KubeletVersion         string   `json:"kubelet_version"`
+	AllocatableCPU         string   `json:"allocatable_cpu"`
+	AllocatableCPUMillis   int64    `json:"allocatable_cpu_millis"`
+	AllocatableMemory      string   `json:"allocatable_memory"`
+	AllocatableMemoryBytes int64    `json:"allocatable_memory_bytes"`
+}
+
+// NamespaceInfo contains summary information about a namespace
+type NamespaceInfo struct {
+	Name      string            `json:"name"`
+	Status    string            `json:"status"`
+	Labels    map[string]string `json:"labels"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,135 @@
+package kube
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ClientFactory builds a Kubernetes client from kubeconfig bytes
+type ClientFactory func(kubeconfig []byte, logger *zap.Logger) (*Client, error)
+
+// cachedClient is a client together with the credentials it was built from
+type cachedClient struct {
+	client          *Client
+	credentialsHash [sha256.Size]byte
+	lastUsed        time.Time
+}
+
+// ClientCache keeps one Kubernetes client per cluster so that repeated
+// operations against the same cluster reuse connections. A cached client is
+// rebuilt when the cluster's credentials change and dropped after it has been
+// idle for longer than the configured timeout.
+type ClientCache struct {
+	mu          sync.Mutex
+	clients     map[uuid.UUID]*cachedClient
+	factory     ClientFactory
+	idleTimeout time.Duration
+	now         func() time.Time
+	logger      *zap.Logger
+}
+
+// NewClientCache creates a new client cache. A nil factory defaults to NewClient.
+func NewClientCache(factory ClientFactory, idleTimeout time.Duration, logger *zap.Logger) *ClientCache {
+	if factory == nil {
+		factory = NewClient
+	}
+	return &ClientCache{
+		clients:     make(map[uuid.UUID]*cachedClient),
+		factory:     factory,
+		idleTimeout: idleTimeout,
+		now:         time.Now,
+		logger:      logger,
+	}
+}
+
+// Get returns the cached client for a cluster, building a new one if none is
+// cached or the kubeconfig differs from the one the cached client was built from
+func (c *ClientCache) Get(clusterID uuid.UUID, kubeconfig []byte) (*Client, error) {
+	hash := sha256.Sum256(kubeconfig)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.clients[clusterID]; ok {
+		if entry.credentialsHash == hash {
+			entry.lastUsed = c.now()
+			return entry.client, nil
+		}
+		c.logger.Info("Cluster credentials changed, rebuilding kube client",
+			zap.String("cluster_id", clusterID.String()))
+		delete(c.clients, clusterID)
+	}
+
+	client, err := c.factory(kubeconfig, c.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	c.clients[clusterID] = &cachedClient{
+		client:          client,
+		credentialsHash: hash,
+		lastUsed:        c.now(),
+	}
+	return client, nil
+}
+
+// Invalidate drops the cached client for a cluster, e.g. after its credentials were updated
+func (c *ClientCache) Invalidate(clusterID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.clients, clusterID)
+}
+
+// Len returns the number of cached clients
+func (c *ClientCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.clients)
+}
+
+// EvictIdle drops clients that have not been used within the idle timeout and
+// returns how many were evicted
+func (c *ClientCache) EvictIdle() int {
+	if c.idleTimeout <= 0 {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cutoff := c.now().Add(-c.idleTimeout)
+	evicted := 0
+	for clusterID, entry := range c.clients {
+		if entry.lastUsed.Before(cutoff) {
+			delete(c.clients, clusterID)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// StartEviction periodically evicts idle clients until the context is cancelled
+func (c *ClientCache) StartEviction(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if evicted := c.EvictIdle(); evicted > 0 {
+				c.logger.Debug("Evicted idle kube clients", zap.Int("count", evicted))
+			}
+		}
+	}
+}
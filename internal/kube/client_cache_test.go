@@ -0,0 +1,84 @@
+package kube
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// countingFactory returns a client factory that records how many clients it built
+func countingFactory(built *int) ClientFactory {
+	return func(kubeconfig []byte, logger *zap.Logger) (*Client, error) {
+		*built++
+		return &Client{logger: logger}, nil
+	}
+}
+
+func TestClientCache_ReusesClientAndInvalidatesOnCredentialChange(t *testing.T) {
+	built := 0
+	cache := NewClientCache(countingFactory(&built), time.Minute, zap.NewNop())
+	clusterID := uuid.New()
+	kubeconfig := []byte("kubeconfig-v1")
+
+	first, err := cache.Get(clusterID, kubeconfig)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	second, err := cache.Get(clusterID, kubeconfig)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if first != second {
+		t.Fatalf("Expected operations for the same cluster to reuse one client")
+	}
+	if built != 1 {
+		t.Fatalf("Expected 1 client to be built, got %d", built)
+	}
+
+	rotated, err := cache.Get(clusterID, []byte("kubeconfig-v2"))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if rotated == first {
+		t.Fatalf("Expected a credential update to invalidate the cached client")
+	}
+	if built != 2 {
+		t.Fatalf("Expected 2 clients to be built, got %d", built)
+	}
+
+	cache.Invalidate(clusterID)
+	if _, err := cache.Get(clusterID, []byte("kubeconfig-v2")); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if built != 3 {
+		t.Fatalf("Expected explicit invalidation to rebuild the client, got %d builds", built)
+	}
+}
+
+func TestClientCache_EvictIdle(t *testing.T) {
+	built := 0
+	cache := NewClientCache(countingFactory(&built), time.Minute, zap.NewNop())
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	idleCluster := uuid.New()
+	activeCluster := uuid.New()
+	if _, err := cache.Get(idleCluster, []byte("a")); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.Get(activeCluster, []byte("b")); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if evicted := cache.EvictIdle(); evicted != 1 {
+		t.Fatalf("Expected 1 idle client to be evicted, got %d", evicted)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("Expected 1 cached client to remain, got %d", cache.Len())
+	}
+}
@@ -0,0 +1,377 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+var (
+	podGVK       = schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
+	podGVR       = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}
+	namespaceGVK = schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"}
+	namespaceGVR = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
+)
+
+// newTestObject builds an unstructured object for the fake dynamic client
+func newTestObject(gvk schema.GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+// newTestClient creates a client backed by a fake dynamic client and a static REST mapper
+func newTestClient(objects ...runtime.Object) *Client {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(podGVK, meta.RESTScopeNamespace)
+	mapper.Add(namespaceGVK, meta.RESTScopeRoot)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			podGVR:       "PodList",
+			namespaceGVR: "NamespaceList",
+		},
+		objects...,
+	)
+
+	return &Client{
+		dynamicClient: dynamicClient,
+		restMapper:    mapper,
+		logger:        zap.NewNop(),
+	}
+}
+
+func TestClient_ListResources(t *testing.T) {
+	client := newTestClient(
+		newTestObject(podGVK, "default", "pod-a"),
+		newTestObject(podGVK, "default", "pod-b"),
+		newTestObject(podGVK, "kube-system", "pod-c"),
+		newTestObject(namespaceGVK, "", "default"),
+		newTestObject(namespaceGVK, "", "kube-system"),
+	)
+
+	tests := []struct {
+		name          string
+		gvk           schema.GroupVersionKind
+		namespace     string
+		expectedCount int
+		expectedError error
+	}{
+		{
+			name:          "namespaced kind in a single namespace",
+			gvk:           podGVK,
+			namespace:     "default",
+			expectedCount: 2,
+		},
+		{
+			name:          "namespaced kind across all namespaces",
+			gvk:           podGVK,
+			namespace:     "",
+			expectedCount: 3,
+		},
+		{
+			name:          "cluster-scoped kind without namespace",
+			gvk:           namespaceGVK,
+			namespace:     "",
+			expectedCount: 2,
+		},
+		{
+			name:          "cluster-scoped kind with namespace",
+			gvk:           namespaceGVK,
+			namespace:     "default",
+			expectedError: ErrNamespaceNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := client.ListResources(context.Background(), tt.gvk, tt.namespace)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("Expected error %v but got: %v", tt.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(list.Items) != tt.expectedCount {
+				t.Errorf("Expected %d items but got %d", tt.expectedCount, len(list.Items))
+			}
+		})
+	}
+}
+
+// resettableMapper simulates a discovery-backed mapper whose cache predates a CRD install
+type resettableMapper struct {
+	meta.RESTMapper
+	fresh  *meta.DefaultRESTMapper
+	resets int
+}
+
+func (m *resettableMapper) Reset() {
+	m.resets++
+	m.RESTMapper = m.fresh
+}
+
+func TestClient_RESTMappingMissResetsMapper(t *testing.T) {
+	widgetGVK := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	widgetGVR := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+
+	// Stale mapper doesn't know about the CRD, the refreshed one does
+	stale := meta.NewDefaultRESTMapper(nil)
+	fresh := meta.NewDefaultRESTMapper(nil)
+	fresh.Add(widgetGVK, meta.RESTScopeNamespace)
+	mapper := &resettableMapper{RESTMapper: stale, fresh: fresh}
+
+	client := &Client{
+		dynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+			runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{widgetGVR: "WidgetList"},
+			newTestObject(widgetGVK, "default", "widget-a"),
+		),
+		restMapper: mapper,
+		logger:     zap.NewNop(),
+	}
+
+	list, err := client.ListResources(context.Background(), widgetGVK, "default")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Errorf("Expected 1 item but got %d", len(list.Items))
+	}
+	if mapper.resets != 1 {
+		t.Errorf("Expected mapper to be reset once but got %d", mapper.resets)
+	}
+
+	// Subsequent lookups hit the refreshed mapper without another reset
+	if _, err := client.ListResources(context.Background(), widgetGVK, "default"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if mapper.resets != 1 {
+		t.Errorf("Expected no further resets but got %d", mapper.resets)
+	}
+}
+
+func TestClient_RESTMappingMissWithoutResettableMapper(t *testing.T) {
+	client := &Client{
+		restMapper: meta.NewDefaultRESTMapper(nil),
+		logger:     zap.NewNop(),
+	}
+
+	_, err := client.ListResources(context.Background(), schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}, "")
+	if !meta.IsNoMatchError(err) {
+		t.Errorf("Expected no match error but got: %v", err)
+	}
+}
+
+func TestClient_ApplyManifestRetryClassification(t *testing.T) {
+	manifest := []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: test-pod\n  namespace: default\n")
+	podGroupResource := schema.GroupResource{Group: "", Resource: "pods"}
+
+	tests := []struct {
+		name          string
+		errs          []error
+		expectedCalls int
+		expectedError bool
+	}{
+		{
+			name:          "validation error is not retried",
+			errs:          []error{apierrors.NewInvalid(podGVK.GroupKind(), "test-pod", nil)},
+			expectedCalls: 1,
+			expectedError: true,
+		},
+		{
+			name:          "forbidden error is not retried",
+			errs:          []error{apierrors.NewForbidden(podGroupResource, "test-pod", errors.New("denied"))},
+			expectedCalls: 1,
+			expectedError: true,
+		},
+		{
+			name:          "conflict is retried until success",
+			errs:          []error{apierrors.NewConflict(podGroupResource, "test-pod", errors.New("conflict")), nil},
+			expectedCalls: 2,
+			expectedError: false,
+		},
+		{
+			name:          "too many requests is retried",
+			errs:          []error{apierrors.NewTooManyRequests("slow down", 0), nil},
+			expectedCalls: 2,
+			expectedError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient()
+			fakeClient := client.dynamicClient.(*dynamicfake.FakeDynamicClient)
+
+			calls := 0
+			fakeClient.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				err := tt.errs[calls]
+				calls++
+				if err != nil {
+					return true, nil, err
+				}
+				return true, newTestObject(podGVK, "default", "test-pod"), nil
+			})
+
+			_, err := client.ApplyManifest(context.Background(), manifest, "", false)
+
+			if tt.expectedError && err == nil {
+				t.Errorf("Expected error but got nil")
+			}
+			if !tt.expectedError && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("Expected %d apply calls but got %d", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+// newTestNode builds a node with the given provider ID, labels and readiness
+func newTestNode(name, providerID string, labels map[string]string, ready bool) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec:       corev1.NodeSpec{ProviderID: providerID},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func TestClient_GetClusterInfoDetectsProvider(t *testing.T) {
+	tests := []struct {
+		name                 string
+		gitVersion           string
+		nodes                []runtime.Object
+		expectedProvider     string
+		expectedDistribution string
+		expectedRegion       string
+		expectedReady        int
+	}{
+		{
+			name:       "EKS on AWS",
+			gitVersion: "v1.28.3-eks-4f4795d",
+			nodes: []runtime.Object{
+				newTestNode("node-1", "aws:///us-east-1a/i-0123", map[string]string{"topology.kubernetes.io/region": "us-east-1"}, true),
+				newTestNode("node-2", "aws:///us-east-1b/i-0456", nil, false),
+			},
+			expectedProvider:     ProviderAWS,
+			expectedDistribution: DistributionEKS,
+			expectedRegion:       "us-east-1",
+			expectedReady:        1,
+		},
+		{
+			name:       "GKE on GCP",
+			gitVersion: "v1.27.8-gke.1067004",
+			nodes: []runtime.Object{
+				newTestNode("node-1", "gce://project/europe-west1-b/node-1", map[string]string{"failure-domain.beta.kubernetes.io/region": "europe-west1"}, true),
+			},
+			expectedProvider:     ProviderGCP,
+			expectedDistribution: DistributionGKE,
+			expectedRegion:       "europe-west1",
+			expectedReady:        1,
+		},
+		{
+			name:       "AKS on Azure from node labels",
+			gitVersion: "v1.28.5",
+			nodes: []runtime.Object{
+				newTestNode("node-1", "azure:///subscriptions/sub/resourceGroups/rg/providers/vm-1", map[string]string{"kubernetes.azure.com/cluster": "rg"}, true),
+			},
+			expectedProvider:     ProviderAzure,
+			expectedDistribution: DistributionAKS,
+			expectedRegion:       "",
+			expectedReady:        1,
+		},
+		{
+			name:       "k3s on bare metal",
+			gitVersion: "v1.28.4+k3s2",
+			nodes: []runtime.Object{
+				newTestNode("node-1", "k3s://node-1", nil, true),
+			},
+			expectedProvider:     ProviderUnknown,
+			expectedDistribution: DistributionK3s,
+			expectedRegion:       "",
+			expectedReady:        1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := kubefake.NewSimpleClientset(tt.nodes...)
+			fakeDiscovery := clientset.Discovery().(*discoveryfake.FakeDiscovery)
+			fakeDiscovery.FakedServerVersion = &version.Info{GitVersion: tt.gitVersion, Platform: "linux/amd64"}
+
+			client := &Client{
+				clientset: clientset,
+				discovery: fakeDiscovery,
+				logger:    zap.NewNop(),
+			}
+
+			info, err := client.GetClusterInfo(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if info.Provider != tt.expectedProvider {
+				t.Errorf("Expected provider %q but got %q", tt.expectedProvider, info.Provider)
+			}
+			if info.Distribution != tt.expectedDistribution {
+				t.Errorf("Expected distribution %q but got %q", tt.expectedDistribution, info.Distribution)
+			}
+			if info.Region != tt.expectedRegion {
+				t.Errorf("Expected region %q but got %q", tt.expectedRegion, info.Region)
+			}
+			if info.NodeCount != len(tt.nodes) {
+				t.Errorf("Expected %d nodes but got %d", len(tt.nodes), info.NodeCount)
+			}
+			if info.ReadyNodes != tt.expectedReady {
+				t.Errorf("Expected %d ready nodes but got %d", tt.expectedReady, info.ReadyNodes)
+			}
+		})
+	}
+}
+
+func TestExecResult(t *testing.T) {
+	// A non-zero exit status is part of the result
+	result, err := execResult([]byte("out"), []byte("boom"), utilexec.CodeExitError{Err: errors.New("command terminated with exit code 2"), Code: 2})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if result.ExitCode != 2 || string(result.Stdout) != "out" || string(result.Stderr) != "boom" {
+		t.Errorf("Expected exit code 2 with the output but got %+v", result)
+	}
+
+	// Stream failures are errors
+	if _, err := execResult(nil, nil, errors.New("connection reset")); err == nil {
+		t.Error("Expected an error for a failed stream")
+	}
+}
@@ -0,0 +1,66 @@
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// CountDocuments returns the number of YAML documents in manifests. Empty
+// documents, such as a leading separator or one holding only comments, are not
+// counted since nothing is applied for them.
+func CountDocuments(manifests []byte) (int, error) {
+	documents, err := SplitDocuments(manifests)
+	if err != nil {
+		return 0, err
+	}
+	return len(documents), nil
+}
+
+// SplitDocuments splits manifests into their YAML documents in order, leaving
+// out empty ones
+func SplitDocuments(manifests []byte) ([][]byte, error) {
+	reader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifests)))
+
+	var documents [][]byte
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to split manifests: %w", err)
+		}
+		if !emptyDocument(document) {
+			documents = append(documents, document)
+		}
+	}
+}
+
+// DecodeDocument decodes a single YAML or JSON document into an object
+func DecodeDocument(document []byte) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(document, &obj.Object); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+		return nil, errors.New("failed to decode manifest: apiVersion and kind are required")
+	}
+	return obj, nil
+}
+
+// emptyDocument reports whether a YAML document holds only whitespace and comments
+func emptyDocument(document []byte) bool {
+	for _, line := range bytes.Split(document, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' && !bytes.Equal(line, []byte("---")) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package kube
+
+import "testing"
+
+func TestCountDocuments(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifests string
+		expected  int
+	}{
+		{"empty", "", 0},
+		{"single", "apiVersion: v1\nkind: Namespace\n", 1},
+		{"separated", "apiVersion: v1\nkind: Namespace\n---\napiVersion: v1\nkind: ConfigMap\n", 2},
+		{"leading separator", "---\napiVersion: v1\nkind: Namespace\n", 1},
+		{"comment only document", "apiVersion: v1\nkind: Namespace\n---\n# nothing here\n---\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := CountDocuments([]byte(tt.manifests))
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if count != tt.expected {
+				t.Errorf("Expected %d documents but got %d", tt.expected, count)
+			}
+		})
+	}
+}
+
+func TestSplitDocuments(t *testing.T) {
+	documents, err := SplitDocuments([]byte("---\napiVersion: v1\nkind: Namespace\n---\n# nothing here\n---\napiVersion: v1\nkind: ConfigMap\n"))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(documents) != 2 {
+		t.Fatalf("Expected 2 documents but got %d", len(documents))
+	}
+
+	for i, kind := range []string{"Namespace", "ConfigMap"} {
+		obj, err := DecodeDocument(documents[i])
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if obj.GetKind() != kind {
+			t.Errorf("Expected document %d to be a %s but got %s", i, kind, obj.GetKind())
+		}
+	}
+}
+
+func TestDecodeDocumentRequiresKind(t *testing.T) {
+	if _, err := DecodeDocument([]byte("metadata:\n  name: test\n")); err == nil {
+		t.Error("Expected an error for a document without apiVersion and kind")
+	}
+}
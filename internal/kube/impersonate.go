@@ -0,0 +1,32 @@
+package kube
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/rest"
+)
+
+// ErrImpersonationUnsupported is returned when a client was not built from a
+// REST config, e.g. a client wrapping fake clientsets, and cannot impersonate
+var ErrImpersonationUnsupported = errors.New("client does not support impersonation")
+
+// Impersonate returns a client that acts as the given user and groups through
+// Kubernetes impersonation headers, so cluster-side audit logs attribute its
+// requests to that user. The credentials of c must be allowed to impersonate.
+func (c *Client) Impersonate(user string, groups []string) (*Client, error) {
+	if c.restConfig == nil {
+		return nil, ErrImpersonationUnsupported
+	}
+	if user == "" {
+		return nil, errors.New("impersonated user must not be empty")
+	}
+
+	config := rest.CopyConfig(c.restConfig)
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: user,
+		Groups:   groups,
+	}
+
+	return newClientForConfig(config, c.logger.With(zap.String("impersonate", user)))
+}
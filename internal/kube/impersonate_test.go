@@ -0,0 +1,99 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+// testKubeconfig points a client at server without credentials
+func testKubeconfig(server string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user: {}
+`, server))
+}
+
+func TestClient_ImpersonateAppliesConfig(t *testing.T) {
+	var mu sync.Mutex
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"kind":"NamespaceList","apiVersion":"v1","items":[]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(testKubeconfig(server.URL), zap.NewNop())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	impersonated, err := client.Impersonate("alice", []string{"mckmt:operator"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if impersonated.restConfig.Impersonate.UserName != "alice" {
+		t.Errorf("Expected impersonated user alice but got %q", impersonated.restConfig.Impersonate.UserName)
+	}
+	if !reflect.DeepEqual(impersonated.restConfig.Impersonate.Groups, []string{"mckmt:operator"}) {
+		t.Errorf("Expected impersonated groups [mckmt:operator] but got %v", impersonated.restConfig.Impersonate.Groups)
+	}
+	if client.restConfig.Impersonate.UserName != "" {
+		t.Errorf("Expected the original client not to impersonate, got %q", client.restConfig.Impersonate.UserName)
+	}
+
+	// Requests of the impersonating client carry the impersonation headers
+	if _, err := impersonated.ListNamespaces(context.Background()); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := client.ListNamespaces(context.Background()); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(headers) != 2 {
+		t.Fatalf("Expected 2 requests but got %d", len(headers))
+	}
+	if user := headers[0].Get("Impersonate-User"); user != "alice" {
+		t.Errorf("Expected Impersonate-User alice but got %q", user)
+	}
+	if groups := headers[0].Values("Impersonate-Group"); !reflect.DeepEqual(groups, []string{"mckmt:operator"}) {
+		t.Errorf("Expected Impersonate-Group [mckmt:operator] but got %v", groups)
+	}
+	if user := headers[1].Get("Impersonate-User"); user != "" {
+		t.Errorf("Expected no impersonation on the original client but got %q", user)
+	}
+}
+
+func TestClient_ImpersonateUnsupported(t *testing.T) {
+	client := NewClientWithInterfaces(kubefake.NewSimpleClientset(), nil, nil, zap.NewNop())
+
+	if _, err := client.Impersonate("alice", nil); !errors.Is(err, ErrImpersonationUnsupported) {
+		t.Errorf("Expected ErrImpersonationUnsupported but got: %v", err)
+	}
+}
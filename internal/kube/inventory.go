@@ -0,0 +1,117 @@
+package kube
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// InventoryEntry identifies a resource in a cluster inventory. Hash digests
+// the resource's content, so a changed resource can be told apart from an
+// unchanged one; it is empty when content is not compared.
+type InventoryEntry struct {
+	APIVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Hash       string `json:"hash,omitempty"`
+}
+
+// Key identifies the resource regardless of its content, as kind/namespace/name
+func (e InventoryEntry) Key() string {
+	return e.Kind + "/" + e.Namespace + "/" + e.Name
+}
+
+// NewInventoryEntry builds the inventory entry of a live object
+func NewInventoryEntry(obj *unstructured.Unstructured) InventoryEntry {
+	return InventoryEntry{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Hash:       ContentHash(obj),
+	}
+}
+
+// ContentHash digests the desired state of an object. Metadata and status are
+// left out since the cluster changes them on its own, e.g. resource versions
+// and managed fields.
+func ContentHash(obj *unstructured.Unstructured) string {
+	content := make(map[string]interface{}, len(obj.Object))
+	for key, value := range obj.Object {
+		if key == "metadata" || key == "status" {
+			continue
+		}
+		content[key] = value
+	}
+	// Labels and annotations are part of the desired state
+	content["labels"] = obj.GetLabels()
+	content["annotations"] = obj.GetAnnotations()
+
+	// encoding/json sorts map keys, so equal content hashes equally
+	data, err := json.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Drift describes how a live inventory differs from a desired one
+type Drift struct {
+	// Added resources exist in the cluster but not in the desired inventory
+	Added []InventoryEntry `json:"added"`
+	// Removed resources are desired but missing from the cluster
+	Removed []InventoryEntry `json:"removed"`
+	// Modified resources exist in both but their content differs
+	Modified []InventoryEntry `json:"modified"`
+}
+
+// Detected reports whether any drift was found
+func (d *Drift) Detected() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Modified) > 0
+}
+
+// DetectDrift compares a live inventory against the desired one. Desired
+// entries without a hash are only checked for presence. Entries are reported
+// in key order.
+func DetectDrift(desired, live []InventoryEntry) *Drift {
+	drift := &Drift{
+		Added:    []InventoryEntry{},
+		Removed:  []InventoryEntry{},
+		Modified: []InventoryEntry{},
+	}
+
+	liveByKey := make(map[string]InventoryEntry, len(live))
+	for _, entry := range live {
+		liveByKey[entry.Key()] = entry
+	}
+
+	desiredKeys := make(map[string]bool, len(desired))
+	for _, want := range desired {
+		desiredKeys[want.Key()] = true
+
+		got, ok := liveByKey[want.Key()]
+		switch {
+		case !ok:
+			drift.Removed = append(drift.Removed, want)
+		case want.Hash != "" && got.Hash != want.Hash:
+			drift.Modified = append(drift.Modified, got)
+		}
+	}
+
+	for _, entry := range live {
+		if !desiredKeys[entry.Key()] {
+			drift.Added = append(drift.Added, entry)
+		}
+	}
+
+	for _, entries := range [][]InventoryEntry{drift.Added, drift.Removed, drift.Modified} {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Key() < entries[j].Key() })
+	}
+
+	return drift
+}
@@ -0,0 +1,77 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodInfo identifies a pod whose logs can be read
+type PodInfo struct {
+	UID        string   `json:"uid"`
+	Namespace  string   `json:"namespace"`
+	Name       string   `json:"name"`
+	Phase      string   `json:"phase"`
+	Containers []string `json:"containers"`
+}
+
+// LogOptions controls which logs StreamPodLogs returns
+type LogOptions struct {
+	// Container is required for pods with more than one container
+	Container string
+	// TailLines limits the initial output to the last lines; zero means all
+	TailLines int64
+	// SinceSeconds limits the output to recent logs; zero means all
+	SinceSeconds int64
+	// Follow keeps the stream open for new log lines
+	Follow bool
+}
+
+// ListPods lists the pods matching a label selector. An empty namespace lists
+// pods across all namespaces.
+func (c *Client) ListPods(ctx context.Context, namespace, selector string) ([]PodInfo, error) {
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	result := make([]PodInfo, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		containers := make([]string, 0, len(pod.Spec.Containers))
+		for _, container := range pod.Spec.Containers {
+			containers = append(containers, container.Name)
+		}
+		result = append(result, PodInfo{
+			UID:        string(pod.UID),
+			Namespace:  pod.Namespace,
+			Name:       pod.Name,
+			Phase:      string(pod.Status.Phase),
+			Containers: containers,
+		})
+	}
+
+	return result, nil
+}
+
+// StreamPodLogs opens a stream of a pod's logs. The caller must close it.
+func (c *Client) StreamPodLogs(ctx context.Context, namespace, pod string, opts LogOptions) (io.ReadCloser, error) {
+	logOptions := &corev1.PodLogOptions{
+		Container: opts.Container,
+		Follow:    opts.Follow,
+	}
+	if opts.TailLines > 0 {
+		logOptions.TailLines = &opts.TailLines
+	}
+	if opts.SinceSeconds > 0 {
+		logOptions.SinceSeconds = &opts.SinceSeconds
+	}
+
+	stream, err := c.clientset.CoreV1().Pods(namespace).GetLogs(pod, logOptions).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream logs of pod %s/%s: %w", namespace, pod, err)
+	}
+	return stream, nil
+}
@@ -0,0 +1,105 @@
+package kube
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Cloud providers detected from node provider IDs
+const (
+	ProviderAWS          = "aws"
+	ProviderGCP          = "gcp"
+	ProviderAzure        = "azure"
+	ProviderDigitalOcean = "digitalocean"
+	ProviderOpenStack    = "openstack"
+	ProviderVSphere      = "vsphere"
+	ProviderKind         = "kind"
+	ProviderUnknown      = "unknown"
+)
+
+// Kubernetes distributions guessed from node labels and version strings
+const (
+	DistributionEKS       = "eks"
+	DistributionGKE       = "gke"
+	DistributionAKS       = "aks"
+	DistributionK3s       = "k3s"
+	DistributionOpenShift = "openshift"
+	DistributionUnknown   = "unknown"
+)
+
+// providerIDPrefixes maps node spec.providerID prefixes to cloud providers
+var providerIDPrefixes = []struct {
+	prefix   string
+	provider string
+}{
+	{"aws://", ProviderAWS},
+	{"gce://", ProviderGCP},
+	{"azure://", ProviderAzure},
+	{"digitalocean://", ProviderDigitalOcean},
+	{"openstack://", ProviderOpenStack},
+	{"vsphere://", ProviderVSphere},
+	{"kind://", ProviderKind},
+}
+
+// regionLabels are the node labels carrying the region, newest first
+var regionLabels = []string{
+	"topology.kubernetes.io/region",
+	"failure-domain.beta.kubernetes.io/region",
+}
+
+// detectProvider returns the cloud provider of the first node with a recognised provider ID
+func detectProvider(nodes []corev1.Node) string {
+	for _, node := range nodes {
+		for _, p := range providerIDPrefixes {
+			if strings.HasPrefix(node.Spec.ProviderID, p.prefix) {
+				return p.provider
+			}
+		}
+	}
+	return ProviderUnknown
+}
+
+// detectDistribution guesses the Kubernetes distribution from the server version and node labels
+func detectDistribution(gitVersion string, nodes []corev1.Node) string {
+	switch {
+	case strings.Contains(gitVersion, "-eks"):
+		return DistributionEKS
+	case strings.Contains(gitVersion, "-gke"):
+		return DistributionGKE
+	case strings.Contains(gitVersion, "+k3s"):
+		return DistributionK3s
+	}
+
+	for _, node := range nodes {
+		for label := range node.Labels {
+			switch {
+			case strings.HasPrefix(label, "eks.amazonaws.com/"):
+				return DistributionEKS
+			case strings.HasPrefix(label, "cloud.google.com/gke-"):
+				return DistributionGKE
+			case strings.HasPrefix(label, "kubernetes.azure.com/"):
+				return DistributionAKS
+			case strings.HasPrefix(label, "node.openshift.io/"):
+				return DistributionOpenShift
+			}
+		}
+		if strings.HasPrefix(node.Spec.ProviderID, "k3s://") {
+			return DistributionK3s
+		}
+	}
+
+	return DistributionUnknown
+}
+
+// detectRegion returns the region of the first node carrying a region label
+func detectRegion(nodes []corev1.Node) string {
+	for _, node := range nodes {
+		for _, label := range regionLabels {
+			if region := node.Labels[label]; region != "" {
+				return region
+			}
+		}
+	}
+	return ""
+}
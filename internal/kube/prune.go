@@ -0,0 +1,238 @@
+package kube
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// applySetKey is the data key of the apply-set ConfigMap holding its objects
+const applySetKey = "objects"
+
+// Labels of objects created by pruning applies. Only objects carrying the
+// ApplySetLabel of an apply-set are ever pruned from it.
+const (
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "mckmt"
+	ApplySetLabel  = "mckmt.io/apply-set"
+)
+
+// ApplySetObject identifies an object applied by a pruning apply
+type ApplySetObject struct {
+	APIVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// PrunedObject reports an object deleted by a pruning apply, or that failed
+// to be deleted
+type PrunedObject struct {
+	ApplySetObject
+	Error string `json:"error,omitempty"`
+}
+
+// ParsePruneSelector parses the label selector bounding a pruning apply.
+// Selectors matching everything are rejected so that a prune can never
+// delete objects it was not meant to.
+func ParsePruneSelector(selector string) (labels.Selector, error) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prune selector: %w", err)
+	}
+	if parsed.Empty() {
+		return nil, errors.New("prune selector must not be empty")
+	}
+	return parsed, nil
+}
+
+// applySetName returns the name of the ConfigMap tracking the objects applied
+// by pruning applies with the given selector
+func applySetName(selector string) string {
+	sum := sha256.Sum256([]byte(selector))
+	return "mckmt-applyset-" + hex.EncodeToString(sum[:8])
+}
+
+// ApplyManifestToSet applies manifest like ApplyManifest as part of the
+// apply-set of selector, labeling the objects it creates as owned by it
+func (c *Client) ApplyManifestToSet(ctx context.Context, manifest []byte, namespace, selector string, dryRun bool) (*ApplyResult, error) {
+	if _, err := ParsePruneSelector(selector); err != nil {
+		return nil, err
+	}
+	return c.applyManifest(ctx, manifest, namespace, applySetName(selector), dryRun)
+}
+
+// Prune deletes the objects of the apply-set of selector that are no longer
+// part of an applied manifest, then records the applied objects as the new
+// apply-set. The apply-set is kept in a ConfigMap of namespace, so it holds
+// the objects of earlier pruning applies only. Only objects created by
+// ApplyManifestToSet for the same selector are deleted; objects that existed
+// before, or whose labels no longer match selector, are left alone. With
+// dryRun the deletions are only validated and the apply-set is not updated.
+func (c *Client) Prune(ctx context.Context, applied *ApplyResult, namespace, selector string, dryRun bool) ([]PrunedObject, error) {
+	parsed, err := ParsePruneSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	previous, err := c.loadApplySet(ctx, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make([]ApplySetObject, 0, len(applied.Objects))
+	keep := make(map[ApplySetObject]bool, len(applied.Objects))
+	for _, obj := range applied.Objects {
+		if obj.Status == ApplyFailed || obj.Status == ApplySkipped {
+			return nil, fmt.Errorf("not pruning after document %d was %s", obj.Index, obj.Status)
+		}
+		id := ApplySetObject{APIVersion: obj.APIVersion, Kind: obj.Kind, Namespace: obj.Namespace, Name: obj.Name}
+		if !keep[id] {
+			keep[id] = true
+			current = append(current, id)
+		}
+	}
+
+	var pruned []PrunedObject
+	var errs []error
+	for _, id := range previous {
+		if keep[id] {
+			continue
+		}
+		deleted, err := c.pruneObject(ctx, id, applySetName(selector), parsed, dryRun)
+		if err != nil {
+			pruned = append(pruned, PrunedObject{ApplySetObject: id, Error: err.Error()})
+			errs = append(errs, fmt.Errorf("%s %s: %w", id.Kind, id.Name, err))
+			// Keep tracking objects that could not be deleted
+			current = append(current, id)
+			continue
+		}
+		if deleted {
+			pruned = append(pruned, PrunedObject{ApplySetObject: id})
+		}
+	}
+
+	if !dryRun {
+		if err := c.saveApplySet(ctx, namespace, selector, current); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return pruned, errors.Join(errs...)
+}
+
+// pruneObject deletes an object of the apply-set if it still exists, is owned
+// by the apply-set and matches the selector. It reports whether the object
+// was deleted.
+func (c *Client) pruneObject(ctx context.Context, id ApplySetObject, applySet string, selector labels.Selector, dryRun bool) (bool, error) {
+	mapping, err := c.restMapping(schema.FromAPIVersionAndKind(id.APIVersion, id.Kind))
+	if err != nil {
+		return false, fmt.Errorf("failed to get REST mapping: %w", err)
+	}
+
+	resource := c.dynamicClient.Resource(mapping.Resource).Namespace(id.Namespace)
+	live, err := resource.Get(ctx, id.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	if live.GetLabels()[ApplySetLabel] != applySet {
+		c.logger.Info("Not pruning object that was not created by mckmt",
+			zap.String("kind", id.Kind),
+			zap.String("name", id.Name),
+			zap.String("namespace", id.Namespace),
+		)
+		return false, nil
+	}
+
+	if !selector.Matches(labels.Set(live.GetLabels())) {
+		c.logger.Info("Not pruning object that no longer matches the prune selector",
+			zap.String("kind", id.Kind),
+			zap.String("name", id.Name),
+			zap.String("namespace", id.Namespace),
+		)
+		return false, nil
+	}
+
+	options := metav1.DeleteOptions{}
+	if dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	if err := resource.Delete(ctx, id.Name, options); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	c.logger.Info("Pruned object",
+		zap.String("kind", id.Kind),
+		zap.String("name", id.Name),
+		zap.String("namespace", id.Namespace),
+		zap.Bool("dry_run", dryRun),
+	)
+	return true, nil
+}
+
+// loadApplySet returns the objects recorded by earlier pruning applies
+func (c *Client) loadApplySet(ctx context.Context, namespace, selector string) ([]ApplySetObject, error) {
+	configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, applySetName(selector), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get apply-set: %w", err)
+	}
+
+	var objects []ApplySetObject
+	if data := configMap.Data[applySetKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &objects); err != nil {
+			return nil, fmt.Errorf("failed to decode apply-set: %w", err)
+		}
+	}
+	return objects, nil
+}
+
+// saveApplySet records the objects of a pruning apply
+func (c *Client) saveApplySet(ctx context.Context, namespace, selector string, objects []ApplySetObject) error {
+	data, err := json.Marshal(objects)
+	if err != nil {
+		return fmt.Errorf("failed to encode apply-set: %w", err)
+	}
+
+	configMaps := c.clientset.CoreV1().ConfigMaps(namespace)
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        applySetName(selector),
+			Namespace:   namespace,
+			Labels:      map[string]string{ManagedByLabel: ManagedByValue},
+			Annotations: map[string]string{"mckmt.io/prune-selector": selector},
+		},
+		Data: map[string]string{applySetKey: string(data)},
+	}
+
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save apply-set: %w", err)
+	}
+	return nil
+}
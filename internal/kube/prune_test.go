@@ -0,0 +1,195 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newPruneTestClient returns a client whose fake dynamic client holds the given pods
+func newPruneTestClient(objects ...runtime.Object) *Client {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(podGVK, meta.RESTScopeNamespace)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+	return NewClientWithInterfaces(kubefake.NewSimpleClientset(), dynamicClient, mapper, zap.NewNop())
+}
+
+// appliedPods returns the result of applying pods of the given names
+func appliedPods(names ...string) *ApplyResult {
+	result := &ApplyResult{}
+	for i, name := range names {
+		result.Objects = append(result.Objects, AppliedObject{Index: i, APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: name, Status: ApplyCreated})
+	}
+	return result
+}
+
+func TestClient_Prune(t *testing.T) {
+	applySet := applySetName("app=demo")
+	matching := newTestObject(podGVK, "default", "old")
+	matching.SetLabels(map[string]string{"app": "demo", ApplySetLabel: applySet})
+	relabeled := newTestObject(podGVK, "default", "relabeled")
+	relabeled.SetLabels(map[string]string{"app": "other", ApplySetLabel: applySet})
+	adopted := newTestObject(podGVK, "default", "adopted")
+	adopted.SetLabels(map[string]string{"app": "demo"})
+	otherSet := newTestObject(podGVK, "default", "other-set")
+	otherSet.SetLabels(map[string]string{"app": "demo", ApplySetLabel: applySetName("app=other")})
+
+	client := newPruneTestClient(matching, relabeled, adopted, otherSet, newTestObject(podGVK, "default", "web"))
+	ctx := context.Background()
+
+	if _, err := client.Prune(ctx, appliedPods("web", "old", "relabeled", "adopted", "other-set", "gone"), "default", "app=demo", false); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	pruned, err := client.Prune(ctx, appliedPods("web"), "default", "app=demo", false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// Objects not owned by the apply-set, no longer matching the selector or
+	// already gone are not pruned
+	if len(pruned) != 1 || pruned[0].Name != "old" || pruned[0].Error != "" {
+		t.Fatalf("Expected only old to be pruned but got %+v", pruned)
+	}
+
+	pods := client.dynamicClient.Resource(podGVR).Namespace("default")
+	if _, err := pods.Get(ctx, "old", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected old to be deleted but got: %v", err)
+	}
+	for _, name := range []string{"relabeled", "adopted", "other-set"} {
+		if _, err := pods.Get(ctx, name, metav1.GetOptions{}); err != nil {
+			t.Errorf("Expected %s to be kept but got: %v", name, err)
+		}
+	}
+
+	// The apply-set now only holds what was applied last
+	objects, err := client.loadApplySet(ctx, "default", "app=demo")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Name != "web" {
+		t.Errorf("Expected apply-set [web] but got %+v", objects)
+	}
+}
+
+func TestClient_PruneDryRunKeepsApplySet(t *testing.T) {
+	client := newPruneTestClient()
+	ctx := context.Background()
+
+	if _, err := client.Prune(ctx, appliedPods("web", "db"), "default", "app=demo", false); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := client.Prune(ctx, appliedPods("web"), "default", "app=demo", true); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	objects, err := client.loadApplySet(ctx, "default", "app=demo")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Errorf("Expected the dry run to leave the apply-set alone but got %+v", objects)
+	}
+}
+
+func TestClient_PruneRefusesAfterFailedDocument(t *testing.T) {
+	client := newPruneTestClient()
+	applied := appliedPods("web", "db")
+	applied.Objects[1].Status = ApplyFailed
+
+	if _, err := client.Prune(context.Background(), applied, "default", "app=demo", false); err == nil {
+		t.Fatal("Expected prune to be refused after a failed document")
+	}
+}
+
+func TestParsePruneSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		wantErr  bool
+	}{
+		{selector: "app=demo"},
+		{selector: "app in (web, db),tier!=cache"},
+		{selector: "", wantErr: true},
+		{selector: "app in (", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			_, err := ParsePruneSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v but got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestClient_ApplyManifestToSetLabelsCreatedObjects(t *testing.T) {
+	applySet := applySetName("app=demo")
+	owned := newTestObject(podGVK, "default", "owned")
+	owned.SetLabels(map[string]string{"app": "demo", ApplySetLabel: applySet})
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(podGVK, meta.RESTScopeNamespace)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), owned, newTestObject(podGVK, "default", "existing"))
+
+	labelsByName := map[string]map[string]string{}
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(action.(k8stesting.PatchAction).GetPatch()); err != nil {
+			return true, nil, err
+		}
+		labelsByName[obj.GetName()] = obj.GetLabels()
+		return true, obj, nil
+	})
+	client := NewClientWithInterfaces(kubefake.NewSimpleClientset(), dynamicClient, mapper, zap.NewNop())
+
+	manifest := []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: fresh
+  labels:
+    app: demo
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: owned
+  labels:
+    app: demo
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: existing
+  labels:
+    app: demo
+`)
+	if _, err := client.ApplyManifestToSet(context.Background(), manifest, "default", "app=demo", false); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// New and already owned objects are labeled, pre-existing ones are not adopted
+	for name, wantOwned := range map[string]bool{"fresh": true, "owned": true, "existing": false} {
+		labels := labelsByName[name]
+		isOwned := labels[ApplySetLabel] == applySet && labels[ManagedByLabel] == ManagedByValue
+		if isOwned != wantOwned {
+			t.Errorf("Expected %s to be owned %v but got labels %v", name, wantOwned, labels)
+		}
+		if labels["app"] != "demo" {
+			t.Errorf("Expected %s to keep its labels but got %v", name, labels)
+		}
+	}
+
+	if _, err := client.ApplyManifestToSet(context.Background(), manifest, "default", "", false); err == nil {
+		t.Error("Expected an empty selector to be rejected")
+	}
+}
@@ -0,0 +1,165 @@
+package kube
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// MaxKustomizationSize caps the uncompressed size of a kustomization archive
+const MaxKustomizationSize = 32 << 20
+
+// ErrInvalidKustomization is returned when a kustomization can't be rendered
+var ErrInvalidKustomization = errors.New("invalid kustomization")
+
+// RenderKustomize renders a gzipped tarball holding a Kustomize directory into
+// plain manifests. The kustomization closest to the archive root is built;
+// remote resources are rejected since the hub must not fetch from the network
+// on a user's behalf.
+func RenderKustomize(archive io.Reader) ([]byte, error) {
+	fs, err := extractArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := kustomizationRoot(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKustomization, err)
+	}
+
+	manifests, err := resources.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rendered manifests: %w", err)
+	}
+	return manifests, nil
+}
+
+// extractArchive unpacks a gzipped tarball into an in-memory file system.
+// Symlinks and other special entries are skipped.
+func extractArchive(archive io.Reader) (filesys.FileSystem, error) {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a gzipped tarball: %v", ErrInvalidKustomization, err)
+	}
+	defer gz.Close()
+
+	fs := filesys.MakeFsInMemory()
+	reader := tar.NewReader(gz)
+	var size int64
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read archive: %v", ErrInvalidKustomization, err)
+		}
+
+		name := path.Clean("/" + header.Name)
+		if strings.HasPrefix(path.Clean(header.Name), "..") {
+			return nil, fmt.Errorf("%w: archive path %q escapes the root", ErrInvalidKustomization, header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := fs.MkdirAll(name); err != nil {
+				return nil, fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		case tar.TypeReg:
+			size += header.Size
+			if size > MaxKustomizationSize {
+				return nil, fmt.Errorf("%w: archive exceeds %d bytes", ErrInvalidKustomization, MaxKustomizationSize)
+			}
+			data, err := io.ReadAll(io.LimitReader(reader, header.Size))
+			if err != nil {
+				return nil, fmt.Errorf("%w: failed to read %s: %v", ErrInvalidKustomization, header.Name, err)
+			}
+			if err := fs.WriteFile(name, data); err != nil {
+				return nil, fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		}
+	}
+	return fs, nil
+}
+
+// kustomizationRoot finds the shallowest directory holding a kustomization and
+// checks that no kustomization in the archive references remote content
+func kustomizationRoot(fs filesys.FileSystem) (string, error) {
+	root := ""
+	err := fs.Walk("/", func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !isKustomizationFile(path.Base(p)) {
+			return err
+		}
+		if err := checkLocalKustomization(fs, p); err != nil {
+			return err
+		}
+		dir := path.Dir(p)
+		if root == "" || strings.Count(dir, "/") < strings.Count(root, "/") {
+			root = dir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if root == "" {
+		return "", fmt.Errorf("%w: archive holds no kustomization file", ErrInvalidKustomization)
+	}
+	return root, nil
+}
+
+// isKustomizationFile reports whether name is one Kustomize recognizes
+func isKustomizationFile(name string) bool {
+	for _, recognized := range konfig.RecognizedKustomizationFileNames() {
+		if name == recognized {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLocalKustomization rejects a kustomization that pulls in resources,
+// bases or components from remote locations
+func checkLocalKustomization(fs filesys.FileSystem, file string) error {
+	data, err := fs.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	var kustomization types.Kustomization
+	if err := yaml.Unmarshal(data, &kustomization); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidKustomization, file, err)
+	}
+
+	references := append(append(append([]string{}, kustomization.Resources...), kustomization.Bases...), kustomization.Components...)
+	for _, reference := range references {
+		if isRemoteReference(reference) {
+			return fmt.Errorf("%w: remote reference %q in %s is not allowed", ErrInvalidKustomization, reference, file)
+		}
+	}
+	return nil
+}
+
+// isRemoteReference reports whether a kustomization reference points outside
+// the archive, e.g. to a git repository or an HTTP URL
+func isRemoteReference(reference string) bool {
+	return strings.Contains(reference, "://") ||
+		strings.HasPrefix(reference, "github.com/") ||
+		strings.HasPrefix(reference, "git@")
+}
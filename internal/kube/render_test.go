@@ -0,0 +1,88 @@
+package kube
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// kustomizationArchive builds a gzipped tarball holding the given files
+func kustomizationArchive(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return &buf
+}
+
+func TestRenderKustomize(t *testing.T) {
+	archive := kustomizationArchive(t, map[string]string{
+		"app/kustomization.yaml": "namePrefix: demo-\ncommonLabels:\n  app: demo\nresources:\n- configmap.yaml\n",
+		"app/configmap.yaml":     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  key: value\n",
+	})
+
+	manifests, err := RenderKustomize(archive)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	rendered := string(manifests)
+	for _, want := range []string{"kind: ConfigMap", "name: demo-settings", "app: demo", "key: value"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("Expected rendered manifests to contain %q but got:\n%s", want, rendered)
+		}
+	}
+}
+
+func TestRenderKustomize_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive *bytes.Buffer
+	}{
+		{
+			name:    "not a tarball",
+			archive: bytes.NewBufferString("apiVersion: v1\nkind: ConfigMap\n"),
+		},
+		{
+			name:    "no kustomization",
+			archive: kustomizationArchive(t, map[string]string{"configmap.yaml": "apiVersion: v1\nkind: ConfigMap\n"}),
+		},
+		{
+			name: "remote resource",
+			archive: kustomizationArchive(t, map[string]string{
+				"kustomization.yaml": "resources:\n- https://github.com/example/repo//deploy?ref=main\n",
+			}),
+		},
+		{
+			name:    "path outside the root",
+			archive: kustomizationArchive(t, map[string]string{"../kustomization.yaml": "resources: []\n"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderKustomize(tt.archive)
+			if !errors.Is(err, ErrInvalidKustomization) {
+				t.Errorf("Expected ErrInvalidKustomization but got: %v", err)
+			}
+		})
+	}
+}
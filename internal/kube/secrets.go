@@ -0,0 +1,76 @@
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// MaskedSecretValue replaces the values of Secret data when manifests are masked
+const MaskedSecretValue = "***masked***"
+
+// MaskSecrets returns a copy of the manifests in which the data and stringData
+// values of every Secret are replaced with MaskedSecretValue, for storing and
+// logging. Keys are kept so the masked manifest still shows what was applied.
+// Documents other than Secrets, including ones that can't be decoded and would
+// fail to apply anyway, are returned unchanged; the second result reports
+// whether any Secret was masked.
+func MaskSecrets(manifests []byte) ([]byte, bool, error) {
+	reader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifests)))
+
+	var documents [][]byte
+	masked := false
+	for {
+		document, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to split manifests: %w", err)
+		}
+
+		if maskedDocument, ok := maskSecret(document); ok {
+			document = maskedDocument
+			masked = true
+		}
+		documents = append(documents, document)
+	}
+
+	if !masked {
+		return manifests, false, nil
+	}
+	return bytes.Join(documents, []byte("---\n")), true, nil
+}
+
+// maskSecret masks a single manifest document if it is a Secret. Masked
+// documents are re-encoded as JSON, which is valid YAML.
+func maskSecret(document []byte) ([]byte, bool) {
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal(document, &obj); err != nil {
+		return nil, false
+	}
+	if obj["kind"] != "Secret" || obj["apiVersion"] != "v1" {
+		return nil, false
+	}
+
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := obj[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range values {
+			values[key] = MaskedSecretValue
+		}
+	}
+
+	encoded, err := json.Marshal(obj)
+	if err != nil {
+		return nil, false
+	}
+	return append(encoded, '\n'), true
+}
@@ -0,0 +1,107 @@
+package kube
+
+import (
+	"bytes"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const secretManifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: production
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+data:
+  password: c3VwZXJzZWNyZXQ=
+stringData:
+  token: plain-token
+`
+
+// decodeDocuments decodes every document of multi-document manifests
+func decodeDocuments(t *testing.T, manifests []byte) []*unstructured.Unstructured {
+	t.Helper()
+
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifests), 4096)
+	var objects []*unstructured.Unstructured
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			break
+		}
+		if len(obj.Object) > 0 {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+func TestMaskSecrets(t *testing.T) {
+	masked, ok, err := MaskSecrets([]byte(secretManifests))
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !ok {
+		t.Fatalf("Expected the Secret to be masked")
+	}
+	if bytes.Contains(masked, []byte("c3VwZXJzZWNyZXQ=")) || bytes.Contains(masked, []byte("plain-token")) {
+		t.Fatalf("Expected no secret values in the masked manifests:\n%s", masked)
+	}
+
+	objects := decodeDocuments(t, masked)
+	if len(objects) != 2 {
+		t.Fatalf("Expected 2 documents but got %d:\n%s", len(objects), masked)
+	}
+
+	if mode, _, _ := unstructured.NestedString(objects[0].Object, "data", "mode"); mode != "production" {
+		t.Errorf("Expected the ConfigMap to be unchanged but got mode %q", mode)
+	}
+
+	secret := objects[1]
+	if secret.GetName() != "credentials" {
+		t.Errorf("Expected the Secret metadata to be kept but got name %q", secret.GetName())
+	}
+	for _, field := range [][]string{{"data", "password"}, {"stringData", "token"}} {
+		value, found, _ := unstructured.NestedString(secret.Object, field...)
+		if !found || value != MaskedSecretValue {
+			t.Errorf("Expected %v to be masked but got %q", field, value)
+		}
+	}
+}
+
+func TestMaskSecretsWithoutSecrets(t *testing.T) {
+	manifests := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n")
+
+	masked, ok, err := MaskSecrets(manifests)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if ok {
+		t.Errorf("Expected nothing to be masked")
+	}
+	if !bytes.Equal(masked, manifests) {
+		t.Errorf("Expected manifests without Secrets to be returned unchanged, got:\n%s", masked)
+	}
+}
+
+func TestMaskSecretsKeepsUndecodableDocuments(t *testing.T) {
+	manifests := []byte("kind: [unterminated\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: credentials\ndata:\n  password: c3VwZXJzZWNyZXQ=\n")
+
+	masked, ok, err := MaskSecrets(manifests)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !ok || bytes.Contains(masked, []byte("c3VwZXJzZWNyZXQ=")) {
+		t.Errorf("Expected the decodable Secret to be masked, got:\n%s", masked)
+	}
+	if !bytes.HasPrefix(masked, []byte("kind: [unterminated")) {
+		t.Errorf("Expected the undecodable document to be kept, got:\n%s", masked)
+	}
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// agentMetricNamespace prefixes the Prometheus names of agent metrics, keeping
+// them apart from the hub's own metrics
+const agentMetricNamespace = "mckmt_cluster_"
+
+// defaultMaxAgentMetrics bounds the number of agent metric names when
+// NewAgentMetrics is given no limit
+const defaultMaxAgentMetrics = 100
+
+// AgentMetrics exposes the metrics agents stream as gauges. Each allowed metric
+// name gets its own gauge, labeled by cluster_id and the metric name as sent
+// by the agent. Only names starting with one of the allowed prefixes are
+// recorded, and at most maxMetrics names, so agents can't grow the registry
+// without bound.
+type AgentMetrics struct {
+	registerer prometheus.Registerer
+	prefixes   []string
+	maxMetrics int
+
+	mu     sync.Mutex
+	gauges map[string]*prometheus.GaugeVec // agent metric name -> gauge
+}
+
+// NewAgentMetrics creates agent metrics registered with registerer. Without
+// prefixes no agent metric is recorded.
+func NewAgentMetrics(registerer prometheus.Registerer, prefixes []string, maxMetrics int) *AgentMetrics {
+	if maxMetrics <= 0 {
+		maxMetrics = defaultMaxAgentMetrics
+	}
+	return &AgentMetrics{
+		registerer: registerer,
+		prefixes:   prefixes,
+		maxMetrics: maxMetrics,
+		gauges:     make(map[string]*prometheus.GaugeVec),
+	}
+}
+
+// Record sets the value of the named metric of a cluster
+func (m *AgentMetrics) Record(clusterID, name string, value float64) error {
+	gauge, err := m.gauge(name)
+	if err != nil {
+		return err
+	}
+	gauge.WithLabelValues(clusterID, name).Set(value)
+	return nil
+}
+
+// ClearCluster removes the metrics of a cluster, e.g. once its agent disconnects
+func (m *AgentMetrics) ClearCluster(clusterID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, gauge := range m.gauges {
+		gauge.DeletePartialMatch(prometheus.Labels{"cluster_id": clusterID})
+	}
+}
+
+// gauge returns the gauge of the named metric, registering it on first use
+func (m *AgentMetrics) gauge(name string) (*prometheus.GaugeVec, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if gauge, exists := m.gauges[name]; exists {
+		return gauge, nil
+	}
+	if !m.allowed(name) {
+		return nil, fmt.Errorf("metric %q is not allowed", name)
+	}
+	if len(m.gauges) >= m.maxMetrics {
+		return nil, fmt.Errorf("metric %q exceeds the limit of %d agent metrics", name, m.maxMetrics)
+	}
+
+	gauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: agentMetricNamespace + sanitizeMetricName(name),
+			Help: fmt.Sprintf("Agent reported metric %s", name),
+		},
+		[]string{"cluster_id", "metric"},
+	)
+	if err := m.registerer.Register(gauge); err != nil {
+		return nil, fmt.Errorf("failed to register metric %q: %w", name, err)
+	}
+	m.gauges[name] = gauge
+	return gauge, nil
+}
+
+// allowed reports whether name starts with one of the allowed prefixes
+func (m *AgentMetrics) allowed(name string) bool {
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeMetricName replaces the characters Prometheus doesn't allow in
+// metric names with underscores
+func sanitizeMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
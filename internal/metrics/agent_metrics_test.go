@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestAgentMetrics_RecordShowsOnMetricsEndpoint(t *testing.T) {
+	agentMetrics := NewAgentMetrics(prometheus.DefaultRegisterer, []string{"agent_"}, 10)
+
+	if err := agentMetrics.Record("cluster-a", "agent_memory.bytes", 1024); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	NewServer(0, zap.NewNop()).server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 but got %d", recorder.Code)
+	}
+
+	body, _ := io.ReadAll(recorder.Body)
+	expected := `mckmt_cluster_agent_memory_bytes{cluster_id="cluster-a",metric="agent_memory.bytes"} 1024`
+	if !strings.Contains(string(body), expected) {
+		t.Errorf("Expected /metrics to contain %q but got:\n%s", expected, body)
+	}
+}
+
+func TestAgentMetrics_LimitsMetricNames(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	agentMetrics := NewAgentMetrics(registry, []string{"agent_", "cluster_"}, 2)
+
+	tests := []struct {
+		name      string
+		metric    string
+		expectErr bool
+	}{
+		{name: "allowed prefix", metric: "agent_goroutines"},
+		{name: "other allowed prefix", metric: "cluster_nodes"},
+		{name: "known metric at the limit", metric: "agent_goroutines"},
+		{name: "not allowed", metric: "process_cpu_seconds", expectErr: true},
+		{name: "over the limit", metric: "agent_threads", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := agentMetrics.Record("cluster-a", tt.metric, 1)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected metric %s to be dropped", tt.metric)
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+
+	if count := testutil.CollectAndCount(registry); count != 2 {
+		t.Errorf("Expected 2 series but got %d", count)
+	}
+}
+
+func TestAgentMetrics_ClearCluster(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	agentMetrics := NewAgentMetrics(registry, []string{"agent_"}, 10)
+
+	_ = agentMetrics.Record("cluster-a", "agent_goroutines", 10)
+	_ = agentMetrics.Record("cluster-b", "agent_goroutines", 20)
+	agentMetrics.ClearCluster("cluster-a")
+
+	expected := `
+# HELP mckmt_cluster_agent_goroutines Agent reported metric agent_goroutines
+# TYPE mckmt_cluster_agent_goroutines gauge
+mckmt_cluster_agent_goroutines{cluster_id="cluster-b",metric="agent_goroutines"} 20
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Errorf("Expected only cluster-b metrics to remain: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// clusterModeAgent is the mode label for clusters managed through an agent
+const clusterModeAgent = "agent"
+
+// ClusterCounter provides the number of registered clusters grouped by status
+type ClusterCounter interface {
+	CountByStatus(ctx context.Context) (map[string]int, error)
+}
+
+// ClusterGaugeReconciler keeps the clusters total gauge in line with the
+// cluster store, so the metric stays accurate across restarts and status changes
+type ClusterGaugeReconciler struct {
+	counter  ClusterCounter
+	metrics  *Metrics
+	logger   *zap.Logger
+	reported map[string]bool // statuses set on the gauge by a previous run
+}
+
+// NewClusterGaugeReconciler creates a new cluster gauge reconciler
+func NewClusterGaugeReconciler(counter ClusterCounter, metrics *Metrics, logger *zap.Logger) *ClusterGaugeReconciler {
+	return &ClusterGaugeReconciler{
+		counter:  counter,
+		metrics:  metrics,
+		logger:   logger,
+		reported: make(map[string]bool),
+	}
+}
+
+// Start reconciles the gauge immediately and then every interval until the context is cancelled
+func (r *ClusterGaugeReconciler) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	if err := r.Reconcile(ctx); err != nil {
+		r.logger.Warn("Failed to reconcile clusters total gauge", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reconcile(ctx); err != nil {
+				r.logger.Warn("Failed to reconcile clusters total gauge", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Reconcile sets the gauge from the current cluster counts. Statuses reported
+// previously but no longer present are reset to zero.
+func (r *ClusterGaugeReconciler) Reconcile(ctx context.Context) error {
+	counts, err := r.counter.CountByStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	for status := range r.reported {
+		if _, ok := counts[status]; !ok {
+			r.metrics.SetClustersTotal(clusterModeAgent, status, 0)
+		}
+	}
+
+	for status, count := range counts {
+		r.metrics.SetClustersTotal(clusterModeAgent, status, float64(count))
+		r.reported[status] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+// fakeClusterCounter returns preset counts
+type fakeClusterCounter struct {
+	counts map[string]int
+}
+
+func (f *fakeClusterCounter) CountByStatus(_ context.Context) (map[string]int, error) {
+	return f.counts, nil
+}
+
+func TestClusterGaugeReconciler_Reconcile(t *testing.T) {
+	m := NewMetrics()
+	counter := &fakeClusterCounter{counts: map[string]int{"connected": 3, "disconnected": 1}}
+	reconciler := NewClusterGaugeReconciler(counter, m, zap.NewNop())
+
+	if err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if got := testutil.ToFloat64(m.ClustersTotal.WithLabelValues(clusterModeAgent, "connected")); got != 3 {
+		t.Errorf("Expected 3 connected clusters, got %f", got)
+	}
+	if got := testutil.ToFloat64(m.ClustersTotal.WithLabelValues(clusterModeAgent, "disconnected")); got != 1 {
+		t.Errorf("Expected 1 disconnected cluster, got %f", got)
+	}
+
+	// The disconnected cluster reconnects and a new one registers
+	counter.counts = map[string]int{"connected": 4, "pending": 1}
+	if err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	if got := testutil.ToFloat64(m.ClustersTotal.WithLabelValues(clusterModeAgent, "connected")); got != 4 {
+		t.Errorf("Expected 4 connected clusters, got %f", got)
+	}
+	if got := testutil.ToFloat64(m.ClustersTotal.WithLabelValues(clusterModeAgent, "pending")); got != 1 {
+		t.Errorf("Expected 1 pending cluster, got %f", got)
+	}
+	if got := testutil.ToFloat64(m.ClustersTotal.WithLabelValues(clusterModeAgent, "disconnected")); got != 0 {
+		t.Errorf("Expected disconnected clusters to be reset to 0, got %f", got)
+	}
+}
@@ -106,6 +106,22 @@ func (d *ClusterRepositoryDecorator) UpdateLastSeen(ctx context.Context, id uuid
 	return err
 }
 
+func (d *ClusterRepositoryDecorator) SetQuarantine(ctx context.Context, id uuid.UUID, quarantinedAt *time.Time, reason string) error {
+	start := time.Now()
+	err := d.repo.SetQuarantine(ctx, id, quarantinedAt, reason)
+
+	d.metrics.DatabaseQueryDuration.WithLabelValues("set_quarantine", "clusters").Observe(time.Since(start).Seconds())
+	return err
+}
+
+func (d *ClusterRepositoryDecorator) CountByStatus(ctx context.Context) (map[string]int, error) {
+	start := time.Now()
+	counts, err := d.repo.CountByStatus(ctx)
+
+	d.metrics.DatabaseQueryDuration.WithLabelValues("count", "clusters").Observe(time.Since(start).Seconds())
+	return counts, err
+}
+
 // OperationRepositoryDecorator wraps an OperationRepository with metrics
 type OperationRepositoryDecorator struct {
 	repo    repo.OperationRepository
@@ -137,6 +153,30 @@ func (d *OperationRepositoryDecorator) ListByCluster(ctx context.Context, cluste
 	return operations, err
 }
 
+func (d *OperationRepositoryDecorator) CountByCluster(ctx context.Context, clusterID uuid.UUID) (int, error) {
+	start := time.Now()
+	count, err := d.repo.CountByCluster(ctx, clusterID)
+
+	d.metrics.DatabaseQueryDuration.WithLabelValues("count", "operations").Observe(time.Since(start).Seconds())
+	return count, err
+}
+
+func (d *OperationRepositoryDecorator) EachByCluster(ctx context.Context, clusterID uuid.UUID, fn func(*repo.Operation) error) error {
+	start := time.Now()
+	err := d.repo.EachByCluster(ctx, clusterID, fn)
+
+	d.metrics.DatabaseQueryDuration.WithLabelValues("each", "operations").Observe(time.Since(start).Seconds())
+	return err
+}
+
+func (d *OperationRepositoryDecorator) ListByStatus(ctx context.Context, status string, limit, offset int) ([]*repo.Operation, error) {
+	start := time.Now()
+	operations, err := d.repo.ListByStatus(ctx, status, limit, offset)
+
+	d.metrics.DatabaseQueryDuration.WithLabelValues("list_by_status", "operations").Observe(time.Since(start).Seconds())
+	return operations, err
+}
+
 func (d *OperationRepositoryDecorator) Update(ctx context.Context, operation *repo.Operation) error {
 	start := time.Now()
 	err := d.repo.Update(ctx, operation)
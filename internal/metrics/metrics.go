@@ -26,6 +26,8 @@ type Metrics struct {
 	AgentsConnected    *prometheus.GaugeVec
 	AgentHeartbeats    *prometheus.CounterVec
 	AgentLastHeartbeat *prometheus.GaugeVec
+	AgentClockSkew     *prometheus.GaugeVec
+	AgentDuplicates    *prometheus.CounterVec
 
 	// Database metrics
 	DatabaseConnections   *prometheus.GaugeVec
@@ -133,6 +135,20 @@ func NewMetrics() *Metrics {
 			},
 			[]string{"cluster_id"},
 		),
+		AgentClockSkew: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "mckmt_agent_clock_skew_seconds",
+				Help: "Difference between the hub clock and the agent clock at the last heartbeat",
+			},
+			[]string{"cluster_id"},
+		),
+		AgentDuplicates: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "mckmt_agent_duplicate_registrations_total",
+				Help: "Total number of registrations rejected because another cluster already registered the name",
+			},
+			[]string{"cluster_id", "cluster_name"},
+		),
 
 		// Database metrics
 		DatabaseConnections: promauto.NewGaugeVec(
@@ -244,6 +260,24 @@ func (m *Metrics) SetAgentLastHeartbeat(clusterID string, timestamp float64) {
 	m.AgentLastHeartbeat.WithLabelValues(clusterID).Set(timestamp)
 }
 
+// SetAgentClockSkew sets the clock skew of an agent in seconds; positive means the agent is behind
+func (m *Metrics) SetAgentClockSkew(clusterID string, seconds float64) {
+	m.AgentClockSkew.WithLabelValues(clusterID).Set(seconds)
+}
+
+// ClearAgent drops the heartbeat and clock skew of a disconnected agent, so
+// they aren't reported as if it were still connected
+func (m *Metrics) ClearAgent(clusterID string) {
+	m.AgentLastHeartbeat.DeleteLabelValues(clusterID)
+	m.AgentClockSkew.DeleteLabelValues(clusterID)
+}
+
+// RecordDuplicateRegistration records a registration rejected because the
+// cluster name is registered by another cluster
+func (m *Metrics) RecordDuplicateRegistration(clusterID, clusterName string) {
+	m.AgentDuplicates.WithLabelValues(clusterID, clusterName).Inc()
+}
+
 // SetDatabaseConnections sets the number of database connections
 func (m *Metrics) SetDatabaseConnections(state string, count float64) {
 	m.DatabaseConnections.WithLabelValues(state).Set(count)
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Bus is an in-process event bus. Handlers run asynchronously so publishers
+// are never held up by slow subscribers such as notifiers.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]*subscription
+	pending  sync.WaitGroup
+	logger   *zap.Logger
+}
+
+type subscription struct {
+	handler func(event interface{}) error
+}
+
+// NewBus creates a new in-process event bus
+func NewBus(logger *zap.Logger) *Bus {
+	return &Bus{
+		handlers: make(map[string][]*subscription),
+		logger:   logger,
+	}
+}
+
+// Publish hands an event to every handler subscribed to the topic. Handler
+// errors are logged, not returned.
+func (b *Bus) Publish(ctx context.Context, topic string, event interface{}) error {
+	b.mu.RLock()
+	subscriptions := append([]*subscription(nil), b.handlers[topic]...)
+	b.mu.RUnlock()
+
+	for _, sub := range subscriptions {
+		b.pending.Add(1)
+		go func(sub *subscription) {
+			defer b.pending.Done()
+			if err := sub.handler(event); err != nil {
+				b.logger.Warn("Event handler failed", zap.String("topic", topic), zap.Error(err))
+			}
+		}(sub)
+	}
+
+	return nil
+}
+
+// Subscribe registers a handler for a topic until the context is done
+func (b *Bus) Subscribe(ctx context.Context, topic string, handler func(event interface{}) error) error {
+	if handler == nil {
+		return errors.New("handler is required")
+	}
+
+	sub := &subscription{handler: handler}
+
+	b.mu.Lock()
+	b.handlers[topic] = append(b.handlers[topic], sub)
+	b.mu.Unlock()
+
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			b.unsubscribe(topic, sub)
+		}()
+	}
+
+	return nil
+}
+
+// Wait blocks until the handlers of all events published so far have returned
+func (b *Bus) Wait() {
+	b.pending.Wait()
+}
+
+// unsubscribe removes a handler from a topic
+func (b *Bus) unsubscribe(topic string, sub *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscriptions := b.handlers[topic]
+	for i, s := range subscriptions {
+		if s == sub {
+			b.handlers[topic] = append(subscriptions[:i:i], subscriptions[i+1:]...)
+			break
+		}
+	}
+	if len(b.handlers[topic]) == 0 {
+		delete(b.handlers, topic)
+	}
+}
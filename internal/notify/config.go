@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// NewDispatcherFromConfig creates a dispatcher with the notifiers enabled in
+// the configuration. The dispatcher has no notifiers when none are enabled.
+func NewDispatcherFromConfig(cfg config.NotificationsConfig, logger *zap.Logger) (*Dispatcher, error) {
+	d := NewDispatcher(logger)
+
+	templates := map[string]string{
+		repo.TopicOperationFailed:     cfg.Templates.OperationFailed,
+		repo.TopicClusterDisconnected: cfg.Templates.ClusterDisconnected,
+	}
+	for topic, text := range templates {
+		if text == "" {
+			continue
+		}
+		if err := d.SetTemplate(topic, text); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Slack.Enabled {
+		if cfg.Slack.WebhookURL == "" {
+			return nil, fmt.Errorf("slack notifications require a webhook URL")
+		}
+		if err := d.AddNotifier(NewSlackNotifier(cfg.Slack.WebhookURL), cfg.Slack.Events...); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Email.Enabled {
+		email, err := NewEmailNotifier(cfg.Email.Host, cfg.Email.Port, cfg.Email.Username, cfg.Email.Password, cfg.Email.From, cfg.Email.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid email notifications: %w", err)
+		}
+		if err := d.AddNotifier(email, cfg.Email.Events...); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
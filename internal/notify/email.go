@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// EmailNotifier sends notifications by SMTP
+type EmailNotifier struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// NewEmailNotifier creates a notifier that sends mail from one address to the
+// given recipients through an SMTP server. Authentication is only used when a
+// username is given.
+func NewEmailNotifier(host string, port int, username, password, from string, to []string) (*EmailNotifier, error) {
+	if host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+	if from == "" {
+		return nil, errors.New("sender address is required")
+	}
+	if len(to) == 0 {
+		return nil, errors.New("at least one recipient is required")
+	}
+
+	return &EmailNotifier{
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+		to:       to,
+	}, nil
+}
+
+// Name returns the notifier name
+func (n *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Notify sends the message to every recipient. smtp.SendMail cannot be
+// cancelled, so the context is only checked before sending.
+func (n *EmailNotifier) Notify(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if n.username != "" {
+		auth = smtp.PlainAuth("", n.username, n.password, n.host)
+	}
+
+	if err := smtp.SendMail(n.addr, auth, n.from, n.to, n.buildMessage(message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// buildMessage formats the message as a plain text mail
+func (n *EmailNotifier) buildMessage(message Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + n.from + "\r\n")
+	b.WriteString("To: " + strings.Join(n.to, ", ") + "\r\n")
+	b.WriteString("Subject: " + message.Subject + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
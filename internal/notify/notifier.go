@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// Message is a rendered notification
+type Message struct {
+	Subject string
+	Body    string
+}
+
+// Notifier delivers notifications to a channel such as email or Slack
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, message Message) error
+}
+
+// defaultTemplates are the message templates of each supported topic. The
+// first line of a rendered template is the subject, the rest is the body.
+var defaultTemplates = map[string]string{
+	repo.TopicOperationFailed: `Operation {{.Type}} failed on cluster {{.ClusterID}}
+Operation {{.OperationID}} failed at {{.FailedAt.UTC.Format "2006-01-02T15:04:05Z07:00"}}: {{.Message}}`,
+	repo.TopicClusterDisconnected: `Cluster {{.ClusterID}} disconnected
+The agent of cluster {{.ClusterID}} stopped reporting and was marked disconnected at {{.DisconnectedAt.UTC.Format "2006-01-02T15:04:05Z07:00"}}.`,
+}
+
+// defaultNotifyTimeout bounds the delivery of a single notification
+const defaultNotifyTimeout = 10 * time.Second
+
+// Dispatcher subscribes to the event bus and sends templated messages to the
+// notifiers configured for each topic
+type Dispatcher struct {
+	notifiers map[string][]Notifier // topic -> notifiers
+	templates map[string]*template.Template
+	timeout   time.Duration
+	logger    *zap.Logger
+}
+
+// NewDispatcher creates a dispatcher with the default message templates
+func NewDispatcher(logger *zap.Logger) *Dispatcher {
+	d := &Dispatcher{
+		notifiers: make(map[string][]Notifier),
+		templates: make(map[string]*template.Template),
+		timeout:   defaultNotifyTimeout,
+		logger:    logger,
+	}
+	for topic, text := range defaultTemplates {
+		d.templates[topic] = template.Must(template.New(topic).Parse(text))
+	}
+	return d
+}
+
+// SetTemplate replaces the message template of a topic
+func (d *Dispatcher) SetTemplate(topic, text string) error {
+	if _, ok := d.templates[topic]; !ok {
+		return fmt.Errorf("unsupported event type: %s", topic)
+	}
+	tmpl, err := template.New(topic).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template for %s: %w", topic, err)
+	}
+	d.templates[topic] = tmpl
+	return nil
+}
+
+// AddNotifier sends the events of the given topics to a notifier
+func (d *Dispatcher) AddNotifier(notifier Notifier, topics ...string) error {
+	for _, topic := range topics {
+		if _, ok := d.templates[topic]; !ok {
+			return fmt.Errorf("unsupported event type: %s", topic)
+		}
+	}
+	for _, topic := range topics {
+		d.notifiers[topic] = append(d.notifiers[topic], notifier)
+	}
+	return nil
+}
+
+// Enabled reports whether any notifier is configured
+func (d *Dispatcher) Enabled() bool {
+	return len(d.notifiers) > 0
+}
+
+// Subscribe registers the dispatcher on the bus for every topic it has
+// notifiers for, until the context is done
+func (d *Dispatcher) Subscribe(ctx context.Context, bus repo.EventBus) error {
+	for topic := range d.notifiers {
+		if err := bus.Subscribe(ctx, topic, d.handler(topic)); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// handler returns the bus handler that notifies about events of a topic
+func (d *Dispatcher) handler(topic string) func(event interface{}) error {
+	return func(event interface{}) error {
+		message, err := d.render(topic, event)
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, notifier := range d.notifiers[topic] {
+			ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+			err := notifier.Notify(ctx, message)
+			cancel()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s notification failed: %w", notifier.Name(), err))
+				continue
+			}
+			d.logger.Debug("Notification sent", zap.String("topic", topic), zap.String("notifier", notifier.Name()))
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// render executes the template of a topic for an event
+func (d *Dispatcher) render(topic string, event interface{}) (Message, error) {
+	var buf bytes.Buffer
+	if err := d.templates[topic].Execute(&buf, event); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s notification: %w", topic, err)
+	}
+
+	subject, body, _ := strings.Cut(buf.String(), "\n")
+	return Message{
+		Subject: strings.TrimSpace(subject),
+		Body:    strings.TrimSpace(body),
+	}, nil
+}
@@ -0,0 +1,402 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/config"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// newSlackTestServer starts a Slack incoming webhook that records the text of
+// the messages it receives
+func newSlackTestServer(t *testing.T) (*httptest.Server, chan string) {
+	messages := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		messages <- payload.Text
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, messages
+}
+
+// newSMTPTestServer starts an SMTP server that accepts every mail and records
+// its data. It returns the server port.
+func newSMTPTestServer(t *testing.T) (int, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	mails := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, mails)
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, mails
+}
+
+// serveSMTP handles a single SMTP session
+func serveSMTP(conn net.Conn, mails chan<- string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL"), strings.HasPrefix(command, "RCPT"), strings.HasPrefix(command, "RSET"), strings.HasPrefix(command, "NOOP"):
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			mails <- data.String()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func failedOperationEvent() repo.OperationFailedEvent {
+	return repo.OperationFailedEvent{
+		OperationID: uuid.New(),
+		ClusterID:   uuid.New(),
+		Type:        repo.OperationTypeApply,
+		Message:     "deployments.apps \"web\" is forbidden",
+		FailedAt:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+// receive waits for a message on a channel
+func receive(t *testing.T, messages <-chan string) string {
+	t.Helper()
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a notification but got none")
+		return ""
+	}
+}
+
+func TestDispatcher_FailedOperationNotifiesSlack(t *testing.T) {
+	webhook, messages := newSlackTestServer(t)
+
+	dispatcher := NewDispatcher(zap.NewNop())
+	if err := dispatcher.AddNotifier(NewSlackNotifier(webhook.URL), repo.TopicOperationFailed); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	bus := NewBus(zap.NewNop())
+	if err := dispatcher.Subscribe(context.Background(), bus); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	event := failedOperationEvent()
+	if err := bus.Publish(context.Background(), repo.TopicOperationFailed, event); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	text := receive(t, messages)
+	for _, want := range []string{"Operation apply failed on cluster " + event.ClusterID.String(), event.OperationID.String(), event.Message, "2024-01-02T03:04:05Z"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected slack message to contain %q but got %q", want, text)
+		}
+	}
+}
+
+func TestDispatcher_FailedOperationSendsEmail(t *testing.T) {
+	port, mails := newSMTPTestServer(t)
+
+	email, err := NewEmailNotifier("127.0.0.1", port, "", "", "mckmt@example.com", []string{"ops@example.com", "oncall@example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	dispatcher := NewDispatcher(zap.NewNop())
+	if err := dispatcher.AddNotifier(email, repo.TopicOperationFailed); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	bus := NewBus(zap.NewNop())
+	if err := dispatcher.Subscribe(context.Background(), bus); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	event := failedOperationEvent()
+	if err := bus.Publish(context.Background(), repo.TopicOperationFailed, event); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	mail := receive(t, mails)
+	for _, want := range []string{
+		"From: mckmt@example.com",
+		"To: ops@example.com, oncall@example.com",
+		"Subject: Operation apply failed on cluster " + event.ClusterID.String(),
+		event.Message,
+	} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("Expected mail to contain %q but got %q", want, mail)
+		}
+	}
+}
+
+func TestDispatcher_OnlyNotifiesConfiguredEvents(t *testing.T) {
+	webhook, messages := newSlackTestServer(t)
+
+	dispatcher := NewDispatcher(zap.NewNop())
+	if err := dispatcher.AddNotifier(NewSlackNotifier(webhook.URL), repo.TopicClusterDisconnected); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	bus := NewBus(zap.NewNop())
+	if err := dispatcher.Subscribe(context.Background(), bus); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	_ = bus.Publish(context.Background(), repo.TopicOperationFailed, failedOperationEvent())
+	bus.Wait()
+	select {
+	case text := <-messages:
+		t.Fatalf("Expected no notification for failed operations but got %q", text)
+	default:
+	}
+
+	clusterID := uuid.New()
+	_ = bus.Publish(context.Background(), repo.TopicClusterDisconnected, repo.ClusterDisconnectedEvent{ClusterID: clusterID, DisconnectedAt: time.Now()})
+	if text := receive(t, messages); !strings.Contains(text, "Cluster "+clusterID.String()+" disconnected") {
+		t.Errorf("Expected disconnect notification but got %q", text)
+	}
+}
+
+func TestDispatcher_CustomTemplate(t *testing.T) {
+	webhook, messages := newSlackTestServer(t)
+
+	dispatcher := NewDispatcher(zap.NewNop())
+	if err := dispatcher.SetTemplate(repo.TopicOperationFailed, "{{.Type}} broke\n{{.Message}}"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if err := dispatcher.AddNotifier(NewSlackNotifier(webhook.URL), repo.TopicOperationFailed); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	bus := NewBus(zap.NewNop())
+	if err := dispatcher.Subscribe(context.Background(), bus); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	event := failedOperationEvent()
+	_ = bus.Publish(context.Background(), repo.TopicOperationFailed, event)
+
+	if text := receive(t, messages); text != "*apply broke*\n"+event.Message {
+		t.Errorf("Expected templated message but got %q", text)
+	}
+}
+
+func TestDispatcher_RejectsUnknownEvents(t *testing.T) {
+	dispatcher := NewDispatcher(zap.NewNop())
+
+	if err := dispatcher.AddNotifier(NewSlackNotifier("http://localhost"), "cluster.deleted"); err == nil {
+		t.Error("Expected error for unknown event type")
+	}
+	if err := dispatcher.SetTemplate(repo.TopicOperationFailed, "{{.Type"); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+	if dispatcher.Enabled() {
+		t.Error("Expected dispatcher without notifiers to be disabled")
+	}
+}
+
+func TestNewDispatcherFromConfig(t *testing.T) {
+	port, mails := newSMTPTestServer(t)
+
+	tests := []struct {
+		name        string
+		cfg         config.NotificationsConfig
+		wantErr     bool
+		wantEnabled bool
+	}{
+		{
+			name: "nothing enabled",
+			cfg: config.NotificationsConfig{
+				Slack: config.SlackNotificationConfig{WebhookURL: "http://localhost", Events: []string{repo.TopicOperationFailed}},
+			},
+		},
+		{
+			name: "slack without webhook",
+			cfg: config.NotificationsConfig{
+				Slack: config.SlackNotificationConfig{Enabled: true, Events: []string{repo.TopicOperationFailed}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "email without recipients",
+			cfg: config.NotificationsConfig{
+				Email: config.EmailNotificationConfig{Enabled: true, Host: "127.0.0.1", Port: port, From: "mckmt@example.com", Events: []string{repo.TopicOperationFailed}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown event",
+			cfg: config.NotificationsConfig{
+				Slack: config.SlackNotificationConfig{Enabled: true, WebhookURL: "http://localhost", Events: []string{"cluster.deleted"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid template",
+			cfg: config.NotificationsConfig{
+				Templates: config.NotificationTemplatesConfig{OperationFailed: "{{.Type"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "email with template",
+			cfg: config.NotificationsConfig{
+				Email: config.EmailNotificationConfig{
+					Enabled: true,
+					Host:    "127.0.0.1",
+					Port:    port,
+					From:    "mckmt@example.com",
+					To:      []string{"ops@example.com"},
+					Events:  []string{repo.TopicOperationFailed},
+				},
+				Templates: config.NotificationTemplatesConfig{OperationFailed: "Failed: {{.Type}}\n{{.Message}}"},
+			},
+			wantEnabled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher, err := NewDispatcherFromConfig(tt.cfg, zap.NewNop())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v but got: %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if dispatcher.Enabled() != tt.wantEnabled {
+				t.Fatalf("Expected enabled %v but got %v", tt.wantEnabled, dispatcher.Enabled())
+			}
+			if !tt.wantEnabled {
+				return
+			}
+
+			bus := NewBus(zap.NewNop())
+			if err := dispatcher.Subscribe(context.Background(), bus); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			_ = bus.Publish(context.Background(), repo.TopicOperationFailed, failedOperationEvent())
+			if mail := receive(t, mails); !strings.Contains(mail, "Subject: Failed: apply") {
+				t.Errorf("Expected templated subject but got %q", mail)
+			}
+		})
+	}
+}
+
+func TestBus_UnsubscribesWhenContextDone(t *testing.T) {
+	bus := NewBus(zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := make(chan struct{}, 10)
+	if err := bus.Subscribe(ctx, repo.TopicOperationFailed, func(event interface{}) error {
+		calls <- struct{}{}
+		return nil
+	}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	_ = bus.Publish(context.Background(), repo.TopicOperationFailed, failedOperationEvent())
+	bus.Wait()
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 handler call but got %d", len(calls))
+	}
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		bus.mu.RLock()
+		remaining := len(bus.handlers[repo.TopicOperationFailed])
+		bus.mu.RUnlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected handler to be removed after its context was cancelled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_ = bus.Publish(context.Background(), repo.TopicOperationFailed, failedOperationEvent())
+	bus.Wait()
+	if len(calls) != 1 {
+		t.Errorf("Expected no further handler calls but got %d", len(calls))
+	}
+}
+
+func TestEmailNotifier_RequiresAddresses(t *testing.T) {
+	tests := []struct {
+		host string
+		from string
+		to   []string
+	}{
+		{host: "", from: "a@example.com", to: []string{"b@example.com"}},
+		{host: "localhost", from: "", to: []string{"b@example.com"}},
+		{host: "localhost", from: "a@example.com"},
+	}
+
+	for i, tt := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if _, err := NewEmailNotifier(tt.host, 25, "", "", tt.from, tt.to); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
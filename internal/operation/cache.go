@@ -0,0 +1,113 @@
+package operation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// DefaultCacheTTL is how long operation reads are cached. It is kept short
+// because operations change state often and other components, such as the gRPC
+// server, update them without going through this service.
+const DefaultCacheTTL = 10 * time.Second
+
+// DefaultResultCacheTTL is how long finished operations are cached. Their state
+// no longer changes, so they can be kept much longer than active ones, which
+// spares the database when many clients poll a completed operation.
+const DefaultResultCacheTTL = 5 * time.Minute
+
+// cacheFills tracks reads that are about to populate the cache after a miss, so
+// an invalidation racing with such a read keeps its stale result out of the cache
+type cacheFills struct {
+	mu    sync.Mutex
+	fills map[uuid.UUID]*cacheFill
+}
+
+// cacheFill is shared by all in-flight reads of the same operation
+type cacheFill struct {
+	readers int
+	stale   bool
+}
+
+func newCacheFills() *cacheFills {
+	return &cacheFills{fills: make(map[uuid.UUID]*cacheFill)}
+}
+
+// begin registers a read of id that will populate the cache
+func (f *cacheFills) begin(id uuid.UUID) *cacheFill {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fill, ok := f.fills[id]
+	if !ok {
+		fill = &cacheFill{}
+		f.fills[id] = fill
+	}
+	fill.readers++
+	return fill
+}
+
+// end unregisters a read and reports whether its result may be cached
+func (f *cacheFills) end(id uuid.UUID, fill *cacheFill) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fill.readers--
+	if fill.readers == 0 && f.fills[id] == fill {
+		delete(f.fills, id)
+	}
+	return !fill.stale
+}
+
+// invalidate marks in-flight reads of id as stale
+func (f *cacheFills) invalidate(id uuid.UUID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if fill, ok := f.fills[id]; ok {
+		fill.stale = true
+		// Reads starting after the invalidation see the new state
+		delete(f.fills, id)
+	}
+}
+
+// SetCacheTTL sets how long operation reads are cached
+func (s *Service) SetCacheTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.cacheTTL = ttl
+	}
+}
+
+// SetResultCacheTTL sets how long finished operations are cached
+func (s *Service) SetResultCacheTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.resultCacheTTL = ttl
+	}
+}
+
+// cacheTTLFor returns how long an operation may be cached given its status
+func (s *Service) cacheTTLFor(operation *repo.Operation) time.Duration {
+	switch operation.Status {
+	case repo.OperationStatusSuccess, repo.OperationStatusFailed, repo.OperationStatusCancelled:
+		return s.resultCacheTTL
+	default:
+		return s.cacheTTL
+	}
+}
+
+// invalidateCache drops the cached copy of an operation after it was modified
+func (s *Service) invalidateCache(ctx context.Context, id uuid.UUID) {
+	s.fills.invalidate(id)
+	// Reads starting after the modification must not join an in-flight fetch
+	s.flight.Forget(id.String())
+
+	key := s.cache.OperationKey(id.String())
+	if err := s.cache.Delete(ctx, key); err != nil {
+		s.logger.Warn("Failed to invalidate operation cache", zap.Error(err))
+	}
+}
@@ -0,0 +1,190 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// DefaultMaxRetries is the number of times a retryable failure is re-queued
+// before the operation is dead-lettered
+const DefaultMaxRetries = 3
+
+// Requeuer hands an operation back to the orchestrator
+type Requeuer interface {
+	QueueOperation(operation *repo.Operation) error
+}
+
+// DeadLetterQueue re-queues operations whose failure is retryable until they
+// run out of retries, and moves them, and operations failing for good, to the
+// dead-letter queue where an admin can inspect and re-drive them
+type DeadLetterQueue struct {
+	operations  repo.OperationRepository
+	events      repo.OperationEventRepository
+	deadLetters repo.DeadLetterRepository
+	queue       Requeuer
+	maxRetries  int
+	logger      *zap.Logger
+}
+
+// NewDeadLetterQueue creates a dead-letter queue retrying operations up to
+// maxRetries times, or DefaultMaxRetries when maxRetries is negative
+func NewDeadLetterQueue(operations repo.OperationRepository, events repo.OperationEventRepository, deadLetters repo.DeadLetterRepository, queue Requeuer, maxRetries int, logger *zap.Logger) *DeadLetterQueue {
+	if maxRetries < 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	return &DeadLetterQueue{
+		operations:  operations,
+		events:      events,
+		deadLetters: deadLetters,
+		queue:       queue,
+		maxRetries:  maxRetries,
+		logger:      logger,
+	}
+}
+
+// Subscribe handles the operation failures published on the bus until the
+// context is done. Both the agent server and the orchestrator must publish on
+// it, so failures reported by agents and failures of the orchestrator itself
+// are caught.
+func (q *DeadLetterQueue) Subscribe(ctx context.Context, bus repo.EventBus) error {
+	if err := bus.Subscribe(ctx, repo.TopicOperationFailed, q.handle); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", repo.TopicOperationFailed, err)
+	}
+	return nil
+}
+
+// handle is the bus handler of operation failures
+func (q *DeadLetterQueue) handle(event interface{}) error {
+	failed, ok := event.(repo.OperationFailedEvent)
+	if !ok {
+		return fmt.Errorf("unexpected %s event: %T", repo.TopicOperationFailed, event)
+	}
+	return q.HandleFailure(context.Background(), failed)
+}
+
+// HandleFailure re-queues a failed operation when its failure is retryable and
+// it has retries left, and dead-letters it otherwise. Attempts are counted from
+// the failed transitions in the operation's history, so a re-driven operation
+// that fails again goes straight back to the dead-letter queue.
+func (q *DeadLetterQueue) HandleFailure(ctx context.Context, event repo.OperationFailedEvent) error {
+	attempts, err := q.attempts(ctx, event.OperationID)
+	if err != nil {
+		return err
+	}
+
+	reason := event.Message
+	if event.Retryable && attempts <= q.maxRetries {
+		err := q.requeue(ctx, event.OperationID)
+		if err == nil {
+			q.logger.Info("Retrying failed operation",
+				zap.String("operation_id", event.OperationID.String()),
+				zap.Int("attempts", attempts),
+				zap.Int("max_retries", q.maxRetries),
+			)
+			return nil
+		}
+		if errors.Is(err, ErrOperationSecretsMasked) {
+			reason = fmt.Sprintf("%s (not retried: %v)", reason, err)
+		} else {
+			reason = fmt.Sprintf("%s (retry failed: %v)", reason, err)
+		}
+	}
+
+	deadLetter := &repo.DeadLetter{
+		OperationID:    event.OperationID,
+		ClusterID:      event.ClusterID,
+		Type:           event.Type,
+		Reason:         reason,
+		Attempts:       attempts,
+		DeadLetteredAt: time.Now().UTC(),
+	}
+	if err := q.deadLetters.Create(ctx, deadLetter); err != nil {
+		return fmt.Errorf("failed to dead-letter operation %s: %w", event.OperationID, err)
+	}
+
+	q.logger.Warn("Operation dead-lettered",
+		zap.String("operation_id", event.OperationID.String()),
+		zap.String("cluster_id", event.ClusterID.String()),
+		zap.String("type", event.Type),
+		zap.Int("attempts", attempts),
+		zap.String("reason", reason),
+	)
+	return nil
+}
+
+// List lists the dead-lettered operations, most recent first
+func (q *DeadLetterQueue) List(ctx context.Context, limit, offset int) ([]*repo.DeadLetter, error) {
+	return q.deadLetters.List(ctx, limit, offset)
+}
+
+// Count counts the dead-lettered operations
+func (q *DeadLetterQueue) Count(ctx context.Context) (int, error) {
+	return q.deadLetters.Count(ctx)
+}
+
+// Redrive re-queues a dead-lettered operation and takes it off the queue.
+// Operations stored with masked Secrets stay dead-lettered and fail with
+// ErrOperationSecretsMasked.
+func (q *DeadLetterQueue) Redrive(ctx context.Context, operationID uuid.UUID) error {
+	if _, err := q.deadLetters.Get(ctx, operationID); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return ErrOperationNotDeadLettered
+		}
+		return err
+	}
+
+	if err := q.requeue(ctx, operationID); err != nil {
+		return err
+	}
+
+	if err := q.deadLetters.Delete(ctx, operationID); err != nil {
+		return fmt.Errorf("failed to remove dead letter: %w", err)
+	}
+
+	q.logger.Info("Operation re-driven from the dead-letter queue", zap.String("operation_id", operationID.String()))
+	return nil
+}
+
+// attempts counts the times an operation failed
+func (q *DeadLetterQueue) attempts(ctx context.Context, operationID uuid.UUID) (int, error) {
+	events, err := q.events.ListByOperation(ctx, operationID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list operation history: %w", err)
+	}
+
+	attempts := 0
+	for _, event := range events {
+		if event.ToStatus == repo.OperationStatusFailed {
+			attempts++
+		}
+	}
+	// The failure being handled may not be recorded yet
+	return max(attempts, 1), nil
+}
+
+// requeue puts an operation back into the queued state and hands it to the
+// orchestrator. Operations stored with masked Secrets are refused, as
+// recovery does, since the stored payload no longer holds the Secret values.
+func (q *DeadLetterQueue) requeue(ctx context.Context, operationID uuid.UUID) error {
+	operation, err := q.operations.GetByID(ctx, operationID)
+	if err != nil {
+		return fmt.Errorf("failed to get operation: %w", err)
+	}
+	if masked, _ := operation.Payload[repo.PayloadSecretsMasked].(bool); masked {
+		return ErrOperationSecretsMasked
+	}
+
+	if err := q.operations.UpdateStatus(ctx, operationID, "queued"); err != nil {
+		return fmt.Errorf("failed to update operation status: %w", err)
+	}
+	operation.Status = "queued"
+
+	return q.queue.QueueOperation(operation)
+}
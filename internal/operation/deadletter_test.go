@@ -0,0 +1,218 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/repo/mocks"
+	"github.com/rizesky/mckmt/internal/testutils"
+)
+
+type deadLetterTest struct {
+	queue         *DeadLetterQueue
+	operationRepo *mocks.MockOperationRepository
+	eventRepo     *mocks.MockOperationEventRepository
+	deadLetters   *mocks.MockDeadLetterRepository
+	orchestrator  *testutils.MockOrchestrator
+}
+
+func newDeadLetterTest(t *testing.T, maxRetries int) *deadLetterTest {
+	ctrl := gomock.NewController(t)
+	test := &deadLetterTest{
+		operationRepo: mocks.NewMockOperationRepository(ctrl),
+		eventRepo:     mocks.NewMockOperationEventRepository(ctrl),
+		deadLetters:   mocks.NewMockDeadLetterRepository(ctrl),
+		orchestrator:  testutils.NewMockOrchestrator(),
+	}
+	test.queue = NewDeadLetterQueue(test.operationRepo, test.eventRepo, test.deadLetters, test.orchestrator, maxRetries, zap.NewNop())
+	return test
+}
+
+// failures returns an operation history with the given number of failures
+func failures(operationID uuid.UUID, count int) []*repo.OperationEvent {
+	events := []*repo.OperationEvent{{OperationID: operationID, FromStatus: "queued", ToStatus: "running"}}
+	for range count {
+		events = append(events, &repo.OperationEvent{OperationID: operationID, FromStatus: "running", ToStatus: "failed"})
+	}
+	return events
+}
+
+func TestDeadLetterQueue_RetriesRetryableFailure(t *testing.T) {
+	test := newDeadLetterTest(t, 2)
+	operation := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: "apply", Status: "failed"}
+
+	test.eventRepo.EXPECT().ListByOperation(gomock.Any(), operation.ID).Return(failures(operation.ID, 2), nil)
+	test.operationRepo.EXPECT().GetByID(gomock.Any(), operation.ID).Return(operation, nil)
+	test.operationRepo.EXPECT().UpdateStatus(gomock.Any(), operation.ID, "queued").Return(nil)
+	test.deadLetters.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	err := test.queue.HandleFailure(context.Background(), repo.OperationFailedEvent{
+		OperationID: operation.ID,
+		ClusterID:   operation.ClusterID,
+		Type:        operation.Type,
+		Message:     "conflict",
+		Retryable:   true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	queued := test.orchestrator.GetQueuedOperations()
+	if len(queued) != 1 || queued[0].ID != operation.ID || queued[0].Status != "queued" {
+		t.Errorf("Expected the operation to be queued again but got %v", queued)
+	}
+}
+
+func TestDeadLetterQueue_DoesNotRetryMaskedSecrets(t *testing.T) {
+	test := newDeadLetterTest(t, 2)
+	operation := &repo.Operation{
+		ID:        uuid.New(),
+		ClusterID: uuid.New(),
+		Type:      "apply",
+		Status:    "failed",
+		Payload:   repo.Payload{repo.PayloadSecretsMasked: true},
+	}
+
+	var created *repo.DeadLetter
+	test.eventRepo.EXPECT().ListByOperation(gomock.Any(), operation.ID).Return(failures(operation.ID, 1), nil)
+	test.operationRepo.EXPECT().GetByID(gomock.Any(), operation.ID).Return(operation, nil)
+	test.operationRepo.EXPECT().UpdateStatus(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	test.deadLetters.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, deadLetter *repo.DeadLetter) error {
+		created = deadLetter
+		return nil
+	})
+
+	err := test.queue.HandleFailure(context.Background(), repo.OperationFailedEvent{
+		OperationID: operation.ID,
+		ClusterID:   operation.ClusterID,
+		Type:        operation.Type,
+		Message:     "conflict",
+		Retryable:   true,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if created == nil || !strings.Contains(created.Reason, "resubmit it") {
+		t.Errorf("Expected the operation to be dead-lettered asking to resubmit it but got %+v", created)
+	}
+	if len(test.orchestrator.GetQueuedOperations()) != 0 {
+		t.Error("Expected the operation not to be queued again")
+	}
+}
+
+func TestDeadLetterQueue_DeadLettersFailures(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		retryable        bool
+		expectedAttempts int
+	}{
+		{name: "retries exhausted", failures: 3, retryable: true, expectedAttempts: 3},
+		{name: "permanent failure", failures: 1, retryable: false, expectedAttempts: 1},
+		{name: "failure not yet recorded", failures: 0, retryable: false, expectedAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newDeadLetterTest(t, 2)
+			event := repo.OperationFailedEvent{
+				OperationID: uuid.New(),
+				ClusterID:   uuid.New(),
+				Type:        "apply",
+				Message:     "Operation failed",
+				Retryable:   tt.retryable,
+			}
+
+			var created *repo.DeadLetter
+			test.eventRepo.EXPECT().ListByOperation(gomock.Any(), event.OperationID).Return(failures(event.OperationID, tt.failures), nil)
+			test.deadLetters.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, deadLetter *repo.DeadLetter) error {
+				created = deadLetter
+				return nil
+			})
+
+			if err := test.queue.HandleFailure(context.Background(), event); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if created == nil {
+				t.Fatal("Expected the operation to be dead-lettered")
+			}
+			if created.OperationID != event.OperationID || created.ClusterID != event.ClusterID || created.Type != "apply" || created.Reason != "Operation failed" {
+				t.Errorf("Expected a dead letter of the failed operation but got %+v", created)
+			}
+			if created.Attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts but got %d", tt.expectedAttempts, created.Attempts)
+			}
+			if len(test.orchestrator.GetQueuedOperations()) != 0 {
+				t.Error("Expected the operation not to be queued again")
+			}
+		})
+	}
+}
+
+func TestDeadLetterQueue_Redrive(t *testing.T) {
+	test := newDeadLetterTest(t, 2)
+	operation := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: "apply", Status: "failed"}
+
+	gomock.InOrder(
+		test.deadLetters.EXPECT().Get(gomock.Any(), operation.ID).Return(&repo.DeadLetter{OperationID: operation.ID}, nil),
+		test.operationRepo.EXPECT().GetByID(gomock.Any(), operation.ID).Return(operation, nil),
+		test.operationRepo.EXPECT().UpdateStatus(gomock.Any(), operation.ID, "queued").Return(nil),
+		test.deadLetters.EXPECT().Delete(gomock.Any(), operation.ID).Return(nil),
+	)
+
+	if err := test.queue.Redrive(context.Background(), operation.ID); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if queued := test.orchestrator.GetQueuedOperations(); len(queued) != 1 || queued[0].ID != operation.ID {
+		t.Errorf("Expected the operation to be queued again but got %v", queued)
+	}
+}
+
+func TestDeadLetterQueue_RedriveMaskedSecrets(t *testing.T) {
+	test := newDeadLetterTest(t, 2)
+	operation := &repo.Operation{
+		ID:        uuid.New(),
+		ClusterID: uuid.New(),
+		Type:      "apply",
+		Status:    "failed",
+		Payload: repo.Payload{
+			"manifests":               "apiVersion: v1\nkind: Secret\ndata:\n  password: '***masked***'\n",
+			repo.PayloadSecretsMasked: true,
+		},
+	}
+
+	test.deadLetters.EXPECT().Get(gomock.Any(), operation.ID).Return(&repo.DeadLetter{OperationID: operation.ID}, nil)
+	test.operationRepo.EXPECT().GetByID(gomock.Any(), operation.ID).Return(operation, nil)
+	test.operationRepo.EXPECT().UpdateStatus(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	test.deadLetters.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+
+	if err := test.queue.Redrive(context.Background(), operation.ID); !errors.Is(err, ErrOperationSecretsMasked) {
+		t.Errorf("Expected ErrOperationSecretsMasked but got: %v", err)
+	}
+	if queued := test.orchestrator.GetQueuedOperations(); len(queued) != 0 {
+		t.Errorf("Expected no operation to be queued but got %v", queued)
+	}
+}
+
+func TestDeadLetterQueue_RedriveNotDeadLettered(t *testing.T) {
+	test := newDeadLetterTest(t, 2)
+	id := uuid.New()
+
+	test.deadLetters.EXPECT().Get(gomock.Any(), id).Return(nil, repo.ErrNotFound)
+
+	if err := test.queue.Redrive(context.Background(), id); err != ErrOperationNotDeadLettered {
+		t.Errorf("Expected ErrOperationNotDeadLettered but got: %v", err)
+	}
+	if len(test.orchestrator.GetQueuedOperations()) != 0 {
+		t.Error("Expected no operation to be queued")
+	}
+}
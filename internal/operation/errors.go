@@ -18,5 +18,15 @@ var (
 	ErrOperationTypeRequired    = errors.New("operation type is required")
 	ErrOperationClusterRequired = errors.New("operation cluster ID is required")
 	ErrOperationPayloadInvalid  = errors.New("invalid operation payload")
+	ErrOperationPayloadTooLarge = errors.New("operation payload too large")
 	ErrOperationResultInvalid   = errors.New("invalid operation result")
 )
+
+// ErrOperationNotDeadLettered is returned when re-driving an operation that is
+// not in the dead-letter queue
+var ErrOperationNotDeadLettered = errors.New("operation is not dead-lettered")
+
+// ErrOperationSecretsMasked is returned when re-queuing an operation whose
+// stored manifests had their Secret values masked, since applying them would
+// overwrite the Secrets with masked values
+var ErrOperationSecretsMasked = errors.New("the operation's Secret values were masked when it was stored; resubmit it to apply them")
@@ -0,0 +1,68 @@
+package operation
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+const (
+	// DefaultOutputChunkSize is the number of output bytes returned when no limit is given
+	DefaultOutputChunkSize = 64 << 10
+
+	// MaxOutputChunkSize bounds the number of output bytes returned by a single read
+	MaxOutputChunkSize = 1 << 20
+)
+
+// OutputChunk is a slice of an operation's output
+type OutputChunk struct {
+	Data []byte
+	// Offset is the position of Data in the output
+	Offset int64
+	// Size is the total number of output bytes stored so far
+	Size int64
+	// Complete reports whether the operation finished, so no more output will follow
+	Complete bool
+}
+
+// GetOperationOutput reads up to limit bytes of an operation's output starting
+// at offset. Clients follow running operations by reading again from
+// Offset+len(Data) until the chunk is complete and no data is left.
+func (s *Service) GetOperationOutput(ctx context.Context, id uuid.UUID, offset int64, limit int) (*OutputChunk, error) {
+	operation, err := s.GetOperation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = DefaultOutputChunkSize
+	}
+	if limit > MaxOutputChunkSize {
+		limit = MaxOutputChunkSize
+	}
+
+	// Output is appended while the operation runs, so it is never cached
+	data, size, err := s.outputRepo.Read(ctx, id, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutputChunk{
+		Data:     data,
+		Offset:   offset,
+		Size:     size,
+		Complete: isFinished(operation.Status),
+	}, nil
+}
+
+// isFinished reports whether an operation status is terminal
+func isFinished(status string) bool {
+	switch status {
+	case repo.OperationStatusSuccess, repo.OperationStatusFailed, repo.OperationStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
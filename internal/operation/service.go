@@ -8,14 +8,28 @@ import (
 	"github.com/google/uuid"
 	"github.com/rizesky/mckmt/internal/repo"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
 // Service handles operation business logic
 type Service struct {
 	operationRepo repo.OperationRepository
+	eventRepo     repo.OperationEventRepository
+	outputRepo    repo.OperationOutputRepository
 	cache         repo.Cache
 	logger        *zap.Logger
 	orchestrator  OrchestratorInterface
+	cacheTTL      time.Duration
+	fills         *cacheFills
+
+	// resultCacheTTL is the cache TTL of finished operations
+	resultCacheTTL time.Duration
+
+	// flight collapses concurrent cache misses for the same operation into one read
+	flight singleflight.Group
+
+	// maxPayloadSize bounds the serialized payload of created operations
+	maxPayloadSize int
 }
 
 // OrchestratorInterface defines the interface for orchestrator operations
@@ -24,12 +38,27 @@ type OrchestratorInterface interface {
 }
 
 // NewService creates a new operation service
-func NewService(operationRepo repo.OperationRepository, cache repo.Cache, logger *zap.Logger, orchestrator OrchestratorInterface) *Service {
+func NewService(operationRepo repo.OperationRepository, eventRepo repo.OperationEventRepository, outputRepo repo.OperationOutputRepository, cache repo.Cache, logger *zap.Logger, orchestrator OrchestratorInterface) *Service {
 	return &Service{
 		operationRepo: operationRepo,
+		eventRepo:     eventRepo,
+		outputRepo:    outputRepo,
 		cache:         cache,
 		logger:        logger,
 		orchestrator:  orchestrator,
+		cacheTTL:      DefaultCacheTTL,
+		fills:         newCacheFills(),
+
+		resultCacheTTL: DefaultResultCacheTTL,
+
+		maxPayloadSize: repo.DefaultMaxPayloadSize,
+	}
+}
+
+// SetMaxPayloadSize sets the maximum serialized size of operation payloads
+func (s *Service) SetMaxPayloadSize(size int) {
+	if size > 0 {
+		s.maxPayloadSize = size
 	}
 }
 
@@ -48,18 +77,41 @@ func (s *Service) GetOperation(ctx context.Context, id uuid.UUID) (*repo.Operati
 		s.logger.Warn("Cache error, falling back to database", zap.Error(err))
 	}
 
-	// Cache miss, get from database
-	operationFromDB, err := s.operationRepo.GetByID(ctx, id)
+	// Cache miss, get from database. Concurrent misses share a single read so an
+	// expiring entry doesn't send every poller to the database at once.
+	shared, err, _ := s.flight.Do(id.String(), func() (interface{}, error) {
+		// The read is shared, so it must not fail because one caller went away
+		readCtx := context.WithoutCancel(ctx)
+
+		fill := s.fills.begin(id)
+		operationFromDB, err := s.operationRepo.GetByID(readCtx, id)
+		cacheable := s.fills.end(id, fill)
+		if err != nil {
+			return nil, err
+		}
+
+		// Cache the result unless the operation was modified while it was being read
+		if cacheable {
+			if err := s.cache.Set(readCtx, key, operationFromDB, s.cacheTTLFor(operationFromDB)); err != nil {
+				s.logger.Warn("Failed to cache operation", zap.Error(err))
+			}
+		}
+
+		return operationFromDB, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Cache the result
-	if err := s.cache.Set(ctx, key, operationFromDB, 30*time.Minute); err != nil {
-		s.logger.Warn("Failed to cache operation", zap.Error(err))
-	}
+	// Hand each caller its own copy of the shared result
+	operationFromDB := *shared.(*repo.Operation)
+	return &operationFromDB, nil
+}
 
-	return operationFromDB, nil
+// GetOperationHistory retrieves the state transitions of an operation, oldest first
+func (s *Service) GetOperationHistory(ctx context.Context, id uuid.UUID) ([]*repo.OperationEvent, error) {
+	// History is not cached since every transition appends to it
+	return s.eventRepo.ListByOperation(ctx, id)
 }
 
 // ListOperationsByCluster retrieves operations for a cluster
@@ -68,16 +120,35 @@ func (s *Service) ListOperationsByCluster(ctx context.Context, clusterID uuid.UU
 	return s.operationRepo.ListByCluster(ctx, clusterID, limit, offset)
 }
 
+// CountOperationsByCluster returns the number of operations of a cluster
+func (s *Service) CountOperationsByCluster(ctx context.Context, clusterID uuid.UUID) (int, error) {
+	return s.operationRepo.CountByCluster(ctx, clusterID)
+}
+
+// EachOperationByCluster calls fn for every operation of a cluster, oldest
+// first, without loading them all into memory
+func (s *Service) EachOperationByCluster(ctx context.Context, clusterID uuid.UUID, fn func(*repo.Operation) error) error {
+	return s.operationRepo.EachByCluster(ctx, clusterID, fn)
+}
+
 // CreateOperation creates a new operation
 func (s *Service) CreateOperation(ctx context.Context, operation *repo.Operation) error {
-	err := s.operationRepo.Create(ctx, operation)
+	size, err := operation.Payload.EncodedSize()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOperationPayloadInvalid, err)
+	}
+	if size > s.maxPayloadSize {
+		return fmt.Errorf("%w: %d bytes exceeds the maximum of %d", ErrOperationPayloadTooLarge, size, s.maxPayloadSize)
+	}
+
+	err = s.operationRepo.Create(ctx, operation)
 	if err != nil {
 		return err
 	}
 
 	// Cache the created operation
 	key := s.cache.OperationKey(operation.ID.String())
-	if err := s.cache.Set(ctx, key, operation, 30*time.Minute); err != nil {
+	if err := s.cache.Set(ctx, key, operation, s.cacheTTLFor(operation)); err != nil {
 		s.logger.Warn("Failed to cache created operation", zap.Error(err))
 	}
 
@@ -92,8 +163,9 @@ func (s *Service) UpdateOperation(ctx context.Context, operation *repo.Operation
 	}
 
 	// Update cache
+	s.fills.invalidate(operation.ID)
 	key := s.cache.OperationKey(operation.ID.String())
-	if err := s.cache.Set(ctx, key, operation, 30*time.Minute); err != nil {
+	if err := s.cache.Set(ctx, key, operation, s.cacheTTLFor(operation)); err != nil {
 		s.logger.Warn("Failed to update operation cache", zap.Error(err))
 	}
 
@@ -108,10 +180,7 @@ func (s *Service) UpdateOperationStatus(ctx context.Context, id uuid.UUID, statu
 	}
 
 	// Invalidate cache to force refresh
-	key := s.cache.OperationKey(id.String())
-	if err := s.cache.Delete(ctx, key); err != nil {
-		s.logger.Warn("Failed to invalidate operation cache", zap.Error(err))
-	}
+	s.invalidateCache(ctx, id)
 
 	return nil
 }
@@ -124,10 +193,7 @@ func (s *Service) UpdateOperationResult(ctx context.Context, id uuid.UUID, resul
 	}
 
 	// Invalidate cache to force refresh
-	key := s.cache.OperationKey(id.String())
-	if err := s.cache.Delete(ctx, key); err != nil {
-		s.logger.Warn("Failed to invalidate operation cache", zap.Error(err))
-	}
+	s.invalidateCache(ctx, id)
 
 	return nil
 }
@@ -154,10 +220,7 @@ func (s *Service) CancelOperation(ctx context.Context, id uuid.UUID, reason stri
 	}
 
 	// Invalidate cache to force refresh
-	key := s.cache.OperationKey(id.String())
-	if err := s.cache.Delete(ctx, key); err != nil {
-		s.logger.Warn("Failed to invalidate operation cache", zap.Error(err))
-	}
+	s.invalidateCache(ctx, id)
 
 	s.logger.Info("Operation cancellation requested",
 		zap.String("operation_id", id.String()),
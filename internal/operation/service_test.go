@@ -0,0 +1,184 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func newTestService(t *testing.T) (*Service, *mocks.MockOperationRepository, *mocks.MockCache) {
+	ctrl := gomock.NewController(t)
+	operationRepo := mocks.NewMockOperationRepository(ctrl)
+	cache := mocks.NewMockCache(ctrl)
+	cache.EXPECT().OperationKey(gomock.Any()).DoAndReturn(func(id string) string { return "operation:" + id }).AnyTimes()
+	return NewService(operationRepo, nil, nil, cache, zap.NewNop(), nil), operationRepo, cache
+}
+
+func TestOperationService_GetOperationCacheHit(t *testing.T) {
+	service, operationRepo, cache := newTestService(t)
+	id := uuid.New()
+
+	cache.EXPECT().Get(gomock.Any(), "operation:"+id.String(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, dest interface{}) error {
+			*dest.(*repo.Operation) = repo.Operation{ID: id, Status: "running"}
+			return nil
+		})
+	operationRepo.EXPECT().GetByID(gomock.Any(), gomock.Any()).Times(0)
+
+	operation, err := service.GetOperation(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if operation.Status != "running" {
+		t.Errorf("Expected cached status running, got %s", operation.Status)
+	}
+}
+
+func TestOperationService_GetOperationCacheMiss(t *testing.T) {
+	service, operationRepo, cache := newTestService(t)
+	service.SetCacheTTL(5 * time.Second)
+	id := uuid.New()
+	stored := &repo.Operation{ID: id, Status: "queued"}
+
+	cache.EXPECT().Get(gomock.Any(), "operation:"+id.String(), gomock.Any()).Return(repo.ErrCacheMiss)
+	operationRepo.EXPECT().GetByID(gomock.Any(), id).Return(stored, nil)
+	cache.EXPECT().Set(gomock.Any(), "operation:"+id.String(), stored, 5*time.Second).Return(nil)
+
+	if _, err := service.GetOperation(context.Background(), id); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
+
+func TestOperationService_UpdateInvalidatesCache(t *testing.T) {
+	service, operationRepo, cache := newTestService(t)
+	id := uuid.New()
+
+	gomock.InOrder(
+		operationRepo.EXPECT().UpdateStatus(gomock.Any(), id, "success").Return(nil),
+		cache.EXPECT().Delete(gomock.Any(), "operation:"+id.String()).Return(nil),
+	)
+	if err := service.UpdateOperationStatus(context.Background(), id, "success"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	gomock.InOrder(
+		operationRepo.EXPECT().UpdateResult(gomock.Any(), id, gomock.Any()).Return(nil),
+		cache.EXPECT().Delete(gomock.Any(), "operation:"+id.String()).Return(nil),
+	)
+	if err := service.UpdateOperationResult(context.Background(), id, repo.Payload{"ok": true}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
+
+func TestOperationService_GetOperationSkipsCachingStaleRead(t *testing.T) {
+	service, operationRepo, cache := newTestService(t)
+	id := uuid.New()
+
+	cache.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(repo.ErrCacheMiss)
+	// A status update lands while the old state is being read from the database
+	operationRepo.EXPECT().GetByID(gomock.Any(), id).
+		DoAndReturn(func(ctx context.Context, _ uuid.UUID) (*repo.Operation, error) {
+			if err := service.UpdateOperationStatus(ctx, id, "success"); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			return &repo.Operation{ID: id, Status: "running"}, nil
+		})
+	operationRepo.EXPECT().UpdateStatus(gomock.Any(), id, "success").Return(nil)
+	cache.EXPECT().Delete(gomock.Any(), "operation:"+id.String()).Return(nil)
+	cache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	if _, err := service.GetOperation(context.Background(), id); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
+
+func TestOperationService_CreateOperationPayloadTooLarge(t *testing.T) {
+	service, operationRepo, _ := newTestService(t)
+	service.SetMaxPayloadSize(1024)
+
+	operationRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+
+	operation := &repo.Operation{
+		ID:      uuid.New(),
+		Type:    repo.OperationTypeExec,
+		Payload: repo.Payload{"env": strings.Repeat("x", 2048)},
+	}
+	if err := service.CreateOperation(context.Background(), operation); !errors.Is(err, ErrOperationPayloadTooLarge) {
+		t.Fatalf("Expected ErrOperationPayloadTooLarge but got: %v", err)
+	}
+}
+
+func TestOperationService_GetOperationCachesFinishedOperationsLonger(t *testing.T) {
+	service, operationRepo, cache := newTestService(t)
+	service.SetResultCacheTTL(time.Hour)
+	id := uuid.New()
+	stored := &repo.Operation{ID: id, Status: repo.OperationStatusSuccess}
+
+	cache.EXPECT().Get(gomock.Any(), "operation:"+id.String(), gomock.Any()).Return(repo.ErrCacheMiss)
+	operationRepo.EXPECT().GetByID(gomock.Any(), id).Return(stored, nil)
+	cache.EXPECT().Set(gomock.Any(), "operation:"+id.String(), stored, time.Hour).Return(nil)
+
+	if _, err := service.GetOperation(context.Background(), id); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
+
+func TestOperationService_GetOperationConcurrentMissesReadOnce(t *testing.T) {
+	service, operationRepo, cache := newTestService(t)
+	id := uuid.New()
+	const readers = 20
+
+	var misses sync.WaitGroup
+	misses.Add(readers)
+	cache.EXPECT().Get(gomock.Any(), "operation:"+id.String(), gomock.Any()).
+		DoAndReturn(func(context.Context, string, interface{}) error {
+			misses.Done()
+			return repo.ErrCacheMiss
+		}).Times(readers)
+
+	// Hold the database read until every reader has missed the cache
+	release := make(chan struct{})
+	operationRepo.EXPECT().GetByID(gomock.Any(), id).
+		DoAndReturn(func(context.Context, uuid.UUID) (*repo.Operation, error) {
+			<-release
+			return &repo.Operation{ID: id, Status: repo.OperationStatusSuccess}, nil
+		}).Times(1)
+	cache.EXPECT().Set(gomock.Any(), "operation:"+id.String(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	var done sync.WaitGroup
+	errs := make(chan error, readers)
+	for i := 0; i < readers; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			operation, err := service.GetOperation(context.Background(), id)
+			if err == nil && operation.ID != id {
+				err = errors.New("unexpected operation returned")
+			}
+			errs <- err
+		}()
+	}
+
+	misses.Wait()
+	// Give the readers a moment to join the in-flight read
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+}
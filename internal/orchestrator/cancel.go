@@ -0,0 +1,42 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AgentCanceller tells the agent of a cluster to stop working on an operation
+type AgentCanceller interface {
+	SignalCancellation(clusterID, operationID string) error
+}
+
+// SetAgentCanceller sets where cancellations are pushed to the agents, which
+// may already be running the cancelled operations
+func (o *Orchestrator) SetAgentCanceller(agents AgentCanceller) {
+	o.agents = agents
+}
+
+// signalAgent tells the agent of the operation's cluster to stop it, if an
+// agent canceller is set. Agents that don't run the operation ignore the
+// signal, so failures are only logged.
+func (o *Orchestrator) signalAgent(ctx context.Context, operationID uuid.UUID) {
+	if o.agents == nil {
+		return
+	}
+
+	operation, err := o.operations.GetByID(ctx, operationID)
+	if err != nil {
+		o.logger.Error("Failed to get cancelled operation", zap.Error(err), zap.String("operation_id", operationID.String()))
+		return
+	}
+
+	if err := o.agents.SignalCancellation(operation.ClusterID.String(), operationID.String()); err != nil {
+		o.logger.Warn("Failed to send operation cancellation to agent",
+			zap.Error(err),
+			zap.String("operation_id", operationID.String()),
+			zap.String("cluster_id", operation.ClusterID.String()),
+		)
+	}
+}
@@ -0,0 +1,36 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// SetEventBus sets the bus operations the orchestrator fails are published
+// on, e.g. for notifications and the dead-letter queue
+func (o *Orchestrator) SetEventBus(bus repo.EventBus) {
+	o.events = bus
+}
+
+// publishFailure publishes the failure of an operation on the event bus, if
+// one is set. The orchestrator fails operations for good, e.g. of an unknown
+// type or past their execution window, so they are never retryable. Failures
+// to publish are only logged since events are informational.
+func (o *Orchestrator) publishFailure(ctx context.Context, operation *repo.Operation, message string) {
+	if o.events == nil {
+		return
+	}
+	event := repo.OperationFailedEvent{
+		OperationID: operation.ID,
+		ClusterID:   operation.ClusterID,
+		Type:        operation.Type,
+		Message:     message,
+		FailedAt:    time.Now(),
+	}
+	if err := o.events.Publish(ctx, repo.TopicOperationFailed, event); err != nil {
+		o.logger.Warn("Failed to publish event", zap.String("topic", repo.TopicOperationFailed), zap.Error(err))
+	}
+}
@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,16 +12,45 @@ import (
 	"github.com/rizesky/mckmt/internal/repo"
 )
 
+// queueCapacity is the number of operations each queue holds
+const queueCapacity = 1000
+
 // Orchestrator manages long-running operations for agent-based clusters
 type Orchestrator struct {
 	operations repo.OperationRepository
 	metrics    MetricsProvider
 	logger     *zap.Logger
 	workers    int
-	queue      chan *repo.Operation
+	queue      *operationQueue
+	partitions map[string]*partition
 	stopCh     chan struct{}
 	cancelCh   chan uuid.UUID
+
+	// runningOps is shared by all workers and the cancellation handler
+	mu         sync.Mutex
 	runningOps map[uuid.UUID]context.CancelFunc
+
+	recoveryBatchSize  int
+	recoveryBatchDelay time.Duration
+
+	// resumed is non-nil while the orchestrator is paused, and closed on resume
+	pauseMu sync.Mutex
+	resumed chan struct{}
+
+	// healthCache holds the cluster health preconditions are checked against
+	healthCache repo.Cache
+
+	// maintenanceWindows restrict when operations of their types are executed,
+	// heldOps counts operations waiting for a window under mu
+	maintenanceWindows []MaintenanceWindow
+	heldOps            int
+
+	// events receives the operations the orchestrator fails; nil disables publishing
+	events repo.EventBus
+
+	// agents receives the cancellations of operations agents may be running;
+	// nil only cancels them on the hub
+	agents AgentCanceller
 }
 
 // NewOrchestrator creates a new orchestrator for agent-based operations
@@ -35,10 +65,14 @@ func NewOrchestrator(operations repo.OperationRepository, metrics MetricsProvide
 		metrics:    metrics,
 		logger:     logger,
 		workers:    workers,
-		queue:      make(chan *repo.Operation, 1000),
+		queue:      newOperationQueue(queueCapacity),
+		partitions: make(map[string]*partition),
 		stopCh:     make(chan struct{}),
 		cancelCh:   make(chan uuid.UUID, 100),
 		runningOps: make(map[uuid.UUID]context.CancelFunc),
+
+		recoveryBatchSize:  DefaultRecoveryBatchSize,
+		recoveryBatchDelay: DefaultRecoveryBatchDelay,
 	}
 }
 
@@ -48,7 +82,17 @@ func (o *Orchestrator) Start(ctx context.Context) error {
 
 	// Start worker goroutines
 	for i := 0; i < o.workers; i++ {
-		go o.worker(ctx, i)
+		go o.worker(ctx, i, o.queue)
+	}
+
+	// Partitioned operation types are served by their own workers only
+	workerID := o.workers
+	for opType, p := range o.partitions {
+		o.logger.Info("Starting partitioned workers", zap.String("type", opType), zap.Int("workers", p.workers))
+		for i := 0; i < p.workers; i++ {
+			go o.worker(ctx, workerID, p.queue)
+			workerID++
+		}
 	}
 
 	// Start operation processor
@@ -62,22 +106,66 @@ func (o *Orchestrator) Start(ctx context.Context) error {
 func (o *Orchestrator) Stop() {
 	o.logger.Info("Stopping orchestrator")
 	close(o.stopCh)
-	close(o.queue)
+	for _, queue := range o.queues() {
+		queue.close()
+	}
+}
+
+// SetOrderingPolicy sets the queue ordering policy used for all clusters without an override
+func (o *Orchestrator) SetOrderingPolicy(policy OrderingPolicy) {
+	for _, queue := range o.queues() {
+		queue.setDefaultPolicy(policy)
+	}
+	o.logger.Info("Queue ordering policy changed", zap.String("policy", string(policy)))
+}
+
+// SetClusterOrderingPolicy overrides the queue ordering policy for a single cluster
+func (o *Orchestrator) SetClusterOrderingPolicy(clusterID uuid.UUID, policy OrderingPolicy) {
+	for _, queue := range o.queues() {
+		queue.setClusterPolicy(clusterID, policy)
+	}
+	o.logger.Info("Cluster queue ordering policy changed",
+		zap.String("cluster_id", clusterID.String()),
+		zap.String("policy", string(policy)),
+	)
+}
+
+// ConfigureOrdering applies ordering policies by name, as read from configuration.
+// clusterPolicies maps cluster IDs to policy names that override the default.
+func (o *Orchestrator) ConfigureOrdering(defaultPolicy string, clusterPolicies map[string]string) error {
+	policy, err := ParseOrderingPolicy(defaultPolicy)
+	if err != nil {
+		return err
+	}
+	o.SetOrderingPolicy(policy)
+
+	for id, name := range clusterPolicies {
+		clusterID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid cluster ID in queue ordering config: %s", id)
+		}
+		clusterPolicy, err := ParseOrderingPolicy(name)
+		if err != nil {
+			return err
+		}
+		o.SetClusterOrderingPolicy(clusterID, clusterPolicy)
+	}
+
+	return nil
 }
 
 // QueueOperation queues an operation for processing
 func (o *Orchestrator) QueueOperation(operation *repo.Operation) error {
-	select {
-	case o.queue <- operation:
-		o.logger.Info("Operation queued",
-			zap.String("operation_id", operation.ID.String()),
-			zap.String("type", operation.Type),
-			zap.String("cluster_id", operation.ClusterID.String()),
-		)
-		return nil
-	default:
+	if !o.queueFor(operation.Type).push(operation) {
 		return fmt.Errorf("operation queue is full")
 	}
+
+	o.logger.Info("Operation queued",
+		zap.String("operation_id", operation.ID.String()),
+		zap.String("type", operation.Type),
+		zap.String("cluster_id", operation.ClusterID.String()),
+	)
+	return nil
 }
 
 // CancelOperation cancels a running operation
@@ -103,17 +191,22 @@ func (o *Orchestrator) processOperations(ctx context.Context) {
 			return
 		case operationID := <-o.cancelCh:
 			o.handleCancellation(operationID)
-		case operation := <-o.queue:
-			if operation == nil {
+		case _, ok := <-o.queue.ready:
+			if !ok {
+				return
+			}
+			if !o.waitWhilePaused(ctx) {
 				return
 			}
-			o.processOperation(ctx, operation)
+			if operation := o.queue.pop(); operation != nil {
+				o.processOperation(ctx, operation)
+			}
 		}
 	}
 }
 
-// worker processes operations from the queue
-func (o *Orchestrator) worker(ctx context.Context, workerID int) {
+// worker processes operations from a queue
+func (o *Orchestrator) worker(ctx context.Context, workerID int, queue *operationQueue) {
 	o.logger.Info("Worker started", zap.Int("worker_id", workerID))
 
 	for {
@@ -122,11 +215,16 @@ func (o *Orchestrator) worker(ctx context.Context, workerID int) {
 			return
 		case <-o.stopCh:
 			return
-		case operation := <-o.queue:
-			if operation == nil {
+		case _, ok := <-queue.ready:
+			if !ok {
+				return
+			}
+			if !o.waitWhilePaused(ctx) {
 				return
 			}
-			o.processOperation(ctx, operation)
+			if operation := queue.pop(); operation != nil {
+				o.processOperation(ctx, operation)
+			}
 		}
 	}
 }
@@ -150,14 +248,28 @@ func (o *Orchestrator) processOperation(ctx context.Context, operation *repo.Ope
 		return
 	}
 
+	// Operations outside their execution window are held or expired
+	switch until, err := o.heldUntil(operation, startTime); {
+	case err != nil:
+		o.expire(ctx, operation, err)
+		return
+	case until.After(startTime):
+		o.hold(ctx, operation, until)
+		return
+	}
+
 	// Create cancellable context for this operation
 	opCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// Store the cancel function for potential cancellation
+	o.mu.Lock()
 	o.runningOps[operation.ID] = cancel
+	o.mu.Unlock()
 	defer func() {
+		o.mu.Lock()
 		delete(o.runningOps, operation.ID)
+		o.mu.Unlock()
 	}()
 
 	// Check if operation was cancelled before processing
@@ -177,23 +289,26 @@ func (o *Orchestrator) processOperation(ctx context.Context, operation *repo.Ope
 	// Increment metrics
 	o.metrics.IncOperationsInProgress(operation.ClusterID.String(), operation.Type)
 
-	// Process based on operation type
+	// Operations whose preconditions aren't met are skipped, not failed
 	var result repo.Payload
 	var success bool
 	var message string
 
-	switch operation.Type {
-	case string(repo.OperationTypeApply):
-		result, success, message = o.processApplyOperation(opCtx, operation)
-	case string(repo.OperationTypeExec):
-		result, success, message = o.processExecOperation(opCtx, operation)
-	case string(repo.OperationTypeSync):
-		result, success, message = o.processSyncOperation(opCtx, operation)
-	case string(repo.OperationTypeDelete):
-		result, success, message = o.processDeleteOperation(opCtx, operation)
+	switch unmet, err := o.unmetPreconditions(opCtx, operation); {
+	case err != nil:
+		message = err.Error()
+		result = repo.Payload{
+			"status":  "failed",
+			"message": message,
+		}
+	case len(unmet) > 0:
+		result, success, message = skippedResult(unmet)
+		o.logger.Info("Skipping operation",
+			zap.String("operation_id", operation.ID.String()),
+			zap.String("reason", message),
+		)
 	default:
-		success = false
-		message = fmt.Sprintf("unknown operation type: %s", operation.Type)
+		result, success, message = o.dispatch(opCtx, operation)
 	}
 
 	// Check if operation was cancelled during processing
@@ -235,6 +350,10 @@ func (o *Orchestrator) processOperation(ctx context.Context, operation *repo.Ope
 	o.metrics.DecOperationsInProgress(operation.ClusterID.String(), operation.Type)
 	o.metrics.RecordOperation(operation.ClusterID.String(), operation.Type, status, duration)
 
+	if status == string(repo.OperationStatusFailed) {
+		o.publishFailure(ctx, operation, message)
+	}
+
 	o.logger.Info("Operation completed",
 		zap.String("operation_id", operation.ID.String()),
 		zap.String("status", status),
@@ -244,6 +363,24 @@ func (o *Orchestrator) processOperation(ctx context.Context, operation *repo.Ope
 	)
 }
 
+// dispatch processes an operation based on its type
+func (o *Orchestrator) dispatch(ctx context.Context, operation *repo.Operation) (repo.Payload, bool, string) {
+	switch operation.Type {
+	case string(repo.OperationTypeApply):
+		return o.processApplyOperation(ctx, operation)
+	case string(repo.OperationTypeExec):
+		return o.processExecOperation(ctx, operation)
+	case string(repo.OperationTypeSync):
+		return o.processSyncOperation(ctx, operation)
+	case string(repo.OperationTypeDelete):
+		return o.processDeleteOperation(ctx, operation)
+	case string(repo.OperationTypeListNamespaces), string(repo.OperationTypeListNodes), repo.OperationTypeListResources, repo.OperationTypePodLogs:
+		return o.processQueryOperation(ctx, operation)
+	default:
+		return nil, false, fmt.Sprintf("unknown operation type: %s", operation.Type)
+	}
+}
+
 // processApplyOperation queues an apply operation for agent processing
 func (o *Orchestrator) processApplyOperation(_ context.Context, operation *repo.Operation) (repo.Payload, bool, string) {
 	// For agent mode, we just queue the operation and let the agent handle it
@@ -301,6 +438,21 @@ func (o *Orchestrator) processDeleteOperation(_ context.Context, operation *repo
 	}, true, "Operation queued for agent processing"
 }
 
+// processQueryOperation queues a read-only query operation for agent processing
+func (o *Orchestrator) processQueryOperation(_ context.Context, operation *repo.Operation) (repo.Payload, bool, string) {
+	// Queries are answered by the agent, the result is reported back via gRPC
+	o.logger.Info("Queued query operation for agent processing",
+		zap.String("operation_id", operation.ID.String()),
+		zap.String("type", operation.Type),
+		zap.String("cluster_id", operation.ClusterID.String()),
+	)
+
+	return repo.Payload{
+		"status":  "queued",
+		"message": "Operation queued for agent processing",
+	}, true, "Operation queued for agent processing"
+}
+
 // handleCancellation handles operation cancellation requests
 func (o *Orchestrator) handleCancellation(operationID uuid.UUID) {
 	o.logger.Info("Handling operation cancellation",
@@ -308,7 +460,11 @@ func (o *Orchestrator) handleCancellation(operationID uuid.UUID) {
 	)
 
 	// Check if operation is currently running
-	if cancel, exists := o.runningOps[operationID]; exists {
+	o.mu.Lock()
+	cancel, exists := o.runningOps[operationID]
+	o.mu.Unlock()
+
+	if exists {
 		// Cancel the running operation
 		cancel()
 		o.logger.Info("Operation cancelled",
@@ -324,4 +480,7 @@ func (o *Orchestrator) handleCancellation(operationID uuid.UUID) {
 			)
 		}
 	}
+
+	// The agent may already have received the operation
+	o.signalAgent(context.Background(), operationID)
 }
@@ -299,3 +299,103 @@ func TestOrchestrator_ContextCancellation(t *testing.T) {
 		t.Fatal("orchestrator should have stopped due to context cancellation")
 	}
 }
+
+func TestOrchestrator_QueueOrdering(t *testing.T) {
+	clusterA := uuid.New()
+	clusterB := uuid.New()
+
+	newOp := func(clusterID uuid.UUID) *repo.Operation {
+		return &repo.Operation{ID: uuid.New(), ClusterID: clusterID, Type: "apply", Status: "queued"}
+	}
+
+	a1, a2, b1, a3 := newOp(clusterA), newOp(clusterA), newOp(clusterB), newOp(clusterA)
+	queued := []*repo.Operation{a1, a2, b1, a3}
+
+	tests := []struct {
+		name     string
+		policy   string
+		clusters map[string]string
+		expected []*repo.Operation
+	}{
+		{
+			name:     "FIFO processes operations in queue order",
+			policy:   "fifo",
+			expected: []*repo.Operation{a1, a2, b1, a3},
+		},
+		{
+			name:     "LIFO processes the most recently queued operation of a cluster first",
+			policy:   "lifo",
+			expected: []*repo.Operation{a3, a2, a1, b1},
+		},
+		{
+			name:     "per-cluster LIFO override",
+			policy:   "fifo",
+			clusters: map[string]string{clusterA.String(): "lifo"},
+			expected: []*repo.Operation{a3, a2, a1, b1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			orchestrator := NewOrchestrator(repomocks.NewMockOperationRepository(ctrl), mocks.NewMockMetricsProvider(ctrl), zap.NewNop(), 1)
+			if err := orchestrator.ConfigureOrdering(tt.policy, tt.clusters); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			for _, op := range queued {
+				if err := orchestrator.QueueOperation(op); err != nil {
+					t.Fatalf("Failed to queue operation: %v", err)
+				}
+			}
+
+			for i, expected := range tt.expected {
+				<-orchestrator.queue.ready
+				if got := orchestrator.queue.pop(); got.ID != expected.ID {
+					t.Errorf("Position %d: expected operation %s, got %s", i, expected.ID, got.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestOrchestrator_ConfigureOrderingRejectsUnknownPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orchestrator := NewOrchestrator(repomocks.NewMockOperationRepository(ctrl), mocks.NewMockMetricsProvider(ctrl), zap.NewNop(), 1)
+
+	if err := orchestrator.ConfigureOrdering("random", nil); err == nil {
+		t.Errorf("Expected error for unknown policy")
+	}
+	if err := orchestrator.ConfigureOrdering("fifo", map[string]string{"not-a-uuid": "lifo"}); err == nil {
+		t.Errorf("Expected error for invalid cluster ID")
+	}
+}
+
+func TestOrchestrator_State(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orchestrator := NewOrchestrator(repomocks.NewMockOperationRepository(ctrl), mocks.NewMockMetricsProvider(ctrl), zap.NewNop(), 3)
+	for i := 0; i < 2; i++ {
+		if err := orchestrator.QueueOperation(&repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: "apply"}); err != nil {
+			t.Fatalf("Failed to queue operation: %v", err)
+		}
+	}
+	running := uuid.New()
+	orchestrator.runningOps[running] = func() {}
+	if err := orchestrator.CancelOperation(uuid.New()); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	state := orchestrator.State()
+	if state.QueueDepth != 2 || state.QueueCapacity != 1000 || state.Workers != 3 || state.CancelQueueDepth != 1 {
+		t.Errorf("Unexpected orchestrator state: %+v", state)
+	}
+	if len(state.RunningOperations) != 1 || state.RunningOperations[0] != running {
+		t.Errorf("Expected running operation %s but got %v", running, state.RunningOperations)
+	}
+}
@@ -0,0 +1,56 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// partition is a queue with dedicated workers for a single operation type, so
+// a flood of slow operations of one type can't starve the others
+type partition struct {
+	queue   *operationQueue
+	workers int
+}
+
+// SetTypeWorkers partitions the worker pool by operation type. Each listed type
+// gets its own queue served by the given number of dedicated workers; types
+// that aren't listed keep sharing the orchestrator's worker pool. It must be
+// called before Start.
+func (o *Orchestrator) SetTypeWorkers(typeWorkers map[string]int) error {
+	for opType, workers := range typeWorkers {
+		if workers <= 0 {
+			return fmt.Errorf("invalid worker count %d for operation type %s", workers, opType)
+		}
+	}
+
+	for opType, workers := range typeWorkers {
+		queue := newOperationQueue(queueCapacity)
+		queue.copyPolicies(o.queue)
+		o.partitions[opType] = &partition{queue: queue, workers: workers}
+
+		o.logger.Info("Operation type partitioned",
+			zap.String("type", opType),
+			zap.Int("workers", workers),
+		)
+	}
+
+	return nil
+}
+
+// queueFor returns the queue operations of the given type are processed from
+func (o *Orchestrator) queueFor(opType string) *operationQueue {
+	if p, ok := o.partitions[opType]; ok {
+		return p.queue
+	}
+	return o.queue
+}
+
+// queues returns the shared queue followed by the partition queues
+func (o *Orchestrator) queues() []*operationQueue {
+	queues := []*operationQueue{o.queue}
+	for _, p := range o.partitions {
+		queues = append(queues, p.queue)
+	}
+	return queues
+}
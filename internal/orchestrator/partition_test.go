@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/orchestrator/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestOrchestrator_PartitionedSyncDoesNotBlockApply(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockMetrics := mocks.NewMockMetricsProvider(ctrl)
+	mockMetrics.EXPECT().IncOperationsInProgress(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().DecOperationsInProgress(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().RecordOperation(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	// Slow sync operations hold their worker until released, then turn out to
+	// have been cancelled so they aren't processed any further
+	release := make(chan struct{})
+	syncOps := []*repo.Operation{
+		{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeSync},
+		{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeSync},
+		{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeSync},
+	}
+	for _, op := range syncOps {
+		mockOpRepo.EXPECT().GetByID(gomock.Any(), op.ID).DoAndReturn(func(context.Context, uuid.UUID) (*repo.Operation, error) {
+			<-release
+			return &repo.Operation{ID: op.ID, Status: repo.OperationStatusCancelled}, nil
+		}).MaxTimes(1)
+	}
+
+	applied := make(chan struct{})
+	applyOp := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeApply}
+	mockOpRepo.EXPECT().GetByID(gomock.Any(), applyOp.ID).Return(applyOp, nil)
+	mockOpRepo.EXPECT().SetStarted(gomock.Any(), applyOp.ID).Return(nil)
+	mockOpRepo.EXPECT().UpdateStatus(gomock.Any(), applyOp.ID, repo.OperationStatusSuccess).Return(nil)
+	mockOpRepo.EXPECT().UpdateResult(gomock.Any(), applyOp.ID, gomock.Any()).Return(nil)
+	mockOpRepo.EXPECT().SetFinished(gomock.Any(), applyOp.ID).DoAndReturn(func(context.Context, uuid.UUID) error {
+		close(applied)
+		return nil
+	})
+
+	orchestrator := NewOrchestrator(mockOpRepo, mockMetrics, zap.NewNop(), 1)
+	if err := orchestrator.SetTypeWorkers(map[string]int{repo.OperationTypeSync: 1, repo.OperationTypeApply: 1}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := orchestrator.Start(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	defer close(release)
+
+	for _, op := range append(syncOps, applyOp) {
+		if err := orchestrator.QueueOperation(op); err != nil {
+			t.Fatalf("Failed to queue operation: %v", err)
+		}
+	}
+
+	select {
+	case <-applied:
+	case <-time.After(2 * time.Second):
+		t.Fatal("apply operation was blocked behind sync operations")
+	}
+
+	if depth := orchestrator.partitions[repo.OperationTypeSync].queue.len(); depth == 0 {
+		t.Errorf("Expected sync operations to still be queued behind the slow one")
+	}
+}
+
+func TestOrchestrator_SetTypeWorkersRejectsInvalidCount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	orchestrator := NewOrchestrator(repomocks.NewMockOperationRepository(ctrl), mocks.NewMockMetricsProvider(ctrl), zap.NewNop(), 1)
+
+	if err := orchestrator.SetTypeWorkers(map[string]int{repo.OperationTypeSync: 0}); err == nil {
+		t.Error("Expected an error for a partition without workers")
+	}
+	if len(orchestrator.partitions) != 0 {
+		t.Errorf("Expected no partitions after a rejected configuration but got %d", len(orchestrator.partitions))
+	}
+}
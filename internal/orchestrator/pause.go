@@ -0,0 +1,62 @@
+package orchestrator
+
+import "context"
+
+// Pause stops workers from taking new operations off the queue, e.g. for
+// maintenance. Queued operations are kept and operations already being
+// processed run to completion. Cancellations are still handled while paused.
+func (o *Orchestrator) Pause() {
+	o.pauseMu.Lock()
+	defer o.pauseMu.Unlock()
+
+	if o.resumed != nil {
+		return
+	}
+	o.resumed = make(chan struct{})
+	o.logger.Info("Orchestrator paused")
+}
+
+// Resume lets workers take operations off the queue again after Pause
+func (o *Orchestrator) Resume() {
+	o.pauseMu.Lock()
+	defer o.pauseMu.Unlock()
+
+	if o.resumed == nil {
+		return
+	}
+	close(o.resumed)
+	o.resumed = nil
+	o.logger.Info("Orchestrator resumed")
+}
+
+// Paused reports whether the orchestrator is paused
+func (o *Orchestrator) Paused() bool {
+	o.pauseMu.Lock()
+	defer o.pauseMu.Unlock()
+	return o.resumed != nil
+}
+
+// waitWhilePaused blocks a worker that is about to take an operation until the
+// orchestrator is resumed. The operation stays queued meanwhile. It returns
+// false when the orchestrator stops first.
+func (o *Orchestrator) waitWhilePaused(ctx context.Context) bool {
+	for {
+		o.pauseMu.Lock()
+		resumed := o.resumed
+		o.pauseMu.Unlock()
+
+		if resumed == nil {
+			return true
+		}
+
+		select {
+		case <-resumed:
+		case operationID := <-o.cancelCh:
+			o.handleCancellation(operationID)
+		case <-ctx.Done():
+			return false
+		case <-o.stopCh:
+			return false
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/orchestrator/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestOrchestrator_PauseAndResume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockMetrics := mocks.NewMockMetricsProvider(ctrl)
+	mockMetrics.EXPECT().IncOperationsInProgress(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().DecOperationsInProgress(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().RecordOperation(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	orchestrator := NewOrchestrator(mockOpRepo, mockMetrics, zap.NewNop(), 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := orchestrator.Start(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	orchestrator.Pause()
+	if !orchestrator.Paused() {
+		t.Fatal("Expected the orchestrator to be paused")
+	}
+
+	// Any repository call while paused fails the test, as none is expected yet
+	op := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeApply, Status: "queued"}
+	if err := orchestrator.QueueOperation(op); err != nil {
+		t.Fatalf("Failed to queue operation: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if state := orchestrator.State(); state.QueueDepth != 1 || !state.Paused {
+		t.Fatalf("Expected the operation to stay queued while paused: %+v", state)
+	}
+
+	processed := make(chan struct{})
+	mockOpRepo.EXPECT().GetByID(gomock.Any(), op.ID).Return(op, nil)
+	mockOpRepo.EXPECT().SetStarted(gomock.Any(), op.ID).Return(nil)
+	mockOpRepo.EXPECT().UpdateStatus(gomock.Any(), op.ID, repo.OperationStatusSuccess).Return(nil)
+	mockOpRepo.EXPECT().UpdateResult(gomock.Any(), op.ID, gomock.Any()).Return(nil)
+	mockOpRepo.EXPECT().SetFinished(gomock.Any(), op.ID).DoAndReturn(func(context.Context, uuid.UUID) error {
+		close(processed)
+		return nil
+	})
+
+	orchestrator.Resume()
+
+	select {
+	case <-processed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the queued operation to be processed after resuming")
+	}
+	if orchestrator.Paused() {
+		t.Error("Expected the orchestrator to be resumed")
+	}
+}
+
+func TestOrchestrator_CancellationWhilePaused(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	orchestrator := NewOrchestrator(mockOpRepo, mocks.NewMockMetricsProvider(ctrl), zap.NewNop(), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := orchestrator.Start(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	orchestrator.Pause()
+
+	// Park the workers on a queued operation, then cancel it
+	op := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeApply, Status: "queued"}
+	if err := orchestrator.QueueOperation(op); err != nil {
+		t.Fatalf("Failed to queue operation: %v", err)
+	}
+
+	cancelled := make(chan struct{})
+	mockOpRepo.EXPECT().UpdateStatus(gomock.Any(), op.ID, repo.OperationStatusCancelled).DoAndReturn(func(context.Context, uuid.UUID, string) error {
+		close(cancelled)
+		return nil
+	})
+	if err := orchestrator.CancelOperation(op.ID); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the cancellation to be handled while paused")
+	}
+}
@@ -0,0 +1,82 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// SetHealthCache sets the cache holding the cluster health agents report in
+// heartbeats, which operation preconditions are checked against. Without it
+// operations with preconditions are always skipped.
+func (o *Orchestrator) SetHealthCache(cache repo.Cache) {
+	o.healthCache = cache
+}
+
+// unmetPreconditions returns the preconditions of an operation that the
+// cluster's latest reported health doesn't meet. An error means the
+// preconditions themselves are invalid.
+func (o *Orchestrator) unmetPreconditions(ctx context.Context, operation *repo.Operation) ([]string, error) {
+	preconditions, err := repo.ParsePreconditions(operation.Payload)
+	if err != nil || preconditions == nil {
+		return nil, err
+	}
+	if !preconditions.ClusterHealthy && preconditions.MinReadyNodes == 0 {
+		return nil, nil
+	}
+
+	health, err := o.clusterHealth(ctx, operation)
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+
+	var unmet []string
+	if preconditions.ClusterHealthy && health.Status != repo.ClusterHealthHealthy {
+		unmet = append(unmet, fmt.Sprintf("cluster is %s, expected %s", health.Status, repo.ClusterHealthHealthy))
+	}
+	if health.ReadyNodes < preconditions.MinReadyNodes {
+		unmet = append(unmet, fmt.Sprintf("%d ready nodes, expected at least %d", health.ReadyNodes, preconditions.MinReadyNodes))
+	}
+	return unmet, nil
+}
+
+// clusterHealth returns the latest health reported for the operation's cluster
+func (o *Orchestrator) clusterHealth(ctx context.Context, operation *repo.Operation) (*repo.ClusterHealth, error) {
+	if o.healthCache == nil {
+		return nil, errors.New("cluster health is not tracked")
+	}
+
+	var health repo.ClusterHealth
+	err := o.healthCache.Get(ctx, o.healthCache.ClusterStatusKey(operation.ClusterID.String()), &health)
+	if errors.Is(err, repo.ErrCacheMiss) {
+		return nil, errors.New("no recent cluster health reported")
+	}
+	if err != nil {
+		o.logger.Warn("Failed to get cluster health",
+			zap.String("cluster_id", operation.ClusterID.String()),
+			zap.Error(err))
+		return nil, errors.New("cluster health is unavailable")
+	}
+	return &health, nil
+}
+
+// skippedResult is the result of an operation skipped for unmet preconditions
+func skippedResult(unmet []string) (repo.Payload, bool, string) {
+	message := "precondition not met: " + strings.Join(unmet, "; ")
+	reasons := make([]interface{}, len(unmet))
+	for i, reason := range unmet {
+		reasons[i] = reason
+	}
+
+	return repo.Payload{
+		"status":              "skipped",
+		"skipped":             true,
+		"message":             message,
+		"unmet_preconditions": reasons,
+	}, true, message
+}
@@ -0,0 +1,145 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/orchestrator/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+// healthCache returns a cache reporting health for every cluster, or a cache
+// miss when health is nil
+func healthCache(ctrl *gomock.Controller, health *repo.ClusterHealth) *repomocks.MockCache {
+	cache := repomocks.NewMockCache(ctrl)
+	cache.EXPECT().ClusterStatusKey(gomock.Any()).DoAndReturn(func(id string) string { return "cluster_status:" + id }).AnyTimes()
+	cache.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, dest interface{}) error {
+		if health == nil {
+			return repo.ErrCacheMiss
+		}
+		*dest.(*repo.ClusterHealth) = *health
+		return nil
+	}).AnyTimes()
+	return cache
+}
+
+func TestOrchestrator_SkipsOperationWithUnmetPreconditions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockMetrics := mocks.NewMockMetricsProvider(ctrl)
+
+	operation := &repo.Operation{
+		ID:        uuid.New(),
+		ClusterID: uuid.New(),
+		Type:      repo.OperationTypeApply,
+		Payload: repo.Payload{
+			repo.PayloadManifests:     "apiVersion: v1\nkind: ConfigMap\n",
+			repo.PayloadPreconditions: map[string]interface{}{"cluster_healthy": true, "min_ready_nodes": float64(3)},
+		},
+	}
+
+	// The operation is skipped, which finishes it successfully with the reason in its result
+	var result repo.Payload
+	mockOpRepo.EXPECT().GetByID(gomock.Any(), operation.ID).Return(operation, nil)
+	mockOpRepo.EXPECT().SetStarted(gomock.Any(), operation.ID).Return(nil)
+	mockOpRepo.EXPECT().UpdateStatus(gomock.Any(), operation.ID, repo.OperationStatusSuccess).Return(nil)
+	mockOpRepo.EXPECT().UpdateResult(gomock.Any(), operation.ID, gomock.Any()).DoAndReturn(func(_ context.Context, _ uuid.UUID, r repo.Payload) error {
+		result = r
+		return nil
+	})
+	mockOpRepo.EXPECT().SetFinished(gomock.Any(), operation.ID).Return(nil)
+	mockMetrics.EXPECT().IncOperationsInProgress(gomock.Any(), gomock.Any())
+	mockMetrics.EXPECT().DecOperationsInProgress(gomock.Any(), gomock.Any())
+	mockMetrics.EXPECT().RecordOperation(gomock.Any(), gomock.Any(), repo.OperationStatusSuccess, gomock.Any())
+
+	orchestrator := NewOrchestrator(mockOpRepo, mockMetrics, zap.NewNop(), 1)
+	orchestrator.SetHealthCache(healthCache(ctrl, &repo.ClusterHealth{Status: "unhealthy", ReadyNodes: 1, TotalNodes: 3}))
+
+	orchestrator.processOperation(context.Background(), operation)
+
+	if result["skipped"] != true || result["status"] != "skipped" {
+		t.Fatalf("Expected the operation to be skipped but got %v", result)
+	}
+	message, _ := result["message"].(string)
+	if !strings.HasPrefix(message, "precondition not met") {
+		t.Errorf("Expected a precondition not met message but got %q", message)
+	}
+	if unmet, _ := result["unmet_preconditions"].([]interface{}); len(unmet) != 2 {
+		t.Errorf("Expected both preconditions to be unmet but got %v", result["unmet_preconditions"])
+	}
+}
+
+func TestOrchestrator_UnmetPreconditions(t *testing.T) {
+	tests := []struct {
+		name          string
+		preconditions interface{}
+		health        *repo.ClusterHealth
+		expectedUnmet int
+		expectedError bool
+	}{
+		{
+			name:          "no preconditions",
+			health:        &repo.ClusterHealth{Status: "unhealthy"},
+			expectedUnmet: 0,
+		},
+		{
+			name:          "healthy cluster with enough nodes",
+			preconditions: map[string]interface{}{"cluster_healthy": true, "min_ready_nodes": float64(2)},
+			health:        &repo.ClusterHealth{Status: repo.ClusterHealthHealthy, ReadyNodes: 3},
+			expectedUnmet: 0,
+		},
+		{
+			name:          "degraded cluster",
+			preconditions: map[string]interface{}{"cluster_healthy": true},
+			health:        &repo.ClusterHealth{Status: "degraded", ReadyNodes: 3},
+			expectedUnmet: 1,
+		},
+		{
+			name:          "no recent health reported",
+			preconditions: map[string]interface{}{"min_ready_nodes": float64(1)},
+			expectedUnmet: 1,
+		},
+		{
+			name:          "invalid preconditions",
+			preconditions: map[string]interface{}{"cluster_ready": true},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			orchestrator := NewOrchestrator(repomocks.NewMockOperationRepository(ctrl), mocks.NewMockMetricsProvider(ctrl), zap.NewNop(), 1)
+			orchestrator.SetHealthCache(healthCache(ctrl, tt.health))
+
+			operation := &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: repo.OperationTypeApply, Payload: repo.Payload{}}
+			if tt.preconditions != nil {
+				operation.Payload[repo.PayloadPreconditions] = tt.preconditions
+			}
+
+			unmet, err := orchestrator.unmetPreconditions(context.Background(), operation)
+			if tt.expectedError {
+				if err == nil {
+					t.Errorf("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(unmet) != tt.expectedUnmet {
+				t.Errorf("Expected %d unmet preconditions but got %v", tt.expectedUnmet, unmet)
+			}
+		})
+	}
+}
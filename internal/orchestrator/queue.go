@@ -0,0 +1,154 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// OrderingPolicy decides which pending operation of a cluster is processed next
+type OrderingPolicy string
+
+const (
+	// OrderingFIFO processes a cluster's operations in the order they were queued
+	OrderingFIFO OrderingPolicy = "fifo"
+	// OrderingLIFO processes a cluster's most recently queued operation first, so
+	// the newest desired state wins over stale intermediate ones
+	OrderingLIFO OrderingPolicy = "lifo"
+)
+
+// ParseOrderingPolicy parses a policy name, defaulting to FIFO when empty
+func ParseOrderingPolicy(name string) (OrderingPolicy, error) {
+	switch OrderingPolicy(strings.ToLower(strings.TrimSpace(name))) {
+	case "", OrderingFIFO:
+		return OrderingFIFO, nil
+	case OrderingLIFO:
+		return OrderingLIFO, nil
+	default:
+		return "", fmt.Errorf("unknown queue ordering policy: %s", name)
+	}
+}
+
+// operationQueue is a bounded queue of pending operations. Clusters are served
+// in the order their operations arrived; within a cluster the ordering policy
+// decides whether the oldest or the newest pending operation goes first.
+//
+// Every queued operation puts one token on ready, so consumers can block on the
+// channel and then take an operation with pop.
+type operationQueue struct {
+	mu              sync.Mutex
+	pending         []*repo.Operation
+	ready           chan struct{}
+	defaultPolicy   OrderingPolicy
+	clusterPolicies map[uuid.UUID]OrderingPolicy
+}
+
+// newOperationQueue creates a FIFO queue holding at most capacity operations
+func newOperationQueue(capacity int) *operationQueue {
+	return &operationQueue{
+		ready:           make(chan struct{}, capacity),
+		defaultPolicy:   OrderingFIFO,
+		clusterPolicies: make(map[uuid.UUID]OrderingPolicy),
+	}
+}
+
+// push adds an operation, returning false if the queue is full
+func (q *operationQueue) push(operation *repo.Operation) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+		q.pending = append(q.pending, operation)
+		return true
+	default:
+		return false
+	}
+}
+
+// pop removes and returns the next operation to process, or nil if none is pending
+func (q *operationQueue) pop() *repo.Operation {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) == 0 {
+		return nil
+	}
+
+	// The oldest pending operation decides which cluster is served next
+	next := 0
+	clusterID := q.pending[0].ClusterID
+	if q.policyFor(clusterID) == OrderingLIFO {
+		for i := len(q.pending) - 1; i > 0; i-- {
+			if q.pending[i].ClusterID == clusterID {
+				next = i
+				break
+			}
+		}
+	}
+
+	operation := q.pending[next]
+	q.pending = append(q.pending[:next], q.pending[next+1:]...)
+	return operation
+}
+
+// close stops consumers waiting on ready
+func (q *operationQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	close(q.ready)
+}
+
+// setDefaultPolicy sets the policy for clusters without an override
+func (q *operationQueue) setDefaultPolicy(policy OrderingPolicy) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.defaultPolicy = policy
+}
+
+// setClusterPolicy overrides the policy for a single cluster
+func (q *operationQueue) setClusterPolicy(clusterID uuid.UUID, policy OrderingPolicy) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.clusterPolicies[clusterID] = policy
+}
+
+// policyFor returns the effective policy for a cluster; callers must hold mu
+func (q *operationQueue) policyFor(clusterID uuid.UUID) OrderingPolicy {
+	if policy, ok := q.clusterPolicies[clusterID]; ok {
+		return policy
+	}
+	return q.defaultPolicy
+}
+
+// len returns the number of pending operations
+func (q *operationQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// capacity returns the maximum number of pending operations
+func (q *operationQueue) capacity() int {
+	return cap(q.ready)
+}
+
+// copyPolicies applies the ordering policies of another queue
+func (q *operationQueue) copyPolicies(from *operationQueue) {
+	from.mu.Lock()
+	defaultPolicy := from.defaultPolicy
+	clusterPolicies := make(map[uuid.UUID]OrderingPolicy, len(from.clusterPolicies))
+	for id, policy := range from.clusterPolicies {
+		clusterPolicies[id] = policy
+	}
+	from.mu.Unlock()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.defaultPolicy = defaultPolicy
+	q.clusterPolicies = clusterPolicies
+}
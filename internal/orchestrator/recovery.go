@@ -0,0 +1,137 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// Recovery defaults
+const (
+	DefaultRecoveryBatchSize  = 100
+	DefaultRecoveryBatchDelay = time.Second
+
+	// recoveryRetryInterval is the shortest wait before retrying a batch that
+	// didn't fit in the queue
+	recoveryRetryInterval = 100 * time.Millisecond
+)
+
+// SetRecoveryBatching sets how many operations RecoverOperations queues at a
+// time and how long it waits between batches. Non-positive values keep the
+// defaults.
+func (o *Orchestrator) SetRecoveryBatching(batchSize int, delay time.Duration) {
+	if batchSize > 0 {
+		o.recoveryBatchSize = batchSize
+	}
+	if delay > 0 {
+		o.recoveryBatchDelay = delay
+	}
+	o.logger.Info("Recovery batching changed",
+		zap.Int("batch_size", o.recoveryBatchSize),
+		zap.Duration("batch_delay", o.recoveryBatchDelay),
+	)
+}
+
+// RecoverOperations re-queues the operations a previous run left queued. They
+// are queued in batches with a delay in between, so a restart with a large
+// backlog doesn't hand agents thousands of operations at once. When the queue
+// is full the batch waits for workers to make room. It returns the number of
+// operations re-queued, which is partial when ctx is cancelled or the
+// orchestrator stops.
+func (o *Orchestrator) RecoverOperations(ctx context.Context) (int, error) {
+	// Collect the backlog up front: re-queued operations leave the queued status
+	// once workers pick them up, which would shift the offsets of later pages
+	var pending []*repo.Operation
+	for offset := 0; ; offset += o.recoveryBatchSize {
+		page, err := o.operations.ListByStatus(ctx, "queued", o.recoveryBatchSize, offset)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list queued operations: %w", err)
+		}
+		pending = append(pending, page...)
+		if len(page) < o.recoveryBatchSize {
+			break
+		}
+	}
+	pending = o.failMaskedOperations(ctx, pending)
+
+	o.logger.Info("Recovering queued operations",
+		zap.Int("operations", len(pending)),
+		zap.Int("batch_size", o.recoveryBatchSize),
+	)
+
+	recovered := 0
+	for start := 0; start < len(pending); start += o.recoveryBatchSize {
+		if start > 0 {
+			if err := o.waitRecovery(ctx, o.recoveryBatchDelay); err != nil {
+				return recovered, err
+			}
+		}
+
+		end := min(start+o.recoveryBatchSize, len(pending))
+		for _, operation := range pending[start:end] {
+			for !o.queueFor(operation.Type).push(operation) {
+				if err := o.waitRecovery(ctx, max(o.recoveryBatchDelay, recoveryRetryInterval)); err != nil {
+					return recovered, err
+				}
+			}
+			recovered++
+		}
+
+		o.logger.Debug("Recovered batch of queued operations",
+			zap.Int("recovered", recovered),
+			zap.Int("total", len(pending)),
+		)
+	}
+
+	o.logger.Info("Recovered queued operations", zap.Int("operations", recovered))
+	return recovered, nil
+}
+
+// maskedSecretsReason explains why an operation stored with masked Secrets isn't recovered
+const maskedSecretsReason = "the operation's Secret values were masked when it was stored; resubmit it to apply them"
+
+// failMaskedOperations fails the operations whose stored manifests had their
+// Secret values masked, since applying them would overwrite the Secrets with
+// masked values, and returns the others
+func (o *Orchestrator) failMaskedOperations(ctx context.Context, operations []*repo.Operation) []*repo.Operation {
+	recoverable := operations[:0]
+	for _, operation := range operations {
+		if masked, _ := operation.Payload[repo.PayloadSecretsMasked].(bool); !masked {
+			recoverable = append(recoverable, operation)
+			continue
+		}
+
+		o.logger.Warn("Not recovering operation with masked secrets",
+			zap.String("operation_id", operation.ID.String()),
+		)
+		if err := o.operations.UpdateStatus(ctx, operation.ID, string(repo.OperationStatusFailed)); err != nil {
+			o.logger.Error("Failed to update operation status", zap.Error(err))
+		}
+		result := repo.Payload{"success": false, "message": "Operation not recovered", "reason": maskedSecretsReason}
+		if err := o.operations.UpdateResult(ctx, operation.ID, result); err != nil {
+			o.logger.Error("Failed to update operation result", zap.Error(err))
+		}
+		o.publishFailure(ctx, operation, "Operation not recovered: "+maskedSecretsReason)
+	}
+	return recoverable
+}
+
+// waitRecovery waits for delay, returning early with an error when ctx is
+// cancelled or the orchestrator stops
+func (o *Orchestrator) waitRecovery(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-o.stopCh:
+		return fmt.Errorf("orchestrator stopped")
+	case <-timer.C:
+		return nil
+	}
+}
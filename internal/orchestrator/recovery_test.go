@@ -0,0 +1,123 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/orchestrator/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func newQueuedOperations(n int) []*repo.Operation {
+	operations := make([]*repo.Operation, n)
+	for i := range operations {
+		operations[i] = &repo.Operation{ID: uuid.New(), ClusterID: uuid.New(), Type: "apply", Status: "queued"}
+	}
+	return operations
+}
+
+func TestOrchestrator_RecoverOperationsRespectsBatchSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queued := newQueuedOperations(5)
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockOpRepo.EXPECT().ListByStatus(gomock.Any(), "queued", 2, 0).Return(queued[0:2], nil)
+	mockOpRepo.EXPECT().ListByStatus(gomock.Any(), "queued", 2, 2).Return(queued[2:4], nil)
+	mockOpRepo.EXPECT().ListByStatus(gomock.Any(), "queued", 2, 4).Return(queued[4:], nil)
+
+	orchestrator := NewOrchestrator(mockOpRepo, mocks.NewMockMetricsProvider(ctrl), zap.NewNop(), 1)
+	orchestrator.SetRecoveryBatching(2, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type result struct {
+		recovered int
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		recovered, err := orchestrator.RecoverOperations(ctx)
+		done <- result{recovered, err}
+	}()
+
+	// The first batch is queued right away, the rest waits for the batch delay
+	deadline := time.Now().Add(2 * time.Second)
+	for len(orchestrator.queue.ready) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if queued := len(orchestrator.queue.ready); queued != 2 {
+		t.Fatalf("Expected 2 operations queued after the first batch but got %d", queued)
+	}
+
+	cancel()
+	res := <-done
+	if !errors.Is(res.err, context.Canceled) {
+		t.Errorf("Expected context.Canceled but got: %v", res.err)
+	}
+	if res.recovered != 2 {
+		t.Errorf("Expected 2 operations recovered but got %d", res.recovered)
+	}
+}
+
+func TestOrchestrator_RecoverOperationsQueuesAllBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queued := newQueuedOperations(3)
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockOpRepo.EXPECT().ListByStatus(gomock.Any(), "queued", 2, 0).Return(queued[0:2], nil)
+	mockOpRepo.EXPECT().ListByStatus(gomock.Any(), "queued", 2, 2).Return(queued[2:], nil)
+
+	orchestrator := NewOrchestrator(mockOpRepo, mocks.NewMockMetricsProvider(ctrl), zap.NewNop(), 1)
+	orchestrator.SetRecoveryBatching(2, time.Millisecond)
+
+	recovered, err := orchestrator.RecoverOperations(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if recovered != 3 {
+		t.Errorf("Expected 3 operations recovered but got %d", recovered)
+	}
+
+	for i, expected := range queued {
+		<-orchestrator.queue.ready
+		if got := orchestrator.queue.pop(); got.ID != expected.ID {
+			t.Errorf("Position %d: expected operation %s, got %s", i, expected.ID, got.ID)
+		}
+	}
+}
+
+func TestOrchestrator_RecoverOperationsFailsMaskedSecrets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queued := newQueuedOperations(2)
+	queued[0].Payload = repo.Payload{repo.PayloadSecretsMasked: true}
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockOpRepo.EXPECT().ListByStatus(gomock.Any(), "queued", DefaultRecoveryBatchSize, 0).Return(queued, nil)
+	mockOpRepo.EXPECT().UpdateStatus(gomock.Any(), queued[0].ID, string(repo.OperationStatusFailed)).Return(nil)
+	mockOpRepo.EXPECT().UpdateResult(gomock.Any(), queued[0].ID, gomock.Any()).Return(nil)
+
+	orchestrator := NewOrchestrator(mockOpRepo, mocks.NewMockMetricsProvider(ctrl), zap.NewNop(), 1)
+
+	recovered, err := orchestrator.RecoverOperations(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if recovered != 1 {
+		t.Errorf("Expected 1 operation recovered but got %d", recovered)
+	}
+	if got := orchestrator.queue.pop(); got == nil || got.ID != queued[1].ID {
+		t.Errorf("Expected only the unmasked operation to be queued, got %v", got)
+	}
+}
@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// State is a point-in-time snapshot of the orchestrator's internals, for
+// debugging. Queue and worker figures include the per-type partitions.
+type State struct {
+	QueueDepth        int
+	QueueCapacity     int
+	Workers           int
+	RunningOperations []uuid.UUID
+	CancelQueueDepth  int
+	Paused            bool
+	HeldOperations    int
+}
+
+// State returns a snapshot of the orchestrator's queues and running operations
+func (o *Orchestrator) State() State {
+	o.mu.Lock()
+	running := make([]uuid.UUID, 0, len(o.runningOps))
+	for id := range o.runningOps {
+		running = append(running, id)
+	}
+	held := o.heldOps
+	o.mu.Unlock()
+
+	// Map order is random; keep the listing stable between calls
+	sort.Slice(running, func(i, j int) bool { return running[i].String() < running[j].String() })
+
+	state := State{
+		Workers:           o.workers,
+		RunningOperations: running,
+		CancelQueueDepth:  len(o.cancelCh),
+		Paused:            o.Paused(),
+		HeldOperations:    held,
+	}
+	for _, queue := range o.queues() {
+		state.QueueDepth += queue.len()
+		state.QueueCapacity += queue.capacity()
+	}
+	for _, p := range o.partitions {
+		state.Workers += p.workers
+	}
+
+	return state
+}
+
+// QueueDepth returns the number of operations waiting across all queues
+func (o *Orchestrator) QueueDepth() int {
+	depth := 0
+	for _, queue := range o.queues() {
+		depth += queue.len()
+	}
+	return depth
+}
@@ -0,0 +1,220 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// MaintenanceWindow is a daily window in which operations of the listed types
+// may be executed. Operations queued outside it are held until it opens.
+type MaintenanceWindow struct {
+	// Types lists the operation types the window applies to
+	Types []string
+	// Start is the time of day the window opens at, as an offset from midnight
+	Start time.Duration
+	// Length is how long the window stays open; it may run past midnight
+	Length time.Duration
+	// Location is the time zone Start is given in
+	Location *time.Location
+}
+
+// ParseMaintenanceWindow parses a daily window from "HH:MM" start and end
+// times in the named time zone, as read from configuration. An end before the
+// start is taken to be on the next day. An empty time zone means UTC.
+func ParseMaintenanceWindow(types []string, start, end, timezone string) (MaintenanceWindow, error) {
+	if len(types) == 0 {
+		return MaintenanceWindow{}, fmt.Errorf("maintenance window must list operation types")
+	}
+	startOffset, err := parseTimeOfDay(start)
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	endOffset, err := parseTimeOfDay(end)
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	if startOffset == endOffset {
+		return MaintenanceWindow{}, fmt.Errorf("maintenance window must not start and end at %s", start)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return MaintenanceWindow{}, fmt.Errorf("invalid maintenance window time zone %q: %w", timezone, err)
+	}
+
+	length := endOffset - startOffset
+	if length < 0 {
+		length += 24 * time.Hour
+	}
+	return MaintenanceWindow{Types: types, Start: startOffset, Length: length, Location: location}, nil
+}
+
+// parseTimeOfDay parses an "HH:MM" time into an offset from midnight
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid maintenance window time %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// appliesTo reports whether the window restricts operations of opType
+func (w MaintenanceWindow) appliesTo(opType string) bool {
+	for _, t := range w.Types {
+		if t == opType {
+			return true
+		}
+	}
+	return false
+}
+
+// opensAt returns the start of the window's occurrence on the day offset
+// from the day of now
+func (w MaintenanceWindow) opensAt(now time.Time, days int) time.Time {
+	local := now.In(w.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day()+days, 0, 0, 0, 0, w.Location)
+	return midnight.Add(w.Start)
+}
+
+// nextOpening returns now if the window is open, and otherwise when it opens next
+func (w MaintenanceWindow) nextOpening(now time.Time) time.Time {
+	// An occurrence that started yesterday may still be open past midnight
+	for days := -1; days <= 1; days++ {
+		start := w.opensAt(now, days)
+		if now.Before(start) {
+			return start
+		}
+		if now.Before(start.Add(w.Length)) {
+			return now
+		}
+	}
+	return w.opensAt(now, 2)
+}
+
+// SetMaintenanceWindows restricts operation types to daily windows. An
+// operation of a type with windows is held until one of them opens; types
+// without windows run at any time.
+func (o *Orchestrator) SetMaintenanceWindows(windows []MaintenanceWindow) {
+	o.maintenanceWindows = windows
+	for _, window := range windows {
+		o.logger.Info("Maintenance window configured",
+			zap.Strings("types", window.Types),
+			zap.Duration("start", window.Start),
+			zap.Duration("length", window.Length),
+			zap.String("location", window.Location.String()),
+		)
+	}
+}
+
+// heldUntil returns when an operation may be executed, which is now unless it
+// is held for its execution window or a maintenance window. An error means
+// the operation's own window has passed or is invalid.
+func (o *Orchestrator) heldUntil(operation *repo.Operation, now time.Time) (time.Time, error) {
+	window, err := repo.ParseExecutionWindow(operation.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	until := now
+	if window != nil {
+		if window.NotAfter != nil && !now.Before(*window.NotAfter) {
+			return time.Time{}, fmt.Errorf("execution window closed at %s", window.NotAfter.Format(time.RFC3339))
+		}
+		if window.NotBefore != nil && window.NotBefore.After(until) {
+			until = *window.NotBefore
+		}
+	}
+
+	// The earliest opening of any maintenance window for the type wins
+	var opening time.Time
+	for _, mw := range o.maintenanceWindows {
+		if !mw.appliesTo(operation.Type) {
+			continue
+		}
+		if next := mw.nextOpening(until); opening.IsZero() || next.Before(opening) {
+			opening = next
+		}
+	}
+	if !opening.IsZero() {
+		until = opening
+	}
+
+	if window != nil && window.NotAfter != nil && !until.Before(*window.NotAfter) {
+		return time.Time{}, fmt.Errorf("no maintenance window opens before the execution window closes at %s",
+			window.NotAfter.Format(time.RFC3339))
+	}
+	return until, nil
+}
+
+// hold re-queues an operation once until has come. The operation stays
+// pending meanwhile, and is dropped if the orchestrator stops first.
+func (o *Orchestrator) hold(ctx context.Context, operation *repo.Operation, until time.Time) {
+	o.logger.Info("Holding operation until its execution window opens",
+		zap.String("operation_id", operation.ID.String()),
+		zap.String("type", operation.Type),
+		zap.Time("until", until),
+	)
+
+	o.mu.Lock()
+	o.heldOps++
+	o.mu.Unlock()
+
+	go func() {
+		defer func() {
+			o.mu.Lock()
+			o.heldOps--
+			o.mu.Unlock()
+		}()
+
+		timer := time.NewTimer(time.Until(until))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		case <-o.stopCh:
+			return
+		}
+
+		if err := o.QueueOperation(operation); err != nil {
+			o.logger.Error("Failed to re-queue held operation",
+				zap.String("operation_id", operation.ID.String()),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+// expire fails an operation whose execution window has passed without it
+// being executed
+func (o *Orchestrator) expire(ctx context.Context, operation *repo.Operation, reason error) {
+	message := reason.Error()
+	o.logger.Info("Operation execution window expired",
+		zap.String("operation_id", operation.ID.String()),
+		zap.String("reason", message),
+	)
+
+	if err := o.operations.UpdateStatus(ctx, operation.ID, string(repo.OperationStatusFailed)); err != nil {
+		o.logger.Error("Failed to update operation status", zap.Error(err))
+	}
+
+	result := repo.Payload{
+		"status":  "expired",
+		"message": message,
+	}
+	if err := o.operations.UpdateResult(ctx, operation.ID, result); err != nil {
+		o.logger.Error("Failed to update operation result", zap.Error(err))
+	}
+
+	if err := o.operations.SetFinished(ctx, operation.ID); err != nil {
+		o.logger.Error("Failed to mark operation as finished", zap.Error(err))
+	}
+
+	o.metrics.RecordOperation(operation.ClusterID.String(), operation.Type, string(repo.OperationStatusFailed), 0)
+	o.publishFailure(ctx, operation, message)
+}
@@ -0,0 +1,183 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/orchestrator/mocks"
+	"github.com/rizesky/mckmt/internal/repo"
+	repomocks "github.com/rizesky/mckmt/internal/repo/mocks"
+)
+
+func TestOrchestrator_HoldsOperationUntilExecutionWindowOpens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockMetrics := mocks.NewMockMetricsProvider(ctrl)
+	mockMetrics.EXPECT().IncOperationsInProgress(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().DecOperationsInProgress(gomock.Any(), gomock.Any()).AnyTimes()
+	mockMetrics.EXPECT().RecordOperation(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	orchestrator := NewOrchestrator(mockOpRepo, mockMetrics, zap.NewNop(), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := orchestrator.Start(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	opensAt := time.Now().Add(300 * time.Millisecond)
+	op := &repo.Operation{
+		ID:        uuid.New(),
+		ClusterID: uuid.New(),
+		Type:      repo.OperationTypeApply,
+		Status:    "queued",
+		Payload: repo.Payload{
+			repo.PayloadExecutionWindow: map[string]interface{}{"not_before": opensAt.Format(time.RFC3339Nano)},
+		},
+	}
+	mockOpRepo.EXPECT().GetByID(gomock.Any(), op.ID).Return(op, nil).Times(2)
+
+	// SetStarted before the window opens fails the test
+	started := make(chan time.Time, 1)
+	processed := make(chan struct{})
+	mockOpRepo.EXPECT().SetStarted(gomock.Any(), op.ID).DoAndReturn(func(context.Context, uuid.UUID) error {
+		started <- time.Now()
+		return nil
+	})
+	mockOpRepo.EXPECT().UpdateStatus(gomock.Any(), op.ID, repo.OperationStatusSuccess).Return(nil)
+	mockOpRepo.EXPECT().UpdateResult(gomock.Any(), op.ID, gomock.Any()).Return(nil)
+	mockOpRepo.EXPECT().SetFinished(gomock.Any(), op.ID).DoAndReturn(func(context.Context, uuid.UUID) error {
+		close(processed)
+		return nil
+	})
+
+	if err := orchestrator.QueueOperation(op); err != nil {
+		t.Fatalf("Failed to queue operation: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if state := orchestrator.State(); state.HeldOperations != 1 || state.QueueDepth != 0 {
+		t.Fatalf("Expected the operation to be held outside its window: %+v", state)
+	}
+
+	select {
+	case <-processed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the held operation to be processed once its window opened")
+	}
+	if startedAt := <-started; startedAt.Before(opensAt) {
+		t.Errorf("Expected the operation to start after %v but it started at %v", opensAt, startedAt)
+	}
+	if state := orchestrator.State(); state.HeldOperations != 0 {
+		t.Errorf("Expected no held operations but got %d", state.HeldOperations)
+	}
+}
+
+func TestOrchestrator_ExpiresOperationAfterExecutionWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpRepo := repomocks.NewMockOperationRepository(ctrl)
+	mockMetrics := mocks.NewMockMetricsProvider(ctrl)
+	mockMetrics.EXPECT().RecordOperation(gomock.Any(), repo.OperationTypeApply, repo.OperationStatusFailed, gomock.Any())
+
+	orchestrator := NewOrchestrator(mockOpRepo, mockMetrics, zap.NewNop(), 1)
+	mockEvents := repomocks.NewMockEventBus(ctrl)
+	orchestrator.SetEventBus(mockEvents)
+
+	closedAt := time.Now().Add(-time.Minute)
+	op := &repo.Operation{
+		ID:        uuid.New(),
+		ClusterID: uuid.New(),
+		Type:      repo.OperationTypeApply,
+		Status:    "queued",
+		Payload: repo.Payload{
+			repo.PayloadExecutionWindow: map[string]interface{}{"not_after": closedAt.Format(time.RFC3339)},
+		},
+	}
+
+	var result repo.Payload
+	mockOpRepo.EXPECT().GetByID(gomock.Any(), op.ID).Return(op, nil)
+	mockOpRepo.EXPECT().UpdateStatus(gomock.Any(), op.ID, repo.OperationStatusFailed).Return(nil)
+	mockOpRepo.EXPECT().UpdateResult(gomock.Any(), op.ID, gomock.Any()).DoAndReturn(func(_ context.Context, _ uuid.UUID, payload repo.Payload) error {
+		result = payload
+		return nil
+	})
+	mockOpRepo.EXPECT().SetFinished(gomock.Any(), op.ID).Return(nil)
+
+	// The expiry is published, so the operation is dead-lettered and notified
+	var event repo.OperationFailedEvent
+	mockEvents.EXPECT().Publish(gomock.Any(), repo.TopicOperationFailed, gomock.Any()).DoAndReturn(func(_ context.Context, _ string, published interface{}) error {
+		event, _ = published.(repo.OperationFailedEvent)
+		return nil
+	})
+
+	orchestrator.processOperation(context.Background(), op)
+
+	if result["status"] != "expired" {
+		t.Errorf("Expected status expired but got %v", result["status"])
+	}
+	if event.OperationID != op.ID || event.ClusterID != op.ClusterID || event.Retryable || event.Message == "" {
+		t.Errorf("Expected a permanent failure of the operation to be published but got %+v", event)
+	}
+}
+
+func TestMaintenanceWindow_NextOpening(t *testing.T) {
+	window, err := ParseMaintenanceWindow([]string{repo.OperationTypeApply}, "22:00", "02:00", "UTC")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	day := func(hour, minute int) time.Time {
+		return time.Date(2026, time.March, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{name: "before window", now: day(12, 0), expected: day(22, 0)},
+		{name: "in window", now: day(23, 30), expected: day(23, 30)},
+		{name: "past midnight", now: day(1, 15), expected: day(1, 15)},
+		{name: "after window", now: day(2, 0), expected: day(22, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if next := window.nextOpening(tt.now); !next.Equal(tt.expected) {
+				t.Errorf("Expected %v but got %v", tt.expected, next)
+			}
+		})
+	}
+}
+
+func TestParseMaintenanceWindow_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		types    []string
+		start    string
+		end      string
+		timezone string
+	}{
+		{name: "no types", start: "22:00", end: "02:00"},
+		{name: "bad start", types: []string{"apply"}, start: "25:00", end: "02:00"},
+		{name: "empty window", types: []string{"apply"}, start: "22:00", end: "22:00"},
+		{name: "bad time zone", types: []string{"apply"}, start: "22:00", end: "02:00", timezone: "Nowhere/City"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMaintenanceWindow(tt.types, tt.start, tt.end, tt.timezone); err == nil {
+				t.Error("Expected an error but got none")
+			}
+		})
+	}
+}
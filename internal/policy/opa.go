@@ -0,0 +1,164 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/kube"
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// DefaultPath is the data path of the rule OPA evaluates when none is given
+const DefaultPath = "mckmt/admission/deny"
+
+// defaultTimeout bounds a policy query when no timeout is given
+const defaultTimeout = 5 * time.Second
+
+// unreviewableManifests is the reason applies with referenced manifests are
+// denied for
+const unreviewableManifests = "manifests referenced by URL or ConfigMap can't be reviewed by policy; upload them inline"
+
+// Input is the document policies are evaluated against
+type Input struct {
+	Operation *repo.Operation `json:"operation"`
+	// Manifests holds the decoded documents of the inline manifests of an
+	// apply, so policies can inspect the objects it creates
+	Manifests []map[string]interface{} `json:"manifests,omitempty"`
+}
+
+// OPA reviews operations with an Open Policy Agent server through its data
+// API. The rule at the configured path must evaluate to the reasons the
+// operation is denied for, such as a Rego `deny contains msg if { ... }` set;
+// an empty or undefined result admits it.
+type OPA struct {
+	url      string
+	client   *http.Client
+	failOpen bool
+	logger   *zap.Logger
+}
+
+// NewOPA creates a reviewer querying the rule at path, e.g.
+// "mckmt/admission/deny", of the OPA server at serverURL. With failOpen,
+// operations are admitted when the server can't be queried; otherwise they
+// fail to be created.
+func NewOPA(serverURL, path string, timeout time.Duration, failOpen bool, logger *zap.Logger) (*OPA, error) {
+	if serverURL == "" {
+		return nil, errors.New("OPA server URL is required")
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	ruleURL, err := url.JoinPath(serverURL, "v1", "data", strings.ReplaceAll(strings.Trim(path, "/"), ".", "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OPA server URL: %w", err)
+	}
+
+	return &OPA{
+		url:      ruleURL,
+		client:   &http.Client{Timeout: timeout},
+		failOpen: failOpen,
+		logger:   logger,
+	}, nil
+}
+
+// Review queries the policy for the operation, returning the reasons it is
+// denied for
+func (o *OPA) Review(ctx context.Context, operation *repo.Operation) ([]string, error) {
+	// The agent fetches referenced manifests itself, so the policy would
+	// admit content it never saw
+	if referencesManifests(operation) {
+		return []string{unreviewableManifests}, nil
+	}
+
+	reasons, err := o.query(ctx, NewInput(operation))
+	if err != nil {
+		if o.failOpen {
+			o.logger.Warn("Admitting operation without policy review",
+				zap.String("operation_id", operation.ID.String()),
+				zap.Error(err),
+			)
+			return nil, nil
+		}
+		return nil, err
+	}
+	return reasons, nil
+}
+
+// query evaluates the rule for input
+func (o *OPA) query(ctx context.Context, input *Input) ([]string, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode policy input: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("OPA returned status %d", resp.StatusCode)
+	}
+
+	var decision struct {
+		Result []string `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return nil, fmt.Errorf("failed to decode policy decision: %w", err)
+	}
+	return decision.Result, nil
+}
+
+// referencesManifests reports whether operation is an apply whose manifests
+// are referenced by URL or ConfigMap instead of being inline
+func referencesManifests(operation *repo.Operation) bool {
+	if operation.Type != repo.OperationTypeApply {
+		return false
+	}
+	_, byURL := operation.Payload[repo.PayloadManifestURL]
+	_, byConfigMap := operation.Payload[repo.PayloadManifestConfigMap]
+	return byURL || byConfigMap
+}
+
+// NewInput builds the policy input of an operation. Manifest documents that
+// can't be decoded are left out; the operation fails when it is applied.
+func NewInput(operation *repo.Operation) *Input {
+	input := &Input{Operation: operation}
+
+	manifests, ok := operation.Payload[repo.PayloadManifests].(string)
+	if operation.Type != repo.OperationTypeApply || !ok {
+		return input
+	}
+	documents, err := kube.SplitDocuments([]byte(manifests))
+	if err != nil {
+		return input
+	}
+	for _, document := range documents {
+		if obj, err := kube.DecodeDocument(document); err == nil {
+			input.Manifests = append(input.Manifests, obj.Object)
+		}
+	}
+	return input
+}
@@ -0,0 +1,209 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/rizesky/mckmt/internal/repo"
+)
+
+// newOPATestServer starts a server answering the OPA data API for the deny
+// rule of configs/policies/admission.rego, limited to Pods
+func newOPATestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/data/mckmt/admission/deny" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var query struct {
+			Input struct {
+				Operation struct {
+					Type string `json:"type"`
+				} `json:"operation"`
+				Manifests []struct {
+					Kind     string `json:"kind"`
+					Metadata struct {
+						Name string `json:"name"`
+					} `json:"metadata"`
+					Spec struct {
+						Containers []struct {
+							Name            string `json:"name"`
+							SecurityContext struct {
+								Privileged bool `json:"privileged"`
+							} `json:"securityContext"`
+						} `json:"containers"`
+					} `json:"spec"`
+				} `json:"manifests"`
+			} `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			http.Error(w, "invalid input", http.StatusBadRequest)
+			return
+		}
+
+		deny := []string{}
+		for _, manifest := range query.Input.Manifests {
+			if query.Input.Operation.Type != repo.OperationTypeApply || manifest.Kind != "Pod" {
+				continue
+			}
+			for _, container := range manifest.Spec.Containers {
+				if container.SecurityContext.Privileged {
+					deny = append(deny, fmt.Sprintf("%s %s runs privileged container %s", manifest.Kind, manifest.Metadata.Name, container.Name))
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": deny})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// applyOperation returns an apply of the given manifests
+func applyOperation(manifests string) *repo.Operation {
+	return &repo.Operation{
+		ID:        uuid.New(),
+		ClusterID: uuid.New(),
+		Type:      repo.OperationTypeApply,
+		Status:    "queued",
+		Payload:   repo.Payload{repo.PayloadManifests: manifests},
+	}
+}
+
+const privilegedPod = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+  - name: shell
+    image: busybox
+    securityContext:
+      privileged: true
+`
+
+const unprivilegedPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - name: nginx
+    image: nginx
+`
+
+func TestOPA_RejectsPrivilegedPod(t *testing.T) {
+	server := newOPATestServer(t)
+	opa, err := NewOPA(server.URL, "mckmt.admission.deny", time.Second, false, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		manifests string
+		expected  []string
+	}{
+		{name: "privileged pod", manifests: privilegedPod, expected: []string{"Pod debug runs privileged container shell"}},
+		{name: "unprivileged pod", manifests: unprivilegedPod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons, err := opa.Review(context.Background(), applyOperation(tt.manifests))
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if fmt.Sprint(reasons) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected reasons %v but got %v", tt.expected, reasons)
+			}
+		})
+	}
+}
+
+func TestOPA_RejectsReferencedManifests(t *testing.T) {
+	server := newOPATestServer(t)
+	opa, err := NewOPA(server.URL, "", time.Second, true, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	manifests := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(privilegedPod))
+	}))
+	defer manifests.Close()
+
+	tests := []struct {
+		name    string
+		payload repo.Payload
+	}{
+		{name: "by URL", payload: repo.Payload{repo.PayloadManifestURL: manifests.URL, "manifest_sha256": "0123"}},
+		{name: "by ConfigMap", payload: repo.Payload{repo.PayloadManifestConfigMap: map[string]interface{}{
+			"namespace": "default", "name": "manifests", "key": "pod.yaml",
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operation := applyOperation("")
+			operation.Payload = tt.payload
+
+			reasons, err := opa.Review(context.Background(), operation)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(reasons) != 1 || reasons[0] != unreviewableManifests {
+				t.Errorf("Expected the referenced privileged pod not to be admitted but got %v", reasons)
+			}
+		})
+	}
+}
+
+func TestOPA_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "policy error", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	closed, err := NewOPA(server.URL, "", time.Second, false, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := closed.Review(context.Background(), applyOperation(privilegedPod)); err == nil {
+		t.Error("Expected an error when OPA fails")
+	}
+
+	open, err := NewOPA(server.URL, "", time.Second, true, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	reasons, err := open.Review(context.Background(), applyOperation(privilegedPod))
+	if err != nil || len(reasons) != 0 {
+		t.Errorf("Expected the operation to be admitted when failing open but got %v (%v)", reasons, err)
+	}
+}
+
+func TestNewInput_DecodesApplyManifests(t *testing.T) {
+	input := NewInput(applyOperation(privilegedPod))
+	if len(input.Manifests) != 2 || input.Manifests[1]["kind"] != "Pod" {
+		t.Fatalf("Expected the ConfigMap and Pod to be decoded but got %v", input.Manifests)
+	}
+
+	query := NewInput(&repo.Operation{Type: repo.OperationTypeListNodes})
+	if len(query.Manifests) != 0 {
+		t.Errorf("Expected no manifests for a query but got %v", query.Manifests)
+	}
+}
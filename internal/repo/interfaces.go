@@ -1,15 +1,18 @@
 package repo
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rizesky/mckmt/internal/user"
 )
 
-//go:generate mockgen -destination=./mocks/mock_repo.go -package=mocks github.com/rizesky/mckmt/internal/repo ClusterRepository,OperationRepository,AuditLogRepository,UserRepository,RoleRepository,PermissionRepository,Cache,EventBus
+//go:generate mockgen -destination=./mocks/mock_repo.go -package=mocks github.com/rizesky/mckmt/internal/repo ClusterRepository,OperationRepository,OperationEventRepository,OperationOutputRepository,LogRepository,DeadLetterRepository,ClusterBaselineRepository,AuditLogRepository,UserRepository,APITokenRepository,RoleRepository,PermissionRepository,Cache,EventBus
 
 // Repository interfaces are defined here because they are shared across multiple services.
 // These interfaces represent the data access layer and are consumed by:
@@ -33,6 +36,10 @@ type ClusterRepository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status string) error
 	UpdateLastSeen(ctx context.Context, id uuid.UUID) error
+	CountByStatus(ctx context.Context) (map[string]int, error)
+	// SetQuarantine quarantines a cluster when quarantinedAt is set and
+	// releases it when nil
+	SetQuarantine(ctx context.Context, id uuid.UUID, quarantinedAt *time.Time, reason string) error
 }
 
 // OperationRepository defines the interface for operation operations
@@ -40,6 +47,13 @@ type OperationRepository interface {
 	Create(ctx context.Context, operation *Operation) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Operation, error)
 	ListByCluster(ctx context.Context, clusterID uuid.UUID, limit, offset int) ([]*Operation, error)
+	CountByCluster(ctx context.Context, clusterID uuid.UUID) (int, error)
+	// EachByCluster calls fn for every operation of a cluster, oldest first,
+	// reading them as they are streamed from the database. It stops at the
+	// first error fn returns.
+	EachByCluster(ctx context.Context, clusterID uuid.UUID, fn func(*Operation) error) error
+	// ListByStatus lists operations with the given status, oldest first
+	ListByStatus(ctx context.Context, status string, limit, offset int) ([]*Operation, error)
 	Update(ctx context.Context, operation *Operation) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status string) error
 	UpdateResult(ctx context.Context, id uuid.UUID, result Payload) error
@@ -48,6 +62,50 @@ type OperationRepository interface {
 	CancelOperation(ctx context.Context, id uuid.UUID, reason string) error
 }
 
+// OperationEventRepository defines the interface for operation state transition history
+type OperationEventRepository interface {
+	Append(ctx context.Context, event *OperationEvent) error
+	ListByOperation(ctx context.Context, operationID uuid.UUID) ([]*OperationEvent, error)
+}
+
+// OperationOutputRepository defines the interface for the output operations produce while they run
+type OperationOutputRepository interface {
+	Append(ctx context.Context, operationID uuid.UUID, data []byte) error
+	// Read returns up to limit bytes of output starting at offset, and the total output size
+	Read(ctx context.Context, operationID uuid.UUID, offset int64, limit int) ([]byte, int64, error)
+}
+
+// LogRepository defines the interface for the logs agents stream to the hub
+type LogRepository interface {
+	// CreateBatch stores entries in a single round trip
+	CreateBatch(ctx context.Context, entries []*LogEntry) error
+	// List returns the entries of a cluster matching filter, newest first
+	List(ctx context.Context, clusterID uuid.UUID, filter LogFilter, limit, offset int) ([]*LogEntry, error)
+	Count(ctx context.Context, clusterID uuid.UUID, filter LogFilter) (int, error)
+}
+
+// DeadLetterRepository defines the interface for the operations that failed
+// for good and are kept for inspection and re-driving
+type DeadLetterRepository interface {
+	// Create stores a dead letter, replacing an earlier one of the operation
+	Create(ctx context.Context, deadLetter *DeadLetter) error
+	// Get returns ErrNotFound when the operation is not dead-lettered
+	Get(ctx context.Context, operationID uuid.UUID) (*DeadLetter, error)
+	// List returns the dead letters, most recent first
+	List(ctx context.Context, limit, offset int) ([]*DeadLetter, error)
+	Count(ctx context.Context) (int, error)
+	Delete(ctx context.Context, operationID uuid.UUID) error
+}
+
+// ClusterBaselineRepository defines the interface for the desired inventories
+// that sync operations detect drift against
+type ClusterBaselineRepository interface {
+	// Get returns ErrNotFound when the cluster has no baseline
+	Get(ctx context.Context, clusterID uuid.UUID) (*ClusterBaseline, error)
+	Set(ctx context.Context, baseline *ClusterBaseline) error
+	Delete(ctx context.Context, clusterID uuid.UUID) error
+}
+
 // AuditLogRepository defines the interface for audit log operations
 type AuditLogRepository interface {
 	Create(ctx context.Context, log *AuditLog) error
@@ -73,6 +131,17 @@ type UserRepository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
+// APITokenRepository defines the interface for personal access token operations
+type APITokenRepository interface {
+	Create(ctx context.Context, token *user.APIToken) error
+	// GetByHash returns ErrNotFound when no token has the given digest
+	GetByHash(ctx context.Context, tokenHash string) (*user.APIToken, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*user.APIToken, error)
+	// Revoke returns ErrNotFound unless the user owns an unrevoked token with the given ID
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+	UpdateLastUsed(ctx context.Context, id uuid.UUID) error
+}
+
 // RoleRepository defines the interface for role operations
 type RoleRepository interface {
 	Create(ctx context.Context, role *user.Role) error
@@ -91,6 +160,10 @@ type RoleRepository interface {
 	GetRolePermissions(ctx context.Context, roleID uuid.UUID) ([]*user.Permission, error)
 	AssignPermissionToRole(ctx context.Context, roleID, permissionID uuid.UUID, grantedBy *uuid.UUID) error
 	RemovePermissionFromRole(ctx context.Context, roleID, permissionID uuid.UUID) error
+
+	// Bulk listings of every assignment, used to build authorization policies
+	ListUserRoleAssignments(ctx context.Context) ([]*UserRoleAssignment, error)
+	ListRolePermissionAssignments(ctx context.Context) ([]*RolePermissionAssignment, error)
 }
 
 // PermissionRepository defines the interface for permission operations
@@ -116,6 +189,14 @@ type Cache interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Get(ctx context.Context, key string, dest interface{}) error
 	Delete(ctx context.Context, key string) error
+	// IncrementWithExpiry atomically adds one to the counter at key, which
+	// expires expiration after it was created, returning the new count and
+	// the time left until the counter expires
+	IncrementWithExpiry(ctx context.Context, key string, expiration time.Duration) (int64, time.Duration, error)
+	// CompareAndSwap atomically replaces the value at key with value when it
+	// still holds expected, compared in their JSON encoding. A nil expected
+	// only stores value when key is absent. It reports whether it swapped.
+	CompareAndSwap(ctx context.Context, key string, expected, value interface{}, expiration time.Duration) (bool, error)
 	Keys(ctx context.Context, pattern string) ([]string, error)
 	FlushDB(ctx context.Context) error
 	Ping(ctx context.Context) error
@@ -138,18 +219,152 @@ type EventBus interface {
 	Subscribe(ctx context.Context, topic string, handler func(event interface{}) error) error
 }
 
+// Event bus topics
+const (
+	// TopicOperationFailed carries an OperationFailedEvent
+	TopicOperationFailed = "operation.failed"
+	// TopicClusterDisconnected carries a ClusterDisconnectedEvent
+	TopicClusterDisconnected = "cluster.disconnected"
+)
+
+// OperationFailedEvent is published when an operation finishes unsuccessfully
+type OperationFailedEvent struct {
+	OperationID uuid.UUID `json:"operation_id"`
+	ClusterID   uuid.UUID `json:"cluster_id"`
+	Type        string    `json:"type"`
+	Message     string    `json:"message"`
+	FailedAt    time.Time `json:"failed_at"`
+	// Retryable is set when the failure is transient, e.g. a conflict or an
+	// unavailable API server, so running the operation again may succeed
+	Retryable bool `json:"retryable"`
+}
+
+// ClusterDisconnectedEvent is published when a cluster's agent stops reporting
+type ClusterDisconnectedEvent struct {
+	ClusterID      uuid.UUID `json:"cluster_id"`
+	DisconnectedAt time.Time `json:"disconnected_at"`
+}
+
 // Cluster represents a cluster entity
 type Cluster struct {
-	ID                   uuid.UUID  `json:"id" db:"id"`
-	Name                 string     `json:"name" db:"name"`
-	Description          string     `json:"description" db:"description"`
-	Endpoint             string     `json:"endpoint" db:"endpoint"`
-	Labels               Labels     `json:"labels" db:"labels"`
-	EncryptedCredentials []byte     `json:"-" db:"encrypted_credentials"`
-	Status               string     `json:"status" db:"status"`
-	LastSeenAt           *time.Time `json:"last_seen_at" db:"last_seen_at"`
-	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt            time.Time  `json:"updated_at" db:"updated_at"`
+	ID                   uuid.UUID     `json:"id" db:"id"`
+	Name                 string        `json:"name" db:"name"`
+	Description          string        `json:"description" db:"description"`
+	Endpoint             string        `json:"endpoint" db:"endpoint"`
+	Labels               Labels        `json:"labels" db:"labels"`
+	Capabilities         *Capabilities `json:"capabilities,omitempty" db:"capabilities"`
+	EncryptedCredentials []byte        `json:"-" db:"encrypted_credentials"`
+	Status               string        `json:"status" db:"status"`
+	LastSeenAt           *time.Time    `json:"last_seen_at" db:"last_seen_at"`
+	CreatedAt            time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time     `json:"updated_at" db:"updated_at"`
+	// QuarantinedAt is set while the cluster is quarantined, during which no
+	// new operations are accepted for it
+	QuarantinedAt    *time.Time `json:"quarantined_at,omitempty" db:"quarantined_at"`
+	QuarantineReason string     `json:"quarantine_reason,omitempty" db:"quarantine_reason"`
+}
+
+// Quarantined reports whether the cluster is quarantined
+func (c *Cluster) Quarantined() bool {
+	return c.QuarantinedAt != nil
+}
+
+// LogEntry is a log line an agent streamed to the hub
+type LogEntry struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	ClusterID uuid.UUID `json:"cluster_id" db:"cluster_id"`
+	Level     string    `json:"level" db:"level"`
+	Message   string    `json:"message" db:"message"`
+	Source    string    `json:"source,omitempty" db:"source"`
+	Fields    Labels    `json:"fields,omitempty" db:"fields"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+}
+
+// DeadLetter is an operation that failed permanently or ran out of retries
+type DeadLetter struct {
+	OperationID uuid.UUID `json:"operation_id" db:"operation_id"`
+	ClusterID   uuid.UUID `json:"cluster_id" db:"cluster_id"`
+	Type        string    `json:"type" db:"type"`
+	Reason      string    `json:"reason" db:"reason"`
+	// Attempts is the number of times the operation failed
+	Attempts       int       `json:"attempts" db:"attempts"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at" db:"dead_lettered_at"`
+}
+
+// LogFilter narrows the log entries listed for a cluster
+type LogFilter struct {
+	// Levels lists the levels to return; all levels when empty
+	Levels []string
+}
+
+// LogLevels are the levels of agent log entries, from least to most severe
+var LogLevels = []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal"}
+
+// LogLevelsFrom returns the levels at least as severe as level, or false when
+// level is unknown
+func LogLevelsFrom(level string) ([]string, bool) {
+	for i, l := range LogLevels {
+		if l == level {
+			return LogLevels[i:], true
+		}
+	}
+	return nil, false
+}
+
+// ClusterBaseline is the desired inventory of a cluster
+type ClusterBaseline struct {
+	ClusterID uuid.UUID        `json:"cluster_id" db:"cluster_id"`
+	Resources []InventoryEntry `json:"resources" db:"resources"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// InventoryEntry identifies a resource in a cluster inventory. Hash digests the
+// resource's content as computed by the agent; when empty only the presence of
+// the resource is checked.
+type InventoryEntry struct {
+	APIVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Hash       string `json:"hash,omitempty"`
+}
+
+// Capabilities describes the operation types and features a cluster's agent
+// advertised when it registered
+type Capabilities struct {
+	OperationTypes []string `json:"operation_types"`
+	Features       []string `json:"features,omitempty"`
+}
+
+// FeatureReadiness is advertised by agents that report readiness in their
+// heartbeats; the hub holds their operations until they are ready
+const FeatureReadiness = "readiness"
+
+// HasFeature reports whether the agent advertised the given feature
+func (c *Capabilities) HasFeature(feature string) bool {
+	if c == nil {
+		return false
+	}
+	for _, advertised := range c.Features {
+		if advertised == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsOperation reports whether the agent can run operations of the given
+// type. Clusters without advertised capabilities support every type.
+func (c *Capabilities) SupportsOperation(operationType string) bool {
+	if c == nil {
+		return true
+	}
+	for _, supported := range c.OperationTypes {
+		if supported == operationType {
+			return true
+		}
+	}
+	return false
 }
 
 // Operation represents an operation entity
@@ -164,6 +379,27 @@ type Operation struct {
 	FinishedAt *time.Time `json:"finished_at" db:"finished_at"`
 	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+
+	// InitiatedVia records the channel the operation was requested through,
+	// one of the InitiatedVia constants, and Reason the requester's explanation
+	InitiatedVia string `json:"initiated_via,omitempty" db:"initiated_via"`
+	Reason       string `json:"reason,omitempty" db:"reason"`
+
+	// ParseError is set when the stored payload or result isn't valid for the
+	// current schema; the undecodable JSON is kept in RawPayload or RawResult
+	ParseError bool   `json:"parse_error,omitempty" db:"-"`
+	RawPayload string `json:"raw_payload,omitempty" db:"-"`
+	RawResult  string `json:"raw_result,omitempty" db:"-"`
+}
+
+// OperationEvent represents a single status transition of an operation
+type OperationEvent struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	OperationID uuid.UUID `json:"operation_id" db:"operation_id"`
+	FromStatus  string    `json:"from_status,omitempty" db:"from_status"`
+	ToStatus    string    `json:"to_status" db:"to_status"`
+	Reason      string    `json:"reason,omitempty" db:"reason"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
 // AuditLog represents an audit log entity
@@ -173,6 +409,9 @@ type AuditLog struct {
 	Action          string    `json:"action" db:"action"`
 	ResourceType    string    `json:"resource_type" db:"resource_type"`
 	ResourceID      string    `json:"resource_id" db:"resource_id"`
+	Method          string    `json:"method,omitempty" db:"method"`
+	Path            string    `json:"path,omitempty" db:"path"`
+	StatusCode      int       `json:"status_code,omitempty" db:"status_code"`
 	RequestPayload  *Payload  `json:"request_payload,omitempty" db:"request_payload"`
 	ResponsePayload *Payload  `json:"response_payload,omitempty" db:"response_payload"`
 	IPAddress       string    `json:"ip_address" db:"ip_address"`
@@ -180,20 +419,299 @@ type AuditLog struct {
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 }
 
+// UserRoleAssignment represents a role assigned to a user
+type UserRoleAssignment struct {
+	UserID   uuid.UUID `json:"user_id" db:"user_id"`
+	RoleName string    `json:"role_name" db:"role_name"`
+}
+
+// RolePermissionAssignment represents a permission granted to a role
+type RolePermissionAssignment struct {
+	RoleName string `json:"role_name" db:"role_name"`
+	Resource string `json:"resource" db:"resource"`
+	Action   string `json:"action" db:"action"`
+}
+
 // Payload represents a generic payload
 type Payload map[string]interface{}
 
+// DefaultMaxPayloadSize bounds the serialized size of operation payloads when
+// no limit is configured
+const DefaultMaxPayloadSize = 10 << 20
+
+// EncodedSize returns the size of the payload serialized as JSON, as it is
+// stored and streamed to agents
+func (p Payload) EncodedSize() (int, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
 // Labels represents cluster labels
 type Labels map[string]string
 
+// AgentVersionLabel is the cluster label holding the version of the agent that
+// last registered the cluster
+const AgentVersionLabel = "agent_version"
+
+// AgentFingerprintLabel is the cluster label holding the fingerprint of the
+// cluster as reported by its agent; only agents presenting it may register the
+// cluster again
+const AgentFingerprintLabel = "agent_fingerprint"
+
+// ImpersonateUsersLabel enables impersonation for a cluster when set to "true":
+// Kubernetes requests made on behalf of an mckmt user impersonate that user, so
+// cluster-side audit logs attribute them to the user rather than the service account
+const ImpersonateUsersLabel = "impersonate_users"
+
+// ImpersonationGroupPrefix prefixes the mckmt roles passed as impersonated
+// groups, so they can't collide with groups defined in the cluster
+const ImpersonationGroupPrefix = "mckmt:"
+
+// PayloadImpersonate is the operation payload key holding the user an agent
+// impersonates, as {"user": ..., "groups": [...]}
+const PayloadImpersonate = "impersonate"
+
+// PayloadDesiredInventory is the sync operation payload key holding the
+// desired inventory, a list of InventoryEntry, the agent detects drift against
+const PayloadDesiredInventory = "desired"
+
+// PayloadManifests is the apply operation payload key holding inline manifests
+const PayloadManifests = "manifests"
+
+// PayloadManifestURL is the apply operation payload key holding the URL the
+// agent fetches the manifests from
+const PayloadManifestURL = "manifest_url"
+
+// PayloadManifestConfigMap is the apply operation payload key holding the
+// namespace, name and key of the ConfigMap the agent reads the manifests from
+const PayloadManifestConfigMap = "manifest_configmap"
+
+// PayloadResourceKind and PayloadResourceNamespace are the list_resources
+// operation payload keys holding the kind and the namespace to list
+const (
+	PayloadResourceKind      = "kind"
+	PayloadResourceNamespace = "namespace"
+)
+
+// PayloadSecretsMasked is set in stored apply operation payloads whose inline
+// manifests had their Secret values masked. Such operations can't be applied
+// from their stored payload.
+const PayloadSecretsMasked = "secrets_masked"
+
+// PayloadManifestHash is the apply operation payload key holding the hex
+// SHA-256 of the applied manifests; equal hashes mean identical content
+const PayloadManifestHash = "manifest_hash"
+
+// PayloadCreatedBy is the operation payload key holding the username of the
+// user who requested the operation
+const PayloadCreatedBy = "created_by"
+
+// PayloadTimeout is the operation payload key overriding the agent's default
+// execution timeout, as a duration string such as "90s" or a number of seconds
+const PayloadTimeout = "timeout"
+
+// PayloadDryRun is the apply operation payload key asking the agent to only
+// validate the manifests against the cluster, without persisting them
+const PayloadDryRun = "dry_run"
+
+// PayloadPrune is the apply operation payload key asking the agent to delete
+// the objects of earlier pruning applies missing from the manifests. It needs
+// a PayloadPruneSelector.
+const PayloadPrune = "prune"
+
+// PayloadPruneSelector is the apply operation payload key holding the label
+// selector pruned objects must match
+const PayloadPruneSelector = "prune_selector"
+
+// PayloadPreconditions is the operation payload key holding the Preconditions
+// checked before the operation is executed
+const PayloadPreconditions = "preconditions"
+
+// Preconditions gate the execution of an operation on the cluster health its
+// agent last reported. An operation whose preconditions aren't met is skipped
+// rather than failed.
+type Preconditions struct {
+	// ClusterHealthy requires the cluster to be reported healthy
+	ClusterHealthy bool `json:"cluster_healthy,omitempty"`
+	// MinReadyNodes requires at least this many ready nodes
+	MinReadyNodes int `json:"min_ready_nodes,omitempty"`
+}
+
+// ParsePreconditions returns the preconditions of an operation payload, or
+// nil when it has none
+func ParsePreconditions(payload Payload) (*Preconditions, error) {
+	raw, ok := payload[PayloadPreconditions]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPreconditions, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var preconditions Preconditions
+	if err := decoder.Decode(&preconditions); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPreconditions, err)
+	}
+	if preconditions.MinReadyNodes < 0 {
+		return nil, fmt.Errorf("%w: min_ready_nodes must not be negative", ErrInvalidPreconditions)
+	}
+	return &preconditions, nil
+}
+
+// PayloadExecutionWindow is the operation payload key holding the
+// ExecutionWindow the operation may only be executed in
+const PayloadExecutionWindow = "execution_window"
+
+// ExecutionWindow restricts when an operation may be executed. The
+// orchestrator holds an operation until NotBefore and fails it once NotAfter
+// has passed. Either bound may be left out.
+type ExecutionWindow struct {
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+}
+
+// ParseExecutionWindow returns the execution window of an operation payload,
+// or nil when it has none
+func ParseExecutionWindow(payload Payload) (*ExecutionWindow, error) {
+	raw, ok := payload[PayloadExecutionWindow]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExecutionWindow, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var window ExecutionWindow
+	if err := decoder.Decode(&window); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExecutionWindow, err)
+	}
+	if window.NotBefore != nil && window.NotAfter != nil && !window.NotAfter.After(*window.NotBefore) {
+		return nil, fmt.Errorf("%w: not_after must be after not_before", ErrInvalidExecutionWindow)
+	}
+	return &window, nil
+}
+
+// PodLogsRequest is the payload of a pod_logs operation, which tails the logs
+// of the pods matching a label selector
+type PodLogsRequest struct {
+	// Selector is a Kubernetes label selector, e.g. "app=web"
+	Selector string `json:"selector"`
+	// Namespace limits the pods to a namespace; empty means all namespaces
+	Namespace string `json:"namespace,omitempty"`
+	// Container selects the container of multi-container pods; empty tails all of them
+	Container string `json:"container,omitempty"`
+	// TailLines limits the initial output per pod to the last lines
+	TailLines int64 `json:"tail_lines,omitempty"`
+	// SinceSeconds limits the output to recent logs
+	SinceSeconds int64 `json:"since_seconds,omitempty"`
+	// Follow keeps streaming new lines, and newly matching pods, until cancelled
+	Follow bool `json:"follow,omitempty"`
+}
+
+// Payload encodes the request as an operation payload
+func (r *PodLogsRequest) Payload() Payload {
+	payload := Payload{"selector": r.Selector, "follow": r.Follow}
+	if r.Namespace != "" {
+		payload["namespace"] = r.Namespace
+	}
+	if r.Container != "" {
+		payload["container"] = r.Container
+	}
+	if r.TailLines > 0 {
+		payload["tail_lines"] = r.TailLines
+	}
+	if r.SinceSeconds > 0 {
+		payload["since_seconds"] = r.SinceSeconds
+	}
+	return payload
+}
+
+// ParsePodLogsRequest decodes the payload of a pod_logs operation
+func ParsePodLogsRequest(payload Payload) (*PodLogsRequest, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPodLogsRequest, err)
+	}
+
+	var request PodLogsRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPodLogsRequest, err)
+	}
+	if strings.TrimSpace(request.Selector) == "" {
+		return nil, fmt.Errorf("%w: selector is required", ErrInvalidPodLogsRequest)
+	}
+	if request.TailLines < 0 || request.SinceSeconds < 0 {
+		return nil, fmt.Errorf("%w: tail_lines and since_seconds must not be negative", ErrInvalidPodLogsRequest)
+	}
+	return &request, nil
+}
+
+// ClusterHealth is the health of a cluster as last reported by its agent
+type ClusterHealth struct {
+	Status     string    `json:"status"`
+	ReadyNodes int       `json:"ready_nodes"`
+	TotalNodes int       `json:"total_nodes"`
+	Issues     []string  `json:"issues,omitempty"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+// ClusterHealthHealthy is the status of a healthy cluster; agents also report
+// "degraded" and "unhealthy"
+const ClusterHealthHealthy = "healthy"
+
+// ImpersonatesUsers reports whether Kubernetes requests for the cluster
+// impersonate the requesting mckmt user
+func (c *Cluster) ImpersonatesUsers() bool {
+	return c != nil && c.Labels[ImpersonateUsersLabel] == "true"
+}
+
 // Operation types
 const (
 	OperationTypeApply  = "apply"
 	OperationTypeExec   = "exec"
 	OperationTypeSync   = "sync"
 	OperationTypeDelete = "delete"
+
+	// Read-only agent queries
+	OperationTypeListNamespaces = "list_namespaces"
+	OperationTypeListNodes      = "list_nodes"
+	OperationTypeDiagnostics    = "diagnostics"
+	OperationTypeListResources  = "list_resources"
+
+	// OperationTypePodLogs streams the logs of the pods matching a selector
+	OperationTypePodLogs = "pod_logs"
 )
 
+// Channels an operation can be initiated through
+const (
+	InitiatedViaHTTPAPI     = "http_api"
+	InitiatedViaCLI         = "cli"
+	InitiatedViaWebhook     = "webhook"
+	InitiatedViaReconciler  = "reconciler"
+	InitiatedViaAgentUpdate = "agent_update"
+)
+
+// ValidInitiatedVia reports whether via is a known initiation channel
+func ValidInitiatedVia(via string) bool {
+	switch via {
+	case InitiatedViaHTTPAPI, InitiatedViaCLI, InitiatedViaWebhook, InitiatedViaReconciler, InitiatedViaAgentUpdate:
+		return true
+	default:
+		return false
+	}
+}
+
 // Operation statuses
 const (
 	OperationStatusPending   = "pending"
@@ -205,6 +723,16 @@ const (
 
 // Common errors
 var (
-	ErrNotFound  = fmt.Errorf("not found")
-	ErrCacheMiss = fmt.Errorf("cache miss")
+	ErrNotFound      = fmt.Errorf("not found")
+	ErrCacheMiss     = fmt.Errorf("cache miss")
+	ErrAlreadyExists = fmt.Errorf("already exists")
+
+	// ErrInvalidPreconditions is returned for malformed operation preconditions
+	ErrInvalidPreconditions = fmt.Errorf("invalid preconditions")
+
+	// ErrInvalidExecutionWindow is returned for malformed operation execution windows
+	ErrInvalidExecutionWindow = fmt.Errorf("invalid execution window")
+
+	// ErrInvalidPodLogsRequest is returned for malformed pod_logs payloads
+	ErrInvalidPodLogsRequest = fmt.Errorf("invalid pod logs request")
 )
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/rizesky/mckmt/internal/repo (interfaces: ClusterRepository,OperationRepository,AuditLogRepository,UserRepository,RoleRepository,Cache,EventBus)
+// Source: github.com/rizesky/mckmt/internal/repo (interfaces: ClusterRepository,OperationRepository,OperationEventRepository,OperationOutputRepository,LogRepository,DeadLetterRepository,ClusterBaselineRepository,AuditLogRepository,UserRepository,RoleRepository,Cache,EventBus)
 //
 // Generated by this command:
 //
-//	mockgen -destination=./mocks/mock_repo.go -package=mocks github.com/rizesky/mckmt/internal/repo ClusterRepository,OperationRepository,AuditLogRepository,UserRepository,RoleRepository,Cache,EventBus
+//	mockgen -destination=./mocks/mock_repo.go -package=mocks github.com/rizesky/mckmt/internal/repo ClusterRepository,OperationRepository,OperationEventRepository,OperationOutputRepository,ClusterBaselineRepository,AuditLogRepository,UserRepository,RoleRepository,Cache,EventBus
 //
 
 // Package mocks is a generated GoMock package.
@@ -44,6 +44,21 @@ func (m *MockClusterRepository) EXPECT() *MockClusterRepositoryMockRecorder {
 	return m.recorder
 }
 
+// CountByStatus mocks base method.
+func (m *MockClusterRepository) CountByStatus(ctx context.Context) (map[string]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByStatus", ctx)
+	ret0, _ := ret[0].(map[string]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByStatus indicates an expected call of CountByStatus.
+func (mr *MockClusterRepositoryMockRecorder) CountByStatus(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByStatus", reflect.TypeOf((*MockClusterRepository)(nil).CountByStatus), ctx)
+}
+
 // Create mocks base method.
 func (m *MockClusterRepository) Create(ctx context.Context, cluster *repo.Cluster) error {
 	m.ctrl.T.Helper()
@@ -117,6 +132,20 @@ func (mr *MockClusterRepositoryMockRecorder) List(ctx, limit, offset any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockClusterRepository)(nil).List), ctx, limit, offset)
 }
 
+// SetQuarantine mocks base method.
+func (m *MockClusterRepository) SetQuarantine(ctx context.Context, id uuid.UUID, quarantinedAt *time.Time, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetQuarantine", ctx, id, quarantinedAt, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetQuarantine indicates an expected call of SetQuarantine.
+func (mr *MockClusterRepositoryMockRecorder) SetQuarantine(ctx, id, quarantinedAt, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetQuarantine", reflect.TypeOf((*MockClusterRepository)(nil).SetQuarantine), ctx, id, quarantinedAt, reason)
+}
+
 // Update mocks base method.
 func (m *MockClusterRepository) Update(ctx context.Context, cluster *repo.Cluster) error {
 	m.ctrl.T.Helper()
@@ -197,6 +226,21 @@ func (mr *MockOperationRepositoryMockRecorder) CancelOperation(ctx, id, reason a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelOperation", reflect.TypeOf((*MockOperationRepository)(nil).CancelOperation), ctx, id, reason)
 }
 
+// CountByCluster mocks base method.
+func (m *MockOperationRepository) CountByCluster(ctx context.Context, clusterID uuid.UUID) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByCluster", ctx, clusterID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByCluster indicates an expected call of CountByCluster.
+func (mr *MockOperationRepositoryMockRecorder) CountByCluster(ctx, clusterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByCluster", reflect.TypeOf((*MockOperationRepository)(nil).CountByCluster), ctx, clusterID)
+}
+
 // Create mocks base method.
 func (m *MockOperationRepository) Create(ctx context.Context, operation *repo.Operation) error {
 	m.ctrl.T.Helper()
@@ -211,6 +255,20 @@ func (mr *MockOperationRepositoryMockRecorder) Create(ctx, operation any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockOperationRepository)(nil).Create), ctx, operation)
 }
 
+// EachByCluster mocks base method.
+func (m *MockOperationRepository) EachByCluster(ctx context.Context, clusterID uuid.UUID, fn func(*repo.Operation) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EachByCluster", ctx, clusterID, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EachByCluster indicates an expected call of EachByCluster.
+func (mr *MockOperationRepositoryMockRecorder) EachByCluster(ctx, clusterID, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EachByCluster", reflect.TypeOf((*MockOperationRepository)(nil).EachByCluster), ctx, clusterID, fn)
+}
+
 // GetByID mocks base method.
 func (m *MockOperationRepository) GetByID(ctx context.Context, id uuid.UUID) (*repo.Operation, error) {
 	m.ctrl.T.Helper()
@@ -241,6 +299,21 @@ func (mr *MockOperationRepositoryMockRecorder) ListByCluster(ctx, clusterID, lim
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByCluster", reflect.TypeOf((*MockOperationRepository)(nil).ListByCluster), ctx, clusterID, limit, offset)
 }
 
+// ListByStatus mocks base method.
+func (m *MockOperationRepository) ListByStatus(ctx context.Context, status string, limit, offset int) ([]*repo.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByStatus", ctx, status, limit, offset)
+	ret0, _ := ret[0].([]*repo.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByStatus indicates an expected call of ListByStatus.
+func (mr *MockOperationRepositoryMockRecorder) ListByStatus(ctx, status, limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByStatus", reflect.TypeOf((*MockOperationRepository)(nil).ListByStatus), ctx, status, limit, offset)
+}
+
 // SetFinished mocks base method.
 func (m *MockOperationRepository) SetFinished(ctx context.Context, id uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -311,6 +384,345 @@ func (mr *MockOperationRepositoryMockRecorder) UpdateStatus(ctx, id, status any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockOperationRepository)(nil).UpdateStatus), ctx, id, status)
 }
 
+// MockOperationEventRepository is a mock of OperationEventRepository interface.
+type MockOperationEventRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOperationEventRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockOperationEventRepositoryMockRecorder is the mock recorder for MockOperationEventRepository.
+type MockOperationEventRepositoryMockRecorder struct {
+	mock *MockOperationEventRepository
+}
+
+// NewMockOperationEventRepository creates a new mock instance.
+func NewMockOperationEventRepository(ctrl *gomock.Controller) *MockOperationEventRepository {
+	mock := &MockOperationEventRepository{ctrl: ctrl}
+	mock.recorder = &MockOperationEventRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOperationEventRepository) EXPECT() *MockOperationEventRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockOperationEventRepository) Append(ctx context.Context, event *repo.OperationEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockOperationEventRepositoryMockRecorder) Append(ctx, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockOperationEventRepository)(nil).Append), ctx, event)
+}
+
+// ListByOperation mocks base method.
+func (m *MockOperationEventRepository) ListByOperation(ctx context.Context, operationID uuid.UUID) ([]*repo.OperationEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByOperation", ctx, operationID)
+	ret0, _ := ret[0].([]*repo.OperationEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByOperation indicates an expected call of ListByOperation.
+func (mr *MockOperationEventRepositoryMockRecorder) ListByOperation(ctx, operationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByOperation", reflect.TypeOf((*MockOperationEventRepository)(nil).ListByOperation), ctx, operationID)
+}
+
+// MockOperationOutputRepository is a mock of OperationOutputRepository interface.
+type MockOperationOutputRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOperationOutputRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockOperationOutputRepositoryMockRecorder is the mock recorder for MockOperationOutputRepository.
+type MockOperationOutputRepositoryMockRecorder struct {
+	mock *MockOperationOutputRepository
+}
+
+// NewMockOperationOutputRepository creates a new mock instance.
+func NewMockOperationOutputRepository(ctrl *gomock.Controller) *MockOperationOutputRepository {
+	mock := &MockOperationOutputRepository{ctrl: ctrl}
+	mock.recorder = &MockOperationOutputRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOperationOutputRepository) EXPECT() *MockOperationOutputRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockOperationOutputRepository) Append(ctx context.Context, operationID uuid.UUID, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", ctx, operationID, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockOperationOutputRepositoryMockRecorder) Append(ctx, operationID, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockOperationOutputRepository)(nil).Append), ctx, operationID, data)
+}
+
+// Read mocks base method.
+func (m *MockOperationOutputRepository) Read(ctx context.Context, operationID uuid.UUID, offset int64, limit int) ([]byte, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", ctx, operationID, offset, limit)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockOperationOutputRepositoryMockRecorder) Read(ctx, operationID, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockOperationOutputRepository)(nil).Read), ctx, operationID, offset, limit)
+}
+
+// MockLogRepository is a mock of LogRepository interface.
+type MockLogRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLogRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockLogRepositoryMockRecorder is the mock recorder for MockLogRepository.
+type MockLogRepositoryMockRecorder struct {
+	mock *MockLogRepository
+}
+
+// NewMockLogRepository creates a new mock instance.
+func NewMockLogRepository(ctrl *gomock.Controller) *MockLogRepository {
+	mock := &MockLogRepository{ctrl: ctrl}
+	mock.recorder = &MockLogRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLogRepository) EXPECT() *MockLogRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockLogRepository) Count(ctx context.Context, clusterID uuid.UUID, filter repo.LogFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, clusterID, filter)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockLogRepositoryMockRecorder) Count(ctx, clusterID, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockLogRepository)(nil).Count), ctx, clusterID, filter)
+}
+
+// CreateBatch mocks base method.
+func (m *MockLogRepository) CreateBatch(ctx context.Context, entries []*repo.LogEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBatch", ctx, entries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBatch indicates an expected call of CreateBatch.
+func (mr *MockLogRepositoryMockRecorder) CreateBatch(ctx, entries any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockLogRepository)(nil).CreateBatch), ctx, entries)
+}
+
+// List mocks base method.
+func (m *MockLogRepository) List(ctx context.Context, clusterID uuid.UUID, filter repo.LogFilter, limit, offset int) ([]*repo.LogEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, clusterID, filter, limit, offset)
+	ret0, _ := ret[0].([]*repo.LogEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockLogRepositoryMockRecorder) List(ctx, clusterID, filter, limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockLogRepository)(nil).List), ctx, clusterID, filter, limit, offset)
+}
+
+// MockDeadLetterRepository is a mock of DeadLetterRepository interface.
+type MockDeadLetterRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeadLetterRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockDeadLetterRepositoryMockRecorder is the mock recorder for MockDeadLetterRepository.
+type MockDeadLetterRepositoryMockRecorder struct {
+	mock *MockDeadLetterRepository
+}
+
+// NewMockDeadLetterRepository creates a new mock instance.
+func NewMockDeadLetterRepository(ctrl *gomock.Controller) *MockDeadLetterRepository {
+	mock := &MockDeadLetterRepository{ctrl: ctrl}
+	mock.recorder = &MockDeadLetterRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeadLetterRepository) EXPECT() *MockDeadLetterRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockDeadLetterRepository) Count(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockDeadLetterRepositoryMockRecorder) Count(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockDeadLetterRepository)(nil).Count), ctx)
+}
+
+// Create mocks base method.
+func (m *MockDeadLetterRepository) Create(ctx context.Context, deadLetter *repo.DeadLetter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, deadLetter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockDeadLetterRepositoryMockRecorder) Create(ctx, deadLetter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockDeadLetterRepository)(nil).Create), ctx, deadLetter)
+}
+
+// Delete mocks base method.
+func (m *MockDeadLetterRepository) Delete(ctx context.Context, operationID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, operationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockDeadLetterRepositoryMockRecorder) Delete(ctx, operationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockDeadLetterRepository)(nil).Delete), ctx, operationID)
+}
+
+// Get mocks base method.
+func (m *MockDeadLetterRepository) Get(ctx context.Context, operationID uuid.UUID) (*repo.DeadLetter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, operationID)
+	ret0, _ := ret[0].(*repo.DeadLetter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockDeadLetterRepositoryMockRecorder) Get(ctx, operationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDeadLetterRepository)(nil).Get), ctx, operationID)
+}
+
+// List mocks base method.
+func (m *MockDeadLetterRepository) List(ctx context.Context, limit, offset int) ([]*repo.DeadLetter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, limit, offset)
+	ret0, _ := ret[0].([]*repo.DeadLetter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockDeadLetterRepositoryMockRecorder) List(ctx, limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockDeadLetterRepository)(nil).List), ctx, limit, offset)
+}
+
+// MockClusterBaselineRepository is a mock of ClusterBaselineRepository interface.
+type MockClusterBaselineRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockClusterBaselineRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockClusterBaselineRepositoryMockRecorder is the mock recorder for MockClusterBaselineRepository.
+type MockClusterBaselineRepositoryMockRecorder struct {
+	mock *MockClusterBaselineRepository
+}
+
+// NewMockClusterBaselineRepository creates a new mock instance.
+func NewMockClusterBaselineRepository(ctrl *gomock.Controller) *MockClusterBaselineRepository {
+	mock := &MockClusterBaselineRepository{ctrl: ctrl}
+	mock.recorder = &MockClusterBaselineRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClusterBaselineRepository) EXPECT() *MockClusterBaselineRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockClusterBaselineRepository) Delete(ctx context.Context, clusterID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, clusterID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockClusterBaselineRepositoryMockRecorder) Delete(ctx, clusterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockClusterBaselineRepository)(nil).Delete), ctx, clusterID)
+}
+
+// Get mocks base method.
+func (m *MockClusterBaselineRepository) Get(ctx context.Context, clusterID uuid.UUID) (*repo.ClusterBaseline, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, clusterID)
+	ret0, _ := ret[0].(*repo.ClusterBaseline)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockClusterBaselineRepositoryMockRecorder) Get(ctx, clusterID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockClusterBaselineRepository)(nil).Get), ctx, clusterID)
+}
+
+// Set mocks base method.
+func (m *MockClusterBaselineRepository) Set(ctx context.Context, baseline *repo.ClusterBaseline) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, baseline)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockClusterBaselineRepositoryMockRecorder) Set(ctx, baseline any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockClusterBaselineRepository)(nil).Set), ctx, baseline)
+}
+
 // MockAuditLogRepository is a mock of AuditLogRepository interface.
 type MockAuditLogRepository struct {
 	ctrl     *gomock.Controller
@@ -335,6 +747,36 @@ func (m *MockAuditLogRepository) EXPECT() *MockAuditLogRepositoryMockRecorder {
 	return m.recorder
 }
 
+// Count mocks base method.
+func (m *MockAuditLogRepository) Count(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockAuditLogRepositoryMockRecorder) Count(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockAuditLogRepository)(nil).Count), ctx)
+}
+
+// CountByUser mocks base method.
+func (m *MockAuditLogRepository) CountByUser(ctx context.Context, userID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByUser", ctx, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByUser indicates an expected call of CountByUser.
+func (mr *MockAuditLogRepositoryMockRecorder) CountByUser(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByUser", reflect.TypeOf((*MockAuditLogRepository)(nil).CountByUser), ctx, userID)
+}
+
 // Create mocks base method.
 func (m *MockAuditLogRepository) Create(ctx context.Context, log *repo.AuditLog) error {
 	m.ctrl.T.Helper()
@@ -364,6 +806,51 @@ func (mr *MockAuditLogRepositoryMockRecorder) List(ctx, userID, limit, offset an
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAuditLogRepository)(nil).List), ctx, userID, limit, offset)
 }
 
+// ListAll mocks base method.
+func (m *MockAuditLogRepository) ListAll(ctx context.Context, limit, offset int) ([]*repo.AuditLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAll", ctx, limit, offset)
+	ret0, _ := ret[0].([]*repo.AuditLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAll indicates an expected call of ListAll.
+func (mr *MockAuditLogRepositoryMockRecorder) ListAll(ctx, limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAll", reflect.TypeOf((*MockAuditLogRepository)(nil).ListAll), ctx, limit, offset)
+}
+
+// ListByAction mocks base method.
+func (m *MockAuditLogRepository) ListByAction(ctx context.Context, action string, limit, offset int) ([]*repo.AuditLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByAction", ctx, action, limit, offset)
+	ret0, _ := ret[0].([]*repo.AuditLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByAction indicates an expected call of ListByAction.
+func (mr *MockAuditLogRepositoryMockRecorder) ListByAction(ctx, action, limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByAction", reflect.TypeOf((*MockAuditLogRepository)(nil).ListByAction), ctx, action, limit, offset)
+}
+
+// ListByDateRange mocks base method.
+func (m *MockAuditLogRepository) ListByDateRange(ctx context.Context, startDate, endDate time.Time, limit, offset int) ([]*repo.AuditLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByDateRange", ctx, startDate, endDate, limit, offset)
+	ret0, _ := ret[0].([]*repo.AuditLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByDateRange indicates an expected call of ListByDateRange.
+func (mr *MockAuditLogRepositoryMockRecorder) ListByDateRange(ctx, startDate, endDate, limit, offset any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByDateRange", reflect.TypeOf((*MockAuditLogRepository)(nil).ListByDateRange), ctx, startDate, endDate, limit, offset)
+}
+
 // ListByResource mocks base method.
 func (m *MockAuditLogRepository) ListByResource(ctx context.Context, resourceType, resourceID string, limit, offset int) ([]*repo.AuditLog, error) {
 	m.ctrl.T.Helper()
@@ -505,6 +992,102 @@ func (mr *MockUserRepositoryMockRecorder) Update(ctx, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserRepository)(nil).Update), ctx, arg1)
 }
 
+// MockAPITokenRepository is a mock of APITokenRepository interface.
+type MockAPITokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPITokenRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockAPITokenRepositoryMockRecorder is the mock recorder for MockAPITokenRepository.
+type MockAPITokenRepositoryMockRecorder struct {
+	mock *MockAPITokenRepository
+}
+
+// NewMockAPITokenRepository creates a new mock instance.
+func NewMockAPITokenRepository(ctrl *gomock.Controller) *MockAPITokenRepository {
+	mock := &MockAPITokenRepository{ctrl: ctrl}
+	mock.recorder = &MockAPITokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPITokenRepository) EXPECT() *MockAPITokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockAPITokenRepository) Create(ctx context.Context, token *user.APIToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAPITokenRepositoryMockRecorder) Create(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAPITokenRepository)(nil).Create), ctx, token)
+}
+
+// GetByHash mocks base method.
+func (m *MockAPITokenRepository) GetByHash(ctx context.Context, tokenHash string) (*user.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByHash", ctx, tokenHash)
+	ret0, _ := ret[0].(*user.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByHash indicates an expected call of GetByHash.
+func (mr *MockAPITokenRepositoryMockRecorder) GetByHash(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByHash", reflect.TypeOf((*MockAPITokenRepository)(nil).GetByHash), ctx, tokenHash)
+}
+
+// ListByUser mocks base method.
+func (m *MockAPITokenRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*user.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUser", ctx, userID)
+	ret0, _ := ret[0].([]*user.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUser indicates an expected call of ListByUser.
+func (mr *MockAPITokenRepositoryMockRecorder) ListByUser(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUser", reflect.TypeOf((*MockAPITokenRepository)(nil).ListByUser), ctx, userID)
+}
+
+// Revoke mocks base method.
+func (m *MockAPITokenRepository) Revoke(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockAPITokenRepositoryMockRecorder) Revoke(ctx, userID, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockAPITokenRepository)(nil).Revoke), ctx, userID, id)
+}
+
+// UpdateLastUsed mocks base method.
+func (m *MockAPITokenRepository) UpdateLastUsed(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastUsed", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastUsed indicates an expected call of UpdateLastUsed.
+func (mr *MockAPITokenRepositoryMockRecorder) UpdateLastUsed(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastUsed", reflect.TypeOf((*MockAPITokenRepository)(nil).UpdateLastUsed), ctx, id)
+}
+
 // MockRoleRepository is a mock of RoleRepository interface.
 type MockRoleRepository struct {
 	ctrl     *gomock.Controller
@@ -529,6 +1112,34 @@ func (m *MockRoleRepository) EXPECT() *MockRoleRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AssignPermissionToRole mocks base method.
+func (m *MockRoleRepository) AssignPermissionToRole(ctx context.Context, roleID, permissionID uuid.UUID, grantedBy *uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignPermissionToRole", ctx, roleID, permissionID, grantedBy)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssignPermissionToRole indicates an expected call of AssignPermissionToRole.
+func (mr *MockRoleRepositoryMockRecorder) AssignPermissionToRole(ctx, roleID, permissionID, grantedBy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignPermissionToRole", reflect.TypeOf((*MockRoleRepository)(nil).AssignPermissionToRole), ctx, roleID, permissionID, grantedBy)
+}
+
+// AssignRoleToUser mocks base method.
+func (m *MockRoleRepository) AssignRoleToUser(ctx context.Context, userID, roleID uuid.UUID, assignedBy *uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignRoleToUser", ctx, userID, roleID, assignedBy)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssignRoleToUser indicates an expected call of AssignRoleToUser.
+func (mr *MockRoleRepositoryMockRecorder) AssignRoleToUser(ctx, userID, roleID, assignedBy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignRoleToUser", reflect.TypeOf((*MockRoleRepository)(nil).AssignRoleToUser), ctx, userID, roleID, assignedBy)
+}
+
 // Create mocks base method.
 func (m *MockRoleRepository) Create(ctx context.Context, role *user.Role) error {
 	m.ctrl.T.Helper()
@@ -587,6 +1198,36 @@ func (mr *MockRoleRepositoryMockRecorder) GetByName(ctx, name any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByName", reflect.TypeOf((*MockRoleRepository)(nil).GetByName), ctx, name)
 }
 
+// GetRolePermissions mocks base method.
+func (m *MockRoleRepository) GetRolePermissions(ctx context.Context, roleID uuid.UUID) ([]*user.Permission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRolePermissions", ctx, roleID)
+	ret0, _ := ret[0].([]*user.Permission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRolePermissions indicates an expected call of GetRolePermissions.
+func (mr *MockRoleRepositoryMockRecorder) GetRolePermissions(ctx, roleID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRolePermissions", reflect.TypeOf((*MockRoleRepository)(nil).GetRolePermissions), ctx, roleID)
+}
+
+// GetUserRoles mocks base method.
+func (m *MockRoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]*user.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserRoles", ctx, userID)
+	ret0, _ := ret[0].([]*user.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserRoles indicates an expected call of GetUserRoles.
+func (mr *MockRoleRepositoryMockRecorder) GetUserRoles(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserRoles", reflect.TypeOf((*MockRoleRepository)(nil).GetUserRoles), ctx, userID)
+}
+
 // List mocks base method.
 func (m *MockRoleRepository) List(ctx context.Context, limit, offset int) ([]*user.Role, error) {
 	m.ctrl.T.Helper()
@@ -602,6 +1243,64 @@ func (mr *MockRoleRepositoryMockRecorder) List(ctx, limit, offset any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRoleRepository)(nil).List), ctx, limit, offset)
 }
 
+// ListRolePermissionAssignments mocks base method.
+func (m *MockRoleRepository) ListRolePermissionAssignments(ctx context.Context) ([]*repo.RolePermissionAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRolePermissionAssignments", ctx)
+	ret0, _ := ret[0].([]*repo.RolePermissionAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRolePermissionAssignments indicates an expected call of ListRolePermissionAssignments.
+func (mr *MockRoleRepositoryMockRecorder) ListRolePermissionAssignments(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRolePermissionAssignments", reflect.TypeOf((*MockRoleRepository)(nil).ListRolePermissionAssignments), ctx)
+}
+
+// ListUserRoleAssignments mocks base method.
+func (m *MockRoleRepository) ListUserRoleAssignments(ctx context.Context) ([]*repo.UserRoleAssignment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserRoleAssignments", ctx)
+	ret0, _ := ret[0].([]*repo.UserRoleAssignment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserRoleAssignments indicates an expected call of ListUserRoleAssignments.
+func (mr *MockRoleRepositoryMockRecorder) ListUserRoleAssignments(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserRoleAssignments", reflect.TypeOf((*MockRoleRepository)(nil).ListUserRoleAssignments), ctx)
+}
+
+// RemovePermissionFromRole mocks base method.
+func (m *MockRoleRepository) RemovePermissionFromRole(ctx context.Context, roleID, permissionID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePermissionFromRole", ctx, roleID, permissionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemovePermissionFromRole indicates an expected call of RemovePermissionFromRole.
+func (mr *MockRoleRepositoryMockRecorder) RemovePermissionFromRole(ctx, roleID, permissionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePermissionFromRole", reflect.TypeOf((*MockRoleRepository)(nil).RemovePermissionFromRole), ctx, roleID, permissionID)
+}
+
+// RemoveRoleFromUser mocks base method.
+func (m *MockRoleRepository) RemoveRoleFromUser(ctx context.Context, userID, roleID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRoleFromUser", ctx, userID, roleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveRoleFromUser indicates an expected call of RemoveRoleFromUser.
+func (mr *MockRoleRepositoryMockRecorder) RemoveRoleFromUser(ctx, userID, roleID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRoleFromUser", reflect.TypeOf((*MockRoleRepository)(nil).RemoveRoleFromUser), ctx, userID, roleID)
+}
+
 // Update mocks base method.
 func (m *MockRoleRepository) Update(ctx context.Context, role *user.Role) error {
 	m.ctrl.T.Helper()
@@ -710,6 +1409,21 @@ func (mr *MockCacheMockRecorder) ClusterStatusKey(clusterID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClusterStatusKey", reflect.TypeOf((*MockCache)(nil).ClusterStatusKey), clusterID)
 }
 
+// CompareAndSwap mocks base method.
+func (m *MockCache) CompareAndSwap(ctx context.Context, key string, expected, value any, expiration time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompareAndSwap", ctx, key, expected, value, expiration)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompareAndSwap indicates an expected call of CompareAndSwap.
+func (mr *MockCacheMockRecorder) CompareAndSwap(ctx, key, expected, value, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompareAndSwap", reflect.TypeOf((*MockCache)(nil).CompareAndSwap), ctx, key, expected, value, expiration)
+}
+
 // Delete mocks base method.
 func (m *MockCache) Delete(ctx context.Context, key string) error {
 	m.ctrl.T.Helper()
@@ -766,6 +1480,22 @@ func (mr *MockCacheMockRecorder) Health(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockCache)(nil).Health), ctx)
 }
 
+// IncrementWithExpiry mocks base method.
+func (m *MockCache) IncrementWithExpiry(ctx context.Context, key string, expiration time.Duration) (int64, time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementWithExpiry", ctx, key, expiration)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(time.Duration)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IncrementWithExpiry indicates an expected call of IncrementWithExpiry.
+func (mr *MockCacheMockRecorder) IncrementWithExpiry(ctx, key, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementWithExpiry", reflect.TypeOf((*MockCache)(nil).IncrementWithExpiry), ctx, key, expiration)
+}
+
 // Keys mocks base method.
 func (m *MockCache) Keys(ctx context.Context, pattern string) ([]string, error) {
 	m.ctrl.T.Helper()
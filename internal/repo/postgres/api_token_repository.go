@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/user"
+	"github.com/rizesky/mckmt/internal/utils"
+)
+
+// apiTokenRepository implements repo.APITokenRepository interface
+type apiTokenRepository struct {
+	db *Database
+}
+
+// NewAPITokenRepository creates a new personal access token repository
+func NewAPITokenRepository(db *Database) repo.APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+const apiTokenColumns = `id, user_id, name, token_hash, scopes, cluster_ids, expires_at, last_used_at, revoked_at, created_at`
+
+func (r *apiTokenRepository) Create(ctx context.Context, token *user.APIToken) error {
+	query := `
+		INSERT INTO api_tokens (id, user_id, name, token_hash, scopes, cluster_ids, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	if token.Scopes == nil {
+		token.Scopes = []string{}
+	}
+	if token.ClusterIDs == nil {
+		token.ClusterIDs = []uuid.UUID{}
+	}
+	token.CreatedAt = time.Now().UTC()
+
+	_, err := r.db.pool.Exec(ctx, query,
+		token.ID,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		token.Scopes,
+		token.ClusterIDs,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	if err != nil {
+		return utils.ErrCreate("api token", err)
+	}
+
+	return nil
+}
+
+func (r *apiTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*user.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE token_hash = $1`
+
+	token, err := scanAPIToken(r.db.pool.QueryRow(ctx, query, tokenHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrNotFound
+		}
+		return nil, utils.ErrGet("api token", err)
+	}
+
+	return token, nil
+}
+
+func (r *apiTokenRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*user.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*user.APIToken, 0)
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (r *apiTokenRepository) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	query := `UPDATE api_tokens SET revoked_at = $3 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	tag, err := r.db.pool.Exec(ctx, query, id, userID, time.Now().UTC())
+	if err != nil {
+		return utils.ErrUpdate("api token", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *apiTokenRepository) UpdateLastUsed(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.pool.Exec(ctx, `UPDATE api_tokens SET last_used_at = $2 WHERE id = $1`, id, time.Now().UTC())
+	if err != nil {
+		return utils.ErrUpdate("api token", err)
+	}
+	return nil
+}
+
+// scanAPIToken reads a token from a row holding apiTokenColumns
+func scanAPIToken(row pgx.Row) (*user.APIToken, error) {
+	var token user.APIToken
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.TokenHash,
+		&token.Scopes,
+		&token.ClusterIDs,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -23,8 +23,8 @@ func (r *auditLogRepository) Create(ctx context.Context, log *repo.AuditLog) err
 	defer cancel()
 
 	query := `
-		INSERT INTO audit_logs (id, user_id, action, resource_type, resource_id, request_payload, response_payload, ip_address, user_agent, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO audit_logs (id, user_id, action, resource_type, resource_id, method, path, status_code, request_payload, response_payload, ip_address, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 	_, err := r.db.pool.Exec(ctx, query,
 		log.ID,
@@ -32,6 +32,9 @@ func (r *auditLogRepository) Create(ctx context.Context, log *repo.AuditLog) err
 		log.Action,
 		log.ResourceType,
 		log.ResourceID,
+		log.Method,
+		log.Path,
+		log.StatusCode,
 		log.RequestPayload,
 		log.ResponsePayload,
 		log.IPAddress,
@@ -46,7 +49,7 @@ func (r *auditLogRepository) List(ctx context.Context, userID string, limit, off
 	defer cancel()
 
 	query := `
-		SELECT id, user_id, action, resource_type, resource_id, request_payload, response_payload, ip_address, user_agent, created_at
+		SELECT id, user_id, action, resource_type, resource_id, method, path, status_code, request_payload, response_payload, ip_address, user_agent, created_at
 		FROM audit_logs
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -67,6 +70,9 @@ func (r *auditLogRepository) List(ctx context.Context, userID string, limit, off
 			&log.Action,
 			&log.ResourceType,
 			&log.ResourceID,
+			&log.Method,
+			&log.Path,
+			&log.StatusCode,
 			&log.RequestPayload,
 			&log.ResponsePayload,
 			&log.IPAddress,
@@ -86,7 +92,7 @@ func (r *auditLogRepository) ListByResource(ctx context.Context, resourceType, r
 	defer cancel()
 
 	query := `
-		SELECT id, user_id, action, resource_type, resource_id, request_payload, response_payload, ip_address, user_agent, created_at
+		SELECT id, user_id, action, resource_type, resource_id, method, path, status_code, request_payload, response_payload, ip_address, user_agent, created_at
 		FROM audit_logs
 		WHERE resource_type = $1 AND resource_id = $2
 		ORDER BY created_at DESC
@@ -107,6 +113,9 @@ func (r *auditLogRepository) ListByResource(ctx context.Context, resourceType, r
 			&log.Action,
 			&log.ResourceType,
 			&log.ResourceID,
+			&log.Method,
+			&log.Path,
+			&log.StatusCode,
 			&log.RequestPayload,
 			&log.ResponsePayload,
 			&log.IPAddress,
@@ -129,7 +138,7 @@ func (r *auditLogRepository) ListAll(ctx context.Context, limit, offset int) ([]
 	defer cancel()
 
 	query := `
-		SELECT id, user_id, action, resource_type, resource_id, request_payload, response_payload, ip_address, user_agent, created_at
+		SELECT id, user_id, action, resource_type, resource_id, method, path, status_code, request_payload, response_payload, ip_address, user_agent, created_at
 		FROM audit_logs
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
@@ -149,6 +158,9 @@ func (r *auditLogRepository) ListAll(ctx context.Context, limit, offset int) ([]
 			&log.Action,
 			&log.ResourceType,
 			&log.ResourceID,
+			&log.Method,
+			&log.Path,
+			&log.StatusCode,
 			&log.RequestPayload,
 			&log.ResponsePayload,
 			&log.IPAddress,
@@ -169,7 +181,7 @@ func (r *auditLogRepository) ListByAction(ctx context.Context, action string, li
 	defer cancel()
 
 	query := `
-		SELECT id, user_id, action, resource_type, resource_id, request_payload, response_payload, ip_address, user_agent, created_at
+		SELECT id, user_id, action, resource_type, resource_id, method, path, status_code, request_payload, response_payload, ip_address, user_agent, created_at
 		FROM audit_logs
 		WHERE action = $1
 		ORDER BY created_at DESC
@@ -190,6 +202,9 @@ func (r *auditLogRepository) ListByAction(ctx context.Context, action string, li
 			&log.Action,
 			&log.ResourceType,
 			&log.ResourceID,
+			&log.Method,
+			&log.Path,
+			&log.StatusCode,
 			&log.RequestPayload,
 			&log.ResponsePayload,
 			&log.IPAddress,
@@ -210,7 +225,7 @@ func (r *auditLogRepository) ListByDateRange(ctx context.Context, startDate, end
 	defer cancel()
 
 	query := `
-		SELECT id, user_id, action, resource_type, resource_id, request_payload, response_payload, ip_address, user_agent, created_at
+		SELECT id, user_id, action, resource_type, resource_id, method, path, status_code, request_payload, response_payload, ip_address, user_agent, created_at
 		FROM audit_logs
 		WHERE created_at >= $1 AND created_at <= $2
 		ORDER BY created_at DESC
@@ -231,6 +246,9 @@ func (r *auditLogRepository) ListByDateRange(ctx context.Context, startDate, end
 			&log.Action,
 			&log.ResourceType,
 			&log.ResourceID,
+			&log.Method,
+			&log.Path,
+			&log.StatusCode,
 			&log.RequestPayload,
 			&log.ResponsePayload,
 			&log.IPAddress,
@@ -89,6 +89,11 @@ func (r *cachedClusterRepository) List(ctx context.Context, limit, offset int) (
 	return r.repo.List(ctx, limit, offset)
 }
 
+func (r *cachedClusterRepository) CountByStatus(ctx context.Context) (map[string]int, error) {
+	// Counts are used to reconcile metrics and must reflect the database
+	return r.repo.CountByStatus(ctx)
+}
+
 func (r *cachedClusterRepository) Update(ctx context.Context, cluster *repo.Cluster) error {
 	err := r.repo.Update(ctx, cluster)
 	if err != nil {
@@ -134,6 +139,21 @@ func (r *cachedClusterRepository) UpdateStatus(ctx context.Context, id uuid.UUID
 	return nil
 }
 
+func (r *cachedClusterRepository) SetQuarantine(ctx context.Context, id uuid.UUID, quarantinedAt *time.Time, reason string) error {
+	err := r.repo.SetQuarantine(ctx, id, quarantinedAt, reason)
+	if err != nil {
+		return err
+	}
+
+	// Invalidate cache to force refresh
+	key := r.cache.ClusterKey(id.String())
+	if err := r.cache.Delete(ctx, key); err != nil {
+		r.logger.Warn("Failed to invalidate cluster cache", zap.Error(err))
+	}
+
+	return nil
+}
+
 func (r *cachedClusterRepository) UpdateLastSeen(ctx context.Context, id uuid.UUID) error {
 	err := r.repo.UpdateLastSeen(ctx, id)
 	if err != nil {
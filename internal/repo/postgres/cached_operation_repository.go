@@ -72,6 +72,19 @@ func (r *cachedOperationRepository) ListByCluster(ctx context.Context, clusterID
 	return r.repo.ListByCluster(ctx, clusterID, limit, offset)
 }
 
+func (r *cachedOperationRepository) CountByCluster(ctx context.Context, clusterID uuid.UUID) (int, error) {
+	return r.repo.CountByCluster(ctx, clusterID)
+}
+
+func (r *cachedOperationRepository) EachByCluster(ctx context.Context, clusterID uuid.UUID, fn func(*repo.Operation) error) error {
+	return r.repo.EachByCluster(ctx, clusterID, fn)
+}
+
+func (r *cachedOperationRepository) ListByStatus(ctx context.Context, status string, limit, offset int) ([]*repo.Operation, error) {
+	// Status listings change with every transition, so they aren't cached either
+	return r.repo.ListByStatus(ctx, status, limit, offset)
+}
+
 func (r *cachedOperationRepository) Update(ctx context.Context, operation *repo.Operation) error {
 	err := r.repo.Update(ctx, operation)
 	if err != nil {
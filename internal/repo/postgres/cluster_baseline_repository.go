@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/utils"
+)
+
+// clusterBaselineRepository implements repo.ClusterBaselineRepository interface
+type clusterBaselineRepository struct {
+	db *Database
+}
+
+// NewClusterBaselineRepository creates a new cluster baseline repository
+func NewClusterBaselineRepository(db *Database) repo.ClusterBaselineRepository {
+	return &clusterBaselineRepository{db: db}
+}
+
+func (r *clusterBaselineRepository) Get(ctx context.Context, clusterID uuid.UUID) (*repo.ClusterBaseline, error) {
+	query := `SELECT cluster_id, resources, updated_at FROM cluster_baselines WHERE cluster_id = $1`
+
+	baseline := &repo.ClusterBaseline{}
+	err := r.db.pool.QueryRow(ctx, query, clusterID).Scan(&baseline.ClusterID, &baseline.Resources, &baseline.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get cluster baseline: %w", err)
+	}
+
+	return baseline, nil
+}
+
+func (r *clusterBaselineRepository) Set(ctx context.Context, baseline *repo.ClusterBaseline) error {
+	query := `
+		INSERT INTO cluster_baselines (cluster_id, resources, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (cluster_id)
+		DO UPDATE SET resources = EXCLUDED.resources, updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := r.db.pool.Exec(ctx, query, baseline.ClusterID, baseline.Resources, baseline.UpdatedAt); err != nil {
+		return utils.ErrUpdate("cluster baseline", err)
+	}
+
+	return nil
+}
+
+func (r *clusterBaselineRepository) Delete(ctx context.Context, clusterID uuid.UUID) error {
+	_, err := r.db.pool.Exec(ctx, `DELETE FROM cluster_baselines WHERE cluster_id = $1`, clusterID)
+	return err
+}
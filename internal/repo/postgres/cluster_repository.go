@@ -2,12 +2,18 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rizesky/mckmt/internal/repo"
 )
 
+// uniqueViolation is the SQLSTATE of a unique constraint violation
+const uniqueViolation = "23505"
+
 // clusterRepository implements repo.ClusterRepository interface
 type clusterRepository struct {
 	db *Database
@@ -20,25 +26,35 @@ func NewClusterRepository(db *Database) repo.ClusterRepository {
 
 func (r *clusterRepository) Create(ctx context.Context, cluster *repo.Cluster) error {
 	query := `
-		INSERT INTO clusters (id, name, description, labels, encrypted_credentials, status, last_seen_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO clusters (id, name, description, labels, capabilities, encrypted_credentials, status, last_seen_at, created_at, updated_at,
+		                      quarantined_at, quarantine_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 	_, err := r.db.pool.Exec(ctx, query,
-		cluster.ID, cluster.Name, cluster.Description, cluster.Labels, cluster.EncryptedCredentials,
-		cluster.Status, cluster.LastSeenAt, cluster.CreatedAt, cluster.UpdatedAt)
+		cluster.ID, cluster.Name, cluster.Description, cluster.Labels, cluster.Capabilities, cluster.EncryptedCredentials,
+		cluster.Status, cluster.LastSeenAt, cluster.CreatedAt, cluster.UpdatedAt, cluster.QuarantinedAt, cluster.QuarantineReason)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return repo.ErrAlreadyExists
+	}
 	return err
 }
 
 func (r *clusterRepository) GetByID(ctx context.Context, id uuid.UUID) (*repo.Cluster, error) {
 	query := `
-		SELECT id, name, description, labels, encrypted_credentials, status, last_seen_at, created_at, updated_at
+		SELECT id, name, description, labels, capabilities, encrypted_credentials, status, last_seen_at, created_at, updated_at,
+		       quarantined_at, quarantine_reason
 		FROM clusters WHERE id = $1
 	`
 	cluster := &repo.Cluster{}
 	err := r.db.pool.QueryRow(ctx, query, id).Scan(
-		&cluster.ID, &cluster.Name, &cluster.Description, &cluster.Labels, &cluster.EncryptedCredentials,
-		&cluster.Status, &cluster.LastSeenAt, &cluster.CreatedAt, &cluster.UpdatedAt)
+		&cluster.ID, &cluster.Name, &cluster.Description, &cluster.Labels, &cluster.Capabilities, &cluster.EncryptedCredentials,
+		&cluster.Status, &cluster.LastSeenAt, &cluster.CreatedAt, &cluster.UpdatedAt,
+		&cluster.QuarantinedAt, &cluster.QuarantineReason)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrNotFound
+		}
 		return nil, err
 	}
 	return cluster, nil
@@ -46,13 +62,15 @@ func (r *clusterRepository) GetByID(ctx context.Context, id uuid.UUID) (*repo.Cl
 
 func (r *clusterRepository) GetByName(ctx context.Context, name string) (*repo.Cluster, error) {
 	query := `
-		SELECT id, name, description, labels, encrypted_credentials, status, last_seen_at, created_at, updated_at
+		SELECT id, name, description, labels, capabilities, encrypted_credentials, status, last_seen_at, created_at, updated_at,
+		       quarantined_at, quarantine_reason
 		FROM clusters WHERE name = $1
 	`
 	cluster := &repo.Cluster{}
 	err := r.db.pool.QueryRow(ctx, query, name).Scan(
-		&cluster.ID, &cluster.Name, &cluster.Description, &cluster.Labels, &cluster.EncryptedCredentials,
-		&cluster.Status, &cluster.LastSeenAt, &cluster.CreatedAt, &cluster.UpdatedAt)
+		&cluster.ID, &cluster.Name, &cluster.Description, &cluster.Labels, &cluster.Capabilities, &cluster.EncryptedCredentials,
+		&cluster.Status, &cluster.LastSeenAt, &cluster.CreatedAt, &cluster.UpdatedAt,
+		&cluster.QuarantinedAt, &cluster.QuarantineReason)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +79,8 @@ func (r *clusterRepository) GetByName(ctx context.Context, name string) (*repo.C
 
 func (r *clusterRepository) List(ctx context.Context, limit, offset int) ([]*repo.Cluster, error) {
 	query := `
-		SELECT id, name, description, labels, encrypted_credentials, status, last_seen_at, created_at, updated_at
+		SELECT id, name, description, labels, capabilities, encrypted_credentials, status, last_seen_at, created_at, updated_at,
+		       quarantined_at, quarantine_reason
 		FROM clusters ORDER BY created_at DESC LIMIT $1 OFFSET $2
 	`
 	rows, err := r.db.pool.Query(ctx, query, limit, offset)
@@ -74,8 +93,9 @@ func (r *clusterRepository) List(ctx context.Context, limit, offset int) ([]*rep
 	for rows.Next() {
 		cluster := &repo.Cluster{}
 		err := rows.Scan(
-			&cluster.ID, &cluster.Name, &cluster.Description, &cluster.Labels, &cluster.EncryptedCredentials,
-			&cluster.Status, &cluster.LastSeenAt, &cluster.CreatedAt, &cluster.UpdatedAt)
+			&cluster.ID, &cluster.Name, &cluster.Description, &cluster.Labels, &cluster.Capabilities, &cluster.EncryptedCredentials,
+			&cluster.Status, &cluster.LastSeenAt, &cluster.CreatedAt, &cluster.UpdatedAt,
+			&cluster.QuarantinedAt, &cluster.QuarantineReason)
 		if err != nil {
 			return nil, err
 		}
@@ -87,12 +107,12 @@ func (r *clusterRepository) List(ctx context.Context, limit, offset int) ([]*rep
 func (r *clusterRepository) Update(ctx context.Context, cluster *repo.Cluster) error {
 	query := `
 		UPDATE clusters 
-		SET name = $2, description = $3, labels = $4, encrypted_credentials = $5, status = $6, 
-		    last_seen_at = $7, updated_at = $8
+		SET name = $2, description = $3, labels = $4, capabilities = $5, encrypted_credentials = $6, status = $7, 
+		    last_seen_at = $8, updated_at = $9
 		WHERE id = $1
 	`
 	_, err := r.db.pool.Exec(ctx, query,
-		cluster.ID, cluster.Name, cluster.Description, cluster.Labels, cluster.EncryptedCredentials,
+		cluster.ID, cluster.Name, cluster.Description, cluster.Labels, cluster.Capabilities, cluster.EncryptedCredentials,
 		cluster.Status, cluster.LastSeenAt, cluster.UpdatedAt)
 	return err
 }
@@ -114,3 +134,35 @@ func (r *clusterRepository) UpdateLastSeen(ctx context.Context, id uuid.UUID) er
 	_, err := r.db.pool.Exec(ctx, query, id, time.Now().UTC(), time.Now().UTC())
 	return err
 }
+
+func (r *clusterRepository) SetQuarantine(ctx context.Context, id uuid.UUID, quarantinedAt *time.Time, reason string) error {
+	query := `UPDATE clusters SET quarantined_at = $2, quarantine_reason = $3, updated_at = $4 WHERE id = $1`
+	tag, err := r.db.pool.Exec(ctx, query, id, quarantinedAt, reason, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return repo.ErrNotFound
+	}
+	return nil
+}
+
+func (r *clusterRepository) CountByStatus(ctx context.Context) (map[string]int, error) {
+	query := `SELECT status, COUNT(*) FROM clusters GROUP BY status`
+	rows, err := r.db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/utils"
+)
+
+// deadLetterRepository implements repo.DeadLetterRepository interface
+type deadLetterRepository struct {
+	db *Database
+}
+
+// NewDeadLetterRepository creates a new operation dead letter repository
+func NewDeadLetterRepository(db *Database) repo.DeadLetterRepository {
+	return &deadLetterRepository{db: db}
+}
+
+func (r *deadLetterRepository) Create(ctx context.Context, deadLetter *repo.DeadLetter) error {
+	ctx, cancel := utils.WithDefaultTimeout(ctx)
+	defer cancel()
+
+	if deadLetter.DeadLetteredAt.IsZero() {
+		deadLetter.DeadLetteredAt = time.Now().UTC()
+	}
+
+	query := `
+		INSERT INTO operation_dead_letters (operation_id, cluster_id, type, reason, attempts, dead_lettered_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (operation_id)
+		DO UPDATE SET reason = EXCLUDED.reason, attempts = EXCLUDED.attempts, dead_lettered_at = EXCLUDED.dead_lettered_at
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		deadLetter.OperationID,
+		deadLetter.ClusterID,
+		deadLetter.Type,
+		deadLetter.Reason,
+		deadLetter.Attempts,
+		deadLetter.DeadLetteredAt,
+	)
+	if err != nil {
+		return utils.ErrCreate("operation dead letter", err)
+	}
+
+	return nil
+}
+
+func (r *deadLetterRepository) Get(ctx context.Context, operationID uuid.UUID) (*repo.DeadLetter, error) {
+	ctx, cancel := utils.WithDefaultTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT operation_id, cluster_id, type, reason, attempts, dead_lettered_at
+		FROM operation_dead_letters
+		WHERE operation_id = $1
+	`
+
+	deadLetter, err := scanDeadLetter(r.db.pool.QueryRow(ctx, query, operationID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get operation dead letter: %w", err)
+	}
+
+	return deadLetter, nil
+}
+
+func (r *deadLetterRepository) List(ctx context.Context, limit, offset int) ([]*repo.DeadLetter, error) {
+	ctx, cancel := utils.WithDefaultTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT operation_id, cluster_id, type, reason, attempts, dead_lettered_at
+		FROM operation_dead_letters
+		ORDER BY dead_lettered_at DESC, operation_id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.pool.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operation dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	deadLetters := make([]*repo.DeadLetter, 0)
+	for rows.Next() {
+		deadLetter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan operation dead letter: %w", err)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating operation dead letters: %w", err)
+	}
+
+	return deadLetters, nil
+}
+
+func (r *deadLetterRepository) Count(ctx context.Context) (int, error) {
+	ctx, cancel := utils.WithDefaultTimeout(ctx)
+	defer cancel()
+
+	var count int
+	if err := r.db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM operation_dead_letters`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count operation dead letters: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *deadLetterRepository) Delete(ctx context.Context, operationID uuid.UUID) error {
+	ctx, cancel := utils.WithDefaultTimeout(ctx)
+	defer cancel()
+
+	if _, err := r.db.pool.Exec(ctx, `DELETE FROM operation_dead_letters WHERE operation_id = $1`, operationID); err != nil {
+		return fmt.Errorf("failed to delete operation dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// scanDeadLetter scans a dead letter row
+func scanDeadLetter(row pgx.Row) (*repo.DeadLetter, error) {
+	var deadLetter repo.DeadLetter
+	if err := row.Scan(
+		&deadLetter.OperationID,
+		&deadLetter.ClusterID,
+		&deadLetter.Type,
+		&deadLetter.Reason,
+		&deadLetter.Attempts,
+		&deadLetter.DeadLetteredAt,
+	); err != nil {
+		return nil, err
+	}
+	return &deadLetter, nil
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/utils"
+)
+
+// logRepository implements repo.LogRepository interface
+type logRepository struct {
+	db *Database
+}
+
+// NewLogRepository creates a new agent log repository
+func NewLogRepository(db *Database) repo.LogRepository {
+	return &logRepository{db: db}
+}
+
+func (r *logRepository) CreateBatch(ctx context.Context, entries []*repo.LogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ctx, cancel := utils.WithDefaultTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO cluster_logs (id, cluster_id, level, message, source, fields, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	batch := &pgx.Batch{}
+	for _, entry := range entries {
+		if entry.ID == uuid.Nil {
+			entry.ID = uuid.New()
+		}
+		if entry.Timestamp.IsZero() {
+			entry.Timestamp = time.Now().UTC()
+		}
+		if entry.Fields == nil {
+			entry.Fields = repo.Labels{}
+		}
+		batch.Queue(query, entry.ID, entry.ClusterID, entry.Level, entry.Message, entry.Source, entry.Fields, entry.Timestamp)
+	}
+
+	if err := r.db.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return utils.ErrCreate("cluster logs", err)
+	}
+
+	return nil
+}
+
+func (r *logRepository) List(ctx context.Context, clusterID uuid.UUID, filter repo.LogFilter, limit, offset int) ([]*repo.LogEntry, error) {
+	ctx, cancel := utils.WithDefaultTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, cluster_id, level, message, source, fields, timestamp
+		FROM cluster_logs
+		WHERE cluster_id = $1 AND (cardinality($2::text[]) = 0 OR level = ANY($2))
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.pool.Query(ctx, query, clusterID, levels(filter), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster logs: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*repo.LogEntry, 0)
+	for rows.Next() {
+		var entry repo.LogEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.ClusterID,
+			&entry.Level,
+			&entry.Message,
+			&entry.Source,
+			&entry.Fields,
+			&entry.Timestamp,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan cluster log: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cluster logs: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *logRepository) Count(ctx context.Context, clusterID uuid.UUID, filter repo.LogFilter) (int, error) {
+	ctx, cancel := utils.WithDefaultTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COUNT(*)
+		FROM cluster_logs
+		WHERE cluster_id = $1 AND (cardinality($2::text[]) = 0 OR level = ANY($2))
+	`
+
+	var count int
+	if err := r.db.pool.QueryRow(ctx, query, clusterID, levels(filter)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count cluster logs: %w", err)
+	}
+
+	return count, nil
+}
+
+// levels returns the levels filter matches, never nil so it is sent as an empty array
+func levels(filter repo.LogFilter) []string {
+	if filter.Levels == nil {
+		return []string{}
+	}
+	return filter.Levels
+}
@@ -52,6 +52,9 @@ type AuditLog struct {
 	Action          string    `json:"action" db:"action"`
 	ResourceType    string    `json:"resource_type" db:"resource_type"`
 	ResourceID      string    `json:"resource_id" db:"resource_id"`
+	Method          string    `json:"method,omitempty" db:"method"`
+	Path            string    `json:"path,omitempty" db:"path"`
+	StatusCode      int       `json:"status_code,omitempty" db:"status_code"`
 	RequestPayload  *Payload  `json:"request_payload,omitempty" db:"request_payload"`
 	ResponsePayload *Payload  `json:"response_payload,omitempty" db:"response_payload"`
 	IPAddress       string    `json:"ip_address" db:"ip_address"`
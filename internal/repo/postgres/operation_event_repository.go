@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/utils"
+)
+
+// operationEventRepository implements repo.OperationEventRepository interface
+type operationEventRepository struct {
+	db *Database
+}
+
+// NewOperationEventRepository creates a new operation event repository
+func NewOperationEventRepository(db *Database) repo.OperationEventRepository {
+	return &operationEventRepository{db: db}
+}
+
+// execer is implemented by both the connection pool and transactions
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func (r *operationEventRepository) Append(ctx context.Context, event *repo.OperationEvent) error {
+	return insertOperationEvent(ctx, r.db.pool, event)
+}
+
+// insertOperationEvent writes an event, so status changes can be recorded in the same transaction
+func insertOperationEvent(ctx context.Context, db execer, event *repo.OperationEvent) error {
+	query := `
+		INSERT INTO operation_events (id, operation_id, from_status, to_status, reason, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+	`
+
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := db.Exec(ctx, query,
+		event.ID,
+		event.OperationID,
+		event.FromStatus,
+		event.ToStatus,
+		event.Reason,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return utils.ErrCreate("operation event", err)
+	}
+
+	return nil
+}
+
+func (r *operationEventRepository) ListByOperation(ctx context.Context, operationID uuid.UUID) ([]*repo.OperationEvent, error) {
+	query := `
+		SELECT id, operation_id, COALESCE(from_status, ''), to_status, reason, created_at
+		FROM operation_events
+		WHERE operation_id = $1
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.pool.Query(ctx, query, operationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operation events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*repo.OperationEvent, 0)
+	for rows.Next() {
+		var event repo.OperationEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.OperationID,
+			&event.FromStatus,
+			&event.ToStatus,
+			&event.Reason,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan operation event: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating operation events: %w", err)
+	}
+
+	return events, nil
+}
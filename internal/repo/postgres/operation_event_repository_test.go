@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestOperation inserts a cluster and a queued operation for event tests
+func createTestOperation(t *testing.T, db *Database) *repo.Operation {
+	t.Helper()
+	ctx := context.Background()
+
+	cluster := &repo.Cluster{
+		ID:        uuid.New(),
+		Name:      "events-" + uuid.NewString(),
+		Labels:    repo.Labels{},
+		Status:    "connected",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	require.NoError(t, NewClusterRepository(db).Create(ctx, cluster))
+
+	operation := &repo.Operation{
+		ID:        uuid.New(),
+		ClusterID: cluster.ID,
+		Type:      "apply",
+		Status:    "queued",
+		Payload:   repo.Payload{},
+	}
+	require.NoError(t, NewOperationRepository(db).Create(ctx, operation))
+
+	return operation
+}
+
+func TestOperationEventRepository_AppendAndList(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	events := NewOperationEventRepository(db)
+	ctx := context.Background()
+	operation := createTestOperation(t, db)
+
+	require.NoError(t, events.Append(ctx, &repo.OperationEvent{
+		OperationID: operation.ID,
+		FromStatus:  "failed",
+		ToStatus:    "queued",
+		Reason:      "retried",
+	}))
+
+	history, err := events.ListByOperation(ctx, operation.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	// Creating the operation records its initial state
+	assert.Equal(t, "", history[0].FromStatus)
+	assert.Equal(t, "queued", history[0].ToStatus)
+	assert.Equal(t, "created", history[0].Reason)
+
+	assert.Equal(t, "failed", history[1].FromStatus)
+	assert.Equal(t, "queued", history[1].ToStatus)
+	assert.Equal(t, "retried", history[1].Reason)
+	assert.False(t, history[1].CreatedAt.Before(history[0].CreatedAt))
+}
+
+func TestOperationEventRepository_RecordsStatusTransitions(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	operations := NewOperationRepository(db)
+	events := NewOperationEventRepository(db)
+	ctx := context.Background()
+	operation := createTestOperation(t, db)
+
+	require.NoError(t, operations.SetStarted(ctx, operation.ID))
+	require.NoError(t, operations.UpdateStatus(ctx, operation.ID, "failed"))
+	// Setting the same status again is not a transition
+	require.NoError(t, operations.UpdateStatus(ctx, operation.ID, "failed"))
+
+	history, err := events.ListByOperation(ctx, operation.ID)
+	require.NoError(t, err)
+
+	transitions := make([]string, len(history))
+	for i, event := range history {
+		transitions[i] = event.FromStatus + "->" + event.ToStatus
+	}
+	assert.Equal(t, []string{"->queued", "queued->running", "running->failed"}, transitions)
+	assert.Equal(t, "started", history[1].Reason)
+}
+
+func TestOperationEventRepository_ListUnknownOperation(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	history, err := NewOperationEventRepository(db).ListByOperation(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
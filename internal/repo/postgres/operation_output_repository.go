@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rizesky/mckmt/internal/repo"
+	"github.com/rizesky/mckmt/internal/utils"
+)
+
+// operationOutputRepository implements repo.OperationOutputRepository interface
+type operationOutputRepository struct {
+	db *Database
+}
+
+// NewOperationOutputRepository creates a new operation output repository
+func NewOperationOutputRepository(db *Database) repo.OperationOutputRepository {
+	return &operationOutputRepository{db: db}
+}
+
+func (r *operationOutputRepository) Append(ctx context.Context, operationID uuid.UUID, data []byte) error {
+	query := `
+		INSERT INTO operation_outputs (operation_id, output, updated_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (operation_id)
+		DO UPDATE SET output = operation_outputs.output || EXCLUDED.output, updated_at = now()
+	`
+
+	if _, err := r.db.pool.Exec(ctx, query, operationID, data); err != nil {
+		return utils.ErrUpdate("operation output", err)
+	}
+
+	return nil
+}
+
+func (r *operationOutputRepository) Read(ctx context.Context, operationID uuid.UUID, offset int64, limit int) ([]byte, int64, error) {
+	query := `
+		SELECT substring(output FROM $2::int + 1 FOR $3::int), octet_length(output)
+		FROM operation_outputs
+		WHERE operation_id = $1
+	`
+
+	var data []byte
+	var size int64
+	err := r.db.pool.QueryRow(ctx, query, operationID, offset, limit).Scan(&data, &size)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The operation has not produced any output yet
+		return []byte{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read operation output: %w", err)
+	}
+
+	return data, size, nil
+}
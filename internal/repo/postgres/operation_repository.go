@@ -3,10 +3,12 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rizesky/mckmt/internal/repo"
 	"github.com/rizesky/mckmt/internal/utils"
 )
@@ -23,8 +25,8 @@ func NewOperationRepository(db *Database) repo.OperationRepository {
 
 func (r *operationRepository) Create(ctx context.Context, operation *repo.Operation) error {
 	query := `
-		INSERT INTO operations (id, cluster_id, type, status, payload, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO operations (id, cluster_id, type, status, payload, initiated_via, reason, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
 	payloadJSON, err := json.Marshal(operation.Payload)
@@ -33,32 +35,40 @@ func (r *operationRepository) Create(ctx context.Context, operation *repo.Operat
 	}
 
 	now := time.Now().UTC()
-	_, err = r.db.pool.Exec(ctx, query,
-		operation.ID,
-		operation.ClusterID,
-		operation.Type,
-		operation.Status,
-		string(payloadJSON),
-		now,
-		now,
-	)
-
-	if err != nil {
-		return utils.ErrCreate("operation", err)
-	}
+	return r.db.Transaction(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query,
+			operation.ID,
+			operation.ClusterID,
+			operation.Type,
+			operation.Status,
+			string(payloadJSON),
+			operation.InitiatedVia,
+			operation.Reason,
+			now,
+			now,
+		)
+		if err != nil {
+			return utils.ErrCreate("operation", err)
+		}
 
-	return nil
+		return insertOperationEvent(ctx, tx, &repo.OperationEvent{
+			OperationID: operation.ID,
+			ToStatus:    operation.Status,
+			Reason:      "created",
+			CreatedAt:   now,
+		})
+	})
 }
 
 func (r *operationRepository) GetByID(ctx context.Context, id uuid.UUID) (*repo.Operation, error) {
 	query := `
-		SELECT id, cluster_id, type, status, payload, result, started_at, finished_at, created_at, updated_at
+		SELECT id, cluster_id, type, status, payload, result, started_at, finished_at, created_at, updated_at, initiated_via, reason
 		FROM operations
 		WHERE id = $1
 	`
 
 	var operation repo.Operation
-	var payloadJSON, resultJSON string
+	var payloadJSON, resultJSON []byte
 
 	err := r.db.pool.QueryRow(ctx, query, id).Scan(
 		&operation.ID,
@@ -71,32 +81,25 @@ func (r *operationRepository) GetByID(ctx context.Context, id uuid.UUID) (*repo.
 		&operation.FinishedAt,
 		&operation.CreatedAt,
 		&operation.UpdatedAt,
+		&operation.InitiatedVia,
+		&operation.Reason,
 	)
 
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.ErrNotFound
+		}
 		return nil, fmt.Errorf("failed to get operation: %w", err)
 	}
 
-	// Parse payload
-	if err := json.Unmarshal([]byte(payloadJSON), &operation.Payload); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
-	}
-
-	// Parse result if exists
-	if resultJSON != "" {
-		var resultPayload repo.Payload
-		if err := json.Unmarshal([]byte(resultJSON), &resultPayload); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal result: %w", err)
-		}
-		operation.Result = &resultPayload
-	}
+	decodeOperationJSON(&operation, payloadJSON, resultJSON)
 
 	return &operation, nil
 }
 
 func (r *operationRepository) ListByCluster(ctx context.Context, clusterID uuid.UUID, limit, offset int) ([]*repo.Operation, error) {
 	query := `
-		SELECT id, cluster_id, type, status, payload, result, started_at, finished_at, created_at, updated_at
+		SELECT id, cluster_id, type, status, payload, result, started_at, finished_at, created_at, updated_at, initiated_via, reason
 		FROM operations
 		WHERE cluster_id = $1
 		ORDER BY created_at DESC
@@ -109,42 +112,101 @@ func (r *operationRepository) ListByCluster(ctx context.Context, clusterID uuid.
 	}
 	defer rows.Close()
 
-	operations := make([]*repo.Operation, 0)
+	return scanOperations(rows)
+}
+
+func (r *operationRepository) EachByCluster(ctx context.Context, clusterID uuid.UUID, fn func(*repo.Operation) error) error {
+	query := `
+		SELECT id, cluster_id, type, status, payload, result, started_at, finished_at, created_at, updated_at, initiated_via, reason
+		FROM operations
+		WHERE cluster_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.pool.Query(ctx, query, clusterID)
+	if err != nil {
+		return fmt.Errorf("failed to list operations: %w", err)
+	}
+	defer rows.Close()
+
 	for rows.Next() {
-		var operation repo.Operation
-		var payloadJSON, resultJSON string
-
-		err := rows.Scan(
-			&operation.ID,
-			&operation.ClusterID,
-			&operation.Type,
-			&operation.Status,
-			&payloadJSON,
-			&resultJSON,
-			&operation.StartedAt,
-			&operation.FinishedAt,
-			&operation.CreatedAt,
-			&operation.UpdatedAt,
-		)
+		operation, err := scanOperation(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan operation: %w", err)
+			return err
 		}
+		if err := fn(operation); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating operations: %w", err)
+	}
+
+	return nil
+}
+
+func (r *operationRepository) CountByCluster(ctx context.Context, clusterID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM operations WHERE cluster_id = $1`, clusterID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count operations: %w", err)
+	}
+	return count, nil
+}
+
+// ListByStatus lists operations with the given status, oldest first
+func (r *operationRepository) ListByStatus(ctx context.Context, status string, limit, offset int) ([]*repo.Operation, error) {
+	query := `
+		SELECT id, cluster_id, type, status, payload, result, started_at, finished_at, created_at, updated_at, initiated_via, reason
+		FROM operations
+		WHERE status = $1
+		ORDER BY created_at ASC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.pool.Query(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+	defer rows.Close()
 
-		// Parse payload
-		if err := json.Unmarshal([]byte(payloadJSON), &operation.Payload); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+	return scanOperations(rows)
+}
+
+// decodeOperationJSON parses the stored payload and result of an operation.
+// Rows that don't decode, such as legacy rows written in another shape, keep
+// their raw JSON and are flagged with ParseError instead of failing the read,
+// so a single bad row can't make an operation or a listing unreadable.
+func decodeOperationJSON(operation *repo.Operation, payloadJSON, resultJSON []byte) {
+	if len(payloadJSON) > 0 {
+		if err := json.Unmarshal(payloadJSON, &operation.Payload); err != nil {
+			operation.Payload = repo.Payload{}
+			operation.RawPayload = string(payloadJSON)
+			operation.ParseError = true
 		}
+	}
 
-		// Parse result if exists
-		if resultJSON != "" {
-			var resultPayload repo.Payload
-			if err := json.Unmarshal([]byte(resultJSON), &resultPayload); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal result: %w", err)
-			}
-			operation.Result = &resultPayload
+	if len(resultJSON) > 0 {
+		var resultPayload repo.Payload
+		if err := json.Unmarshal(resultJSON, &resultPayload); err != nil {
+			operation.RawResult = string(resultJSON)
+			operation.ParseError = true
+			return
 		}
+		operation.Result = &resultPayload
+	}
+}
 
-		operations = append(operations, &operation)
+// scanOperations reads the operations of a list query
+func scanOperations(rows pgx.Rows) ([]*repo.Operation, error) {
+	operations := make([]*repo.Operation, 0)
+	for rows.Next() {
+		operation, err := scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -154,6 +216,33 @@ func (r *operationRepository) ListByCluster(ctx context.Context, clusterID uuid.
 	return operations, nil
 }
 
+// scanOperation reads the current row of a list query
+func scanOperation(rows pgx.Rows) (*repo.Operation, error) {
+	var operation repo.Operation
+	var payloadJSON, resultJSON []byte
+
+	err := rows.Scan(
+		&operation.ID,
+		&operation.ClusterID,
+		&operation.Type,
+		&operation.Status,
+		&payloadJSON,
+		&resultJSON,
+		&operation.StartedAt,
+		&operation.FinishedAt,
+		&operation.CreatedAt,
+		&operation.UpdatedAt,
+		&operation.InitiatedVia,
+		&operation.Reason,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan operation: %w", err)
+	}
+
+	decodeOperationJSON(&operation, payloadJSON, resultJSON)
+	return &operation, nil
+}
+
 func (r *operationRepository) Update(ctx context.Context, operation *repo.Operation) error {
 	query := `
 		UPDATE operations 
@@ -165,9 +254,15 @@ func (r *operationRepository) Update(ctx context.Context, operation *repo.Operat
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
+	// Don't overwrite JSON that couldn't be decoded with the empty placeholder
+	if operation.RawPayload != "" {
+		payloadJSON = []byte(operation.RawPayload)
+	}
 
 	var resultJSON *string
-	if operation.Result != nil {
+	if operation.RawResult != "" {
+		resultJSON = &operation.RawResult
+	} else if operation.Result != nil {
 		resultBytes, err := json.Marshal(*operation.Result)
 		if err != nil {
 			return fmt.Errorf("failed to marshal result: %w", err)
@@ -177,7 +272,7 @@ func (r *operationRepository) Update(ctx context.Context, operation *repo.Operat
 	}
 
 	now := time.Now().UTC()
-	_, err = r.db.pool.Exec(ctx, query,
+	_, err = r.updateWithEvent(ctx, operation.ID, "", query,
 		operation.ID,
 		operation.Status,
 		string(payloadJSON),
@@ -201,7 +296,7 @@ func (r *operationRepository) UpdateStatus(ctx context.Context, id uuid.UUID, st
 		WHERE id = $1
 	`
 
-	_, err := r.db.pool.Exec(ctx, query, id, status)
+	_, err := r.updateWithEvent(ctx, id, "", query, id, status)
 	if err != nil {
 		return fmt.Errorf("failed to update operation status: %w", err)
 	}
@@ -236,12 +331,11 @@ func (r *operationRepository) SetStarted(ctx context.Context, id uuid.UUID) erro
 		WHERE id = $1 AND status = 'queued'
 	`
 
-	result, err := r.db.pool.Exec(ctx, query, id)
+	rowsAffected, err := r.updateWithEvent(ctx, id, "started", query, id)
 	if err != nil {
 		return fmt.Errorf("failed to set operation as started: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
 	if rowsAffected == 0 {
 		return fmt.Errorf("operation not found or not in queued status")
 	}
@@ -289,15 +383,57 @@ func (r *operationRepository) CancelOperation(ctx context.Context, id uuid.UUID,
 		return fmt.Errorf("failed to marshal result: %w", err)
 	}
 
-	result, err := r.db.pool.Exec(ctx, query, id, string(resultJSON))
+	rowsAffected, err := r.updateWithEvent(ctx, id, reason, query, id, string(resultJSON))
 	if err != nil {
 		return fmt.Errorf("failed to cancel operation: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
 	if rowsAffected == 0 {
 		return fmt.Errorf("operation not found or cannot be cancelled")
 	}
 
 	return nil
 }
+
+// updateWithEvent runs a status-changing update of a single operation inside a
+// transaction and records the resulting transition in operation_events. The
+// query must take the operation ID as its first argument. It returns the
+// number of updated rows.
+func (r *operationRepository) updateWithEvent(ctx context.Context, id uuid.UUID, reason, query string, args ...any) (int64, error) {
+	var rowsAffected int64
+	err := r.db.Transaction(ctx, func(tx pgx.Tx) error {
+		var fromStatus string
+		err := tx.QueryRow(ctx, `SELECT status FROM operations WHERE id = $1 FOR UPDATE`, id).Scan(&fromStatus)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		rowsAffected = tag.RowsAffected()
+		if rowsAffected == 0 {
+			return nil
+		}
+
+		var toStatus string
+		if err := tx.QueryRow(ctx, `SELECT status FROM operations WHERE id = $1`, id).Scan(&toStatus); err != nil {
+			return err
+		}
+		if toStatus == fromStatus {
+			return nil
+		}
+
+		return insertOperationEvent(ctx, tx, &repo.OperationEvent{
+			OperationID: id,
+			FromStatus:  fromStatus,
+			ToStatus:    toStatus,
+			Reason:      reason,
+		})
+	})
+	return rowsAffected, err
+}
@@ -1,12 +1,14 @@
 package postgres
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rizesky/mckmt/internal/repo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestOperationRepository_Create(t *testing.T) {
@@ -170,3 +172,82 @@ func TestOperationRepository_JSONSerialization(t *testing.T) {
 	assert.NotNil(t, operation.Result)
 	assert.Empty(t, *operation.Result)
 }
+
+func TestOperationRepository_GetByIDWithMalformedPayload(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	ctx := context.Background()
+	operation := createTestOperation(t, db)
+
+	// Legacy rows stored payloads that aren't objects
+	_, err := db.pool.Exec(ctx, `UPDATE operations SET payload = '"legacy manifest"'::jsonb WHERE id = $1`, operation.ID)
+	require.NoError(t, err)
+
+	operations := NewOperationRepository(db)
+	got, err := operations.GetByID(ctx, operation.ID)
+	require.NoError(t, err)
+	assert.True(t, got.ParseError)
+	assert.Equal(t, `"legacy manifest"`, got.RawPayload)
+	assert.Empty(t, got.Payload)
+
+	// The bad row doesn't break listings either
+	listed, err := operations.ListByCluster(ctx, operation.ClusterID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.True(t, listed[0].ParseError)
+}
+
+func TestDecodeOperationJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		payload    string
+		result     string
+		parseError bool
+		rawPayload string
+		rawResult  string
+		hasResult  bool
+	}{
+		{
+			name:    "valid payload without result",
+			payload: `{"manifests":"kind: Pod"}`,
+		},
+		{
+			name:      "valid payload and result",
+			payload:   `{"manifests":"kind: Pod"}`,
+			result:    `{"status":"applied"}`,
+			hasResult: true,
+		},
+		{
+			name:       "payload that isn't an object",
+			payload:    `"legacy manifest"`,
+			parseError: true,
+			rawPayload: `"legacy manifest"`,
+		},
+		{
+			name:       "result that isn't an object",
+			payload:    `{}`,
+			result:     `["applied"]`,
+			parseError: true,
+			rawResult:  `["applied"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operation := &repo.Operation{}
+			var result []byte
+			if tt.result != "" {
+				result = []byte(tt.result)
+			}
+
+			decodeOperationJSON(operation, []byte(tt.payload), result)
+
+			assert.Equal(t, tt.parseError, operation.ParseError)
+			assert.Equal(t, tt.rawPayload, operation.RawPayload)
+			assert.Equal(t, tt.rawResult, operation.RawResult)
+			assert.Equal(t, tt.hasResult, operation.Result != nil)
+			assert.NotNil(t, operation.Payload)
+		})
+	}
+}
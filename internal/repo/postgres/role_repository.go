@@ -196,3 +196,54 @@ func (r *roleRepository) RemovePermissionFromRole(ctx context.Context, roleID, p
 	_, err := r.db.pool.Exec(ctx, query, roleID, permissionID)
 	return err
 }
+
+// ListUserRoleAssignments returns the roles assigned to every user
+func (r *roleRepository) ListUserRoleAssignments(ctx context.Context) ([]*repo.UserRoleAssignment, error) {
+	query := `
+		SELECT ur.user_id, r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		ORDER BY ur.user_id, r.name
+	`
+	rows, err := r.db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []*repo.UserRoleAssignment
+	for rows.Next() {
+		var assignment repo.UserRoleAssignment
+		if err := rows.Scan(&assignment.UserID, &assignment.RoleName); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, &assignment)
+	}
+	return assignments, rows.Err()
+}
+
+// ListRolePermissionAssignments returns the permissions granted to every role
+func (r *roleRepository) ListRolePermissionAssignments(ctx context.Context) ([]*repo.RolePermissionAssignment, error) {
+	query := `
+		SELECT r.name, p.resource, p.action
+		FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		ORDER BY r.name, p.resource, p.action
+	`
+	rows, err := r.db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []*repo.RolePermissionAssignment
+	for rows.Next() {
+		var assignment repo.RolePermissionAssignment
+		if err := rows.Scan(&assignment.RoleName, &assignment.Resource, &assignment.Action); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, &assignment)
+	}
+	return assignments, rows.Err()
+}
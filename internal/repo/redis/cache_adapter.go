@@ -58,6 +58,80 @@ func (c *CacheAdapter) Delete(ctx context.Context, key string) error {
 	return c.client.Del(ctx, key).Err()
 }
 
+// IncrementWithExpiry atomically increments the counter at key, starting its
+// expiration when it is created
+func (c *CacheAdapter) IncrementWithExpiry(ctx context.Context, key string, expiration time.Duration) (int64, time.Duration, error) {
+	return incrementWithExpiry(ctx, c.client, key, expiration)
+}
+
+// incrementWithExpiryScript increments a counter, setting its expiration in
+// milliseconds when it is created, and returns the count and its time to live
+var incrementWithExpiryScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}
+`)
+
+// incrementWithExpiry runs incrementWithExpiryScript, so concurrent
+// increments are never lost and the counter always expires
+func incrementWithExpiry(ctx context.Context, client *redis.Client, key string, expiration time.Duration) (int64, time.Duration, error) {
+	result, err := incrementWithExpiryScript.Run(ctx, client, []string{key}, expiration.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+	return result[0], time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// CompareAndSwap atomically replaces the value at key when it still holds
+// expected, or when it is absent for a nil expected
+func (c *CacheAdapter) CompareAndSwap(ctx context.Context, key string, expected, value interface{}, expiration time.Duration) (bool, error) {
+	return compareAndSwap(ctx, c.client, key, expected, value, expiration)
+}
+
+// compareAndSwapScript sets a key to ARGV[2] when it holds ARGV[1], or when it
+// is absent for an empty ARGV[1], expiring it after ARGV[3] milliseconds
+var compareAndSwapScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if ARGV[1] == "" then
+	if current then
+		return 0
+	end
+elseif current ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// compareAndSwap runs compareAndSwapScript, so a value read by one client is
+// never replaced after another client changed it
+func compareAndSwap(ctx context.Context, client *redis.Client, key string, expected, value interface{}, expiration time.Duration) (bool, error) {
+	var expectedData []byte
+	if expected != nil {
+		data, err := json.Marshal(expected)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal expected value: %w", err)
+		}
+		expectedData = data
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	swapped, err := compareAndSwapScript.Run(ctx, client, []string{key}, expectedData, data, expiration.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to compare and swap value: %w", err)
+	}
+	return swapped == 1, nil
+}
+
 // Keys returns all keys matching pattern
 func (c *CacheAdapter) Keys(ctx context.Context, pattern string) ([]string, error) {
 	return c.client.Keys(ctx, pattern).Result()
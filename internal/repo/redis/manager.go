@@ -82,6 +82,18 @@ func (m *Manager) Increment(ctx context.Context, key string) (int64, error) {
 	return m.client.Incr(ctx, key).Result()
 }
 
+// IncrementWithExpiry atomically increments the counter at key, starting its
+// expiration when it is created
+func (m *Manager) IncrementWithExpiry(ctx context.Context, key string, expiration time.Duration) (int64, time.Duration, error) {
+	return incrementWithExpiry(ctx, m.client, key, expiration)
+}
+
+// CompareAndSwap atomically replaces the value at key when it still holds
+// expected, or when it is absent for a nil expected
+func (m *Manager) CompareAndSwap(ctx context.Context, key string, expected, value interface{}, expiration time.Duration) (bool, error) {
+	return compareAndSwap(ctx, m.client, key, expected, value, expiration)
+}
+
 // IncrementBy increments a counter by a specific amount
 func (m *Manager) IncrementBy(ctx context.Context, key string, value int64) (int64, error) {
 	return m.client.IncrBy(ctx, key, value).Result()
@@ -3,6 +3,7 @@ package testutils
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -98,6 +99,56 @@ func (m *MockOperationRepository) ListByCluster(ctx context.Context, clusterID u
 	return operations, nil
 }
 
+// EachByCluster implements repo.OperationRepository
+func (m *MockOperationRepository) EachByCluster(ctx context.Context, clusterID uuid.UUID, fn func(*repo.Operation) error) error {
+	if m.listErr != nil {
+		return m.listErr
+	}
+
+	for _, op := range m.operations {
+		if op.ClusterID == clusterID {
+			if err := fn(op); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CountByCluster implements repo.OperationRepository
+func (m *MockOperationRepository) CountByCluster(ctx context.Context, clusterID uuid.UUID) (int, error) {
+	if m.listErr != nil {
+		return 0, m.listErr
+	}
+
+	count := 0
+	for _, op := range m.operations {
+		if op.ClusterID == clusterID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ListByStatus implements repo.OperationRepository
+func (m *MockOperationRepository) ListByStatus(ctx context.Context, status string, limit, offset int) ([]*repo.Operation, error) {
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+
+	var operations []*repo.Operation
+	count := 0
+	for _, op := range m.operations {
+		if op.Status == status {
+			if count >= offset && len(operations) < limit {
+				operations = append(operations, op)
+			}
+			count++
+		}
+	}
+	return operations, nil
+}
+
 // Update implements repo.OperationRepository
 func (m *MockOperationRepository) Update(ctx context.Context, operation *repo.Operation) error {
 	if m.updateErr != nil {
@@ -329,6 +380,33 @@ func (m *MockClusterRepository) UpdateStatus(ctx context.Context, id uuid.UUID,
 	return nil
 }
 
+// SetQuarantine implements repo.ClusterRepository
+func (m *MockClusterRepository) SetQuarantine(ctx context.Context, id uuid.UUID, quarantinedAt *time.Time, reason string) error {
+	if m.updateErr != nil {
+		return m.updateErr
+	}
+	cluster, exists := m.clusters[id]
+	if !exists {
+		return repo.ErrNotFound
+	}
+	cluster.QuarantinedAt = quarantinedAt
+	cluster.QuarantineReason = reason
+	cluster.UpdatedAt = time.Now()
+	return nil
+}
+
+// CountByStatus implements repo.ClusterRepository
+func (m *MockClusterRepository) CountByStatus(ctx context.Context) (map[string]int, error) {
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	counts := make(map[string]int)
+	for _, cluster := range m.clusters {
+		counts[cluster.Status]++
+	}
+	return counts, nil
+}
+
 // MockCache is a mock implementation of repo.Cache
 type MockCache struct {
 	data      map[string]interface{}
@@ -407,6 +485,35 @@ func (m *MockCache) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+// IncrementWithExpiry implements repo.Cache. Expirations are ignored.
+func (m *MockCache) IncrementWithExpiry(ctx context.Context, key string, expiration time.Duration) (int64, time.Duration, error) {
+	if m.setErr != nil {
+		return 0, 0, m.setErr
+	}
+	count, _ := m.data[key].(int64)
+	count++
+	m.data[key] = count
+	return count, expiration, nil
+}
+
+// CompareAndSwap implements repo.Cache. Values are compared as stored and
+// expirations are ignored.
+func (m *MockCache) CompareAndSwap(ctx context.Context, key string, expected, value interface{}, expiration time.Duration) (bool, error) {
+	if m.setErr != nil {
+		return false, m.setErr
+	}
+	current, exists := m.data[key]
+	if expected == nil {
+		if exists {
+			return false, nil
+		}
+	} else if !reflect.DeepEqual(current, expected) {
+		return false, nil
+	}
+	m.data[key] = value
+	return true, nil
+}
+
 // ClusterKey implements repo.Cache
 func (m *MockCache) ClusterKey(id string) string {
 	return "cluster:" + id
@@ -484,8 +591,9 @@ func (m *MockCache) Health(ctx context.Context) error {
 
 // MockOrchestrator is a mock implementation of OrchestratorInterface
 type MockOrchestrator struct {
-	queueErr  error
-	queuedOps []*repo.Operation
+	queueErr     error
+	queuedOps    []*repo.Operation
+	cancelledOps []uuid.UUID
 }
 
 // NewMockOrchestrator creates a new mock orchestrator
@@ -514,6 +622,17 @@ func (m *MockOrchestrator) QueueOperation(operation *repo.Operation) error {
 	return nil
 }
 
+// GetCancelledOperations returns the IDs of cancelled operations
+func (m *MockOrchestrator) GetCancelledOperations() []uuid.UUID {
+	return m.cancelledOps
+}
+
+// CancelOperation implements OrchestratorInterface
+func (m *MockOrchestrator) CancelOperation(operationID uuid.UUID) error {
+	m.cancelledOps = append(m.cancelledOps, operationID)
+	return nil
+}
+
 // NewTestLogger creates a test logger
 func NewTestLogger() *zap.Logger {
 	cfg := config.LoggingConfig{
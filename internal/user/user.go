@@ -84,3 +84,28 @@ type UserCluster struct {
 	GrantedAt   time.Time  `json:"granted_at" db:"granted_at"`
 	GrantedBy   *uuid.UUID `json:"granted_by,omitempty" db:"granted_by"`
 }
+
+// APIToken is a personal access token, a long-lived credential a user issues
+// for non-interactive clients such as CI systems. Only a digest of the token
+// is stored; the token itself is shown once, when it is created.
+type APIToken struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	TokenHash string    `json:"-" db:"token_hash"`
+	Scopes    []string  `json:"scopes" db:"scopes"`
+	// ClusterIDs restricts the token to the given clusters; empty means all
+	ClusterIDs []uuid.UUID `json:"cluster_ids,omitempty" db:"cluster_ids"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+}
+
+// Usable reports whether the token is neither revoked nor expired at now
+func (t *APIToken) Usable(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
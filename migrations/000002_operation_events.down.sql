@@ -0,0 +1,4 @@
+-- Rollback operation state transition history
+
+DROP INDEX IF EXISTS idx_operation_events_operation_id;
+DROP TABLE IF EXISTS operation_events;
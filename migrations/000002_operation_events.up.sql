@@ -0,0 +1,13 @@
+-- Operation state transition history
+-- Every status change of an operation is recorded with its timestamp and reason
+
+CREATE TABLE IF NOT EXISTS operation_events (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    operation_id uuid NOT NULL REFERENCES operations(id) ON DELETE CASCADE,
+    from_status text,
+    to_status text NOT NULL,
+    reason text NOT NULL DEFAULT '',
+    created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_operation_events_operation_id ON operation_events(operation_id, created_at);
@@ -0,0 +1,3 @@
+-- Rollback cluster capabilities
+
+ALTER TABLE clusters DROP COLUMN IF EXISTS capabilities;
@@ -0,0 +1,4 @@
+-- Operation types and features advertised by a cluster's agent at registration
+-- NULL means the agent did not advertise capabilities and supports every type
+
+ALTER TABLE clusters ADD COLUMN IF NOT EXISTS capabilities jsonb;
@@ -0,0 +1,3 @@
+-- Rollback operation output
+
+DROP TABLE IF EXISTS operation_outputs;
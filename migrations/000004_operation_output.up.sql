@@ -0,0 +1,8 @@
+-- Output produced by operations while they run
+-- Agents append chunks as they go so clients can tail the output by byte offset
+
+CREATE TABLE IF NOT EXISTS operation_outputs (
+    operation_id uuid PRIMARY KEY REFERENCES operations(id) ON DELETE CASCADE,
+    output bytea NOT NULL DEFAULT '',
+    updated_at timestamptz DEFAULT now()
+);
@@ -0,0 +1,5 @@
+-- Rollback audit request details
+
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS status_code;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS path;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS method;
@@ -0,0 +1,6 @@
+-- HTTP request details of audit entries recorded for mutating API calls
+-- Entries recorded by the auth service leave them empty
+
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS method text NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS path text NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS status_code integer NOT NULL DEFAULT 0;
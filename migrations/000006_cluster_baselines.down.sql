@@ -0,0 +1,3 @@
+-- Rollback cluster baselines
+
+DROP TABLE IF EXISTS cluster_baselines;
@@ -0,0 +1,8 @@
+-- Desired inventory of each cluster
+-- Sync operations compare the live inventory against it and report drift
+
+CREATE TABLE IF NOT EXISTS cluster_baselines (
+    cluster_id uuid PRIMARY KEY REFERENCES clusters(id) ON DELETE CASCADE,
+    resources jsonb NOT NULL DEFAULT '[]',
+    updated_at timestamptz DEFAULT now()
+);
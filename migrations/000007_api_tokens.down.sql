@@ -0,0 +1,3 @@
+-- Rollback personal access tokens
+
+DROP TABLE IF EXISTS api_tokens;
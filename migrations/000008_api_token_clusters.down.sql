@@ -0,0 +1,3 @@
+-- Rollback cluster scope of personal access tokens
+
+ALTER TABLE api_tokens DROP COLUMN IF EXISTS cluster_ids;
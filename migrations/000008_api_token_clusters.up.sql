@@ -0,0 +1,4 @@
+-- Cluster scope of personal access tokens
+-- A token listing clusters can only act on those clusters
+
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS cluster_ids jsonb NOT NULL DEFAULT '[]';
@@ -0,0 +1,5 @@
+-- Rollback operation provenance
+
+DROP INDEX IF EXISTS idx_operations_initiated_via;
+ALTER TABLE operations DROP COLUMN IF EXISTS reason;
+ALTER TABLE operations DROP COLUMN IF EXISTS initiated_via;
@@ -0,0 +1,9 @@
+-- Provenance of operations: the channel they were initiated through and an optional reason
+-- Existing operations recorded the channel ad hoc in the "source" payload key
+
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS initiated_via varchar(32) NOT NULL DEFAULT '';
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS reason text NOT NULL DEFAULT '';
+
+UPDATE operations SET initiated_via = payload->>'source' WHERE payload ? 'source';
+
+CREATE INDEX IF NOT EXISTS idx_operations_initiated_via ON operations(initiated_via);
@@ -0,0 +1,4 @@
+-- Rollback cluster quarantine
+
+ALTER TABLE clusters DROP COLUMN IF EXISTS quarantine_reason;
+ALTER TABLE clusters DROP COLUMN IF EXISTS quarantined_at;
@@ -0,0 +1,4 @@
+-- Quarantine of misbehaving clusters: no new operations are accepted while quarantined_at is set
+
+ALTER TABLE clusters ADD COLUMN IF NOT EXISTS quarantined_at timestamptz;
+ALTER TABLE clusters ADD COLUMN IF NOT EXISTS quarantine_reason text NOT NULL DEFAULT '';
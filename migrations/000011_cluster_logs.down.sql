@@ -0,0 +1,3 @@
+-- Rollback cluster logs
+
+DROP TABLE IF EXISTS cluster_logs;
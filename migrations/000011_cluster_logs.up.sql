@@ -0,0 +1,13 @@
+-- Logs agents stream to the hub, kept per cluster and listed newest first
+
+CREATE TABLE IF NOT EXISTS cluster_logs (
+    id uuid PRIMARY KEY,
+    cluster_id uuid NOT NULL REFERENCES clusters(id) ON DELETE CASCADE,
+    level text NOT NULL,
+    message text NOT NULL,
+    source text NOT NULL DEFAULT '',
+    fields jsonb NOT NULL DEFAULT '{}',
+    timestamp timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cluster_logs_cluster_timestamp ON cluster_logs (cluster_id, timestamp DESC);
@@ -0,0 +1,3 @@
+-- Rollback operation dead letters
+
+DROP TABLE IF EXISTS operation_dead_letters;
@@ -0,0 +1,13 @@
+-- Operations that failed permanently or ran out of retries, kept for
+-- inspection until they are re-driven
+
+CREATE TABLE IF NOT EXISTS operation_dead_letters (
+    operation_id uuid PRIMARY KEY REFERENCES operations(id) ON DELETE CASCADE,
+    cluster_id uuid NOT NULL,
+    type text NOT NULL,
+    reason text NOT NULL DEFAULT '',
+    attempts integer NOT NULL DEFAULT 1,
+    dead_lettered_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_operation_dead_letters_dead_lettered_at ON operation_dead_letters (dead_lettered_at DESC);